@@ -8,12 +8,38 @@ package anthropic
 
 import "encoding/json"
 
+// DefaultModel is the model requests use when they name none.
+const DefaultModel = "claude-sonnet-4-20250514"
+
 const (
 	defaultBaseURL   = "https://api.anthropic.com"
-	defaultModel     = "claude-sonnet-4-20250514"
 	defaultMaxTokens = 8192
 	apiVersion       = "2023-06-01"
 	messagesPath     = "/v1/messages"
+
+	// Server tool versions and the beta flag code execution requires.
+	webSearchToolType     = "web_search_20250305"
+	codeExecutionToolType = "code_execution_20250825"
+	codeExecutionBeta     = "code-execution-2025-08-25"
+
+	// invalidJSONKey wraps tool input that did not stream as valid JSON.
+	invalidJSONKey = "INVALID_JSON"
+
+	// serverToolResultSuffix joins a server tool name to its result block
+	// type, e.g. "web_search" -> "web_search_tool_result".
+	serverToolResultSuffix = "_tool_result"
+)
+
+// Beta flags that change API behavior, for use with [WithBetas].
+const (
+	// BetaFineGrainedToolStreaming streams tool input without buffering or
+	// JSON validation. Input cut short (e.g. by max_tokens) may be invalid
+	// JSON; it is wrapped as {"INVALID_JSON": "<raw input>"} so the call can
+	// still be replayed and rejected by the tool.
+	BetaFineGrainedToolStreaming = "fine-grained-tool-streaming-2025-05-14"
+	// BetaInterleavedThinking lets the model think between tool calls, so
+	// thinking blocks may follow tool_use blocks within one message.
+	BetaInterleavedThinking = "interleaved-thinking-2025-05-14"
 )
 
 // apiCacheControl specifies a cache breakpoint for prompt caching.
@@ -64,20 +90,40 @@ type apiContentBlock struct {
 	// image
 	Source *apiImageSource `json:"source,omitempty"`
 
+	// server tool results (e.g. web_search_tool_result) carry a
+	// provider-defined payload under "content" instead of content blocks.
+	RawContent json.RawMessage `json:"-"`
+
 	// cache control
 	CacheControl *apiCacheControl `json:"cache_control,omitempty"`
 }
 
+// MarshalJSON emits RawContent as the "content" field when set. The outer
+// Content field shadows the embedded one because it is less deeply nested.
+func (b apiContentBlock) MarshalJSON() ([]byte, error) {
+	type plain apiContentBlock
+	if b.RawContent == nil {
+		return json.Marshal(plain(b))
+	}
+	return json.Marshal(struct {
+		plain
+		Content json.RawMessage `json:"content"`
+	}{plain(b), b.RawContent})
+}
+
 type apiImageSource struct {
 	Type      string `json:"type"`
 	MediaType string `json:"media_type"`
 	Data      string `json:"data"`
 }
 
+// apiTool is a tool definition. Client tools set Description and InputSchema;
+// server tools set Type instead.
 type apiTool struct {
+	Type         string           `json:"type,omitempty"`
 	Name         string           `json:"name"`
-	Description  string           `json:"description"`
-	InputSchema  json.RawMessage  `json:"input_schema"`
+	Description  string           `json:"description,omitempty"`
+	InputSchema  json.RawMessage  `json:"input_schema,omitempty"`
 	CacheControl *apiCacheControl `json:"cache_control,omitempty"`
 }
 
@@ -121,11 +167,13 @@ type sseContentBlockStart struct {
 }
 
 type sseContentBlock struct {
-	Type     string `json:"type"`
-	Text     string `json:"text,omitempty"`
-	Thinking string `json:"thinking,omitempty"`
-	ID       string `json:"id,omitempty"`
-	Name     string `json:"name,omitempty"`
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
 }
 
 type sseContentBlockDelta struct {
@@ -135,11 +183,22 @@ type sseContentBlockDelta struct {
 }
 
 type sseDelta struct {
-	Type        string `json:"type"`
-	Text        string `json:"text,omitempty"`
-	PartialJSON string `json:"partial_json,omitempty"`
-	Thinking    string `json:"thinking,omitempty"`
-	Signature   string `json:"signature,omitempty"`
+	Type        string       `json:"type"`
+	Text        string       `json:"text,omitempty"`
+	PartialJSON string       `json:"partial_json,omitempty"`
+	Thinking    string       `json:"thinking,omitempty"`
+	Signature   string       `json:"signature,omitempty"`
+	Citation    *sseCitation `json:"citation,omitempty"`
+}
+
+// sseCitation is carried by citations_delta events. Web search citations
+// set URL and Title; document citations set DocumentTitle.
+type sseCitation struct {
+	Type          string `json:"type"`
+	URL           string `json:"url,omitempty"`
+	Title         string `json:"title,omitempty"`
+	DocumentTitle string `json:"document_title,omitempty"`
+	CitedText     string `json:"cited_text,omitempty"`
 }
 
 type sseContentBlockStop struct {
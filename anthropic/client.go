@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/fwojciec/pipe"
 )
@@ -21,6 +23,9 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	cacheTTL   string
+	cache      CacheStrategy
+	betas      []string
+	results    pipe.ToolResultFormat
 }
 
 // Option configures a [Client].
@@ -42,6 +47,49 @@ func WithCacheTTL(ttl string) Option {
 	return func(c *Client) { c.cacheTTL = ttl }
 }
 
+// maxCacheBreakpoints is how many cache_control breakpoints the API
+// accepts in one request.
+const maxCacheBreakpoints = 4
+
+// CacheStrategy controls where prompt caching breakpoints go. The zero
+// value marks the system prompt and the tools, and lets the API cache the
+// conversation with its automatic message-window breakpoint.
+type CacheStrategy struct {
+	// RecentMessages marks the last block of each of the last
+	// RecentMessages messages instead of using the automatic breakpoint,
+	// so a long agentic session can reuse the cached prefix up to any of
+	// them even when the latest message changes. The count is capped to
+	// fit the API's limit of four breakpoints alongside the system prompt
+	// and tools.
+	RecentMessages int
+}
+
+// WithCacheStrategy sets where prompt caching breakpoints go.
+func WithCacheStrategy(s CacheStrategy) Option {
+	return func(c *Client) { c.cache = s }
+}
+
+// WithBetas opts in to beta API behaviors by sending the given flags in the
+// Anthropic-Beta header of every request, e.g. [BetaInterleavedThinking].
+func WithBetas(flags ...string) Option {
+	return func(c *Client) { c.betas = append(c.betas, flags...) }
+}
+
+// WithToolResultFormat sets how tool result text is rewritten before it is
+// sent. By default it is sent unchanged; [CompactToolResults] spends fewer
+// tokens on it.
+func WithToolResultFormat(f pipe.ToolResultFormat) Option {
+	return func(c *Client) { c.results = f }
+}
+
+// CompactToolResults returns the tool result format suited to Claude:
+// terminal escapes and a fence around the whole result are dropped.
+// Whitespace is kept; tool results reach the model as plain text, where
+// it costs little.
+func CompactToolResults() pipe.ToolResultFormat {
+	return pipe.ToolResultFormat{StripANSI: true, Unfence: true}
+}
+
 // New creates a new Anthropic [Client] with the given API key and options.
 func New(apiKey string, opts ...Option) *Client {
 	c := &Client{
@@ -70,6 +118,9 @@ func (c *Client) Stream(ctx context.Context, req pipe.Request) (pipe.Stream, err
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("X-Api-Key", c.apiKey)
 	httpReq.Header.Set("Anthropic-Version", apiVersion)
+	if betas := c.betaFlags(req); len(betas) > 0 {
+		httpReq.Header.Set("Anthropic-Beta", strings.Join(betas, ","))
+	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -88,10 +139,13 @@ func (c *Client) buildRequestBody(req pipe.Request) ([]byte, error) {
 	if c.cacheTTL != "" && c.cacheTTL != "1h" {
 		return nil, fmt.Errorf("invalid cache TTL %q: must be \"\" or \"1h\"", c.cacheTTL)
 	}
+	if c.cache.RecentMessages < 0 {
+		return nil, fmt.Errorf("invalid cache strategy: recent messages %d is negative", c.cache.RecentMessages)
+	}
 
 	model := req.Model
 	if model == "" {
-		model = defaultModel
+		model = DefaultModel
 	}
 	maxTokens := req.MaxTokens
 	if maxTokens == 0 {
@@ -103,11 +157,11 @@ func (c *Client) buildRequestBody(req pipe.Request) ([]byte, error) {
 		MaxTokens:   maxTokens,
 		Stream:      true,
 		System:      convertSystem(req.SystemPrompt),
-		Messages:    convertMessages(req.Messages),
-		Tools:       convertTools(req.Tools),
+		Messages:    convertMessages(pipe.FormatToolResults(req.Messages, c.results)),
+		Tools:       append(convertTools(req.Tools), convertServerTools(req.ServerTools)...),
 		Temperature: req.Temperature,
 	}
-	injectCacheMarkers(&apiReq, c.cacheTTL)
+	injectCacheMarkers(&apiReq, c.cacheTTL, c.cache)
 
 	return json.Marshal(apiReq)
 }
@@ -122,27 +176,58 @@ func convertSystem(prompt string) []apiContentBlock {
 }
 
 // injectCacheMarkers sets cache_control breakpoints on the request:
-//  1. Top-level: automatic caching for the conversation message window.
+//  1. Top-level: automatic caching for the conversation message window,
+//     unless the strategy marks recent messages instead.
 //  2. System prompt last block: stable content breakpoint.
 //  3. Last tool: stable tool definitions breakpoint.
-func injectCacheMarkers(req *apiRequest, ttl string) {
+//  4. Recent messages' last blocks, as many as the strategy asks for and
+//     the breakpoint limit leaves room for.
+func injectCacheMarkers(req *apiRequest, ttl string, strategy CacheStrategy) {
 	// cc is shared across all breakpoints; safe because it is read-only after assignment.
 	cc := &apiCacheControl{Type: "ephemeral", TTL: ttl}
-
-	// Top-level cache_control for automatic message-window caching.
-	req.CacheControl = cc
+	free := maxCacheBreakpoints
 
 	// System prompt last block.
 	if len(req.System) > 0 {
 		req.System[len(req.System)-1].CacheControl = cc
+		free--
 	}
 
 	// Last tool.
 	if len(req.Tools) > 0 {
 		req.Tools[len(req.Tools)-1].CacheControl = cc
+		free--
+	}
+
+	if strategy.RecentMessages == 0 {
+		// Top-level cache_control for automatic message-window caching.
+		req.CacheControl = cc
+		return
+	}
+	marked := 0
+	for i := len(req.Messages) - 1; i >= 0 && marked < min(strategy.RecentMessages, free); i-- {
+		if markLastBlock(req.Messages[i].Content, cc) {
+			marked++
+		}
 	}
 }
 
+// markLastBlock sets cc on the last block of content that accepts a
+// breakpoint. Thinking blocks and empty text do not. It reports whether a
+// block was marked.
+func markLastBlock(content []apiContentBlock, cc *apiCacheControl) bool {
+	for i := len(content) - 1; i >= 0; i-- {
+		b := &content[i]
+		switch {
+		case b.Type == "thinking", b.Type == "text" && b.Text == "":
+			continue
+		}
+		b.CacheControl = cc
+		return true
+	}
+	return false
+}
+
 func convertMessages(msgs []pipe.Message) []apiMessage {
 	var result []apiMessage
 	for _, msg := range msgs {
@@ -153,6 +238,11 @@ func convertMessages(msgs []pipe.Message) []apiMessage {
 				Content: convertContentBlocks(m.Content),
 			})
 		case pipe.AssistantMessage:
+			if len(m.Content) == 0 {
+				// Failed requests are recorded as empty messages; the API
+				// rejects empty content.
+				continue
+			}
 			result = append(result, apiMessage{
 				Role:    "assistant",
 				Content: convertContentBlocks(m.Content),
@@ -192,6 +282,18 @@ func convertContentBlocks(blocks []pipe.ContentBlock) []apiContentBlock {
 			result = append(result, apiContentBlock{Type: "thinking", Thinking: bl.Thinking, Signature: string(bl.Signature)})
 		case pipe.ToolCallBlock:
 			result = append(result, apiContentBlock{Type: "tool_use", ID: bl.ID, Name: bl.Name, Input: bl.Arguments})
+		case pipe.ServerToolCallBlock:
+			input := bl.Arguments
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			result = append(result, apiContentBlock{Type: "server_tool_use", ID: bl.ID, Name: bl.Name, Input: input})
+		case pipe.ServerToolResultBlock:
+			result = append(result, apiContentBlock{
+				Type:       bl.Name + serverToolResultSuffix,
+				ToolUseID:  bl.ToolCallID,
+				RawContent: bl.Content,
+			})
 		case pipe.ImageBlock:
 			result = append(result, apiContentBlock{
 				Type: "image",
@@ -221,14 +323,58 @@ func convertTools(tools []pipe.Tool) []apiTool {
 	return result
 }
 
+// convertServerTools maps provider-hosted tools onto Anthropic's server tool
+// definitions. Unsupported values are ignored.
+func convertServerTools(tools []pipe.ServerTool) []apiTool {
+	var result []apiTool
+	for _, t := range tools {
+		switch t {
+		case pipe.ServerToolWebSearch:
+			result = append(result, apiTool{Type: webSearchToolType, Name: "web_search"})
+		case pipe.ServerToolCodeExecution:
+			result = append(result, apiTool{Type: codeExecutionToolType, Name: "code_execution"})
+		}
+	}
+	return result
+}
+
+// betaFlags returns the anthropic-beta flags required by the request.
+func (c *Client) betaFlags(req pipe.Request) []string {
+	var betas []string
+	if slices.Contains(req.ServerTools, pipe.ServerToolCodeExecution) {
+		betas = append(betas, codeExecutionBeta)
+	}
+	for _, b := range c.betas {
+		if !slices.Contains(betas, b) {
+			betas = append(betas, b)
+		}
+	}
+	return betas
+}
+
 func parseHTTPError(resp *http.Response) error {
+	raw := fmt.Sprintf("http_%d", resp.StatusCode)
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("anthropic: HTTP %d (failed to read body: %w)", resp.StatusCode, err)
+		return providerError(resp.StatusCode, raw, fmt.Errorf("anthropic: HTTP %d (failed to read body: %w)", resp.StatusCode, err))
 	}
 	var apiErr apiErrorResponse
 	if err := json.Unmarshal(body, &apiErr); err != nil {
-		return fmt.Errorf("anthropic: HTTP %d: %s", resp.StatusCode, string(body))
+		return providerError(resp.StatusCode, raw, fmt.Errorf("anthropic: HTTP %d: %s", resp.StatusCode, string(body)))
+	}
+	return providerError(resp.StatusCode, apiErr.Error.Type, fmt.Errorf("anthropic: %s: %s", apiErr.Error.Type, apiErr.Error.Message))
+}
+
+// providerError classifies an API failure by HTTP status (0 for errors
+// reported mid-stream) and error type.
+func providerError(status int, errType string, err error) *pipe.ProviderError {
+	reason := pipe.StopError
+	switch {
+	case status == http.StatusTooManyRequests, errType == "rate_limit_error", errType == "overloaded_error":
+		reason = pipe.StopRateLimited
+	case status == http.StatusUnauthorized, status == http.StatusForbidden,
+		errType == "authentication_error", errType == "permission_error":
+		reason = pipe.StopAuthFailed
 	}
-	return fmt.Errorf("anthropic: %s: %s", apiErr.Error.Type, apiErr.Error.Message)
+	return &pipe.ProviderError{Reason: reason, Raw: errType, Err: err}
 }
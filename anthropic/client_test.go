@@ -568,6 +568,99 @@ func TestClient_CacheTTL(t *testing.T) {
 	})
 }
 
+func TestClient_CacheStrategy(t *testing.T) {
+	t.Parallel()
+
+	minimalSSE := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"content\":[],\"model\":\"m\",\"stop_reason\":null,\"stop_sequence\":null,\"usage\":{\"input_tokens\":0,\"output_tokens\":0}}}\n\nevent: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":0}}\n\nevent: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	// A multi-turn agentic history: prompt, tool call, tool result, and a
+	// final answer that starts with a thinking block.
+	history := []pipe.Message{
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Read a.go"}}},
+		pipe.AssistantMessage{Content: []pipe.ContentBlock{
+			pipe.TextBlock{Text: "Reading."},
+			pipe.ToolCallBlock{ID: "call_1", Name: "read", Arguments: json.RawMessage(`{"file_path":"a.go"}`)},
+		}},
+		pipe.ToolResultMessage{ToolCallID: "call_1", ToolName: "read", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "package a"}}},
+		pipe.AssistantMessage{Content: []pipe.ContentBlock{
+			pipe.TextBlock{Text: "It declares package a."},
+			pipe.ThinkingBlock{Thinking: "done", Signature: []byte("sig")},
+		}},
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Thanks"}}},
+	}
+
+	// send returns the request body sent for req with strategy, and the
+	// cache_control marks of each message's blocks.
+	send := func(t *testing.T, strategy anthropic.CacheStrategy, req pipe.Request) (map[string]any, [][]bool) {
+		t.Helper()
+		var captured []byte
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured, _ = io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(minimalSSE))
+		}))
+		defer srv.Close()
+
+		client := anthropic.New("key", anthropic.WithBaseURL(srv.URL), anthropic.WithCacheStrategy(strategy))
+		s, err := client.Stream(context.Background(), req)
+		require.NoError(t, err)
+		defer s.Close()
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(captured, &body))
+		var marks [][]bool
+		for _, m := range body["messages"].([]any) {
+			var blocks []bool
+			for _, b := range m.(map[string]any)["content"].([]any) {
+				blocks = append(blocks, b.(map[string]any)["cache_control"] != nil)
+			}
+			marks = append(marks, blocks)
+		}
+		return body, marks
+	}
+
+	t.Run("default marks no messages", func(t *testing.T) {
+		t.Parallel()
+		body, marks := send(t, anthropic.CacheStrategy{}, pipe.Request{Messages: history})
+		assert.NotNil(t, body["cache_control"])
+		assert.Equal(t, [][]bool{{false}, {false, false}, {false}, {false, false}, {false}}, marks)
+	})
+
+	t.Run("recent messages replace the automatic breakpoint", func(t *testing.T) {
+		t.Parallel()
+		body, marks := send(t, anthropic.CacheStrategy{RecentMessages: 3}, pipe.Request{Messages: history})
+		assert.Nil(t, body["cache_control"])
+		// The thinking block is skipped for the text before it.
+		assert.Equal(t, [][]bool{{false}, {false, false}, {true}, {true, false}, {true}}, marks)
+	})
+
+	t.Run("system prompt and tools leave room for two", func(t *testing.T) {
+		t.Parallel()
+		body, marks := send(t, anthropic.CacheStrategy{RecentMessages: 4}, pipe.Request{
+			SystemPrompt: "Be helpful.",
+			Messages:     history,
+			Tools:        []pipe.Tool{{Name: "read", Description: "Read", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		})
+		assert.NotNil(t, body["system"].([]any)[0].(map[string]any)["cache_control"])
+		assert.NotNil(t, body["tools"].([]any)[0].(map[string]any)["cache_control"])
+		assert.Equal(t, [][]bool{{false}, {false, false}, {false}, {true, false}, {true}}, marks)
+	})
+
+	t.Run("marks move with the conversation", func(t *testing.T) {
+		t.Parallel()
+		_, marks := send(t, anthropic.CacheStrategy{RecentMessages: 2}, pipe.Request{Messages: history[:3]})
+		assert.Equal(t, [][]bool{{false}, {false, true}, {true}}, marks)
+	})
+
+	t.Run("negative count is rejected", func(t *testing.T) {
+		t.Parallel()
+		client := anthropic.New("key", anthropic.WithBaseURL("http://127.0.0.1:0"), anthropic.WithCacheStrategy(anthropic.CacheStrategy{RecentMessages: -1}))
+		_, err := client.Stream(context.Background(), pipe.Request{Messages: history})
+		assert.ErrorContains(t, err, "invalid cache strategy")
+	})
+}
+
 func TestClient_CacheFallbackResilience(t *testing.T) {
 	t.Parallel()
 	t.Skip("cache fallback not yet needed: Anthropic API currently ignores cache_control on unsupported models")
@@ -593,6 +686,73 @@ func TestClient_HTTPError(t *testing.T) {
 	assert.Contains(t, err.Error(), "max_tokens")
 }
 
+func TestClient_HTTPErrorClassification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		reason pipe.StopReason
+		raw    string
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`, pipe.StopRateLimited, "rate_limit_error"},
+		{"overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, pipe.StopRateLimited, "overloaded_error"},
+		{"bad key", http.StatusUnauthorized, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, pipe.StopAuthFailed, "authentication_error"},
+		{"forbidden without body", http.StatusForbidden, `nope`, pipe.StopAuthFailed, "http_403"},
+		{"invalid request", http.StatusBadRequest, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`, pipe.StopError, "invalid_request_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			client := anthropic.New("test-key", anthropic.WithBaseURL(srv.URL))
+			_, err := client.Stream(context.Background(), pipe.Request{
+				Messages: []pipe.Message{
+					pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Hi"}}},
+				},
+			})
+			var pe *pipe.ProviderError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tt.reason, pe.Reason)
+			assert.Equal(t, tt.raw, pe.Raw)
+		})
+	}
+}
+
+func TestClient_SkipsEmptyAssistantMessages(t *testing.T) {
+	t.Parallel()
+	var captured []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := anthropic.New("test-key", anthropic.WithBaseURL(srv.URL))
+	_, _ = client.Stream(context.Background(), pipe.Request{
+		Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Hi"}}},
+			pipe.AssistantMessage{StopReason: pipe.StopRateLimited},
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Again"}}},
+		},
+	})
+
+	var body struct {
+		Messages []struct {
+			Role string `json:"role"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(captured, &body))
+	require.Len(t, body.Messages, 2)
+	assert.Equal(t, "user", body.Messages[1].Role)
+}
+
 func TestClient_HTTPErrorNonJSON(t *testing.T) {
 	t.Parallel()
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -610,3 +770,196 @@ func TestClient_HTTPErrorNonJSON(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "500")
 }
+
+func TestClient_ServerTools(t *testing.T) {
+	t.Parallel()
+
+	minimalSSE := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"content\":[],\"model\":\"m\",\"stop_reason\":null,\"stop_sequence\":null,\"usage\":{\"input_tokens\":0,\"output_tokens\":0}}}\n\nevent: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":0}}\n\nevent: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	t.Run("server tools appended after client tools", func(t *testing.T) {
+		t.Parallel()
+		var captured []byte
+		var beta string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured, _ = io.ReadAll(r.Body)
+			beta = r.Header.Get("Anthropic-Beta")
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(minimalSSE))
+		}))
+		defer srv.Close()
+
+		client := anthropic.New("key", anthropic.WithBaseURL(srv.URL))
+		s, err := client.Stream(context.Background(), pipe.Request{
+			Messages: []pipe.Message{
+				pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Hi"}}},
+			},
+			Tools: []pipe.Tool{
+				{Name: "read", Description: "Read a file", Parameters: json.RawMessage(`{"type":"object"}`)},
+			},
+			ServerTools: []pipe.ServerTool{pipe.ServerToolWebSearch, pipe.ServerToolCodeExecution},
+		})
+		require.NoError(t, err)
+		defer s.Close()
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(captured, &body))
+
+		tools := body["tools"].([]interface{})
+		require.Len(t, tools, 3)
+		assert.Equal(t, "read", tools[0].(map[string]interface{})["name"])
+		search := tools[1].(map[string]interface{})
+		assert.Equal(t, "web_search_20250305", search["type"])
+		assert.Equal(t, "web_search", search["name"])
+		_, hasSchema := search["input_schema"]
+		assert.False(t, hasSchema, "server tools must not carry input_schema")
+		exec := tools[2].(map[string]interface{})
+		assert.Equal(t, "code_execution_20250825", exec["type"])
+		assert.Contains(t, exec, "cache_control", "last tool carries the cache breakpoint")
+
+		assert.Equal(t, "code-execution-2025-08-25", beta)
+	})
+
+	t.Run("no beta header without code execution", func(t *testing.T) {
+		t.Parallel()
+		beta := "unset"
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			beta = r.Header.Get("Anthropic-Beta")
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(minimalSSE))
+		}))
+		defer srv.Close()
+
+		client := anthropic.New("key", anthropic.WithBaseURL(srv.URL))
+		s, err := client.Stream(context.Background(), pipe.Request{
+			Messages: []pipe.Message{
+				pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Hi"}}},
+			},
+			ServerTools: []pipe.ServerTool{pipe.ServerToolWebSearch},
+		})
+		require.NoError(t, err)
+		defer s.Close()
+
+		assert.Empty(t, beta)
+	})
+
+	t.Run("configured betas merged with code execution beta", func(t *testing.T) {
+		t.Parallel()
+		var beta string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			beta = r.Header.Get("Anthropic-Beta")
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(minimalSSE))
+		}))
+		defer srv.Close()
+
+		client := anthropic.New("key", anthropic.WithBaseURL(srv.URL),
+			anthropic.WithBetas(anthropic.BetaFineGrainedToolStreaming, anthropic.BetaInterleavedThinking, "code-execution-2025-08-25"))
+		s, err := client.Stream(context.Background(), pipe.Request{
+			Messages: []pipe.Message{
+				pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Hi"}}},
+			},
+			ServerTools: []pipe.ServerTool{pipe.ServerToolCodeExecution},
+		})
+		require.NoError(t, err)
+		defer s.Close()
+
+		assert.Equal(t, "code-execution-2025-08-25,fine-grained-tool-streaming-2025-05-14,interleaved-thinking-2025-05-14", beta)
+	})
+
+	t.Run("server tool blocks replayed in history", func(t *testing.T) {
+		t.Parallel()
+		var captured []byte
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured, _ = io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(minimalSSE))
+		}))
+		defer srv.Close()
+
+		client := anthropic.New("key", anthropic.WithBaseURL(srv.URL))
+		s, err := client.Stream(context.Background(), pipe.Request{
+			Messages: []pipe.Message{
+				pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Search"}}},
+				pipe.AssistantMessage{Content: []pipe.ContentBlock{
+					pipe.ServerToolCallBlock{ID: "srvtoolu_1", Name: "web_search", Arguments: json.RawMessage(`{"query":"go"}`)},
+					pipe.ServerToolResultBlock{ToolCallID: "srvtoolu_1", Name: "web_search", Content: json.RawMessage(`[{"type":"web_search_result","url":"https://go.dev","title":"Go"}]`)},
+					pipe.TextBlock{Text: "Go is a language."},
+				}},
+				pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Thanks"}}},
+			},
+		})
+		require.NoError(t, err)
+		defer s.Close()
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(captured, &body))
+
+		msgs := body["messages"].([]interface{})
+		content := msgs[1].(map[string]interface{})["content"].([]interface{})
+		require.Len(t, content, 3)
+		call := content[0].(map[string]interface{})
+		assert.Equal(t, "server_tool_use", call["type"])
+		assert.Equal(t, "srvtoolu_1", call["id"])
+		assert.Equal(t, map[string]interface{}{"query": "go"}, call["input"])
+		result := content[1].(map[string]interface{})
+		assert.Equal(t, "web_search_tool_result", result["type"])
+		assert.Equal(t, "srvtoolu_1", result["tool_use_id"])
+		results := result["content"].([]interface{})
+		require.Len(t, results, 1)
+		assert.Equal(t, "https://go.dev", results[0].(map[string]interface{})["url"])
+	})
+}
+
+func TestClient_ToolResultFormat(t *testing.T) {
+	t.Parallel()
+
+	minimalSSE := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"content\":[],\"model\":\"m\",\"stop_reason\":null,\"stop_sequence\":null,\"usage\":{\"input_tokens\":0,\"output_tokens\":0}}}\n\nevent: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":0}}\n\nevent: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+	output := "\x1b[32mok\x1b[0m  \n\n\n\nPASS\n"
+
+	// send returns the text of the tool result sent with opts.
+	send := func(t *testing.T, opts ...anthropic.Option) string {
+		t.Helper()
+		var captured []byte
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured, _ = io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(minimalSSE))
+		}))
+		defer srv.Close()
+
+		client := anthropic.New("key", append([]anthropic.Option{anthropic.WithBaseURL(srv.URL)}, opts...)...)
+		s, err := client.Stream(context.Background(), pipe.Request{Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Test it"}}},
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.ToolCallBlock{ID: "call_1", Name: "bash", Arguments: json.RawMessage(`{}`)}}},
+			pipe.ToolResultMessage{ToolCallID: "call_1", ToolName: "bash", Content: []pipe.ContentBlock{pipe.TextBlock{Text: output}}},
+		}})
+		require.NoError(t, err)
+		defer s.Close()
+
+		var body struct {
+			Messages []struct {
+				Content []struct {
+					Content []struct {
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.Unmarshal(captured, &body))
+		return body.Messages[2].Content[0].Content[0].Text
+	}
+
+	t.Run("raw by default", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, output, send(t))
+	})
+	t.Run("compact keeps whitespace", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, "ok  \n\n\n\nPASS\n", send(t, anthropic.WithToolResultFormat(anthropic.CompactToolResults())))
+	})
+}
@@ -0,0 +1,69 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/fwojciec/pipe"
+)
+
+// Interface compliance check.
+var _ pipe.ModelLister = (*Client)(nil)
+
+const modelsPath = "/v1/models"
+
+// modelsPage is a page of the Models API list.
+type modelsPage struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+// ListModels returns the IDs of the models the API key can use, newest
+// first.
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
+	q := url.Values{}
+	q.Set("limit", "1000")
+	var ids []string
+	for {
+		page, err := c.modelsPage(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range page.Data {
+			ids = append(ids, m.ID)
+		}
+		if !page.HasMore || page.LastID == "" {
+			return ids, nil
+		}
+		q.Set("after_id", page.LastID)
+	}
+}
+
+func (c *Client) modelsPage(ctx context.Context, q url.Values) (modelsPage, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+modelsPath+"?"+q.Encode(), nil)
+	if err != nil {
+		return modelsPage{}, fmt.Errorf("anthropic: list models: %w", err)
+	}
+	httpReq.Header.Set("X-Api-Key", c.apiKey)
+	httpReq.Header.Set("Anthropic-Version", apiVersion)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return modelsPage{}, fmt.Errorf("anthropic: list models: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return modelsPage{}, parseHTTPError(resp)
+	}
+	var page modelsPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return modelsPage{}, fmt.Errorf("anthropic: list models: %w", err)
+	}
+	return page, nil
+}
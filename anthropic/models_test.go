@@ -0,0 +1,56 @@
+package anthropic_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/anthropic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ListModels(t *testing.T) {
+	t.Parallel()
+
+	var after []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/v1/models", r.URL.Path)
+		assert.Equal(t, "sk-ant-api", r.Header.Get("X-Api-Key"))
+		assert.Equal(t, "2023-06-01", r.Header.Get("Anthropic-Version"))
+		after = append(after, r.URL.Query().Get("after_id"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("after_id") == "" {
+			_, _ = w.Write([]byte(`{"data":[{"type":"model","id":"claude-opus-4-1"},{"type":"model","id":"claude-sonnet-4-5"}],"has_more":true,"first_id":"claude-opus-4-1","last_id":"claude-sonnet-4-5"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"type":"model","id":"claude-3-5-haiku-latest"}],"has_more":false,"first_id":"claude-3-5-haiku-latest","last_id":"claude-3-5-haiku-latest"}`))
+	}))
+	defer srv.Close()
+
+	client := anthropic.New("sk-ant-api", anthropic.WithBaseURL(srv.URL))
+	got, err := client.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"claude-opus-4-1", "claude-sonnet-4-5", "claude-3-5-haiku-latest"}, got)
+	assert.Equal(t, []string{"", "claude-sonnet-4-5"}, after)
+}
+
+func TestClient_ListModelsHTTPError(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer srv.Close()
+
+	client := anthropic.New("bad-key", anthropic.WithBaseURL(srv.URL))
+	_, err := client.ListModels(context.Background())
+	var pe *pipe.ProviderError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, pipe.StopAuthFailed, pe.Reason)
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -31,6 +32,7 @@ type blockState struct {
 	textBuf      strings.Builder
 	thinkingBuf  strings.Builder
 	signatureBuf strings.Builder
+	citations    []pipe.Citation
 }
 
 // signature returns the accumulated signature as a byte slice, or nil if empty.
@@ -41,6 +43,21 @@ func (bs *blockState) signature() []byte {
 	return []byte(bs.signatureBuf.String())
 }
 
+// arguments returns the accumulated tool input. Empty input becomes {}; input
+// that is not valid JSON, which fine-grained tool streaming can produce, is
+// wrapped under invalidJSONKey so the message stays replayable.
+func (bs *blockState) arguments() json.RawMessage {
+	raw := bs.inputBuf.String()
+	if raw == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(raw)) {
+		return json.RawMessage(raw)
+	}
+	wrapped, _ := json.Marshal(map[string]string{invalidJSONKey: raw})
+	return wrapped
+}
+
 // Interface compliance check.
 var _ pipe.Stream = (*stream)(nil)
 
@@ -122,16 +139,20 @@ func (s *stream) terminate(err error) {
 		// Normal completion via message_stop should set StreamStateComplete
 		// before we reach here. If we get raw EOF, the stream ended unexpectedly.
 		s.state = pipe.StreamStateError
-		s.err = fmt.Errorf("anthropic: unexpected end of stream")
+		s.err = fmt.Errorf("anthropic: end of stream before message_stop: %w", io.ErrUnexpectedEOF)
 		s.msg.StopReason = pipe.StopError
 		s.msg.RawStopReason = "error"
 		return
 	}
 	s.state = pipe.StreamStateError
 	s.err = err
+	var pe *pipe.ProviderError
 	if s.ctx.Err() != nil {
 		s.msg.StopReason = pipe.StopAborted
 		s.msg.RawStopReason = "aborted"
+	} else if errors.As(err, &pe) {
+		s.msg.StopReason = pe.Reason
+		s.msg.RawStopReason = pe.Raw
 	} else {
 		s.msg.StopReason = pipe.StopError
 		s.msg.RawStopReason = "error"
@@ -240,6 +261,12 @@ func (s *stream) handleContentBlockStart(data string) (pipe.Event, error) {
 		bs.toolName = evt.ContentBlock.Name
 		s.msg.Content[evt.Index] = pipe.ToolCallBlock{ID: bs.toolID, Name: bs.toolName}
 		return pipe.EventToolCallBegin{ID: evt.ContentBlock.ID, Name: evt.ContentBlock.Name}, nil
+	case "server_tool_use":
+		// Arguments stream as input_json_delta; the event fires on stop.
+		bs.toolID = evt.ContentBlock.ID
+		bs.toolName = evt.ContentBlock.Name
+		s.msg.Content[evt.Index] = pipe.ServerToolCallBlock{ID: bs.toolID, Name: bs.toolName}
+		return nil, nil
 	case "text":
 		// No semantic event for text block start.
 		return nil, nil
@@ -247,10 +274,34 @@ func (s *stream) handleContentBlockStart(data string) (pipe.Event, error) {
 		// No semantic event for thinking block start.
 		return nil, nil
 	default:
+		// Server tool results (web_search_tool_result, ...) arrive complete
+		// in the start event.
+		if name, ok := strings.CutSuffix(evt.ContentBlock.Type, serverToolResultSuffix); ok {
+			result := pipe.ServerToolResultBlock{
+				ToolCallID: evt.ContentBlock.ToolUseID,
+				Name:       name,
+				Content:    evt.ContentBlock.Content,
+				IsError:    isServerToolError(evt.ContentBlock.Content),
+			}
+			s.msg.Content[evt.Index] = result
+			return pipe.EventServerToolResult{Result: result}, nil
+		}
 		return nil, nil
 	}
 }
 
+// isServerToolError reports whether a server tool result payload is an error
+// object, e.g. {"type":"web_search_tool_result_error","error_code":"..."}.
+func isServerToolError(content json.RawMessage) bool {
+	var payload struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(content, &payload); err != nil {
+		return false
+	}
+	return strings.HasSuffix(payload.Type, "_error")
+}
+
 func (s *stream) handleContentBlockDelta(data string) (pipe.Event, error) {
 	var evt sseContentBlockDelta
 	if err := json.Unmarshal([]byte(data), &evt); err != nil {
@@ -265,10 +316,28 @@ func (s *stream) handleContentBlockDelta(data string) (pipe.Event, error) {
 	switch evt.Delta.Type {
 	case "text_delta":
 		bs.textBuf.WriteString(evt.Delta.Text)
-		s.msg.Content[evt.Index] = pipe.TextBlock{Text: bs.textBuf.String()}
+		s.msg.Content[evt.Index] = pipe.TextBlock{Text: bs.textBuf.String(), Citations: bs.citations}
 		return pipe.EventTextDelta{Index: evt.Index, Delta: evt.Delta.Text}, nil
+	case "citations_delta":
+		if evt.Delta.Citation == nil {
+			return nil, nil
+		}
+		c := pipe.Citation{
+			URL:       evt.Delta.Citation.URL,
+			Title:     evt.Delta.Citation.Title,
+			CitedText: evt.Delta.Citation.CitedText,
+		}
+		if c.Title == "" {
+			c.Title = evt.Delta.Citation.DocumentTitle
+		}
+		bs.citations = append(bs.citations, c)
+		s.msg.Content[evt.Index] = pipe.TextBlock{Text: bs.textBuf.String(), Citations: bs.citations}
+		return pipe.EventCitation{Index: evt.Index, Citation: c}, nil
 	case "input_json_delta":
 		bs.inputBuf.WriteString(evt.Delta.PartialJSON)
+		if bs.blockType == "server_tool_use" {
+			return nil, nil
+		}
 		return pipe.EventToolCallDelta{ID: bs.toolID, Delta: evt.Delta.PartialJSON}, nil
 	case "thinking_delta":
 		bs.thinkingBuf.WriteString(evt.Delta.Thinking)
@@ -303,17 +372,21 @@ func (s *stream) handleContentBlockStop(data string) (pipe.Event, error) {
 
 	switch bs.blockType {
 	case "tool_use":
-		raw := bs.inputBuf.String()
-		if raw == "" {
-			raw = "{}"
-		}
 		call := pipe.ToolCallBlock{
 			ID:        bs.toolID,
 			Name:      bs.toolName,
-			Arguments: json.RawMessage(raw),
+			Arguments: bs.arguments(),
 		}
 		s.msg.Content[evt.Index] = call
 		return pipe.EventToolCallEnd{Call: call}, nil
+	case "server_tool_use":
+		call := pipe.ServerToolCallBlock{
+			ID:        bs.toolID,
+			Name:      bs.toolName,
+			Arguments: bs.arguments(),
+		}
+		s.msg.Content[evt.Index] = call
+		return pipe.EventServerToolCall{Call: call}, nil
 	default:
 		return nil, nil
 	}
@@ -349,7 +422,7 @@ func (s *stream) handleError(data string) error {
 	if err := json.Unmarshal([]byte(data), &evt); err != nil {
 		return fmt.Errorf("anthropic: failed to parse error event: %w", err)
 	}
-	return fmt.Errorf("anthropic: %s: %s", evt.Error.Type, evt.Error.Message)
+	return providerError(0, evt.Error.Type, fmt.Errorf("anthropic: %s: %s", evt.Error.Type, evt.Error.Message))
 }
 
 func mapStopReason(raw string) pipe.StopReason {
@@ -360,6 +433,8 @@ func mapStopReason(raw string) pipe.StopReason {
 		return pipe.StopLength
 	case "tool_use":
 		return pipe.StopToolUse
+	case "refusal":
+		return pipe.StopContentFiltered
 	default:
 		return pipe.StopUnknown
 	}
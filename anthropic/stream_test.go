@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -269,6 +270,28 @@ func TestStream_SSEError(t *testing.T) {
 	_, err := s.Next()
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "overloaded_error")
+
+	msg, err := s.Message()
+	require.NoError(t, err)
+	assert.Equal(t, pipe.StopRateLimited, msg.StopReason)
+	assert.Equal(t, "overloaded_error", msg.RawStopReason)
+}
+
+func TestStream_RefusalStopReason(t *testing.T) {
+	t.Parallel()
+	resp := sseResponse{events: []sseEvent{
+		{"message_start", `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"m","stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":1}}}`},
+		{"message_delta", `{"type":"message_delta","delta":{"stop_reason":"refusal","stop_sequence":null},"usage":{"output_tokens":1}}`},
+		{"message_stop", `{"type":"message_stop"}`},
+	}}
+
+	s := streamFromSSE(t, resp)
+	collectEvents(t, s)
+
+	msg, err := s.Message()
+	require.NoError(t, err)
+	assert.Equal(t, pipe.StopContentFiltered, msg.StopReason)
+	assert.Equal(t, "refusal", msg.RawStopReason)
 }
 
 func TestStream_ContextCancellation(t *testing.T) {
@@ -595,9 +618,10 @@ func TestStream_ReadErrorMidStream(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, pipe.EventTextDelta{Index: 0, Delta: "partial"}, evt)
 
-	// Next should return an error (unexpected EOF or read error).
+	// Next should return an unexpected EOF, which the loop retries.
 	_, err = s.Next()
-	assert.Error(t, err)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.True(t, pipe.Retryable(err))
 	assert.Equal(t, pipe.StreamStateError, s.State())
 
 	// Message should have partial content with StopError.
@@ -607,3 +631,174 @@ func TestStream_ReadErrorMidStream(t *testing.T) {
 	require.Len(t, msg.Content, 1)
 	assert.Equal(t, pipe.TextBlock{Text: "partial"}, msg.Content[0])
 }
+
+func TestStream_TruncatedIsRetryable(t *testing.T) {
+	t.Parallel()
+	// The response ends cleanly, but before message_stop.
+	full := textStreamResponse()
+	s := streamFromSSE(t, sseResponse{events: full.events[:4]})
+
+	evt, err := s.Next()
+	require.NoError(t, err)
+	assert.Equal(t, pipe.EventTextDelta{Index: 0, Delta: "Hello"}, evt)
+
+	_, err = s.Next()
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.True(t, pipe.Retryable(err))
+	msg, err := s.Message()
+	require.NoError(t, err)
+	assert.Equal(t, pipe.StopError, msg.StopReason)
+}
+
+func TestStream_ServerToolUse(t *testing.T) {
+	t.Parallel()
+	resp := sseResponse{events: []sseEvent{
+		{"message_start", `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-sonnet-4-20250514","stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":1}}}`},
+		{"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"server_tool_use","id":"srvtoolu_1","name":"web_search","input":{}}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"query\": \"go\"}"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":0}`},
+		{"content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":[{"type":"web_search_result","url":"https://go.dev","title":"Go"}]}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":1}`},
+		{"content_block_start", `{"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"Go is a language."}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":2}`},
+		{"message_delta", `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":42}}`},
+		{"message_stop", `{"type":"message_stop"}`},
+	}}
+
+	s := streamFromSSE(t, resp)
+	events := collectEvents(t, s)
+
+	call := pipe.ServerToolCallBlock{ID: "srvtoolu_1", Name: "web_search", Arguments: json.RawMessage(`{"query": "go"}`)}
+	result := pipe.ServerToolResultBlock{
+		ToolCallID: "srvtoolu_1",
+		Name:       "web_search",
+		Content:    json.RawMessage(`[{"type":"web_search_result","url":"https://go.dev","title":"Go"}]`),
+	}
+	require.Len(t, events, 3)
+	assert.Equal(t, pipe.EventServerToolCall{Call: call}, events[0])
+	assert.Equal(t, pipe.EventServerToolResult{Result: result}, events[1])
+	assert.Equal(t, pipe.EventTextDelta{Index: 2, Delta: "Go is a language."}, events[2])
+
+	msg, err := s.Message()
+	require.NoError(t, err)
+	assert.Equal(t, pipe.StopEndTurn, msg.StopReason)
+	require.Len(t, msg.Content, 3)
+	assert.Equal(t, call, msg.Content[0])
+	assert.Equal(t, result, msg.Content[1])
+	assert.Equal(t, pipe.TextBlock{Text: "Go is a language."}, msg.Content[2])
+}
+
+func TestStream_ServerToolResultError(t *testing.T) {
+	t.Parallel()
+	resp := sseResponse{events: []sseEvent{
+		{"message_start", `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-sonnet-4-20250514","stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":1}}}`},
+		{"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"web_search_tool_result","tool_use_id":"srvtoolu_1","content":{"type":"web_search_tool_result_error","error_code":"max_uses_exceeded"}}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":0}`},
+		{"message_delta", `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":1}}`},
+		{"message_stop", `{"type":"message_stop"}`},
+	}}
+
+	s := streamFromSSE(t, resp)
+	collectEvents(t, s)
+
+	msg, err := s.Message()
+	require.NoError(t, err)
+	require.Len(t, msg.Content, 1)
+	result, ok := msg.Content[0].(pipe.ServerToolResultBlock)
+	require.True(t, ok)
+	assert.True(t, result.IsError)
+}
+
+func TestStream_CitationsDelta(t *testing.T) {
+	t.Parallel()
+	resp := sseResponse{events: []sseEvent{
+		{"message_start", `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-sonnet-4-20250514","stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":100,"output_tokens":1}}}`},
+		{"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"citations_delta","citation":{"type":"web_search_result_location","url":"https://go.dev/doc","title":"Go Docs","cited_text":"Go is an open source language."}}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Go is open source."}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"citations_delta","citation":{"type":"char_location","document_title":"README","cited_text":"MIT"}}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":0}`},
+		{"message_delta", `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":5}}`},
+		{"message_stop", `{"type":"message_stop"}`},
+	}}
+
+	s := streamFromSSE(t, resp)
+	events := collectEvents(t, s)
+
+	require.Len(t, events, 3)
+	assert.Equal(t, pipe.EventCitation{Index: 0, Citation: pipe.Citation{
+		URL:       "https://go.dev/doc",
+		Title:     "Go Docs",
+		CitedText: "Go is an open source language.",
+	}}, events[0])
+	assert.Equal(t, pipe.EventTextDelta{Index: 0, Delta: "Go is open source."}, events[1])
+	assert.Equal(t, pipe.EventCitation{Index: 0, Citation: pipe.Citation{Title: "README", CitedText: "MIT"}}, events[2])
+
+	msg, err := s.Message()
+	require.NoError(t, err)
+	require.Len(t, msg.Content, 1)
+	text, ok := msg.Content[0].(pipe.TextBlock)
+	require.True(t, ok)
+	assert.Equal(t, "Go is open source.", text.Text)
+	require.Len(t, text.Citations, 2)
+	assert.Equal(t, "https://go.dev/doc", text.Citations[0].URL)
+	assert.Equal(t, "README", text.Citations[1].Title)
+}
+
+func TestStream_FineGrainedToolStreamingInvalidJSON(t *testing.T) {
+	t.Parallel()
+	// With fine-grained tool streaming, max_tokens can cut tool input short.
+	resp := sseResponse{events: []sseEvent{
+		{"message_start", `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"m","stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":1}}}`},
+		{"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"write","input":{}}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"content\": \"unterminated"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":0}`},
+		{"message_delta", `{"type":"message_delta","delta":{"stop_reason":"max_tokens","stop_sequence":null},"usage":{"output_tokens":20}}`},
+		{"message_stop", `{"type":"message_stop"}`},
+	}}
+
+	s := streamFromSSE(t, resp)
+	events := collectEvents(t, s)
+
+	require.Len(t, events, 3)
+	end, ok := events[2].(pipe.EventToolCallEnd)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"INVALID_JSON": "{\"content\": \"unterminated"}`, string(end.Call.Arguments))
+
+	msg, err := s.Message()
+	require.NoError(t, err)
+	assert.Equal(t, pipe.StopLength, msg.StopReason)
+	require.Len(t, msg.Content, 1)
+	assert.Equal(t, end.Call, msg.Content[0])
+}
+
+func TestStream_InterleavedThinking(t *testing.T) {
+	t.Parallel()
+	// With interleaved thinking, thinking blocks may follow tool_use blocks.
+	resp := sseResponse{events: []sseEvent{
+		{"message_start", `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"m","stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":1}}}`},
+		{"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"read","input":{}}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"path\":\"a.go\"}"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":0}`},
+		{"content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"thinking","thinking":""}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"thinking_delta","thinking":"Also need b.go."}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"signature_delta","signature":"sig"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":1}`},
+		{"content_block_start", `{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_2","name":"read","input":{}}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"path\":\"b.go\"}"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":2}`},
+		{"message_delta", `{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":30}}`},
+		{"message_stop", `{"type":"message_stop"}`},
+	}}
+
+	s := streamFromSSE(t, resp)
+	collectEvents(t, s)
+
+	msg, err := s.Message()
+	require.NoError(t, err)
+	require.Len(t, msg.Content, 3)
+	assert.Equal(t, pipe.ToolCallBlock{ID: "toolu_1", Name: "read", Arguments: json.RawMessage(`{"path":"a.go"}`)}, msg.Content[0])
+	assert.Equal(t, pipe.ThinkingBlock{Thinking: "Also need b.go.", Signature: []byte("sig")}, msg.Content[1])
+	assert.Equal(t, pipe.ToolCallBlock{ID: "toolu_2", Name: "read", Arguments: json.RawMessage(`{"path":"b.go"}`)}, msg.Content[2])
+}
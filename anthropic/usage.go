@@ -0,0 +1,91 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// Interface compliance check.
+var _ pipe.UsageReporter = (*Client)(nil)
+
+const usageReportPath = "/v1/organizations/usage_report/messages"
+
+// usageReport is a page of the Admin API messages usage report.
+type usageReport struct {
+	Data []struct {
+		Results []struct {
+			UncachedInputTokens  int `json:"uncached_input_tokens"`
+			CacheReadInputTokens int `json:"cache_read_input_tokens"`
+			CacheCreation        struct {
+				Ephemeral5mInputTokens int `json:"ephemeral_5m_input_tokens"`
+				Ephemeral1hInputTokens int `json:"ephemeral_1h_input_tokens"`
+			} `json:"cache_creation"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"results"`
+	} `json:"data"`
+	HasMore  bool   `json:"has_more"`
+	NextPage string `json:"next_page"`
+}
+
+// ReportedUsage returns the organization's token usage between start and
+// end from the Admin API usage report. The client must be created with an
+// Admin API key (sk-ant-admin...), not a regular API key. The report
+// counts usage in hourly buckets, so start and end are widened to whole
+// hours, and it covers every key in the organization.
+func (c *Client) ReportedUsage(ctx context.Context, start, end time.Time) (pipe.Usage, error) {
+	q := url.Values{}
+	q.Set("starting_at", start.UTC().Truncate(time.Hour).Format(time.RFC3339))
+	q.Set("ending_at", end.UTC().Add(time.Hour-1).Truncate(time.Hour).Format(time.RFC3339))
+	q.Set("bucket_width", "1h")
+
+	var total pipe.Usage
+	for {
+		report, err := c.usageReportPage(ctx, q)
+		if err != nil {
+			return pipe.Usage{}, err
+		}
+		for _, bucket := range report.Data {
+			for _, r := range bucket.Results {
+				total = total.Add(pipe.Usage{
+					InputTokens:      r.UncachedInputTokens,
+					OutputTokens:     r.OutputTokens,
+					CacheReadTokens:  r.CacheReadInputTokens,
+					CacheWriteTokens: r.CacheCreation.Ephemeral5mInputTokens + r.CacheCreation.Ephemeral1hInputTokens,
+				})
+			}
+		}
+		if !report.HasMore || report.NextPage == "" {
+			return total, nil
+		}
+		q.Set("page", report.NextPage)
+	}
+}
+
+func (c *Client) usageReportPage(ctx context.Context, q url.Values) (usageReport, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+usageReportPath+"?"+q.Encode(), nil)
+	if err != nil {
+		return usageReport{}, fmt.Errorf("anthropic: usage report: %w", err)
+	}
+	httpReq.Header.Set("X-Api-Key", c.apiKey)
+	httpReq.Header.Set("Anthropic-Version", apiVersion)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return usageReport{}, fmt.Errorf("anthropic: usage report: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return usageReport{}, parseHTTPError(resp)
+	}
+	var report usageReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return usageReport{}, fmt.Errorf("anthropic: usage report: %w", err)
+	}
+	return report, nil
+}
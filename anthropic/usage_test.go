@@ -0,0 +1,65 @@
+package anthropic_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/anthropic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ReportedUsage(t *testing.T) {
+	t.Parallel()
+
+	var pages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/v1/organizations/usage_report/messages", r.URL.Path)
+		assert.Equal(t, "admin-key", r.Header.Get("X-Api-Key"))
+		assert.Equal(t, "2023-06-01", r.Header.Get("Anthropic-Version"))
+		q := r.URL.Query()
+		assert.Equal(t, "2026-03-31T10:00:00Z", q.Get("starting_at"))
+		assert.Equal(t, "2026-03-31T13:00:00Z", q.Get("ending_at"))
+		assert.Equal(t, "1h", q.Get("bucket_width"))
+		pages = append(pages, q.Get("page"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if q.Get("page") == "" {
+			_, _ = w.Write([]byte(`{"data":[{"starting_at":"2026-03-31T10:00:00Z","results":[
+				{"uncached_input_tokens":100,"cache_read_input_tokens":40,"cache_creation":{"ephemeral_5m_input_tokens":20,"ephemeral_1h_input_tokens":5},"output_tokens":7},
+				{"uncached_input_tokens":1,"output_tokens":1}
+			]}],"has_more":true,"next_page":"p2"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"starting_at":"2026-03-31T11:00:00Z","results":[{"uncached_input_tokens":10,"output_tokens":3}]}],"has_more":false,"next_page":null}`))
+	}))
+	defer srv.Close()
+
+	client := anthropic.New("admin-key", anthropic.WithBaseURL(srv.URL))
+	start := time.Date(2026, 3, 31, 10, 15, 0, 0, time.UTC)
+	got, err := client.ReportedUsage(context.Background(), start, start.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, pipe.Usage{InputTokens: 111, OutputTokens: 11, CacheReadTokens: 40, CacheWriteTokens: 25}, got)
+	assert.Equal(t, []string{"", "p2"}, pages)
+}
+
+func TestClient_ReportedUsageHTTPError(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer srv.Close()
+
+	client := anthropic.New("sk-ant-api", anthropic.WithBaseURL(srv.URL))
+	_, err := client.ReportedUsage(context.Background(), time.Now().Add(-time.Hour), time.Now())
+	var pe *pipe.ProviderError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, pipe.StopAuthFailed, pe.Reason)
+}
@@ -0,0 +1,87 @@
+package pipe_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// The API embedders build on, pinned so that a change that would break
+// them fails to compile here. Do not edit a declaration to make it
+// compile: keep the old identifier as a deprecated shim instead (see the
+// package documentation).
+var (
+	_ func(pipe.Provider, pipe.ToolExecutor) *pipe.Loop                                      = pipe.NewLoop
+	_ func(*pipe.Loop, context.Context, *pipe.Session, []pipe.Tool, ...pipe.RunOption) error = (*pipe.Loop).Run
+	_ func(pipe.Clock, string) pipe.Session                                                  = pipe.NewSession
+	_ func() pipe.Clock                                                                      = pipe.SystemClock
+	_ func(pipe.Session) pipe.Usage                                                          = pipe.Session.TotalUsage
+
+	_ func(func(pipe.Event)) pipe.RunOption                                                 = pipe.WithEventHandler
+	_ func(func(pipe.Message)) pipe.RunOption                                               = pipe.WithMessageHandler
+	_ func(string) pipe.RunOption                                                           = pipe.WithModel
+	_ func(...pipe.ServerTool) pipe.RunOption                                               = pipe.WithServerTools
+	_ func(int) pipe.RunOption                                                              = pipe.WithMaxTokens
+	_ func(float64) pipe.RunOption                                                          = pipe.WithTemperature
+	_ func(pipe.ProviderOptions) pipe.RunOption                                             = pipe.WithProviderOptions
+	_ func(int) pipe.RunOption                                                              = pipe.WithContextBudget
+	_ func(int) pipe.RunOption                                                              = pipe.WithToolConcurrency
+	_ func(time.Duration) pipe.RunOption                                                    = pipe.WithToolTimeout
+	_ func(pipe.RetryPolicy) pipe.RunOption                                                 = pipe.WithRetry
+	_ func(pipe.Clock) pipe.RunOption                                                       = pipe.WithClock
+	_ func(pipe.EventSink) pipe.RunOption                                                   = pipe.WithEventTee
+	_ func(pipe.TranscriptSink) pipe.RunOption                                              = pipe.WithTranscript
+	_ func(func(context.Context, pipe.ToolCallBlock) (pipe.Decision, error)) pipe.RunOption = pipe.WithApprover
+	_ func(func(context.Context, pipe.Request) (pipe.Decision, error)) pipe.RunOption       = pipe.WithRequestApprover
+	_ func(pipe.SpeechSink) pipe.RunOption                                                  = pipe.WithSpeechSink
+	_ func(int) pipe.RunOption                                                              = pipe.WithToolResultTurns
+	_ func() pipe.RunOption                                                                 = pipe.WithRunStatus
+	_ func(int) pipe.RunOption                                                              = pipe.WithMaxTurns
+	_ func(int) pipe.RunOption                                                              = pipe.WithMaxCostTokens
+	_ func(int) pipe.RunOption                                                              = pipe.WithLoopDetection
+	_ func(msgs []pipe.Message, tools []pipe.Tool) []pipe.Tool                              = pipe.UnavailableTools
+)
+
+// Embedders implement these interfaces, so they may not gain methods:
+// each is assigned a value of exactly its current method set.
+var (
+	_ pipe.Provider = interface {
+		Stream(context.Context, pipe.Request) (pipe.Stream, error)
+	}(nil)
+	_ pipe.ToolExecutor = interface {
+		Execute(context.Context, string, json.RawMessage) (*pipe.ToolResult, error)
+	}(nil)
+	_ pipe.Stream = interface {
+		Next() (pipe.Event, error)
+		State() pipe.StreamState
+		Message() (pipe.AssistantMessage, error)
+		Close() error
+	}(nil)
+	_ pipe.Clock = interface {
+		Now() time.Time
+		After(time.Duration) <-chan time.Time
+	}(nil)
+	_ pipe.EventSink = interface {
+		Write(pipe.Event) error
+	}(nil)
+	_ pipe.TranscriptSink = interface {
+		WriteRecord(pipe.TranscriptRecord) error
+	}(nil)
+	_ pipe.Embedder = interface {
+		Embed(context.Context, []string) ([][]float32, error)
+	}(nil)
+	_ pipe.UsageReporter = interface {
+		ReportedUsage(context.Context, time.Time, time.Time) (pipe.Usage, error)
+	}(nil)
+	_ pipe.ModelLister = interface {
+		ListModels(context.Context) ([]string, error)
+	}(nil)
+)
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+	// The declarations above are the test: it passes if they compile.
+}
@@ -0,0 +1,52 @@
+package pipe
+
+import "context"
+
+// Decision is the outcome of asking whether a tool call may run.
+type Decision string
+
+// Decision values.
+const (
+	DecisionAllow       Decision = "allow"
+	DecisionDeny        Decision = "deny"
+	DecisionAlwaysAllow Decision = "always_allow" // allow this and later calls to the same tool
+)
+
+// deniedMessage is the tool result the model sees for a denied call.
+const deniedMessage = "The user denied this tool call. Do not retry it; ask the user how to proceed if unsure."
+
+// WithApprover asks approve before each tool call is executed. A denied
+// call is not executed; the model receives an error result saying so.
+// DecisionAlwaysAllow approves later calls to the same tool for the rest
+// of the run without asking. An error from approve, such as the context
+// being cancelled while waiting for the user, becomes the call's error
+// result. If not set, every call is executed.
+func WithApprover(approve func(ctx context.Context, call ToolCallBlock) (Decision, error)) RunOption {
+	return func(c *runConfig) {
+		c.approve = approve
+	}
+}
+
+// checkApproval asks cfg's approver whether tc may run, remembering tools that
+// were always allowed. It returns the error result to record instead of
+// running tc, or nil to run it.
+func checkApproval(ctx context.Context, cfg *runConfig, tc ToolCallBlock) *ToolResult {
+	if cfg.approve == nil || cfg.alwaysAllowed[tc.Name] {
+		return nil
+	}
+	decision, err := cfg.approve(ctx, tc)
+	switch {
+	case err != nil:
+		return &ToolResult{Content: []ContentBlock{TextBlock{Text: err.Error()}}, IsError: true}
+	case decision == DecisionAllow:
+		return nil
+	case decision == DecisionAlwaysAllow:
+		if cfg.alwaysAllowed == nil {
+			cfg.alwaysAllowed = make(map[string]bool)
+		}
+		cfg.alwaysAllowed[tc.Name] = true
+		return nil
+	default:
+		return &ToolResult{Content: []ContentBlock{TextBlock{Text: deniedMessage}}, IsError: true}
+	}
+}
@@ -0,0 +1,153 @@
+package pipe_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoop_Approver(t *testing.T) {
+	t.Parallel()
+
+	// run answers with one round of calls, then ends the turn; it returns
+	// the executed tool names and the tool results in the session.
+	run := func(t *testing.T, calls []pipe.ToolCallBlock, approve func(context.Context, pipe.ToolCallBlock) (pipe.Decision, error)) ([]string, []pipe.ToolResultMessage, []string) {
+		t.Helper()
+		content := make([]pipe.ContentBlock, len(calls))
+		for i, c := range calls {
+			content[i] = c
+		}
+		turns := 0
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			turns++
+			if turns == 1 {
+				return completedStream(pipe.AssistantMessage{Content: content, StopReason: pipe.StopToolUse}), nil
+			}
+			return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "done"}}, StopReason: pipe.StopEndTurn}), nil
+		}}
+		var executed []string
+		executor := &mock.ToolExecutor{ExecuteFn: func(_ context.Context, name string, _ json.RawMessage) (*pipe.ToolResult, error) {
+			executed = append(executed, name)
+			return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}}}, nil
+		}}
+		var asked []string
+		session := &pipe.Session{}
+		err := pipe.NewLoop(provider, executor).Run(context.Background(), session, nil,
+			pipe.WithApprover(func(ctx context.Context, call pipe.ToolCallBlock) (pipe.Decision, error) {
+				asked = append(asked, call.ID)
+				return approve(ctx, call)
+			}))
+		require.NoError(t, err)
+		var results []pipe.ToolResultMessage
+		for _, m := range session.Messages {
+			if r, ok := m.(pipe.ToolResultMessage); ok {
+				results = append(results, r)
+			}
+		}
+		return executed, results, asked
+	}
+	call := func(id, name string) pipe.ToolCallBlock {
+		return pipe.ToolCallBlock{ID: id, Name: name, Arguments: json.RawMessage(`{}`)}
+	}
+
+	t.Run("denied calls are not executed", func(t *testing.T) {
+		t.Parallel()
+		executed, results, _ := run(t, []pipe.ToolCallBlock{call("c1", "read"), call("c2", "bash")},
+			func(_ context.Context, c pipe.ToolCallBlock) (pipe.Decision, error) {
+				if c.Name == "bash" {
+					return pipe.DecisionDeny, nil
+				}
+				return pipe.DecisionAllow, nil
+			})
+		assert.Equal(t, []string{"read"}, executed)
+		require.Len(t, results, 2)
+		assert.False(t, results[0].IsError)
+		assert.True(t, results[1].IsError)
+		assert.Equal(t, "c2", results[1].ToolCallID)
+		assert.Contains(t, results[1].Content[0].(pipe.TextBlock).Text, "denied")
+	})
+
+	t.Run("always allow skips later approvals of the tool", func(t *testing.T) {
+		t.Parallel()
+		executed, _, asked := run(t, []pipe.ToolCallBlock{call("c1", "bash"), call("c2", "bash"), call("c3", "read")},
+			func(context.Context, pipe.ToolCallBlock) (pipe.Decision, error) {
+				return pipe.DecisionAlwaysAllow, nil
+			})
+		assert.Equal(t, []string{"bash", "bash", "read"}, executed)
+		assert.Equal(t, []string{"c1", "c3"}, asked)
+	})
+
+	t.Run("approver errors become error results", func(t *testing.T) {
+		t.Parallel()
+		executed, results, _ := run(t, []pipe.ToolCallBlock{call("c1", "bash")},
+			func(context.Context, pipe.ToolCallBlock) (pipe.Decision, error) {
+				return "", context.Canceled
+			})
+		assert.Empty(t, executed)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].IsError)
+		assert.Equal(t, "context canceled", results[0].Content[0].(pipe.TextBlock).Text)
+	})
+}
+
+func TestLoop_RequestApprover(t *testing.T) {
+	t.Parallel()
+
+	// provider calls a tool on the first turn and ends on the second.
+	provider := func(requests *int) *mock.Provider {
+		return &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			*requests++
+			if *requests == 1 {
+				return completedStream(pipe.AssistantMessage{
+					Content:    []pipe.ContentBlock{pipe.ToolCallBlock{ID: "c1", Name: "read", Arguments: json.RawMessage(`{}`)}},
+					StopReason: pipe.StopToolUse,
+				}), nil
+			}
+			return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "done"}}, StopReason: pipe.StopEndTurn}), nil
+		}}
+	}
+	executor := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+		return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}}}, nil
+	}}
+
+	t.Run("denied request ends the run unsent", func(t *testing.T) {
+		t.Parallel()
+		var requests int
+		err := pipe.NewLoop(provider(&requests), executor).Run(context.Background(), &pipe.Session{}, nil,
+			pipe.WithRequestApprover(func(context.Context, pipe.Request) (pipe.Decision, error) {
+				return pipe.DecisionDeny, nil
+			}))
+		require.ErrorIs(t, err, pipe.ErrRequestDenied)
+		assert.Zero(t, requests)
+	})
+
+	t.Run("always allow sends the rest of the run without asking", func(t *testing.T) {
+		t.Parallel()
+		var requests, asked int
+		err := pipe.NewLoop(provider(&requests), executor).Run(context.Background(), &pipe.Session{}, nil,
+			pipe.WithRequestApprover(func(context.Context, pipe.Request) (pipe.Decision, error) {
+				asked++
+				return pipe.DecisionAlwaysAllow, nil
+			}))
+		require.NoError(t, err)
+		assert.Equal(t, 2, requests)
+		assert.Equal(t, 1, asked)
+	})
+
+	t.Run("allow asks before each request", func(t *testing.T) {
+		t.Parallel()
+		var requests, asked int
+		err := pipe.NewLoop(provider(&requests), executor).Run(context.Background(), &pipe.Session{}, nil,
+			pipe.WithRequestApprover(func(context.Context, pipe.Request) (pipe.Decision, error) {
+				asked++
+				return pipe.DecisionAllow, nil
+			}))
+		require.NoError(t, err)
+		assert.Equal(t, 2, asked)
+	})
+}
@@ -0,0 +1,114 @@
+package bubbletea
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fwojciec/pipe"
+)
+
+// approvalRequest is a tool call, or with cost set a provider request,
+// waiting for the user's decision.
+type approvalRequest struct {
+	call  pipe.ToolCallBlock
+	cost  *costEstimate
+	reply chan<- pipe.Decision
+}
+
+// costEstimate describes a provider request awaiting confirmation.
+type costEstimate struct {
+	usage   pipe.Usage
+	dollars float64
+}
+
+// approvalMsg delivers a tool call awaiting approval to the model.
+type approvalMsg struct {
+	req approvalRequest
+}
+
+// Approver asks the user through the TUI whether tool calls may run. Pass
+// its Approve method to [pipe.WithApprover] and the Approver to the Model
+// via [Config].
+type Approver struct {
+	requests chan approvalRequest
+
+	mu         sync.Mutex
+	always     map[string]bool // tools the user always allowed
+	alwaysCost bool            // the user allowed every costly request
+}
+
+// NewApprover creates an Approver.
+func NewApprover() *Approver {
+	return &Approver{requests: make(chan approvalRequest), always: make(map[string]bool)}
+}
+
+// Approve shows call to the user and blocks until they allow or deny it,
+// or ctx is cancelled. Tools the user always allowed are approved without
+// asking for as long as the Approver lives, across runs.
+func (a *Approver) Approve(ctx context.Context, call pipe.ToolCallBlock) (pipe.Decision, error) {
+	a.mu.Lock()
+	always := a.always[call.Name]
+	a.mu.Unlock()
+	if always {
+		return pipe.DecisionAllow, nil
+	}
+	d, err := a.ask(ctx, approvalRequest{call: call})
+	if d == pipe.DecisionAlwaysAllow {
+		a.mu.Lock()
+		a.always[call.Name] = true
+		a.mu.Unlock()
+	}
+	return d, err
+}
+
+// ConfirmCost asks the user whether to send a provider request with the
+// estimated usage and cost in US dollars, blocking like Approve. Once the
+// user always allows one, later requests are allowed without asking for as
+// long as the Approver lives.
+func (a *Approver) ConfirmCost(ctx context.Context, usage pipe.Usage, dollars float64) (pipe.Decision, error) {
+	a.mu.Lock()
+	always := a.alwaysCost
+	a.mu.Unlock()
+	if always {
+		return pipe.DecisionAllow, nil
+	}
+	d, err := a.ask(ctx, approvalRequest{cost: &costEstimate{usage: usage, dollars: dollars}})
+	if d == pipe.DecisionAlwaysAllow {
+		a.mu.Lock()
+		a.alwaysCost = true
+		a.mu.Unlock()
+	}
+	return d, err
+}
+
+// ask shows req to the user and waits for their decision.
+func (a *Approver) ask(ctx context.Context, req approvalRequest) (pipe.Decision, error) {
+	reply := make(chan pipe.Decision, 1)
+	req.reply = reply
+	select {
+	case a.requests <- req:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	select {
+	case d := <-reply:
+		return d, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// approvalDecision maps a key pressed while a tool call awaits approval to
+// decisions.
+func approvalDecision(key string) (pipe.Decision, bool) {
+	switch key {
+	case "y":
+		return pipe.DecisionAllow, true
+	case "n":
+		return pipe.DecisionDeny, true
+	case "a":
+		return pipe.DecisionAlwaysAllow, true
+	}
+	return "", false
+}
@@ -0,0 +1,119 @@
+package bubbletea_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type approveOutcome struct {
+	decision pipe.Decision
+	err      error
+}
+
+// approveAsync asks a to approve call in the background.
+func approveAsync(ctx context.Context, a *bt.Approver, call pipe.ToolCallBlock) <-chan approveOutcome {
+	out := make(chan approveOutcome, 1)
+	go func() {
+		d, err := a.Approve(ctx, call)
+		out <- approveOutcome{decision: d, err: err}
+	}()
+	return out
+}
+
+func TestApprover(t *testing.T) {
+	t.Parallel()
+	bash := pipe.ToolCallBlock{ID: "c1", Name: "bash", Arguments: json.RawMessage(`{"command":"rm -rf build"}`)}
+
+	t.Run("shows the call and sends the decision", func(t *testing.T) {
+		t.Parallel()
+		for _, tc := range []struct {
+			key  string
+			want pipe.Decision
+			text string
+		}{
+			{"y", pipe.DecisionAllow, "→ allowed"},
+			{"n", pipe.DecisionDeny, "→ denied"},
+		} {
+			approver := bt.NewApprover()
+			m := initModelWithConfig(t, nopAgent, bt.Config{Approver: approver})
+			m, _ = bt.SetRunning(m)
+
+			out := approveAsync(context.Background(), approver, bash)
+			m = updateModel(t, m, bt.NextApproval(approver))
+			view := m.View()
+			assert.Contains(t, view, "? Run bash?")
+			assert.Contains(t, view, "command: rm -rf build")
+			assert.Contains(t, view, "y allow · n deny · a always allow bash")
+
+			m = typeText(t, m, "x") // ignored
+			m = typeText(t, m, tc.key)
+			got := <-out
+			require.NoError(t, got.err)
+			assert.Equal(t, tc.want, got.decision)
+			assert.Contains(t, m.View(), tc.text)
+			assert.True(t, m.Running())
+		}
+	})
+
+	t.Run("always allow approves later calls without asking", func(t *testing.T) {
+		t.Parallel()
+		approver := bt.NewApprover()
+		m := initModelWithConfig(t, nopAgent, bt.Config{Approver: approver})
+		m, _ = bt.SetRunning(m)
+
+		out := approveAsync(context.Background(), approver, bash)
+		m = updateModel(t, m, bt.NextApproval(approver))
+		_ = updateModel(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+		got := <-out
+		require.NoError(t, got.err)
+		assert.Equal(t, pipe.DecisionAlwaysAllow, got.decision)
+
+		d, err := approver.Approve(context.Background(), pipe.ToolCallBlock{ID: "c2", Name: "bash"})
+		require.NoError(t, err)
+		assert.Equal(t, pipe.DecisionAllow, d)
+	})
+
+	t.Run("confirms a costly request", func(t *testing.T) {
+		t.Parallel()
+		approver := bt.NewApprover()
+		m := initModelWithConfig(t, nopAgent, bt.Config{Approver: approver})
+		m, _ = bt.SetRunning(m)
+
+		out := make(chan approveOutcome, 1)
+		go func() {
+			d, err := approver.ConfirmCost(context.Background(), pipe.Usage{InputTokens: 2000, CacheReadTokens: 150000}, 1.25)
+			out <- approveOutcome{decision: d, err: err}
+		}()
+		m = updateModel(t, m, bt.NextApproval(approver))
+		view := m.View()
+		assert.Contains(t, view, "152.0k input tokens")
+		assert.Contains(t, view, "~$1.25")
+		assert.Contains(t, view, "a always send this session")
+
+		m = typeText(t, m, "a")
+		got := <-out
+		require.NoError(t, got.err)
+		assert.Equal(t, pipe.DecisionAlwaysAllow, got.decision)
+		assert.Contains(t, m.View(), "not asking again")
+
+		d, err := approver.ConfirmCost(context.Background(), pipe.Usage{InputTokens: 1}, 9)
+		require.NoError(t, err)
+		assert.Equal(t, pipe.DecisionAllow, d, "later requests are sent without asking")
+	})
+
+	t.Run("cancelled context unblocks", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		out := approveAsync(ctx, bt.NewApprover(), bash)
+		cancel()
+		got := <-out
+		require.ErrorIs(t, got.err, context.Canceled)
+	})
+}
@@ -0,0 +1,108 @@
+package bubbletea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fwojciec/pipe"
+)
+
+// askUserArgs holds the arguments for the ask_user tool.
+type askUserArgs struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+}
+
+// askRequest is a question waiting for the user's answer.
+type askRequest struct {
+	question string
+	options  []string
+	reply    chan<- string
+}
+
+// questionMsg delivers a question from the ask_user tool to the model.
+type questionMsg struct {
+	req askRequest
+}
+
+// AskUserTool returns the tool definition for ask_user.
+func AskUserTool() pipe.Tool {
+	return pipe.Tool{
+		Name: "ask_user",
+		Description: "Ask the user a clarifying question and wait for their answer. " +
+			"Use this instead of guessing when a requirement is ambiguous. " +
+			"Optionally provide options for a multiple-choice answer.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"question": {
+					"type": "string",
+					"description": "The question to ask the user"
+				},
+				"options": {
+					"type": "array",
+					"items": {"type": "string"},
+					"description": "Optional answer choices"
+				}
+			},
+			"required": ["question"]
+		}`),
+	}
+}
+
+// Asker executes ask_user tool calls by presenting the question in the TUI
+// and blocking until the user answers. Pass the same Asker to the tool
+// executor and to the Model via [Config].
+type Asker struct {
+	requests chan askRequest
+}
+
+// NewAsker creates an Asker.
+func NewAsker() *Asker {
+	return &Asker{requests: make(chan askRequest)}
+}
+
+// Execute presents the question to the user and returns their answer as the
+// tool result. It blocks until the user answers or ctx is cancelled.
+func (a *Asker) Execute(ctx context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
+	var in askUserArgs
+	if err := json.Unmarshal(args, &in); err != nil {
+		return askError(fmt.Sprintf("invalid arguments: %s", err)), nil
+	}
+	if strings.TrimSpace(in.Question) == "" {
+		return askError("question is required"), nil
+	}
+
+	reply := make(chan string, 1)
+	select {
+	case a.requests <- askRequest{question: in.Question, options: in.Options, reply: reply}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case answer := <-reply:
+		return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: answer}}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// resolveAnswer maps a typed answer onto the question's options: a number
+// selects the corresponding option, anything else is returned verbatim.
+func resolveAnswer(input string, options []string) string {
+	if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(options) {
+		return options[n-1]
+	}
+	return input
+}
+
+func askError(msg string) *pipe.ToolResult {
+	return &pipe.ToolResult{
+		Content: []pipe.ContentBlock{pipe.TextBlock{Text: msg}},
+		IsError: true,
+	}
+}
@@ -0,0 +1,110 @@
+package bubbletea_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type askOutcome struct {
+	result *pipe.ToolResult
+	err    error
+}
+
+// askAsync runs the ask_user tool in the background.
+func askAsync(ctx context.Context, a *bt.Asker, args string) <-chan askOutcome {
+	out := make(chan askOutcome, 1)
+	go func() {
+		result, err := a.Execute(ctx, json.RawMessage(args))
+		out <- askOutcome{result: result, err: err}
+	}()
+	return out
+}
+
+// typeText sends each rune of s as a key press.
+func typeText(t *testing.T, m bt.Model, s string) bt.Model {
+	t.Helper()
+	return updateModel(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+}
+
+func TestAsker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("option number selects option", func(t *testing.T) {
+		t.Parallel()
+		asker := bt.NewAsker()
+		m := initModelWithConfig(t, nopAgent, bt.Config{Asker: asker})
+		m, _ = bt.SetRunning(m)
+
+		out := askAsync(context.Background(), asker, `{"question":"Which database?","options":["postgres","sqlite"]}`)
+		m = updateModel(t, m, bt.NextQuestion(asker))
+		view := m.View()
+		assert.Contains(t, view, "? Which database?")
+		assert.Contains(t, view, "1. postgres")
+		assert.Contains(t, view, "2. sqlite")
+
+		m = typeText(t, m, "2")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+		got := <-out
+		require.NoError(t, got.err)
+		assert.False(t, got.result.IsError)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "sqlite"}}, got.result.Content)
+		assert.Contains(t, m.View(), "→ sqlite")
+		assert.True(t, m.Running(), "agent keeps running after the answer")
+	})
+
+	t.Run("free text answer", func(t *testing.T) {
+		t.Parallel()
+		asker := bt.NewAsker()
+		m := initModelWithConfig(t, nopAgent, bt.Config{Asker: asker})
+		m, _ = bt.SetRunning(m)
+
+		out := askAsync(context.Background(), asker, `{"question":"Name?","options":["a"]}`)
+		m = updateModel(t, m, bt.NextQuestion(asker))
+		m = typeText(t, m, "neither")
+		_ = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+		got := <-out
+		require.NoError(t, got.err)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "neither"}}, got.result.Content)
+	})
+
+	t.Run("cancelled context unblocks", func(t *testing.T) {
+		t.Parallel()
+		asker := bt.NewAsker()
+		ctx, cancel := context.WithCancel(context.Background())
+		out := askAsync(ctx, asker, `{"question":"Still there?"}`)
+		cancel()
+
+		got := <-out
+		require.ErrorIs(t, got.err, context.Canceled)
+	})
+
+	t.Run("missing question is a tool error", func(t *testing.T) {
+		t.Parallel()
+		result, err := bt.NewAsker().Execute(context.Background(), json.RawMessage(`{"options":["a"]}`))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+
+	t.Run("invalid arguments are a tool error", func(t *testing.T) {
+		t.Parallel()
+		result, err := bt.NewAsker().Execute(context.Background(), json.RawMessage(`{`))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
+
+func TestAskUserTool(t *testing.T) {
+	t.Parallel()
+	tool := bt.AskUserTool()
+	assert.Equal(t, "ask_user", tool.Name)
+	assert.True(t, json.Valid(tool.Parameters))
+}
@@ -0,0 +1,58 @@
+package bubbletea
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+)
+
+// pastedImageMsg carries the result of Config.PasteImage.
+type pastedImageMsg struct {
+	image pipe.ImageBlock
+	err   error
+}
+
+// pasteImage reads an image from the clipboard in the background.
+func pasteImage(paste func() (pipe.ImageBlock, error)) tea.Cmd {
+	return func() tea.Msg {
+		img, err := paste()
+		return pastedImageMsg{image: img, err: err}
+	}
+}
+
+// attach adds img to the images sent with the next prompt.
+func (m Model) attach(img pipe.ImageBlock) Model {
+	m.attachments = append(m.attachments, img)
+	m.Viewport.Height = m.viewportHeight(m.Input.Height())
+	return m
+}
+
+// detachLast removes the most recently attached image.
+func (m Model) detachLast() Model {
+	m.attachments = m.attachments[:len(m.attachments)-1]
+	m.Viewport.Height = m.viewportHeight(m.Input.Height())
+	return m
+}
+
+// attachmentHeight is the number of lines the attachment chips take above
+// the input.
+func (m Model) attachmentHeight() int {
+	if len(m.attachments) == 0 || m.compact() {
+		return 0
+	}
+	return 1
+}
+
+// attachmentView renders a chip for each image attached to the next
+// prompt.
+func (m Model) attachmentView() string {
+	chips := make([]string, len(m.attachments))
+	for i, img := range m.attachments {
+		kind := strings.TrimPrefix(img.MimeType, "image/")
+		chips[i] = m.styles.Accent.Render(fmt.Sprintf("[image %d · %s · %s]", i+1, kind, formatBytes(len(img.Data))))
+	}
+	hint := m.styles.Muted.Render(" Backspace on empty input removes")
+	return truncateRight(strings.Join(chips, " ")+hint, m.Viewport.Width)
+}
@@ -0,0 +1,87 @@
+package bubbletea_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_AttachImages(t *testing.T) {
+	t.Parallel()
+	png := pipe.ImageBlock{Data: make([]byte, 2048), MimeType: "image/png"}
+
+	// paste presses Ctrl+V and delivers the clipboard read.
+	paste := func(t *testing.T, m bt.Model) bt.Model {
+		t.Helper()
+		updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlV})
+		require.NotNil(t, cmd)
+		return updateModel(t, updated.(bt.Model), cmd())
+	}
+
+	t.Run("pasted images are sent with the next prompt", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{
+			PasteImage: func() (pipe.ImageBlock, error) { return png, nil },
+		})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		height := m.Viewport.Height
+
+		m = paste(t, m)
+		assert.Contains(t, m.View(), "[image 1 · png · 2 KB]")
+		assert.Equal(t, height-1, m.Viewport.Height, "the chips take a line from the viewport")
+		assert.Len(t, strings.Split(m.View(), "\n"), 24)
+
+		m.Input.SetValue("what is this?")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		require.Len(t, session.Messages, 1)
+		um := session.Messages[0].(pipe.UserMessage)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "what is this?"}, png}, um.Content)
+		assert.NotContains(t, m.View(), "[image 1 ·")
+		assert.Contains(t, m.View(), "[image: image/png, 2 KB]", "the transcript shows the image")
+		assert.Equal(t, height, m.Viewport.Height)
+	})
+
+	t.Run("backspace on empty input removes the last image", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{
+			PasteImage: func() (pipe.ImageBlock, error) { return png, nil },
+		})
+		m = paste(t, m)
+		m = paste(t, m)
+		assert.Contains(t, m.View(), "[image 2 ·")
+
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+		assert.NotContains(t, m.View(), "[image 2 ·")
+		assert.Contains(t, m.View(), "[image 1 ·")
+	})
+
+	t.Run("paste errors are shown", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{
+			PasteImage: func() (pipe.ImageBlock, error) { return pipe.ImageBlock{}, errors.New("the clipboard holds no image") },
+		})
+		m = paste(t, m)
+		assert.Contains(t, m.View(), "paste image: the clipboard holds no image")
+	})
+
+	t.Run("commands attach images", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{Commands: []bt.Command{{
+			Name: "image",
+			Run: func(string) (bt.CommandResult, error) {
+				return bt.CommandResult{Attach: []pipe.ImageBlock{png}}, nil
+			},
+		}}})
+		m.Input.SetValue("/image shot.png")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Contains(t, m.View(), "[image 1 · png · 2 KB]")
+		assert.False(t, m.Running())
+	})
+}
@@ -1,6 +1,29 @@
 package bubbletea
 
-import tea "github.com/charmbracelet/bubbletea"
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// narrowWidth is the width below which blocks switch to a compact layout:
+// no background padding, bare tool headers such as "bash ✓", and content
+// wrapped to the width even inside code blocks, so pipe stays usable in a
+// phone SSH session or a side pane.
+const narrowWidth = 40
+
+// narrow reports whether blocks rendered at width use the compact layout.
+func narrow(width int) bool {
+	return width > 0 && width < narrowWidth
+}
+
+// frame renders content in a block's background style at width, dropping
+// the left padding on narrow widths.
+func frame(style lipgloss.Style, width int, content string) string {
+	if narrow(width) {
+		style = style.PaddingLeft(0)
+	}
+	return style.Width(width).Render(content)
+}
 
 // MessageBlock is a renderable element in the conversation.
 // Unlike tea.Model, View takes a width parameter so the root model
@@ -0,0 +1,93 @@
+package bubbletea
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fwojciec/pipe"
+)
+
+var _ MessageBlock = (*ApprovalBlock)(nil)
+
+// ApprovalBlock renders a tool call awaiting the user's approval with its
+// arguments, or a costly provider request awaiting confirmation. Once
+// decided, the decision replaces the key hint.
+type ApprovalBlock struct {
+	call     pipe.ToolCallBlock
+	cost     *costEstimate
+	locale   pipe.Locale
+	decision pipe.Decision
+	styles   Styles
+	cache    viewCache
+}
+
+// NewApprovalBlock creates an ApprovalBlock.
+func NewApprovalBlock(call pipe.ToolCallBlock, styles Styles) *ApprovalBlock {
+	return &ApprovalBlock{call: call, styles: styles}
+}
+
+// newCostApprovalBlock creates an ApprovalBlock for a provider request.
+func newCostApprovalBlock(cost costEstimate, locale pipe.Locale, styles Styles) *ApprovalBlock {
+	return &ApprovalBlock{cost: &cost, locale: locale, styles: styles}
+}
+
+// SetDecision records the user's decision.
+func (b *ApprovalBlock) SetDecision(d pipe.Decision) {
+	b.decision = d
+	b.cache.changed()
+}
+
+func (b *ApprovalBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
+	return b, nil
+}
+
+func (b *ApprovalBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *ApprovalBlock) render(width int) string {
+	if b.cost != nil {
+		return b.costView(width)
+	}
+	wrap := lipgloss.NewStyle().Width(width)
+
+	lines := []string{b.styles.Accent.Render(wrap.Render("? Run " + b.call.Name + "?"))}
+	if fields, ok := parsePartialArgs(string(b.call.Arguments)); ok && len(fields) > 0 {
+		lines = append(lines, b.styles.Muted.Render(wrap.Render(formatArgs(fields))))
+	} else if len(b.call.Arguments) > 0 {
+		lines = append(lines, b.styles.Muted.Render(wrap.Render(string(b.call.Arguments))))
+	}
+	switch b.decision {
+	case pipe.DecisionAllow:
+		lines = append(lines, b.styles.UserMsg.Render(wrap.Render("→ allowed")))
+	case pipe.DecisionAlwaysAllow:
+		lines = append(lines, b.styles.UserMsg.Render(wrap.Render("→ always allowed")))
+	case pipe.DecisionDeny:
+		lines = append(lines, b.styles.Error.Render(wrap.Render("→ denied")))
+	default:
+		lines = append(lines, b.styles.Muted.Render(wrap.Render("y allow · n deny · a always allow "+b.call.Name)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// costView renders a provider request awaiting confirmation.
+func (b *ApprovalBlock) costView(width int) string {
+	wrap := lipgloss.NewStyle().Width(width)
+	u := b.cost.usage
+	tokens := formatTokens(b.locale, u.InputTokens+u.CacheReadTokens+u.CacheWriteTokens)
+	question := fmt.Sprintf("? Send the next request? About %s input tokens, ~$%s.", tokens, b.locale.Float(b.cost.dollars, 2))
+	lines := []string{b.styles.Accent.Render(wrap.Render(question))}
+	switch b.decision {
+	case pipe.DecisionAllow:
+		lines = append(lines, b.styles.UserMsg.Render(wrap.Render("→ sent")))
+	case pipe.DecisionAlwaysAllow:
+		lines = append(lines, b.styles.UserMsg.Render(wrap.Render("→ sent; not asking again this session")))
+	case pipe.DecisionDeny:
+		lines = append(lines, b.styles.Error.Render(wrap.Render("→ cancelled")))
+	default:
+		lines = append(lines, b.styles.Muted.Render(wrap.Render("y send · n cancel the run · a always send this session")))
+	}
+	return strings.Join(lines, "\n")
+}
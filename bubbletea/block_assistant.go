@@ -1,9 +1,12 @@
 package bubbletea
 
 import (
+	"fmt"
+	"slices"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/fwojciec/pipe"
 	"github.com/fwojciec/pipe/goldmark"
 )
@@ -21,6 +24,11 @@ type AssistantTextBlock struct {
 	// It's rendered once per width and cached in finalizedByWidth.
 	finalizedRaw     string
 	finalizedByWidth map[int]string
+
+	// footnotes are source numbers cited by this text, shown after it.
+	footnotes []int
+
+	cache viewCache
 }
 
 // NewAssistantTextBlock creates a new block for streaming assistant text.
@@ -35,6 +43,16 @@ func NewAssistantTextBlock(theme pipe.Theme) *AssistantTextBlock {
 func (b *AssistantTextBlock) Append(text string) {
 	b.content.WriteString(text)
 	b.promoteFinalized()
+	b.cache.changed()
+}
+
+// AddFootnote marks the text as citing source n. Repeated numbers are ignored.
+func (b *AssistantTextBlock) AddFootnote(n int) {
+	if slices.Contains(b.footnotes, n) {
+		return
+	}
+	b.footnotes = append(b.footnotes, n)
+	b.cache.changed()
 }
 
 func (b *AssistantTextBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
@@ -42,6 +60,30 @@ func (b *AssistantTextBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
 }
 
 func (b *AssistantTextBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *AssistantTextBlock) render(width int) string {
+	body := b.viewText(width)
+	if narrow(width) {
+		// Code blocks are not reflowed; break their lines rather than let
+		// them run off a narrow screen.
+		body = ansi.Hardwrap(body, width, true)
+	}
+	if len(b.footnotes) == 0 {
+		return body
+	}
+	var markers strings.Builder
+	for _, n := range b.footnotes {
+		fmt.Fprintf(&markers, "[%d]", n)
+	}
+	if body == "" {
+		return markers.String()
+	}
+	return strings.TrimRight(body, "\n") + "\n" + markers.String()
+}
+
+func (b *AssistantTextBlock) viewText(width int) string {
 	finalizedRendered := b.renderFinalized(width)
 	trailing := b.trailingRaw()
 	if hasUnclosedFence(trailing) {
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/fwojciec/pipe"
 	bt "github.com/fwojciec/pipe/bubbletea"
 	"github.com/fwojciec/pipe/goldmark"
@@ -34,6 +35,16 @@ func TestAssistantTextBlock_View(t *testing.T) {
 		assert.Contains(t, view, "hello world")
 	})
 
+	t.Run("wraps code blocks on narrow widths", func(t *testing.T) {
+		t.Parallel()
+		block := bt.NewAssistantTextBlock(pipe.DefaultTheme())
+		block.Append("```\n" + strings.Repeat("x", 50) + "\n```\n")
+		view := block.View(20)
+		for _, line := range strings.Split(view, "\n") {
+			assert.LessOrEqual(t, lipgloss.Width(line), 20)
+		}
+	})
+
 	t.Run("wraps paragraphs to width", func(t *testing.T) {
 		t.Parallel()
 		theme := pipe.DefaultTheme()
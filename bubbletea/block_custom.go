@@ -0,0 +1,56 @@
+package bubbletea
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+)
+
+var _ MessageBlock = customToolBlock{}
+
+// ToolRenderer builds custom blocks for one tool, replacing the generic
+// [ToolCallBlock] and [ToolResultBlock]. Either function may be nil to keep
+// the generic block. Custom blocks are clustered and focused like the
+// generic ones and receive [ToggleMsg], [SetCollapsedMsg], and
+// [SetPathDisplayMsg]; blocks can ignore those that do not apply to them.
+type ToolRenderer struct {
+	// Call builds the block for a completed call. Arguments stream into a
+	// generic block until the call completes, then this block replaces it.
+	Call func(call pipe.ToolCallBlock, styles Styles) MessageBlock
+
+	// Result builds the block for the tool's result.
+	Result func(content string, isError bool, styles Styles) MessageBlock
+}
+
+// customToolBlock marks a block built by a ToolRenderer so the model treats
+// it as a tool block for focus and spacing.
+type customToolBlock struct {
+	MessageBlock
+}
+
+func (b customToolBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
+	inner, cmd := b.MessageBlock.Update(msg)
+	return customToolBlock{inner}, cmd
+}
+
+// customToolCall returns the registered custom block for call, if any.
+func (m Model) customToolCall(call pipe.ToolCallBlock) (MessageBlock, bool) {
+	r, ok := m.config.ToolRenderers[call.Name]
+	if !ok || r.Call == nil {
+		return nil, false
+	}
+	b, _ := customToolBlock{r.Call(call, m.styles)}.Update(SetPathDisplayMsg{Paths: m.config.Paths})
+	return b, true
+}
+
+// toolResultBlock returns the block for a tool result: the registered custom
+// block if there is one, otherwise a ToolResultBlock.
+func (m Model) toolResultBlock(name, content string, isError bool) MessageBlock {
+	var b MessageBlock
+	if r, ok := m.config.ToolRenderers[name]; ok && r.Result != nil {
+		b = customToolBlock{r.Result(content, isError, m.styles)}
+	} else {
+		b = NewToolResultBlock(name, content, isError, m.styles)
+	}
+	b, _ = b.Update(SetPathDisplayMsg{Paths: m.config.Paths})
+	return b
+}
@@ -0,0 +1,100 @@
+package bubbletea_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+// labelBlock is a custom block that renders a fixed label and counts toggles.
+type labelBlock struct {
+	label   string
+	toggles int
+}
+
+func (b *labelBlock) Update(msg tea.Msg) (bt.MessageBlock, tea.Cmd) {
+	if _, ok := msg.(bt.ToggleMsg); ok {
+		b.toggles++
+	}
+	return b, nil
+}
+
+func (b *labelBlock) View(_ int) string { return b.label }
+
+func queryRenderers() map[string]bt.ToolRenderer {
+	return map[string]bt.ToolRenderer{
+		"query": {
+			Call: func(call pipe.ToolCallBlock, _ bt.Styles) bt.MessageBlock {
+				var args struct {
+					SQL string `json:"sql"`
+				}
+				_ = json.Unmarshal(call.Arguments, &args)
+				return &labelBlock{label: "SQL> " + args.SQL}
+			},
+			Result: func(content string, _ bool, _ bt.Styles) bt.MessageBlock {
+				return &labelBlock{label: "TABLE " + content}
+			},
+		},
+	}
+}
+
+func TestModel_ToolRenderers(t *testing.T) {
+	t.Parallel()
+
+	t.Run("custom blocks replace generic tool blocks", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{ToolRenderers: queryRenderers()})
+		call := pipe.ToolCallBlock{ID: "tc_1", Name: "query", Arguments: json.RawMessage(`{"sql":"select 1"}`)}
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolCallBegin{ID: "tc_1", Name: "query"}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolCallDelta{ID: "tc_1", Delta: `{"sql":"select 1"}`}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolCallEnd{Call: call}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolResult{ID: "tc_1", ToolName: "query", Content: "| 1 |"}})
+
+		view := m.View()
+		assert.Contains(t, view, "SQL> select 1")
+		assert.Contains(t, view, "TABLE | 1 |")
+		assert.NotContains(t, view, "▶ query")
+	})
+
+	t.Run("other tools keep generic blocks", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{ToolRenderers: queryRenderers()})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolCallBegin{ID: "tc_1", Name: "bash"}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolCallEnd{Call: pipe.ToolCallBlock{ID: "tc_1", Name: "bash"}}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolResult{ID: "tc_1", ToolName: "bash", Content: "ok"}})
+
+		assert.Contains(t, m.View(), "▶ bash")
+	})
+
+	t.Run("custom blocks take focus and toggles", func(t *testing.T) {
+		t.Parallel()
+		renderers := queryRenderers()
+		result := &labelBlock{label: "TABLE"}
+		renderers["query"] = bt.ToolRenderer{Result: func(string, bool, bt.Styles) bt.MessageBlock { return result }}
+		m := initModelWithConfig(t, nopAgent, bt.Config{ToolRenderers: renderers})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolResult{ID: "tc_1", ToolName: "query", Content: "x"}})
+
+		updateModel(t, m, tea.KeyMsg{Type: tea.KeyTab})
+		assert.Equal(t, 1, result.toggles)
+	})
+
+	t.Run("session reload uses custom blocks", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{
+				pipe.ToolCallBlock{ID: "tc_1", Name: "query", Arguments: json.RawMessage(`{"sql":"select 2"}`)},
+			}},
+			pipe.ToolResultMessage{ToolCallID: "tc_1", ToolName: "query", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "| 2 |"}}},
+		}}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{ToolRenderers: queryRenderers()})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+		view := m.View()
+		assert.Contains(t, view, "SQL> select 2")
+		assert.Contains(t, view, "TABLE | 2 |")
+	})
+}
@@ -12,6 +12,7 @@ var _ MessageBlock = (*ErrorBlock)(nil)
 type ErrorBlock struct {
 	err    error
 	styles Styles
+	cache  viewCache
 }
 
 // NewErrorBlock creates an ErrorBlock.
@@ -24,8 +25,10 @@ func (b *ErrorBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
 }
 
 func (b *ErrorBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *ErrorBlock) render(width int) string {
 	content := fmt.Sprintf("Error: %v", b.err)
-	return b.styles.ErrorBg.
-		Width(width).
-		Render(content)
+	return frame(b.styles.ErrorBg, width, content)
 }
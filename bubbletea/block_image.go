@@ -0,0 +1,250 @@
+package bubbletea
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // register GIF decoding
+	_ "image/jpeg" // register JPEG decoding
+	_ "image/png"  // register PNG decoding
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+)
+
+var _ MessageBlock = (*ImageBlock)(nil)
+
+// ImageProtocol is a terminal graphics protocol used to draw images inline.
+type ImageProtocol int
+
+const (
+	ImageNone   ImageProtocol = iota // no graphics; images show as a placeholder
+	ImageKitty                       // kitty graphics protocol
+	ImageITerm2                      // iTerm2 inline images
+	ImageSixel                       // DEC sixel graphics
+)
+
+const (
+	// maxImageCols and maxImageRows cap the size of an inline image in
+	// terminal cells.
+	maxImageCols = 60
+	maxImageRows = 16
+	// cellWidthPx and cellHeightPx approximate a terminal cell's size in
+	// pixels, used to keep the aspect ratio and to size sixel output.
+	cellWidthPx  = 10
+	cellHeightPx = 20
+	// kittyChunkSize is the largest base64 payload per kitty escape sequence.
+	kittyChunkSize = 4096
+)
+
+// DetectImageProtocol guesses the graphics protocol supported by the
+// terminal from its environment, looked up with getenv. It returns ImageNone
+// when the terminal is unknown or runs inside a multiplexer.
+func DetectImageProtocol(getenv func(string) string) ImageProtocol {
+	term := getenv("TERM")
+	switch {
+	case getenv("TMUX") != "" || strings.HasPrefix(term, "screen"):
+		return ImageNone
+	case getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || getenv("TERM_PROGRAM") == "ghostty":
+		return ImageKitty
+	case getenv("TERM_PROGRAM") == "iTerm.app" || getenv("TERM_PROGRAM") == "WezTerm":
+		return ImageITerm2
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || strings.Contains(term, "sixel"):
+		return ImageSixel
+	default:
+		return ImageNone
+	}
+}
+
+// ImageBlock renders an image inline using a terminal graphics protocol, or
+// a textual placeholder when the terminal has none or the image cannot be
+// decoded.
+type ImageBlock struct {
+	image    pipe.ImageBlock
+	protocol ImageProtocol
+	styles   Styles
+
+	// Encoding is costly; the view is rendered once per width.
+	cache viewCache
+}
+
+// NewImageBlock creates an ImageBlock.
+func NewImageBlock(img pipe.ImageBlock, protocol ImageProtocol, styles Styles) *ImageBlock {
+	return &ImageBlock{image: img, protocol: protocol, styles: styles}
+}
+
+func (b *ImageBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
+	return b, nil
+}
+
+func (b *ImageBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *ImageBlock) render(width int) string {
+	if b.protocol == ImageNone {
+		return b.placeholder()
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b.image.Data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return b.placeholder()
+	}
+	cols, rows := imageCells(cfg.Width, cfg.Height, width)
+
+	var seq string
+	switch b.protocol {
+	case ImageKitty:
+		if b.image.MimeType != "image/png" {
+			// Kitty only accepts PNG directly; other formats would need
+			// re-encoding.
+			return b.placeholder()
+		}
+		seq = kittySequence(b.image.Data, cols, rows)
+	case ImageITerm2:
+		seq = iterm2Sequence(b.image.Data, cols, rows)
+	case ImageSixel:
+		img, _, err := image.Decode(bytes.NewReader(b.image.Data))
+		if err != nil {
+			return b.placeholder()
+		}
+		seq = sixelSequence(img, cols*cellWidthPx, rows*cellHeightPx)
+	}
+	// The sequence draws over the rows below it; blank lines reserve them
+	// in the layout.
+	return seq + strings.Repeat("\n", rows-1)
+}
+
+// placeholder describes the image in text.
+func (b *ImageBlock) placeholder() string {
+	return b.styles.Muted.Render(fmt.Sprintf("[image: %s, %s]", b.image.MimeType, formatBytes(len(b.image.Data))))
+}
+
+// imageCells fits a w×h pixel image into at most width columns, capped at
+// maxImageCols×maxImageRows cells, keeping its aspect ratio.
+func imageCells(w, h, width int) (cols, rows int) {
+	cols = min(width, maxImageCols, max(w/cellWidthPx, 1))
+	cols = max(cols, 1)
+	rows = max((cols*cellWidthPx*h+w*cellHeightPx-1)/(w*cellHeightPx), 1)
+	if rows > maxImageRows {
+		rows = maxImageRows
+		cols = max(rows*cellHeightPx*w/(h*cellWidthPx), 1)
+	}
+	return cols, rows
+}
+
+// kittySequence transmits and displays a PNG in a cols×rows cell box using
+// the kitty graphics protocol. q=2 suppresses the terminal's replies, which
+// would otherwise arrive as input.
+func kittySequence(png []byte, cols, rows int) string {
+	payload := base64.StdEncoding.EncodeToString(png)
+	var b strings.Builder
+	for i := 0; i < len(payload); i += kittyChunkSize {
+		end := min(i+kittyChunkSize, len(payload))
+		more := 1
+		if end == len(payload) {
+			more = 0
+		}
+		if i == 0 {
+			fmt.Fprintf(&b, "\x1b_Ga=T,f=100,q=2,C=1,c=%d,r=%d,m=%d;%s\x1b\\", cols, rows, more, payload[i:end])
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, payload[i:end])
+		}
+	}
+	return b.String()
+}
+
+// iterm2Sequence displays an image in a cols×rows cell box using the iTerm2
+// inline image protocol.
+func iterm2Sequence(data []byte, cols, rows int) string {
+	return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+		len(data), cols, rows, base64.StdEncoding.EncodeToString(data))
+}
+
+// sixelSequence encodes img scaled to w×h pixels as sixel graphics, using a
+// 6×6×6 color cube palette.
+func sixelSequence(img image.Image, w, h int) string {
+	bounds := img.Bounds()
+	// Nearest-neighbor scale into palette indexes.
+	pixels := make([]int, w*h)
+	for y := range h {
+		for x := range w {
+			sx := bounds.Min.X + x*bounds.Dx()/w
+			sy := bounds.Min.Y + y*bounds.Dy()/h
+			pixels[y*w+x] = cubeIndex(img.At(sx, sy))
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\x1bPq\"1;1;%d;%d", w, h)
+	for i := range 216 {
+		r, g, bl := i/36, i/6%6, i%6
+		fmt.Fprintf(&b, "#%d;2;%d;%d;%d", i, r*20, g*20, bl*20)
+	}
+	row := make([]byte, w)
+	for band := 0; band < h; band += 6 {
+		used := make(map[int]bool)
+		for y := band; y < min(band+6, h); y++ {
+			for x := range w {
+				used[pixels[y*w+x]] = true
+			}
+		}
+		for c := range 216 {
+			if !used[c] {
+				continue
+			}
+			for x := range w {
+				bits := 0
+				for dy := range 6 {
+					if y := band + dy; y < h && pixels[y*w+x] == c {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = byte('?' + bits)
+			}
+			fmt.Fprintf(&b, "#%d", c)
+			writeSixelRun(&b, row)
+			b.WriteByte('$')
+		}
+		b.WriteByte('-')
+	}
+	b.WriteString("\x1b\\")
+	return b.String()
+}
+
+// writeSixelRun writes sixel characters with run-length encoding.
+func writeSixelRun(b *strings.Builder, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(b, "!%d%c", n, row[i])
+		} else {
+			b.Write(row[i:j])
+		}
+		i = j
+	}
+}
+
+// cubeIndex maps a color to the nearest entry of the 6×6×6 color cube.
+func cubeIndex(c color.Color) int {
+	r, g, b, _ := c.RGBA()
+	level := func(v uint32) int { return int((v*5 + 0x7fff) / 0xffff) }
+	return level(r)*36 + level(g)*6 + level(b)
+}
+
+// formatBytes renders a byte count as "512 B", "12 KB", or "3.4 MB".
+func formatBytes(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%d KB", n/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
@@ -0,0 +1,123 @@
+package bubbletea_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T, w, h int) pipe.ImageBlock {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return pipe.ImageBlock{Data: buf.Bytes(), MimeType: "image/png"}
+}
+
+func TestDetectImageProtocol(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bt.ImageProtocol
+	}{
+		{"kitty", map[string]string{"TERM": "xterm-kitty"}, bt.ImageKitty},
+		{"ghostty", map[string]string{"TERM_PROGRAM": "ghostty"}, bt.ImageKitty},
+		{"iterm2", map[string]string{"TERM_PROGRAM": "iTerm.app"}, bt.ImageITerm2},
+		{"foot", map[string]string{"TERM": "foot"}, bt.ImageSixel},
+		{"tmux disables graphics", map[string]string{"TERM": "xterm-kitty", "TMUX": "/tmp/tmux"}, bt.ImageNone},
+		{"plain xterm", map[string]string{"TERM": "xterm-256color"}, bt.ImageNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := bt.DetectImageProtocol(func(k string) string { return tt.env[k] })
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestImageBlock_View(t *testing.T) {
+	t.Parallel()
+	styles := bt.NewStyles(pipe.DefaultTheme())
+
+	t.Run("placeholder without graphics", func(t *testing.T) {
+		t.Parallel()
+		img := pipe.ImageBlock{Data: make([]byte, 2048), MimeType: "image/png"}
+		view := bt.NewImageBlock(img, bt.ImageNone, styles).View(80)
+		assert.Contains(t, view, "[image: image/png, 2 KB]")
+	})
+
+	t.Run("placeholder for undecodable data", func(t *testing.T) {
+		t.Parallel()
+		img := pipe.ImageBlock{Data: []byte("not an image"), MimeType: "image/png"}
+		view := bt.NewImageBlock(img, bt.ImageKitty, styles).View(80)
+		assert.Contains(t, view, "[image: image/png")
+	})
+
+	t.Run("kitty draws the image and reserves its rows", func(t *testing.T) {
+		t.Parallel()
+		view := bt.NewImageBlock(testPNG(t, 400, 400), bt.ImageKitty, styles).View(80)
+		assert.True(t, strings.HasPrefix(view, "\x1b_Ga=T,f=100,q=2"))
+		assert.Contains(t, view, "r=16")
+		assert.Len(t, strings.Split(view, "\n"), 16)
+	})
+
+	t.Run("iterm2 inline image fits the width", func(t *testing.T) {
+		t.Parallel()
+		view := bt.NewImageBlock(testPNG(t, 1000, 100), bt.ImageITerm2, styles).View(30)
+		assert.Contains(t, view, "\x1b]1337;File=inline=1")
+		assert.Contains(t, view, "width=30;")
+	})
+
+	t.Run("sixel encodes the image", func(t *testing.T) {
+		t.Parallel()
+		view := bt.NewImageBlock(testPNG(t, 20, 20), bt.ImageSixel, styles).View(80)
+		assert.True(t, strings.HasPrefix(view, "\x1bPq"))
+		assert.Contains(t, view, "\x1b\\")
+	})
+}
+
+func TestModel_Images(t *testing.T) {
+	t.Parallel()
+
+	t.Run("tool result images render after the result", func(t *testing.T) {
+		t.Parallel()
+		m := initModel(t, nopAgent)
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolResult{
+			ID: "tc_1", ToolName: "screenshot",
+			Images: []pipe.ImageBlock{{Data: make([]byte, 10), MimeType: "image/png"}},
+		}})
+		view := m.View()
+		assert.Contains(t, view, "screenshot")
+		assert.Contains(t, view, "[image: image/png, 10 B]")
+	})
+
+	t.Run("session reload renders attached images", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{
+				pipe.TextBlock{Text: "what is this?"},
+				pipe.ImageBlock{Data: make([]byte, 10), MimeType: "image/jpeg"},
+			}},
+		}}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		assert.Contains(t, m.View(), "[image: image/jpeg, 10 B]")
+	})
+}
@@ -0,0 +1,39 @@
+package bubbletea
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var _ MessageBlock = (*InterruptedBlock)(nil)
+
+// interruptedLabel starts the rule an InterruptedBlock draws.
+const interruptedLabel = "── interrupted "
+
+// InterruptedBlock marks where the user cut a run short. Output above it
+// is what the model produced before the interrupt.
+type InterruptedBlock struct {
+	styles Styles
+	cache  viewCache
+}
+
+// NewInterruptedBlock creates an InterruptedBlock.
+func NewInterruptedBlock(styles Styles) *InterruptedBlock {
+	return &InterruptedBlock{styles: styles}
+}
+
+func (b *InterruptedBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
+	return b, nil
+}
+
+func (b *InterruptedBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *InterruptedBlock) render(width int) string {
+	label := truncateRight(interruptedLabel, width)
+	rule := strings.Repeat("─", max(width-lipgloss.Width(label), 0))
+	return b.styles.Error.Render(label) + b.styles.Muted.Render(rule)
+}
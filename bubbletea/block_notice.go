@@ -0,0 +1,33 @@
+package bubbletea
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var _ MessageBlock = (*NoticeBlock)(nil)
+
+// NoticeBlock renders informational output that is not part of the
+// conversation, such as slash command results.
+type NoticeBlock struct {
+	text   string
+	styles Styles
+	cache  viewCache
+}
+
+// NewNoticeBlock creates a NoticeBlock.
+func NewNoticeBlock(text string, styles Styles) *NoticeBlock {
+	return &NoticeBlock{text: text, styles: styles}
+}
+
+func (b *NoticeBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
+	return b, nil
+}
+
+func (b *NoticeBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *NoticeBlock) render(width int) string {
+	return b.styles.Muted.Render(lipgloss.NewStyle().Width(width).Render(b.text))
+}
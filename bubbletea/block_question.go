@@ -0,0 +1,62 @@
+package bubbletea
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var _ MessageBlock = (*QuestionBlock)(nil)
+
+// QuestionBlock renders a question from the model with numbered options.
+// Once answered, the answer is shown beneath the question.
+type QuestionBlock struct {
+	question string
+	options  []string
+	answer   string
+	answered bool
+	styles   Styles
+	cache    viewCache
+}
+
+// NewQuestionBlock creates a QuestionBlock.
+func NewQuestionBlock(question string, options []string, styles Styles) *QuestionBlock {
+	return &QuestionBlock{question: question, options: options, styles: styles}
+}
+
+// SetAnswer records the user's answer.
+func (b *QuestionBlock) SetAnswer(answer string) {
+	b.answer = answer
+	b.answered = true
+	b.cache.changed()
+}
+
+func (b *QuestionBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
+	return b, nil
+}
+
+func (b *QuestionBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *QuestionBlock) render(width int) string {
+	wrap := lipgloss.NewStyle().Width(width)
+
+	var lines []string
+	lines = append(lines, b.styles.Accent.Render(wrap.Render("? "+b.question)))
+	for i, opt := range b.options {
+		lines = append(lines, wrap.Render(fmt.Sprintf("  %d. %s", i+1, opt)))
+	}
+	if b.answered {
+		lines = append(lines, b.styles.UserMsg.Render(wrap.Render("→ "+b.answer)))
+	} else {
+		hint := "Type your answer and press Enter"
+		if len(b.options) > 0 {
+			hint = "Type an option number or your own answer and press Enter"
+		}
+		lines = append(lines, b.styles.Muted.Render(wrap.Render(hint)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,119 @@
+package bubbletea
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fwojciec/pipe"
+)
+
+var _ MessageBlock = (*RunSummaryBlock)(nil)
+
+// SummaryAction is a quick action offered by the end-of-run summary block.
+// It runs when its key is pressed while the block is focused and the agent
+// is idle. Actions bound to one of the model's own keys, such as ctrl+g, are
+// dropped so they cannot shadow it.
+type SummaryAction struct {
+	Key   string // key as reported by tea.KeyMsg.String, e.g. "ctrl+y"
+	Label string // short description shown in the block, e.g. "copy"
+	// Run performs the action given the summary as plain text.
+	Run func(summary string) (CommandResult, error)
+}
+
+// RunStats describes a finished run.
+type RunStats struct {
+	StopReason pipe.StopReason
+	Turns      int // assistant messages produced
+	ToolCalls  int
+	Usage      pipe.Usage
+	Elapsed    time.Duration
+	Changes    string // summary of files the run changed; may be empty
+}
+
+// RunSummaryBlock shows the outcome of a run and the quick actions available
+// for it.
+type RunSummaryBlock struct {
+	stats   RunStats
+	actions []SummaryAction
+	locale  pipe.Locale
+	styles  Styles
+	cache   viewCache
+}
+
+// NewRunSummaryBlock creates a RunSummaryBlock that formats numbers for
+// locale.
+func NewRunSummaryBlock(stats RunStats, actions []SummaryAction, locale pipe.Locale, styles Styles) *RunSummaryBlock {
+	actions = slices.DeleteFunc(slices.Clone(actions), func(a SummaryAction) bool { return builtinKey(a.Key) })
+	return &RunSummaryBlock{stats: stats, actions: actions, locale: locale, styles: styles}
+}
+
+// Text returns the summary as plain text, without action hints.
+func (b *RunSummaryBlock) Text() string {
+	s := b.stats
+	stop := string(s.StopReason)
+	if stop == "" {
+		stop = "interrupted"
+	}
+	parts := []string{
+		"Run finished: " + stop,
+		plural(s.Turns, "turn"),
+		plural(s.ToolCalls, "tool call"),
+		fmt.Sprintf("%s in / %s out tokens", formatTokens(b.locale, s.Usage.InputTokens+s.Usage.CacheReadTokens+s.Usage.CacheWriteTokens), formatTokens(b.locale, s.Usage.OutputTokens)),
+		formatElapsed(s.Elapsed),
+	}
+	text := strings.Join(parts, " · ")
+	if s.Changes != "" {
+		text += "\n" + s.Changes
+	}
+	return text
+}
+
+// action returns the action bound to key, if any.
+func (b *RunSummaryBlock) action(key string) (SummaryAction, bool) {
+	for _, a := range b.actions {
+		if a.Key == key {
+			return a, true
+		}
+	}
+	return SummaryAction{}, false
+}
+
+func (b *RunSummaryBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
+	return b, nil
+}
+
+func (b *RunSummaryBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *RunSummaryBlock) render(width int) string {
+	content := b.Text()
+	if len(b.actions) > 0 {
+		hints := make([]string, len(b.actions))
+		for i, a := range b.actions {
+			hints[i] = a.Key + " " + a.Label
+		}
+		content += "\n" + strings.Join(hints, " · ")
+	}
+	return b.styles.Muted.Render(lipgloss.NewStyle().Width(width).Render(content))
+}
+
+// plural renders n with noun, adding "s" unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// formatTokens renders a token count as "950" or "12.3k" in locale.
+func formatTokens(locale pipe.Locale, n int) string {
+	if n < 1000 {
+		return locale.Int(n)
+	}
+	return locale.Float(float64(n)/1000, 1) + "k"
+}
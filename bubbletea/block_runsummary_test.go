@@ -0,0 +1,118 @@
+package bubbletea_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunSummaryBlock_Text(t *testing.T) {
+	t.Parallel()
+	b := bt.NewRunSummaryBlock(bt.RunStats{
+		StopReason: pipe.StopEndTurn,
+		Turns:      3,
+		ToolCalls:  1,
+		Usage:      pipe.Usage{InputTokens: 1000, CacheReadTokens: 11300, OutputTokens: 950},
+		Elapsed:    42 * time.Second,
+		Changes:    "1 file changed: a.go +3",
+	}, nil, pipe.Locale{}, bt.NewStyles(pipe.DefaultTheme()))
+
+	assert.Equal(t, "Run finished: end_turn · 3 turns · 1 tool call · 12.3k in / 950 out tokens · 42s\n1 file changed: a.go +3", b.Text())
+}
+
+func TestRunSummaryBlock_TextLocale(t *testing.T) {
+	t.Parallel()
+	de, _ := pipe.LookupLocale("de-DE")
+	b := bt.NewRunSummaryBlock(bt.RunStats{
+		StopReason: pipe.StopEndTurn,
+		Turns:      1,
+		Usage:      pipe.Usage{InputTokens: 12300, OutputTokens: 950},
+	}, nil, de, bt.NewStyles(pipe.DefaultTheme()))
+
+	assert.Contains(t, b.Text(), "12,3k in / 950 out tokens")
+}
+
+func TestModel_RunSummaryBlock(t *testing.T) {
+	t.Parallel()
+
+	// runOnce submits a prompt, fills the session as an agent would, and
+	// finishes the run.
+	runOnce := func(t *testing.T, config bt.Config) bt.Model {
+		t.Helper()
+		session := &pipe.Session{}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), config)
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		m.Input.SetValue("hello")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		session.Messages = append(session.Messages,
+			pipe.AssistantMessage{
+				Content:    []pipe.ContentBlock{pipe.ToolCallBlock{ID: "tc_1", Name: "bash"}},
+				StopReason: pipe.StopToolUse,
+				Usage:      pipe.Usage{InputTokens: 100, OutputTokens: 10},
+			},
+			pipe.ToolResultMessage{ToolCallID: "tc_1", ToolName: "bash"},
+			pipe.AssistantMessage{
+				Content:    []pipe.ContentBlock{pipe.TextBlock{Text: "done"}},
+				StopReason: pipe.StopEndTurn,
+				Usage:      pipe.Usage{InputTokens: 200, OutputTokens: 20},
+			},
+		)
+		return updateModel(t, m, bt.AgentDoneMsg{})
+	}
+
+	t.Run("summarizes the run", func(t *testing.T) {
+		t.Parallel()
+		m := runOnce(t, bt.Config{RunSummary: func() string { return "1 file changed: a.go +3" }})
+		view := bt.RenderContent(m)
+		assert.Contains(t, view, "Run finished: end_turn · 2 turns · 1 tool call · 300 in / 30 out tokens")
+		assert.Contains(t, view, "1 file changed: a.go +3")
+	})
+
+	t.Run("focused summary runs quick actions", func(t *testing.T) {
+		t.Parallel()
+		var copied string
+		m := runOnce(t, bt.Config{SummaryActions: []bt.SummaryAction{{
+			Key: "ctrl+y", Label: "copy",
+			Run: func(summary string) (bt.CommandResult, error) {
+				copied = summary
+				return bt.CommandResult{Notice: "Copied."}, nil
+			},
+		}}})
+		assert.Contains(t, bt.RenderContent(m), "ctrl+y copy")
+
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyCtrlY})
+		assert.Contains(t, copied, "Run finished: end_turn")
+		assert.Contains(t, bt.RenderContent(m), "Copied.")
+	})
+
+	t.Run("actions cannot shadow built-in keys", func(t *testing.T) {
+		t.Parallel()
+		ran := false
+		m := runOnce(t, bt.Config{SummaryActions: []bt.SummaryAction{{
+			Key: "ctrl+g", Label: "diff",
+			Run: func(string) (bt.CommandResult, error) {
+				ran = true
+				return bt.CommandResult{}, nil
+			},
+		}}})
+		assert.NotContains(t, bt.RenderContent(m), "ctrl+g diff")
+
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyCtrlG})
+		assert.False(t, ran)
+		assert.Contains(t, m.View(), "follow: text", "ctrl+g still cycles the follow mode")
+	})
+
+	t.Run("runs without turns get no summary block", func(t *testing.T) {
+		t.Parallel()
+		m := initModel(t, func(context.Context, *pipe.Session, func(pipe.Event)) error { return nil })
+		m.Input.SetValue("hello")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		m = updateModel(t, m, bt.AgentDoneMsg{})
+		assert.NotContains(t, bt.RenderContent(m), "Run finished")
+	})
+}
@@ -0,0 +1,96 @@
+package bubbletea
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fwojciec/pipe"
+)
+
+var _ MessageBlock = (*SourcesBlock)(nil)
+
+// SourcesBlock lists the citations referenced by footnote markers in the
+// preceding assistant text. It starts collapsed, showing only the count.
+type SourcesBlock struct {
+	sources   []pipe.Citation
+	collapsed bool
+	styles    Styles
+	cache     viewCache
+}
+
+// NewSourcesBlock creates a SourcesBlock that starts collapsed.
+func NewSourcesBlock(styles Styles) *SourcesBlock {
+	return &SourcesBlock{collapsed: true, styles: styles}
+}
+
+// Add records a citation and returns its 1-based footnote number. Citations
+// pointing at an already listed source reuse that source's number.
+func (b *SourcesBlock) Add(c pipe.Citation) int {
+	key := sourceKey(c)
+	for i, s := range b.sources {
+		if sourceKey(s) == key {
+			return i + 1
+		}
+	}
+	b.sources = append(b.sources, c)
+	b.cache.changed()
+	return len(b.sources)
+}
+
+func (b *SourcesBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ToggleMsg:
+		b.collapsed = !b.collapsed
+	case SetCollapsedMsg:
+		b.collapsed = msg.Collapsed
+	default:
+		return b, nil
+	}
+	b.cache.changed()
+	return b, nil
+}
+
+func (b *SourcesBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *SourcesBlock) render(width int) string {
+	wrap := lipgloss.NewStyle().Width(width)
+
+	indicator := "▶"
+	if !b.collapsed {
+		indicator = "▼"
+	}
+	header := b.styles.Muted.Render(wrap.Render(fmt.Sprintf("%s Sources (%d)", indicator, len(b.sources))))
+	if b.collapsed {
+		return header
+	}
+	lines := make([]string, len(b.sources))
+	for i, s := range b.sources {
+		lines[i] = fmt.Sprintf("[%d] %s", i+1, sourceLabel(s))
+	}
+	return header + "\n" + b.styles.Muted.Render(wrap.Render(strings.Join(lines, "\n")))
+}
+
+// sourceKey identifies a source for deduplication: the URL when present,
+// otherwise the title.
+func sourceKey(c pipe.Citation) string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return c.Title
+}
+
+// sourceLabel formats a citation as "title — url", omitting missing parts.
+func sourceLabel(c pipe.Citation) string {
+	switch {
+	case c.Title != "" && c.URL != "":
+		return c.Title + " — " + c.URL
+	case c.URL != "":
+		return c.URL
+	default:
+		return c.Title
+	}
+}
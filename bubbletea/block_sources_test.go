@@ -0,0 +1,69 @@
+package bubbletea_test
+
+import (
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourcesBlock_Add(t *testing.T) {
+	t.Parallel()
+	block := bt.NewSourcesBlock(bt.NewStyles(pipe.DefaultTheme()))
+	assert.Equal(t, 1, block.Add(pipe.Citation{URL: "https://go.dev", Title: "go.dev"}))
+	assert.Equal(t, 2, block.Add(pipe.Citation{Title: "README"}))
+	assert.Equal(t, 1, block.Add(pipe.Citation{URL: "https://go.dev", CitedText: "another excerpt"}))
+	assert.Equal(t, 2, block.Add(pipe.Citation{Title: "README"}))
+}
+
+func TestSourcesBlock_View(t *testing.T) {
+	t.Parallel()
+
+	t.Run("collapsed shows count only", func(t *testing.T) {
+		t.Parallel()
+		block := bt.NewSourcesBlock(bt.NewStyles(pipe.DefaultTheme()))
+		block.Add(pipe.Citation{URL: "https://go.dev", Title: "go.dev"})
+		block.Add(pipe.Citation{URL: "https://pkg.go.dev"})
+		view := block.View(80)
+		assert.Contains(t, view, "▶ Sources (2)")
+		assert.NotContains(t, view, "https://go.dev")
+	})
+
+	t.Run("expanded lists numbered sources", func(t *testing.T) {
+		t.Parallel()
+		block := bt.NewSourcesBlock(bt.NewStyles(pipe.DefaultTheme()))
+		block.Add(pipe.Citation{URL: "https://go.dev", Title: "go.dev"})
+		block.Add(pipe.Citation{URL: "https://pkg.go.dev"})
+		block.Add(pipe.Citation{Title: "README"})
+		updated, _ := block.Update(bt.ToggleMsg{})
+		view := updated.(*bt.SourcesBlock).View(80)
+		assert.Contains(t, view, "▼ Sources (3)")
+		assert.Contains(t, view, "[1] go.dev — https://go.dev")
+		assert.Contains(t, view, "[2] https://pkg.go.dev")
+		assert.Contains(t, view, "[3] README")
+	})
+
+	t.Run("SetCollapsedMsg", func(t *testing.T) {
+		t.Parallel()
+		block := bt.NewSourcesBlock(bt.NewStyles(pipe.DefaultTheme()))
+		block.Add(pipe.Citation{URL: "https://go.dev"})
+		b, _ := block.Update(bt.SetCollapsedMsg{Collapsed: false})
+		assert.Contains(t, b.View(80), "https://go.dev")
+		b, _ = b.Update(bt.SetCollapsedMsg{Collapsed: true})
+		assert.NotContains(t, b.View(80), "https://go.dev")
+	})
+}
+
+func TestAssistantTextBlock_Footnotes(t *testing.T) {
+	t.Parallel()
+	block := bt.NewAssistantTextBlock(pipe.DefaultTheme())
+	block.Append("Go is open source.")
+	block.AddFootnote(1)
+	block.AddFootnote(2)
+	block.AddFootnote(1)
+	view := block.View(80)
+	assert.Contains(t, view, "Go is open source.")
+	assert.Contains(t, view, "[1][2]")
+	assert.NotContains(t, view, "[1][2][1]")
+}
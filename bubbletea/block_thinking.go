@@ -14,6 +14,7 @@ type ThinkingBlock struct {
 	content   strings.Builder
 	collapsed bool
 	styles    Styles
+	cache     viewCache
 }
 
 // NewThinkingBlock creates a ThinkingBlock that starts collapsed.
@@ -24,6 +25,7 @@ func NewThinkingBlock(styles Styles) *ThinkingBlock {
 // Append adds a thinking text delta.
 func (b *ThinkingBlock) Append(text string) {
 	b.content.WriteString(text)
+	b.cache.changed()
 }
 
 func (b *ThinkingBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
@@ -32,11 +34,18 @@ func (b *ThinkingBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
 		b.collapsed = !b.collapsed
 	case SetCollapsedMsg:
 		b.collapsed = msg.Collapsed
+	default:
+		return b, nil
 	}
+	b.cache.changed()
 	return b, nil
 }
 
 func (b *ThinkingBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *ThinkingBlock) render(width int) string {
 	wrap := lipgloss.NewStyle().Width(width)
 
 	indicator := "▶"
@@ -1,9 +1,12 @@
 package bubbletea
 
 import (
+	"encoding/json"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/fwojciec/pipe"
 )
 
@@ -15,7 +18,9 @@ type ToolCallBlock struct {
 	id        string
 	args      strings.Builder
 	collapsed bool
+	paths     PathDisplay
 	styles    Styles
+	cache     viewCache
 }
 
 // NewToolCallBlock creates a ToolCallBlock that starts collapsed.
@@ -29,6 +34,7 @@ func (b *ToolCallBlock) ID() string { return b.id }
 // AppendArgs adds a tool call argument delta.
 func (b *ToolCallBlock) AppendArgs(text string) {
 	b.args.WriteString(text)
+	b.cache.changed()
 }
 
 // FinalizeWithCall applies the completed tool call, including arguments
@@ -37,6 +43,7 @@ func (b *ToolCallBlock) AppendArgs(text string) {
 func (b *ToolCallBlock) FinalizeWithCall(call pipe.ToolCallBlock) {
 	if b.args.Len() == 0 && len(call.Arguments) > 0 {
 		b.args.Write(call.Arguments)
+		b.cache.changed()
 	}
 }
 
@@ -46,21 +53,78 @@ func (b *ToolCallBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
 		b.collapsed = !b.collapsed
 	case SetCollapsedMsg:
 		b.collapsed = msg.Collapsed
+	case SetPathDisplayMsg:
+		b.paths = msg.Paths
+	default:
+		return b, nil
 	}
+	b.cache.changed()
 	return b, nil
 }
 
 func (b *ToolCallBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *ToolCallBlock) render(width int) string {
 	indicator := "▶"
 	if !b.collapsed {
 		indicator = "▼"
 	}
 	header := b.styles.ToolCall.Render(indicator + " " + b.name)
+	fields, ok := parsePartialArgs(b.args.String())
 	content := header
-	if !b.collapsed && b.args.Len() > 0 {
-		content = header + "\n" + b.styles.Muted.Render(b.args.String())
+	switch {
+	case b.collapsed && narrow(width):
+		// No room for a preview next to the header.
+	case b.collapsed:
+		// Preview the first string argument, e.g. a bash command, so it is
+		// visible as it streams without expanding the block.
+		if preview := b.paths.Shorten(argsPreview(fields)); preview != "" {
+			avail := width - lipgloss.Width(header) - 3
+			if avail > 1 {
+				content += " " + b.styles.Muted.Render(ansi.Truncate(preview, avail, "…"))
+			}
+		}
+	case ok && len(fields) > 0:
+		content += "\n" + b.styles.Muted.Render(b.paths.Shorten(formatArgs(fields)))
+	case b.args.Len() > 0:
+		content += "\n" + b.styles.Muted.Render(b.paths.Shorten(b.args.String()))
+	}
+	return frame(b.styles.ToolCallBg, width, content)
+}
+
+// argsPreview returns the first line of the first non-empty string
+// argument.
+func argsPreview(fields []argField) string {
+	for _, f := range fields {
+		if f.isString && strings.TrimSpace(f.value) != "" {
+			line, _, _ := strings.Cut(strings.TrimSpace(f.value), "\n")
+			return line
+		}
+	}
+	return ""
+}
+
+// formatArgs renders argument fields as "key: value" lines, indenting the
+// continuation lines of multi-line values.
+func formatArgs(fields []argField) string {
+	lines := make([]string, len(fields))
+	for i, f := range fields {
+		lines[i] = f.key + ": " + strings.ReplaceAll(f.value, "\n", "\n  ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// serverToolCallText renders a provider-hosted tool call's arguments for
+// display. Code execution calls show their code; anything else is shown as
+// raw JSON.
+func serverToolCallText(args json.RawMessage) string {
+	var exec struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(args, &exec); err == nil && exec.Code != "" {
+		return exec.Code
 	}
-	return b.styles.ToolCallBg.
-		Width(width).
-		Render(content)
+	return string(args)
 }
@@ -94,6 +94,46 @@ func TestToolCallBlock_View(t *testing.T) {
 		assert.Contains(t, view, "/tmp/foo")
 	})
 
+	t.Run("collapsed previews streaming string argument", func(t *testing.T) {
+		t.Parallel()
+		styles := bt.NewStyles(pipe.DefaultTheme())
+		block := bt.NewToolCallBlock("bash", "tc-1", styles)
+		block.AppendArgs(`{"command":"go test ./..`)
+		assert.Contains(t, block.View(80), "go test ./..")
+		assert.NotContains(t, block.View(80), `{"command"`)
+	})
+
+	t.Run("collapsed preview is truncated to width", func(t *testing.T) {
+		t.Parallel()
+		styles := bt.NewStyles(pipe.DefaultTheme())
+		block := bt.NewToolCallBlock("bash", "tc-1", styles)
+		block.AppendArgs(`{"command":"` + strings.Repeat("x", 100) + `"}`)
+		view := block.View(40)
+		assert.Len(t, strings.Split(view, "\n"), 1)
+		assert.Contains(t, view, "…")
+	})
+
+	t.Run("expanded shows decoded arguments", func(t *testing.T) {
+		t.Parallel()
+		styles := bt.NewStyles(pipe.DefaultTheme())
+		block := bt.NewToolCallBlock("write", "tc-1", styles)
+		block.AppendArgs(`{"path":"a.go","content":"package a\nfunc A() {}"}`)
+		updated, _ := block.Update(bt.ToggleMsg{})
+		view := ansi.Strip(updated.(*bt.ToolCallBlock).View(80))
+		assert.Contains(t, view, "path: a.go")
+		assert.Contains(t, view, "content: package a")
+		assert.Contains(t, view, "  func A() {}")
+	})
+
+	t.Run("expanded falls back to raw text", func(t *testing.T) {
+		t.Parallel()
+		styles := bt.NewStyles(pipe.DefaultTheme())
+		block := bt.NewToolCallBlock("bash", "tc-1", styles)
+		block.AppendArgs(`not json`)
+		updated, _ := block.Update(bt.ToggleMsg{})
+		assert.Contains(t, updated.(*bt.ToolCallBlock).View(80), "not json")
+	})
+
 	t.Run("ID returns tool call ID", func(t *testing.T) {
 		t.Parallel()
 		styles := bt.NewStyles(pipe.DefaultTheme())
@@ -144,4 +184,14 @@ func TestToolCallBlock_View(t *testing.T) {
 		stripped := ansi.Strip(firstLine)
 		assert.True(t, strings.HasPrefix(stripped, " "), "expected leading space, got: %q", stripped)
 	})
+
+	t.Run("drops padding and preview on narrow widths", func(t *testing.T) {
+		t.Parallel()
+		styles := bt.NewStyles(pipe.DefaultTheme())
+		block := bt.NewToolCallBlock("bash", "tc-1", styles)
+		block.AppendArgs(`{"command": "go test ./..."}`)
+		view := block.View(30)
+		assert.Equal(t, "▶ bash", strings.TrimRight(ansi.Strip(view), " "))
+		assert.Equal(t, 30, lipgloss.Width(view))
+	})
 }
@@ -1,6 +1,7 @@
 package bubbletea
 
 import (
+	"encoding/json"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -17,7 +18,9 @@ type ToolResultBlock struct {
 	content   string
 	isError   bool
 	collapsed bool
+	paths     PathDisplay
 	styles    Styles
+	cache     viewCache
 }
 
 // NewToolResultBlock creates a ToolResultBlock.
@@ -50,11 +53,20 @@ func (b *ToolResultBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
 			break
 		}
 		b.collapsed = msg.Collapsed
+	case SetPathDisplayMsg:
+		b.paths = msg.Paths
+	default:
+		return b, nil
 	}
+	b.cache.changed()
 	return b, nil
 }
 
 func (b *ToolResultBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *ToolResultBlock) render(width int) string {
 	statusIcon := "✓"
 	if b.isError {
 		statusIcon = "✗"
@@ -71,9 +83,13 @@ func (b *ToolResultBlock) viewCollapsed(width int, statusIcon string) string {
 	if b.isError {
 		iconStyle = b.styles.Error
 	}
+	if narrow(width) {
+		// Just "bash ✓": the result is a toggle away.
+		return frame(b.styles.ToolResultBg, width, b.styles.ToolCall.Render(b.toolName)+" "+iconStyle.Render(statusIcon))
+	}
 	header := b.styles.ToolCall.Render("▶ "+b.toolName) + " " + iconStyle.Render(statusIcon)
 	if b.content != "" {
-		preview := firstLine(b.content)
+		preview := b.paths.Shorten(firstLine(b.content))
 		runes := []rune(preview)
 		if len(runes) > maxPreviewLen {
 			preview = string(runes[:maxPreviewLen]) + "…"
@@ -84,9 +100,7 @@ func (b *ToolResultBlock) viewCollapsed(width int, statusIcon string) string {
 			header += "  " + preview
 		}
 	}
-	return b.styles.ToolResultBg.
-		Width(width).
-		Render(header)
+	return frame(b.styles.ToolResultBg, width, header)
 }
 
 func (b *ToolResultBlock) viewExpanded(width int, statusIcon string) string {
@@ -97,15 +111,13 @@ func (b *ToolResultBlock) viewExpanded(width int, statusIcon string) string {
 	header := b.styles.ToolCall.Render("▼ "+b.toolName) + " " + iconStyle.Render(statusIcon)
 	content := header
 	if b.content != "" {
-		rendered := b.content
+		rendered := b.paths.Shorten(b.content)
 		if b.isError {
-			rendered = b.styles.Error.Render(b.content)
+			rendered = b.styles.Error.Render(rendered)
 		}
 		content = header + "\n" + rendered
 	}
-	return b.styles.ToolResultBg.
-		Width(width).
-		Render(content)
+	return frame(b.styles.ToolResultBg, width, content)
 }
 
 func firstLine(s string) string {
@@ -114,3 +126,30 @@ func firstLine(s string) string {
 	}
 	return s
 }
+
+// serverToolResultText renders a provider-hosted tool result payload for
+// display. Search-style results (a list of objects with title and url) are
+// shown one per line; Gemini code execution results show their output;
+// anything else is shown as raw JSON.
+func serverToolResultText(content json.RawMessage) string {
+	var exec struct {
+		Outcome string `json:"outcome"`
+		Output  string `json:"output"`
+	}
+	if err := json.Unmarshal(content, &exec); err == nil && exec.Outcome != "" {
+		return strings.TrimRight(exec.Output, "\n")
+	}
+
+	var results []struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	}
+	if err := json.Unmarshal(content, &results); err != nil || len(results) == 0 || results[0].URL == "" {
+		return string(content)
+	}
+	lines := make([]string, len(results))
+	for i, r := range results {
+		lines[i] = r.Title + " — " + r.URL
+	}
+	return strings.Join(lines, "\n")
+}
@@ -14,6 +14,15 @@ import (
 func TestToolResultBlock_View(t *testing.T) {
 	t.Parallel()
 
+	t.Run("collapsed narrow view shows only the tool name and status", func(t *testing.T) {
+		t.Parallel()
+		styles := bt.NewStyles(pipe.DefaultTheme())
+		block := bt.NewToolResultBlock("bash", "ok\nPASS", false, styles)
+		view := block.View(30)
+		assert.Equal(t, "bash ✓", strings.TrimRight(ansi.Strip(view), " "))
+		assert.Equal(t, 30, lipgloss.Width(view))
+	})
+
 	t.Run("success result starts collapsed with summary", func(t *testing.T) {
 		t.Parallel()
 		styles := bt.NewStyles(pipe.DefaultTheme())
@@ -8,6 +8,7 @@ var _ MessageBlock = (*UserMessageBlock)(nil)
 type UserMessageBlock struct {
 	text   string
 	styles Styles
+	cache  viewCache
 }
 
 // NewUserMessageBlock creates a UserMessageBlock.
@@ -20,8 +21,10 @@ func (b *UserMessageBlock) Update(msg tea.Msg) (MessageBlock, tea.Cmd) {
 }
 
 func (b *UserMessageBlock) View(width int) string {
+	return b.cache.view(width, b.render)
+}
+
+func (b *UserMessageBlock) render(width int) string {
 	content := b.styles.UserMsg.Render(b.text)
-	return b.styles.UserBg.
-		Width(width).
-		Render(content)
+	return frame(b.styles.UserBg, width, content)
 }
@@ -0,0 +1,247 @@
+// Package bttest provides an expect-style driver for testing Bubble Tea
+// models, such as a customized pipe TUI, in a virtual terminal.
+//
+// A Driver runs the model in a program built by teatest and keeps the most
+// recently rendered screen, so tests can type, press keys, wait for text to
+// appear, and compare the screen with golden files:
+//
+//	d := bttest.New(t, model, bttest.WithSize(80, 24))
+//	d.Type("hello").Press("enter").WaitFor("Hello!")
+//	d.Press("tab").Snapshot("expanded")
+//	d.Quit()
+//
+// Golden files live in testdata and are rewritten when tests run with the
+// -update flag.
+package bttest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aymanbagabas/go-udiff"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/exp/teatest"
+)
+
+const (
+	defaultWidth   = 80
+	defaultHeight  = 24
+	defaultTimeout = 5 * time.Second
+	// pollInterval is how often WaitFor checks the screen.
+	pollInterval = 10 * time.Millisecond
+)
+
+// Option configures a Driver.
+type Option func(*Driver)
+
+// WithSize sets the terminal size. Default is 80x24.
+func WithSize(width, height int) Option {
+	return func(d *Driver) { d.width, d.height = width, height }
+}
+
+// WithTimeout sets how long WaitFor and Quit wait. Default is 5s.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) { d.timeout = timeout }
+}
+
+// Driver runs a model in a virtual terminal and drives it like a user.
+// Its methods fail the test on timeout and return the Driver for chaining.
+type Driver struct {
+	tb      testing.TB
+	tm      *teatest.TestModel
+	screen  *atomic.Pointer[string]
+	width   int
+	height  int
+	timeout time.Duration
+}
+
+// New starts m in a virtual terminal.
+func New(tb testing.TB, m tea.Model, opts ...Option) *Driver {
+	tb.Helper()
+	d := &Driver{
+		tb:      tb,
+		screen:  &atomic.Pointer[string]{},
+		width:   defaultWidth,
+		height:  defaultHeight,
+		timeout: defaultTimeout,
+	}
+	for _, o := range opts {
+		o(d)
+	}
+	d.tm = teatest.NewTestModel(tb, recorder{model: m, screen: d.screen},
+		teatest.WithInitialTermSize(d.width, d.height))
+	return d
+}
+
+// Send delivers msg to the model.
+func (d *Driver) Send(msg tea.Msg) *Driver {
+	d.tm.Send(msg)
+	return d
+}
+
+// Type types s one rune at a time.
+func (d *Driver) Type(s string) *Driver {
+	for _, r := range s {
+		d.tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return d
+}
+
+// Press presses each key in turn. Keys are named as tea.KeyMsg.String
+// names them, e.g. "enter", "tab", "shift+tab", "ctrl+c", "alt+up", or a
+// single character such as "q".
+func (d *Driver) Press(keys ...string) *Driver {
+	d.tb.Helper()
+	for _, k := range keys {
+		msg, err := KeyMsg(k)
+		if err != nil {
+			d.tb.Fatal(err)
+		}
+		d.tm.Send(msg)
+	}
+	return d
+}
+
+// WaitFor waits until the screen contains text.
+func (d *Driver) WaitFor(text string) *Driver {
+	d.tb.Helper()
+	return d.WaitUntil(func(screen string) bool { return strings.Contains(screen, text) }, fmt.Sprintf("screen to contain %q", text))
+}
+
+// WaitUntil waits until cond reports true for the screen. what describes
+// the condition in the failure message.
+func (d *Driver) WaitUntil(cond func(screen string) bool, what string) *Driver {
+	d.tb.Helper()
+	deadline := time.Now().Add(d.timeout)
+	for {
+		screen := d.Screen()
+		if cond(screen) {
+			return d
+		}
+		if time.Now().After(deadline) {
+			d.tb.Fatalf("timed out after %s waiting for %s; screen:\n%s", d.timeout, what, screen)
+			return d
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Screen returns the most recently rendered screen, normalized.
+func (d *Driver) Screen() string {
+	if s := d.screen.Load(); s != nil {
+		return Normalize(*s)
+	}
+	return ""
+}
+
+// Snapshot compares the screen with testdata/<test name>/<name>.golden,
+// failing the test with a diff when they differ. With -update it writes
+// the golden file instead.
+func (d *Driver) Snapshot(name string) *Driver {
+	d.tb.Helper()
+	RequireGolden(d.tb, name, d.Screen())
+	return d
+}
+
+// Quit stops the program and returns its final model.
+func (d *Driver) Quit() tea.Model {
+	d.tb.Helper()
+	_ = d.tm.Quit()
+	m := d.tm.FinalModel(d.tb, teatest.WithFinalTimeout(d.timeout))
+	if r, ok := m.(recorder); ok {
+		return r.model
+	}
+	return m
+}
+
+// recorder wraps a model and records each view it renders.
+type recorder struct {
+	model  tea.Model
+	screen *atomic.Pointer[string]
+}
+
+func (r recorder) Init() tea.Cmd { return r.model.Init() }
+
+func (r recorder) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	r.model, cmd = r.model.Update(msg)
+	return r, cmd
+}
+
+func (r recorder) View() string {
+	v := r.model.View()
+	r.screen.Store(&v)
+	return v
+}
+
+// KeyMsg returns the key message for a key name as tea.KeyMsg.String
+// formats it.
+func KeyMsg(name string) (tea.KeyMsg, error) {
+	var msg tea.KeyMsg
+	key := name
+	if rest, ok := strings.CutPrefix(key, "alt+"); ok && rest != "" {
+		msg.Alt = true
+		key = rest
+	}
+	for k := tea.KeyType(-100); k < 256; k++ {
+		if k != tea.KeyRunes && k.String() == key {
+			msg.Type = k
+			return msg, nil
+		}
+	}
+	if r := []rune(key); len(r) == 1 {
+		msg.Type = tea.KeyRunes
+		msg.Runes = r
+		return msg, nil
+	}
+	return tea.KeyMsg{}, fmt.Errorf("bttest: unknown key %q", name)
+}
+
+// Normalize makes a rendered screen comparable: it strips ANSI escape
+// sequences and carriage returns, trailing spaces on each line, and
+// trailing blank lines.
+func Normalize(screen string) string {
+	screen = strings.ReplaceAll(ansi.Strip(screen), "\r", "")
+	lines := strings.Split(screen, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// RequireGolden compares got with testdata/<test name>/<name>.golden,
+// failing the test with a diff when they differ. With -update it writes
+// the golden file instead.
+func RequireGolden(tb testing.TB, name, got string) {
+	tb.Helper()
+	path := filepath.Join("testdata", filepath.FromSlash(tb.Name()), name+".golden")
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o600); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("%v (run with -update to create it)", err)
+	}
+	if diff := udiff.Unified("golden", "screen", string(want), got); diff != "" {
+		tb.Fatalf("screen does not match %s:\n\n%s", path, diff)
+	}
+}
+
+// updateGolden reports whether the -update flag, registered by teatest's
+// golden package, is set.
+func updateGolden() bool {
+	f := flag.Lookup("update")
+	return f != nil && f.Value.String() == "true"
+}
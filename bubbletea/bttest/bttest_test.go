@@ -0,0 +1,98 @@
+package bttest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/fwojciec/pipe/bubbletea/bttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counter is a minimal model: up and down change the count, typed runes
+// are echoed.
+type counter struct {
+	count int
+	typed string
+}
+
+func (c counter) Init() tea.Cmd { return nil }
+
+func (c counter) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if k, ok := msg.(tea.KeyMsg); ok {
+		switch k.String() {
+		case "up":
+			c.count++
+		case "down":
+			c.count--
+		case "ctrl+c":
+			return c, tea.Quit
+		default:
+			if k.Type == tea.KeyRunes {
+				c.typed += string(k.Runes)
+			}
+		}
+	}
+	return c, nil
+}
+
+func (c counter) View() string {
+	return fmt.Sprintf("\x1b[1mcount: %d\x1b[0m   \r\ntyped: %s\n\n", c.count, c.typed)
+}
+
+func TestDriver(t *testing.T) {
+	t.Parallel()
+
+	t.Run("types, presses, and waits", func(t *testing.T) {
+		t.Parallel()
+		d := bttest.New(t, counter{})
+		d.Press("up", "up", "down").WaitFor("count: 1")
+		d.Type("héllo").WaitFor("typed: héllo")
+
+		final, ok := d.Quit().(counter)
+		require.True(t, ok)
+		assert.Equal(t, 1, final.count)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		t.Parallel()
+		d := bttest.New(t, counter{})
+		d.Press("up").Type("x").WaitFor("typed: x").Snapshot("after-input")
+		d.Quit()
+	})
+
+	t.Run("drives the pipe TUI", func(t *testing.T) {
+		t.Parallel()
+		agent := func(_ context.Context, s *pipe.Session, onEvent func(pipe.Event)) error {
+			onEvent(pipe.EventTextDelta{Delta: "Hello!"})
+			s.Messages = append(s.Messages, pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Hello!"}}})
+			return nil
+		}
+		m := bt.New(agent, &pipe.Session{}, pipe.DefaultTheme(), bt.Config{ModelName: "test-model"})
+		d := bttest.New(t, m, bttest.WithSize(60, 20))
+		d.Type("hi").Press("enter").WaitFor("Hello!").WaitFor("test-model")
+		_, ok := d.Quit().(bt.Model)
+		assert.True(t, ok)
+	})
+}
+
+func TestKeyMsg(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"enter", "tab", "shift+tab", "ctrl+c", "esc", "up", "pgdown", "alt+up", "q", "alt+x", " "} {
+		msg, err := bttest.KeyMsg(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, name, msg.String())
+	}
+	_, err := bttest.KeyMsg("hyper+q")
+	require.EqualError(t, err, `bttest: unknown key "hyper+q"`)
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+	got := bttest.Normalize("\x1b[31mred\x1b[0m   \r\nplain\n\n\n")
+	assert.Equal(t, "red\nplain\n", got)
+}
@@ -17,7 +17,18 @@ type AgentFunc func(ctx context.Context, session *pipe.Session, onEvent func(pip
 // exits. The context is used for graceful shutdown — when cancelled, the
 // program quits.
 func Run(ctx context.Context, m Model) error {
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	var opts []tea.ProgramOption
+	if m.config.Mouse {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	_, err := runProgram(ctx, m, opts...)
+	return err
+}
+
+// runProgram runs m full-screen until it quits or ctx is cancelled and
+// returns its final state.
+func runProgram(ctx context.Context, m tea.Model, opts ...tea.ProgramOption) (tea.Model, error) {
+	p := tea.NewProgram(m, append([]tea.ProgramOption{tea.WithAltScreen()}, opts...)...)
 	done := make(chan struct{})
 	go func() {
 		select {
@@ -26,9 +37,9 @@ func Run(ctx context.Context, m Model) error {
 		case <-done:
 		}
 	}()
-	_, err := p.Run()
+	final, err := p.Run()
 	close(done)
-	return err
+	return final, err
 }
 
 // StreamEventMsg wraps a streaming event for delivery to the Bubble Tea model.
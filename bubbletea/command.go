@@ -0,0 +1,112 @@
+package bubbletea
+
+import (
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+)
+
+// Command is a slash command entered in the input box, e.g. "/rollback".
+// Commands run only while the agent is idle and never reach the model.
+type Command struct {
+	Name        string // without the leading slash
+	Description string
+	// Run executes the command with the text following its name.
+	Run func(args string) (CommandResult, error)
+}
+
+// CommandResult describes what a command wants shown after it runs.
+type CommandResult struct {
+	Notice    string // text displayed in a notice block; may be empty
+	ModelName string // replaces the model shown in the status bar when non-empty
+	// Pricing replaces Config.Pricing along with ModelName; nil when the
+	// new model has no known price.
+	Pricing *pipe.Pricing
+	// Retry removes the latest assistant turn, everything after the last
+	// user message, and requests it again.
+	Retry bool
+	// Attach adds images to the next prompt.
+	Attach []pipe.ImageBlock
+	// InputHeight replaces Config.InputHeight when positive.
+	InputHeight int
+	// RefreshGit looks up the git status again in the background, as
+	// Config.DetectGit does at startup.
+	RefreshGit bool
+}
+
+// lookupCommand parses input of the form "/name args" and returns the
+// matching configured command. Input naming no configured command is not a
+// command, so prompts starting with a path like "/etc/hosts" reach the model.
+func (m Model) lookupCommand(input string) (Command, string, bool) {
+	rest, ok := strings.CutPrefix(input, "/")
+	if !ok {
+		return Command{}, "", false
+	}
+	name, args, _ := strings.Cut(rest, " ")
+	for _, c := range m.config.Commands {
+		if c.Name == name {
+			return c, strings.TrimSpace(args), true
+		}
+	}
+	return Command{}, "", false
+}
+
+// runCommand executes c and shows its output (or error) as a block.
+func (m Model) runCommand(c Command, args string) (tea.Model, tea.Cmd) {
+	m.Input.SetValue("")
+	m.Input.SetHeight(1)
+	m.Viewport.Height = m.viewportHeight(1)
+
+	res, err := c.Run(args)
+	if err == nil && res.Retry {
+		return m.retryLastTurn(res)
+	}
+	var cmd tea.Cmd
+	if err == nil && res.RefreshGit {
+		cmd = m.detectGit()
+	}
+	return m.showResult(res, err), cmd
+}
+
+// retryLastTurn drops the latest assistant turn and its tool results from
+// the session, redraws the transcript, and starts a new run for the last
+// user message.
+func (m Model) retryLastTurn(res CommandResult) (tea.Model, tea.Cmd) {
+	last := m.session.LastPrompt()
+	if last < 0 {
+		return m.showResult(res, errors.New("nothing to retry: the session has no prompt")), nil
+	}
+	m.session.Messages = m.session.Messages[:last+1]
+	m.blocks = nil
+	m = m.renderSession()
+	if res.Notice != "" {
+		m.blocks = append(m.blocks, NewNoticeBlock(res.Notice, m.styles))
+	}
+	if res.ModelName != "" {
+		m.config.ModelName, m.config.Pricing = res.ModelName, res.Pricing
+	}
+	m.blockFocus = -1
+	return m.startRun()
+}
+
+// showResult displays the outcome of a command or quick action.
+func (m Model) showResult(res CommandResult, err error) Model {
+	switch {
+	case err != nil:
+		m.blocks = append(m.blocks, NewErrorBlock(err, m.styles))
+	case res.Notice != "":
+		m.blocks = append(m.blocks, NewNoticeBlock(res.Notice, m.styles))
+	}
+	if res.ModelName != "" {
+		m.config.ModelName, m.config.Pricing = res.ModelName, res.Pricing
+	}
+	for _, img := range res.Attach {
+		m = m.attach(img)
+	}
+	if res.InputHeight > 0 {
+		m = m.setInputHeight(res.InputHeight)
+	}
+	return m.refreshViewport()
+}
@@ -1,5 +1,12 @@
 package bubbletea
 
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+)
+
 // BlockSeparator exports blockSeparator for testing.
 func BlockSeparator(prev, curr MessageBlock) string {
 	return blockSeparator(prev, curr)
@@ -14,3 +21,56 @@ func RenderContent(m Model) string {
 func AllExpanded(m Model) bool {
 	return m.allExpanded
 }
+
+// RenderTickMsg exports renderTickMsg for testing.
+type RenderTickMsg = renderTickMsg
+
+// ListenForEvent returns the message the model receives next from a run
+// streaming events on ch.
+func ListenForEvent(ch <-chan pipe.Event, done <-chan error) tea.Msg {
+	return listenForEvent(ch, done, nil, nil)()
+}
+
+// NextQuestion blocks until a receives an ask_user request and returns the
+// message the model would receive for it.
+func NextQuestion(a *Asker) tea.Msg {
+	return questionMsg{req: <-a.requests}
+}
+
+// HeartbeatMsg returns the heartbeat message for the model's current run.
+func HeartbeatMsg(m Model) tea.Msg {
+	return heartbeatMsg{run: m.runCount}
+}
+
+// SetNow replaces the clock the model uses for stream health.
+func SetNow(m Model, now func() time.Time) Model {
+	m.now = now
+	return m
+}
+
+// ParsePartialArgs exposes the partial JSON argument parser for testing,
+// rendering each field as "key=value".
+func ParsePartialArgs(s string) ([]string, bool) {
+	fields, ok := parsePartialArgs(s)
+	out := make([]string, len(fields))
+	for i, f := range fields {
+		out[i] = f.key + "=" + f.value
+	}
+	return out, ok
+}
+
+// NextApproval blocks until a receives a tool call to approve and returns
+// the message the model would receive for it.
+func NextApproval(a *Approver) tea.Msg {
+	return approvalMsg{req: <-a.requests}
+}
+
+// FindMatches returns the matches of query in content as they would be
+// found by a search, each as {line, start cell, end cell}.
+func FindMatches(content, query string) [][3]int {
+	var out [][3]int
+	for _, mt := range findMatches(content, search{query: query}.pattern()) {
+		out = append(out, [3]int{mt.line, mt.start, mt.end})
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package bubbletea
+
+import "github.com/fwojciec/pipe"
+
+// FollowMode controls when streaming output scrolls the viewport to the
+// bottom.
+type FollowMode int
+
+const (
+	// FollowAll scrolls to the bottom on every update.
+	FollowAll FollowMode = iota
+	// FollowText scrolls only for assistant text and thinking, so long tool
+	// output does not yank the view away from what is being read.
+	FollowText
+	// FollowManual never scrolls automatically.
+	FollowManual
+)
+
+// next returns the mode Ctrl+G switches to.
+func (f FollowMode) next() FollowMode {
+	return (f + 1) % 3
+}
+
+// label is the status bar indicator for f; the default FollowAll has none.
+func (f FollowMode) label() string {
+	switch f {
+	case FollowText:
+		return "follow: text"
+	case FollowManual:
+		return "follow: off"
+	default:
+		return ""
+	}
+}
+
+// follows reports whether evt should scroll the viewport in mode f.
+func (f FollowMode) follows(evt pipe.Event) bool {
+	switch f {
+	case FollowAll:
+		return true
+	case FollowText:
+		switch evt.(type) {
+		case pipe.EventTextDelta, pipe.EventThinkingDelta:
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,13 +19,159 @@ import (
 
 var _ tea.Model = Model{}
 
-// Config holds display metadata for the TUI status bar.
+// Config holds display metadata for the TUI status bar and rendering options.
 type Config struct {
 	WorkDir   string // Working directory path
 	GitBranch string // Current git branch (empty if not in a repo)
+	GitDirty  bool   // Whether the work tree has uncommitted changes
 	ModelName string // LLM model name
+
+	// DetectGitBranch, when set, looks up the git branch after the first
+	// frame is drawn so startup does not wait on git. Its result replaces
+	// GitBranch.
+	//
+	// Deprecated: use DetectGit, which also reports uncommitted changes.
+	DetectGitBranch func() string
+
+	// DetectGit, when set, looks up the git branch and work tree state in
+	// the background after the first frame is drawn, and again when a
+	// command asks with CommandResult.RefreshGit. Its result replaces
+	// GitBranch and GitDirty. It takes precedence over DetectGitBranch.
+	DetectGit func() GitStatus
+
+	// RenderInterval batches viewport re-renders during streaming: when
+	// positive, events are applied immediately but the viewport is redrawn at
+	// most once per interval; see DefaultRenderInterval. Zero re-renders on
+	// every event.
+	RenderInterval time.Duration
+
+	// Asker answers ask_user tool calls through the TUI. Nil when the tool
+	// is not registered.
+	Asker *Asker
+
+	// Approver asks the user to allow or deny tool calls before they run.
+	// Nil when calls run without approval.
+	Approver *Approver
+
+	// Commands are the slash commands available in the input box.
+	Commands []Command
+
+	// StatusSegments are extra status bar segments refreshed in the
+	// background.
+	StatusSegments []StatusSegment
+
+	// Notices delivers messages from outside the agent run, such as a
+	// reloaded config file, to show in notice blocks as they arrive.
+	Notices <-chan string
+
+	// RunSummary, when set, is called after each run; non-empty text is shown
+	// in a notice block, e.g. a summary of the files the run changed.
+	RunSummary func() string
+
+	// ToolRenderers maps tool names to custom renderers for their calls and
+	// results. Tools without an entry use the generic blocks.
+	ToolRenderers map[string]ToolRenderer
+
+	// SummaryActions are the quick actions offered by the summary block
+	// appended after each run.
+	SummaryActions []SummaryAction
+
+	// Images selects the terminal graphics protocol for drawing images
+	// inline. ImageNone shows a textual placeholder instead.
+	Images ImageProtocol
+
+	// Locale formats numbers in stats and summaries. The zero value formats
+	// like en-US.
+	Locale pipe.Locale
+
+	// Follow is the initial viewport follow mode; Ctrl+G cycles through the
+	// modes.
+	Follow FollowMode
+
+	// Pricing, when set, prices the session's usage for the estimated cost
+	// shown next to its token count in the status bar.
+	Pricing *pipe.Pricing
+
+	// PasteImage reads an image from the system clipboard when the user
+	// presses Ctrl+V, attaching it to the next prompt. Nil disables image
+	// paste.
+	PasteImage func() (pipe.ImageBlock, error)
+
+	// RawRequest returns the provider request, as sent, that produced turn
+	// number turn, counting the turns completed since the program started
+	// from 0. Ctrl+R shows it for the focused block's turn. Nil disables
+	// the view.
+	RawRequest func(turn int) (string, bool)
+
+	// InputHeight is how many lines the input grows to in the full layout,
+	// at most as many as leave room for the output. Zero means 3. A
+	// command changes it with CommandResult.InputHeight.
+	InputHeight int
+
+	// Paths shortens the paths shown in tool blocks; Alt+P toggles between
+	// them and full paths.
+	Paths PathDisplay
+
+	// PasteGap is the longest pause between keys that still counts as one
+	// paste, so an Enter within it of text adds a newline instead of
+	// sending the input; see DefaultPasteGap. Zero sends on every Enter.
+	PasteGap time.Duration
+
+	// Mouse turns on mouse reporting: the wheel scrolls the output and
+	// clicking the first line of a collapsible block toggles it. Most
+	// terminals then select text only while Shift is held.
+	Mouse bool
+}
+
+const (
+	// defaultInputHeight is how many lines the input grows to in the full
+	// layout unless Config.InputHeight says otherwise.
+	defaultInputHeight = 3
+	// compactHeight is the terminal height below which the status area
+	// collapses to a single-character indicator.
+	compactHeight = 8
+	// minViewportHeight is the fewest output lines the compact layout must
+	// fit before the view gives up and asks for a larger terminal.
+	minViewportHeight = 3
+)
+
+// GitStatus is the state of the git repository shown in the status bar.
+type GitStatus struct {
+	Branch string // empty if not in a repo
+	Dirty  bool   // the work tree has uncommitted changes
 }
 
+// gitStatusMsg carries the state found by Config.DetectGit or
+// Config.DetectGitBranch.
+type gitStatusMsg struct{ status GitStatus }
+
+// noticeMsg carries a message received on Config.Notices.
+type noticeMsg struct{ text string }
+
+// DefaultRenderInterval redraws a streaming response at most 30 times a
+// second, fast enough to read as smooth while a provider sending thousands
+// of small deltas does not redraw the viewport for each.
+const DefaultRenderInterval = time.Second / 30
+
+// maxEventBatch caps the events listenForEvent hands to one update, so a
+// flood of deltas cannot hold off key presses for long.
+const maxEventBatch = 256
+
+// renderTickMsg triggers a coalesced viewport re-render.
+type renderTickMsg struct{}
+
+// streamEventsMsg carries events that were already waiting when
+// listenForEvent woke, to be applied in one update.
+type streamEventsMsg struct{ events []pipe.Event }
+
+// heartbeatInterval is how often the status bar refreshes while a run is in
+// progress, independent of stream events.
+const heartbeatInterval = time.Second
+
+// heartbeatMsg refreshes the status bar during run number run. Heartbeats
+// from earlier runs are dropped.
+type heartbeatMsg struct{ run int }
+
 // Model is the Bubble Tea model for the pipe TUI.
 type Model struct {
 	// Input is the multi-line text input component. Exported for test access.
@@ -39,7 +186,7 @@ type Model struct {
 	config  Config
 
 	blocks     []MessageBlock
-	blockFocus int // index of focused collapsible block (-1 = none)
+	blockFocus int // index of focused block (-1 = none)
 
 	// Active block maps for event correlation within the current turn.
 	// Text/thinking indices restart at 0 each assistant turn. Tool call
@@ -47,6 +194,7 @@ type Model struct {
 	activeText     map[int]*AssistantTextBlock // keyed by EventTextDelta.Index
 	activeThinking map[int]*ThinkingBlock      // keyed by EventThinkingDelta.Index
 	activeToolCall map[string]*ToolCallBlock   // keyed by EventToolCall*.ID
+	activeSources  map[int]*SourcesBlock       // keyed by EventCitation.Index
 
 	// hadToolCalls is set on EventToolCallBegin. When text/thinking arrives
 	// after tool calls, it signals a new assistant turn — the text and
@@ -56,8 +204,64 @@ type Model struct {
 
 	windowHeight int // stored for viewport recomputation on InputHeightMsg
 
+	renderPending bool // a renderTickMsg is scheduled
+	scrollPending bool // the next streaming render scrolls to the bottom
+
+	meter    streamMeter      // throughput and liveness of the current run
+	now      func() time.Time // clock for run timing; replaced in tests
+	runCount int              // number of the current or last run
+	runStart time.Time
+	runFirst int // index of the run's first session message after the prompt
+
+	// lastText is when the last text key arrived, to tell a paste from
+	// typing; see pasteKey.
+	lastText time.Time
+
+	// usage is the session's usage when it was opened plus that of every
+	// turn completed since, including turns compaction later summarizes.
+	usage pipe.Usage
+
+	// question is the ask_user request awaiting an answer, if any. While set,
+	// Enter sends the input to the tool instead of starting a new run.
+	question      *askRequest
+	questionBlock *QuestionBlock
+
+	// approval is the tool call awaiting the user's decision, if any. While
+	// set, y, n, and a decide it and other keys are ignored.
+	approval      *approvalRequest
+	approvalBlock *ApprovalBlock
+
 	allExpanded bool
 
+	// attachments are images sent with the next prompt, shown as chips
+	// above the input.
+	attachments []pipe.ImageBlock
+
+	// queued holds prompts submitted during a run. They are sent together
+	// as the next prompt when the run ends.
+	queued []string
+
+	segments []string // latest text of each status segment
+
+	// outline replaces the viewport with a list of blocks to jump to.
+	outline       bool
+	outlineCursor int // index of the selected block
+
+	// pager, when set, replaces the viewport with a scrollable text such
+	// as a raw request.
+	pager *pager
+
+	// search, when set, highlights matches of a query in the viewport and
+	// takes the keys for moving between them.
+	search *search
+
+	// turns counts the turns completed since the program started. The
+	// assistant blocks of each are recorded in blockTurn when it
+	// completes; turnStart is the first block of the current turn.
+	turns     int
+	turnStart int
+	blockTurn map[MessageBlock]int
+
 	spinner spinner.Model
 	running bool
 	cancel  context.CancelFunc
@@ -65,12 +269,16 @@ type Model struct {
 	doneCh  chan error
 	err     error
 	ready   bool
+
+	// interrupted is set when the user cancels the run; the run then ends
+	// with an InterruptedBlock instead of an error.
+	interrupted bool
 }
 
 // New creates a new TUI Model with the given agent function, session, theme, and config.
 func New(run AgentFunc, session *pipe.Session, theme pipe.Theme, config Config) Model {
 	ta := textarea.New()
-	ta.MaxHeight = 3
+	ta.MaxHeight = Model{config: config}.inputMaxHeight()
 	// Defensive fallback: handleKey intercepts Enter at line 225 before the
 	// textarea sees it, so this callback is normally never invoked. It exists
 	// as a safety net — if a code path ever lets Enter through, this prevents
@@ -94,6 +302,10 @@ func New(run AgentFunc, session *pipe.Session, theme pipe.Theme, config Config)
 		activeText:     make(map[int]*AssistantTextBlock),
 		activeThinking: make(map[int]*ThinkingBlock),
 		activeToolCall: make(map[string]*ToolCallBlock),
+		activeSources:  make(map[int]*SourcesBlock),
+		blockTurn:      make(map[MessageBlock]int),
+		now:            time.Now,
+		usage:          session.TotalUsage(),
 	}
 }
 
@@ -119,7 +331,55 @@ func SetRunningWithCancel(m Model, cancel func()) (Model, tea.Cmd) {
 
 // Init implements tea.Model.
 func (m Model) Init() tea.Cmd {
-	return cursor.Blink
+	cmds := []tea.Cmd{cursor.Blink}
+	if detect := m.detectGit(); detect != nil {
+		cmds = append(cmds, detect)
+	}
+	if m.config.Notices != nil {
+		cmds = append(cmds, listenForNotice(m.config.Notices))
+	}
+	for i, seg := range m.config.StatusSegments {
+		cmds = append(cmds, refreshSegment(i, seg, 0))
+	}
+	return tea.Batch(cmds...)
+}
+
+// handleEvents applies streamed events, redraws the viewport now or on the
+// next render tick, and listens for more.
+func (m Model) handleEvents(events ...pipe.Event) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	for _, evt := range events {
+		m.meter = m.meter.observe(evt, m.now())
+		m = m.processEvent(evt)
+		if m.config.Follow.follows(evt) {
+			m.scrollPending = true
+		}
+	}
+	switch {
+	case m.config.RenderInterval <= 0:
+		m = m.renderStream()
+	case !m.renderPending:
+		m.renderPending = true
+		cmds = append(cmds, tea.Tick(m.config.RenderInterval, func(time.Time) tea.Msg {
+			return renderTickMsg{}
+		}))
+	}
+	if m.eventCh != nil {
+		cmds = append(cmds, listenForEvent(m.eventCh, m.doneCh, m.questions(), m.approvals()))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// detectGit returns a command that looks up the git status in the
+// background, or nil when the config sets no detector.
+func (m Model) detectGit() tea.Cmd {
+	if detect := m.config.DetectGit; detect != nil {
+		return func() tea.Msg { return gitStatusMsg{status: detect()} }
+	}
+	if detect := m.config.DetectGitBranch; detect != nil {
+		return func() tea.Msg { return gitStatusMsg{status: GitStatus{Branch: detect()}} }
+	}
+	return nil
 }
 
 // Update implements tea.Model.
@@ -131,6 +391,33 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m = m.handleWindowSize(msg)
 		return m, nil
 
+	case gitStatusMsg:
+		m.config.GitBranch = msg.status.Branch
+		m.config.GitDirty = msg.status.Dirty
+		return m, nil
+
+	case segmentMsg:
+		if m.segments == nil {
+			m.segments = make([]string, len(m.config.StatusSegments))
+		}
+		m.segments[msg.index] = msg.text
+		if seg := m.config.StatusSegments[msg.index]; seg.Interval > 0 {
+			return m, refreshSegment(msg.index, seg, seg.Interval)
+		}
+		return m, nil
+
+	case pastedImageMsg:
+		if msg.err != nil {
+			m.blocks = append(m.blocks, NewErrorBlock(fmt.Errorf("paste image: %w", msg.err), m.styles))
+			return m.refreshViewport(), nil
+		}
+		return m.attach(msg.image), nil
+
+	case noticeMsg:
+		m.blocks = append(m.blocks, NewNoticeBlock(msg.text, m.styles))
+		m = m.refreshViewport()
+		return m, listenForNotice(m.config.Notices)
+
 	case textarea.InputHeightMsg:
 		if m.windowHeight == 0 {
 			return m, nil
@@ -141,15 +428,43 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		return m.handleKey(msg)
 
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
+
 	case StreamEventMsg:
-		m = m.processEvent(msg.Event)
-		m.Viewport.SetContent(m.renderContent())
-		m.Viewport.GotoBottom()
+		return m.handleEvents(msg.Event)
+
+	case streamEventsMsg:
+		return m.handleEvents(msg.events...)
+
+	case questionMsg:
+		m = m.askQuestion(msg.req)
+		cmds = append(cmds, m.Input.Focus())
+		if m.eventCh != nil {
+			cmds = append(cmds, listenForEvent(m.eventCh, m.doneCh, m.questions(), m.approvals()))
+		}
+		return m, tea.Batch(cmds...)
+
+	case approvalMsg:
+		m = m.askApproval(msg.req)
 		if m.eventCh != nil {
-			return m, listenForEvent(m.eventCh, m.doneCh)
+			cmds = append(cmds, listenForEvent(m.eventCh, m.doneCh, m.questions(), m.approvals()))
 		}
+		return m, tea.Batch(cmds...)
+
+	case renderTickMsg:
+		m.renderPending = false
+		m = m.renderStream()
 		return m, nil
 
+	case heartbeatMsg:
+		if !m.running || msg.run != m.runCount {
+			return m, nil
+		}
+		// Returning redraws the status bar (elapsed time, stream health) even
+		// when no events arrive.
+		return m, heartbeat(m.runCount)
+
 	case spinner.TickMsg:
 		if m.running {
 			var cmd tea.Cmd
@@ -163,11 +478,43 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.cancel = nil
 		m.eventCh = nil
 		m.doneCh = nil
-		if msg.Err != nil && !errors.Is(msg.Err, context.Canceled) {
+		m.question = nil
+		m.questionBlock = nil
+		m.approval = nil
+		m.approvalBlock = nil
+		if msg.Err != nil && !m.interrupted && !errors.Is(msg.Err, context.Canceled) {
 			m.err = msg.Err
 		}
+		// Flush any render still waiting on a coalescing tick.
+		refresh := m.renderPending
+		if m.interrupted {
+			m.interrupted = false
+			m.blocks = append(m.blocks, NewInterruptedBlock(m.styles))
+			refresh = true
+		}
+		var changes string
+		if m.config.RunSummary != nil {
+			changes = m.config.RunSummary()
+		}
+		if stats := m.runStats(changes); stats.Turns > 0 {
+			m.blocks = append(m.blocks, NewRunSummaryBlock(stats, m.config.SummaryActions, m.config.Locale, m.styles))
+			refresh = true
+		} else if changes != "" {
+			m.blocks = append(m.blocks, NewNoticeBlock(changes, m.styles))
+			refresh = true
+		}
 		m = m.updateBlockFocus()
-		cmd := m.Input.Focus()
+		if refresh {
+			m.scrollPending = m.scrollPending || m.config.Follow != FollowManual
+			m = m.renderStream()
+		}
+		cmds = append(cmds, m.Input.Focus())
+		if m.err != nil {
+			// Don't pile more work onto a failed run.
+			m = m.unqueue()
+		}
+		var cmd tea.Cmd
+		m, cmd = m.sendQueued()
 		cmds = append(cmds, cmd)
 		return m, tea.Batch(cmds...)
 
@@ -178,13 +525,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.Viewport, cmd = m.Viewport.Update(msg)
 	cmds = append(cmds, cmd)
 
-	if m.running {
+	if m.running && m.question == nil {
 		m.spinner, cmd = m.spinner.Update(msg)
 		cmds = append(cmds, cmd)
-	} else {
-		m.Input, cmd = m.Input.Update(msg)
-		cmds = append(cmds, cmd)
 	}
+	m.Input, cmd = m.Input.Update(msg)
+	cmds = append(cmds, cmd)
 
 	return m, tea.Batch(cmds...)
 }
@@ -195,30 +541,93 @@ func (m Model) View() string {
 		return "Initializing..."
 	}
 
+	if m.tooSmall() {
+		return truncateRight(m.styles.Muted.Render("terminal too small"), m.Viewport.Width)
+	}
+
+	output := m.Viewport.View()
+	switch {
+	case m.pager != nil:
+		output = m.pagerView()
+	case m.outline:
+		output = m.outlineView()
+	}
+
+	if m.compact() {
+		// Short terminal: no separators or status line, just a one-character
+		// indicator in front of the input.
+		if m.search != nil {
+			return output + "\n" + m.searchLine()
+		}
+		return output + "\n" + m.indicator() + " " + m.Input.View()
+	}
+
 	sep := strings.Repeat("─", m.Viewport.Width)
 
 	var b strings.Builder
 
 	// Output area.
-	b.WriteString(m.Viewport.View())
+	b.WriteString(output)
 	b.WriteString("\n")
 
 	// Status bar with separators.
 	b.WriteString(sep)
 	b.WriteString("\n")
-	b.WriteString(m.statusLine())
+	if m.search != nil {
+		b.WriteString(m.searchLine())
+	} else {
+		b.WriteString(m.statusLine())
+	}
 	b.WriteString("\n")
 	b.WriteString(sep)
 	b.WriteString("\n")
 
 	// Input area.
+	if m.queueHeight() > 0 {
+		b.WriteString(m.queueView())
+		b.WriteString("\n")
+	}
+	if m.attachmentHeight() > 0 {
+		b.WriteString(m.attachmentView())
+		b.WriteString("\n")
+	}
 	b.WriteString(m.Input.View())
 
 	return b.String()
 }
 
+// refreshViewport re-renders all blocks into the viewport and scrolls to the
+// bottom.
+func (m Model) refreshViewport() Model {
+	m.Viewport.SetContent(m.renderContent())
+	m.Viewport.GotoBottom()
+	return m
+}
+
+// renderStream re-renders all blocks into the viewport, scrolling to the
+// bottom only when an update since the last render called for it under the
+// follow mode.
+func (m Model) renderStream() Model {
+	m.Viewport.SetContent(m.renderContent())
+	// Stay on the search results; the scroll waits until the search closes.
+	if m.scrollPending && m.search == nil {
+		m.Viewport.GotoBottom()
+		m.scrollPending = false
+	}
+	return m
+}
+
 func (m Model) handleWindowSize(msg tea.WindowSizeMsg) Model {
 	m.windowHeight = msg.Height
+	inputW := msg.Width
+	if m.compact() {
+		m.Input.MaxHeight = 1
+		m.Input.SetHeight(1)
+		inputW -= 2 // indicator and space
+	} else if h := m.inputMaxHeight(); m.Input.MaxHeight != h {
+		m.Input.MaxHeight = h
+		m.Input.SetHeight(min(m.Input.LineCount(), h))
+	}
 	vpHeight := m.viewportHeight(m.Input.Height())
 
 	if !m.ready {
@@ -234,14 +643,71 @@ func (m Model) handleWindowSize(msg tea.WindowSizeMsg) Model {
 		m.Viewport.SetContent(m.renderContent())
 	}
 
-	m.Input.SetWidth(msg.Width)
+	m.Input.SetWidth(max(inputW, 1))
 	return m
 }
 
+// compact reports whether the terminal is too short for the full status
+// area, in which case the layout drops the separators and status line.
+func (m Model) compact() bool {
+	return m.windowHeight > 0 && m.windowHeight < compactHeight
+}
+
+// tooSmall reports whether even the compact layout cannot fit
+// minViewportHeight lines of output above the input.
+func (m Model) tooSmall() bool {
+	return m.compact() && m.windowHeight-m.Input.Height() < minViewportHeight
+}
+
+// indicator is the one-character stand-in for the status line in the compact
+// layout: the spinner while running, "!" after an error, "·" when idle.
+func (m Model) indicator() string {
+	switch {
+	case m.running:
+		return truncateRight(m.spinner.View(), 1) // drop the frame's padding
+	case m.err != nil:
+		return m.styles.Error.Render("!")
+	default:
+		return m.styles.Muted.Render("·")
+	}
+}
+
 // viewportHeight computes the viewport height given the current input height.
+// inputMaxHeight is how many lines the input grows to in the full layout:
+// Config.InputHeight, capped to leave minViewportHeight lines of output
+// once the window size is known.
+func (m Model) inputMaxHeight() int {
+	h := m.config.InputHeight
+	if h <= 0 {
+		h = defaultInputHeight
+	}
+	if m.windowHeight > 0 {
+		h = min(h, max(m.windowHeight-3-minViewportHeight, 1)) // 3: status area
+	}
+	return h
+}
+
+// setInputHeight changes Config.InputHeight and resizes the input and the
+// viewport to match.
+func (m Model) setInputHeight(h int) Model {
+	m.config.InputHeight = h
+	if m.compact() {
+		return m
+	}
+	m.Input.MaxHeight = m.inputMaxHeight()
+	m.Input.SetHeight(min(max(m.Input.LineCount(), 1), m.Input.MaxHeight))
+	if m.windowHeight > 0 {
+		m.Viewport.Height = m.viewportHeight(m.Input.Height())
+	}
+	return m
+}
+
 func (m Model) viewportHeight(inputH int) int {
-	const statusHeight = 3 // separator + status + separator
-	h := m.windowHeight - inputH - statusHeight
+	statusHeight := 3 // separator + status + separator
+	if m.compact() {
+		statusHeight = 0
+	}
+	h := m.windowHeight - inputH - statusHeight - m.queueHeight() - m.attachmentHeight()
 	if h < 1 {
 		h = 1
 	}
@@ -249,37 +715,72 @@ func (m Model) viewportHeight(inputH int) int {
 }
 
 func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.pager != nil && msg.Type != tea.KeyCtrlC {
+		return m.handlePagerKey(msg)
+	}
+	if m.outline && msg.Type != tea.KeyCtrlC {
+		return m.handleOutlineKey(msg)
+	}
+	if m.search != nil && msg.Type != tea.KeyCtrlC {
+		return m.handleSearchKey(msg)
+	}
+	if m.approval != nil && msg.Type != tea.KeyCtrlC && msg.Type != tea.KeyEsc {
+		if d, ok := approvalDecision(msg.String()); ok {
+			return m.decideApproval(d), nil
+		}
+		return m, nil
+	}
+	if !m.running && m.blockFocus >= 0 && m.blockFocus < len(m.blocks) {
+		if summary, ok := m.blocks[m.blockFocus].(*RunSummaryBlock); ok {
+			if a, ok := summary.action(msg.String()); ok {
+				return m.showResult(a.Run(summary.Text())), nil
+			}
+		}
+	}
+
+	m, msg = m.pasteKey(msg)
 	switch msg.Type {
 	case tea.KeyCtrlC:
 		if m.running {
-			if m.cancel != nil {
-				m.cancel()
-			}
-			return m, nil
+			return m.interrupt().unqueue(), nil
 		}
 		return m, tea.Quit
 
-	case tea.KeyEnter:
+	case tea.KeyEsc:
 		if m.running {
-			return m, nil
+			return m.interrupt().unqueue(), nil
 		}
+
+	case tea.KeyEnter:
 		text := strings.TrimSpace(m.Input.Value())
+		if m.question != nil {
+			if text == "" {
+				return m, nil
+			}
+			return m.answerQuestion(text), nil
+		}
 		if text == "" {
 			return m, nil
 		}
+		if m.running {
+			if _, _, ok := m.lookupCommand(text); ok {
+				m.blocks = append(m.blocks, NewNoticeBlock("Commands run when the agent is idle; press Enter again once the run ends.", m.styles))
+				return m.refreshViewport(), nil
+			}
+			m = m.queueInput(text)
+			if msg.Alt {
+				m = m.interrupt()
+			}
+			return m, nil
+		}
+		if c, args, ok := m.lookupCommand(text); ok {
+			return m.runCommand(c, args)
+		}
 		return m.submitInput(text)
 
 	case tea.KeyTab:
 		if !m.running && m.blockFocus >= 0 && m.blockFocus < len(m.blocks) {
-			// Error results never collapse, so skip the toggle entirely.
-			if tr, ok := m.blocks[m.blockFocus].(*ToolResultBlock); ok && tr.IsError() {
-				return m, nil
-			}
-			block, cmd := m.blocks[m.blockFocus].Update(ToggleMsg{})
-			m.blocks[m.blockFocus] = block
-			m.allExpanded = false
-			m.Viewport.SetContent(m.renderContent())
-			return m, cmd
+			return m.toggleBlock(m.blockFocus)
 		}
 		return m, nil
 
@@ -290,6 +791,41 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case tea.KeyCtrlL:
+		return m.openOutline(), nil
+
+	case tea.KeyCtrlF:
+		return m.openSearch(), nil
+
+	case tea.KeyCtrlR:
+		if m.config.RawRequest == nil {
+			return m, nil
+		}
+		return m.showRawRequest(), nil
+
+	case tea.KeyCtrlV:
+		if m.config.PasteImage == nil {
+			return m, nil
+		}
+		return m, pasteImage(m.config.PasteImage)
+
+	case tea.KeyBackspace:
+		if m.Input.Value() == "" && len(m.attachments) > 0 {
+			return m.detachLast(), nil
+		}
+
+	case tea.KeyRunes:
+		if msg.Alt && string(msg.Runes) == "p" {
+			return m.togglePaths(), nil
+		}
+
+	case tea.KeyCtrlG:
+		m.config.Follow = m.config.Follow.next()
+		if m.config.Follow != FollowManual {
+			m.Viewport.GotoBottom()
+		}
+		return m, nil
+
 	case tea.KeyCtrlO:
 		m.allExpanded = !m.allExpanded
 		setMsg := SetCollapsedMsg{Collapsed: !m.allExpanded}
@@ -313,42 +849,100 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(cmds...)
 	}
 
-	// When idle, pass keys to both textarea (for typing) and viewport
-	// (for scrolling). Only forward non-character keys to viewport to avoid
-	// conflicts (e.g. 'j'/'k' are viewport scroll AND text characters).
-	if !m.running {
-		var cmd tea.Cmd
-		var cmds []tea.Cmd
-
-		if msg.Type != tea.KeyRunes {
-			m.Viewport, cmd = m.Viewport.Update(msg)
-			cmds = append(cmds, cmd)
-		}
+	// Pass other keys to both textarea (for typing, including a prompt to
+	// queue during a run) and viewport (for scrolling). Only forward
+	// non-character keys to viewport to avoid conflicts (e.g. 'j'/'k' are
+	// viewport scroll AND text characters).
+	var cmd tea.Cmd
+	var cmds []tea.Cmd
 
-		m.Input, cmd = m.Input.Update(msg)
+	if msg.Type != tea.KeyRunes {
+		m.Viewport, cmd = m.Viewport.Update(msg)
 		cmds = append(cmds, cmd)
+	}
 
-		return m, tea.Batch(cmds...)
+	m.Input, cmd = m.Input.Update(msg)
+	cmds = append(cmds, cmd)
+
+	return m, tea.Batch(cmds...)
+}
+
+// builtinKey reports whether handleKey binds key, as reported by
+// tea.KeyMsg.String, to one of the model's own actions.
+func builtinKey(key string) bool {
+	switch key {
+	case "ctrl+c", "esc", "enter", "tab", "shift+tab", "backspace", "alt+p",
+		"ctrl+l", "ctrl+f", "ctrl+r", "ctrl+v", "ctrl+g", "ctrl+o":
+		return true
 	}
+	return false
+}
 
-	return m, nil
+// toggleBlock collapses or expands block i.
+func (m Model) toggleBlock(i int) (tea.Model, tea.Cmd) {
+	// Error results never collapse, so skip the toggle entirely.
+	if tr, ok := m.blocks[i].(*ToolResultBlock); ok && tr.IsError() {
+		return m, nil
+	}
+	block, cmd := m.blocks[i].Update(ToggleMsg{})
+	m.blocks[i] = block
+	m.allExpanded = false
+	m.Viewport.SetContent(m.renderContent())
+	return m, cmd
+}
+
+// handleMouse scrolls with the wheel and toggles a collapsible block when
+// its first line is clicked. Like Tab, clicks are ignored during a run.
+func (m Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.pager != nil {
+		return m.handlePagerMouse(msg), nil
+	}
+	if m.outline {
+		return m, nil
+	}
+	if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+		i := m.headerAt(msg.Y)
+		if m.running || i < 0 || !isCollapsible(m.blocks[i]) {
+			return m, nil
+		}
+		m.blockFocus = i
+		return m.toggleBlock(i)
+	}
+	var cmd tea.Cmd
+	m.Viewport, cmd = m.Viewport.Update(msg)
+	return m, cmd
 }
 
 func (m Model) submitInput(text string) (tea.Model, tea.Cmd) {
 	m.Input.SetValue("")
 	m.Input.SetHeight(1)
+	images := m.attachments
+	m.attachments = nil
 	m.Viewport.Height = m.viewportHeight(1)
-	m.err = nil
 
 	// Append user message to session.
 	userMsg := pipe.UserMessage{
 		Content:   []pipe.ContentBlock{pipe.TextBlock{Text: text}},
 		Timestamp: time.Now(),
 	}
+	for _, img := range images {
+		userMsg.Content = append(userMsg.Content, img)
+	}
 	m.session.Messages = append(m.session.Messages, userMsg)
 
 	// Add user message block.
 	m.blocks = append(m.blocks, NewUserMessageBlock(text, m.styles))
+	for _, img := range images {
+		m.blocks = append(m.blocks, NewImageBlock(img, m.config.Images, m.styles))
+	}
+	return m.startRun()
+}
+
+// startRun runs the agent on the session as it stands.
+func (m Model) startRun() (tea.Model, tea.Cmd) {
+	m.err = nil
+	m.runFirst = len(m.session.Messages)
+	m.turnStart = len(m.blocks)
 	m.Viewport.SetContent(m.renderContent())
 	m.Viewport.GotoBottom()
 
@@ -361,24 +955,126 @@ func (m Model) submitInput(text string) (tea.Model, tea.Cmd) {
 	m.eventCh = make(chan pipe.Event, 256)
 	m.doneCh = make(chan error, 1)
 	m.running = true
-
-	m.Input.Blur()
+	m.runCount++
+	m.runStart = m.now()
+	m.meter = newStreamMeter(m.runStart)
 
 	return m, tea.Batch(
 		m.spinner.Tick,
+		heartbeat(m.runCount),
 		startAgent(m.run, ctx, m.session, m.eventCh, m.doneCh),
-		listenForEvent(m.eventCh, m.doneCh),
+		listenForEvent(m.eventCh, m.doneCh, m.questions(), m.approvals()),
 	)
 }
 
+// interrupt cancels the current run. The agent keeps the partial response
+// in the session, and the run ends with an InterruptedBlock.
+func (m Model) interrupt() Model {
+	if m.cancel != nil {
+		m.cancel()
+		m.interrupted = true
+	}
+	return m
+}
+
+// runStats summarizes the session messages added by the run that just
+// finished. The agent goroutine has exited, so the session is safe to read.
+func (m Model) runStats(changes string) RunStats {
+	stats := RunStats{Elapsed: m.now().Sub(m.runStart), Changes: changes}
+	if m.runFirst > len(m.session.Messages) {
+		return stats
+	}
+	msgs := m.session.Messages[m.runFirst:]
+	stats.Usage = pipe.SumUsage(msgs)
+	for _, msg := range msgs {
+		am, ok := msg.(pipe.AssistantMessage)
+		if !ok {
+			continue
+		}
+		stats.Turns++
+		stats.StopReason = am.StopReason
+		for _, b := range am.Content {
+			if _, ok := b.(pipe.ToolCallBlock); ok {
+				stats.ToolCalls++
+			}
+		}
+	}
+	return stats
+}
+
+// questions returns the channel ask_user requests arrive on, or nil when no
+// Asker is configured (a nil channel never delivers).
+func (m Model) questions() <-chan askRequest {
+	if m.config.Asker == nil {
+		return nil
+	}
+	return m.config.Asker.requests
+}
+
+// askQuestion shows an ask_user question and hands the input to the user.
+func (m Model) askQuestion(req askRequest) Model {
+	m.question = &req
+	m.questionBlock = NewQuestionBlock(req.question, req.options, m.styles)
+	m.blocks = append(m.blocks, m.questionBlock)
+	return m.refreshViewport()
+}
+
+// answerQuestion sends the user's answer to the waiting ask_user tool call.
+func (m Model) answerQuestion(text string) Model {
+	answer := resolveAnswer(text, m.question.options)
+	m.question.reply <- answer
+	m.questionBlock.SetAnswer(answer)
+	m.question = nil
+	m.questionBlock = nil
+
+	m.Input.SetValue("")
+	m.Input.SetHeight(1)
+	m.Viewport.Height = m.viewportHeight(1)
+	return m.refreshViewport()
+}
+
+// approvals returns the channel tool calls awaiting approval arrive on, or
+// nil when no Approver is configured.
+func (m Model) approvals() <-chan approvalRequest {
+	if m.config.Approver == nil {
+		return nil
+	}
+	return m.config.Approver.requests
+}
+
+// askApproval shows a tool call or request awaiting approval; the run is
+// paused until the user decides.
+func (m Model) askApproval(req approvalRequest) Model {
+	m.approval = &req
+	if req.cost != nil {
+		m.approvalBlock = newCostApprovalBlock(*req.cost, m.config.Locale, m.styles)
+	} else {
+		m.approvalBlock = NewApprovalBlock(req.call, m.styles)
+	}
+	m.blocks = append(m.blocks, m.approvalBlock)
+	return m.refreshViewport()
+}
+
+// decideApproval sends the user's decision to the waiting tool call.
+func (m Model) decideApproval(d pipe.Decision) Model {
+	m.approval.reply <- d
+	m.approvalBlock.SetDecision(d)
+	m.approval = nil
+	m.approvalBlock = nil
+	return m.refreshViewport()
+}
+
 // renderSession creates blocks from existing session messages.
 func (m Model) renderSession() Model {
 	for _, msg := range m.session.Messages {
 		switch msg := msg.(type) {
 		case pipe.UserMessage:
 			for _, b := range msg.Content {
-				if tb, ok := b.(pipe.TextBlock); ok {
-					m.blocks = append(m.blocks, NewUserMessageBlock(tb.Text, m.styles))
+				switch cb := b.(type) {
+				case pipe.TextBlock:
+					m.blocks = append(m.blocks, NewUserMessageBlock(cb.Text, m.styles))
+				case pipe.ImageBlock:
+					m.blocks = append(m.blocks, NewImageBlock(cb, m.config.Images, m.styles))
 				}
 			}
 		case pipe.AssistantMessage:
@@ -388,30 +1084,71 @@ func (m Model) renderSession() Model {
 					block := NewAssistantTextBlock(m.theme)
 					block.Append(cb.Text)
 					m.blocks = append(m.blocks, block)
+					if len(cb.Citations) > 0 {
+						sources := NewSourcesBlock(m.styles)
+						for _, c := range cb.Citations {
+							block.AddFootnote(sources.Add(c))
+						}
+						m.blocks = append(m.blocks, sources)
+					}
 				case pipe.ThinkingBlock:
 					block := NewThinkingBlock(m.styles)
 					block.Append(cb.Thinking)
 					m.blocks = append(m.blocks, block)
 				case pipe.ToolCallBlock:
+					if custom, ok := m.customToolCall(cb); ok {
+						m.blocks = append(m.blocks, custom)
+						break
+					}
 					block := NewToolCallBlock(cb.Name, cb.ID, m.styles)
+					_, _ = block.Update(SetPathDisplayMsg{Paths: m.config.Paths})
 					block.FinalizeWithCall(cb)
 					m.blocks = append(m.blocks, block)
+				case pipe.ServerToolCallBlock:
+					block := NewToolCallBlock(cb.Name, cb.ID, m.styles)
+					_, _ = block.Update(SetPathDisplayMsg{Paths: m.config.Paths})
+					block.AppendArgs(serverToolCallText(cb.Arguments))
+					m.blocks = append(m.blocks, block)
+				case pipe.ServerToolResultBlock:
+					m.blocks = append(m.blocks, NewToolResultBlock(cb.Name, serverToolResultText(cb.Content), cb.IsError, m.styles))
 				}
 			}
+			if msg.StopReason == pipe.StopAborted {
+				m.blocks = append(m.blocks, NewInterruptedBlock(m.styles))
+			}
 		case pipe.ToolResultMessage:
 			var content strings.Builder
+			var images []MessageBlock
 			for _, b := range msg.Content {
-				if tb, ok := b.(pipe.TextBlock); ok {
-					content.WriteString(tb.Text)
+				switch cb := b.(type) {
+				case pipe.TextBlock:
+					content.WriteString(cb.Text)
+				case pipe.ImageBlock:
+					images = append(images, NewImageBlock(cb, m.config.Images, m.styles))
 				}
 			}
-			m.blocks = append(m.blocks, NewToolResultBlock(msg.ToolName, content.String(), msg.IsError, m.styles))
+			m.blocks = append(m.blocks, m.toolResultBlock(msg.ToolName, content.String(), msg.IsError))
+			m.blocks = append(m.blocks, images...)
+		case pipe.OpaqueMessage:
+			m.blocks = append(m.blocks, NewNoticeBlock(fmt.Sprintf("Skipped a %q message from a newer version of pipe.", msg.Type), m.styles))
 		}
 	}
 	return m
 }
 
+// renderContent renders the transcript with any search matches
+// highlighted.
 func (m Model) renderContent() string {
+	content := m.renderTranscript()
+	if m.search != nil && len(m.blocks) > 0 {
+		content = highlightMatches(content, findMatches(content, m.search.pattern()), m.search.current, m.styles)
+	}
+	return content
+}
+
+// renderTranscript renders all blocks, or the welcome view when there are
+// none.
+func (m Model) renderTranscript() string {
 	if len(m.blocks) == 0 {
 		return m.welcomeView()
 	}
@@ -425,20 +1162,42 @@ func (m Model) renderContent() string {
 	return b.String()
 }
 
-// isCollapsible reports whether b is a collapsible block (thinking, tool call, or tool result).
+// headerAt returns the index of the block whose first line is shown on row
+// y of the viewport, or -1 if none is.
+func (m Model) headerAt(y int) int {
+	if y < 0 || y >= m.Viewport.Height {
+		return -1
+	}
+	line := m.Viewport.YOffset + y
+	i, found := slices.BinarySearch(m.blockOffsets(), line)
+	if !found {
+		return -1
+	}
+	return i
+}
+
+// isCollapsible reports whether b is a collapsible block (thinking, tool call,
+// tool result, or sources).
 func isCollapsible(b MessageBlock) bool {
 	switch b.(type) {
-	case *ThinkingBlock, *ToolCallBlock, *ToolResultBlock:
+	case *ThinkingBlock, *ToolCallBlock, *ToolResultBlock, *SourcesBlock, customToolBlock:
 		return true
 	default:
 		return false
 	}
 }
 
+// isFocusable reports whether b can take focus: collapsible blocks toggle,
+// and the run summary block offers quick actions.
+func isFocusable(b MessageBlock) bool {
+	_, summary := b.(*RunSummaryBlock)
+	return summary || isCollapsible(b)
+}
+
 // isToolBlock reports whether b is a tool call or tool result block.
 func isToolBlock(b MessageBlock) bool {
 	switch b.(type) {
-	case *ToolCallBlock, *ToolResultBlock:
+	case *ToolCallBlock, *ToolResultBlock, customToolBlock:
 		return true
 	default:
 		return false
@@ -461,6 +1220,7 @@ func (m Model) resetTurnState() Model {
 	m.activeText = make(map[int]*AssistantTextBlock)
 	m.activeThinking = make(map[int]*ThinkingBlock)
 	m.activeToolCall = make(map[string]*ToolCallBlock)
+	m.activeSources = make(map[int]*SourcesBlock)
 	m.hadToolCalls = false
 	return m
 }
@@ -481,6 +1241,28 @@ func (m Model) processEvent(evt pipe.Event) Model {
 			m.activeText[e.Index] = b
 			m = m.updateBlockFocus()
 		}
+	case pipe.EventCitation:
+		if m.hadToolCalls {
+			m = m.resetTurnState()
+		}
+		text, ok := m.activeText[e.Index]
+		if !ok {
+			// Citations may precede the text they support.
+			text = NewAssistantTextBlock(m.theme)
+			m.blocks = append(m.blocks, text)
+			m.activeText[e.Index] = text
+		}
+		sources, ok := m.activeSources[e.Index]
+		if !ok {
+			sources = NewSourcesBlock(m.styles)
+			if m.allExpanded {
+				_, _ = sources.Update(SetCollapsedMsg{Collapsed: false})
+			}
+			m.blocks = append(m.blocks, sources)
+			m.activeSources[e.Index] = sources
+		}
+		text.AddFootnote(sources.Add(e.Citation))
+		m = m.updateBlockFocus()
 	case pipe.EventThinkingDelta:
 		if m.hadToolCalls {
 			m = m.resetTurnState()
@@ -500,6 +1282,7 @@ func (m Model) processEvent(evt pipe.Event) Model {
 	case pipe.EventToolCallBegin:
 		m.hadToolCalls = true
 		b := NewToolCallBlock(e.Name, e.ID, m.styles)
+		_, _ = b.Update(SetPathDisplayMsg{Paths: m.config.Paths})
 		if m.allExpanded {
 			_, _ = b.Update(SetCollapsedMsg{Collapsed: false})
 		}
@@ -513,25 +1296,87 @@ func (m Model) processEvent(evt pipe.Event) Model {
 	case pipe.EventToolCallEnd:
 		if b, ok := m.activeToolCall[e.Call.ID]; ok {
 			b.FinalizeWithCall(e.Call)
+			if custom, ok := m.customToolCall(e.Call); ok {
+				m = m.replaceBlock(b, custom)
+				delete(m.activeToolCall, e.Call.ID)
+			}
 		}
+	case pipe.EventServerToolCall:
+		b := NewToolCallBlock(e.Call.Name, e.Call.ID, m.styles)
+		_, _ = b.Update(SetPathDisplayMsg{Paths: m.config.Paths})
+		if m.allExpanded {
+			_, _ = b.Update(SetCollapsedMsg{Collapsed: false})
+		}
+		b.AppendArgs(serverToolCallText(e.Call.Arguments))
+		m.blocks = append(m.blocks, b)
+		m = m.updateBlockFocus()
+	case pipe.EventServerToolResult:
+		b := NewToolResultBlock(e.Result.Name, serverToolResultText(e.Result.Content), e.Result.IsError, m.styles)
+		if m.allExpanded && !e.Result.IsError {
+			_, _ = b.Update(SetCollapsedMsg{Collapsed: false})
+		}
+		m.blocks = append(m.blocks, b)
+		m = m.updateBlockFocus()
 	case pipe.EventToolResult:
-		b := NewToolResultBlock(e.ToolName, e.Content, e.IsError, m.styles)
+		m.usage = m.usage.Add(e.Usage)
+		b := m.toolResultBlock(e.ToolName, e.Content, e.IsError)
 		if m.allExpanded && !e.IsError {
-			_, _ = b.Update(SetCollapsedMsg{Collapsed: false})
+			b, _ = b.Update(SetCollapsedMsg{Collapsed: false})
 		}
 		m.blocks = append(m.blocks, b)
+		for _, img := range e.Images {
+			m.blocks = append(m.blocks, NewImageBlock(img, m.config.Images, m.styles))
+		}
 		m = m.updateBlockFocus()
+	case pipe.EventRetry:
+		if !e.Resumed {
+			// The response starts over; keep the partial one and stream the
+			// retry into new blocks.
+			m = m.resetTurnState()
+		}
+		notice := fmt.Sprintf("%v — retrying in %s (%d/%d)…", e.Err, e.Delay.Round(100*time.Millisecond), e.Attempt, e.MaxRetries)
+		m.blocks = append(m.blocks, NewNoticeBlock(notice, m.styles))
+	case pipe.EventToolTimeout:
+		notice := fmt.Sprintf("%s ran longer than %s and was cancelled.", e.ToolName, e.Timeout)
+		m.blocks = append(m.blocks, NewNoticeBlock(notice, m.styles))
+	case pipe.EventTurnComplete:
+		m.usage = m.usage.Add(e.Usage)
+		m = m.completeTurn()
+	case pipe.EventCompaction:
+		notice := fmt.Sprintf("Compacted %d earlier messages into a summary (context was %s tokens).",
+			e.Messages, m.config.Locale.Int(e.TokensBefore))
+		m.blocks = append(m.blocks, NewNoticeBlock(notice, m.styles))
+	case pipe.EventRunStatus:
+		// A finished run speaks for itself; a blocked one needs the user.
+		if e.Status.State == pipe.RunBlocked {
+			m.blocks = append(m.blocks, NewNoticeBlock("Blocked: "+e.Status.Detail, m.styles))
+		}
+	}
+	return m
+}
+
+// replaceBlock swaps old for replacement in place, carrying over the
+// expanded state when all blocks are expanded.
+func (m Model) replaceBlock(old, replacement MessageBlock) Model {
+	if m.allExpanded {
+		replacement, _ = replacement.Update(SetCollapsedMsg{Collapsed: false})
+	}
+	for i, b := range m.blocks {
+		if b == old {
+			m.blocks[i] = replacement
+			break
+		}
 	}
 	return m
 }
 
-// updateBlockFocus scans backwards to find the last collapsible block.
+// updateBlockFocus scans backwards to find the last focusable block.
 // Only the focused block responds to Tab. ShiftTab cycles to the previous
-// collapsible block. Full arrow-key navigation is deferred to a follow-up.
+// focusable block. Full arrow-key navigation is deferred to a follow-up.
 func (m Model) updateBlockFocus() Model {
 	m.blockFocus = -1
 	for i := len(m.blocks) - 1; i >= 0; i-- {
-		if isCollapsible(m.blocks[i]) {
+		if isFocusable(m.blocks[i]) {
 			m.blockFocus = i
 			return m
 		}
@@ -539,7 +1384,7 @@ func (m Model) updateBlockFocus() Model {
 	return m
 }
 
-// cycleFocusPrev moves blockFocus to the previous collapsible block, wrapping around.
+// cycleFocusPrev moves blockFocus to the previous focusable block, wrapping around.
 func (m Model) cycleFocusPrev() Model {
 	start := m.blockFocus - 1
 	if start < 0 {
@@ -547,7 +1392,7 @@ func (m Model) cycleFocusPrev() Model {
 	}
 	for i := range len(m.blocks) {
 		idx := (start - i + len(m.blocks)) % len(m.blocks)
-		if isCollapsible(m.blocks[idx]) {
+		if isFocusable(m.blocks[idx]) {
 			m.blockFocus = idx
 			return m
 		}
@@ -613,18 +1458,36 @@ func (m Model) statusLine() string {
 		return lipgloss.NewStyle().Width(w).Render(content)
 	}
 
-	// Left: spinner (when running) + working directory + git branch.
+	// Left: spinner and elapsed time (when running) + working directory +
+	// git branch + status segments.
 	left := ""
 	if m.running {
-		left = m.spinner.View() + " "
+		left = m.spinner.View() + " " + m.styles.Muted.Render(formatElapsed(m.now().Sub(m.runStart))) + " "
 	}
 	left += m.styles.Muted.Render(m.config.WorkDir)
 	if m.config.GitBranch != "" {
-		left += m.styles.Muted.Render(" ") + m.styles.Accent.Render(m.config.GitBranch)
+		branch := m.config.GitBranch
+		if m.config.GitDirty {
+			branch += "*"
+		}
+		left += m.styles.Muted.Render(" ") + m.styles.Accent.Render(branch)
 	}
+	left += m.segmentsView()
 
-	// Right: model name.
+	// Right: stream health (when running) + follow mode + session usage +
+	// model name.
 	right := m.styles.Muted.Render(m.config.ModelName)
+	if usage := m.usageView(); usage != "" {
+		right = m.styles.Muted.Render(usage) + "  " + right
+	}
+	if follow := m.config.Follow.label(); follow != "" {
+		right = m.styles.Accent.Render(follow) + "  " + right
+	}
+	if m.running {
+		if health := m.meter.status(m.now(), m.styles); health != "" {
+			right = health + "  " + right
+		}
+	}
 
 	// Layout: left ... right, padded to fill width.
 	// Truncate left and right to fit within available width.
@@ -659,6 +1522,38 @@ func (m Model) statusLine() string {
 	return left + strings.Repeat(" ", gap) + right
 }
 
+// usageView renders the session's token total and, with Config.Pricing,
+// its estimated cost, e.g. "12.3k tokens · $0.42". It is empty before the
+// first request.
+func (m Model) usageView() string {
+	u := m.usage
+	total := u.InputTokens + u.CacheReadTokens + u.CacheWriteTokens + u.OutputTokens
+	if total == 0 {
+		return ""
+	}
+	text := formatTokens(m.config.Locale, total) + " tokens"
+	if p := m.config.Pricing; p != nil {
+		text += " · $" + m.config.Locale.Float(p.Cost(u), 2)
+	}
+	return text
+}
+
+// formatElapsed renders a run duration as "42s" or "3m07s".
+func formatElapsed(d time.Duration) string {
+	secs := int(d.Seconds())
+	if secs < 60 {
+		return fmt.Sprintf("%ds", secs)
+	}
+	return fmt.Sprintf("%dm%02ds", secs/60, secs%60)
+}
+
+// heartbeat schedules the next status bar refresh for run.
+func heartbeat(run int) tea.Cmd {
+	return tea.Tick(heartbeatInterval, func(time.Time) tea.Msg {
+		return heartbeatMsg{run: run}
+	})
+}
+
 // truncateRight truncates an ANSI-styled string to fit within maxWidth visible
 // characters using lipgloss's ANSI-aware width limiting.
 func truncateRight(s string, maxWidth int) string {
@@ -686,15 +1581,58 @@ func startAgent(run AgentFunc, ctx context.Context, session *pipe.Session, event
 	}
 }
 
-// listenForEvent waits for the next event from the channel.
-// When the channel closes, it reads the error from doneCh and returns AgentDoneMsg.
-func listenForEvent(ch <-chan pipe.Event, doneCh <-chan error) tea.Cmd {
+// listenForEvent waits for the next event from the channel, the next
+// ask_user question, or the next tool call awaiting approval. When the
+// event channel closes, it reads the error from doneCh and returns
+// AgentDoneMsg. Events already queued behind the first are delivered with
+// it, so a burst of deltas costs one update.
+func listenForEvent(ch <-chan pipe.Event, doneCh <-chan error, questions <-chan askRequest, approvals <-chan approvalRequest) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case evt, ok := <-ch:
+			if !ok {
+				err := <-doneCh
+				return AgentDoneMsg{Err: err}
+			}
+			events := drainEvents(ch, evt)
+			if len(events) == 1 {
+				return StreamEventMsg{Event: evt}
+			}
+			return streamEventsMsg{events: events}
+		case req := <-questions:
+			return questionMsg{req: req}
+		case req := <-approvals:
+			return approvalMsg{req: req}
+		}
+	}
+}
+
+// drainEvents returns first followed by the events waiting on ch, up to
+// maxEventBatch. It leaves a closed channel for the next listen to report.
+func drainEvents(ch <-chan pipe.Event, first pipe.Event) []pipe.Event {
+	events := []pipe.Event{first}
+	for len(events) < maxEventBatch {
+		select {
+		case evt, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, evt)
+		default:
+			return events
+		}
+	}
+	return events
+}
+
+// listenForNotice waits for the next message on notices. A closed channel
+// stops listening.
+func listenForNotice(notices <-chan string) tea.Cmd {
 	return func() tea.Msg {
-		evt, ok := <-ch
+		text, ok := <-notices
 		if !ok {
-			err := <-doneCh
-			return AgentDoneMsg{Err: err}
+			return nil
 		}
-		return StreamEventMsg{Event: evt}
+		return noticeMsg{text: text}
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync/atomic"
@@ -317,6 +318,37 @@ func TestModel_Update(t *testing.T) {
 		// Still running (agent hasn't responded to cancellation yet).
 		assert.True(t, model.Running())
 	})
+
+	t.Run("esc during agent run interrupts it", func(t *testing.T) {
+		t.Parallel()
+
+		var cancelCalled bool
+		m := initModel(t, nopAgent)
+		m, _ = bt.SetRunningWithCancel(m, func() { cancelCalled = true })
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Delta: "partial answer"}})
+
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+		assert.True(t, cancelCalled)
+		m = updateModel(t, m, bt.AgentDoneMsg{Err: errors.New("stream read: context canceled")})
+
+		assert.False(t, m.Running())
+		assert.NoError(t, m.Err(), "an interrupt is not an error")
+		view := m.View()
+		assert.Contains(t, view, "partial answer")
+		assert.Contains(t, view, "── interrupted ─")
+	})
+
+	t.Run("resumed sessions mark interrupted responses", func(t *testing.T) {
+		t.Parallel()
+
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}},
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "partial answer"}}, StopReason: pipe.StopAborted},
+		}}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		assert.Contains(t, m.View(), "── interrupted ─")
+	})
 }
 
 func TestModel_BlockAssembly(t *testing.T) {
@@ -400,6 +432,63 @@ func TestModel_BlockToggle(t *testing.T) {
 	})
 }
 
+func TestModel_Mouse(t *testing.T) {
+	t.Parallel()
+
+	// row returns the first row of the view containing text.
+	row := func(t *testing.T, m bt.Model, text string) int {
+		t.Helper()
+		for i, line := range strings.Split(m.View(), "\n") {
+			if strings.Contains(line, text) {
+				return i
+			}
+		}
+		t.Fatalf("%q not in view", text)
+		return -1
+	}
+	click := func(y int) tea.MouseMsg {
+		return tea.MouseMsg{Y: y, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft}
+	}
+
+	t.Run("click on a block header toggles it", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}},
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{
+				pipe.ThinkingBlock{Thinking: "deep thought"},
+				pipe.TextBlock{Text: "answer"},
+			}},
+		}}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		assert.NotContains(t, m.View(), "deep thought")
+
+		// Text outside a collapsible header does nothing.
+		m = updateModel(t, m, click(row(t, m, "answer")))
+		assert.NotContains(t, m.View(), "deep thought")
+
+		m = updateModel(t, m, click(row(t, m, "Thinking")))
+		assert.Contains(t, m.View(), "deep thought")
+		m = updateModel(t, m, click(row(t, m, "Thinking")))
+		assert.NotContains(t, m.View(), "deep thought")
+	})
+
+	t.Run("wheel scrolls the output", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{
+				pipe.TextBlock{Text: strings.Repeat("line\n\n", 40) + "last"},
+			}},
+		}}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		bottom := m.Viewport.YOffset
+		require.Positive(t, bottom)
+		m = updateModel(t, m, tea.MouseMsg{Action: tea.MouseActionPress, Button: tea.MouseButtonWheelUp})
+		assert.Less(t, m.Viewport.YOffset, bottom)
+	})
+}
+
 func TestModel_BlockToggle_ToolResult(t *testing.T) {
 	t.Parallel()
 
@@ -474,6 +563,20 @@ func TestModel_ToolResultEvent(t *testing.T) {
 	})
 }
 
+func TestModel_CompactionEvent(t *testing.T) {
+	t.Parallel()
+	m := initModel(t, nopAgent)
+	m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventCompaction{Messages: 12, TokensBefore: 180000, Summary: "the summary"}})
+	assert.Contains(t, m.View(), "Compacted 12 earlier messages into a summary (context was 180,000 tokens).")
+}
+
+func TestModel_ToolTimeoutEvent(t *testing.T) {
+	t.Parallel()
+	m := initModel(t, nopAgent)
+	m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolTimeout{ID: "tc-1", ToolName: "bash", Timeout: 2 * time.Minute}})
+	assert.Contains(t, m.View(), "bash ran longer than 2m0s and was cancelled.")
+}
+
 func TestModel_MultiTurnReset(t *testing.T) {
 	t.Parallel()
 
@@ -614,7 +717,7 @@ func TestModel_GlobalToggle(t *testing.T) {
 		// New tool call should start expanded (not collapsed).
 		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolCallBegin{ID: "tc-1", Name: "read"}})
 		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolCallDelta{ID: "tc-1", Delta: `{"path":"/tmp"}`}})
-		assert.Contains(t, m.View(), `{"path":"/tmp"}`)
+		assert.Contains(t, m.View(), "path: /tmp")
 	})
 
 	t.Run("new thinking block inherits expanded state", func(t *testing.T) {
@@ -728,6 +831,88 @@ func TestModel_StatusBar(t *testing.T) {
 		assert.Contains(t, view, "feat/login")
 	})
 
+	t.Run("detects git branch after the first frame", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{DetectGitBranch: func() string { return "feat/async" }})
+		assert.NotContains(t, m.View(), "feat/async")
+
+		batch, ok := m.Init()().(tea.BatchMsg)
+		require.True(t, ok)
+		for _, cmd := range batch {
+			m = updateModel(t, m, cmd())
+		}
+		assert.Contains(t, m.View(), "feat/async")
+	})
+
+	t.Run("detects git status after the first frame", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{
+			DetectGit:       func() bt.GitStatus { return bt.GitStatus{Branch: "feat/async", Dirty: true} },
+			DetectGitBranch: func() string { return "ignored" },
+		})
+		assert.NotContains(t, m.View(), "feat/async")
+
+		batch, ok := m.Init()().(tea.BatchMsg)
+		require.True(t, ok)
+		for _, cmd := range batch {
+			m = updateModel(t, m, cmd())
+		}
+		view := m.View()
+		assert.Contains(t, view, "feat/async*")
+		assert.NotContains(t, view, "ignored")
+	})
+
+	t.Run("shows notices as they arrive", func(t *testing.T) {
+		t.Parallel()
+		notices := make(chan string, 1)
+		notices <- "Reloaded .pipe/prompt.md."
+		m := initModelWithConfig(t, nopAgent, bt.Config{Notices: notices})
+
+		batch, ok := m.Init()().(tea.BatchMsg)
+		require.True(t, ok)
+		require.Len(t, batch, 2)
+		updated, cmd := m.Update(batch[1]())
+		assert.Contains(t, updated.View(), "Reloaded .pipe/prompt.md.")
+
+		// The model keeps listening until the channel closes.
+		close(notices)
+		require.NotNil(t, cmd)
+		assert.Nil(t, cmd())
+	})
+
+	t.Run("shows status segments", func(t *testing.T) {
+		t.Parallel()
+		refreshes := 0
+		m := initModelWithConfig(t, nopAgent, bt.Config{StatusSegments: []bt.StatusSegment{
+			{Refresh: func() string { return "ctx: prod-cluster-eu-west-1" }, MaxWidth: 12},
+			{Refresh: func() string {
+				refreshes++
+				return fmt.Sprintf("CI #%d", refreshes)
+			}, Interval: time.Millisecond},
+			{Refresh: func() string { return "" }},
+		}})
+
+		batch, ok := m.Init()().(tea.BatchMsg)
+		require.True(t, ok)
+		require.Len(t, batch, 4)
+		var next tea.Cmd
+		for i, cmd := range batch[1:] {
+			updated, c := m.Update(cmd())
+			m = updated.(bt.Model)
+			if i == 1 {
+				next = c
+			}
+		}
+		view := m.View()
+		assert.Contains(t, view, "ctx: prod-c")
+		assert.NotContains(t, view, "prod-cluster")
+		assert.Contains(t, view, "CI #1")
+
+		require.NotNil(t, next, "interval segments refresh again")
+		m = updateModel(t, m, next())
+		assert.Contains(t, m.View(), "CI #2")
+	})
+
 	t.Run("displays model name", func(t *testing.T) {
 		t.Parallel()
 		m := initModelWithConfig(t, nopAgent, bt.Config{ModelName: "claude-opus"})
@@ -735,6 +920,29 @@ func TestModel_StatusBar(t *testing.T) {
 		assert.Contains(t, view, "claude-opus")
 	})
 
+	t.Run("displays session usage and cost", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 10_000, OutputTokens: 2_000, CacheReadTokens: 300}},
+		}}
+		price := pipe.Pricing{Input: 3, Output: 15, CacheRead: 0.30}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{ModelName: "claude-opus", Pricing: &price})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		assert.Contains(t, m.View(), "12.3k tokens · $0.06  claude-opus")
+
+		// The total counts up as turns complete.
+		m.Input.SetValue("more")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTurnComplete{StopReason: pipe.StopToolUse, Usage: pipe.Usage{InputTokens: 10_000}}})
+		assert.Contains(t, m.View(), "22.3k tokens · $0.09")
+	})
+
+	t.Run("hides usage before the first request", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{ModelName: "claude-opus"})
+		assert.NotContains(t, m.View(), "tokens")
+	})
+
 	t.Run("displays spinner when running", func(t *testing.T) {
 		t.Parallel()
 		m := initModelWithConfig(t, nopAgent, bt.Config{ModelName: "claude-opus"})
@@ -783,6 +991,232 @@ func TestModel_StatusBar(t *testing.T) {
 			assert.LessOrEqual(t, lipgloss.Width(line), 20, "line exceeds viewport width: %q", line)
 		}
 	})
+
+	t.Run("short terminal collapses the status area", func(t *testing.T) {
+		t.Parallel()
+		m := bt.New(nopAgent, &pipe.Session{}, pipe.DefaultTheme(), bt.Config{
+			WorkDir:   "~/project",
+			ModelName: "claude-opus-4",
+		})
+		updated, _ := m.Update(tea.WindowSizeMsg{Width: 40, Height: 6})
+		model, ok := updated.(bt.Model)
+		require.True(t, ok)
+
+		view := model.View()
+		lines := strings.Split(view, "\n")
+		assert.Len(t, lines, 6)
+		assert.NotContains(t, view, "─")
+		assert.NotContains(t, view, "claude-opus-4")
+		assert.Contains(t, lines[len(lines)-1], "·")
+		assert.GreaterOrEqual(t, model.Viewport.Height, 3)
+		for _, line := range lines {
+			assert.LessOrEqual(t, lipgloss.Width(line), 40, "line exceeds viewport width: %q", line)
+		}
+	})
+
+	t.Run("growing the terminal restores the status area", func(t *testing.T) {
+		t.Parallel()
+		m := bt.New(nopAgent, &pipe.Session{}, pipe.DefaultTheme(), bt.Config{ModelName: "claude-opus-4"})
+		updated, _ := m.Update(tea.WindowSizeMsg{Width: 40, Height: 6})
+		updated, _ = updated.Update(tea.WindowSizeMsg{Width: 40, Height: 24})
+		model, ok := updated.(bt.Model)
+		require.True(t, ok)
+
+		view := model.View()
+		assert.Contains(t, view, "─")
+		assert.Contains(t, view, "claude-opus-4")
+		assert.Equal(t, 3, model.Input.MaxHeight)
+	})
+
+	t.Run("configured input height is kept to what leaves room for output", func(t *testing.T) {
+		t.Parallel()
+		m := bt.New(nopAgent, &pipe.Session{}, pipe.DefaultTheme(), bt.Config{InputHeight: 10})
+		updated, _ := m.Update(tea.WindowSizeMsg{Width: 40, Height: 24})
+		model, ok := updated.(bt.Model)
+		require.True(t, ok)
+		assert.Equal(t, 10, model.Input.MaxHeight)
+
+		updated, _ = model.Update(tea.WindowSizeMsg{Width: 40, Height: 12})
+		model, ok = updated.(bt.Model)
+		require.True(t, ok)
+		assert.Equal(t, 6, model.Input.MaxHeight)
+	})
+
+	t.Run("terminal too short for any output shows a notice", func(t *testing.T) {
+		t.Parallel()
+		m := bt.New(nopAgent, &pipe.Session{}, pipe.DefaultTheme(), bt.Config{})
+		updated, _ := m.Update(tea.WindowSizeMsg{Width: 40, Height: 3})
+		model, ok := updated.(bt.Model)
+		require.True(t, ok)
+
+		assert.Contains(t, model.View(), "terminal too small")
+	})
+}
+
+func TestModel_StreamHealth(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	setup := func(t *testing.T) (bt.Model, *time.Time) {
+		t.Helper()
+		now := start
+		m := initModelWithConfig(t, nopAgent, bt.Config{ModelName: "claude-opus"})
+		m = bt.SetNow(m, func() time.Time { return now })
+		m.Input.SetValue("hello")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		return m, &now
+	}
+
+	t.Run("waiting before first output", func(t *testing.T) {
+		t.Parallel()
+		m, _ := setup(t)
+		assert.Contains(t, m.View(), "waiting")
+	})
+
+	t.Run("shows tokens per second while streaming", func(t *testing.T) {
+		t.Parallel()
+		m, now := setup(t)
+		*now = start.Add(time.Second)
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Delta: strings.Repeat("a", 40)}})
+		*now = start.Add(2 * time.Second)
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Delta: strings.Repeat("a", 40)}})
+		// 80 chars = 20 tokens over one second.
+		assert.Contains(t, m.View(), "20 tok/s · streaming")
+	})
+
+	t.Run("reports stall after three seconds without events", func(t *testing.T) {
+		t.Parallel()
+		m, now := setup(t)
+		*now = start.Add(time.Second)
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Delta: "hi"}})
+		*now = start.Add(6 * time.Second)
+		view := m.View()
+		assert.Contains(t, view, "stalled 5s")
+		assert.NotContains(t, view, "streaming")
+	})
+
+	t.Run("hidden while local tools run", func(t *testing.T) {
+		t.Parallel()
+		m, now := setup(t)
+		call := pipe.ToolCallBlock{ID: "t1", Name: "bash"}
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolCallBegin{ID: "t1", Name: "bash"}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolCallEnd{Call: call}})
+		*now = start.Add(10 * time.Second)
+		view := m.View()
+		assert.NotContains(t, view, "stalled")
+		assert.NotContains(t, view, "waiting")
+
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventToolResult{ToolName: "bash"}})
+		assert.Contains(t, m.View(), "waiting")
+	})
+
+	t.Run("counts down a pending retry", func(t *testing.T) {
+		t.Parallel()
+		m, now := setup(t)
+		*now = start.Add(time.Second)
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Delta: "Hel"}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventRetry{
+			Attempt: 1, MaxRetries: 3, Delay: 4 * time.Second, Err: errors.New("overloaded"),
+		}})
+		*now = start.Add(2500 * time.Millisecond)
+		view := m.View()
+		assert.Contains(t, view, "retrying in 3s…")
+		assert.Contains(t, view, "overloaded — retrying in 4s (1/3)…")
+
+		// A restarted response streams into a new block.
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Delta: "Hello"}})
+		view = m.View()
+		assert.NotContains(t, view, "HelHello")
+		assert.NotContains(t, view, "stalled")
+	})
+
+	t.Run("hidden when idle", func(t *testing.T) {
+		t.Parallel()
+		m, _ := setup(t)
+		m = updateModel(t, m, bt.AgentDoneMsg{})
+		view := m.View()
+		assert.NotContains(t, view, "waiting")
+		assert.NotContains(t, view, "tok/s")
+	})
+}
+
+func TestModel_Heartbeat(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	setup := func(t *testing.T) (bt.Model, *time.Time) {
+		t.Helper()
+		now := start
+		m := initModel(t, nopAgent)
+		m = bt.SetNow(m, func() time.Time { return now })
+		m.Input.SetValue("hello")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		return m, &now
+	}
+
+	t.Run("shows elapsed time without events", func(t *testing.T) {
+		t.Parallel()
+		m, now := setup(t)
+		assert.Contains(t, m.View(), "0s")
+
+		*now = start.Add(65 * time.Second)
+		updated, cmd := m.Update(bt.HeartbeatMsg(m))
+		m, ok := updated.(bt.Model)
+		require.True(t, ok)
+		assert.NotNil(t, cmd, "heartbeat reschedules itself while running")
+		assert.Contains(t, m.View(), "1m05s")
+	})
+
+	t.Run("stops after the run ends", func(t *testing.T) {
+		t.Parallel()
+		m, _ := setup(t)
+		hb := bt.HeartbeatMsg(m)
+		m = updateModel(t, m, bt.AgentDoneMsg{})
+
+		_, cmd := m.Update(hb)
+		assert.Nil(t, cmd)
+		assert.NotContains(t, m.View(), "0s ")
+	})
+
+	t.Run("drops heartbeats from earlier runs", func(t *testing.T) {
+		t.Parallel()
+		m, _ := setup(t)
+		stale := bt.HeartbeatMsg(m)
+		m = updateModel(t, m, bt.AgentDoneMsg{})
+		m.Input.SetValue("again")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		require.True(t, m.Running())
+
+		_, cmd := m.Update(stale)
+		assert.Nil(t, cmd, "a stale heartbeat must not start a second chain")
+	})
+}
+
+func TestModel_RunSummary(t *testing.T) {
+	t.Parallel()
+
+	t.Run("shows summary after the run", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{RunSummary: func() string {
+			return "1 file changed: a.go +3"
+		}})
+		m.Input.SetValue("hello")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		assert.NotContains(t, m.View(), "1 file changed")
+
+		m = updateModel(t, m, bt.AgentDoneMsg{})
+		assert.Contains(t, m.View(), "1 file changed: a.go +3")
+	})
+
+	t.Run("empty summary adds no block", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{RunSummary: func() string { return "" }})
+		m.Input.SetValue("hello")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		before := bt.RenderContent(m)
+		m = updateModel(t, m, bt.AgentDoneMsg{})
+		assert.Equal(t, before, bt.RenderContent(m))
+	})
 }
 
 func TestModel_WelcomeScreen(t *testing.T) {
@@ -1178,3 +1612,378 @@ func TestModel_Teatest(t *testing.T) {
 		assert.Equal(t, int32(2), callCount.Load())
 	})
 }
+
+func TestModel_ServerToolEvents(t *testing.T) {
+	t.Parallel()
+
+	t.Run("server tool call and search results render as tool blocks", func(t *testing.T) {
+		t.Parallel()
+		m := initModel(t, nopAgent)
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventServerToolCall{Call: pipe.ServerToolCallBlock{
+			ID: "srv_1", Name: "web_search", Arguments: json.RawMessage(`{"query":"go"}`),
+		}}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventServerToolResult{Result: pipe.ServerToolResultBlock{
+			ToolCallID: "srv_1", Name: "web_search", Content: json.RawMessage(`[{"title":"The Go Programming Language","url":"https://go.dev"}]`),
+		}}})
+		view := m.View()
+		assert.Contains(t, view, "web_search")
+		assert.Contains(t, view, "The Go Programming Language — https://go.dev")
+	})
+
+	t.Run("code execution shows code and output", func(t *testing.T) {
+		t.Parallel()
+		m := initModel(t, nopAgent)
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventServerToolCall{Call: pipe.ServerToolCallBlock{
+			ID: "srv_1", Name: "code_execution", Arguments: json.RawMessage(`{"language":"PYTHON","code":"print(6 * 7)"}`),
+		}}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventServerToolResult{Result: pipe.ServerToolResultBlock{
+			ToolCallID: "srv_1", Name: "code_execution", Content: json.RawMessage(`{"outcome":"OUTCOME_OK","output":"42\n"}`),
+		}}})
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyCtrlO})
+		view := m.View()
+		assert.Contains(t, view, "print(6 * 7)")
+		assert.NotContains(t, view, `"language"`)
+		assert.Contains(t, view, "42")
+		assert.NotContains(t, view, "OUTCOME_OK")
+	})
+
+	t.Run("session reload renders server tool blocks", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{
+				pipe.ServerToolCallBlock{ID: "srv_1", Name: "code_execution", Arguments: json.RawMessage(`{}`)},
+				pipe.ServerToolResultBlock{ToolCallID: "srv_1", Name: "code_execution", Content: json.RawMessage(`{"stdout":"42"}`)},
+			}},
+		}}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		view := m.View()
+		assert.Contains(t, view, "code_execution")
+		assert.Contains(t, view, `{"stdout":"42"}`)
+	})
+}
+
+func TestModel_CitationEvents(t *testing.T) {
+	t.Parallel()
+
+	t.Run("citations add footnotes and a sources block", func(t *testing.T) {
+		t.Parallel()
+		m := initModel(t, nopAgent)
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventCitation{Index: 0, Citation: pipe.Citation{URL: "https://go.dev", Title: "go.dev"}}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Index: 0, Delta: "Go is open source."}})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventCitation{Index: 0, Citation: pipe.Citation{URL: "https://pkg.go.dev"}}})
+		view := m.View()
+		assert.Contains(t, view, "Go is open source.")
+		assert.Contains(t, view, "[1][2]")
+		assert.Contains(t, view, "Sources (2)")
+		assert.NotContains(t, view, "https://pkg.go.dev")
+
+		// The sources block is the focused collapsible block; Tab expands it.
+		m = updateModel(t, m, bt.AgentDoneMsg{})
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyTab})
+		view = m.View()
+		assert.Contains(t, view, "[1] go.dev — https://go.dev")
+		assert.Contains(t, view, "[2] https://pkg.go.dev")
+	})
+
+	t.Run("session reload renders citations", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{
+				pipe.TextBlock{Text: "Grounded answer.", Citations: []pipe.Citation{
+					{URL: "https://go.dev", Title: "go.dev"},
+				}},
+			}},
+		}}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		view := m.View()
+		assert.Contains(t, view, "Grounded answer.")
+		assert.Contains(t, view, "[1]")
+		assert.Contains(t, view, "Sources (1)")
+	})
+}
+
+func TestModel_RenderInterval(t *testing.T) {
+	t.Parallel()
+
+	t.Run("coalesces re-renders until the tick", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{RenderInterval: 50 * time.Millisecond})
+
+		updated, cmd := m.Update(bt.StreamEventMsg{Event: pipe.EventTextDelta{Index: 0, Delta: "first"}})
+		m = updated.(bt.Model)
+		require.NotNil(t, cmd, "first event schedules a render tick")
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Index: 0, Delta: " second"}})
+		assert.NotContains(t, m.View(), "first")
+
+		m = updateModel(t, m, bt.RenderTickMsg{})
+		assert.Contains(t, m.View(), "first second")
+	})
+
+	t.Run("agent completion flushes pending render", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{RenderInterval: time.Hour})
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Index: 0, Delta: "done"}})
+		assert.NotContains(t, m.View(), "done")
+
+		m = updateModel(t, m, bt.AgentDoneMsg{})
+		assert.Contains(t, m.View(), "done")
+	})
+
+	t.Run("queued events arrive in one update", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{RenderInterval: time.Hour})
+		ch := make(chan pipe.Event, 3)
+		done := make(chan error, 1)
+		for _, d := range []string{"one", " two", " three"} {
+			ch <- pipe.EventTextDelta{Index: 0, Delta: d}
+		}
+		close(ch)
+		done <- nil
+
+		updated, cmd := m.Update(bt.ListenForEvent(ch, done))
+		m = updated.(bt.Model)
+		require.NotNil(t, cmd)
+		m = updateModel(t, m, bt.RenderTickMsg{})
+		assert.Contains(t, m.View(), "one two three")
+		assert.Equal(t, bt.AgentDoneMsg{}, bt.ListenForEvent(ch, done))
+	})
+
+	t.Run("zero interval renders every event", func(t *testing.T) {
+		t.Parallel()
+		m := initModel(t, nopAgent)
+		m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Index: 0, Delta: "immediate"}})
+		assert.Contains(t, m.View(), "immediate")
+	})
+}
+
+func TestModel_SlashCommands(t *testing.T) {
+	t.Parallel()
+
+	submit := func(t *testing.T, m bt.Model, text string) (bt.Model, tea.Cmd) {
+		t.Helper()
+		m.Input.SetValue(text)
+		updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		model, ok := updated.(bt.Model)
+		require.True(t, ok)
+		return model, cmd
+	}
+
+	t.Run("runs configured command and shows its output", func(t *testing.T) {
+		t.Parallel()
+		var gotArgs string
+		cfg := bt.Config{Commands: []bt.Command{{
+			Name: "rollback",
+			Run: func(args string) (bt.CommandResult, error) {
+				gotArgs = args
+				return bt.CommandResult{Notice: "Rolled back 1 file(s)"}, nil
+			},
+		}}}
+		agentCalled := false
+		m := initModelWithConfig(t, func(context.Context, *pipe.Session, func(pipe.Event)) error {
+			agentCalled = true
+			return nil
+		}, cfg)
+
+		m, _ = submit(t, m, "/rollback  now ")
+		assert.False(t, m.Running())
+		assert.False(t, agentCalled)
+		assert.Equal(t, "now", gotArgs)
+		assert.Contains(t, m.View(), "Rolled back 1 file(s)")
+		assert.Empty(t, m.Input.Value())
+	})
+
+	t.Run("command error is shown", func(t *testing.T) {
+		t.Parallel()
+		cfg := bt.Config{Commands: []bt.Command{{
+			Name: "rollback",
+			Run:  func(string) (bt.CommandResult, error) { return bt.CommandResult{}, errors.New("disk on fire") },
+		}}}
+		m := initModelWithConfig(t, nopAgent, cfg)
+		m, _ = submit(t, m, "/rollback")
+		assert.Contains(t, m.View(), "disk on fire")
+	})
+
+	t.Run("command can update the model name", func(t *testing.T) {
+		t.Parallel()
+		cfg := bt.Config{ModelName: "old-model", Commands: []bt.Command{{
+			Name: "profile",
+			Run: func(string) (bt.CommandResult, error) {
+				return bt.CommandResult{ModelName: "new-model"}, nil
+			},
+		}}}
+		m := initModelWithConfig(t, nopAgent, cfg)
+		m, _ = submit(t, m, "/profile fast")
+		view := m.View()
+		assert.Contains(t, view, "new-model")
+		assert.NotContains(t, view, "old-model")
+	})
+
+	t.Run("command re-prices the session with the new model", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 10_000, OutputTokens: 2_000}},
+		}}
+		cheap, dear := pipe.Pricing{Input: 3, Output: 15}, pipe.Pricing{Input: 15, Output: 75}
+		cfg := bt.Config{ModelName: "old-model", Pricing: &cheap, Commands: []bt.Command{{
+			Name: "model",
+			Run: func(string) (bt.CommandResult, error) {
+				return bt.CommandResult{ModelName: "new-model", Pricing: &dear}, nil
+			},
+		}}}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), cfg)
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		assert.Contains(t, m.View(), "$0.06  old-model")
+
+		m, _ = submit(t, m, "/model new-model")
+		assert.Contains(t, m.View(), "$0.30  new-model")
+	})
+
+	t.Run("command can change the input height", func(t *testing.T) {
+		t.Parallel()
+		cfg := bt.Config{Commands: []bt.Command{{
+			Name: "set",
+			Run: func(string) (bt.CommandResult, error) {
+				return bt.CommandResult{InputHeight: 8}, nil
+			},
+		}}}
+		m := initModelWithConfig(t, nopAgent, cfg)
+		assert.Equal(t, 3, m.Input.MaxHeight)
+		viewport := m.Viewport.Height
+
+		m, _ = submit(t, m, "/set input_height 8")
+		assert.Equal(t, 8, m.Input.MaxHeight)
+		m = typeText(t, m, strings.Repeat("line\n", 6))
+		assert.Equal(t, 7, m.Input.Height())
+		m = updateModel(t, m, textarea.InputHeightMsg{Height: 7})
+		assert.Equal(t, viewport-6, m.Viewport.Height, "the viewport gives up the lines the input takes")
+		assert.Len(t, strings.Split(m.View(), "\n"), 24)
+	})
+
+	t.Run("command can refresh the git status", func(t *testing.T) {
+		t.Parallel()
+		dirty := false
+		cfg := bt.Config{
+			DetectGit: func() bt.GitStatus { return bt.GitStatus{Branch: "main", Dirty: dirty} },
+			Commands: []bt.Command{{
+				Name: "model",
+				Run: func(string) (bt.CommandResult, error) {
+					return bt.CommandResult{RefreshGit: true}, nil
+				},
+			}},
+		}
+		m := initModelWithConfig(t, nopAgent, cfg)
+		dirty = true
+		m, cmd := submit(t, m, "/model")
+		require.NotNil(t, cmd)
+		m = updateModel(t, m, cmd())
+		assert.Contains(t, m.View(), "main*")
+	})
+
+	t.Run("retry drops the last turn and runs again", func(t *testing.T) {
+		t.Parallel()
+		retry := bt.Command{Name: "retry", Run: func(string) (bt.CommandResult, error) {
+			return bt.CommandResult{Notice: "Retrying with opus", Retry: true}, nil
+		}}
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "first question"}}},
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "first answer"}}},
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "second question"}}},
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.ToolCallBlock{ID: "tc_1", Name: "bash"}}},
+			pipe.ToolResultMessage{ToolCallID: "tc_1", ToolName: "bash", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}}},
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "bad answer"}}},
+		}}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{Commands: []bt.Command{retry}})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+		m, cmd := submit(t, m, "/retry")
+		assert.True(t, m.Running())
+		assert.NotNil(t, cmd)
+		require.Len(t, session.Messages, 3)
+		assert.IsType(t, pipe.UserMessage{}, session.Messages[2])
+
+		view := m.View()
+		assert.Contains(t, view, "second question")
+		assert.Contains(t, view, "Retrying with opus")
+		assert.NotContains(t, view, "bad answer")
+	})
+
+	t.Run("retry without a prompt is an error", func(t *testing.T) {
+		t.Parallel()
+		retry := bt.Command{Name: "retry", Run: func(string) (bt.CommandResult, error) {
+			return bt.CommandResult{Retry: true}, nil
+		}}
+		m := initModelWithConfig(t, nopAgent, bt.Config{Commands: []bt.Command{retry}})
+		m, _ = submit(t, m, "/retry")
+		assert.False(t, m.Running())
+		assert.Contains(t, m.View(), "nothing to retry")
+	})
+
+	t.Run("unknown slash input is sent as a prompt", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{Commands: []bt.Command{{Name: "rollback"}}})
+		m, _ = submit(t, m, "/etc/hosts looks wrong")
+		assert.True(t, m.Running())
+	})
+}
+
+func TestModel_FollowModes(t *testing.T) {
+	t.Parallel()
+
+	// fill streams enough text blocks to overflow the viewport, then scrolls
+	// to the top.
+	fill := func(t *testing.T, m bt.Model) bt.Model {
+		t.Helper()
+		for i := range 60 {
+			m = updateModel(t, m, bt.StreamEventMsg{Event: pipe.EventTextDelta{Index: i, Delta: fmt.Sprintf("line-%d", i)}})
+		}
+		m.Viewport.GotoTop()
+		require.False(t, m.Viewport.AtBottom())
+		return m
+	}
+	toolResult := bt.StreamEventMsg{Event: pipe.EventToolResult{ToolName: "bash", Content: "spam"}}
+	text := bt.StreamEventMsg{Event: pipe.EventTextDelta{Index: 100, Delta: "answer"}}
+
+	t.Run("follow all scrolls on every event", func(t *testing.T) {
+		t.Parallel()
+		m := fill(t, initModel(t, nopAgent))
+		m = updateModel(t, m, toolResult)
+		assert.True(t, m.Viewport.AtBottom())
+	})
+
+	t.Run("follow text ignores tool output", func(t *testing.T) {
+		t.Parallel()
+		m := fill(t, initModelWithConfig(t, nopAgent, bt.Config{Follow: bt.FollowText}))
+		m = updateModel(t, m, toolResult)
+		assert.True(t, m.Viewport.AtTop())
+		m = updateModel(t, m, text)
+		assert.True(t, m.Viewport.AtBottom())
+	})
+
+	t.Run("manual never scrolls", func(t *testing.T) {
+		t.Parallel()
+		m := fill(t, initModelWithConfig(t, nopAgent, bt.Config{Follow: bt.FollowManual}))
+		m = updateModel(t, m, toolResult)
+		m = updateModel(t, m, text)
+		assert.True(t, m.Viewport.AtTop())
+	})
+
+	t.Run("ctrl+g cycles modes and shows them in the status bar", func(t *testing.T) {
+		t.Parallel()
+		m := initModelWithConfig(t, nopAgent, bt.Config{ModelName: "claude"})
+		assert.NotContains(t, m.View(), "follow:")
+
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyCtrlG})
+		assert.Contains(t, m.View(), "follow: text")
+
+		m = fill(t, updateModel(t, m, tea.KeyMsg{Type: tea.KeyCtrlG}))
+		assert.Contains(t, m.View(), "follow: off")
+		m = updateModel(t, m, text)
+		assert.True(t, m.Viewport.AtTop())
+
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyCtrlG})
+		assert.NotContains(t, m.View(), "follow:")
+		assert.True(t, m.Viewport.AtBottom(), "returning to follow all jumps to the bottom")
+	})
+}
@@ -0,0 +1,141 @@
+package bubbletea
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// outlineLabel is the one-line outline entry for a block.
+func outlineLabel(b MessageBlock) string {
+	switch b := b.(type) {
+	case *UserMessageBlock:
+		return "> " + firstNonBlankLine(b.text)
+	case *AssistantTextBlock:
+		return firstNonBlankLine(b.content.String())
+	case *ThinkingBlock:
+		return "Thinking"
+	case *ToolCallBlock:
+		label := "▶ " + b.name
+		if fields, ok := parsePartialArgs(b.args.String()); ok {
+			if preview := argsPreview(fields); preview != "" {
+				label += " " + preview
+			}
+		}
+		return label
+	case *ToolResultBlock:
+		if b.isError {
+			return "  ✗ " + b.toolName
+		}
+		return "  ✓ " + b.toolName
+	case *SourcesBlock:
+		return fmt.Sprintf("Sources (%d)", len(b.sources))
+	case *QuestionBlock:
+		return "? " + b.question
+	case *ErrorBlock:
+		return fmt.Sprintf("Error: %v", b.err)
+	case *NoticeBlock:
+		return firstNonBlankLine(b.text)
+	case *RunSummaryBlock:
+		return "Run summary"
+	case *InterruptedBlock:
+		return "Interrupted"
+	case *ImageBlock:
+		return "[image]"
+	case customToolBlock:
+		return outlineLabel(b.MessageBlock)
+	default:
+		return "…"
+	}
+}
+
+// firstNonBlankLine returns the first line of s with any text.
+func firstNonBlankLine(s string) string {
+	for line := range strings.Lines(s) {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// blockOffsets returns the line each block starts at in the rendered
+// content.
+func (m Model) blockOffsets() []int {
+	offsets := make([]int, len(m.blocks))
+	line := 0
+	for i, block := range m.blocks {
+		if i > 0 {
+			line += strings.Count(blockSeparator(m.blocks[i-1], block), "\n")
+		}
+		offsets[i] = line
+		line += strings.Count(block.View(m.Viewport.Width), "\n")
+	}
+	return offsets
+}
+
+// openOutline shows the outline with the block at the top of the viewport
+// selected.
+func (m Model) openOutline() Model {
+	if len(m.blocks) == 0 {
+		return m
+	}
+	m.outline = true
+	m.outlineCursor = 0
+	for i, offset := range m.blockOffsets() {
+		if offset > m.Viewport.YOffset {
+			break
+		}
+		m.outlineCursor = i
+	}
+	return m
+}
+
+// handleOutlineKey moves the outline selection, jumps to the selected block,
+// or closes the outline.
+func (m Model) handleOutlineKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	page := max(m.Viewport.Height-1, 1)
+	switch msg.String() {
+	case "up", "k", "ctrl+p":
+		m.outlineCursor--
+	case "down", "j", "ctrl+n":
+		m.outlineCursor++
+	case "pgup":
+		m.outlineCursor -= page
+	case "pgdown":
+		m.outlineCursor += page
+	case "home", "g":
+		m.outlineCursor = 0
+	case "end", "G":
+		m.outlineCursor = len(m.blocks) - 1
+	case "enter":
+		m.outline = false
+		m.Viewport.SetYOffset(m.blockOffsets()[m.outlineCursor])
+	case "esc", "ctrl+l":
+		m.outline = false
+	}
+	m.outlineCursor = max(0, min(m.outlineCursor, len(m.blocks)-1))
+	return m, nil
+}
+
+// outlineView renders the outline in place of the viewport: a header line
+// and one line per block, scrolled to keep the selection visible.
+func (m Model) outlineView() string {
+	width, height := m.Viewport.Width, m.Viewport.Height
+	lines := []string{m.styles.Muted.Render(ansi.Truncate("Outline · ↑/↓ select · Enter jump · Esc close", width, "…"))}
+	rows := max(height-1, 0)
+	first := max(0, m.outlineCursor-rows+1)
+	for i := first; i < len(m.blocks) && len(lines) < height; i++ {
+		label := ansi.Truncate("  "+outlineLabel(m.blocks[i]), width, "…")
+		if i == m.outlineCursor {
+			label = m.styles.Accent.Render(ansi.Truncate("› "+outlineLabel(m.blocks[i]), width, "…"))
+		}
+		lines = append(lines, label)
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines[:height], "\n")
+}
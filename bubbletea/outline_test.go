@@ -0,0 +1,85 @@
+package bubbletea_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_Outline(t *testing.T) {
+	t.Parallel()
+
+	// session has a prompt, a long answer, and a tool call with its result.
+	session := func(t *testing.T) bt.Model {
+		t.Helper()
+		var answer strings.Builder
+		for i := range 40 {
+			fmt.Fprintf(&answer, "paragraph %d\n\n", i)
+		}
+		s := &pipe.Session{Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "fix the build"}}},
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{
+				pipe.TextBlock{Text: "Looking into it.\n\n" + answer.String()},
+				pipe.ToolCallBlock{ID: "1", Name: "bash", Arguments: json.RawMessage(`{"command":"go build ./..."}`)},
+			}},
+			pipe.ToolResultMessage{ToolCallID: "1", ToolName: "bash", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}}},
+		}}
+		m := bt.New(nopAgent, s, pipe.DefaultTheme(), bt.Config{})
+		return updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+	}
+	key := func(k tea.KeyType) tea.KeyMsg { return tea.KeyMsg{Type: k} }
+
+	t.Run("lists one line per block", func(t *testing.T) {
+		t.Parallel()
+		m := updateModel(t, session(t), key(tea.KeyCtrlL))
+
+		view := ansi.Strip(m.View())
+		assert.Contains(t, view, "Outline")
+		assert.Contains(t, view, "> fix the build")
+		assert.Contains(t, view, "Looking into it.")
+		assert.Contains(t, view, "▶ bash go build ./...")
+		assert.Contains(t, view, "✓ bash")
+		assert.NotContains(t, view, "paragraph 10")
+	})
+
+	t.Run("enter jumps to the selected block", func(t *testing.T) {
+		t.Parallel()
+		m := session(t)
+		require.True(t, m.Viewport.AtBottom())
+
+		m = updateModel(t, m, key(tea.KeyCtrlL))
+		assert.Contains(t, ansi.Strip(m.View()), "› Looking into it.", "the block at the top of the viewport is selected")
+		m = updateModel(t, m, key(tea.KeyHome))
+		m = updateModel(t, m, key(tea.KeyDown))
+		m = updateModel(t, m, key(tea.KeyEnter))
+
+		assert.NotContains(t, ansi.Strip(m.View()), "Outline")
+		assert.Contains(t, m.Viewport.View(), "Looking into it.")
+		assert.False(t, m.Viewport.AtBottom())
+	})
+
+	t.Run("esc closes without moving", func(t *testing.T) {
+		t.Parallel()
+		m := updateModel(t, session(t), key(tea.KeyCtrlL))
+		m = updateModel(t, m, key(tea.KeyHome))
+		m = updateModel(t, m, key(tea.KeyEsc))
+
+		assert.NotContains(t, ansi.Strip(m.View()), "Outline")
+		assert.True(t, m.Viewport.AtBottom())
+	})
+
+	t.Run("keys do not reach the input while open", func(t *testing.T) {
+		t.Parallel()
+		m := updateModel(t, session(t), key(tea.KeyCtrlL))
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+		assert.Empty(t, m.Input.Value())
+	})
+}
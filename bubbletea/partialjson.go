@@ -0,0 +1,157 @@
+package bubbletea
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// argField is a top-level field of a possibly incomplete JSON object.
+type argField struct {
+	key      string
+	value    string // decoded for strings, raw JSON text otherwise
+	isString bool
+}
+
+// parsePartialArgs extracts the top-level fields of a JSON object that may be
+// cut off anywhere, as tool call arguments are while they stream. String
+// values are decoded as far as they have arrived; other values are kept as
+// raw text. It reports false when the text does not start an object.
+func parsePartialArgs(s string) ([]argField, bool) {
+	s = strings.TrimLeft(s, " \t\r\n")
+	if !strings.HasPrefix(s, "{") {
+		return nil, false
+	}
+	s = s[1:]
+	var fields []argField
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,")
+		if s == "" || s[0] == '}' || s[0] != '"' {
+			return fields, true
+		}
+		key, rest, complete := decodePartialString(s[1:])
+		if !complete {
+			return fields, true
+		}
+		s = strings.TrimLeft(rest, " \t\r\n")
+		if !strings.HasPrefix(s, ":") {
+			return fields, true
+		}
+		s = strings.TrimLeft(s[1:], " \t\r\n")
+		if s == "" {
+			return append(fields, argField{key: key}), true
+		}
+		if s[0] == '"' {
+			value, rest, _ := decodePartialString(s[1:])
+			fields = append(fields, argField{key: key, value: value, isString: true})
+			s = rest
+			continue
+		}
+		n := rawValueLen(s)
+		fields = append(fields, argField{key: key, value: strings.TrimSpace(s[:n])})
+		s = s[n:]
+	}
+}
+
+// decodePartialString decodes a JSON string body up to its closing quote or
+// the end of input, returning the decoded text, the input after the closing
+// quote, and whether the quote was found. An escape cut off at the end of
+// input is dropped.
+func decodePartialString(s string) (text, rest string, complete bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			return b.String(), s[i+1:], true
+		case c != '\\':
+			_, size := utf8.DecodeRuneInString(s[i:])
+			b.WriteString(s[i : i+size])
+			i += size
+			continue
+		case i+1 >= len(s):
+			return b.String(), "", false
+		}
+		switch esc := s[i+1]; esc {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, n, ok := decodeUnicodeEscape(s[i:])
+			if !ok {
+				return b.String(), "", false
+			}
+			b.WriteRune(r)
+			i += n
+			continue
+		default: // '"', '\\', '/'
+			b.WriteByte(esc)
+		}
+		i += 2
+	}
+	return b.String(), "", false
+}
+
+// decodeUnicodeEscape decodes a \uXXXX escape at the start of s, combining a
+// UTF-16 surrogate pair when the second half follows. It returns the rune
+// and the number of bytes consumed, or false when s is cut off.
+func decodeUnicodeEscape(s string) (rune, int, bool) {
+	hex := func(s string) (rune, bool) {
+		if len(s) < 6 {
+			return 0, false
+		}
+		v, err := strconv.ParseUint(s[2:6], 16, 16)
+		return rune(v), err == nil
+	}
+	r, ok := hex(s)
+	if !ok {
+		return 0, 0, false
+	}
+	if !utf16.IsSurrogate(r) {
+		return r, 6, true
+	}
+	if len(s) >= 8 && s[6:8] != `\u` {
+		return utf8.RuneError, 6, true
+	}
+	r2, ok := hex(s[6:])
+	if !ok {
+		return 0, 0, false
+	}
+	if pair := utf16.DecodeRune(r, r2); pair != utf8.RuneError {
+		return pair, 12, true
+	}
+	return utf8.RuneError, 6, true
+}
+
+// rawValueLen returns the length of the non-string JSON value at the start
+// of s: up to the comma or closing brace that ends it at the top level, or
+// all of s when it is cut off.
+func rawValueLen(s string) int {
+	depth := 0
+	inString := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			depth++
+		case (c == '}' || c == ']') && depth > 0:
+			depth--
+		case (c == ',' || c == '}') && depth == 0:
+			return i
+		}
+	}
+	return len(s)
+}
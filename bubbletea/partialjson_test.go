@@ -0,0 +1,43 @@
+package bubbletea_test
+
+import (
+	"testing"
+
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePartialArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		input  string
+		want   []string
+		wantOK bool
+	}{
+		{"empty", "", nil, false},
+		{"not an object", `["a"]`, nil, false},
+		{"open brace", "{", nil, true},
+		{"partial key", `{"comm`, nil, true},
+		{"key without value", `{"command":`, []string{"command="}, true},
+		{"partial string", `{"command":"go te`, []string{"command=go te"}, true},
+		{"escapes", `{"content":"a\n\"b\"\té😀"}`, []string{"content=a\n\"b\"\té😀"}, true},
+		{"cut-off escape", `{"content":"a\`, []string{"content=a"}, true},
+		{"cut-off unicode escape", `{"content":"a\u00`, []string{"content=a"}, true},
+		{"numbers and nesting", `{"timeout": 30, "opts": {"a": [1, "}"]}, "path": "x"}`, []string{"timeout=30", `opts={"a": [1, "}"]}`, "path=x"}, true},
+		{"partial nested value", `{"opts": {"a": 1`, []string{`opts={"a": 1`}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := bt.ParsePartialArgs(tt.input)
+			assert.Equal(t, tt.wantOK, ok)
+			if len(tt.want) == 0 {
+				assert.Empty(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
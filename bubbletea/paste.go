@@ -0,0 +1,39 @@
+package bubbletea
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DefaultPasteGap is a Config.PasteGap that tells pastes from typing.
+// Terminals without bracketed paste deliver a paste as ordinary keys, each
+// line ending in Enter, far faster than anyone types.
+const DefaultPasteGap = 10 * time.Millisecond
+
+// pasteKey keeps a paste from sending the input. An Enter that arrives
+// within Config.PasteGap of text becomes a newline, so a pasted snippet
+// lands in the input whole and sending it takes an Enter of its own.
+// Bracketed pastes carry their newlines as text; their CRLF line endings
+// become single newlines.
+func (m Model) pasteKey(msg tea.KeyMsg) (Model, tea.KeyMsg) {
+	if msg.Paste {
+		msg.Runes = []rune(strings.ReplaceAll(string(msg.Runes), "\r\n", "\n"))
+	}
+	if m.config.PasteGap <= 0 {
+		return m, msg
+	}
+	now := m.now()
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace, tea.KeyTab:
+		m.lastText = now
+	case tea.KeyEnter:
+		if msg.Alt || m.lastText.IsZero() || now.Sub(m.lastText) >= m.config.PasteGap {
+			return m, msg
+		}
+		m.lastText = now
+		return m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'\n'}, Paste: true}
+	}
+	return m, msg
+}
@@ -0,0 +1,81 @@
+package bubbletea_test
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_PasteGap(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	// newModel returns a model whose clock reads what *now holds.
+	newModel := func(t *testing.T, session *pipe.Session, now *time.Time) bt.Model {
+		t.Helper()
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{PasteGap: bt.DefaultPasteGap})
+		m = bt.SetNow(m, func() time.Time { return *now })
+		return updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	t.Run("an unbracketed paste waits for an explicit enter", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{}
+		now := start
+		m := newModel(t, session, &now)
+
+		// The terminal delivers "go test\ngo vet\n" as keys in one burst.
+		for _, msg := range []tea.KeyMsg{runes("go test"), enter, runes("go vet"), enter} {
+			m = updateModel(t, m, msg)
+			now = now.Add(time.Millisecond)
+		}
+		assert.Empty(t, session.Messages)
+		assert.Equal(t, "go test\ngo vet\n", m.Input.Value())
+
+		now = now.Add(time.Second)
+		m = updateModel(t, m, enter)
+		require.Len(t, session.Messages, 1)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "go test\ngo vet"}}, session.Messages[0].(pipe.UserMessage).Content)
+		assert.Empty(t, m.Input.Value())
+	})
+
+	t.Run("typing sends on enter", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{}
+		now := start
+		m := newModel(t, session, &now)
+
+		m = updateModel(t, m, runes("h"))
+		now = now.Add(80 * time.Millisecond)
+		m = updateModel(t, m, runes("i"))
+		now = now.Add(150 * time.Millisecond)
+		updateModel(t, m, enter)
+		require.Len(t, session.Messages, 1)
+	})
+
+	t.Run("a bracketed paste keeps its newlines single", func(t *testing.T) {
+		t.Parallel()
+		now := start
+		m := newModel(t, &pipe.Session{}, &now)
+
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a\r\nb"), Paste: true})
+		assert.Equal(t, "a\nb", m.Input.Value())
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{})
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		m = updateModel(t, m, runes("hi"))
+		updateModel(t, m, enter)
+		require.Len(t, session.Messages, 1)
+	})
+}
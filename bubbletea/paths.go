@@ -0,0 +1,84 @@
+package bubbletea
+
+import "strings"
+
+// PathDisplay shortens the file paths shown in tool call and result blocks:
+// paths in the workspace are shown relative to it, and other paths in the
+// home directory under "~". Only the display changes; tools get the paths
+// the model wrote. The zero value shows paths as they are.
+type PathDisplay struct {
+	Root string // absolute workspace root
+	Home string // absolute home directory
+	// Full shows paths as they are. Alt+P toggles it.
+	Full bool
+}
+
+// Shorten returns s with the paths in it shortened.
+func (p PathDisplay) Shorten(s string) string {
+	if p.Full {
+		return s
+	}
+	if p.Root != "" {
+		s = replaceDir(s, p.Root, ".")
+	}
+	if p.Home != "" {
+		s = replaceDir(s, p.Home, "~")
+	}
+	return s
+}
+
+// SetPathDisplayMsg tells tool blocks how to display paths. Sent by the
+// root model when Alt+P toggles full paths.
+type SetPathDisplayMsg struct{ Paths PathDisplay }
+
+// replaceDir replaces dir where it starts a path in s with short:
+// "dir/a.go" becomes "short/a.go", or "a.go" when short is ".", and "dir"
+// alone becomes short. Occurrences inside a longer path, such as
+// "/srv/dir" or "dir2", are left alone.
+func replaceDir(s, dir, short string) string {
+	dir = strings.TrimRight(dir, "/")
+	if dir == "" {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, dir)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(dir)
+		if (i > 0 && isPathByte(s[i-1])) || (end < len(s) && s[end] != '/' && isPathByte(s[end])) {
+			b.WriteString(s[:end])
+			s = s[end:]
+			continue
+		}
+		b.WriteString(s[:i])
+		if short == "." && end+1 < len(s) && s[end] == '/' && s[end+1] != '/' && isPathByte(s[end+1]) {
+			end++ // "dir/a.go" becomes "a.go"
+		} else {
+			b.WriteString(short)
+		}
+		s = s[end:]
+	}
+}
+
+// isPathByte reports whether c may appear in a path next to a directory
+// name, so that the name is part of a longer one.
+func isPathByte(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.IndexByte("/._-~", c) >= 0
+}
+
+// togglePaths switches the tool blocks between shortened and full paths.
+func (m Model) togglePaths() Model {
+	m.config.Paths.Full = !m.config.Paths.Full
+	for i, b := range m.blocks {
+		m.blocks[i], _ = b.Update(SetPathDisplayMsg{Paths: m.config.Paths})
+	}
+	m.Viewport.SetContent(m.renderContent())
+	return m
+}
@@ -0,0 +1,75 @@
+package bubbletea_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathDisplay_Shorten(t *testing.T) {
+	t.Parallel()
+
+	paths := bt.PathDisplay{Root: "/home/ann/src/pipe", Home: "/home/ann"}
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"in the workspace", "file_path: /home/ann/src/pipe/loop.go", "file_path: loop.go"},
+		{"the workspace itself", "cd /home/ann/src/pipe && go test", "cd . && go test"},
+		{"the workspace with a slash", "ls /home/ann/src/pipe/", "ls ./"},
+		{"in the home directory", "cat /home/ann/.gitconfig", "cat ~/.gitconfig"},
+		{"quoted", `{"path":"/home/ann/src/pipe/json"}`, `{"path":"json"}`},
+		{"several", "/home/ann/src/pipe/a.go:1\n/home/ann/src/pipe/b.go:2", "a.go:1\nb.go:2"},
+		{"a sibling directory", "/home/ann/src/pipe2/x.go", "~/src/pipe2/x.go"},
+		{"inside another path", "/srv/home/ann/x", "/srv/home/ann/x"},
+		{"outside", "/etc/hosts", "/etc/hosts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, paths.Shorten(tt.in))
+		})
+	}
+
+	t.Run("full", func(t *testing.T) {
+		t.Parallel()
+		full := paths
+		full.Full = true
+		assert.Equal(t, "/home/ann/src/pipe/loop.go", full.Shorten("/home/ann/src/pipe/loop.go"))
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, "/home/ann/x", bt.PathDisplay{}.Shorten("/home/ann/x"))
+	})
+}
+
+func TestModel_PathDisplay(t *testing.T) {
+	t.Parallel()
+
+	session := &pipe.Session{Messages: []pipe.Message{
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "read it"}}},
+		pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.ToolCallBlock{
+			ID: "c1", Name: "read", Arguments: json.RawMessage(`{"file_path":"/work/pipe/loop.go"}`),
+		}}},
+		pipe.ToolResultMessage{ToolCallID: "c1", ToolName: "read", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "/work/pipe/loop.go: 12 lines"}}},
+	}}
+	m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{Paths: bt.PathDisplay{Root: "/work/pipe"}})
+	m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	view := bt.RenderContent(m)
+	assert.Contains(t, view, "▶ read loop.go")
+	assert.Contains(t, view, "loop.go: 12 lines")
+	assert.NotContains(t, view, "/work/pipe")
+
+	m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p"), Alt: true})
+	view = bt.RenderContent(m)
+	assert.Contains(t, view, "▶ read /work/pipe/loop.go")
+	assert.Contains(t, view, "/work/pipe/loop.go: 12 lines")
+	assert.Empty(t, m.Input.Value(), "the toggle is not typed")
+}
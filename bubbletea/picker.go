@@ -0,0 +1,115 @@
+package bubbletea
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/fwojciec/pipe"
+)
+
+// SessionEntry summarizes a saved session for the resume picker.
+type SessionEntry struct {
+	Path      string
+	UpdatedAt time.Time
+	Preview   string // first user message
+	Tokens    int    // input and output tokens over the session
+}
+
+// SessionPicker is a full-screen list of saved sessions. Enter selects the
+// highlighted one; Esc, q, or Ctrl+C quit without a selection.
+type SessionPicker struct {
+	entries  []SessionEntry
+	cursor   int
+	selected string
+	width    int
+	height   int
+	locale   pipe.Locale
+	styles   Styles
+}
+
+// NewSessionPicker creates a picker listing entries in the given order.
+func NewSessionPicker(entries []SessionEntry, theme pipe.Theme, locale pipe.Locale) SessionPicker {
+	return SessionPicker{entries: entries, locale: locale, styles: NewStyles(theme)}
+}
+
+// Selected returns the path of the chosen session, or "" if none was chosen.
+func (p SessionPicker) Selected() string {
+	return p.selected
+}
+
+// Init implements tea.Model.
+func (p SessionPicker) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model.
+func (p SessionPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.width, p.height = msg.Width, msg.Height
+	case tea.KeyMsg:
+		page := max(p.height-1, 1)
+		switch msg.String() {
+		case "up", "k", "ctrl+p":
+			p.cursor--
+		case "down", "j", "ctrl+n":
+			p.cursor++
+		case "pgup":
+			p.cursor -= page
+		case "pgdown":
+			p.cursor += page
+		case "home", "g":
+			p.cursor = 0
+		case "end", "G":
+			p.cursor = len(p.entries) - 1
+		case "enter":
+			if len(p.entries) > 0 {
+				p.selected = p.entries[p.cursor].Path
+			}
+			return p, tea.Quit
+		case "esc", "q", "ctrl+c":
+			return p, tea.Quit
+		}
+		p.cursor = max(0, min(p.cursor, len(p.entries)-1))
+	}
+	return p, nil
+}
+
+// View implements tea.Model.
+func (p SessionPicker) View() string {
+	lines := []string{p.styles.Muted.Render(ansi.Truncate("Resume session · ↑/↓ select · Enter open · Esc quit", p.width, "…"))}
+	rows := max(p.height-1, 0)
+	first := max(0, p.cursor-rows+1)
+	for i := first; i < len(p.entries) && len(lines) < p.height; i++ {
+		label := p.label(p.entries[i])
+		if i == p.cursor {
+			lines = append(lines, p.styles.Accent.Render(ansi.Truncate("› "+label, p.width, "…")))
+			continue
+		}
+		lines = append(lines, ansi.Truncate("  "+label, p.width, "…"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// label is the one-line entry for a session: when it was last saved, its
+// token total, and what it started with.
+func (p SessionPicker) label(e SessionEntry) string {
+	preview := firstNonBlankLine(e.Preview)
+	if preview == "" {
+		preview = "(no prompt)"
+	}
+	return p.locale.DateTime(e.UpdatedAt) + "  " + formatTokens(p.locale, e.Tokens) + " tokens  " + preview
+}
+
+// PickSession shows the picker and returns the path of the chosen session,
+// or "" if the user quit without choosing. The context works as for Run.
+func PickSession(ctx context.Context, entries []SessionEntry, theme pipe.Theme, locale pipe.Locale) (string, error) {
+	final, err := runProgram(ctx, NewSessionPicker(entries, theme, locale))
+	if err != nil {
+		return "", err
+	}
+	return final.(SessionPicker).Selected(), nil
+}
@@ -0,0 +1,60 @@
+package bubbletea_test
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionPicker(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 3, 1, 14, 30, 0, 0, time.UTC)
+	entries := []bt.SessionEntry{
+		{Path: "/s/b.json", UpdatedAt: at, Preview: "fix the build\nand more", Tokens: 12300},
+		{Path: "/s/a.json", UpdatedAt: at.Add(-time.Hour), Preview: "", Tokens: 950},
+	}
+	picker := func(t *testing.T) bt.SessionPicker {
+		t.Helper()
+		p := bt.NewSessionPicker(entries, pipe.DefaultTheme(), pipe.Locale{Location: time.UTC})
+		return update(t, p, tea.WindowSizeMsg{Width: 80, Height: 10})
+	}
+
+	t.Run("lists sessions with time, tokens, and prompt", func(t *testing.T) {
+		t.Parallel()
+		view := ansi.Strip(picker(t).View())
+		assert.Contains(t, view, "› 03/01/2026 2:30 PM  12.3k tokens  fix the build")
+		assert.Contains(t, view, "  03/01/2026 1:30 PM  950 tokens  (no prompt)")
+		assert.NotContains(t, view, "and more")
+	})
+
+	t.Run("enter selects the highlighted session", func(t *testing.T) {
+		t.Parallel()
+		p := update(t, picker(t), tea.KeyMsg{Type: tea.KeyDown})
+		p = update(t, p, tea.KeyMsg{Type: tea.KeyDown})
+		updated, cmd := p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Equal(t, "/s/a.json", updated.(bt.SessionPicker).Selected(), "the cursor stops at the last entry")
+		require.NotNil(t, cmd)
+		assert.Equal(t, tea.QuitMsg{}, cmd())
+	})
+
+	t.Run("esc quits without a selection", func(t *testing.T) {
+		t.Parallel()
+		p := update(t, picker(t), tea.KeyMsg{Type: tea.KeyEsc})
+		assert.Empty(t, p.Selected())
+	})
+}
+
+func update(t *testing.T, p bt.SessionPicker, msg tea.Msg) bt.SessionPicker {
+	t.Helper()
+	updated, _ := p.Update(msg)
+	picker, ok := updated.(bt.SessionPicker)
+	require.True(t, ok)
+	return picker
+}
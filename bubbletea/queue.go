@@ -0,0 +1,78 @@
+package bubbletea
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// queueInput holds text submitted during a run so it can be sent as the
+// next prompt once the run ends.
+func (m Model) queueInput(text string) Model {
+	m.queued = append(m.queued, text)
+	m.Input.SetValue("")
+	m.Input.SetHeight(1)
+	m.Viewport.Height = m.viewportHeight(1)
+	return m
+}
+
+// unqueue moves the queued messages back into the input, ahead of any
+// draft, so a cancelled or failed run doesn't send them unasked.
+func (m Model) unqueue() Model {
+	if len(m.queued) == 0 {
+		return m
+	}
+	text := strings.Join(m.queued, "\n\n")
+	if draft := m.Input.Value(); draft != "" {
+		text += "\n\n" + draft
+	}
+	m.queued = nil
+	m.Input.SetValue(text)
+	if !m.compact() {
+		m.Input.SetHeight(min(m.Input.LineCount(), m.Input.MaxHeight))
+	}
+	m.Viewport.Height = m.viewportHeight(m.Input.Height())
+	return m
+}
+
+// sendQueued starts a run with the queued messages as one prompt. It
+// returns a nil command when nothing is queued.
+func (m Model) sendQueued() (Model, tea.Cmd) {
+	if len(m.queued) == 0 {
+		return m, nil
+	}
+	text := strings.Join(m.queued, "\n\n")
+	m.queued = nil
+	// submitInput clears the input, so keep whatever the user has started
+	// typing since.
+	draft := m.Input.Value()
+	updated, cmd := m.submitInput(text)
+	m = updated.(Model)
+	m.Input.SetValue(draft)
+	if !m.compact() {
+		m.Input.SetHeight(min(max(m.Input.LineCount(), 1), m.Input.MaxHeight))
+	}
+	m.Viewport.Height = m.viewportHeight(m.Input.Height())
+	return m, cmd
+}
+
+// queueHeight is the number of lines the queued-message line takes above
+// the input.
+func (m Model) queueHeight() int {
+	if len(m.queued) == 0 || m.compact() {
+		return 0
+	}
+	return 1
+}
+
+// queueView renders the messages waiting for the run to end.
+func (m Model) queueView() string {
+	label := "Queued"
+	if n := len(m.queued); n > 1 {
+		label = fmt.Sprintf("Queued ×%d", n)
+	}
+	last := strings.Join(strings.Fields(m.queued[len(m.queued)-1]), " ")
+	hint := m.styles.Muted.Render(" (sent when the run ends · Alt+Enter interrupts · Esc restores)")
+	return truncateRight(m.styles.Accent.Render(label+": ")+last+hint, m.Viewport.Width)
+}
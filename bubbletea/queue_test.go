@@ -0,0 +1,123 @@
+package bubbletea_test
+
+import (
+	"context"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_QueueDuringRun(t *testing.T) {
+	t.Parallel()
+
+	// start opens a model on session and submits a first prompt, leaving
+	// the run in progress.
+	start := func(t *testing.T, session *pipe.Session, config bt.Config) bt.Model {
+		t.Helper()
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), config)
+		m = updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+		m = typeText(t, m, "first")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		require.True(t, m.Running())
+		return m
+	}
+
+	lastPrompt := func(t *testing.T, session *pipe.Session) string {
+		t.Helper()
+		um := session.Messages[session.LastPrompt()].(pipe.UserMessage)
+		return um.Content[0].(pipe.TextBlock).Text
+	}
+
+	t.Run("messages typed during a run are sent when it ends", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{}
+		m := start(t, session, bt.Config{})
+		height := m.Viewport.Height
+
+		m = typeText(t, m, "second")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		m = typeText(t, m, "third")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Contains(t, m.View(), "Queued ×2: third")
+		assert.Equal(t, height-1, m.Viewport.Height, "the queue takes a line from the viewport")
+		assert.Len(t, session.Messages, 1, "nothing is sent mid-run")
+
+		m = typeText(t, m, "draft")
+		m = updateModel(t, m, bt.AgentDoneMsg{})
+		assert.True(t, m.Running(), "the queued prompt starts the next run")
+		require.Len(t, session.Messages, 2)
+		assert.Equal(t, "second\n\nthird", lastPrompt(t, session))
+		assert.NotContains(t, m.View(), "Queued")
+		assert.Equal(t, "draft", m.Input.Value(), "the draft stays in the input")
+		assert.Equal(t, height, m.Viewport.Height)
+	})
+
+	t.Run("alt+enter queues and interrupts the run", func(t *testing.T) {
+		t.Parallel()
+		var cancelled bool
+		session := &pipe.Session{}
+		m := start(t, session, bt.Config{})
+		m, _ = bt.SetRunningWithCancel(m, func() { cancelled = true })
+
+		m = typeText(t, m, "stop, do this instead")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter, Alt: true})
+		assert.True(t, cancelled)
+
+		m = updateModel(t, m, bt.AgentDoneMsg{Err: context.Canceled})
+		assert.True(t, m.Running())
+		assert.Equal(t, "stop, do this instead", lastPrompt(t, session))
+	})
+
+	t.Run("ctrl+c cancels and restores the queue to the input", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{}
+		m := start(t, session, bt.Config{})
+		m = typeText(t, m, "second")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		m = typeText(t, m, "more")
+
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyCtrlC})
+		assert.Equal(t, "second\n\nmore", m.Input.Value())
+
+		m = updateModel(t, m, bt.AgentDoneMsg{Err: context.Canceled})
+		assert.False(t, m.Running())
+		assert.Len(t, session.Messages, 1)
+	})
+
+	t.Run("a failed run keeps the queue in the input", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{}
+		m := start(t, session, bt.Config{})
+		m = typeText(t, m, "second")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+
+		m = updateModel(t, m, bt.AgentDoneMsg{Err: assert.AnError})
+		assert.False(t, m.Running())
+		assert.Len(t, session.Messages, 1)
+		assert.Equal(t, "second", m.Input.Value())
+	})
+
+	t.Run("commands wait for the run to end", func(t *testing.T) {
+		t.Parallel()
+		var ran bool
+		session := &pipe.Session{}
+		m := start(t, session, bt.Config{Commands: []bt.Command{{
+			Name: "clear",
+			Run: func(string) (bt.CommandResult, error) {
+				ran = true
+				return bt.CommandResult{}, nil
+			},
+		}}})
+
+		m = typeText(t, m, "/clear")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		assert.False(t, ran)
+		assert.Equal(t, "/clear", m.Input.Value())
+		assert.Contains(t, m.View(), "Commands run when the agent is idle")
+		assert.NotContains(t, m.View(), "Queued")
+	})
+}
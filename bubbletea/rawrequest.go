@@ -0,0 +1,133 @@
+package bubbletea
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// pager is a scrollable text shown in place of the viewport.
+type pager struct {
+	title  string
+	text   string
+	offset int // first line shown
+}
+
+// completeTurn records the assistant blocks of the turn that just
+// completed as belonging to it.
+func (m Model) completeTurn() Model {
+	for _, b := range m.blocks[min(m.turnStart, len(m.blocks)):] {
+		switch b.(type) {
+		case *AssistantTextBlock, *ThinkingBlock, *ToolCallBlock, *SourcesBlock:
+			m.blockTurn[b] = m.turns
+		}
+	}
+	m.turns++
+	m.turnStart = len(m.blocks)
+	return m
+}
+
+// focusedTurn returns the turn of the focused block, or of the latest turn
+// when no block is focused. Blocks outside a response, such as tool
+// results, belong to the next turn, whose request carried them, or else to
+// the turn before them.
+func (m Model) focusedTurn() (int, bool) {
+	i := len(m.blocks) - 1
+	if m.blockFocus >= 0 && m.blockFocus < len(m.blocks) {
+		i = m.blockFocus
+	}
+	for j := i; j < len(m.blocks); j++ {
+		if turn, ok := m.blockTurn[m.blocks[j]]; ok {
+			return turn, true
+		}
+	}
+	for j := i - 1; j >= 0; j-- {
+		if turn, ok := m.blockTurn[m.blocks[j]]; ok {
+			return turn, true
+		}
+	}
+	return 0, false
+}
+
+// showRawRequest opens the provider request behind the focused turn in
+// the pager.
+func (m Model) showRawRequest() Model {
+	turn, ok := m.focusedTurn()
+	if !ok {
+		m.blocks = append(m.blocks, NewNoticeBlock("No provider request was recorded for this turn.", m.styles))
+		return m.refreshViewport()
+	}
+	text, ok := m.config.RawRequest(turn)
+	if !ok {
+		m.blocks = append(m.blocks, NewNoticeBlock("The provider request of this turn is no longer recorded.", m.styles))
+		return m.refreshViewport()
+	}
+	m.pager = &pager{title: "Raw request", text: text}
+	return m
+}
+
+// pagerLines wraps the pager text to the viewport width.
+func (m Model) pagerLines() []string {
+	return strings.Split(ansi.Hardwrap(m.pager.text, max(m.Viewport.Width, 1), true), "\n")
+}
+
+// handlePagerKey scrolls or closes the pager.
+func (m Model) handlePagerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rows := max(m.Viewport.Height-1, 1)
+	p := *m.pager
+	switch msg.String() {
+	case "up", "k":
+		p.offset--
+	case "down", "j":
+		p.offset++
+	case "pgup", "b":
+		p.offset -= rows
+	case "pgdown", " ", "f":
+		p.offset += rows
+	case "home", "g":
+		p.offset = 0
+	case "end", "G":
+		p.offset = len(m.pagerLines())
+	case "esc", "q", "ctrl+r":
+		m.pager = nil
+		return m, nil
+	}
+	p.offset = max(0, min(p.offset, len(m.pagerLines())-rows))
+	m.pager = &p
+	return m, nil
+}
+
+// handlePagerMouse scrolls the pager with the mouse wheel.
+func (m Model) handlePagerMouse(msg tea.MouseMsg) Model {
+	if msg.Action != tea.MouseActionPress {
+		return m
+	}
+	p := *m.pager
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		p.offset -= m.Viewport.MouseWheelDelta
+	case tea.MouseButtonWheelDown:
+		p.offset += m.Viewport.MouseWheelDelta
+	default:
+		return m
+	}
+	p.offset = max(0, min(p.offset, len(m.pagerLines())-max(m.Viewport.Height-1, 1)))
+	m.pager = &p
+	return m
+}
+
+// pagerView renders the pager in place of the viewport: a header line and
+// a page of text.
+func (m Model) pagerView() string {
+	width, height := m.Viewport.Width, m.Viewport.Height
+	lines := []string{m.styles.Muted.Render(ansi.Truncate(m.pager.title+" · ↑/↓ scroll · Esc close", width, "…"))}
+	text := m.pagerLines()
+	for i := m.pager.offset; i < len(text) && len(lines) < height; i++ {
+		lines = append(lines, text[i])
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines[:height], "\n")
+}
@@ -0,0 +1,70 @@
+package bubbletea_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModel_RawRequest(t *testing.T) {
+	t.Parallel()
+
+	// run streams two turns: thinking and a tool call, then an answer.
+	run := func(t *testing.T, config bt.Config) bt.Model {
+		t.Helper()
+		m := initModelWithConfig(t, nopAgent, config)
+		m = typeText(t, m, "hi")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		for _, e := range []pipe.Event{
+			pipe.EventThinkingDelta{Delta: "pondering"},
+			pipe.EventToolCallBegin{ID: "t1", Name: "bash"},
+			pipe.EventToolCallEnd{Call: pipe.ToolCallBlock{ID: "t1", Name: "bash"}},
+			pipe.EventTurnComplete{StopReason: pipe.StopToolUse},
+			pipe.EventToolResult{ID: "t1", ToolName: "bash", Content: "ok"},
+			pipe.EventTextDelta{Delta: "done"},
+			pipe.EventTurnComplete{StopReason: pipe.StopEndTurn},
+		} {
+			m = updateModel(t, m, bt.StreamEventMsg{Event: e})
+		}
+		return updateModel(t, m, bt.AgentDoneMsg{})
+	}
+	requests := func(turn int) (string, bool) {
+		return fmt.Sprintf("POST /v1/messages\n\n{\"turn\": %d}", turn), true
+	}
+
+	t.Run("shows the request of the latest turn", func(t *testing.T) {
+		t.Parallel()
+		m := run(t, bt.Config{RawRequest: requests})
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyCtrlR})
+		view := m.View()
+		assert.Contains(t, view, "Raw request")
+		assert.Contains(t, view, `{"turn": 1}`)
+		assert.Len(t, strings.Split(view, "\n"), 24)
+
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+		assert.NotContains(t, m.View(), "Raw request")
+	})
+
+	t.Run("shows the request of the focused block's turn", func(t *testing.T) {
+		t.Parallel()
+		m := run(t, bt.Config{RawRequest: requests})
+		// The tool result is focused after the run; the thinking block of
+		// the first turn is two blocks up.
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyShiftTab})
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyShiftTab})
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyCtrlR})
+		assert.Contains(t, m.View(), `{"turn": 0}`)
+	})
+
+	t.Run("reports turns without a recorded request", func(t *testing.T) {
+		t.Parallel()
+		m := run(t, bt.Config{RawRequest: func(int) (string, bool) { return "", false }})
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyCtrlR})
+		assert.Contains(t, m.View(), "no longer recorded")
+	})
+}
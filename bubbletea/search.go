@@ -0,0 +1,210 @@
+package bubbletea
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// search is the state of a transcript search. While it is open, matches
+// are highlighted in the viewport and the status line shows the query.
+type search struct {
+	query   string
+	editing bool // the query is being typed
+	current int  // index of the selected match
+	origin  int  // viewport offset when the query was started
+}
+
+// searchMatch is one occurrence of the query in the rendered content, in
+// cells of one line.
+type searchMatch struct {
+	line, start, end int
+}
+
+// pattern returns the query as a regular expression matching it literally,
+// ignoring case unless the query has an upper-case letter. It returns nil
+// for an empty query.
+func (s search) pattern() *regexp.Regexp {
+	if s.query == "" {
+		return nil
+	}
+	expr := regexp.QuoteMeta(s.query)
+	if !strings.ContainsFunc(s.query, unicode.IsUpper) {
+		expr = "(?i)" + expr
+	}
+	return regexp.MustCompile(expr)
+}
+
+// findMatches returns the matches of re in the visible text of content, in
+// order.
+func findMatches(content string, re *regexp.Regexp) []searchMatch {
+	if re == nil {
+		return nil
+	}
+	var matches []searchMatch
+	for i, line := range strings.Split(content, "\n") {
+		text := ansi.Strip(line)
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			matches = append(matches, searchMatch{
+				line:  i,
+				start: ansi.StringWidth(text[:loc[0]]),
+				end:   ansi.StringWidth(text[:loc[1]]),
+			})
+		}
+	}
+	return matches
+}
+
+// highlightMatches styles the matches in content, the current one apart
+// from the rest.
+func highlightMatches(content string, matches []searchMatch, current int, styles Styles) string {
+	if len(matches) == 0 {
+		return content
+	}
+	match := lipgloss.NewStyle().Reverse(true)
+	selected := styles.Accent.Reverse(true)
+	lines := strings.Split(content, "\n")
+	// Work from the last match so earlier cell positions stay valid.
+	for i := len(matches) - 1; i >= 0; i-- {
+		mt := matches[i]
+		style := match
+		if i == current {
+			style = selected
+		}
+		line := lines[mt.line]
+		lines[mt.line] = ansi.Cut(line, 0, mt.start) +
+			style.Render(ansi.Strip(ansi.Cut(line, mt.start, mt.end))) +
+			ansi.Cut(line, mt.end, ansi.StringWidth(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// searchMatches returns the matches of the search query in the transcript.
+func (m Model) searchMatches() []searchMatch {
+	if m.search == nil {
+		return nil
+	}
+	return findMatches(m.renderTranscript(), m.search.pattern())
+}
+
+// openSearch starts typing a new search query.
+func (m Model) openSearch() Model {
+	m.search = &search{editing: true, origin: m.Viewport.YOffset}
+	m.Viewport.SetContent(m.renderContent())
+	return m
+}
+
+// closeSearch ends the search and clears its highlights.
+func (m Model) closeSearch() Model {
+	m.search = nil
+	m.Viewport.SetContent(m.renderContent())
+	return m
+}
+
+// handleSearchKey edits the query while it is typed, and afterwards moves
+// between matches with n and N or scrolls the viewport.
+func (m Model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := *m.search
+	if s.editing {
+		switch msg.Type {
+		case tea.KeyEsc:
+			m = m.closeSearch()
+			m.Viewport.SetYOffset(s.origin)
+			return m, nil
+		case tea.KeyEnter:
+			if s.query == "" {
+				return m.closeSearch(), nil
+			}
+			s.editing = false
+			m.search = &s
+			return m, nil
+		case tea.KeyBackspace:
+			runes := []rune(s.query)
+			s.query = string(runes[:max(len(runes)-1, 0)])
+		case tea.KeyRunes, tea.KeySpace:
+			s.query += string(msg.Runes)
+		default:
+			return m, nil
+		}
+		// Select the first match from where the search started, wrapping
+		// to the top.
+		m.search = &s
+		matches := m.searchMatches()
+		s.current = 0
+		for i, mt := range matches {
+			if mt.line >= s.origin {
+				s.current = i
+				break
+			}
+		}
+		return m.showMatch(matches), nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		return m.closeSearch(), nil
+	case "/", "ctrl+f":
+		return m.openSearch(), nil
+	case "n", "N":
+		matches := m.searchMatches()
+		if len(matches) == 0 {
+			return m, nil
+		}
+		step := 1
+		if msg.String() == "N" {
+			step = -1
+		}
+		s.current = (s.current + step + len(matches)) % len(matches)
+		m.search = &s
+		return m.showMatch(matches), nil
+	}
+	if msg.Type == tea.KeyRunes {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.Viewport, cmd = m.Viewport.Update(msg)
+	return m, cmd
+}
+
+// showMatch highlights the matches and scrolls the selected one into view.
+func (m Model) showMatch(matches []searchMatch) Model {
+	m.Viewport.SetContent(m.renderContent())
+	if m.search.current >= len(matches) {
+		return m
+	}
+	line := matches[m.search.current].line
+	if line < m.Viewport.YOffset || line >= m.Viewport.YOffset+m.Viewport.Height {
+		m.Viewport.SetYOffset(line - m.Viewport.Height/2)
+	}
+	return m
+}
+
+// searchLine replaces the status line while a search is open: the query,
+// the selected match, and the keys.
+func (m Model) searchLine() string {
+	line := m.styles.Accent.Render("/") + m.search.query
+	if m.search.editing {
+		line += "▏"
+	}
+	if m.search.query != "" {
+		count := "no matches"
+		if n := len(m.searchMatches()); n > 0 {
+			count = fmt.Sprintf("%d of %d", min(m.search.current, n-1)+1, n)
+		}
+		line += "  " + m.styles.Muted.Render(count)
+	}
+	keys := "Enter search · Esc cancel"
+	if !m.search.editing {
+		keys = "n/N next/previous · / new search · Esc close"
+	}
+	line += "  " + m.styles.Muted.Render(keys)
+	return ansi.Truncate(line, m.Viewport.Width, "…")
+}
@@ -0,0 +1,105 @@
+package bubbletea_test
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_Search(t *testing.T) {
+	t.Parallel()
+
+	filler := strings.Repeat("filler\n\n", 15)
+	text := func(s string) pipe.AssistantMessage {
+		return pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: s}}}
+	}
+	newModel := func(t *testing.T) bt.Model {
+		t.Helper()
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "find the needle one"}}},
+			text(filler + "a needle two"),
+			text(filler + "Needle three\n\n" + filler),
+		}}
+		m := bt.New(nopAgent, session, pipe.DefaultTheme(), bt.Config{})
+		return updateModel(t, m, tea.WindowSizeMsg{Width: 80, Height: 24})
+	}
+	keys := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	ctrlF := tea.KeyMsg{Type: tea.KeyCtrlF}
+	esc := tea.KeyMsg{Type: tea.KeyEsc}
+	view := func(m bt.Model) string { return ansi.Strip(m.View()) }
+
+	t.Run("n and N move between matches", func(t *testing.T) {
+		t.Parallel()
+		m := newModel(t)
+		m = updateModel(t, m, ctrlF)
+		m = updateModel(t, m, keys("needle"))
+		// No match below the view, so the search wraps to the first.
+		assert.Contains(t, view(m), "/needle")
+		assert.Contains(t, view(m), "1 of 3")
+		assert.Contains(t, view(m), "find the needle one")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Contains(t, view(m), "n/N next/previous")
+
+		m = updateModel(t, m, keys("n"))
+		assert.Contains(t, view(m), "2 of 3")
+		assert.Contains(t, view(m), "a needle two")
+		assert.NotContains(t, view(m), "find the needle one")
+
+		m = updateModel(t, m, keys("N"))
+		m = updateModel(t, m, keys("N"))
+		assert.Contains(t, view(m), "3 of 3")
+		assert.Contains(t, view(m), "Needle three")
+		assert.Equal(t, "", m.Input.Value())
+
+		m = updateModel(t, m, esc)
+		assert.NotContains(t, view(m), "n/N next/previous")
+	})
+
+	t.Run("an upper-case query matches case", func(t *testing.T) {
+		t.Parallel()
+		m := newModel(t)
+		m = updateModel(t, m, ctrlF)
+		m = updateModel(t, m, keys("Needle"))
+		assert.Contains(t, view(m), "1 of 1")
+		m = updateModel(t, m, keys("x"))
+		assert.Contains(t, view(m), "no matches")
+		m = updateModel(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+		assert.Contains(t, view(m), "1 of 1")
+	})
+
+	t.Run("highlighting keeps the text", func(t *testing.T) {
+		t.Parallel()
+		m := newModel(t)
+		before := m.Viewport.View()
+		m = updateModel(t, m, ctrlF)
+		m = updateModel(t, m, keys("filler"))
+		assert.Equal(t, ansi.Strip(before), ansi.Strip(m.Viewport.View()))
+	})
+
+	t.Run("esc while typing returns to where the search started", func(t *testing.T) {
+		t.Parallel()
+		m := newModel(t)
+		bottom := m.Viewport.YOffset
+		m = updateModel(t, m, ctrlF)
+		m = updateModel(t, m, keys("needle"))
+		require.NotEqual(t, bottom, m.Viewport.YOffset)
+		m = updateModel(t, m, esc)
+		assert.Equal(t, bottom, m.Viewport.YOffset)
+		assert.NotContains(t, view(m), "/needle")
+	})
+}
+
+func TestFindMatches(t *testing.T) {
+	t.Parallel()
+
+	content := "\x1b[1m日本 go\x1b[0m and Go\n\nno match\ngogo"
+	assert.Equal(t, [][3]int{{0, 5, 7}, {0, 12, 14}, {3, 0, 2}, {3, 2, 4}}, bt.FindMatches(content, "go"))
+	assert.Equal(t, [][3]int{{0, 12, 14}}, bt.FindMatches(content, "Go"))
+	assert.Empty(t, bt.FindMatches(content, ""))
+}
@@ -0,0 +1,55 @@
+package bubbletea
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// segmentGap separates status bar segments.
+const segmentGap = "  "
+
+// StatusSegment is an extra status bar segment, such as the current
+// Kubernetes context or CI status, shown after the git branch. Segments
+// are truncated before the working directory when the line is too narrow.
+type StatusSegment struct {
+	// Refresh returns the segment's text; empty hides the segment. It runs
+	// outside the update loop, so it may block, e.g. on a command.
+	Refresh func() string
+	// Interval is how often Refresh runs. Zero runs it once at startup.
+	Interval time.Duration
+	// MaxWidth caps the segment's width in cells. Zero leaves it to the
+	// status line's own truncation.
+	MaxWidth int
+}
+
+// segmentMsg carries the refreshed text of status segment index.
+type segmentMsg struct {
+	index int
+	text  string
+}
+
+// refreshSegment runs segment index's Refresh after delay.
+func refreshSegment(index int, seg StatusSegment, delay time.Duration) tea.Cmd {
+	refresh := func() tea.Msg { return segmentMsg{index: index, text: seg.Refresh()} }
+	if delay <= 0 {
+		return refresh
+	}
+	return tea.Tick(delay, func(time.Time) tea.Msg { return refresh() })
+}
+
+// segmentsView renders the non-empty status segments, each within its
+// width budget.
+func (m Model) segmentsView() string {
+	var out string
+	for i, text := range m.segments {
+		if text == "" {
+			continue
+		}
+		if limit := m.config.StatusSegments[i].MaxWidth; limit > 0 {
+			text = truncateRight(text, limit)
+		}
+		out += segmentGap + m.styles.Muted.Render(text)
+	}
+	return out
+}
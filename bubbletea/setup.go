@@ -0,0 +1,231 @@
+package bubbletea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/fwojciec/pipe"
+)
+
+// SetupStep is one question asked by the Setup wizard.
+type SetupStep struct {
+	Title string // e.g. "Provider"
+	Help  string // shown under the title
+	// Choices lists the answers to pick from, given the answers to the
+	// earlier steps. It runs in the background while the step shows a
+	// loading line; an error or an empty list falls back to a typed
+	// answer. Nil asks for a typed answer.
+	Choices func(answers []string) ([]string, error)
+	// Secret masks the typed answer, e.g. an API key.
+	Secret bool
+}
+
+// setupChoicesMsg carries the choices loaded for step.
+type setupChoicesMsg struct {
+	step    int
+	choices []string
+	err     error
+}
+
+// Setup is a full-screen wizard that asks its steps in order. Enter
+// confirms an answer; Esc or Ctrl+C quit without finishing.
+type Setup struct {
+	steps   []SetupStep
+	answers []string
+
+	loading bool
+	choices []string
+	err     error // why the step's choices fell back to typing
+	cursor  int
+	input   textinput.Model
+
+	done   bool
+	width  int
+	height int
+	styles Styles
+}
+
+// NewSetup creates a wizard asking steps, which must not be empty.
+func NewSetup(steps []SetupStep, theme pipe.Theme) Setup {
+	input := textinput.New()
+	input.Prompt = "› "
+	return Setup{steps: steps, input: input, styles: NewStyles(theme)}.prepare()
+}
+
+// Answers returns the answer to each step, and false if the user quit
+// before answering them all.
+func (s Setup) Answers() ([]string, bool) {
+	return s.answers, s.done
+}
+
+// Init implements tea.Model.
+func (s Setup) Init() tea.Cmd {
+	return s.stepCmd()
+}
+
+// step returns the step being asked.
+func (s Setup) step() SetupStep {
+	return s.steps[len(s.answers)]
+}
+
+// prepare resets the view for the current step.
+func (s Setup) prepare() Setup {
+	step := s.step()
+	s.choices, s.err, s.cursor = nil, nil, 0
+	s.loading = step.Choices != nil
+	s.input.Reset()
+	s.input.EchoMode = textinput.EchoNormal
+	if step.Secret {
+		s.input.EchoMode = textinput.EchoPassword
+	}
+	if s.loading {
+		s.input.Blur()
+	} else {
+		s.input.Focus()
+	}
+	return s
+}
+
+// stepCmd loads the current step's choices in the background, or blinks
+// the cursor of its text input.
+func (s Setup) stepCmd() tea.Cmd {
+	choices := s.step().Choices
+	if choices == nil {
+		return textinput.Blink
+	}
+	index, answers := len(s.answers), append([]string(nil), s.answers...)
+	return func() tea.Msg {
+		list, err := choices(answers)
+		return setupChoicesMsg{step: index, choices: list, err: err}
+	}
+}
+
+// Update implements tea.Model.
+func (s Setup) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.width, s.height = msg.Width, msg.Height
+		return s, nil
+	case setupChoicesMsg:
+		if s.done || msg.step != len(s.answers) {
+			return s, nil
+		}
+		s.loading = false
+		switch {
+		case msg.err != nil:
+			s.err = msg.err
+		case len(msg.choices) == 0:
+			s.err = errors.New("nothing to choose from")
+		default:
+			s.choices = msg.choices
+			return s, nil
+		}
+		return s, s.input.Focus()
+	case tea.KeyMsg:
+		return s.handleKey(msg)
+	}
+	return s, nil
+}
+
+func (s Setup) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		return s, tea.Quit
+	}
+	if s.loading {
+		return s, nil
+	}
+	if s.choices != nil {
+		switch msg.String() {
+		case "up", "k", "ctrl+p":
+			s.cursor = max(s.cursor-1, 0)
+		case "down", "j", "ctrl+n":
+			s.cursor = min(s.cursor+1, len(s.choices)-1)
+		case "enter":
+			return s.answer(s.choices[s.cursor])
+		}
+		return s, nil
+	}
+	if msg.Type == tea.KeyEnter {
+		answer := strings.TrimSpace(s.input.Value())
+		if answer == "" {
+			return s, nil
+		}
+		return s.answer(answer)
+	}
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+	return s, cmd
+}
+
+// answer records the current step's answer and moves to the next step,
+// quitting after the last.
+func (s Setup) answer(text string) (tea.Model, tea.Cmd) {
+	s.answers = append(s.answers, text)
+	if len(s.answers) == len(s.steps) {
+		s.done = true
+		return s, tea.Quit
+	}
+	s = s.prepare()
+	return s, s.stepCmd()
+}
+
+// View implements tea.Model.
+func (s Setup) View() string {
+	if s.done || len(s.answers) >= len(s.steps) {
+		return ""
+	}
+	step := s.step()
+	header := fmt.Sprintf("Set up pipe · step %d of %d · Enter confirm · Esc quit", len(s.answers)+1, len(s.steps))
+	lines := []string{s.styles.Muted.Render(header), "", s.styles.Accent.Render(step.Title)}
+	if step.Help != "" {
+		lines = append(lines, step.Help)
+	}
+	lines = append(lines, "")
+	top := len(lines)
+	switch {
+	case s.loading:
+		lines = append(lines, s.styles.Muted.Render("Loading…"))
+	case s.choices != nil:
+		for i, choice := range s.choices {
+			if i == s.cursor {
+				lines = append(lines, s.styles.Accent.Render("› "+choice))
+				continue
+			}
+			lines = append(lines, "  "+choice)
+		}
+	default:
+		if s.err != nil {
+			lines = append(lines, s.styles.Error.Render(fmt.Sprintf("Could not load choices: %s. Type the answer instead.", s.err)))
+		}
+		lines = append(lines, s.input.View())
+	}
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(line, s.width, "…")
+	}
+	if s.height > 0 && len(lines) > s.height {
+		// Keep the highlighted choice on screen.
+		first := min(max(0, top+s.cursor-s.height+1), len(lines)-s.height)
+		lines = lines[first : first+s.height]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RunSetup shows the wizard and returns the answer to each step, or nil if
+// the user quit before finishing. The context works as for Run.
+func RunSetup(ctx context.Context, steps []SetupStep, theme pipe.Theme) ([]string, error) {
+	final, err := runProgram(ctx, NewSetup(steps, theme))
+	if err != nil {
+		return nil, err
+	}
+	answers, ok := final.(Setup).Answers()
+	if !ok {
+		return nil, nil
+	}
+	return answers, nil
+}
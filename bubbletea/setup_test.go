@@ -0,0 +1,77 @@
+package bubbletea_test
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup(t *testing.T) {
+	t.Parallel()
+
+	var modelAnswers []string
+	steps := []bt.SetupStep{
+		{Title: "Provider", Choices: func([]string) ([]string, error) { return []string{"anthropic", "gemini"}, nil }},
+		{Title: "API key", Help: "Paste your key.", Secret: true},
+		{Title: "Model", Choices: func(answers []string) ([]string, error) {
+			modelAnswers = answers
+			return nil, assert.AnError
+		}},
+	}
+	// step sends msg to the wizard.
+	step := func(t *testing.T, s bt.Setup, msg tea.Msg) (bt.Setup, tea.Cmd) {
+		t.Helper()
+		updated, cmd := s.Update(msg)
+		s, ok := updated.(bt.Setup)
+		require.True(t, ok)
+		return s, cmd
+	}
+	keys := func(text string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)} }
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	s := bt.NewSetup(steps, pipe.DefaultTheme())
+	s, _ = step(t, s, tea.WindowSizeMsg{Width: 80, Height: 20})
+	assert.Contains(t, ansi.Strip(s.View()), "Loading…")
+
+	// The provider choices load in the background.
+	s, _ = step(t, s, s.Init()())
+	view := ansi.Strip(s.View())
+	assert.Contains(t, view, "step 1 of 3")
+	assert.Contains(t, view, "› anthropic")
+	s, _ = step(t, s, tea.KeyMsg{Type: tea.KeyDown})
+	s, _ = step(t, s, enter)
+
+	// The key is masked and must not be empty.
+	s, _ = step(t, s, enter)
+	assert.Contains(t, ansi.Strip(s.View()), "API key")
+	s, _ = step(t, s, keys("sk-secret"))
+	view = ansi.Strip(s.View())
+	assert.Contains(t, view, "Paste your key.")
+	assert.NotContains(t, view, "sk-secret")
+	s, cmd := step(t, s, enter)
+
+	// Failing to list models falls back to typing one.
+	s, _ = step(t, s, cmd())
+	assert.Equal(t, []string{"gemini", "sk-secret"}, modelAnswers)
+	assert.Contains(t, ansi.Strip(s.View()), "Could not load choices")
+	s, _ = step(t, s, keys("gemini-2.5-pro"))
+	s, cmd = step(t, s, enter)
+	require.NotNil(t, cmd)
+
+	answers, ok := s.Answers()
+	require.True(t, ok)
+	assert.Equal(t, []string{"gemini", "sk-secret", "gemini-2.5-pro"}, answers)
+
+	t.Run("esc quits without answers", func(t *testing.T) {
+		t.Parallel()
+		s := bt.NewSetup(steps[1:2], pipe.DefaultTheme())
+		s, _ = step(t, s, tea.KeyMsg{Type: tea.KeyEsc})
+		_, ok := s.Answers()
+		assert.False(t, ok)
+	})
+}
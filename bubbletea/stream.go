@@ -0,0 +1,123 @@
+package bubbletea
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+const (
+	// stallThreshold is how long the stream may go without an event before
+	// the status bar reports it as stalled.
+	stallThreshold = 3 * time.Second
+	// rateWindow is the span of recent output the tokens/sec figure averages
+	// over.
+	rateWindow = 2 * time.Second
+)
+
+// rateSample records output characters received at a point in time.
+type rateSample struct {
+	at    time.Time
+	chars int
+}
+
+// streamMeter derives throughput and liveness of the current run from event
+// arrival times.
+type streamMeter struct {
+	lastEvent    time.Time
+	firstOutput  time.Time    // arrival of the first output delta of the run
+	samples      []rateSample // output deltas within rateWindow
+	pendingTools int          // local tool calls executing; no stream is open
+	retryAt      time.Time    // when a pending retry of a failed request starts
+}
+
+// newStreamMeter starts measuring a run that began at now.
+func newStreamMeter(now time.Time) streamMeter {
+	return streamMeter{lastEvent: now}
+}
+
+// observe records the arrival of evt at now.
+func (s streamMeter) observe(evt pipe.Event, now time.Time) streamMeter {
+	s.lastEvent = now
+	chars := 0
+	switch e := evt.(type) {
+	case pipe.EventTextDelta:
+		chars = len(e.Delta)
+	case pipe.EventThinkingDelta:
+		chars = len(e.Delta)
+	case pipe.EventToolCallDelta:
+		chars = len(e.Delta)
+	case pipe.EventToolCallEnd:
+		s.pendingTools++
+	case pipe.EventToolResult:
+		if s.pendingTools > 0 {
+			s.pendingTools--
+		}
+	case pipe.EventRetry:
+		// The stream is not stalled while the retry waits.
+		s.retryAt = now.Add(e.Delay)
+		s.lastEvent = s.retryAt
+	}
+	if chars > 0 {
+		if s.firstOutput.IsZero() {
+			s.firstOutput = now
+		}
+		s.samples = append(s.prune(now), rateSample{at: now, chars: chars})
+	}
+	return s
+}
+
+// prune returns the samples still inside rateWindow at now.
+func (s streamMeter) prune(now time.Time) []rateSample {
+	cutoff := now.Add(-rateWindow)
+	i := 0
+	for i < len(s.samples) && !s.samples[i].at.After(cutoff) {
+		i++
+	}
+	return s.samples[i:]
+}
+
+// tokensPerSecond estimates output throughput over the recent window. It
+// returns zero until output has been arriving for a moment.
+func (s streamMeter) tokensPerSecond(now time.Time) float64 {
+	start := now.Add(-rateWindow)
+	if s.firstOutput.After(start) {
+		start = s.firstOutput
+	}
+	elapsed := now.Sub(start)
+	if elapsed < 100*time.Millisecond {
+		return 0
+	}
+	chars := 0
+	for _, sample := range s.prune(now) {
+		chars += sample.chars
+	}
+	return float64(chars) / pipe.CharsPerToken / elapsed.Seconds()
+}
+
+// status renders the health indicator for the status bar: "waiting" before
+// the first output, "42 tok/s · streaming", "retrying in 3s…" while a failed
+// request waits to be retried, or "stalled 5s" once no event has arrived for
+// stallThreshold. It returns "" while local tools run, since no stream is
+// open then.
+func (s streamMeter) status(now time.Time, styles Styles) string {
+	if s.pendingTools > 0 || s.lastEvent.IsZero() {
+		return ""
+	}
+	if wait := s.retryAt.Sub(now); wait > 0 {
+		return styles.Error.Render(fmt.Sprintf("retrying in %ds…", int(math.Ceil(wait.Seconds()))))
+	}
+	if idle := now.Sub(s.lastEvent); idle > stallThreshold {
+		return styles.Error.Render(fmt.Sprintf("stalled %ds", int(idle.Seconds())))
+	}
+	if s.firstOutput.IsZero() {
+		return styles.Muted.Render("waiting")
+	}
+	health := "streaming"
+	if rate := s.tokensPerSecond(now); rate > 0 {
+		health = fmt.Sprintf("%.0f tok/s · %s", rate, health)
+	}
+	return styles.Muted.Render(health)
+}
@@ -0,0 +1,27 @@
+package bubbletea
+
+// viewCache holds a block's last rendering. The viewport draws every block
+// on each event, so caching lets a long session re-render only the blocks
+// that changed. A block calls changed after each change to what it shows
+// and renders through view.
+type viewCache struct {
+	version  uint64 // changes made to the block
+	rendered uint64 // the version text shows
+	width    int    // the width text was rendered at
+	text     string
+	valid    bool
+}
+
+// changed invalidates the cached rendering.
+func (c *viewCache) changed() {
+	c.version++
+}
+
+// view returns the rendering at width, calling render only when the block
+// changed or the width differs from the last call.
+func (c *viewCache) view(width int, render func(width int) string) string {
+	if !c.valid || c.rendered != c.version || c.width != width {
+		c.text, c.rendered, c.width, c.valid = render(width), c.version, width, true
+	}
+	return c.text
+}
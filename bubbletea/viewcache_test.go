@@ -0,0 +1,85 @@
+package bubbletea_test
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBlockViewCache checks that blocks, which cache their last rendering,
+// show each change made after a view was cached.
+func TestBlockViewCache(t *testing.T) {
+	t.Parallel()
+
+	styles := bt.NewStyles(pipe.DefaultTheme())
+	view := func(b bt.MessageBlock, width int) string { return ansi.Strip(b.View(width)) }
+
+	t.Run("assistant text", func(t *testing.T) {
+		t.Parallel()
+		b := bt.NewAssistantTextBlock(pipe.DefaultTheme())
+		b.Append("Hello")
+		assert.Contains(t, view(b, 60), "Hello")
+		b.Append(" world")
+		assert.Contains(t, view(b, 60), "Hello world")
+		b.AddFootnote(1)
+		assert.Contains(t, view(b, 60), "[1]")
+	})
+
+	t.Run("tool call", func(t *testing.T) {
+		t.Parallel()
+		b := bt.NewToolCallBlock("bash", "c1", styles)
+		b.AppendArgs(`{"command":"go `)
+		assert.Contains(t, view(b, 60), "▶ bash")
+		b.AppendArgs(`test"}`)
+		assert.Contains(t, view(b, 60), "go test")
+		b.Update(bt.ToggleMsg{})
+		assert.Contains(t, view(b, 60), "▼ bash")
+	})
+
+	t.Run("tool result", func(t *testing.T) {
+		t.Parallel()
+		b := bt.NewToolResultBlock("read", "/home/me/app/main.go", false, styles)
+		b.Update(bt.SetCollapsedMsg{Collapsed: false})
+		assert.Contains(t, view(b, 60), "/home/me/app/main.go")
+		b.Update(bt.SetPathDisplayMsg{Paths: bt.PathDisplay{Root: "/home/me/app"}})
+		assert.NotContains(t, view(b, 60), "/home/me/app/main.go")
+	})
+
+	t.Run("thinking and sources", func(t *testing.T) {
+		t.Parallel()
+		thinking := bt.NewThinkingBlock(styles)
+		thinking.Append("hmm")
+		assert.NotContains(t, view(thinking, 60), "hmm")
+		thinking.Update(bt.ToggleMsg{})
+		assert.Contains(t, view(thinking, 60), "hmm")
+
+		sources := bt.NewSourcesBlock(styles)
+		assert.Contains(t, view(sources, 60), "Sources (0)")
+		sources.Add(pipe.Citation{Title: "README"})
+		assert.Contains(t, view(sources, 60), "Sources (1)")
+	})
+
+	t.Run("question and approval", func(t *testing.T) {
+		t.Parallel()
+		question := bt.NewQuestionBlock("Which?", nil, styles)
+		assert.NotContains(t, view(question, 60), "→ this")
+		question.SetAnswer("this")
+		assert.Contains(t, view(question, 60), "→ this")
+
+		approval := bt.NewApprovalBlock(pipe.ToolCallBlock{Name: "bash"}, styles)
+		assert.NotContains(t, view(approval, 60), "→ denied")
+		approval.SetDecision(pipe.DecisionDeny)
+		assert.Contains(t, view(approval, 60), "→ denied")
+	})
+
+	t.Run("width", func(t *testing.T) {
+		t.Parallel()
+		b := bt.NewUserMessageBlock("a fairly long prompt that wraps at a narrow width", styles)
+		wide := view(b, 80)
+		assert.NotEqual(t, wide, view(b, 20))
+		assert.Equal(t, wide, view(b, 80))
+	})
+}
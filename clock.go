@@ -0,0 +1,35 @@
+package pipe
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock tells the time and waits. The loop reads it for message and
+// session timestamps and waits on it between retries, so tests and replays
+// can produce the same session every time.
+type Clock interface {
+	Now() time.Time
+	// After sends the time on the returned channel once d has passed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock returns the [Clock] of the system's wall time.
+func SystemClock() Clock { return systemClock{} }
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// NewSession returns an empty session with systemPrompt, created now by
+// clock. Its ID is the creation time in nanoseconds.
+func NewSession(clock Clock, systemPrompt string) Session {
+	now := clock.Now()
+	return Session{
+		ID:           fmt.Sprintf("%d", now.UnixNano()),
+		SystemPrompt: systemPrompt,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
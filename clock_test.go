@@ -0,0 +1,24 @@
+package pipe_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSession(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := &mock.Clock{NowFn: func() time.Time { return now }}
+
+	s := pipe.NewSession(clock, "Be brief.")
+	assert.Equal(t, pipe.Session{
+		ID:           "1772355600000000000",
+		SystemPrompt: "Be brief.",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}, s)
+}
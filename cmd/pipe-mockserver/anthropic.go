@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// mockSignature signs scripted thinking; clients send it back unverified.
+const mockSignature = "pipe-mockserver"
+
+// writeAnthropic streams resp, the response to request n, as Messages API
+// server-sent events.
+func writeAnthropic(w http.ResponseWriter, resp response, n int, model string) {
+	e := newEventWriter(w, resp)
+	e.send("message_start", map[string]any{
+		"type": "message_start",
+		"message": map[string]any{
+			"id":            fmt.Sprintf("msg_mock_%d", n+1),
+			"type":          "message",
+			"role":          "assistant",
+			"model":         model,
+			"content":       []any{},
+			"stop_reason":   nil,
+			"stop_sequence": nil,
+			"usage":         map[string]int{"input_tokens": resp.Usage.InputTokens, "output_tokens": 0},
+		},
+	})
+
+	index := 0
+	block := func(start map[string]any, deltas []map[string]any) {
+		e.send("content_block_start", map[string]any{"type": "content_block_start", "index": index, "content_block": start})
+		for _, d := range deltas {
+			e.send("content_block_delta", map[string]any{"type": "content_block_delta", "index": index, "delta": d})
+		}
+		e.send("content_block_stop", map[string]any{"type": "content_block_stop", "index": index})
+		index++
+	}
+	if resp.Thinking != "" {
+		var deltas []map[string]any
+		for _, c := range resp.chunks(resp.Thinking) {
+			deltas = append(deltas, map[string]any{"type": "thinking_delta", "thinking": c})
+		}
+		deltas = append(deltas, map[string]any{"type": "signature_delta", "signature": mockSignature})
+		block(map[string]any{"type": "thinking", "thinking": ""}, deltas)
+	}
+	if resp.Text != "" {
+		var deltas []map[string]any
+		for _, c := range resp.chunks(resp.Text) {
+			deltas = append(deltas, map[string]any{"type": "text_delta", "text": c})
+		}
+		block(map[string]any{"type": "text", "text": ""}, deltas)
+	}
+	for i, c := range resp.ToolCalls {
+		var deltas []map[string]any
+		for _, chunk := range resp.chunks(string(c.arguments())) {
+			deltas = append(deltas, map[string]any{"type": "input_json_delta", "partial_json": chunk})
+		}
+		block(map[string]any{"type": "tool_use", "id": toolCallID(c, n, i), "name": c.Name, "input": map[string]any{}}, deltas)
+	}
+
+	e.send("message_delta", map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": resp.stopReason(), "stop_sequence": nil},
+		"usage": map[string]int{"output_tokens": resp.Usage.OutputTokens},
+	})
+	e.send("message_stop", map[string]any{"type": "message_stop"})
+}
+
+// writeAnthropicError answers with e as a Messages API error.
+func writeAnthropicError(w http.ResponseWriter, e apiError) {
+	errType := e.Type
+	if errType == "" {
+		errType = "api_error"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"type":  "error",
+		"error": map[string]string{"type": errType, "message": e.Message},
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// geminiFinishReason returns Gemini's finish reason for the scenario's stop
+// reason, or "" for one Gemini has no counterpart of. Gemini finishes tool
+// calls with STOP.
+func geminiFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "tool_use":
+		return "STOP"
+	case "max_tokens":
+		return "MAX_TOKENS"
+	}
+	return ""
+}
+
+// writeGemini streams resp, the response to request n, as
+// streamGenerateContent server-sent events: one chunk per text delta, then
+// the tool calls, then the finish reason with the usage.
+func writeGemini(w http.ResponseWriter, resp response, n int, model string) {
+	e := newEventWriter(w, resp)
+	chunk := func(part map[string]any) {
+		e.send("", map[string]any{
+			"candidates":   []any{map[string]any{"content": map[string]any{"role": "model", "parts": []any{part}}, "index": 0}},
+			"modelVersion": model,
+		})
+	}
+	for _, c := range resp.chunks(resp.Thinking) {
+		chunk(map[string]any{"text": c, "thought": true})
+	}
+	for _, c := range resp.chunks(resp.Text) {
+		chunk(map[string]any{"text": c})
+	}
+	for i, c := range resp.ToolCalls {
+		chunk(map[string]any{"functionCall": map[string]any{"id": toolCallID(c, n, i), "name": c.Name, "args": c.arguments()}})
+	}
+	e.send("", map[string]any{
+		"candidates": []any{map[string]any{
+			"content":      map[string]any{"role": "model", "parts": []any{map[string]any{"text": ""}}},
+			"finishReason": geminiFinishReason(resp.stopReason()),
+			"index":        0,
+		}},
+		"usageMetadata": map[string]int{
+			"promptTokenCount":     resp.Usage.InputTokens,
+			"candidatesTokenCount": resp.Usage.OutputTokens,
+			"totalTokenCount":      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		},
+		"modelVersion": model,
+	})
+}
+
+// geminiStatus names the error status of an HTTP code, or UNKNOWN for an
+// uncommon one.
+func geminiStatus(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	case http.StatusInternalServerError:
+		return "INTERNAL"
+	}
+	return "UNKNOWN"
+}
+
+// writeGeminiError answers with e as a Gemini API error.
+func writeGeminiError(w http.ResponseWriter, e apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"code": e.Status, "message": e.Message, "status": geminiStatus(e.Status)},
+	})
+}
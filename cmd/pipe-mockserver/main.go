@@ -0,0 +1,93 @@
+// Command pipe-mockserver serves scripted model responses over the Anthropic
+// Messages and Gemini streaming protocols, so integration tests can run pipe,
+// or programs embedding it, end to end over HTTP without API calls.
+//
+// Usage:
+//
+//	pipe-mockserver [-addr 127.0.0.1:8089] [-scenario file.json]
+//
+// Point the clients at it with ANTHROPIC_BASE_URL=http://ADDR and
+// GOOGLE_GEMINI_BASE_URL=http://ADDR (or anthropic.WithBaseURL and
+// gemini.WithBaseURL), with any API key.
+//
+// A scenario lists the responses to serve, one per request in order,
+// whichever protocol asks:
+//
+//	{
+//	  "responses": [
+//	    {"tool_calls": [{"name": "bash", "arguments": {"command": "ls"}}]},
+//	    {"text": "There are two files.", "usage": {"input_tokens": 120, "output_tokens": 8}},
+//	    {"error": {"status": 529, "type": "overloaded_error", "message": "Overloaded"}}
+//	  ],
+//	  "loop": false
+//	}
+//
+// A response may also set thinking, stop_reason (end_turn, tool_use,
+// max_tokens), chunk_size (characters per text delta), and delay_ms (pause
+// between streamed events). With loop set, the responses repeat; otherwise
+// requests after the last get an error. Without -scenario, every request is
+// answered with a short greeting.
+//
+// GET /requests returns the request bodies received so far as a JSON array,
+// for tests to check what the client sent; DELETE /requests clears it and
+// restarts the scenario.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// shutdownTimeout bounds how long exit waits for open streams.
+const shutdownTimeout = 5 * time.Second
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "pipe-mockserver: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		addr         = flag.String("addr", "127.0.0.1:8089", "Address to listen on; port 0 picks a free one")
+		scenarioPath = flag.String("scenario", "", "Path to a scenario file (default: answer every request with a greeting)")
+	)
+	flag.Parse()
+
+	sc := defaultScenario()
+	if *scenarioPath != "" {
+		var err error
+		if sc, err = loadScenario(*scenarioPath); err != nil {
+			return err
+		}
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	// Print the address so scripts starting the server on port 0 can find it.
+	fmt.Printf("listening on http://%s\n", ln.Addr())
+
+	srv := &http.Server{Handler: newServer(sc)}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// scenario is the script of responses the server plays.
+type scenario struct {
+	Responses []response `json:"responses"`
+	Loop      bool       `json:"loop,omitempty"` // repeat the responses instead of failing after the last
+}
+
+// response is one scripted model response.
+type response struct {
+	Thinking   string     `json:"thinking,omitempty"`
+	Text       string     `json:"text,omitempty"`
+	ToolCalls  []toolCall `json:"tool_calls,omitempty"`
+	StopReason string     `json:"stop_reason,omitempty"` // end_turn, tool_use, or max_tokens; default from the content
+	Usage      usage      `json:"usage,omitempty"`
+	Error      *apiError  `json:"error,omitempty"`      // answer with this error instead
+	ChunkSize  int        `json:"chunk_size,omitempty"` // characters per delta; default defaultChunkSize
+	DelayMS    int        `json:"delay_ms,omitempty"`   // pause between streamed events
+}
+
+type toolCall struct {
+	ID        string          `json:"id,omitempty"` // default toolu_mock_N
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type usage struct {
+	InputTokens  int `json:"input_tokens,omitempty"`
+	OutputTokens int `json:"output_tokens,omitempty"`
+}
+
+// apiError is an error response. Type is the Anthropic error type, e.g.
+// "overloaded_error"; Gemini clients get the status code and message.
+type apiError struct {
+	Status  int    `json:"status"`
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// defaultChunkSize is how many characters of text each delta carries.
+const defaultChunkSize = 16
+
+// defaultScenario returns the scenario that answers every request with a
+// greeting.
+func defaultScenario() scenario {
+	return scenario{
+		Responses: []response{{Text: "Hello from pipe-mockserver.", Usage: usage{InputTokens: 10, OutputTokens: 6}}},
+		Loop:      true,
+	}
+}
+
+// loadScenario reads and validates the scenario file at path.
+func loadScenario(path string) (scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return scenario{}, fmt.Errorf("read scenario: %w", err)
+	}
+	var sc scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return scenario{}, fmt.Errorf("parse scenario %s: %w", path, err)
+	}
+	if err := sc.validate(); err != nil {
+		return scenario{}, fmt.Errorf("scenario %s: %w", path, err)
+	}
+	return sc, nil
+}
+
+func (sc scenario) validate() error {
+	if len(sc.Responses) == 0 {
+		return errors.New("no responses")
+	}
+	for i, r := range sc.Responses {
+		switch r.StopReason {
+		case "", "end_turn", "tool_use", "max_tokens":
+		default:
+			return fmt.Errorf("responses[%d]: unknown stop_reason %q", i, r.StopReason)
+		}
+		if r.Error != nil && r.Error.Status < 400 {
+			return fmt.Errorf("responses[%d]: error status must be 400 or above, got %d", i, r.Error.Status)
+		}
+		for j, c := range r.ToolCalls {
+			if c.Name == "" {
+				return fmt.Errorf("responses[%d].tool_calls[%d]: missing name", i, j)
+			}
+			if len(c.Arguments) > 0 && !json.Valid(c.Arguments) {
+				return fmt.Errorf("responses[%d].tool_calls[%d]: arguments are not valid JSON", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// stopReason is the Anthropic stop reason of r.
+func (r response) stopReason() string {
+	switch {
+	case r.StopReason != "":
+		return r.StopReason
+	case len(r.ToolCalls) > 0:
+		return "tool_use"
+	default:
+		return "end_turn"
+	}
+}
+
+// chunks splits s into deltas of r.ChunkSize characters.
+func (r response) chunks(s string) []string {
+	size := r.ChunkSize
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	runes := []rune(s)
+	var out []string
+	for len(runes) > 0 {
+		n := min(size, len(runes))
+		out = append(out, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return out
+}
+
+// script hands out a scenario's responses in order.
+type script struct {
+	mu   sync.Mutex
+	sc   scenario
+	next int
+}
+
+// take returns the response to the next request, or false once a
+// non-looping scenario has run out.
+func (s *script) take() (response, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.next
+	if n >= len(s.sc.Responses) && !s.sc.Loop {
+		return response{}, n, false
+	}
+	s.next++
+	return s.sc.Responses[n%len(s.sc.Responses)], n, true
+}
+
+// reset restarts the scenario.
+func (s *script) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next = 0
+}
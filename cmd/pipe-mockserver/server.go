@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// server plays a scenario to whichever client asks and records what the
+// clients sent.
+type server struct {
+	script *script
+
+	mu       sync.Mutex
+	requests []json.RawMessage
+}
+
+// newServer returns a handler serving sc.
+func newServer(sc scenario) http.Handler {
+	s := &server{script: &script{sc: sc}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/messages", s.handleAnthropic)
+	mux.HandleFunc("POST /{version}/models/{method}", s.handleGemini)
+	mux.HandleFunc("GET /requests", s.handleRequests)
+	mux.HandleFunc("DELETE /requests", s.handleReset)
+	return mux
+}
+
+func (s *server) handleAnthropic(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+	}
+	if !s.record(w, r, &req) {
+		return
+	}
+	resp, n, ok := s.script.take()
+	if !ok {
+		writeAnthropicError(w, exhausted(n))
+		return
+	}
+	if resp.Error != nil {
+		writeAnthropicError(w, *resp.Error)
+		return
+	}
+	writeAnthropic(w, resp, n, req.Model)
+}
+
+func (s *server) handleGemini(w http.ResponseWriter, r *http.Request) {
+	model, ok := strings.CutSuffix(r.PathValue("method"), ":streamGenerateContent")
+	if !ok {
+		http.Error(w, "only streamGenerateContent is supported", http.StatusNotFound)
+		return
+	}
+	if !s.record(w, r, nil) {
+		return
+	}
+	resp, n, ok := s.script.take()
+	if !ok {
+		writeGeminiError(w, exhausted(n))
+		return
+	}
+	if resp.Error != nil {
+		writeGeminiError(w, *resp.Error)
+		return
+	}
+	writeGemini(w, resp, n, model)
+}
+
+// record reads and logs the request body, decoding it into v if not nil.
+// It reports false after answering a malformed request.
+func (s *server) record(w http.ResponseWriter, r *http.Request, v any) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(body) {
+		http.Error(w, "request body is not JSON", http.StatusBadRequest)
+		return false
+	}
+	if v != nil {
+		_ = json.Unmarshal(body, v) // valid JSON; missing fields stay zero
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, body)
+	s.mu.Unlock()
+	return true
+}
+
+func (s *server) handleRequests(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	requests := append([]json.RawMessage{}, s.requests...)
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(requests)
+}
+
+func (s *server) handleReset(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	s.requests = nil
+	s.mu.Unlock()
+	s.script.reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// exhausted is the error for request number n of a scenario that has no
+// more responses.
+func exhausted(n int) apiError {
+	return apiError{
+		Status:  http.StatusInternalServerError,
+		Type:    "api_error",
+		Message: fmt.Sprintf("pipe-mockserver: request %d is past the end of the scenario", n+1),
+	}
+}
+
+// eventWriter streams server-sent events, pausing delay before each.
+type eventWriter struct {
+	w     http.ResponseWriter
+	delay time.Duration
+}
+
+func newEventWriter(w http.ResponseWriter, resp response) *eventWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	return &eventWriter{w: w, delay: time.Duration(resp.DelayMS) * time.Millisecond}
+}
+
+// send writes one event; name is omitted when empty.
+func (e *eventWriter) send(name string, payload any) {
+	if e.delay > 0 {
+		time.Sleep(e.delay)
+	}
+	data, _ := json.Marshal(payload) // payloads are built from JSON-safe values
+	if name != "" {
+		fmt.Fprintf(e.w, "event: %s\n", name)
+	}
+	fmt.Fprintf(e.w, "data: %s\n\n", data)
+	if f, ok := e.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// toolCallID is the ID of call i of response n, unless the scenario set
+// one.
+func toolCallID(c toolCall, n, i int) string {
+	if c.ID != "" {
+		return c.ID
+	}
+	return fmt.Sprintf("toolu_mock_%d_%d", n+1, i+1)
+}
+
+// arguments returns the call's arguments, {} when unset.
+func (c toolCall) arguments() json.RawMessage {
+	if len(c.Arguments) == 0 {
+		return json.RawMessage("{}")
+	}
+	return c.Arguments
+}
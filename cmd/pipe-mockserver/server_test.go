@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/anthropic"
+	"github.com/fwojciec/pipe/gemini"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collect drains a stream and returns its final message.
+func collect(t *testing.T, p pipe.Provider) (pipe.AssistantMessage, error) {
+	t.Helper()
+	s, err := p.Stream(context.Background(), pipe.Request{
+		Messages: []pipe.Message{pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}}},
+	})
+	if err != nil {
+		return pipe.AssistantMessage{}, err
+	}
+	defer s.Close()
+	for {
+		_, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			return s.Message()
+		}
+		if err != nil {
+			msg, _ := s.Message()
+			return msg, err
+		}
+	}
+}
+
+var testScenario = scenario{Responses: []response{
+	{
+		Thinking:  "The user wants files listed.",
+		ToolCalls: []toolCall{{Name: "bash", Arguments: json.RawMessage(`{"command":"ls"}`)}},
+		Usage:     usage{InputTokens: 100, OutputTokens: 20},
+	},
+	{Text: "There are two files: a.go and b.go.", ChunkSize: 5, Usage: usage{InputTokens: 130, OutputTokens: 12}},
+	{Error: &apiError{Status: http.StatusTooManyRequests, Type: "rate_limit_error", Message: "slow down"}},
+}}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	providers := map[string]func(t *testing.T, url string) pipe.Provider{
+		"anthropic": func(_ *testing.T, url string) pipe.Provider {
+			return anthropic.New("key", anthropic.WithBaseURL(url))
+		},
+		"gemini": func(t *testing.T, url string) pipe.Provider {
+			c, err := gemini.New(context.Background(), "key", gemini.WithBaseURL(url))
+			require.NoError(t, err)
+			return c
+		},
+	}
+	for name, newProvider := range providers {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			srv := httptest.NewServer(newServer(testScenario))
+			defer srv.Close()
+			p := newProvider(t, srv.URL)
+
+			msg, err := collect(t, p)
+			require.NoError(t, err)
+			assert.Equal(t, pipe.StopToolUse, msg.StopReason)
+			require.Len(t, msg.Content, 2)
+			assert.Equal(t, "The user wants files listed.", msg.Content[0].(pipe.ThinkingBlock).Thinking)
+			call := msg.Content[1].(pipe.ToolCallBlock)
+			assert.Equal(t, "toolu_mock_1_1", call.ID)
+			assert.Equal(t, "bash", call.Name)
+			assert.JSONEq(t, `{"command":"ls"}`, string(call.Arguments))
+			assert.Equal(t, pipe.Usage{InputTokens: 100, OutputTokens: 20}, msg.Usage)
+
+			msg, err = collect(t, p)
+			require.NoError(t, err)
+			assert.Equal(t, pipe.StopEndTurn, msg.StopReason)
+			assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "There are two files: a.go and b.go."}}, msg.Content)
+
+			_, err = collect(t, p)
+			var pe *pipe.ProviderError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, pipe.StopRateLimited, pe.Reason)
+
+			_, err = collect(t, p)
+			require.ErrorContains(t, err, "request 4 is past the end of the scenario")
+
+			resp, err := http.Get(srv.URL + "/requests")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var requests []json.RawMessage
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&requests))
+			assert.Len(t, requests, 4)
+			assert.Contains(t, string(requests[0]), `"hi"`)
+		})
+	}
+}
+
+func TestServer_Loop(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(newServer(defaultScenario()))
+	defer srv.Close()
+	p := anthropic.New("key", anthropic.WithBaseURL(srv.URL))
+	for range 3 {
+		msg, err := collect(t, p)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello from pipe-mockserver.", msg.Content[0].(pipe.TextBlock).Text)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, srv.URL+"/requests", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	resp, err = http.Get(srv.URL + "/requests")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, string(body))
+}
+
+func TestLoadScenario(t *testing.T) {
+	t.Parallel()
+	write := func(t *testing.T, body string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "scenario.json")
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+		return path
+	}
+
+	sc, err := loadScenario(write(t, `{"responses":[{"text":"ok"}],"loop":true}`))
+	require.NoError(t, err)
+	assert.Equal(t, scenario{Responses: []response{{Text: "ok"}}, Loop: true}, sc)
+
+	for body, want := range map[string]string{
+		`{"responses":[]}`:                                          "no responses",
+		`{"responses":[{"stop_reason":"done"}]}`:                    `responses[0]: unknown stop_reason "done"`,
+		`{"responses":[{"error":{"status":200}}]}`:                  "responses[0]: error status must be 400 or above, got 200",
+		`{"responses":[{"tool_calls":[{"arguments":{}}]}]}`:         "responses[0].tool_calls[0]: missing name",
+		`{"responses":[{"text":"a"},{"tool_calls":[{"name":""}]}]}`: "responses[1].tool_calls[0]: missing name",
+	} {
+		_, err := loadScenario(write(t, body))
+		require.ErrorContains(t, err, want, body)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+// summaryActions returns the quick actions offered by the end-of-run summary
+// block. Clipboard escapes are written to term.
+func summaryActions(term io.Writer, saver *sessionSaver, snaps *snapshots) []bt.SummaryAction {
+	return []bt.SummaryAction{
+		{Key: "ctrl+y", Label: "copy", Run: copyToClipboard(term)},
+		{Key: "ctrl+s", Label: "save session", Run: saver.save},
+		{Key: "ctrl+x", Label: "diff", Run: snaps.diff},
+	}
+}
+
+// copyToClipboard returns an action that copies its text to the system
+// clipboard with an OSC 52 escape, which works over SSH in terminals that
+// support it.
+func copyToClipboard(term io.Writer) func(string) (bt.CommandResult, error) {
+	return func(text string) (bt.CommandResult, error) {
+		seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+		if _, err := io.WriteString(term, seq); err != nil {
+			return bt.CommandResult{}, fmt.Errorf("copy: %w", err)
+		}
+		return bt.CommandResult{Notice: "Summary copied to clipboard."}, nil
+	}
+}
+
+// sessionSaver writes the session to disk on demand, ahead of the save on
+// exit.
+type sessionSaver struct {
+	path        string // explicit -session path; empty uses the default location
+	session     *pipe.Session
+	artifactDir string // recorded in the session on save; may be empty
+	persist     string // persist mode; see persistMode
+}
+
+// save writes the session. It only runs while the agent is idle, so the
+// session is not being modified.
+func (s *sessionSaver) save(string) (bt.CommandResult, error) {
+	path := s.path
+	if path == "" {
+		path = defaultSessionPath(s.session.ID)
+	}
+	if s.persist == persistNone {
+		return bt.CommandResult{Notice: "Session not saved: saving is off (incognito or persist: none)."}, nil
+	}
+	if err := recordArtifacts(s.session, s.artifactDir); err != nil {
+		return bt.CommandResult{}, fmt.Errorf("record artifacts: %w", err)
+	}
+	if _, err := saveSession(path, *s.session, s.persist); err != nil {
+		return bt.CommandResult{}, fmt.Errorf("save session: %w", err)
+	}
+	return bt.CommandResult{Notice: "Session saved to " + path}, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipeexec "github.com/fwojciec/pipe/exec"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryActions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("copy writes an OSC 52 escape", func(t *testing.T) {
+		t.Parallel()
+		var term bytes.Buffer
+		out, err := copyToClipboard(&term)("hi")
+		require.NoError(t, err)
+		assert.Equal(t, "\x1b]52;c;aGk=\a", term.String())
+		assert.Equal(t, "Summary copied to clipboard.", out.Notice)
+	})
+
+	t.Run("save writes the session", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		session := &pipe.Session{ID: "s1", Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hello"}}},
+		}}
+		saver := &sessionSaver{path: path, session: session}
+
+		out, err := saver.save("")
+		require.NoError(t, err)
+		assert.Equal(t, "Session saved to "+path, out.Notice)
+		loaded, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Len(t, loaded.Messages, 1)
+	})
+
+	t.Run("save records artifacts", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		artifacts := filepath.Join(dir, "artifacts")
+		require.NoError(t, os.MkdirAll(artifacts, 0o755))
+		log := filepath.Join(artifacts, "pipe-bash-1.log")
+		require.NoError(t, os.WriteFile(log, []byte("output"), 0o644))
+		path := filepath.Join(dir, "session.json")
+		session := &pipe.Session{ID: "s1", Artifacts: []string{log}}
+		saver := &sessionSaver{path: path, session: session, artifactDir: artifacts}
+
+		_, err := saver.save("")
+		require.NoError(t, err)
+		loaded, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, []string{log}, loaded.Artifacts)
+	})
+
+	t.Run("diff shows the latest run's changes", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "f.txt")
+		require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+		snaps := &snapshots{root: filepath.Join(dir, "snapshots")}
+		exec := &executor{bash: pipeexec.NewBashExecutor(), snap: snaps}
+
+		out, err := snaps.diff("")
+		require.NoError(t, err)
+		assert.Equal(t, "No file changes.", out.Notice)
+
+		snaps.begin("run1")
+		args, _ := json.Marshal(map[string]any{"file_path": path, "content": "new\n", "overwrite": true})
+		_, err = exec.Execute(context.Background(), "write", args)
+		require.NoError(t, err)
+
+		out, err = snaps.diff("")
+		require.NoError(t, err)
+		assert.Equal(t, "--- "+path+"\n+++ "+path+"\n-old\n+new", out.Notice)
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/fwojciec/pipe"
+)
+
+// defaultArtifactDir is where a session's offloaded tool outputs are kept,
+// next to the session file, so truncation notices stay readable after the
+// OS temp dir is cleared.
+func defaultArtifactDir(id string) string {
+	return filepath.Join(defaultSessionDir(), id, "artifacts")
+}
+
+// recordArtifacts adds the files in dir that s does not reference yet to
+// s.Artifacts. A missing dir records nothing.
+func recordArtifacts(s *pipe.Session, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if !e.IsDir() && !slices.Contains(s.Artifacts, path) {
+			s.Artifacts = append(s.Artifacts, path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,614 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	pipeexec "github.com/fwojciec/pipe/exec"
+)
+
+const defaultConfigPath = ".pipe/config.json"
+
+// config holds settings read from the project config file. Zero values mean
+// "use the built-in default".
+type config struct {
+	// Model is the model used when neither -model nor the active profile
+	// names one. Empty means the provider's default.
+	Model string `json:"model,omitempty"`
+	// ServerTools lists provider-hosted tools to enable, e.g. "web_search".
+	ServerTools []string `json:"server_tools,omitempty"`
+	// AnthropicBetas opts in to Anthropic beta API behaviors by header value,
+	// e.g. "interleaved-thinking-2025-05-14". Ignored by other providers.
+	AnthropicBetas []string `json:"anthropic_betas,omitempty"`
+	// AnthropicCacheMessages places Anthropic prompt caching breakpoints
+	// on the last this many messages, as many as fit beside those on the
+	// system prompt and tools, instead of the automatic one. It raises
+	// cache hits in long agentic sessions. Ignored by other providers.
+	AnthropicCacheMessages int `json:"anthropic_cache_messages,omitempty"`
+	// ToolResultFormat is how tool result text is sent to the provider:
+	// "raw" (default) sends it as the tool produced it; "compact" drops
+	// what the provider's format says costs tokens for nothing, such as
+	// terminal escapes. Read results are always sent raw, so edits can
+	// quote them exactly.
+	ToolResultFormat string `json:"tool_result_format,omitempty"`
+	// ToolLimits caps concurrency and call rate per tool, keyed by tool name.
+	ToolLimits map[string]toolLimit `json:"tool_limits,omitempty"`
+	// Profiles are named setting bundles selected with -profile or /profile.
+	Profiles map[string]profile `json:"profiles,omitempty"`
+	// WebhookURL receives JSON progress payloads for every run: start, turn
+	// end, tool errors, and completion.
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// UsageMetrics opts in to recording per-run token and tool counts in a
+	// local usage log for "pipe usage". Nothing leaves the machine.
+	UsageMetrics bool `json:"usage_metrics,omitempty"`
+	// Locale overrides the locale taken from LC_ALL or LANG for formatting
+	// numbers and dates, e.g. "de-DE".
+	Locale string `json:"locale,omitempty"`
+	// TimeZone overrides the local time zone for dates and times, as an
+	// IANA name such as "Europe/Warsaw".
+	TimeZone string `json:"time_zone,omitempty"`
+	// TempArtifacts keeps offloaded tool outputs in the OS temp dir instead
+	// of the session's artifacts folder under ~/.pipe/sessions.
+	TempArtifacts bool `json:"temp_artifacts,omitempty"`
+	// Memory enables the remember and recall tools, which keep notes in
+	// .pipe/memory.jsonl for later sessions.
+	Memory bool `json:"memory,omitempty"`
+	// Task enables the task tool, which delegates a self-contained task to
+	// a sub-agent with its own system prompt, tools, and turn limit and
+	// returns only its final report.
+	Task *taskConfig `json:"task,omitempty"`
+	// ContextBudget compacts a session by summarizing older messages when
+	// a request's context nears this many tokens. Zero disables compaction.
+	ContextBudget int `json:"context_budget,omitempty"`
+	// ToolResultTurns sends only the tool results of this many recent
+	// turns in full to the provider; older ones are cut to a line saying
+	// what was left out, keeping long sessions within the context window.
+	// The session and the TUI keep every result. Zero sends them all.
+	ToolResultTurns int `json:"tool_result_turns,omitempty"`
+	// Retries is how many times a request that fails transiently, e.g. on
+	// a rate limit or a dropped connection, is retried with backoff.
+	// Default 3; negative disables retries.
+	Retries int `json:"retries,omitempty"`
+	// Approve lists tools whose calls wait for the user to allow or deny
+	// them in the TUI, e.g. ["bash", "write"]; "*" covers every tool.
+	Approve []string `json:"approve,omitempty"`
+	// StatusSegments are shell commands whose output is shown in the status
+	// bar, e.g. the current Kubernetes context.
+	StatusSegments []statusSegment `json:"status_segments,omitempty"`
+	// Persist controls what is saved of a session: "full" (default),
+	// "no_thinking" to drop the model's reasoning, or "none" to save
+	// nothing.
+	Persist string `json:"persist,omitempty"`
+	// Journal appends each turn to a journal beside the session file as it
+	// completes, so a session survives the process dying before it is
+	// saved. The next load replays the journal.
+	Journal bool `json:"journal,omitempty"`
+	// FastestProvider picks, when API keys for several providers are set
+	// and -provider is not given, whichever answers a tiny probe request
+	// first instead of refusing to guess.
+	FastestProvider bool `json:"fastest_provider,omitempty"`
+	// ToolConcurrency is how many tool calls from one model response run at
+	// the same time, e.g. several reads. Default 1: one after another.
+	ToolConcurrency int `json:"tool_concurrency,omitempty"`
+	// ToolTimeout cancels a tool call that runs longer than this, e.g.
+	// "10m", and reports it to the model as failed. Bash backgrounds
+	// commands after its own, shorter timeout, so this catches tools that
+	// hang. Empty means no timeout.
+	ToolTimeout string `json:"tool_timeout,omitempty"`
+	// MaxTurns ends a run that has made this many requests without
+	// finishing, so a model stuck calling tools cannot run up the bill.
+	// Zero means no limit.
+	MaxTurns int `json:"max_turns,omitempty"`
+	// MaxCostTokens ends a run once its responses have used this many
+	// tokens, input, output, and cached alike. Zero means no limit.
+	MaxCostTokens int `json:"max_cost_tokens,omitempty"`
+	// LoopDetection is how many identical tool calls in a row count as the
+	// model looping: that call is not run, and the model is told to change
+	// course. Zero, the default, disables detection: polling a background
+	// command with bash check_pid repeats the same call by design, so set
+	// it above the number of polls runs are expected to make.
+	LoopDetection int `json:"loop_detection,omitempty"`
+	// Exec selects where bash commands run; the default is this machine.
+	Exec execConfig `json:"exec,omitempty"`
+	// Pricing sets the price of models, keyed by model ID, for the cost
+	// estimate in the status bar. Known models use their list price.
+	Pricing map[string]modelPrice `json:"pricing,omitempty"`
+	// ConfirmCost asks before sending a request whose estimated input cost
+	// exceeds this many US dollars, e.g. 0.5. Zero never asks; models
+	// without a known price are not asked about.
+	ConfirmCost float64 `json:"confirm_cost,omitempty"`
+	// MaxToolResultBytes caps the text of any tool result kept in the
+	// session; the full text of a larger one is saved to an artifact file
+	// the result names. Default 256 KiB.
+	MaxToolResultBytes int `json:"max_tool_result_bytes,omitempty"`
+	// Style is the response style preset added to the system prompt:
+	// "concise", "explanatory", or "code-only". /style sets it.
+	Style string `json:"style,omitempty"`
+	// KeepWarm pings the provider with a one-token request after it has
+	// been idle this long, e.g. "5m", while the TUI is open. It spares
+	// endpoints that scale down when idle, such as some Vertex and
+	// enterprise deployments, a cold start on the next prompt. Pings stop
+	// once no prompt has run for 30 minutes and resume after the next.
+	// Read at startup. Empty never pings; the minimum is 30s.
+	KeepWarm string `json:"keep_warm,omitempty"`
+	// InputHeight is how many lines the TUI input grows to, 1 to 20.
+	// Default 3; /set input_height changes it for the session.
+	InputHeight int `json:"input_height,omitempty"`
+	// Mouse turns on mouse support in the TUI: the wheel scrolls and
+	// clicking a tool or thinking block's first line expands or collapses
+	// it. Most terminals then select text only while Shift is held. Read
+	// at startup.
+	Mouse bool `json:"mouse,omitempty"`
+	// ProviderOptions sets generation options only some providers
+	// support, such as Gemini's thinking budget. Providers ignore the ones
+	// they don't.
+	ProviderOptions providerOptions `json:"provider_options,omitempty"`
+	// RunStatus asks the model to end each run with a "DONE: summary" or
+	// "BLOCKED: reason" line, which is stored on the session and included
+	// in -output-format json, so scripts can tell whether it finished.
+	RunStatus bool `json:"run_status,omitempty"`
+	// Env sets environment variables for the commands the bash and
+	// run_tests tools run, e.g. a test database URL, without putting them
+	// in the shell pipe was started from. They are never sent to the
+	// provider. /env changes them for a session.
+	Env map[string]string `json:"env,omitempty"`
+	// Roots names the directories of a workspace split across several,
+	// e.g. {"frontend": "web", "backend": "server"}. File and bash tool
+	// calls may then name a root to work in; their paths resolve in it and
+	// may not leave it. Relative directories are relative to the workspace.
+	Roots map[string]string `json:"roots,omitempty"`
+}
+
+// providerOptions is the config file form of [pipe.ProviderOptions].
+type providerOptions struct {
+	ThinkingBudget *int            `json:"thinking_budget,omitempty"` // 0 turns thinking off
+	TopP           *float64        `json:"top_p,omitempty"`
+	TopK           *int            `json:"top_k,omitempty"`
+	SafetySettings []safetySetting `json:"safety_settings,omitempty"`
+}
+
+// safetySetting is the config file form of a [pipe.SafetySetting], e.g.
+// {"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_ONLY_HIGH"}.
+type safetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// modelPrice is the config file form of a [pipe.Pricing], in US dollars per
+// million tokens.
+type modelPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cache_read,omitempty"`
+	CacheWrite float64 `json:"cache_write,omitempty"`
+}
+
+// pricing returns the price of model: the configured one, else its list
+// price.
+func (c config) pricing(model string) (pipe.Pricing, bool) {
+	if p, ok := c.Pricing[model]; ok {
+		return pipe.Pricing(p), true
+	}
+	return pipe.LookupPricing(model)
+}
+
+// costGuard returns a request approver that asks confirm about requests to
+// model whose estimated input cost exceeds ConfirmCost, or nil if there is
+// no threshold or no price for model.
+func (c config) costGuard(model string, confirm func(context.Context, pipe.Usage, float64) (pipe.Decision, error)) func(context.Context, pipe.Request) (pipe.Decision, error) {
+	price, ok := c.pricing(model)
+	if c.ConfirmCost <= 0 || !ok {
+		return nil
+	}
+	return func(ctx context.Context, req pipe.Request) (pipe.Decision, error) {
+		usage := pipe.EstimateRequestUsage(req)
+		cost := price.Cost(usage)
+		if cost <= c.ConfirmCost {
+			return pipe.DecisionAllow, nil
+		}
+		return confirm(ctx, usage, cost)
+	}
+}
+
+// defaultMaxToolResultBytes caps tool results when the config sets no
+// limit. Bash and grep keep their output well under it; it catches other
+// tools.
+const defaultMaxToolResultBytes = 256 << 10
+
+// maxToolResultBytes returns the cap on the text of a tool result.
+func (c config) maxToolResultBytes() int {
+	if c.MaxToolResultBytes > 0 {
+		return c.MaxToolResultBytes
+	}
+	return defaultMaxToolResultBytes
+}
+
+// execConfig selects the backend bash commands run on. Only bash runs
+// there: read, write, edit, grep, glob, and run_tests work on the local
+// workspace. So an ssh host must see the workspace at the same path, e.g.
+// over a shared mount, and a container must bind-mount it; paths in the
+// commands and output of a container are translated to the mount.
+type execConfig struct {
+	Backend   string `json:"backend,omitempty"`   // "local" (default), "ssh", or "docker"
+	Host      string `json:"host,omitempty"`      // ssh destination, e.g. "user@build"
+	Container string `json:"container,omitempty"` // running container for docker exec
+	Image     string `json:"image,omitempty"`     // image for a fresh container per command
+	// Root is the working directory on the host or in the container. Over
+	// ssh it must be the workspace's own path, its default. In a running
+	// container it must be where the container mounts the workspace, its
+	// default. With an image, the workspace is mounted there; default
+	// /workspace.
+	Root string `json:"root,omitempty"`
+}
+
+// note tells the model where bash commands run when it is not this
+// machine, or returns "" for the local backend.
+func (e execConfig) note() string {
+	switch e.Backend {
+	case "ssh":
+		return fmt.Sprintf("Bash commands run on the host %s, which shares the workspace at the same path. "+
+			"The other tools, run_tests included, work on this machine.", e.Host)
+	case "docker":
+		where := "the container " + e.Container
+		if e.Container == "" {
+			where = "a fresh container of the image " + e.Image
+		}
+		return fmt.Sprintf("Bash commands run in %s, which mounts the workspace; use this machine's paths, "+
+			"which are translated to the container's. The other tools, run_tests included, work on this machine.", where)
+	default:
+		return ""
+	}
+}
+
+// statusSegment is the config file form of a [bt.StatusSegment] whose text
+// is the first line a shell command prints.
+type statusSegment struct {
+	Command  string `json:"command"`
+	Interval string `json:"interval,omitempty"` // e.g. "30s"; empty runs the command once
+	MaxWidth int    `json:"max_width,omitempty"`
+}
+
+// defaultRetries is the number of retries when the config sets none.
+const defaultRetries = 3
+
+// retryPolicy returns the retry policy for requests to the named provider.
+// Anthropic continues a trailing assistant message, so its responses that
+// fail mid-stream are resumed rather than requested again.
+func (c config) retryPolicy(provider string) pipe.RetryPolicy {
+	retries := c.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+	return pipe.RetryPolicy{MaxRetries: max(retries, 0), Resume: provider == "anthropic"}
+}
+
+// loopDetection returns the loop detection threshold; zero disables it.
+func (c config) loopDetection() int {
+	return max(c.LoopDetection, 0)
+}
+
+// loadConfig reads the config file at path. A missing default config file is
+// not an error; a missing explicitly requested file is.
+func loadConfig(path string) (config, error) {
+	var cfg config
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist) && path == defaultConfigPath:
+		return cfg, nil
+	default:
+		return cfg, fmt.Errorf("read config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// locale resolves the display locale: the configured locale, else the one
+// named by the LC_ALL or LANG environment variable read with getenv, else
+// en-US; in the configured time zone, else the local one.
+func (c config) locale(getenv func(string) string) (pipe.Locale, error) {
+	var locale pipe.Locale
+	if c.Locale != "" {
+		l, ok := pipe.LookupLocale(c.Locale)
+		if !ok {
+			return pipe.Locale{}, fmt.Errorf("unknown locale %q", c.Locale)
+		}
+		locale = l
+	} else {
+		for _, name := range []string{"LC_ALL", "LANG"} {
+			if l, ok := pipe.LookupLocale(getenv(name)); ok {
+				locale = l
+				break
+			}
+		}
+	}
+	if c.TimeZone != "" {
+		loc, err := time.LoadLocation(c.TimeZone)
+		if err != nil {
+			return pipe.Locale{}, fmt.Errorf("time zone: %w", err)
+		}
+		locale = locale.In(loc)
+	}
+	return locale, nil
+}
+
+// enabledTools returns the built-in tools the config enables.
+func (c config) enabledTools() []pipe.Tool {
+	enabled := withExecNote(withRootParam(tools(), c.Roots), c.Exec.note())
+	if c.Memory {
+		enabled = append(enabled, rememberTool(), recallTool())
+	}
+	if c.Task != nil {
+		enabled = append(enabled, taskTool())
+	}
+	return enabled
+}
+
+// withExecNote returns tools with note, where bash commands run, added to
+// the bash tool's description.
+func withExecNote(tools []pipe.Tool, note string) []pipe.Tool {
+	if note == "" {
+		return tools
+	}
+	out := slices.Clone(tools)
+	for i, t := range out {
+		if t.Name == "bash" {
+			out[i].Description += " " + note
+		}
+	}
+	return out
+}
+
+// serverTools converts the configured server tool names to domain values,
+// rejecting names pipe does not know about.
+func (c config) serverTools() ([]pipe.ServerTool, error) {
+	var tools []pipe.ServerTool
+	for _, name := range c.ServerTools {
+		switch t := pipe.ServerTool(name); t {
+		case pipe.ServerToolWebSearch, pipe.ServerToolCodeExecution:
+			tools = append(tools, t)
+		default:
+			return nil, fmt.Errorf("unknown server tool %q: must be %q or %q", name, pipe.ServerToolWebSearch, pipe.ServerToolCodeExecution)
+		}
+	}
+	return tools, nil
+}
+
+// approveAllTools in the approve list requires approval for every tool.
+const approveAllTools = "*"
+
+// approver returns the approval hook for the loop: ask decides calls to
+// the tools listed in the approve setting and other calls are allowed. It
+// returns nil when no tool needs approval and rejects unknown tools.
+func (c config) approver(ask func(context.Context, pipe.ToolCallBlock) (pipe.Decision, error)) (func(context.Context, pipe.ToolCallBlock) (pipe.Decision, error), error) {
+	if len(c.Approve) == 0 {
+		return nil, nil
+	}
+	known := c.enabledTools()
+	gated := make(map[string]bool, len(c.Approve))
+	for _, name := range c.Approve {
+		if name != approveAllTools && !slices.ContainsFunc(known, func(t pipe.Tool) bool { return t.Name == name }) {
+			return nil, fmt.Errorf("approve: unknown tool %q", name)
+		}
+		gated[name] = true
+	}
+	return func(ctx context.Context, call pipe.ToolCallBlock) (pipe.Decision, error) {
+		if !gated[approveAllTools] && !gated[call.Name] {
+			return pipe.DecisionAllow, nil
+		}
+		return ask(ctx, call)
+	}, nil
+}
+
+// toolTimeout parses the configured tool timeout; zero means none.
+func (c config) toolTimeout() (time.Duration, error) {
+	if c.ToolTimeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.ToolTimeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("tool_timeout: invalid duration %q", c.ToolTimeout)
+	}
+	return d, nil
+}
+
+// Values of the tool_result_format setting.
+const (
+	toolResultsCompact = "compact"
+	toolResultsRaw     = "raw"
+)
+
+// toolResultFormat returns the configured tool result format for a
+// provider whose compact format is compact.
+func (c config) toolResultFormat(compact pipe.ToolResultFormat) (pipe.ToolResultFormat, error) {
+	switch c.ToolResultFormat {
+	case "", toolResultsRaw:
+		return pipe.ToolResultFormat{}, nil
+	case toolResultsCompact:
+		compact.Verbatim = []string{"read"}
+		return compact, nil
+	}
+	return pipe.ToolResultFormat{}, fmt.Errorf("tool_result_format: must be %q or %q, got %q", toolResultsCompact, toolResultsRaw, c.ToolResultFormat)
+}
+
+// providerOptions converts and validates the configured provider options.
+func (c config) providerOptions() (pipe.ProviderOptions, error) {
+	o := c.ProviderOptions
+	opts := pipe.ProviderOptions{ThinkingBudget: o.ThinkingBudget, TopP: o.TopP, TopK: o.TopK}
+	for _, s := range o.SafetySettings {
+		opts.SafetySettings = append(opts.SafetySettings, pipe.SafetySetting{Category: s.Category, Threshold: s.Threshold})
+	}
+	if err := opts.Validate(); err != nil {
+		return pipe.ProviderOptions{}, fmt.Errorf("provider_options: %w", err)
+	}
+	return opts, nil
+}
+
+// keepWarm parses the configured keep-warm interval; zero means none.
+func (c config) keepWarm() (time.Duration, error) {
+	if c.KeepWarm == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.KeepWarm)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("keep_warm: invalid duration %q", c.KeepWarm)
+	}
+	if d < minKeepWarm {
+		return 0, fmt.Errorf("keep_warm: interval %s is below the %s minimum", d, minKeepWarm)
+	}
+	return d, nil
+}
+
+// roots returns the configured workspace roots, with relative dirs in
+// base, or the working directory when base is empty. It returns nil when
+// there are none.
+func (c config) roots(base string) (*workspaceRoots, error) {
+	if len(c.Roots) == 0 {
+		return nil, nil
+	}
+	return newWorkspaceRoots(base, c.Roots)
+}
+
+// shell returns the shell that runs bash commands on the configured exec
+// backend for the workspace at dir, an absolute path, and how the
+// workspace's paths translate there. Mounts returns the bind mounts of a
+// running container.
+func (c config) shell(dir string, mounts func(container string) ([]pipeexec.DockerMount, error)) (pipeexec.Shell, pipeexec.PathMap, error) {
+	// A leading dash would be read as a command-line flag.
+	valid := func(name string) bool { return name != "" && !strings.HasPrefix(name, "-") }
+	switch e := c.Exec; e.Backend {
+	case "", "local":
+		return pipeexec.LocalShell, pipeexec.PathMap{}, nil
+	case "ssh":
+		if !valid(e.Host) {
+			return nil, pipeexec.PathMap{}, fmt.Errorf("exec: ssh backend needs a host, got %q", e.Host)
+		}
+		// The file tools work on the local workspace; commands that see
+		// another checkout would read and build stale files.
+		if e.Root != "" && filepath.Clean(e.Root) != dir {
+			return nil, pipeexec.PathMap{}, fmt.Errorf("exec: ssh root %q is not the workspace %s; the host must see the workspace at the same path", e.Root, dir)
+		}
+		return pipeexec.SSHShell(e.Host, dir), pipeexec.PathMap{}, nil
+	case "docker":
+		switch {
+		case e.Container != "" && e.Image != "":
+			return nil, pipeexec.PathMap{}, errors.New("exec: docker backend takes a container or an image, not both")
+		case valid(e.Container):
+			// The file tools work on the local workspace, so the container
+			// must see it through a bind mount.
+			binds, err := mounts(e.Container)
+			if err != nil {
+				return nil, pipeexec.PathMap{}, fmt.Errorf("exec: %w", err)
+			}
+			paths, ok := pipeexec.WorkspaceMount(binds, dir)
+			if !ok {
+				return nil, pipeexec.PathMap{}, fmt.Errorf("exec: container %s does not mount the workspace %s; start it with a bind mount, e.g. -v %s:%s", e.Container, dir, dir, pipeexec.DefaultContainerDir)
+			}
+			if e.Root != "" && path.Clean(e.Root) != paths.Remote {
+				return nil, pipeexec.PathMap{}, fmt.Errorf("exec: docker root %q is not where container %s mounts the workspace, %s", e.Root, e.Container, paths.Remote)
+			}
+			return pipeexec.DockerExecShell(e.Container, paths.Remote), paths, nil
+		case valid(e.Image):
+			root := e.Root
+			if root == "" {
+				root = pipeexec.DefaultContainerDir
+			}
+			if !path.IsAbs(root) || path.Clean(root) == "/" {
+				return nil, pipeexec.PathMap{}, fmt.Errorf("exec: docker root %q must be an absolute path below /", e.Root)
+			}
+			root = path.Clean(root)
+			return pipeexec.DockerRunShell(e.Image, root), pipeexec.PathMap{Local: dir, Remote: root}, nil
+		default:
+			return nil, pipeexec.PathMap{}, errors.New("exec: docker backend needs a container or an image")
+		}
+	default:
+		return nil, pipeexec.PathMap{}, fmt.Errorf("exec: unknown backend %q: must be \"local\", \"ssh\", or \"docker\"", e.Backend)
+	}
+}
+
+// statusSegments converts the configured status segments, rejecting empty
+// commands and malformed intervals.
+func (c config) statusSegments() ([]bt.StatusSegment, error) {
+	var segs []bt.StatusSegment
+	for i, sc := range c.StatusSegments {
+		if strings.TrimSpace(sc.Command) == "" {
+			return nil, fmt.Errorf("status_segments[%d]: command is required", i)
+		}
+		seg := bt.StatusSegment{Refresh: commandSegment(sc.Command), MaxWidth: sc.MaxWidth}
+		if sc.Interval != "" {
+			interval, err := time.ParseDuration(sc.Interval)
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("status_segments[%d]: invalid interval %q", i, sc.Interval)
+			}
+			seg.Interval = interval
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+// segmentTimeout bounds each run of a status segment command.
+const segmentTimeout = 5 * time.Second
+
+// commandSegment returns a segment refresh that runs command in the shell
+// and returns the first line of its output, or "" if it fails.
+func commandSegment(command string) func() string {
+	return func() string {
+		ctx, cancel := context.WithTimeout(context.Background(), segmentTimeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "sh", "-c", command).Output()
+		if err != nil {
+			return ""
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+		return line
+	}
+}
+
+// toolLimit is the config file form of [pipeexec.ToolLimit].
+type toolLimit struct {
+	MaxConcurrent int    `json:"max_concurrent,omitempty"`
+	MaxCalls      int    `json:"max_calls,omitempty"`
+	Window        string `json:"window,omitempty"` // e.g. "1m"; default 1m when max_calls is set
+}
+
+// toolLimits converts the configured tool limits, rejecting unknown tools and
+// malformed windows.
+func (c config) toolLimits() (map[string]pipeexec.ToolLimit, error) {
+	if len(c.ToolLimits) == 0 {
+		return nil, nil
+	}
+	known := c.enabledTools()
+	limits := make(map[string]pipeexec.ToolLimit, len(c.ToolLimits))
+	for name, l := range c.ToolLimits {
+		if !slices.ContainsFunc(known, func(t pipe.Tool) bool { return t.Name == name }) {
+			return nil, fmt.Errorf("tool_limits: unknown tool %q", name)
+		}
+		limit := pipeexec.ToolLimit{MaxConcurrent: l.MaxConcurrent, MaxCalls: l.MaxCalls}
+		if l.MaxCalls > 0 {
+			limit.Window = time.Minute
+			if l.Window != "" {
+				window, err := time.ParseDuration(l.Window)
+				if err != nil || window <= 0 {
+					return nil, fmt.Errorf("tool_limits: %s: invalid window %q", name, l.Window)
+				}
+				limit.Window = window
+			}
+		}
+		limits[name] = limit
+	}
+	return limits, nil
+}
@@ -0,0 +1,426 @@
+package main_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	. "github.com/fwojciec/pipe/cmd/pipe"
+	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_ServerTools(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"server_tools":["web_search","code_execution"]}`), 0o600))
+
+	tools, err := LoadServerToolsForTest(path)
+	require.NoError(t, err)
+	assert.Equal(t, []pipe.ServerTool{pipe.ServerToolWebSearch, pipe.ServerToolCodeExecution}, tools)
+}
+
+func TestLoadConfig_UnknownServerTool(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"server_tools":["teleport"]}`), 0o600))
+
+	_, err := LoadServerToolsForTest(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown server tool")
+}
+
+func TestLoadConfig_MissingExplicitFile(t *testing.T) {
+	t.Parallel()
+	_, err := LoadServerToolsForTest(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestLoadConfig_InvalidJSON(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{`), 0o600))
+
+	_, err := LoadServerToolsForTest(path)
+	require.Error(t, err)
+}
+
+func TestLoadConfig_ToolLimits(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"tool_limits":{
+		"bash":{"max_concurrent":1},
+		"read":{"max_concurrent":4,"max_calls":30,"window":"10s"},
+		"grep":{"max_calls":5}
+	}}`), 0o600))
+
+	limits, err := LoadToolLimitsForTest(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]pipeexec.ToolLimit{
+		"bash": {MaxConcurrent: 1},
+		"read": {MaxConcurrent: 4, MaxCalls: 30, Window: 10 * time.Second},
+		"grep": {MaxCalls: 5, Window: time.Minute},
+	}, limits)
+}
+
+func TestLoadConfig_Retries(t *testing.T) {
+	t.Parallel()
+	write := func(t *testing.T, data string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		return path
+	}
+
+	policy, err := LoadRetryPolicyForTest(write(t, `{}`), "anthropic")
+	require.NoError(t, err)
+	assert.Equal(t, pipe.RetryPolicy{MaxRetries: 3, Resume: true}, policy)
+
+	policy, err = LoadRetryPolicyForTest(write(t, `{"retries": 5}`), "gemini")
+	require.NoError(t, err)
+	assert.Equal(t, pipe.RetryPolicy{MaxRetries: 5}, policy)
+
+	policy, err = LoadRetryPolicyForTest(write(t, `{"retries": -1}`), "gemini")
+	require.NoError(t, err)
+	assert.Zero(t, policy.MaxRetries)
+}
+
+func TestLoadConfig_LoopDetection(t *testing.T) {
+	t.Parallel()
+	write := func(t *testing.T, data string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		return path
+	}
+
+	for _, tt := range []struct {
+		config string
+		want   int
+	}{
+		{`{}`, 0},
+		{`{"loop_detection": 5}`, 5},
+		{`{"loop_detection": -1}`, 0},
+	} {
+		n, err := LoadLoopDetectionForTest(write(t, tt.config))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, n, tt.config)
+	}
+
+	t.Run("the default config lets the model poll a background command", func(t *testing.T) {
+		t.Parallel()
+		n, err := LoadLoopDetectionForTest(write(t, `{}`))
+		require.NoError(t, err)
+		const polls = 5
+		poll := pipe.ToolCallBlock{ID: "c", Name: "bash", Arguments: json.RawMessage(`{"check_pid": 42}`)}
+		turn := 0
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			msg := pipe.AssistantMessage{Content: []pipe.ContentBlock{poll}, StopReason: pipe.StopToolUse}
+			if turn == polls {
+				msg = pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Done."}}, StopReason: pipe.StopEndTurn}
+			}
+			turn++
+			return &mock.Stream{
+				NextFn:    func() (pipe.Event, error) { return nil, io.EOF },
+				MessageFn: func() (pipe.AssistantMessage, error) { return msg, nil },
+			}, nil
+		}}
+		executed := 0
+		executor := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+			executed++
+			return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "still running"}}}, nil
+		}}
+		err = pipe.NewLoop(provider, executor).Run(context.Background(), &pipe.Session{}, []pipe.Tool{{Name: "bash"}}, pipe.WithLoopDetection(n))
+		require.NoError(t, err)
+		assert.Equal(t, polls, executed)
+	})
+}
+
+func TestLoadConfig_Approve(t *testing.T) {
+	t.Parallel()
+	write := func(t *testing.T, data string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		return path
+	}
+	var asked []string
+	ask := func(_ context.Context, call pipe.ToolCallBlock) (pipe.Decision, error) {
+		asked = append(asked, call.Name)
+		return pipe.DecisionDeny, nil
+	}
+
+	approve, err := LoadApproverForTest(write(t, `{}`), ask)
+	require.NoError(t, err)
+	assert.Nil(t, approve)
+
+	approve, err = LoadApproverForTest(write(t, `{"approve": ["bash"]}`), ask)
+	require.NoError(t, err)
+	d, err := approve(context.Background(), pipe.ToolCallBlock{Name: "read"})
+	require.NoError(t, err)
+	assert.Equal(t, pipe.DecisionAllow, d)
+	d, err = approve(context.Background(), pipe.ToolCallBlock{Name: "bash"})
+	require.NoError(t, err)
+	assert.Equal(t, pipe.DecisionDeny, d)
+
+	approve, err = LoadApproverForTest(write(t, `{"approve": ["*"]}`), ask)
+	require.NoError(t, err)
+	_, err = approve(context.Background(), pipe.ToolCallBlock{Name: "read"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bash", "read"}, asked)
+
+	_, err = LoadApproverForTest(write(t, `{"approve": ["teleport"]}`), ask)
+	require.EqualError(t, err, `approve: unknown tool "teleport"`)
+}
+
+func TestLoadConfig_StatusSegments(t *testing.T) {
+	t.Parallel()
+	write := func(t *testing.T, data string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		return path
+	}
+
+	segs, err := LoadStatusSegmentsForTest(write(t, `{"status_segments": [
+		{"command": "printf 'prod\\nextra'", "interval": "30s", "max_width": 10},
+		{"command": "exit 1"}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, segs, 2)
+	assert.Equal(t, "prod", segs[0].Refresh())
+	assert.Equal(t, 30*time.Second, segs[0].Interval)
+	assert.Equal(t, 10, segs[0].MaxWidth)
+	assert.Empty(t, segs[1].Refresh())
+	assert.Zero(t, segs[1].Interval)
+
+	_, err = LoadStatusSegmentsForTest(write(t, `{"status_segments": [{"command": "date", "interval": "soon"}]}`))
+	require.EqualError(t, err, `status_segments[0]: invalid interval "soon"`)
+	_, err = LoadStatusSegmentsForTest(write(t, `{"status_segments": [{"command": " "}]}`))
+	require.EqualError(t, err, `status_segments[0]: command is required`)
+}
+
+func TestLoadConfig_Exec(t *testing.T) {
+	t.Parallel()
+	write := func(t *testing.T, data string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		return path
+	}
+
+	argv, paths, err := LoadShellForTest(write(t, `{}`), "/srv/work", "ls")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bash", "-c", "ls"}, argv)
+	assert.Zero(t, paths)
+
+	argv, paths, err = LoadShellForTest(write(t, `{"exec": {"backend": "ssh", "host": "dev@build"}}`), "/srv/work", "ls")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ssh", "-o", "BatchMode=yes", "--", "dev@build", "cd '/srv/work' && bash -c 'ls'"}, argv)
+	assert.Zero(t, paths)
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "ssh", "host": "dev@build", "root": "/srv/work/"}}`), "/srv/work", "ls")
+	require.NoError(t, err)
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "ssh", "host": "dev@build", "root": "/home/dev/work"}}`), "/srv/work", "ls")
+	require.EqualError(t, err, `exec: ssh root "/home/dev/work" is not the workspace /srv/work; the host must see the workspace at the same path`)
+
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "ssh"}}`), "/srv/work", "ls")
+	require.EqualError(t, err, `exec: ssh backend needs a host, got ""`)
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "ssh", "host": "-oProxyCommand=x"}}`), "/srv/work", "ls")
+	require.Error(t, err)
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "telnet"}}`), "/srv/work", "ls")
+	require.EqualError(t, err, `exec: unknown backend "telnet": must be "local", "ssh", or "docker"`)
+
+	mount := pipeexec.DockerMount{Source: "/srv", Destination: "/src"}
+	argv, paths, err = LoadShellForTest(write(t, `{"exec": {"backend": "docker", "container": "dev"}}`), "/srv/work", "ls", mount)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docker", "exec", "-w", "/src/work", "dev", "bash", "-c", "ls"}, argv)
+	assert.Equal(t, pipeexec.PathMap{Local: "/srv/work", Remote: "/src/work"}, paths)
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "docker", "container": "dev", "root": "/src/work"}}`), "/srv/work", "ls", mount)
+	require.NoError(t, err)
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "docker", "container": "dev", "root": "/src"}}`), "/srv/work", "ls", mount)
+	require.EqualError(t, err, `exec: docker root "/src" is not where container dev mounts the workspace, /src/work`)
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "docker", "container": "dev"}}`), "/srv/work", "ls")
+	require.EqualError(t, err, "exec: container dev does not mount the workspace /srv/work; start it with a bind mount, e.g. -v /srv/work:/workspace")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	argv, paths, err = LoadShellForTest(write(t, `{"exec": {"backend": "docker", "image": "golang:1.24"}}`), wd, "ls")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docker", "run", "--rm", "-v", wd + ":/workspace", "-w", "/workspace", "--", "golang:1.24", "bash", "-c", "ls"}, argv)
+	assert.Equal(t, pipeexec.PathMap{Local: wd, Remote: "/workspace"}, paths)
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "docker", "image": "golang:1.24", "root": "src"}}`), wd, "ls")
+	require.EqualError(t, err, `exec: docker root "src" must be an absolute path below /`)
+
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "docker"}}`), "/srv/work", "ls")
+	require.EqualError(t, err, "exec: docker backend needs a container or an image")
+	_, _, err = LoadShellForTest(write(t, `{"exec": {"backend": "docker", "container": "dev", "image": "golang"}}`), "/srv/work", "ls")
+	require.EqualError(t, err, "exec: docker backend takes a container or an image, not both")
+}
+
+func TestLoadConfig_ExecNote(t *testing.T) {
+	t.Parallel()
+	write := func(t *testing.T, data string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		return path
+	}
+
+	note, desc, err := LoadExecNoteForTest(write(t, `{}`))
+	require.NoError(t, err)
+	assert.Empty(t, note)
+	assert.NotContains(t, desc, "this machine")
+
+	note, desc, err = LoadExecNoteForTest(write(t, `{"exec": {"backend": "ssh", "host": "dev@build"}}`))
+	require.NoError(t, err)
+	assert.Contains(t, note, "dev@build")
+	assert.Contains(t, note, "run_tests included, work on this machine")
+	assert.True(t, strings.HasSuffix(desc, " "+note), desc)
+
+	note, _, err = LoadExecNoteForTest(write(t, `{"exec": {"backend": "docker", "image": "golang:1.24"}}`))
+	require.NoError(t, err)
+	assert.Contains(t, note, "a fresh container of the image golang:1.24, which mounts the workspace")
+}
+
+func TestLoadConfig_Pricing(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"pricing":{"local-llama":{"input":0.1,"output":0.2},"claude-sonnet-4-20250514":{"input":2,"output":10}}}`), 0o600))
+
+	p, ok, err := LoadPricingForTest(path, "local-llama")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, pipe.Pricing{Input: 0.1, Output: 0.2}, p)
+
+	p, _, err = LoadPricingForTest(path, "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, p.Input, "the config overrides the list price")
+
+	p, ok, err = LoadPricingForTest(path, "claude-haiku-4-5")
+	require.NoError(t, err)
+	assert.True(t, ok, "other models use their list price")
+	assert.Equal(t, 1.0, p.Input)
+
+	_, ok, err = LoadPricingForTest(path, "unknown")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLoadConfig_ToolLimitsInvalid(t *testing.T) {
+	t.Parallel()
+
+	for name, body := range map[string]string{
+		"unknown tool":   `{"tool_limits":{"teleport":{"max_concurrent":1}}}`,
+		"invalid window": `{"tool_limits":{"bash":{"max_calls":1,"window":"soon"}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "config.json")
+			require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+
+			_, err := LoadToolLimitsForTest(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "tool_limits")
+		})
+	}
+}
+
+func TestLoadConfig_Locale(t *testing.T) {
+	t.Parallel()
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+
+	t.Run("environment locale", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{}`), 0o600))
+
+		locale, err := LoadLocaleForTest(path, env(map[string]string{"LANG": "pl_PL.UTF-8"}))
+		require.NoError(t, err)
+		assert.Equal(t, "pl-PL", locale.Tag)
+	})
+
+	t.Run("config overrides environment", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"locale":"de-DE","time_zone":"Asia/Tokyo"}`), 0o600))
+
+		locale, err := LoadLocaleForTest(path, env(map[string]string{"LC_ALL": "fr_FR.UTF-8"}))
+		require.NoError(t, err)
+		assert.Equal(t, "de-DE", locale.Tag)
+		assert.Equal(t, "Asia/Tokyo", locale.Zone().String())
+	})
+
+	t.Run("rejects unknown values", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"time_zone":"Mars/Olympus"}`), 0o600))
+
+		_, err := LoadLocaleForTest(path, env(nil))
+		assert.ErrorContains(t, err, "time zone")
+	})
+}
+
+func TestLoadConfig_ConfirmCost(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"confirm_cost":0.5,"pricing":{"local":{"input":10,"output":20}}}`), 0o600))
+
+	var asked []float64
+	confirm := func(_ context.Context, _ pipe.Usage, cost float64) (pipe.Decision, error) {
+		asked = append(asked, cost)
+		return pipe.DecisionDeny, nil
+	}
+	guard, err := LoadCostGuardForTest(path, "local", confirm)
+	require.NoError(t, err)
+	require.NotNil(t, guard)
+
+	// 40k chars estimate 10k tokens: $0.10 at $10 per million.
+	small := pipe.Request{Messages: []pipe.Message{pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: strings.Repeat("x", 40000)}}}}}
+	d, err := guard(context.Background(), small)
+	require.NoError(t, err)
+	assert.Equal(t, pipe.DecisionAllow, d)
+	assert.Empty(t, asked, "cheap requests are not asked about")
+
+	big := pipe.Request{Messages: []pipe.Message{pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: strings.Repeat("x", 400000)}}}}}
+	d, err = guard(context.Background(), big)
+	require.NoError(t, err)
+	assert.Equal(t, pipe.DecisionDeny, d)
+	require.Len(t, asked, 1)
+	assert.InDelta(t, 1.0, asked[0], 1e-9)
+
+	guard, err = LoadCostGuardForTest(path, "unknown", confirm)
+	require.NoError(t, err)
+	assert.Nil(t, guard, "models without a price are not guarded")
+}
+
+func TestLoadConfig_ProviderOptions(t *testing.T) {
+	t.Parallel()
+	write := func(t *testing.T, body string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+		return path
+	}
+
+	opts, err := LoadProviderOptionsForTest(write(t, `{"provider_options":{"thinking_budget":0,"top_k":40,
+		"safety_settings":[{"category":"HARM_CATEGORY_HARASSMENT","threshold":"BLOCK_ONLY_HIGH"}]}}`))
+	require.NoError(t, err)
+	require.NotNil(t, opts.ThinkingBudget)
+	assert.Equal(t, 0, *opts.ThinkingBudget, "an explicit zero turns thinking off")
+	assert.Nil(t, opts.TopP)
+	assert.Equal(t, 40, *opts.TopK)
+	assert.Equal(t, []pipe.SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH"}}, opts.SafetySettings)
+
+	_, err = LoadProviderOptionsForTest(write(t, `{"provider_options":{"top_p":2}}`))
+	require.ErrorContains(t, err, "provider_options: top_p must be in [0, 1]")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+)
+
+const datasetUsage = "usage: pipe sessions dataset [-format openai|anthropic] [-successful] [-strip-tools] [-o file] [-dir sessions] [FILE...]"
+
+// datasetSessions implements "pipe sessions dataset": it writes the given
+// session files, or all those in -dir, as dataset records, one JSON line
+// per session, for evaluation or fine-tuning pipelines.
+func datasetSessions(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("sessions dataset", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", string(pipejson.DatasetOpenAI), "")
+	successful := flags.Bool("successful", false, "")
+	stripTools := flags.Bool("strip-tools", false, "")
+	output := flags.String("o", "", "")
+	dir := flags.String("dir", defaultSessionDir(), "")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", datasetUsage, err)
+	}
+	opts := pipejson.DatasetOptions{Format: pipejson.DatasetFormat(*format), StripTools: *stripTools}
+	if !slices.Contains([]pipejson.DatasetFormat{pipejson.DatasetOpenAI, pipejson.DatasetAnthropic}, opts.Format) {
+		return fmt.Errorf("unknown dataset format %q: must be openai or anthropic", *format)
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		var err error
+		if paths, err = filepath.Glob(filepath.Join(*dir, "*.json")); err != nil {
+			return err
+		}
+		slices.Sort(paths)
+	}
+	if len(paths) == 0 {
+		return errors.New(datasetUsage)
+	}
+
+	out := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	records := 0
+	for _, path := range paths {
+		s, err := pipejson.Load(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if s.LastPrompt() < 0 || (*successful && !runSucceeded(s)) {
+			continue
+		}
+		line, err := pipejson.MarshalDatasetRecord(s, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		records++
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if *output != "" {
+		if _, err := fmt.Fprintf(stdout, "Wrote %d of %d sessions to %s\n", records, len(paths), *output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runSucceeded reports whether the session's last run finished: the model
+// ended its turn normally, rather than on an error, a limit, or an
+// interruption, and did not report itself blocked.
+func runSucceeded(s pipe.Session) bool {
+	if len(s.Messages) == 0 || s.Status.State == pipe.RunBlocked {
+		return false
+	}
+	last, ok := s.Messages[len(s.Messages)-1].(pipe.AssistantMessage)
+	return ok && last.StopReason == pipe.StopEndTurn
+}
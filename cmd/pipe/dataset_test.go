@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSessionsDataset(t *testing.T) {
+	t.Parallel()
+
+	text := func(s string) []pipe.ContentBlock { return []pipe.ContentBlock{pipe.TextBlock{Text: s}} }
+	dir := t.TempDir()
+	save := func(t *testing.T, name string, s pipe.Session) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		require.NoError(t, pipejson.Save(path, s))
+		return path
+	}
+	done := save(t, "a.json", pipe.Session{ID: "a", Messages: []pipe.Message{
+		pipe.UserMessage{Content: text("fix it")},
+		pipe.AssistantMessage{Content: text("Fixed."), StopReason: pipe.StopEndTurn},
+	}})
+	save(t, "b.json", pipe.Session{ID: "b", Messages: []pipe.Message{
+		pipe.UserMessage{Content: text("build it")},
+		pipe.AssistantMessage{Content: text("Stopped."), StopReason: pipe.StopLimit},
+	}})
+	save(t, "c.json", pipe.Session{ID: "c"})
+
+	t.Run("all sessions with a prompt", func(t *testing.T) {
+		t.Parallel()
+		var out bytes.Buffer
+		require.NoError(t, runSessions([]string{"dataset", "-dir", dir}, &out))
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], "fix it")
+		assert.Contains(t, lines[1], "build it")
+	})
+
+	t.Run("successful runs to a file", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "evals.jsonl")
+		var out bytes.Buffer
+		require.NoError(t, runSessions([]string{"dataset", "-format", "anthropic", "-successful", "-o", path, "-dir", dir}, &out))
+		assert.Equal(t, "Wrote 1 of 3 sessions to "+path+"\n", out.String())
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"messages":[
+			{"role":"user","content":[{"type":"text","text":"fix it"}]},
+			{"role":"assistant","content":[{"type":"text","text":"Fixed."}]}
+		]}`, string(data))
+	})
+
+	t.Run("named files", func(t *testing.T) {
+		t.Parallel()
+		var out bytes.Buffer
+		require.NoError(t, runSessions([]string{"dataset", done}, &out))
+		assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		t.Parallel()
+		err := runSessions([]string{"dataset", "-format", "csv", done}, &bytes.Buffer{})
+		require.EqualError(t, err, `unknown dataset format "csv": must be openai or anthropic`)
+	})
+}
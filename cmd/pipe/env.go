@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+// envName matches the variable names the env config key and /env accept:
+// names a shell can export.
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// env returns the configured tool environment as sorted "KEY=value"
+// entries.
+func (c config) env() ([]string, error) {
+	for name := range c.Env {
+		if !envName.MatchString(name) {
+			return nil, fmt.Errorf("env: invalid variable name %q", name)
+		}
+	}
+	return environ(c.Env), nil
+}
+
+// environ returns vars as sorted "KEY=value" entries.
+func environ(vars map[string]string) []string {
+	var env []string
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		env = append(env, name+"="+vars[name])
+	}
+	return env
+}
+
+// sessionEnv holds the variables /env sets and unsets for this session.
+// They apply over the configured ones to the commands tools run and are
+// never sent to the provider or saved.
+type sessionEnv struct {
+	// base returns the configured variables, which may change on reload.
+	base func() map[string]string
+
+	mu    sync.Mutex
+	set   map[string]string
+	unset map[string]bool
+}
+
+// environ returns the tool environment: the configured variables with the
+// session's changes applied.
+func (s *sessionEnv) environ() []string {
+	base := s.base()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vars := maps.Clone(base)
+	if vars == nil {
+		vars = make(map[string]string)
+	}
+	for name := range s.unset {
+		delete(vars, name)
+	}
+	maps.Copy(vars, s.set)
+	return environ(vars)
+}
+
+// command implements /env: with no arguments it lists the variable names,
+// "set KEY=value" sets one, and "unset KEY" removes one. Values are not
+// shown, as they are often credentials.
+func (s *sessionEnv) command(args string) (bt.CommandResult, error) {
+	verb, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+	switch verb {
+	case "":
+		var names []string
+		for _, kv := range s.environ() {
+			name, _, _ := strings.Cut(kv, "=")
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return bt.CommandResult{Notice: "No tool environment variables (/env set KEY=value to add one)."}, nil
+		}
+		return bt.CommandResult{Notice: "Tool environment: " + strings.Join(names, ", ")}, nil
+	case "set":
+		name, value, ok := strings.Cut(rest, "=")
+		if !ok || !envName.MatchString(name) {
+			return bt.CommandResult{}, fmt.Errorf("env: usage: /env set KEY=value")
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.set == nil {
+			s.set = make(map[string]string)
+		}
+		s.set[name] = value
+		delete(s.unset, name)
+		return bt.CommandResult{Notice: fmt.Sprintf("Set %s for tool commands in this session; it applies from the next prompt.", name)}, nil
+	case "unset":
+		if !envName.MatchString(rest) {
+			return bt.CommandResult{}, fmt.Errorf("env: usage: /env unset KEY")
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.unset == nil {
+			s.unset = make(map[string]bool)
+		}
+		s.unset[rest] = true
+		delete(s.set, rest)
+		return bt.CommandResult{Notice: fmt.Sprintf("Unset %s for tool commands in this session.", rest)}, nil
+	default:
+		return bt.CommandResult{}, fmt.Errorf("env: unknown subcommand %q; use set or unset", verb)
+	}
+}
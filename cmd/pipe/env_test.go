@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Env(t *testing.T) {
+	t.Parallel()
+	env, err := config{Env: map[string]string{"TEST_DB": "postgres://x", "API_MODE": "stub"}}.env()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"API_MODE=stub", "TEST_DB=postgres://x"}, env)
+
+	_, err = config{Env: map[string]string{"BAD-NAME": "x"}}.env()
+	assert.ErrorContains(t, err, `env: invalid variable name "BAD-NAME"`)
+}
+
+func TestSessionEnv(t *testing.T) {
+	t.Parallel()
+	base := map[string]string{"TEST_DB": "postgres://x", "API_MODE": "stub"}
+	s := &sessionEnv{base: func() map[string]string { return base }}
+
+	res, err := s.command("set TEST_DB=postgres://y?sslmode=disable")
+	require.NoError(t, err)
+	assert.Contains(t, res.Notice, "Set TEST_DB")
+	_, err = s.command("unset API_MODE")
+	require.NoError(t, err)
+	_, err = s.command("set TOKEN=a=b")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TEST_DB=postgres://y?sslmode=disable", "TOKEN=a=b"}, s.environ())
+	assert.Equal(t, "postgres://x", base["TEST_DB"], "the config is not modified")
+
+	res, err = s.command("")
+	require.NoError(t, err)
+	assert.Equal(t, "Tool environment: TEST_DB, TOKEN", res.Notice)
+
+	_, err = s.command("set 1BAD=x")
+	assert.ErrorContains(t, err, "usage: /env set KEY=value")
+	_, err = s.command("export X=1")
+	assert.ErrorContains(t, err, `unknown subcommand "export"`)
+}
@@ -1,11 +1,207 @@
 package main
 
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	pipeexec "github.com/fwojciec/pipe/exec"
+)
+
 // ResolveConfigForTest exposes resolveConfig for external tests, returning
 // the resolved provider name and key.
 func ResolveConfigForTest(providerFlag, apiKeyFlag, anthropicEnvKey, geminiEnvKey string) (name, key string, err error) {
-	cfg, err := resolveConfig(providerFlag, apiKeyFlag, anthropicEnvKey, geminiEnvKey)
+	env := map[string]string{"ANTHROPIC_API_KEY": anthropicEnvKey, "GEMINI_API_KEY": geminiEnvKey}
+	cfg, err := resolveConfig(newProviderRegistry(&config{}, nil), providerFlag, apiKeyFlag, func(k string) string { return env[k] })
+	if err != nil {
+		return "", "", err
+	}
+	return cfg.name, cfg.key, nil
+}
+
+// LoadServerToolsForTest exposes loadConfig for external tests, returning the
+// configured server tools.
+func LoadServerToolsForTest(path string) ([]pipe.ServerTool, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.serverTools()
+}
+
+// LoadToolLimitsForTest exposes loadConfig for external tests, returning the
+// configured tool limits.
+func LoadToolLimitsForTest(path string) (map[string]pipeexec.ToolLimit, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.toolLimits()
+}
+
+// LoadRetryPolicyForTest loads the config at path and returns its retry
+// policy for the named provider.
+func LoadRetryPolicyForTest(path, provider string) (pipe.RetryPolicy, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return pipe.RetryPolicy{}, err
+	}
+	return cfg.retryPolicy(provider), nil
+}
+
+// LoadLoopDetectionForTest loads the config at path and returns its loop
+// detection threshold.
+func LoadLoopDetectionForTest(path string) (int, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return 0, err
+	}
+	return cfg.loopDetection(), nil
+}
+
+// LoadStatusSegmentsForTest loads the config at path and returns its
+// status segments.
+func LoadStatusSegmentsForTest(path string) ([]bt.StatusSegment, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.statusSegments()
+}
+
+// LoadShellForTest loads the config at path and returns the argv its exec
+// backend uses to run command for the workspace at dir, and how paths
+// translate there. A running container has mounts.
+func LoadShellForTest(path, dir, command string, mounts ...pipeexec.DockerMount) ([]string, pipeexec.PathMap, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, pipeexec.PathMap{}, err
+	}
+	shell, paths, err := cfg.shell(dir, func(string) ([]pipeexec.DockerMount, error) { return mounts, nil })
+	if err != nil {
+		return nil, pipeexec.PathMap{}, err
+	}
+	return shell(command, nil).Argv, paths, nil
+}
+
+// LoadExecNoteForTest loads the config at path and returns where it tells
+// the model bash commands run, and the description of its bash tool.
+func LoadExecNoteForTest(path string) (string, string, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return "", "", err
+	}
+	for _, t := range cfg.enabledTools() {
+		if t.Name == "bash" {
+			return cfg.Exec.note(), t.Description, nil
+		}
+	}
+	return cfg.Exec.note(), "", nil
+}
+
+// LoadApproverForTest loads the config at path and returns its approval
+// hook with ask deciding the gated calls.
+func LoadApproverForTest(path string, ask func(context.Context, pipe.ToolCallBlock) (pipe.Decision, error)) (func(context.Context, pipe.ToolCallBlock) (pipe.Decision, error), error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.approver(ask)
+}
+
+// RunHeatmapForTest exposes the heatmap subcommand for external tests.
+func RunHeatmapForTest(args []string, stdout io.Writer) error {
+	return runHeatmap(args, stdout, pipe.Locale{})
+}
+
+// StartProviderForTest exposes startProvider for external tests.
+func StartProviderForTest(build func() (pipe.Provider, error)) pipe.Provider {
+	return startProvider(build)
+}
+
+// RunUsageForTest exposes the usage subcommand for external tests.
+func RunUsageForTest(args []string, stdout io.Writer, now time.Time, locale pipe.Locale) error {
+	return runUsage(args, stdout, now, locale)
+}
+
+// LoadLocaleForTest exposes loadConfig for external tests, returning the
+// display locale resolved with getenv.
+func LoadLocaleForTest(path string, getenv func(string) string) (pipe.Locale, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return pipe.Locale{}, err
+	}
+	return cfg.locale(getenv)
+}
+
+// RunReconcileForTest exposes the usage reconcile subcommand for external
+// tests.
+func RunReconcileForTest(ctx context.Context, args []string, stdout io.Writer, now time.Time, locale pipe.Locale, reporter pipe.UsageReporter) error {
+	return runReconcile(ctx, args, stdout, now, locale, reporter)
+}
+
+// FastestProviderForTest exposes fastestProvider for external tests.
+func FastestProviderForTest(ctx context.Context, registry *pipe.ProviderRegistry, names []string, getenv func(string) string) (string, error) {
+	return fastestProvider(ctx, registry, names, getenv)
+}
+
+// RunRunsForTest exposes the runs subcommand for external tests.
+func RunRunsForTest(ctx context.Context, args []string, stdout io.Writer, now time.Time, execute func(context.Context, *pipe.Session, pipe.ScheduledRun) error) error {
+	return runRuns(ctx, args, stdout, now, func(string) (pipe.Locale, scheduledRunner, error) {
+		return pipe.Locale{Location: time.UTC}, execute, nil
+	})
+}
+
+// RunsConfigPathForTest runs the runs subcommand with args and returns the
+// config path it loaded.
+func RunsConfigPathForTest(args []string) (string, error) {
+	var loaded string
+	err := runRuns(context.Background(), args, io.Discard, time.Now(), func(path string) (pipe.Locale, scheduledRunner, error) {
+		loaded = path
+		return pipe.Locale{Location: time.UTC}, nil, nil
+	})
+	return loaded, err
+}
+
+// ResolveProviderForTest exposes resolveConfig with a custom registry for
+// external tests, returning the resolved provider name and key.
+func ResolveProviderForTest(registry *pipe.ProviderRegistry, providerFlag, apiKeyFlag string, getenv func(string) string) (name, key string, err error) {
+	cfg, err := resolveConfig(registry, providerFlag, apiKeyFlag, getenv)
 	if err != nil {
 		return "", "", err
 	}
 	return cfg.name, cfg.key, nil
 }
+
+// LoadPricingForTest loads the config at path and returns the price of
+// model.
+func LoadPricingForTest(path, model string) (pipe.Pricing, bool, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return pipe.Pricing{}, false, err
+	}
+	p, ok := cfg.pricing(model)
+	return p, ok, nil
+}
+
+// LoadCostGuardForTest loads the config at path and returns its request
+// approver for model, or nil if it has none.
+func LoadCostGuardForTest(path, model string, confirm func(context.Context, pipe.Usage, float64) (pipe.Decision, error)) (func(context.Context, pipe.Request) (pipe.Decision, error), error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.costGuard(model, confirm), nil
+}
+
+// LoadProviderOptionsForTest loads the config at path and returns its
+// provider options.
+func LoadProviderOptionsForTest(path string) (pipe.ProviderOptions, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return pipe.ProviderOptions{}, err
+	}
+	return cfg.providerOptions()
+}
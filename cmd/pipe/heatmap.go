@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/html"
+	pipejson "github.com/fwojciec/pipe/json"
+)
+
+const heatmapUsage = "usage: pipe heatmap [-format json|html] [-o file] SESSION"
+
+// runHeatmap implements "pipe heatmap": it writes a report attributing
+// estimated tokens to every block of a saved session. The HTML report is
+// formatted for locale; JSON stays machine-readable.
+func runHeatmap(args []string, stdout io.Writer, locale pipe.Locale) error {
+	flags := flag.NewFlagSet("heatmap", flag.ContinueOnError)
+	format := flags.String("format", "json", "Report format: json, html")
+	outPath := flags.String("o", "", "Output file (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(heatmapUsage)
+	}
+
+	session, err := pipejson.Load(flags.Arg(0), pipejson.WithPreserveUnknown())
+	if err != nil {
+		return fmt.Errorf("load session: %w", err)
+	}
+	attrs := pipe.AttributeTokens(session)
+
+	var buf bytes.Buffer
+	switch *format {
+	case "json":
+		data, err := pipejson.MarshalTokenReport(session.ID, attrs)
+		if err != nil {
+			return fmt.Errorf("heatmap: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	case "html":
+		if err := html.WriteTokenReport(&buf, session.ID, attrs, locale); err != nil {
+			return fmt.Errorf("heatmap: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown format %q: must be json or html", *format)
+	}
+
+	if *outPath == "" {
+		_, err := stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(*outPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	. "github.com/fwojciec/pipe/cmd/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func saveHeatmapSession(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.json")
+	require.NoError(t, pipejson.Save(path, pipe.Session{
+		ID:           "sess-heat",
+		SystemPrompt: "You are helpful.",
+		Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hello"}}},
+		},
+	}))
+	return path
+}
+
+func TestRunHeatmap(t *testing.T) {
+	t.Parallel()
+
+	t.Run("writes json to stdout", func(t *testing.T) {
+		t.Parallel()
+		var out bytes.Buffer
+		require.NoError(t, RunHeatmapForTest([]string{saveHeatmapSession(t)}, &out))
+		assert.Contains(t, out.String(), `"session_id": "sess-heat"`)
+		assert.Contains(t, out.String(), `"kind": "system"`)
+	})
+
+	t.Run("writes html to file", func(t *testing.T) {
+		t.Parallel()
+		outPath := filepath.Join(t.TempDir(), "report.html")
+		require.NoError(t, RunHeatmapForTest([]string{"-format", "html", "-o", outPath, saveHeatmapSession(t)}, &bytes.Buffer{}))
+		data, err := os.ReadFile(outPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "<h1>Token heatmap</h1>")
+	})
+
+	t.Run("rejects unknown format", func(t *testing.T) {
+		t.Parallel()
+		err := RunHeatmapForTest([]string{"-format", "pdf", saveHeatmapSession(t)}, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown format")
+	})
+
+	t.Run("requires session argument", func(t *testing.T) {
+		t.Parallel()
+		err := RunHeatmapForTest(nil, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "usage")
+	})
+}
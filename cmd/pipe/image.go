@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+// maxImageBytes is the largest image attached to a prompt; providers
+// reject larger ones.
+const maxImageBytes = 5 << 20
+
+// clipboardTimeout bounds reading the clipboard.
+const clipboardTimeout = 5 * time.Second
+
+// clipboardCommands returns the commands that read a PNG image from the
+// system clipboard, to be tried in order: Wayland, X11, then macOS.
+func clipboardCommands() [][]string {
+	return [][]string{
+		{"wl-paste", "--no-newline", "--type", "image/png"},
+		{"xclip", "-selection", "clipboard", "-target", "image/png", "-out"},
+		{"pngpaste", "-"},
+	}
+}
+
+// pasteImage reads an image from the system clipboard with the first
+// clipboard command installed.
+func pasteImage() (pipe.ImageBlock, error) {
+	for _, args := range clipboardCommands() {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), clipboardTimeout)
+		data, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+		cancel()
+		if err != nil || len(data) == 0 {
+			return pipe.ImageBlock{}, errors.New("the clipboard holds no image")
+		}
+		return imageBlock(data)
+	}
+	return pipe.ImageBlock{}, errors.New("no clipboard tool found; install wl-paste, xclip, or pngpaste, or use /image PATH")
+}
+
+// imageCommand implements /image: it attaches the image file at the given
+// path to the next prompt.
+func imageCommand(args string) (bt.CommandResult, error) {
+	path := strings.TrimSpace(args)
+	if path == "" {
+		return bt.CommandResult{}, errors.New("image: usage: /image PATH")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return bt.CommandResult{}, fmt.Errorf("image: %w", err)
+	}
+	img, err := imageBlock(data)
+	if err != nil {
+		return bt.CommandResult{}, fmt.Errorf("image: %s: %w", path, err)
+	}
+	return bt.CommandResult{Attach: []pipe.ImageBlock{img}}, nil
+}
+
+// imageBlock checks that data is an image providers accept.
+func imageBlock(data []byte) (pipe.ImageBlock, error) {
+	if len(data) > maxImageBytes {
+		return pipe.ImageBlock{}, fmt.Errorf("image is %d MB; the limit is %d MB", len(data)>>20, maxImageBytes>>20)
+	}
+	switch mime := http.DetectContentType(data); mime {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return pipe.ImageBlock{Data: data, MimeType: mime}, nil
+	default:
+		return pipe.ImageBlock{}, fmt.Errorf("not a PNG, JPEG, GIF, or WebP image (%s)", mime)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageCommand(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 16)...)
+	shot := filepath.Join(dir, "shot.png")
+	require.NoError(t, os.WriteFile(shot, png, 0o600))
+	notes := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(notes, []byte("hello"), 0o600))
+	big := filepath.Join(dir, "big.png")
+	require.NoError(t, os.WriteFile(big, append(png, make([]byte, maxImageBytes)...), 0o600))
+
+	res, err := imageCommand(" " + shot + " ")
+	require.NoError(t, err)
+	assert.Equal(t, []pipe.ImageBlock{{Data: png, MimeType: "image/png"}}, res.Attach)
+
+	_, err = imageCommand(notes)
+	require.ErrorContains(t, err, "not a PNG, JPEG, GIF, or WebP image (text/plain; charset=utf-8)")
+	_, err = imageCommand(big)
+	require.ErrorContains(t, err, "the limit is 5 MB")
+	_, err = imageCommand(filepath.Join(dir, "missing.png"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = imageCommand("")
+	require.EqualError(t, err, "image: usage: /image PATH")
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+const exportUsage = "usage: /export issue [PATH]"
+
+// maxExportLogLines caps each tool log in an exported issue; the tail is
+// kept because it usually holds the result or the error.
+const maxExportLogLines = 40
+
+// exporter writes the session in formats meant for other tools.
+type exporter struct {
+	session *pipe.Session
+	dir     string // where drafts go when no path is given
+}
+
+// command implements /export. It only runs while the agent is idle, so the
+// session is not being modified.
+func (e *exporter) command(args string) (bt.CommandResult, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 || fields[0] != "issue" {
+		return bt.CommandResult{}, errors.New(exportUsage)
+	}
+	if e.session.LastPrompt() < 0 {
+		return bt.CommandResult{}, errors.New("nothing to export yet")
+	}
+	path := filepath.Join(e.dir, "issue-"+e.session.ID+".md")
+	if len(fields) == 2 {
+		path = fields[1]
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return bt.CommandResult{}, fmt.Errorf("export issue: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(formatIssue(*e.session)), 0o644); err != nil {
+		return bt.CommandResult{}, fmt.Errorf("export issue: %w", err)
+	}
+	return bt.CommandResult{Notice: "Issue draft written to " + path}, nil
+}
+
+// formatIssue renders a session as a GitHub/GitLab issue draft: the first
+// prompt is the problem, the last assistant text the conclusion, and
+// everything in between the investigation, with tool logs collapsed in
+// details sections.
+func formatIssue(s pipe.Session) string {
+	problem, conclusion := "", -1 // conclusion indexes the message holding it
+	for i, msg := range s.Messages {
+		switch msg := msg.(type) {
+		case pipe.UserMessage:
+			if problem == "" {
+				problem = blockText(msg.Content)
+			}
+		case pipe.AssistantMessage:
+			if blockText(msg.Content) != "" {
+				conclusion = i
+			}
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n## Problem\n\n%s\n", issueTitle(problem), problem)
+
+	var investigation strings.Builder
+	calls := make(map[string]pipe.ToolCallBlock)
+	seenProblem := false
+	for i, msg := range s.Messages {
+		switch msg := msg.(type) {
+		case pipe.UserMessage:
+			if !seenProblem {
+				seenProblem = true
+				continue
+			}
+			if text := blockText(msg.Content); text != "" {
+				fmt.Fprintf(&investigation, "\n> **Follow-up:** %s\n", strings.ReplaceAll(text, "\n", "\n> "))
+			}
+		case pipe.AssistantMessage:
+			for _, block := range msg.Content {
+				if call, ok := block.(pipe.ToolCallBlock); ok {
+					calls[call.ID] = call
+				}
+			}
+			if text := blockText(msg.Content); text != "" && i != conclusion {
+				fmt.Fprintf(&investigation, "\n%s\n", text)
+			}
+		case pipe.ToolResultMessage:
+			writeToolLog(&investigation, calls[msg.ToolCallID], msg)
+		}
+	}
+	if investigation.Len() > 0 {
+		fmt.Fprintf(&b, "\n## Investigation\n%s", investigation.String())
+	}
+	if conclusion >= 0 {
+		text := blockText(s.Messages[conclusion].(pipe.AssistantMessage).Content)
+		fmt.Fprintf(&b, "\n## Conclusion\n\n%s\n", text)
+	}
+	return b.String()
+}
+
+// writeToolLog writes a tool call and its result as a collapsed details
+// section.
+func writeToolLog(b *strings.Builder, call pipe.ToolCallBlock, result pipe.ToolResultMessage) {
+	summary := result.ToolName
+	if arg := toolSummaryArg(call.Arguments); arg != "" {
+		summary += ": " + arg
+	}
+	if result.IsError {
+		summary += " (failed)"
+	}
+	log := blockText(result.Content)
+	lines := strings.Split(log, "\n")
+	if len(lines) > maxExportLogLines {
+		omitted := len(lines) - maxExportLogLines
+		log = fmt.Sprintf("[%d earlier lines omitted]\n%s", omitted, strings.Join(lines[omitted:], "\n"))
+	}
+	fence := codeFence(log)
+	fmt.Fprintf(b, "\n<details>\n<summary>%s</summary>\n\n%s\n%s\n%s\n\n</details>\n",
+		escapeHTML(summary), fence, log, fence)
+}
+
+// toolSummaryArg picks the argument that best identifies a tool call, such
+// as a bash command or a file path, shortened to one line.
+func toolSummaryArg(args json.RawMessage) string {
+	var fields map[string]any
+	if json.Unmarshal(args, &fields) != nil {
+		return ""
+	}
+	for _, key := range []string{"command", "file_path", "pattern", "path", "question", "prompt"} {
+		if v, ok := fields[key].(string); ok && v != "" {
+			return truncatePrompt(v, 80)
+		}
+	}
+	return ""
+}
+
+// issueTitle derives a title from the problem statement's first line.
+func issueTitle(problem string) string {
+	if problem == "" {
+		return "Untitled"
+	}
+	return truncatePrompt(problem, 72)
+}
+
+// blockText joins the text blocks of a message.
+func blockText(blocks []pipe.ContentBlock) string {
+	var parts []string
+	for _, block := range blocks {
+		if text, ok := block.(pipe.TextBlock); ok && strings.TrimSpace(text.Text) != "" {
+			parts = append(parts, strings.TrimSpace(text.Text))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// codeFence returns a backtick fence longer than any backtick run in s, so
+// logs containing markdown cannot break out of their code block.
+func codeFence(s string) string {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// escapeHTML escapes text for use inside an HTML element such as summary.
+func escapeHTML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatIssue(t *testing.T) {
+	t.Parallel()
+
+	text := func(s string) []pipe.ContentBlock { return []pipe.ContentBlock{pipe.TextBlock{Text: s}} }
+	session := pipe.Session{ID: "s1", Messages: []pipe.Message{
+		pipe.UserMessage{Content: text("TestParse fails on CI\nIt passes locally.")},
+		pipe.AssistantMessage{Content: []pipe.ContentBlock{
+			pipe.TextBlock{Text: "Let me run the test."},
+			pipe.ToolCallBlock{ID: "c1", Name: "bash", Arguments: json.RawMessage(`{"command":"go test -run TestParse"}`)},
+		}},
+		pipe.ToolResultMessage{ToolCallID: "c1", ToolName: "bash", Content: text("--- FAIL: TestParse\n```\nwant <nil>"), IsError: true},
+		pipe.UserMessage{Content: text("Check the time zone.")},
+		pipe.AssistantMessage{Content: text("The test depends on the local time zone; pin it to UTC.")},
+	}}
+
+	assert.Equal(t, "# TestParse fails on CI…\n"+
+		"\n## Problem\n\nTestParse fails on CI\nIt passes locally.\n"+
+		"\n## Investigation\n"+
+		"\nLet me run the test.\n"+
+		"\n<details>\n<summary>bash: go test -run TestParse (failed)</summary>\n\n"+
+		"````\n--- FAIL: TestParse\n```\nwant <nil>\n````\n\n</details>\n"+
+		"\n> **Follow-up:** Check the time zone.\n"+
+		"\n## Conclusion\n\nThe test depends on the local time zone; pin it to UTC.\n",
+		formatIssue(session))
+}
+
+func TestFormatIssue_TruncatesLongLogs(t *testing.T) {
+	t.Parallel()
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	session := pipe.Session{Messages: []pipe.Message{
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "build"}}},
+		pipe.ToolResultMessage{ToolName: "bash", Content: []pipe.ContentBlock{pipe.TextBlock{Text: strings.Join(lines, "\n")}}},
+	}}
+
+	issue := formatIssue(session)
+	assert.Contains(t, issue, "[60 earlier lines omitted]\n")
+	assert.Equal(t, maxExportLogLines, strings.Count(issue, "line\n"))
+}
+
+func TestExporter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("writes an issue draft", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		session := &pipe.Session{ID: "s1", Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "fix it"}}},
+		}}
+		e := &exporter{session: session, dir: filepath.Join(dir, "exports")}
+
+		res, err := e.command("issue")
+		require.NoError(t, err)
+		path := filepath.Join(dir, "exports", "issue-s1.md")
+		assert.Equal(t, "Issue draft written to "+path, res.Notice)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "## Problem\n\nfix it\n")
+
+		custom := filepath.Join(dir, "draft.md")
+		_, err = e.command("issue " + custom)
+		require.NoError(t, err)
+		assert.FileExists(t, custom)
+	})
+
+	t.Run("rejects unknown formats and empty sessions", func(t *testing.T) {
+		t.Parallel()
+		e := &exporter{session: &pipe.Session{}, dir: t.TempDir()}
+		_, err := e.command("pdf")
+		require.EqualError(t, err, exportUsage)
+		_, err = e.command("issue")
+		require.EqualError(t, err, "nothing to export yet")
+	})
+}
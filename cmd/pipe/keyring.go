@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// keyringService names pipe's entries in the system keyring.
+const keyringService = "pipe"
+
+// keyringTimeout bounds one call to the keyring tool, which may wait on
+// the user to unlock the keyring.
+const keyringTimeout = 30 * time.Second
+
+// keyring stores provider API keys in the system keyring through its
+// command-line tool: security on macOS, secret-tool (libsecret) on Linux.
+type keyring struct {
+	goos string
+	// run runs a command with stdin and returns its standard output.
+	run func(ctx context.Context, stdin string, name string, args ...string) (string, error)
+}
+
+// systemKeyring returns the keyring of the running system.
+func systemKeyring() keyring {
+	return keyring{goos: runtime.GOOS, run: runCommand}
+}
+
+func runCommand(ctx context.Context, stdin string, name string, args ...string) (string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// errNoKeyring reports a system without a supported keyring tool.
+var errNoKeyring = errors.New("no supported keyring: install secret-tool (libsecret) or set the API key environment variable")
+
+// get returns the key stored for provider, or "" when none is.
+func (k keyring) get(provider string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), keyringTimeout)
+	defer cancel()
+	var out string
+	var err error
+	switch k.goos {
+	case "darwin":
+		out, err = k.run(ctx, "", "security", "find-generic-password", "-s", keyringService, "-a", provider, "-w")
+	case "linux", "freebsd", "openbsd", "netbsd":
+		out, err = k.run(ctx, "", "secret-tool", "lookup", "service", keyringService, "provider", provider)
+	default:
+		return ""
+	}
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// set stores key for provider, replacing any stored before.
+func (k keyring) set(provider, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), keyringTimeout)
+	defer cancel()
+	var err error
+	switch k.goos {
+	case "darwin":
+		// A trailing -w without a value makes security prompt for the
+		// password and its confirmation on stdin, keeping the key out of
+		// the process list.
+		_, err = k.run(ctx, key+"\n"+key+"\n", "security", "add-generic-password", "-U", "-s", keyringService, "-a", provider, "-w")
+	case "linux", "freebsd", "openbsd", "netbsd":
+		// secret-tool reads the secret from stdin, keeping it out of the
+		// process list.
+		_, err = k.run(ctx, key, "secret-tool", "store", "--label", fmt.Sprintf("pipe %s API key", provider), "service", keyringService, "provider", provider)
+	default:
+		return errNoKeyring
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return errNoKeyring
+	}
+	if err != nil {
+		return fmt.Errorf("keyring: store %s key: %w", provider, err)
+	}
+	return nil
+}
+
+// withStoredKeys returns getenv with the backends' API key variables
+// falling back to the keys stored in kr. Each stored key is looked up at
+// most once, and only when its variable is unset.
+func withStoredKeys(getenv func(string) string, kr keyring, backends []pipe.ProviderBackend) func(string) string {
+	stored := make(map[string]func() string)
+	for _, b := range backends {
+		if b.EnvKey != "" {
+			name := b.Name
+			stored[b.EnvKey] = sync.OnceValue(func() string { return kr.get(name) })
+		}
+	}
+	return func(key string) string {
+		if v := getenv(key); v != "" {
+			return v
+		}
+		if get, ok := stored[key]; ok {
+			return get()
+		}
+		return ""
+	}
+}
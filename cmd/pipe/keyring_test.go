@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring(t *testing.T) {
+	t.Parallel()
+
+	// fake returns a keyring for goos that records its commands and
+	// answers lookups with out.
+	fake := func(goos, out string, err error) (keyring, *[]string) {
+		var calls []string
+		return keyring{goos: goos, run: func(_ context.Context, stdin, name string, args ...string) (string, error) {
+			call := name + " " + strings.Join(args, " ")
+			if stdin != "" {
+				call += " <" + stdin
+			}
+			calls = append(calls, call)
+			return out, err
+		}}, &calls
+	}
+
+	t.Run("linux uses secret-tool", func(t *testing.T) {
+		t.Parallel()
+		kr, calls := fake("linux", "sk-stored\n", nil)
+		require.NoError(t, kr.set("anthropic", "sk-new"))
+		assert.Equal(t, "sk-stored", kr.get("anthropic"))
+		assert.Equal(t, []string{
+			"secret-tool store --label pipe anthropic API key service pipe provider anthropic <sk-new",
+			"secret-tool lookup service pipe provider anthropic",
+		}, *calls)
+	})
+
+	t.Run("macOS uses security", func(t *testing.T) {
+		t.Parallel()
+		kr, calls := fake("darwin", "gk-stored\n", nil)
+		require.NoError(t, kr.set("gemini", "gk-new"))
+		assert.Equal(t, "gk-stored", kr.get("gemini"))
+		assert.Equal(t, []string{
+			"security add-generic-password -U -s pipe -a gemini -w <gk-new\ngk-new\n",
+			"security find-generic-password -s pipe -a gemini -w",
+		}, *calls)
+	})
+
+	t.Run("missing tool", func(t *testing.T) {
+		t.Parallel()
+		kr, _ := fake("linux", "", exec.ErrNotFound)
+		assert.Empty(t, kr.get("anthropic"))
+		assert.ErrorIs(t, kr.set("anthropic", "sk"), errNoKeyring)
+
+		kr, calls := fake("windows", "", nil)
+		assert.ErrorIs(t, kr.set("anthropic", "sk"), errNoKeyring)
+		assert.Empty(t, kr.get("anthropic"))
+		assert.Empty(t, *calls)
+	})
+}
+
+func TestWithStoredKeys(t *testing.T) {
+	t.Parallel()
+
+	lookups := 0
+	kr := keyring{goos: "linux", run: func(_ context.Context, _, _ string, args ...string) (string, error) {
+		lookups++
+		if args[len(args)-1] == "anthropic" {
+			return "sk-stored", nil
+		}
+		return "", assert.AnError
+	}}
+	backends := []pipe.ProviderBackend{
+		{Name: "anthropic", EnvKey: "ANTHROPIC_API_KEY"},
+		{Name: "gemini", EnvKey: "GEMINI_API_KEY"},
+	}
+	env := map[string]string{"GEMINI_API_KEY": "gk-env", "HOME": "/home/me"}
+	getenv := withStoredKeys(func(k string) string { return env[k] }, kr, backends)
+
+	assert.Equal(t, "sk-stored", getenv("ANTHROPIC_API_KEY"))
+	assert.Equal(t, "sk-stored", getenv("ANTHROPIC_API_KEY"))
+	assert.Equal(t, "gk-env", getenv("GEMINI_API_KEY"))
+	assert.Equal(t, "/home/me", getenv("HOME"))
+	assert.Empty(t, getenv("OTHER"))
+	assert.Equal(t, 1, lookups, "the keyring is asked once, only for unset keys")
+}
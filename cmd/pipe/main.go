@@ -5,13 +5,61 @@
 //	ANTHROPIC_API_KEY=sk-... pipe [flags]
 //	GEMINI_API_KEY=gk-...   pipe [flags]
 //
+// ANTHROPIC_BASE_URL and GOOGLE_GEMINI_BASE_URL send requests to another
+// endpoint, e.g. a pipe-mockserver.
+//
+// Run without an API key or config file, pipe starts a setup wizard that
+// stores the key in the system keyring, picks a default model, and can
+// create .pipe/prompt.md from a template.
+//
 // Flags:
 //
-//	-provider string     Provider: anthropic, gemini (auto-detected from env vars if omitted)
+//	-provider string     Provider: a registered backend such as anthropic or gemini (auto-detected from env vars if omitted;
+//	                     with several keys set, fastest_provider in the config picks the quickest to answer)
 //	-model string        Model ID (default: provider default)
 //	-session string      Path to session file to resume
+//	-resume              Pick a saved session to resume from a list
+//	-p string            Print mode: run this prompt without the TUI, write the answer to stdout, and exit
+//	                     (non-zero on error); ask_user is unavailable and tools needing approval are denied
+//	-output-format text|json|stream-json  Print mode output: streamed text (default), a JSON object
+//	                     with the final answer and usage, or the run's events as JSON lines
+//	-include-events list  With stream-json: comma-separated event types to write, e.g. text,tool_call
+//	-exclude-events list  With stream-json: comma-separated event types to leave out
+//	-compact-events      With stream-json: merge text deltas into one event per block and write each
+//	                     tool call once
 //	-system-prompt string Path to system prompt file (default: .pipe/prompt.md)
 //	-api-key string      API key (overrides provider's env var)
+//	-config string       Path to config file (default: .pipe/config.json)
+//	-render-interval duration  Batch TUI re-renders while streaming (default: 33ms, about 30fps; 0 = every event)
+//	-profile string      Profile from the config file (switch at runtime with /profile)
+//	-force               Open a session even if another pipe process holds it
+//	-incognito           Leave no record: no session file, usage log entry, memory notes, or webhook payloads
+//	-dump-turns dir      Debug: write the session JSON after every turn to dir, keeping the last 50
+//	-tee target          Mirror streaming events as JSON lines to a file, or to a listening unix
+//	                     socket as unix:PATH, e.g. for a monitor in another terminal
+//	-transcript file     Append a JSON lines log of every event, provider request and response,
+//	                     and tool execution, with timestamps, for debugging and audit
+//
+// Subcommands:
+//
+//	pipe heatmap [-format json|html] [-o file] SESSION
+//	    Report estimated tokens per message block of a saved session.
+//	pipe usage [-last 30d] [-log file]
+//	    Report per-day usage recorded when usage_metrics is enabled.
+//	pipe usage reconcile [-session file | -last 1d] [-tolerance 0.05]
+//	    Compare local usage with the usage Anthropic reports for the period
+//	    (requires ANTHROPIC_ADMIN_KEY).
+//	pipe sessions diff A B
+//	    Show a structural diff of two session files, e.g. turn dumps.
+//	pipe sessions migrate FILE...
+//	    Rewrite session files in the current, compact format.
+//	pipe sessions dataset [-format openai|anthropic] [-successful] [-strip-tools] [-o file] [FILE...]
+//	    Write sessions (default: all saved ones) as JSON lines in the OpenAI
+//	    chat fine-tuning or Anthropic Messages format, for evals or training.
+//	pipe runs list|exec [-dir sessions] [-config file]
+//	pipe runs cancel [-dir sessions] ID
+//	    List, execute (e.g. from cron), or cancel the follow-up runs sessions
+//	    have scheduled with the schedule tool.
 package main
 
 import (
@@ -19,21 +67,32 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fwojciec/pipe"
 	bt "github.com/fwojciec/pipe/bubbletea"
 	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/fwojciec/pipe/fs"
+	pipehttp "github.com/fwojciec/pipe/http"
 	pipejson "github.com/fwojciec/pipe/json"
 )
 
 const defaultPromptPath = ".pipe/prompt.md"
 
+// defaultExportDir is where /export writes drafts when no path is given.
+const defaultExportDir = ".pipe/exports"
+
+// webhookFlushTimeout bounds how long exit waits for queued webhook payloads.
+const webhookFlushTimeout = 5 * time.Second
+
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "pipe: %v\n", err)
@@ -42,13 +101,66 @@ func main() {
 }
 
 func run() error {
+	if len(os.Args) > 1 && os.Args[1] == "sessions" {
+		return runSessions(os.Args[2:], os.Stdout)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "runs" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return runRuns(ctx, os.Args[2:], os.Stdout, time.Now(), loadRunsConfig)
+	}
+	if len(os.Args) > 1 && (os.Args[1] == "heatmap" || os.Args[1] == "usage") {
+		cfg, err := loadConfig(defaultConfigPath)
+		if err != nil {
+			return err
+		}
+		locale, err := cfg.locale(os.Getenv)
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		switch os.Args[1] {
+		case "heatmap":
+			return runHeatmap(os.Args[2:], os.Stdout, locale)
+		}
+		if len(os.Args) > 2 && os.Args[2] == "reconcile" {
+			reporter, err := newUsageReporter(os.Getenv)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			return runReconcile(ctx, os.Args[3:], os.Stdout, time.Now(), locale, reporter)
+		}
+		return runUsage(os.Args[2:], os.Stdout, time.Now(), locale)
+	}
+
+	// The registry reads provider options from cfg once it is loaded below.
+	// Provider requests are recorded for the TUI's raw request view.
+	var cfg config
+	requests := &requestLog{rec: pipehttp.NewRecorder(nil, maxRecordedRequests)}
+	providers := newProviderRegistry(&cfg, &http.Client{Transport: requests.rec})
+
 	// Parse flags.
 	var (
-		model        = flag.String("model", "", "Model ID (provider-specific)")
-		sessionPath  = flag.String("session", "", "Path to session file to resume")
-		promptPath   = flag.String("system-prompt", defaultPromptPath, "Path to system prompt file")
-		providerFlag = flag.String("provider", "", "Provider: anthropic, gemini (auto-detected from env vars if omitted)")
-		apiKey       = flag.String("api-key", "", "API key (overrides provider's env var)")
+		model         = flag.String("model", "", "Model ID (provider-specific)")
+		sessionPath   = flag.String("session", "", "Path to session file to resume")
+		resume        = flag.Bool("resume", false, "Pick a saved session to resume from a list")
+		printPrompt   = flag.String("p", "", "Run this prompt without the TUI, print the answer, and exit")
+		outputFormat  = flag.String("output-format", outputText, "Print mode output: text, json, or stream-json")
+		includeEvents = flag.String("include-events", "", "With -output-format stream-json: comma-separated event types to write (default: all)")
+		excludeEvents = flag.String("exclude-events", "", "With -output-format stream-json: comma-separated event types to leave out")
+		compactEvents = flag.Bool("compact-events", false, "With -output-format stream-json: merge text deltas per block and write each tool call once")
+		promptPath    = flag.String("system-prompt", defaultPromptPath, "Path to system prompt file")
+		providerFlag  = flag.String("provider", "", "Provider: "+strings.Join(providers.Names(), ", ")+" (auto-detected from env vars if omitted)")
+		apiKey        = flag.String("api-key", "", "API key (overrides provider's env var)")
+		configPath    = flag.String("config", defaultConfigPath, "Path to config file")
+		renderEvery   = flag.Duration("render-interval", bt.DefaultRenderInterval, "Re-render the TUI at most once per interval while streaming (0 = every event)")
+		profileName   = flag.String("profile", "", "Profile from the config file")
+		force         = flag.Bool("force", false, "Open the session even if another pipe process is using it")
+		incognito     = flag.Bool("incognito", false, "Leave no record: no session file, usage log entry, memory notes, or webhook payloads")
+		dumpTurns     = flag.String("dump-turns", "", "Debug: write the session JSON after every turn to this directory (compare with pipe sessions diff)")
+		teeTarget     = flag.String("tee", "", "Mirror events as JSON lines to this file or unix:SOCKET")
+		transcript    = flag.String("transcript", "", "Append a timestamped JSON lines log of events, requests, responses, and tool executions to this file")
 	)
 	flag.Parse()
 
@@ -56,42 +168,490 @@ func run() error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
-	// Resolve provider. Env vars are read here and passed as values.
-	provider, err := resolveProvider(*providerFlag, *apiKey,
-		os.Getenv("ANTHROPIC_API_KEY"), os.Getenv("GEMINI_API_KEY"))
+	// API keys not in the environment may be in the system keyring, put
+	// there by the first-run setup, which replaces the missing key error.
+	kr := systemKeyring()
+	getenv := withStoredKeys(os.Getenv, kr, providers.Backends())
+	if *printPrompt == "" && *providerFlag == "" && *apiKey == "" && needsSetup(*configPath, providers, getenv) {
+		done, err := setup(ctx, providers, kr, *configPath, *promptPath)
+		if err != nil {
+			return err
+		}
+		if done {
+			getenv = withStoredKeys(os.Getenv, kr, providers.Backends())
+		}
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if err := checkOutputFormat(*outputFormat); err != nil {
+		return err
+	}
+	printEvents, err := newPrintEvents(os.Stdout, *outputFormat, *includeEvents, *excludeEvents, *compactEvents)
+	if err != nil {
+		return err
+	}
+	if *printPrompt != "" && *resume {
+		return errors.New("-resume cannot be used with -p; pass -session instead")
+	}
+	if *incognito && *dumpTurns != "" {
+		return errors.New("-dump-turns cannot be used with -incognito")
+	}
+	if *incognito && *teeTarget != "" {
+		return errors.New("-tee cannot be used with -incognito")
+	}
+	if *incognito && *transcript != "" {
+		return errors.New("-transcript cannot be used with -incognito")
+	}
+	if *incognito {
+		cfg = cfg.incognito()
+	}
+	persist, err := cfg.persistMode()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	settings, err := cfg.resolve(*profileName, *model)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	toolLimits, err := cfg.toolLimits()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	segments, err := cfg.statusSegments()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.toolTimeout(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.toolResultFormat(pipe.ToolResultFormat{}); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.stylePrompt(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.providerOptions(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	keepWarm, err := cfg.keepWarm()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	inputHeight, err := cfg.inputHeight()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.roots(""); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.env(); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.subAgent(nil); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	// Roots may be added by a reload, so the active root is always shown.
+	active := &activeRoot{}
+	segments = append(segments, bt.StatusSegment{Refresh: active.label, Interval: time.Second})
+	locale, err := cfg.locale(os.Getenv)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if *resume {
+		if *sessionPath != "" {
+			return errors.New("-resume cannot be used with -session")
+		}
+		dir := defaultSessionDir()
+		entries, err := sessionEntries(dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("no saved sessions in %s", dir)
+		}
+		picked, err := bt.PickSession(ctx, entries, pipe.DefaultTheme(), locale)
+		if err != nil || picked == "" {
+			return err
+		}
+		*sessionPath = picked
+	}
+	if *model != "" {
+		// An explicit -model wins over the profile's model.
+		settings.model = *model
+	}
+
+	// Resolve provider. Env vars are read here and passed as values. Flag and
+	// key errors are reported before the TUI starts; the client itself is
+	// built in the background while the first frame is drawn.
+	providerName := *providerFlag
+	if providerName == "" && cfg.FastestProvider {
+		if names := detectedProviders(providers, getenv); len(names) > 1 {
+			probeCtx, cancel := context.WithTimeout(ctx, providerProbeTimeout)
+			providerName, err = fastestProvider(probeCtx, providers, names, getenv)
+			cancel()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	providerCfg, err := resolveConfig(providers, providerName, *apiKey, getenv)
 	if err != nil {
 		return err
 	}
+	provider := startProvider(func() (pipe.Provider, error) {
+		return newProvider(providers, providerCfg)
+	})
+
+	// Lock a resumed session so a second pipe process cannot clobber its
+	// saves. New sessions get a unique path and need no lock.
+	if *sessionPath != "" && !*force {
+		lock, err := lockSession(*sessionPath)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+	}
 
 	// Load or create session.
 	session, err := loadOrCreateSession(*sessionPath, *promptPath)
 	if err != nil {
 		return err
 	}
+	startupPrompt := session.SystemPrompt // restored by /profile default
+	if settings.systemPrompt != "" {
+		session.SystemPrompt = settings.systemPrompt
+	}
+	var journal *sessionJournal
+	if cfg.Journal {
+		path := *sessionPath
+		if path == "" {
+			path = defaultSessionPath(session.ID)
+		}
+		journal = newSessionJournal(path, session, persist)
+	}
 
-	// Create tool executor and get tool definitions.
-	exec := &executor{bash: pipeexec.NewBashExecutor()}
-	toolDefs := tools()
+	// Fingerprint the environment for new sessions in the background; it is
+	// added to the system prompt before each run, surviving /profile
+	// prompt switches, so the model need not rediscover the platform and
+	// tools every session.
+	envInfo := func() string { return "" }
+	if *sessionPath == "" {
+		detected := make(chan string, 1)
+		go func() {
+			detected <- pipeexec.DetectEnvironment(ctx, ".", pipeexec.DefaultBinaries()).String()
+		}()
+		envInfo = sync.OnceValue(func() string { return <-detected })
+	}
 
-	// Create agent loop.
-	loop := pipe.NewLoop(provider, exec)
+	backend, _ := providers.Lookup(providerCfg.name)
+	profiles := &profileSwitcher{cfg: cfg, model: *model, defaultModel: backend.DefaultModel, prompt: startupPrompt, session: &session, current: settings}
+	reload := newReloader(*promptPath, *configPath, cfg, profiles, &session)
+	reload.incognito = *incognito
+	notices := make(chan string)
+	go reload.watch(ctx, notices)
+	retry := &retrier{session: &session}
+	exports := &exporter{session: &session, dir: defaultExportDir}
+	styles := &styleSwitcher{reload: reload}
+	sched := &scheduler{session: &session, dir: workDir(), now: time.Now}
+	mem := newMemory(cfg, os.Getenv)
 
-	// Build agent function closure for the TUI.
-	modelID := *model
-	agentFn := func(ctx context.Context, s *pipe.Session, onEvent func(pipe.Event)) error {
-		opts := []pipe.RunOption{pipe.WithEventHandler(onEvent)}
-		if modelID != "" {
-			opts = append(opts, pipe.WithModel(modelID))
+	// Create long-lived tool state shared across runs.
+	asker := bt.NewAsker()
+	approver := bt.NewApprover()
+	if _, err := cfg.approver(approver.Approve); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	snaps := &snapshots{root: defaultSnapshotDir}
+	if *incognito {
+		// Snapshots hold file contents from before each edit; keep them
+		// only for the life of the process.
+		dir, err := os.MkdirTemp("", "pipe-snapshots-")
+		if err != nil {
+			return err
 		}
-		return loop.Run(ctx, s, toolDefs, opts...)
+		defer os.RemoveAll(dir)
+		snaps.root = dir
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	shell, paths, err := cfg.shell(wd, func(container string) ([]pipeexec.DockerMount, error) {
+		return pipeexec.InspectDockerMounts(ctx, container)
+	})
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	// The shell is set up once, so the note on where it runs is too.
+	execNote := cfg.Exec.note()
+	bash := pipeexec.NewBashExecutor()
+	bash.SetShell(shell)
+	bash.SetPathMap(paths)
+	env := &sessionEnv{base: func() map[string]string { return reload.config().Env }}
+	var artifactDir string
+	if !cfg.TempArtifacts {
+		artifactDir = defaultArtifactDir(session.ID)
+		bash.SetArtifactDir(artifactDir)
+	}
+	limiter := pipeexec.NewLimiter(toolLimits)
+	var notifier *pipehttp.Notifier
+	if cfg.WebhookURL != "" {
+		notifier = pipehttp.NewNotifier(cfg.WebhookURL, nil)
+		defer func() {
+			// Give queued progress payloads a moment to go out on exit.
+			flushCtx, cancel := context.WithTimeout(context.Background(), webhookFlushTimeout)
+			defer cancel()
+			_ = notifier.Close(flushCtx)
+		}()
+	}
+
+	var dumper *turnDumper
+	if *dumpTurns != "" {
+		dumper = &turnDumper{dir: *dumpTurns, keep: defaultTurnDumps, now: time.Now}
+	}
+
+	var tee *pipejson.EventWriter
+	if *teeTarget != "" {
+		w, err := openTee(*teeTarget)
+		if err != nil {
+			return fmt.Errorf("tee: %w", err)
+		}
+		defer w.Close()
+		tee, _ = pipejson.NewEventWriter(w, pipejson.EventOptions{}) // no type filter to reject
+	}
+
+	var transcriptLog *pipejson.TranscriptWriter
+	if *transcript != "" {
+		f, err := os.OpenFile(*transcript, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("transcript: %w", err)
+		}
+		defer f.Close()
+		transcriptLog = pipejson.NewTranscriptWriter(f)
+	}
+
+	// Keep the provider warm while the TUI waits for prompts; one-shot -p
+	// runs start with a request anyway.
+	var warmer *keepWarmer
+	if keepWarm > 0 && *printPrompt == "" {
+		warmer = newKeepWarmer(provider, func() string { return profiles.settings().model }, keepWarm)
+		warmCtx, stopWarm := context.WithCancel(ctx)
+		defer stopWarm()
+		go warmer.run(warmCtx)
+	}
+
+	// appended collects the messages of each run for its usage record and
+	// the -p output.
+	appended := &runMessages{}
+
+	// Build agent function closure for the TUI. Settings are read per run so
+	// /profile and edits to the prompt and config files take effect on the
+	// next prompt.
+	agentFn := func(ctx context.Context, s *pipe.Session, onEvent func(pipe.Event)) (err error) {
+		reload.applyChanges()
+		cfg := reload.config()
+		snaps.begin(fmt.Sprintf("%d", time.Now().UnixNano()))
+		if warmer != nil {
+			warmer.begin()
+			defer warmer.end()
+		}
+		var runProvider pipe.Provider = provider
+		if dumper != nil {
+			runProvider = &dumpingProvider{Provider: runProvider, dumper: dumper, session: s}
+			defer func() {
+				if dumpErr := dumper.dump(*s); dumpErr != nil && err == nil {
+					err = dumpErr
+				}
+			}()
+		}
+		if notifier != nil {
+			run := notifier.StartRun(s.ID)
+			runProvider = run.Provider(runProvider)
+			onEvent = run.OnEvent(onEvent)
+			defer func() { run.End(err) }()
+		}
+		runProvider = requests.provider(runProvider)
+		onEvent = requests.onEvent(onEvent)
+		if journal != nil {
+			var end func() error
+			onEvent, end = journal.run(s, onEvent)
+			defer func() {
+				if endErr := end(); endErr != nil && err == nil {
+					err = endErr
+				}
+			}()
+		}
+		if env := envInfo(); env != "" && !strings.Contains(s.SystemPrompt, env) {
+			s.SystemPrompt += "\n\n" + env
+		}
+		if execNote != "" && !strings.Contains(s.SystemPrompt, execNote) {
+			s.SystemPrompt += "\n\n" + execNote
+		}
+		style, _ := cfg.stylePrompt() // validated when loaded
+		s.SystemPrompt = withStyle(s.SystemPrompt, style)
+		st := profiles.settings()
+		model, temperature := retry.take()
+		if model == "" {
+			model = st.model
+		}
+		// Records and the cost guard price the model the provider will use.
+		priced := profiles.effectiveModel(model)
+		appended.begin()
+		if cfg.UsageMetrics {
+			start := time.Now()
+			defer func() {
+				record := pipe.NewRunRecord(start, s.ID, priced, appended.messages())
+				if recErr := pipejson.AppendRunRecord(defaultUsageLogPath(), record); recErr != nil && err == nil {
+					err = fmt.Errorf("record usage: %w", recErr)
+				}
+			}()
+		}
+		toolEnv := env.environ()
+		bash.SetEnv(toolEnv)
+		roots, _ := cfg.roots("") // validated when loaded
+		if roots != nil {
+			roots.active = active
+		}
+		exec := &executor{bash: bash, ask: asker, sched: sched, snap: snaps, mem: mem, roots: roots, env: toolEnv, allowed: st.allowedTools(), artifactDir: artifactDir}
+		loopExec := limiter.Wrap(pipeexec.BoundResults(exec, cfg.maxToolResultBytes(), artifactDir))
+		loop := pipe.NewLoop(runProvider, loopExec)
+
+		opts := []pipe.RunOption{pipe.WithEventHandler(onEvent), pipe.WithMessageHandler(appended.add)}
+		if model != "" {
+			opts = append(opts, pipe.WithModel(model))
+		}
+		if temperature != nil {
+			opts = append(opts, pipe.WithTemperature(*temperature))
+		}
+		if len(st.serverTools) > 0 {
+			opts = append(opts, pipe.WithServerTools(st.serverTools...))
+		}
+		if st.maxTokens > 0 {
+			opts = append(opts, pipe.WithMaxTokens(st.maxTokens))
+		}
+		if cfg.ContextBudget > 0 {
+			opts = append(opts, pipe.WithContextBudget(cfg.ContextBudget))
+		}
+		if cfg.ToolResultTurns > 0 {
+			opts = append(opts, pipe.WithToolResultTurns(cfg.ToolResultTurns))
+		}
+		if cfg.RunStatus {
+			opts = append(opts, pipe.WithRunStatus())
+		}
+		if cfg.MaxTurns > 0 {
+			opts = append(opts, pipe.WithMaxTurns(cfg.MaxTurns))
+		}
+		if cfg.MaxCostTokens > 0 {
+			opts = append(opts, pipe.WithMaxCostTokens(cfg.MaxCostTokens))
+		}
+		if n := cfg.loopDetection(); n > 0 {
+			opts = append(opts, pipe.WithLoopDetection(n))
+		}
+		opts = append(opts, pipe.WithRetry(cfg.retryPolicy(providerCfg.name)))
+		if cfg.ToolConcurrency > 1 {
+			opts = append(opts, pipe.WithToolConcurrency(cfg.ToolConcurrency))
+		}
+		if d, _ := cfg.toolTimeout(); d > 0 {
+			opts = append(opts, pipe.WithToolTimeout(d))
+		}
+		providerOpts, _ := cfg.providerOptions() // validated when loaded
+		opts = append(opts, pipe.WithProviderOptions(providerOpts))
+		ask, confirm, tools := approver.Approve, approver.ConfirmCost, st.tools
+		if *printPrompt != "" {
+			ask, confirm = denyApproval, denyCost
+			tools = slices.DeleteFunc(slices.Clone(tools), func(t pipe.Tool) bool { return t.Name == "ask_user" })
+		}
+		approve, _ := cfg.approver(ask)
+		if approve != nil {
+			opts = append(opts, pipe.WithApprover(approve))
+		}
+		guard := cfg.costGuard(priced, confirm)
+		if agent, _ := cfg.subAgent(tools); agent != nil { // validated when loaded
+			// The sub-agent's calls go through the same executor, approval,
+			// and limits as the run's; its events are not shown, and its
+			// usage counts toward the run's in its tool results.
+			agent.Provider, agent.Executor = runProvider, loopExec
+			agent.Options = []pipe.RunOption{pipe.WithRetry(cfg.retryPolicy(providerCfg.name)), pipe.WithProviderOptions(providerOpts)}
+			if model != "" {
+				agent.Options = append(agent.Options, pipe.WithModel(model))
+			}
+			if d, _ := cfg.toolTimeout(); d > 0 {
+				agent.Options = append(agent.Options, pipe.WithToolTimeout(d))
+			}
+			if n := cfg.loopDetection(); n > 0 {
+				agent.Options = append(agent.Options, pipe.WithLoopDetection(n))
+			}
+			if approve != nil {
+				agent.Options = append(agent.Options, pipe.WithApprover(approve))
+			}
+			if cfg.MaxCostTokens > 0 {
+				agent.Options = append(agent.Options, pipe.WithMaxCostTokens(cfg.MaxCostTokens))
+			}
+			if guard != nil {
+				agent.Options = append(agent.Options, pipe.WithRequestApprover(guard))
+			}
+			exec.task = &taskRunner{agent: *agent}
+		}
+		if guard != nil {
+			opts = append(opts, pipe.WithRequestApprover(guard))
+		}
+		if tee != nil {
+			opts = append(opts, pipe.WithEventTee(tee))
+		}
+		if transcriptLog != nil {
+			opts = append(opts, pipe.WithTranscript(transcriptLog))
+		}
+		return loop.Run(ctx, s, tools, opts...)
+	}
+
+	if *printPrompt != "" {
+		runErr := runPrint(ctx, agentFn, appended, &session, *printPrompt, *outputFormat, printEvents, os.Stdout)
+		if err := saveOnExit(&session, *sessionPath, artifactDir, persist); err != nil && runErr == nil {
+			runErr = err
+		}
+		return runErr
 	}
 
 	// Create and run TUI.
+	models := newModelCatalog(ctx, provider, profiles, notices)
 	theme := pipe.DefaultTheme()
 	config := bt.Config{
 		WorkDir:   workDir(),
-		GitBranch: gitBranch(),
-		ModelName: modelID,
+		Paths:     pathDisplay(),
+		ModelName: profiles.effectiveModel(settings.model),
+		Pricing:   profiles.pricing(settings.model),
+
+		DetectGit: gitStatus,
+
+		RenderInterval: *renderEvery,
+		Notices:        notices,
+		StatusSegments: segments,
+		Asker:          asker,
+		Approver:       approver,
+		RunSummary:     snaps.summary,
+		Images:         bt.DetectImageProtocol(os.Getenv),
+		Locale:         locale,
+		PasteImage:     pasteImage,
+		RawRequest:     requests.request,
+		InputHeight:    inputHeight,
+		PasteGap:       bt.DefaultPasteGap,
+		Mouse:          cfg.Mouse,
+		SummaryActions: summaryActions(os.Stdout, &sessionSaver{path: *sessionPath, session: &session, artifactDir: artifactDir, persist: persist}, snaps),
+		Commands: []bt.Command{
+			{Name: "rollback", Description: "Restore files changed by the last run", Run: snaps.rollback},
+			{Name: "profile", Description: "List profiles or switch to one", Run: profiles.command},
+			{Name: "model", Description: "List the provider's models or switch to one: /model NAME", Run: models.command},
+			{Name: "reload", Description: "Apply changes to the system prompt and config files now", Run: reload.command},
+			{Name: "retry", Description: "Re-request the last turn, e.g. /retry --model NAME", Run: retry.command},
+			{Name: "image", Description: "Attach an image file to the next prompt: /image PATH", Run: imageCommand},
+			{Name: "style", Description: "List response styles or choose one for this project", Run: styles.command},
+			{Name: "export", Description: "Draft an issue from the session: /export issue [PATH]", Run: exports.command},
+			{Name: "env", Description: "List, set, or unset tool environment variables: /env set KEY=value", Run: env.command},
+			{Name: "set", Description: "Change a TUI setting for this session: /set input_height 8", Run: setCommand},
+		},
 	}
 	tuiModel := bt.New(agentFn, &session, theme, config)
 
@@ -99,27 +659,38 @@ func run() error {
 		return fmt.Errorf("TUI: %w", err)
 	}
 
-	// Save session on exit.
-	if *sessionPath != "" {
-		if err := pipejson.Save(*sessionPath, session); err != nil {
+	return saveOnExit(&session, *sessionPath, artifactDir, persist)
+}
+
+// saveOnExit records the session's artifacts and saves it, as the persist
+// setting allows: to sessionPath when resuming, otherwise to the default
+// location if anything was said.
+func saveOnExit(session *pipe.Session, sessionPath, artifactDir, persist string) error {
+	if err := recordArtifacts(session, artifactDir); err != nil {
+		return fmt.Errorf("record artifacts: %w", err)
+	}
+	if sessionPath != "" {
+		if _, err := saveSession(sessionPath, *session, persist); err != nil {
 			return fmt.Errorf("save session: %w", err)
 		}
 	} else if len(session.Messages) > 0 {
 		// Auto-save to default location.
 		savePath := defaultSessionPath(session.ID)
-		if err := pipejson.Save(savePath, session); err != nil {
+		saved, err := saveSession(savePath, *session, persist)
+		if err != nil {
 			return fmt.Errorf("auto-save session: %w", err)
 		}
-		fmt.Fprintf(os.Stderr, "Session saved to %s\n", savePath)
+		if saved {
+			fmt.Fprintf(os.Stderr, "Session saved to %s\n", savePath)
+		}
 	}
-
 	return nil
 }
 
 func loadOrCreateSession(sessionPath, promptPath string) (pipe.Session, error) {
 	// Load existing session if path provided.
 	if sessionPath != "" {
-		s, err := pipejson.Load(sessionPath)
+		s, err := pipejson.Load(sessionPath, pipejson.WithPreserveUnknown())
 		if err != nil {
 			return pipe.Session{}, fmt.Errorf("load session: %w", err)
 		}
@@ -138,22 +709,37 @@ func loadOrCreateSession(sessionPath, promptPath string) (pipe.Session, error) {
 		return pipe.Session{}, fmt.Errorf("read system prompt: %w", err)
 	}
 
-	// Create new session.
-	now := time.Now()
-	return pipe.Session{
-		ID:           fmt.Sprintf("%d", now.UnixNano()),
-		SystemPrompt: systemPrompt,
-		CreatedAt:    now,
-		UpdatedAt:    now,
-	}, nil
+	return pipe.NewSession(pipe.SystemClock(), systemPrompt), nil
+}
+
+// lockSession locks the session file at path, explaining how to override a
+// lock held by another process.
+func lockSession(path string) (*fs.FileLock, error) {
+	lock, err := fs.Lock(path)
+	var locked *fs.LockedError
+	if errors.As(err, &locked) && locked.PID != 0 {
+		return nil, fmt.Errorf("session in use by pid %d (use -force to open it anyway)", locked.PID)
+	}
+	if errors.As(err, &locked) {
+		return nil, fmt.Errorf("session in use by another process (use -force to open it anyway)")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock session: %w", err)
+	}
+	return lock, nil
 }
 
 func defaultSessionPath(id string) string {
+	return filepath.Join(defaultSessionDir(), id+".json")
+}
+
+// defaultSessionDir is where sessions are auto-saved.
+func defaultSessionDir() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		home = "."
 	}
-	return filepath.Join(home, ".pipe", "sessions", id+".json")
+	return filepath.Join(home, ".pipe", "sessions")
 }
 
 func workDir() string {
@@ -174,12 +760,23 @@ func workDir() string {
 	return dir
 }
 
-func gitBranch() string {
+// pathDisplay shortens the paths in tool blocks against the working
+// directory and the home directory.
+func pathDisplay() bt.PathDisplay {
+	var paths bt.PathDisplay
+	paths.Root, _ = os.Getwd()
+	paths.Home, _ = os.UserHomeDir()
+	return paths
+}
+
+// gitStatus returns the branch and whether the work tree has uncommitted
+// changes, running the two git commands concurrently.
+func gitStatus() bt.GitStatus {
 	// Walk up from cwd looking for a .git entry to avoid spawning git
 	// outside repositories (saves ~50-100ms startup latency).
 	dir, err := os.Getwd()
 	if err != nil {
-		return ""
+		return bt.GitStatus{}
 	}
 	found := false
 	for {
@@ -194,13 +791,18 @@ func gitBranch() string {
 		dir = parent
 	}
 	if !found {
-		return ""
+		return bt.GitStatus{}
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
+	dirty := make(chan bool, 1)
+	go func() {
+		out, err := exec.CommandContext(ctx, "git", "status", "--porcelain", "--untracked-files=no").Output()
+		dirty <- err == nil && strings.TrimSpace(string(out)) != ""
+	}()
 	out, err := exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD").Output()
 	if err != nil {
-		return ""
+		return bt.GitStatus{}
 	}
-	return strings.TrimSpace(string(out))
+	return bt.GitStatus{Branch: strings.TrimSpace(string(out)), Dirty: <-dirty}
 }
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/gemini"
+	pipejson "github.com/fwojciec/pipe/json"
+)
+
+const (
+	defaultMemoryPath = ".pipe/memory.jsonl"
+	// defaultRecallLimit is how many notes recall returns when the model
+	// does not ask for a number.
+	defaultRecallLimit = 5
+)
+
+// rememberTool returns the definition of the remember tool.
+func rememberTool() pipe.Tool {
+	return pipe.Tool{
+		Name: "remember",
+		Description: "Save a note to long-term project memory so later sessions can recall it, " +
+			"e.g. a decision and its reason, a convention, or where something lives. " +
+			"Keep each note short and self-contained.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"text": {
+					"type": "string",
+					"description": "The note to remember"
+				}
+			},
+			"required": ["text"]
+		}`),
+	}
+}
+
+// recallTool returns the definition of the recall tool.
+func recallTool() pipe.Tool {
+	return pipe.Tool{
+		Name:        "recall",
+		Description: "Search long-term project memory for notes saved in earlier sessions, most relevant first.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"query": {
+					"type": "string",
+					"description": "What to look for"
+				},
+				"limit": {
+					"type": "integer",
+					"description": "Maximum number of notes to return (default: 5)"
+				}
+			},
+			"required": ["query"]
+		}`),
+	}
+}
+
+type rememberArgs struct {
+	Text string `json:"text"`
+}
+
+type recallArgs struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+// memory implements the remember and recall tools over a note store.
+type memory struct {
+	store    pipe.NoteStore
+	embedder pipe.Embedder
+	model    string // Note.Model of the notes embedder produces
+	now      func() time.Time
+}
+
+// remember embeds and stores a note.
+func (m *memory) remember(ctx context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
+	var a rememberArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return toolError(fmt.Sprintf("invalid arguments: %s", err)), nil
+	}
+	if strings.TrimSpace(a.Text) == "" {
+		return toolError("text is required"), nil
+	}
+	vectors, err := m.embedder.Embed(ctx, []string{a.Text})
+	if err != nil {
+		return toolError(fmt.Sprintf("embed note: %s", err)), nil
+	}
+	now := m.now()
+	n := pipe.Note{
+		ID:        strconv.FormatInt(now.UnixNano(), 36),
+		Text:      a.Text,
+		Model:     m.model,
+		Embedding: vectors[0],
+		CreatedAt: now,
+	}
+	if err := m.store.Add(ctx, n); err != nil {
+		return nil, fmt.Errorf("remember: %w", err)
+	}
+	return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Remembered note " + n.ID + "."}}}, nil
+}
+
+// recall returns the stored notes most similar to the query. Notes embedded
+// by a different model are re-embedded for the search.
+func (m *memory) recall(ctx context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
+	var a recallArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return toolError(fmt.Sprintf("invalid arguments: %s", err)), nil
+	}
+	if strings.TrimSpace(a.Query) == "" {
+		return toolError("query is required"), nil
+	}
+	limit := a.Limit
+	if limit <= 0 {
+		limit = defaultRecallLimit
+	}
+	notes, err := m.store.Notes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("recall: %w", err)
+	}
+	if len(notes) == 0 {
+		return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Memory is empty."}}}, nil
+	}
+
+	texts := []string{a.Query}
+	var stale []int
+	for i, n := range notes {
+		if n.Model != m.model {
+			stale = append(stale, i)
+			texts = append(texts, n.Text)
+		}
+	}
+	vectors, err := m.embedder.Embed(ctx, texts)
+	if err != nil {
+		return toolError(fmt.Sprintf("embed query: %s", err)), nil
+	}
+	for j, i := range stale {
+		notes[i].Embedding = vectors[j+1]
+	}
+
+	var b strings.Builder
+	for _, n := range pipe.RankNotes(notes, vectors[0], limit) {
+		fmt.Fprintf(&b, "- [%s, %s] %s\n", n.ID, n.CreatedAt.Format(time.DateOnly), n.Text)
+	}
+	return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: b.String()}}}, nil
+}
+
+// memoryDisabled answers calls to the memory tools when memory is off.
+type memoryDisabled struct{}
+
+func (memoryDisabled) Execute(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+	return toolError("memory is not enabled: set \"memory\": true in .pipe/config.json"), nil
+}
+
+// newMemory returns the memory tools' state, or nil when memory is not
+// enabled. Notes are embedded with Gemini when GEMINI_API_KEY is set and
+// with the local pipe.HashEmbedder otherwise.
+func newMemory(cfg config, getenv func(string) string) *memory {
+	if !cfg.Memory {
+		return nil
+	}
+	m := &memory{
+		store:    pipejson.NewNoteStore(defaultMemoryPath),
+		embedder: pipe.HashEmbedder{},
+		model:    pipe.HashEmbedderModel,
+		now:      time.Now,
+	}
+	if key := getenv("GEMINI_API_KEY"); key != "" {
+		m.embedder = &geminiEmbedder{client: sync.OnceValues(func() (*gemini.Client, error) {
+			return gemini.New(context.Background(), key)
+		})}
+		m.model = gemini.EmbeddingModel
+	}
+	return m
+}
+
+// in returns m with notes stored in the project memory of dir rather than
+// the working directory's. A nil m stays nil.
+func (m *memory) in(dir string) *memory {
+	if m == nil {
+		return nil
+	}
+	c := *m
+	c.store = pipejson.NewNoteStore(filepath.Join(dir, defaultMemoryPath))
+	return &c
+}
+
+// geminiEmbedder creates its Gemini client on first use.
+type geminiEmbedder struct {
+	client func() (*gemini.Client, error)
+}
+
+func (e *geminiEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	c, err := e.client()
+	if err != nil {
+		return nil, err
+	}
+	return c.Embed(ctx, texts)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	newTestMemory := func(t *testing.T) *memory {
+		t.Helper()
+		return &memory{
+			store:    pipejson.NewNoteStore(filepath.Join(t.TempDir(), "memory.jsonl")),
+			embedder: pipe.HashEmbedder{},
+			model:    pipe.HashEmbedderModel,
+			now:      func() time.Time { return now },
+		}
+	}
+	text := func(t *testing.T, tool func(context.Context, json.RawMessage) (*pipe.ToolResult, error), args string) string {
+		t.Helper()
+		result, err := tool(context.Background(), json.RawMessage(args))
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		return result.Content[0].(pipe.TextBlock).Text
+	}
+
+	t.Run("recalls the most relevant notes", func(t *testing.T) {
+		t.Parallel()
+		m := newTestMemory(t)
+		for _, note := range []string{
+			"Releases are cut with make release from the main branch.",
+			"The team prefers table-driven tests.",
+		} {
+			args, _ := json.Marshal(rememberArgs{Text: note})
+			assert.Contains(t, text(t, m.remember, string(args)), "Remembered note")
+		}
+
+		got := text(t, m.recall, `{"query": "how do I make a release?", "limit": 1}`)
+		assert.Contains(t, got, "2026-03-01] Releases are cut")
+		assert.NotContains(t, got, "table-driven")
+	})
+
+	t.Run("re-embeds notes from another model", func(t *testing.T) {
+		t.Parallel()
+		m := newTestMemory(t)
+		require.NoError(t, m.store.Add(context.Background(), pipe.Note{ID: "old", Text: "deploys need VPN access", Model: "other", Embedding: []float32{1}}))
+		got := text(t, m.recall, `{"query": "VPN"}`)
+		assert.Contains(t, got, "[old, ")
+	})
+
+	t.Run("empty memory", func(t *testing.T) {
+		t.Parallel()
+		got := text(t, newTestMemory(t).recall, `{"query": "anything"}`)
+		assert.Equal(t, "Memory is empty.", got)
+	})
+
+	t.Run("rejects empty arguments", func(t *testing.T) {
+		t.Parallel()
+		m := newTestMemory(t)
+		result, err := m.remember(context.Background(), json.RawMessage(`{"text": " "}`))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		result, err = m.recall(context.Background(), json.RawMessage(`{}`))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+
+	t.Run("tools are only enabled by config", func(t *testing.T) {
+		t.Parallel()
+		assert.Nil(t, newMemory(config{}, func(string) string { return "" }))
+		names := func(tools []pipe.Tool) []string {
+			var names []string
+			for _, tool := range tools {
+				names = append(names, tool.Name)
+			}
+			return names
+		}
+		assert.NotContains(t, names(config{}.enabledTools()), "recall")
+		assert.Contains(t, names(config{Memory: true}.enabledTools()), "recall")
+
+		result, err := (&executor{}).Execute(context.Background(), "recall", json.RawMessage(`{"query": "x"}`))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
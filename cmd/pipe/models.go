@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+// modelListTimeout bounds one fetch of the provider's model list.
+const modelListTimeout = 10 * time.Second
+
+// modelCatalog caches the provider's model list, fetched in the background
+// at startup and again on each /model, so the command never waits on the
+// network. It implements /model.
+type modelCatalog struct {
+	ctx      context.Context
+	lister   pipe.ModelLister
+	profiles *profileSwitcher
+	// notices receives the list when a fetch that /model waited on
+	// finishes.
+	notices chan<- string
+
+	mu       sync.Mutex
+	models   []string
+	err      error
+	loaded   bool // a fetch has finished, successfully or not
+	fetching bool
+	announce bool // send the list to notices when the fetch finishes
+}
+
+// newModelCatalog returns a catalog and starts fetching its list; fetches
+// stop when ctx is done.
+func newModelCatalog(ctx context.Context, lister pipe.ModelLister, profiles *profileSwitcher, notices chan<- string) *modelCatalog {
+	c := &modelCatalog{ctx: ctx, lister: lister, profiles: profiles, notices: notices}
+	c.mu.Lock()
+	c.refresh()
+	c.mu.Unlock()
+	return c
+}
+
+// refresh starts a fetch unless one is running. c.mu must be held.
+func (c *modelCatalog) refresh() {
+	if c.fetching {
+		return
+	}
+	c.fetching = true
+	go func() {
+		ctx, cancel := context.WithTimeout(c.ctx, modelListTimeout)
+		models, err := c.lister.ListModels(ctx)
+		cancel()
+
+		c.mu.Lock()
+		if err == nil {
+			c.models = models
+		}
+		c.err, c.loaded, c.fetching = err, true, false
+		announce := c.announce
+		c.announce = false
+		notice := c.listLocked()
+		c.mu.Unlock()
+
+		if announce {
+			select {
+			case c.notices <- notice:
+			case <-c.ctx.Done():
+			}
+		}
+	}()
+}
+
+// listLocked describes the cached list, marking the active model. c.mu
+// must be held.
+func (c *modelCatalog) listLocked() string {
+	if c.err != nil && c.models == nil {
+		return fmt.Sprintf("Could not list models: %s", c.err)
+	}
+	current := c.profiles.settings().model
+	var b strings.Builder
+	b.WriteString("Models:")
+	for _, name := range c.models {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+		fmt.Fprintf(&b, "\n%s %s", marker, name)
+	}
+	if len(c.models) == 0 {
+		b.WriteString("\n  (none)")
+	}
+	if c.err != nil {
+		fmt.Fprintf(&b, "\n\nThe latest refresh failed: %s", c.err)
+	}
+	return b.String()
+}
+
+// command implements /model: without arguments it lists the models from
+// the last fetch, otherwise it switches to the named model. Either way it
+// refreshes the list and the git status in the background.
+func (c *modelCatalog) command(args string) (bt.CommandResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if args == "" {
+		if !c.loaded {
+			c.announce = true
+			c.refresh()
+			return bt.CommandResult{Notice: "Loading the model list; it will be shown when ready.", RefreshGit: true}, nil
+		}
+		notice := c.listLocked()
+		c.refresh()
+		return bt.CommandResult{Notice: notice, RefreshGit: true}, nil
+	}
+
+	price := c.profiles.setModel(args)
+	notice := fmt.Sprintf("Switched to model %s.", args)
+	if c.loaded && c.models != nil && !slices.Contains(c.models, args) {
+		notice = fmt.Sprintf("Switched to model %s, which is not in the provider's model list.", args)
+	}
+	c.refresh()
+	return bt.CommandResult{Notice: notice, ModelName: args, Pricing: price, RefreshGit: true}, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelCatalog_Command(t *testing.T) {
+	t.Parallel()
+
+	// newCatalog returns a catalog whose fetches wait for a value on
+	// release, and the channel its announcements arrive on.
+	newCatalog := func(t *testing.T, release <-chan struct{}, err error) (*modelCatalog, *profileSwitcher, chan string) {
+		t.Helper()
+		lister := &mock.ModelLister{ListModelsFn: func(context.Context) ([]string, error) {
+			<-release
+			if err != nil {
+				return nil, err
+			}
+			return []string{"big-model", "small-model"}, nil
+		}}
+		profiles := &profileSwitcher{session: &pipe.Session{}, current: runSettings{model: "small-model"}}
+		notices := make(chan string)
+		return newModelCatalog(context.Background(), lister, profiles, notices), profiles, notices
+	}
+
+	t.Run("announces the list when it arrives", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		catalog, _, notices := newCatalog(t, release, nil)
+
+		out, err := catalog.command("")
+		require.NoError(t, err)
+		assert.Contains(t, out.Notice, "Loading the model list")
+		assert.True(t, out.RefreshGit)
+
+		close(release)
+		assert.Equal(t, "Models:\n  big-model\n* small-model", <-notices)
+
+		out, err = catalog.command("")
+		require.NoError(t, err)
+		assert.Equal(t, "Models:\n  big-model\n* small-model", out.Notice)
+	})
+
+	t.Run("switches model without waiting for the list", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		defer close(release)
+		catalog, profiles, _ := newCatalog(t, release, nil)
+
+		out, err := catalog.command("big-model")
+		require.NoError(t, err)
+		assert.Equal(t, "big-model", out.ModelName)
+		assert.Equal(t, "Switched to model big-model.", out.Notice)
+		assert.Equal(t, "big-model", profiles.settings().model)
+	})
+
+	t.Run("prices the new model", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		defer close(release)
+		catalog, _, _ := newCatalog(t, release, nil)
+
+		out, err := catalog.command("claude-opus-4-1")
+		require.NoError(t, err)
+		require.NotNil(t, out.Pricing)
+		assert.Equal(t, 15.0, out.Pricing.Input)
+
+		out, err = catalog.command("big-model")
+		require.NoError(t, err)
+		assert.Nil(t, out.Pricing, "unknown models have no price")
+	})
+
+	t.Run("reports a failed fetch", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		catalog, _, notices := newCatalog(t, release, assert.AnError)
+
+		_, err := catalog.command("")
+		require.NoError(t, err)
+		close(release)
+		assert.Contains(t, <-notices, "Could not list models: "+assert.AnError.Error())
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+)
+
+// Values of the persist setting.
+const (
+	persistFull       = "full"        // the whole transcript (default)
+	persistNoThinking = "no_thinking" // the transcript without thinking blocks
+	persistNone       = "none"        // nothing; the session is ephemeral
+)
+
+// persistMode returns the validated persist setting; empty means full.
+func (c config) persistMode() (string, error) {
+	switch c.Persist {
+	case "":
+		return persistFull, nil
+	case persistFull, persistNoThinking, persistNone:
+		return c.Persist, nil
+	}
+	return "", fmt.Errorf("invalid persist %q: must be %q, %q, or %q", c.Persist, persistFull, persistNoThinking, persistNone)
+}
+
+// incognito returns c adjusted so a session leaves no record: no session
+// file, usage log entry, memory notes, or webhook payloads, and tool
+// outputs are offloaded to the OS temp dir.
+func (c config) incognito() config {
+	c.Persist = persistNone
+	c.UsageMetrics = false
+	c.Memory = false
+	c.WebhookURL = ""
+	c.TempArtifacts = true
+	return c
+}
+
+// saveSession writes s to path as mode allows. It reports whether a file
+// was written.
+func saveSession(path string, s pipe.Session, mode string) (bool, error) {
+	switch mode {
+	case persistNone:
+		return false, nil
+	case persistNoThinking:
+		s = s.WithoutThinking()
+	}
+	if err := pipejson.Save(path, s); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sessionJournal journals the turns of a session, as the persist setting
+// allows, to the journal beside the file it will be saved to.
+type sessionJournal struct {
+	journal *pipejson.Journal
+	mode    string
+}
+
+// newSessionJournal returns the journal for s, which is saved to path, or
+// nil when mode saves nothing.
+func newSessionJournal(path string, s pipe.Session, mode string) *sessionJournal {
+	if mode == persistNone {
+		return nil
+	}
+	j := &sessionJournal{mode: mode}
+	j.journal = pipejson.NewJournal(path, j.persisted(s))
+	return j
+}
+
+func (j *sessionJournal) persisted(s pipe.Session) pipe.Session {
+	if j.mode == persistNoThinking {
+		return s.WithoutThinking()
+	}
+	return s
+}
+
+// run journals s each time the run whose events go to next completes a
+// turn, before passing the event on. The returned end journals what the
+// run added after its last turn, such as tool results, and returns the
+// first error.
+func (j *sessionJournal) run(s *pipe.Session, next func(pipe.Event)) (onEvent func(pipe.Event), end func() error) {
+	var err error
+	onEvent = func(e pipe.Event) {
+		if _, ok := e.(pipe.EventTurnComplete); ok && err == nil {
+			err = j.journal.Append(j.persisted(*s))
+		}
+		next(e)
+	}
+	end = func() error {
+		if err == nil {
+			err = j.journal.Append(j.persisted(*s))
+		}
+		if err != nil {
+			return fmt.Errorf("journal session: %w", err)
+		}
+		return nil
+	}
+	return onEvent, end
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveSession(t *testing.T) {
+	t.Parallel()
+	session := pipe.Session{ID: "s1", Messages: []pipe.Message{
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}},
+		pipe.AssistantMessage{Content: []pipe.ContentBlock{
+			pipe.ThinkingBlock{Thinking: "private reasoning"},
+			pipe.TextBlock{Text: "hello"},
+		}},
+	}}
+
+	t.Run("full", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "s.json")
+		saved, err := saveSession(path, session, persistFull)
+		require.NoError(t, err)
+		assert.True(t, saved)
+		got, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Len(t, got.Messages[1].(pipe.AssistantMessage).Content, 2)
+	})
+
+	t.Run("without thinking", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "s.json")
+		saved, err := saveSession(path, session, persistNoThinking)
+		require.NoError(t, err)
+		assert.True(t, saved)
+		got, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "hello"}}, got.Messages[1].(pipe.AssistantMessage).Content)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "s.json")
+		saved, err := saveSession(path, session, persistNone)
+		require.NoError(t, err)
+		assert.False(t, saved)
+		assert.NoFileExists(t, path)
+
+		saver := &sessionSaver{path: path, session: &session, persist: persistNone}
+		res, err := saver.save("")
+		require.NoError(t, err)
+		assert.Contains(t, res.Notice, "Session not saved")
+		assert.NoFileExists(t, path)
+	})
+}
+
+func TestConfig_Persistence(t *testing.T) {
+	t.Parallel()
+
+	mode, err := config{}.persistMode()
+	require.NoError(t, err)
+	assert.Equal(t, persistFull, mode)
+
+	_, err = config{Persist: "some"}.persistMode()
+	require.EqualError(t, err, `invalid persist "some": must be "full", "no_thinking", or "none"`)
+
+	cfg := config{Persist: persistFull, UsageMetrics: true, Memory: true, WebhookURL: "https://example.com/hook", ContextBudget: 1000}.incognito()
+	mode, err = cfg.persistMode()
+	require.NoError(t, err)
+	assert.Equal(t, persistNone, mode)
+	assert.False(t, cfg.UsageMetrics)
+	assert.False(t, cfg.Memory)
+	assert.Empty(t, cfg.WebhookURL)
+	assert.True(t, cfg.TempArtifacts)
+	assert.Equal(t, 1000, cfg.ContextBudget, "other settings are kept")
+}
+
+func TestSessionJournal(t *testing.T) {
+	t.Parallel()
+
+	t.Run("journals completed turns without thinking", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "s.json")
+		session := pipe.Session{ID: "s1"}
+		journal := newSessionJournal(path, session, persistNoThinking)
+		var events []pipe.Event
+		onEvent, end := journal.run(&session, func(e pipe.Event) { events = append(events, e) })
+
+		session.Messages = append(session.Messages,
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}},
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{
+				pipe.ThinkingBlock{Thinking: "private reasoning"},
+				pipe.TextBlock{Text: "hello"},
+			}},
+		)
+		onEvent(pipe.EventTurnComplete{})
+		assert.Len(t, events, 1)
+
+		// The session file was never written, as if the process died.
+		got, err := pipejson.Load(path)
+		require.NoError(t, err)
+		require.Len(t, got.Messages, 2)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "hello"}}, got.Messages[1].(pipe.AssistantMessage).Content)
+
+		session.Messages = append(session.Messages, pipe.ToolResultMessage{ToolCallID: "c1"})
+		require.NoError(t, end())
+		got, err = pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Len(t, got.Messages, 3)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+		assert.Nil(t, newSessionJournal(filepath.Join(t.TempDir(), "s.json"), pipe.Session{}, persistNone))
+	})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	pipejson "github.com/fwojciec/pipe/json"
+)
+
+// Output formats for print mode.
+const (
+	outputText       = "text"        // stream the assistant's text as it arrives
+	outputJSON       = "json"        // one JSON object with the final answer and usage
+	outputStreamJSON = "stream-json" // the run's events as JSON lines
+)
+
+// checkOutputFormat validates the -output-format flag.
+func checkOutputFormat(format string) error {
+	switch format {
+	case outputText, outputJSON, outputStreamJSON:
+		return nil
+	default:
+		return fmt.Errorf("-output-format: must be %s, %s, or %s, got %q", outputText, outputJSON, outputStreamJSON, format)
+	}
+}
+
+// newPrintEvents returns the writer for the stream-json output format,
+// selecting events with the -include-events, -exclude-events, and
+// -compact-events flags, or nil for other formats, which take no filters.
+func newPrintEvents(w io.Writer, format, include, exclude string, compact bool) (*pipejson.EventWriter, error) {
+	if format != outputStreamJSON {
+		if include != "" || exclude != "" || compact {
+			return nil, fmt.Errorf("-include-events, -exclude-events, and -compact-events need -output-format %s", outputStreamJSON)
+		}
+		return nil, nil
+	}
+	events, err := pipejson.NewEventWriter(w, pipejson.EventOptions{
+		Include: eventTypes(include),
+		Exclude: eventTypes(exclude),
+		Compact: compact,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("-include-events/-exclude-events: %w", err)
+	}
+	return events, nil
+}
+
+// eventTypes splits a comma-separated list of event type names.
+func eventTypes(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// denyApproval answers approval prompts in print mode, where nobody is
+// there to answer them.
+func denyApproval(context.Context, pipe.ToolCallBlock) (pipe.Decision, error) {
+	return pipe.DecisionDeny, nil
+}
+
+// denyCost answers cost confirmations in print mode: a request over the
+// threshold ends the run.
+func denyCost(context.Context, pipe.Usage, float64) (pipe.Decision, error) {
+	return pipe.DecisionDeny, nil
+}
+
+// runPrint runs the agent once on prompt without the TUI and writes the
+// outcome to stdout in format. Tools run as they would in the TUI; ones
+// that need the user are left out by the agent. Appended collects the
+// messages of the agent's run. Events, from newPrintEvents, writes the
+// stream-json format and is nil for the others.
+func runPrint(ctx context.Context, agent bt.AgentFunc, appended *runMessages, s *pipe.Session, prompt, format string, events *pipejson.EventWriter, stdout io.Writer) error {
+	s.Messages = append(s.Messages, pipe.UserMessage{
+		Content:   []pipe.ContentBlock{pipe.TextBlock{Text: prompt}},
+		Timestamp: time.Now(),
+	})
+
+	var (
+		last     string // the most recent text written, to end output with a newline
+		nextTurn bool   // a tool ran since the last text; separate the turns
+		writeErr error  // the first failure to write an event
+	)
+	onEvent := func(e pipe.Event) {
+		if format == outputStreamJSON {
+			if err := events.Write(e); err != nil && writeErr == nil {
+				writeErr = err
+			}
+			return
+		}
+		if format != outputText {
+			return
+		}
+		switch e := e.(type) {
+		case pipe.EventToolResult:
+			nextTurn = last != ""
+		case pipe.EventTextDelta:
+			if e.Delta == "" {
+				return
+			}
+			if nextTurn {
+				fmt.Fprint(stdout, strings.Repeat("\n", 2-trailingNewlines(last)))
+				nextTurn = false
+			}
+			fmt.Fprint(stdout, e.Delta)
+			last = e.Delta
+		}
+	}
+	if err := agent(ctx, s, onEvent); err != nil {
+		endLine(stdout, last)
+		if events != nil {
+			_ = events.Flush()
+		}
+		return err
+	}
+
+	if format == outputStreamJSON {
+		if err := events.Flush(); err != nil && writeErr == nil {
+			writeErr = err
+		}
+		return writeErr
+	}
+
+	if format == outputJSON {
+		data, err := pipejson.MarshalRunResult(s.ID, appended.messages(), s.Status)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "%s\n", data)
+		return err
+	}
+	endLine(stdout, last)
+	return nil
+}
+
+// endLine ends the output with a newline unless it is empty or the last
+// text written already did.
+func endLine(w io.Writer, last string) {
+	if last != "" && !strings.HasSuffix(last, "\n") {
+		fmt.Fprintln(w)
+	}
+}
+
+// trailingNewlines counts the newlines s ends with, up to two.
+func trailingNewlines(s string) int {
+	n := len(s) - len(strings.TrimRight(s, "\n"))
+	return min(n, 2)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPrint(t *testing.T) {
+	t.Parallel()
+
+	// newAgent returns an agent that answers in two turns around a tool
+	// call, collecting its messages in appended.
+	newAgent := func(appended *runMessages) bt.AgentFunc {
+		return func(_ context.Context, s *pipe.Session, onEvent func(pipe.Event)) error {
+			call := pipe.ToolCallBlock{ID: "1", Name: "bash", Arguments: json.RawMessage(`{}`)}
+			onEvent(pipe.EventTextDelta{Delta: "Checking."})
+			onEvent(pipe.EventToolResult{ID: "1", ToolName: "bash", Content: "ok"})
+			onEvent(pipe.EventTextDelta{Delta: "All "})
+			onEvent(pipe.EventTextDelta{Delta: "good."})
+			msgs := []pipe.Message{
+				pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Checking."}, call}, StopReason: pipe.StopToolUse, Usage: pipe.Usage{InputTokens: 100, OutputTokens: 10}},
+				pipe.ToolResultMessage{ToolCallID: "1", ToolName: "bash", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}}},
+				pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "All good."}}, StopReason: pipe.StopEndTurn, Usage: pipe.Usage{InputTokens: 120, CacheReadTokens: 80, OutputTokens: 5}},
+			}
+			appended.begin()
+			for _, msg := range msgs {
+				s.Messages = append(s.Messages, msg)
+				appended.add(msg)
+			}
+			return nil
+		}
+	}
+
+	t.Run("streams text with turns separated", func(t *testing.T) {
+		t.Parallel()
+		s := &pipe.Session{ID: "s1"}
+		var out strings.Builder
+		appended := &runMessages{}
+		require.NoError(t, runPrint(context.Background(), newAgent(appended), appended, s, "check it", outputText, nil, &out))
+		assert.Equal(t, "Checking.\n\nAll good.\n", out.String())
+		assert.Equal(t, pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "check it"}}, Timestamp: s.Messages[0].(pipe.UserMessage).Timestamp}, s.Messages[0])
+	})
+
+	t.Run("json reports the final answer and usage", func(t *testing.T) {
+		t.Parallel()
+		s := &pipe.Session{ID: "s1"}
+		var out strings.Builder
+		appended := &runMessages{}
+		require.NoError(t, runPrint(context.Background(), newAgent(appended), appended, s, "check it", outputJSON, nil, &out))
+		assert.JSONEq(t, `{
+			"session_id": "s1",
+			"text": "All good.",
+			"stop_reason": "end_turn",
+			"tool_calls": 1,
+			"usage": {"input_tokens": 220, "output_tokens": 15, "cache_read_tokens": 80}
+		}`, out.String())
+	})
+
+	t.Run("json reports the run status", func(t *testing.T) {
+		t.Parallel()
+		appended := &runMessages{}
+		reporting := func(ctx context.Context, s *pipe.Session, onEvent func(pipe.Event)) error {
+			s.Status = pipe.RunStatus{State: pipe.RunDone, Detail: "tests pass"}
+			return newAgent(appended)(ctx, s, onEvent)
+		}
+		s := &pipe.Session{ID: "s1"}
+		var out strings.Builder
+		require.NoError(t, runPrint(context.Background(), reporting, appended, s, "check it", outputJSON, nil, &out))
+		var result struct {
+			Status map[string]string `json:"status"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(out.String()), &result))
+		assert.Equal(t, map[string]string{"state": "done", "detail": "tests pass"}, result.Status)
+	})
+
+	t.Run("stream-json writes the selected events", func(t *testing.T) {
+		t.Parallel()
+		var out strings.Builder
+		events, err := newPrintEvents(&out, outputStreamJSON, "text, tool_result", "", true)
+		require.NoError(t, err)
+		appended := &runMessages{}
+		require.NoError(t, runPrint(context.Background(), newAgent(appended), appended, &pipe.Session{ID: "s1"}, "check it", outputStreamJSON, events, &out))
+		assert.Equal(t, `{"type":"text","index":0,"text":"Checking."}
+{"type":"tool_result","id":"1","name":"bash","content":"ok"}
+{"type":"text","index":0,"text":"All good."}
+`, out.String())
+	})
+
+	t.Run("returns the agent's error", func(t *testing.T) {
+		t.Parallel()
+		failing := func(_ context.Context, _ *pipe.Session, onEvent func(pipe.Event)) error {
+			onEvent(pipe.EventTextDelta{Delta: "partial"})
+			return errors.New("overloaded")
+		}
+		var out strings.Builder
+		err := runPrint(context.Background(), failing, &runMessages{}, &pipe.Session{}, "hi", outputText, nil, &out)
+		require.EqualError(t, err, "overloaded")
+		assert.Equal(t, "partial\n", out.String())
+	})
+
+	t.Run("compaction during the run keeps its messages", func(t *testing.T) {
+		t.Parallel()
+		text := func(s string) []pipe.ContentBlock { return []pipe.ContentBlock{pipe.TextBlock{Text: s}} }
+		call := pipe.ToolCallBlock{ID: "1", Name: "bash", Arguments: json.RawMessage(`{"command":"go test ./..."}`)}
+		// The first turn's usage crosses the budget, so the history up to
+		// its tool call, the prompt included, is summarized before the
+		// second turn.
+		replies := []pipe.AssistantMessage{
+			{Content: []pipe.ContentBlock{call}, StopReason: pipe.StopToolUse, Usage: pipe.Usage{InputTokens: 900, OutputTokens: 10}},
+			{Content: text("earlier work summarized")},
+			{Content: text("All good."), StopReason: pipe.StopEndTurn, Usage: pipe.Usage{InputTokens: 300, OutputTokens: 5}},
+		}
+		var calls int
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			msg := replies[calls]
+			calls++
+			return &mock.Stream{
+				NextFn:    func() (pipe.Event, error) { return nil, io.EOF },
+				MessageFn: func() (pipe.AssistantMessage, error) { return msg, nil },
+			}, nil
+		}}
+		executor := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+			return &pipe.ToolResult{Content: text(strings.Repeat("ok ", 1000))}, nil
+		}}
+		appended := &runMessages{}
+		agent := func(ctx context.Context, s *pipe.Session, onEvent func(pipe.Event)) error {
+			appended.begin()
+			return pipe.NewLoop(provider, executor).Run(ctx, s, nil,
+				pipe.WithEventHandler(onEvent), pipe.WithMessageHandler(appended.add), pipe.WithContextBudget(1000))
+		}
+		s := &pipe.Session{ID: "s1", Messages: []pipe.Message{
+			pipe.UserMessage{Content: text("fix the bug")},
+			pipe.AssistantMessage{Content: text("fixed"), Usage: pipe.Usage{InputTokens: 50}},
+		}}
+		var out strings.Builder
+		require.NoError(t, runPrint(context.Background(), agent, appended, s, "run the tests", outputJSON, nil, &out))
+		require.Equal(t, 3, calls, "the session was compacted")
+		// The summary replaced the prompt and the messages before it, so
+		// the run's messages no longer start where they did.
+		require.Len(t, s.Messages, 4)
+		assert.Contains(t, s.Messages[0].(pipe.UserMessage).Content[0].(pipe.TextBlock).Text, "earlier work summarized")
+
+		assert.JSONEq(t, `{
+			"session_id": "s1",
+			"text": "All good.",
+			"stop_reason": "end_turn",
+			"tool_calls": 1,
+			"usage": {"input_tokens": 1200, "output_tokens": 15}
+		}`, out.String())
+		record := pipe.NewRunRecord(time.Now(), s.ID, "", appended.messages())
+		assert.Equal(t, pipe.Usage{InputTokens: 1200, OutputTokens: 15}, record.Usage)
+		assert.Equal(t, map[string]int{"bash": 1}, record.ToolCalls)
+	})
+}
+
+func TestCheckOutputFormat(t *testing.T) {
+	t.Parallel()
+	assert.NoError(t, checkOutputFormat("text"))
+	assert.NoError(t, checkOutputFormat("json"))
+	assert.NoError(t, checkOutputFormat("stream-json"))
+	assert.EqualError(t, checkOutputFormat("yaml"), `-output-format: must be text, json, or stream-json, got "yaml"`)
+}
+
+func TestNewPrintEvents(t *testing.T) {
+	t.Parallel()
+
+	events, err := newPrintEvents(io.Discard, outputText, "", "", false)
+	require.NoError(t, err)
+	assert.Nil(t, events)
+
+	_, err = newPrintEvents(io.Discard, outputJSON, "text", "", false)
+	assert.EqualError(t, err, "-include-events, -exclude-events, and -compact-events need -output-format stream-json")
+
+	_, err = newPrintEvents(io.Discard, outputStreamJSON, "", "bogus", false)
+	assert.ErrorContains(t, err, `-include-events/-exclude-events: unknown event type "bogus"`)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+// defaultProfile names the settings used when no profile is selected. It can
+// be passed to /profile to leave a profile.
+const defaultProfile = "default"
+
+// profile bundles run settings that are switched together with -profile or
+// /profile. Zero values fall back to the defaults.
+type profile struct {
+	Model        string   `json:"model,omitempty"`
+	SystemPrompt string   `json:"system_prompt,omitempty"` // path to a prompt file
+	Tools        []string `json:"tools,omitempty"`         // allowed built-in tools; empty allows all
+	ServerTools  []string `json:"server_tools,omitempty"`  // replaces the top-level server_tools
+	MaxTokens    int      `json:"max_tokens,omitempty"`
+}
+
+// runSettings are the resolved settings applied to each agent run.
+type runSettings struct {
+	profile      string
+	model        string
+	systemPrompt string // prompt text; empty leaves the session's prompt unchanged
+	tools        []pipe.Tool
+	serverTools  []pipe.ServerTool
+	maxTokens    int
+}
+
+// allowedTools returns the set of tool names the settings permit.
+func (s runSettings) allowedTools() map[string]bool {
+	allowed := make(map[string]bool, len(s.tools))
+	for _, t := range s.tools {
+		allowed[t.Name] = true
+	}
+	return allowed
+}
+
+// resolve builds the run settings for the named profile. An empty name or
+// defaultProfile selects the defaults. model, or the config's Model when
+// model is empty, is used when the profile does not set one.
+func (c config) resolve(name, model string) (runSettings, error) {
+	if model == "" {
+		model = c.Model
+	}
+	serverTools, err := c.serverTools()
+	if err != nil {
+		return runSettings{}, err
+	}
+	s := runSettings{profile: defaultProfile, model: model, tools: c.enabledTools(), serverTools: serverTools}
+	if name == "" || name == defaultProfile {
+		return s, nil
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return runSettings{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(c.profileNames(), ", "))
+	}
+	s.profile = name
+	if p.Model != "" {
+		s.model = p.Model
+	}
+	s.maxTokens = p.MaxTokens
+	if p.SystemPrompt != "" {
+		data, err := os.ReadFile(p.SystemPrompt)
+		if err != nil {
+			return runSettings{}, fmt.Errorf("profile %s: read system prompt: %w", name, err)
+		}
+		s.systemPrompt = string(data)
+	}
+	if len(p.Tools) > 0 {
+		var allowed []pipe.Tool
+		for _, toolName := range p.Tools {
+			i := slices.IndexFunc(s.tools, func(t pipe.Tool) bool { return t.Name == toolName })
+			if i < 0 {
+				return runSettings{}, fmt.Errorf("profile %s: unknown tool %q", name, toolName)
+			}
+			allowed = append(allowed, s.tools[i])
+		}
+		s.tools = allowed
+	}
+	if len(p.ServerTools) > 0 {
+		s.serverTools, err = config{ServerTools: p.ServerTools}.serverTools()
+		if err != nil {
+			return runSettings{}, fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	return s, nil
+}
+
+// profileNames returns the configured profile names, sorted.
+func (c config) profileNames() []string {
+	names := []string{defaultProfile}
+	for name := range c.Profiles {
+		if name != defaultProfile {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names[1:])
+	return names
+}
+
+// profileSwitcher holds the active run settings and implements /profile.
+type profileSwitcher struct {
+	cfg          config
+	model        string // -model flag value, used when a profile sets no model
+	defaultModel string // the provider's model for runs that name none
+	// prompt is the session's prompt under profiles that set none: the
+	// startup prompt, or the prompt file's once a reload applies it.
+	prompt  string
+	session *pipe.Session
+
+	mu      sync.Mutex
+	current runSettings
+}
+
+// settings returns the active run settings.
+func (p *profileSwitcher) settings() runSettings {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current
+}
+
+// reconfigure resolves the active profile again from cfg, e.g. after the
+// config file changed, and makes it current.
+func (p *profileSwitcher) reconfigure(cfg config) (runSettings, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, err := cfg.resolve(p.current.profile, p.model)
+	if err != nil {
+		return runSettings{}, err
+	}
+	p.cfg, p.current = cfg, s
+	return s, nil
+}
+
+// effectiveModel returns the model runs with model use: model itself, or
+// the provider's default when it is empty.
+func (p *profileSwitcher) effectiveModel(model string) string {
+	return cmp.Or(model, p.defaultModel)
+}
+
+// pricing returns the price of runs with model, or nil when it is unknown.
+func (p *profileSwitcher) pricing(model string) *pipe.Pricing {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pricingLocked(model)
+}
+
+func (p *profileSwitcher) pricingLocked(model string) *pipe.Pricing {
+	price, ok := p.cfg.pricing(p.effectiveModel(model))
+	if !ok {
+		return nil
+	}
+	return &price
+}
+
+// setPrompt makes prompt the session's prompt under profiles that set none.
+func (p *profileSwitcher) setPrompt(prompt string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompt = prompt
+}
+
+// setModel makes name the active model until the next profile switch or
+// config reload, and returns its price.
+func (p *profileSwitcher) setModel(name string) *pipe.Pricing {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current.model = name
+	return p.pricingLocked(name)
+}
+
+// command implements /profile: without arguments it lists profiles,
+// otherwise it switches to the named profile.
+func (p *profileSwitcher) command(args string) (bt.CommandResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if args == "" {
+		var b strings.Builder
+		b.WriteString("Profiles:")
+		for _, name := range p.cfg.profileNames() {
+			marker := " "
+			if name == p.current.profile {
+				marker = "*"
+			}
+			fmt.Fprintf(&b, "\n%s %s", marker, name)
+		}
+		return bt.CommandResult{Notice: b.String()}, nil
+	}
+
+	s, err := p.cfg.resolve(args, p.model)
+	if err != nil {
+		return bt.CommandResult{}, err
+	}
+	p.current = s
+	p.session.SystemPrompt = cmp.Or(s.systemPrompt, p.prompt)
+	return bt.CommandResult{
+		Notice:    fmt.Sprintf("Switched to profile %s.", s.profile),
+		ModelName: p.effectiveModel(s.model),
+		Pricing:   p.pricingLocked(s.model),
+	}, nil
+}
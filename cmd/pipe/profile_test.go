@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Resolve(t *testing.T) {
+	t.Parallel()
+
+	t.Run("default profile uses all tools and the given model", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{ServerTools: []string{"web_search"}}
+
+		s, err := cfg.resolve("", "flag-model")
+		require.NoError(t, err)
+		assert.Equal(t, defaultProfile, s.profile)
+		assert.Equal(t, "flag-model", s.model)
+		assert.Len(t, s.tools, len(tools()))
+		assert.Equal(t, []pipe.ServerTool{pipe.ServerToolWebSearch}, s.serverTools)
+		assert.Zero(t, s.maxTokens)
+	})
+
+	t.Run("named profile overrides settings", func(t *testing.T) {
+		t.Parallel()
+		promptPath := filepath.Join(t.TempDir(), "reviewer.md")
+		require.NoError(t, os.WriteFile(promptPath, []byte("You review code."), 0o644))
+		cfg := config{
+			ServerTools: []string{"web_search"},
+			Profiles: map[string]profile{
+				"reviewer": {
+					Model:        "review-model",
+					SystemPrompt: promptPath,
+					Tools:        []string{"read", "grep"},
+					ServerTools:  []string{"code_execution"},
+					MaxTokens:    2048,
+				},
+			},
+		}
+
+		s, err := cfg.resolve("reviewer", "flag-model")
+		require.NoError(t, err)
+		assert.Equal(t, "reviewer", s.profile)
+		assert.Equal(t, "review-model", s.model)
+		assert.Equal(t, "You review code.", s.systemPrompt)
+		assert.Equal(t, map[string]bool{"read": true, "grep": true}, s.allowedTools())
+		assert.Equal(t, []pipe.ServerTool{pipe.ServerToolCodeExecution}, s.serverTools)
+		assert.Equal(t, 2048, s.maxTokens)
+	})
+
+	t.Run("config model is the fallback", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Model: "config-model", Profiles: map[string]profile{"reviewer": {Model: "review-model"}}}
+
+		s, err := cfg.resolve("", "")
+		require.NoError(t, err)
+		assert.Equal(t, "config-model", s.model)
+		s, err = cfg.resolve("", "flag-model")
+		require.NoError(t, err)
+		assert.Equal(t, "flag-model", s.model)
+		s, err = cfg.resolve("reviewer", "")
+		require.NoError(t, err)
+		assert.Equal(t, "review-model", s.model)
+	})
+
+	t.Run("unknown profile lists available profiles", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Profiles: map[string]profile{"reviewer": {}, "fast": {}}}
+
+		_, err := cfg.resolve("missing", "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "default, fast, reviewer")
+	})
+
+	t.Run("unknown tool is an error", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Profiles: map[string]profile{"bad": {Tools: []string{"teleport"}}}}
+
+		_, err := cfg.resolve("bad", "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown tool "teleport"`)
+	})
+}
+
+func TestProfileSwitcher_Command(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lists profiles with the active one marked", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Profiles: map[string]profile{"reviewer": {}}}
+		current, err := cfg.resolve("", "")
+		require.NoError(t, err)
+		p := &profileSwitcher{cfg: cfg, session: &pipe.Session{}, current: current}
+
+		out, err := p.command("")
+		require.NoError(t, err)
+		assert.Equal(t, "Profiles:\n* default\n  reviewer", out.Notice)
+	})
+
+	t.Run("switches profile and updates the session", func(t *testing.T) {
+		t.Parallel()
+		promptPath := filepath.Join(t.TempDir(), "reviewer.md")
+		require.NoError(t, os.WriteFile(promptPath, []byte("You review code."), 0o644))
+		cfg := config{Profiles: map[string]profile{
+			"reviewer": {Model: "review-model", SystemPrompt: promptPath},
+		}}
+		current, err := cfg.resolve("", "flag-model")
+		require.NoError(t, err)
+		session := &pipe.Session{SystemPrompt: "original"}
+		p := &profileSwitcher{cfg: cfg, model: "flag-model", session: session, current: current}
+
+		out, err := p.command("reviewer")
+		require.NoError(t, err)
+		assert.Equal(t, "Switched to profile reviewer.", out.Notice)
+		assert.Equal(t, "review-model", out.ModelName)
+		assert.Equal(t, "You review code.", session.SystemPrompt)
+		assert.Equal(t, "reviewer", p.settings().profile)
+
+		out, err = p.command(defaultProfile)
+		require.NoError(t, err)
+		assert.Equal(t, "flag-model", out.ModelName)
+		assert.Equal(t, defaultProfile, p.settings().profile)
+	})
+
+	t.Run("switching back to default restores the startup prompt and model", func(t *testing.T) {
+		t.Parallel()
+		promptPath := filepath.Join(t.TempDir(), "reviewer.md")
+		require.NoError(t, os.WriteFile(promptPath, []byte("You review code."), 0o644))
+		cfg := config{Model: "base-model", Profiles: map[string]profile{
+			"reviewer": {Model: "review-model", SystemPrompt: promptPath},
+		}}
+		current, err := cfg.resolve("", "")
+		require.NoError(t, err)
+		session := &pipe.Session{SystemPrompt: "You write code."}
+		p := &profileSwitcher{cfg: cfg, prompt: session.SystemPrompt, session: session, current: current}
+
+		_, err = p.command("reviewer")
+		require.NoError(t, err)
+		assert.Equal(t, "You review code.", session.SystemPrompt)
+		assert.Equal(t, "review-model", p.settings().model)
+
+		out, err := p.command(defaultProfile)
+		require.NoError(t, err)
+		assert.Equal(t, "base-model", out.ModelName)
+		assert.Equal(t, "base-model", p.settings().model)
+		assert.Equal(t, "You write code.", session.SystemPrompt)
+	})
+
+	t.Run("prices the provider's default model", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Profiles: map[string]profile{"opus": {Model: "claude-opus-4-1"}}}
+		current, err := cfg.resolve("", "")
+		require.NoError(t, err)
+		p := &profileSwitcher{cfg: cfg, defaultModel: "claude-sonnet-4-20250514", session: &pipe.Session{}, current: current}
+		require.NotNil(t, p.pricing(""))
+		assert.Equal(t, 3.0, p.pricing("").Input)
+
+		out, err := p.command("opus")
+		require.NoError(t, err)
+		require.NotNil(t, out.Pricing)
+		assert.Equal(t, 15.0, out.Pricing.Input)
+
+		out, err = p.command(defaultProfile)
+		require.NoError(t, err)
+		assert.Equal(t, "claude-sonnet-4-20250514", out.ModelName)
+		require.NotNil(t, out.Pricing)
+		assert.Equal(t, 3.0, out.Pricing.Input)
+	})
+
+	t.Run("unknown profile keeps the current settings", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{}
+		current, err := cfg.resolve("", "")
+		require.NoError(t, err)
+		p := &profileSwitcher{cfg: cfg, session: &pipe.Session{}, current: current}
+
+		_, err = p.command("missing")
+		require.Error(t, err)
+		assert.Equal(t, defaultProfile, p.settings().profile)
+	})
+}
+
+func TestExecutor_AllowedTools(t *testing.T) {
+	t.Parallel()
+	exec := &executor{bash: pipeexec.NewBashExecutor(), allowed: map[string]bool{"read": true}}
+	args, _ := json.Marshal(map[string]any{"command": "echo hi"})
+
+	result, err := exec.Execute(context.Background(), "bash", args)
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	text, ok := result.Content[0].(pipe.TextBlock)
+	require.True(t, ok)
+	assert.Contains(t, text.Text, "not allowed")
+}
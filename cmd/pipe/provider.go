@@ -2,7 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/fwojciec/pipe"
 	"github.com/fwojciec/pipe/anthropic"
@@ -14,73 +21,268 @@ type providerConfig struct {
 	key  string
 }
 
-// resolveConfig determines the provider name and API key from flags and env
-// vars. Pure logic — no side effects.
-func resolveConfig(providerFlag, apiKeyFlag, anthropicEnvKey, geminiEnvKey string) (providerConfig, error) {
+// newProviderRegistry returns the registry of built-in providers, followed
+// by the third-party ones added with pipe.RegisterProvider. Built-in
+// factories read provider options from *settings when they run, so the
+// registry can be built before the config file is loaded. Their clients
+// send requests with hc, or their default client when nil.
+func newProviderRegistry(settings *config, hc *http.Client) *pipe.ProviderRegistry {
+	var r pipe.ProviderRegistry
+	mustRegister := func(b pipe.ProviderBackend) {
+		if err := r.Register(b); err != nil {
+			panic(err)
+		}
+	}
+	mustRegister(pipe.ProviderBackend{
+		Name:         "anthropic",
+		EnvKey:       "ANTHROPIC_API_KEY",
+		DefaultModel: anthropic.DefaultModel,
+		New: func(_ context.Context, key string) (pipe.Provider, error) {
+			results, _ := settings.toolResultFormat(anthropic.CompactToolResults()) // validated when loaded
+			opts := []anthropic.Option{anthropic.WithToolResultFormat(results)}
+			if u := os.Getenv("ANTHROPIC_BASE_URL"); u != "" {
+				opts = append(opts, anthropic.WithBaseURL(u))
+			}
+			if len(settings.AnthropicBetas) > 0 {
+				opts = append(opts, anthropic.WithBetas(settings.AnthropicBetas...))
+			}
+			if settings.AnthropicCacheMessages > 0 {
+				opts = append(opts, anthropic.WithCacheStrategy(anthropic.CacheStrategy{RecentMessages: settings.AnthropicCacheMessages}))
+			}
+			if hc != nil {
+				opts = append(opts, anthropic.WithHTTPClient(hc))
+			}
+			return anthropic.New(key, opts...), nil
+		},
+	})
+	mustRegister(pipe.ProviderBackend{
+		Name:         "gemini",
+		EnvKey:       "GEMINI_API_KEY",
+		DefaultModel: gemini.DefaultModel,
+		New: func(ctx context.Context, key string) (pipe.Provider, error) {
+			results, _ := settings.toolResultFormat(gemini.CompactToolResults()) // validated when loaded
+			opts := []gemini.Option{gemini.WithToolResultFormat(results)}
+			if hc != nil {
+				opts = append(opts, gemini.WithHTTPClient(hc))
+			}
+			client, err := gemini.New(ctx, key, opts...)
+			if err != nil {
+				return nil, fmt.Errorf("gemini: %w", err)
+			}
+			return client, nil
+		},
+	})
+	for _, b := range pipe.RegisteredProviders() {
+		mustRegister(b)
+	}
+	return &r
+}
+
+// resolveConfig determines the provider name and API key from flags and the
+// registered backends' env vars, read with getenv. Pure logic — no side
+// effects.
+func resolveConfig(registry *pipe.ProviderRegistry, providerFlag, apiKeyFlag string, getenv func(string) string) (providerConfig, error) {
+	backends := registry.Backends()
+	envKeys := make([]string, len(backends))
+	for i, b := range backends {
+		envKeys[i] = b.EnvKey
+	}
+
 	provider := providerFlag
 
 	// Auto-detect from env vars if no flag.
 	if provider == "" {
-		hasAnthropic := anthropicEnvKey != ""
-		hasGemini := geminiEnvKey != ""
+		var found []string
+		for _, b := range backends {
+			if b.EnvKey != "" && getenv(b.EnvKey) != "" {
+				provider = b.Name
+				found = append(found, b.EnvKey)
+			}
+		}
 		switch {
-		case hasAnthropic && hasGemini:
-			return providerConfig{}, fmt.Errorf("multiple API keys found (ANTHROPIC_API_KEY, GEMINI_API_KEY): use -provider flag to select")
-		case hasAnthropic:
-			provider = "anthropic"
-		case hasGemini:
-			provider = "gemini"
-		default:
-			return providerConfig{}, fmt.Errorf("no API key found: set ANTHROPIC_API_KEY or GEMINI_API_KEY (or use -provider and -api-key flags)")
+		case len(found) > 1:
+			return providerConfig{}, fmt.Errorf("multiple API keys found (%s): use -provider flag to select", strings.Join(found, ", "))
+		case len(found) == 0:
+			return providerConfig{}, fmt.Errorf("no API key found: set %s (or use -provider and -api-key flags)", orList(envKeys, false))
 		}
 	}
 
+	backend, ok := registry.Lookup(provider)
+	if !ok {
+		return providerConfig{}, fmt.Errorf("unknown provider %q: must be %s", provider, orList(registry.Names(), true))
+	}
+
 	// Resolve API key: explicit flag overrides env var.
 	key := apiKeyFlag
-	switch provider {
-	case "anthropic":
-		if key == "" {
-			key = anthropicEnvKey
-		}
-		if key == "" {
-			return providerConfig{}, fmt.Errorf("ANTHROPIC_API_KEY not set (use -api-key flag or environment variable)")
-		}
-	case "gemini":
-		if key == "" {
-			key = geminiEnvKey
-		}
-		if key == "" {
-			return providerConfig{}, fmt.Errorf("GEMINI_API_KEY not set (use -api-key flag or environment variable)")
+	if key == "" && backend.EnvKey != "" {
+		key = getenv(backend.EnvKey)
+	}
+	if key == "" {
+		env := backend.EnvKey
+		if env == "" {
+			env = provider + " API key"
 		}
-	default:
-		return providerConfig{}, fmt.Errorf("unknown provider %q: must be \"anthropic\" or \"gemini\"", provider)
+		return providerConfig{}, fmt.Errorf("%s not set (use -api-key flag or environment variable)", env)
 	}
 
 	return providerConfig{name: provider, key: key}, nil
 }
 
-// resolveProvider selects and constructs the provider. All env var values are
-// passed in as parameters — env is only read in main().
-func resolveProvider(providerFlag, apiKeyFlag, anthropicEnvKey, geminiEnvKey string) (pipe.Provider, error) {
-	cfg, err := resolveConfig(providerFlag, apiKeyFlag, anthropicEnvKey, geminiEnvKey)
+// providerProbeTimeout bounds how long startup waits on fastestProvider.
+const providerProbeTimeout = 5 * time.Second
+
+// detectedProviders returns the names of the registered backends whose env
+// var is set, in registration order.
+func detectedProviders(registry *pipe.ProviderRegistry, getenv func(string) string) []string {
+	var names []string
+	for _, b := range registry.Backends() {
+		if b.EnvKey != "" && getenv(b.EnvKey) != "" {
+			names = append(names, b.Name)
+		}
+	}
+	return names
+}
+
+// fastestProvider sends a one-token request to each named provider at once
+// and returns the name of the first to start streaming. Providers that fail
+// to build or to answer before ctx is done are skipped; if none answers the
+// errors are joined.
+func fastestProvider(ctx context.Context, registry *pipe.ProviderRegistry, names []string, getenv func(string) string) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type probe struct {
+		name string
+		err  error
+	}
+	results := make(chan probe, len(names))
+	for _, name := range names {
+		go func() {
+			results <- probe{name: name, err: probeProvider(ctx, registry, name, getenv)}
+		}()
+	}
+	var errs []error
+	for range names {
+		r := <-results
+		if r.err == nil {
+			return r.name, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", r.name, r.err))
+	}
+	return "", fmt.Errorf("no provider answered: %w", errors.Join(errs...))
+}
+
+// probeProvider builds the named provider and waits for the first event of
+// a minimal request. A stream that ends without events also counts as an
+// answer.
+func probeProvider(ctx context.Context, registry *pipe.ProviderRegistry, name string, getenv func(string) string) error {
+	backend, ok := registry.Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown provider %q", name)
+	}
+	provider, err := backend.New(context.Background(), getenv(backend.EnvKey))
 	if err != nil {
-		return nil, err
-	}
-
-	switch cfg.name {
-	case "anthropic":
-		return anthropic.New(cfg.key), nil
-	case "gemini":
-		// Use context.Background() for client construction — the genai SDK may
-		// store this context for the client's lifetime. The signal context is
-		// passed per-call via Stream(ctx, ...).
-		client, err := gemini.New(context.Background(), cfg.key)
-		if err != nil {
-			return nil, fmt.Errorf("gemini: %w", err)
+		return err
+	}
+	stream, err := provider.Stream(ctx, pipe.Request{
+		Messages:  []pipe.Message{pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ping"}}}},
+		MaxTokens: 1,
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	if _, err := stream.Next(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// newProvider constructs the client for a resolved provider config.
+func newProvider(registry *pipe.ProviderRegistry, cfg providerConfig) (pipe.Provider, error) {
+	backend, ok := registry.Lookup(cfg.name)
+	if !ok {
+		// Defensive: resolveConfig validates the name, but guard against future drift.
+		return nil, fmt.Errorf("unknown provider %q: must be %s", cfg.name, orList(registry.Names(), true))
+	}
+	// Use context.Background() for client construction — the genai SDK may
+	// store this context for the client's lifetime. The signal context is
+	// passed per-call via Stream(ctx, ...).
+	return backend.New(context.Background(), cfg.key)
+}
+
+// orList joins items as "a or b" or "a, b, or c", quoting them when quote
+// is set.
+func orList(items []string, quote bool) string {
+	if quote {
+		quoted := make([]string, len(items))
+		for i, item := range items {
+			quoted[i] = strconv.Quote(item)
 		}
-		return client, nil
+		items = quoted
+	}
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	case 2:
+		return items[0] + " or " + items[1]
 	default:
-		// Defensive: resolveConfig validates the name, but guard against future drift.
-		return nil, fmt.Errorf("unknown provider %q: must be \"anthropic\" or \"gemini\"", cfg.name)
+		return strings.Join(items[:len(items)-1], ", ") + ", or " + items[len(items)-1]
+	}
+}
+
+var _ pipe.Provider = (*lazyProvider)(nil)
+
+// lazyProvider is a provider whose client is constructed in the background
+// so the TUI can draw its first frame without waiting on client setup.
+// Requests block until construction finishes; a construction error is
+// returned from every request.
+type lazyProvider struct {
+	ready    chan struct{}
+	provider pipe.Provider
+	err      error
+}
+
+// startProvider runs build in a new goroutine and returns a provider that
+// delegates to its result.
+func startProvider(build func() (pipe.Provider, error)) *lazyProvider {
+	p := &lazyProvider{ready: make(chan struct{})}
+	go func() {
+		defer close(p.ready)
+		p.provider, p.err = build()
+	}()
+	return p
+}
+
+func (p *lazyProvider) Stream(ctx context.Context, req pipe.Request) (pipe.Stream, error) {
+	select {
+	case <-p.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.provider.Stream(ctx, req)
+}
+
+var _ pipe.ModelLister = (*lazyProvider)(nil)
+
+// ListModels waits for the client like Stream and lists its models, failing
+// when the provider cannot list them.
+func (p *lazyProvider) ListModels(ctx context.Context) ([]string, error) {
+	select {
+	case <-p.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	lister, ok := p.provider.(pipe.ModelLister)
+	if !ok {
+		return nil, errors.New("the provider cannot list its models")
 	}
+	return lister.ListModels(ctx)
 }
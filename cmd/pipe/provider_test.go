@@ -1,9 +1,16 @@
 package main_test
 
 import (
+	"context"
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fwojciec/pipe"
 	. "github.com/fwojciec/pipe/cmd/pipe"
+	"github.com/fwojciec/pipe/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -24,6 +31,19 @@ func TestResolveConfig_ExplicitGemini(t *testing.T) {
 	assert.Equal(t, "gk-test", key)
 }
 
+func TestResolveConfig_RegisteredProvider(t *testing.T) {
+	t.Parallel()
+	pipe.RegisterProvider(pipe.ProviderBackend{
+		Name:   "plugin-test",
+		EnvKey: "PLUGIN_TEST_API_KEY",
+		New:    func(context.Context, string) (pipe.Provider, error) { return &mock.Provider{}, nil },
+	})
+	name, key, err := ResolveConfigForTest("plugin-test", "pk-test", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "plugin-test", name)
+	assert.Equal(t, "pk-test", key)
+}
+
 func TestResolveConfig_UnknownProvider(t *testing.T) {
 	t.Parallel()
 	_, _, err := ResolveConfigForTest("openai", "key", "", "")
@@ -82,3 +102,141 @@ func TestResolveConfig_ExplicitGeminiMissingKey(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "GEMINI_API_KEY not set")
 }
+
+func TestResolveConfig_RegisteredBackends(t *testing.T) {
+	t.Parallel()
+	factory := func(context.Context, string) (pipe.Provider, error) { return &mock.Provider{}, nil }
+	var registry pipe.ProviderRegistry
+	require.NoError(t, registry.Register(pipe.ProviderBackend{Name: "anthropic", EnvKey: "ANTHROPIC_API_KEY", New: factory}))
+	require.NoError(t, registry.Register(pipe.ProviderBackend{Name: "local", EnvKey: "LOCAL_API_KEY", New: factory}))
+	require.NoError(t, registry.Register(pipe.ProviderBackend{Name: "other", EnvKey: "OTHER_API_KEY", New: factory}))
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+
+	name, key, err := ResolveProviderForTest(&registry, "", "", env(map[string]string{"LOCAL_API_KEY": "lk"}))
+	require.NoError(t, err)
+	assert.Equal(t, "local", name)
+	assert.Equal(t, "lk", key)
+
+	_, _, err = ResolveProviderForTest(&registry, "", "", env(map[string]string{"LOCAL_API_KEY": "lk", "OTHER_API_KEY": "ok"}))
+	require.EqualError(t, err, "multiple API keys found (LOCAL_API_KEY, OTHER_API_KEY): use -provider flag to select")
+
+	_, _, err = ResolveProviderForTest(&registry, "", "", env(nil))
+	require.EqualError(t, err, "no API key found: set ANTHROPIC_API_KEY, LOCAL_API_KEY, or OTHER_API_KEY (or use -provider and -api-key flags)")
+
+	_, _, err = ResolveProviderForTest(&registry, "openai", "key", env(nil))
+	require.EqualError(t, err, `unknown provider "openai": must be "anthropic", "local", or "other"`)
+}
+
+func TestFastestProvider(t *testing.T) {
+	t.Parallel()
+	backend := func(name string, delay time.Duration, err error) pipe.ProviderBackend {
+		return pipe.ProviderBackend{
+			Name:   name,
+			EnvKey: strings.ToUpper(name) + "_API_KEY",
+			New: func(context.Context, string) (pipe.Provider, error) {
+				return &mock.Provider{StreamFn: func(ctx context.Context, _ pipe.Request) (pipe.Stream, error) {
+					return &mock.Stream{NextFn: func() (pipe.Event, error) {
+						select {
+						case <-time.After(delay):
+						case <-ctx.Done():
+							return nil, ctx.Err()
+						}
+						if err != nil {
+							return nil, err
+						}
+						return pipe.EventTextDelta{Delta: "p"}, nil
+					}}, nil
+				}}, nil
+			},
+		}
+	}
+	getenv := func(string) string { return "key" }
+
+	t.Run("picks the first provider to answer", func(t *testing.T) {
+		t.Parallel()
+		var registry pipe.ProviderRegistry
+		require.NoError(t, registry.Register(backend("slow", time.Second, nil)))
+		require.NoError(t, registry.Register(backend("fast", 0, nil)))
+		name, err := FastestProviderForTest(context.Background(), &registry, []string{"slow", "fast"}, getenv)
+		require.NoError(t, err)
+		assert.Equal(t, "fast", name)
+	})
+
+	t.Run("skips providers that fail", func(t *testing.T) {
+		t.Parallel()
+		var registry pipe.ProviderRegistry
+		require.NoError(t, registry.Register(backend("broken", 0, errors.New("overloaded"))))
+		require.NoError(t, registry.Register(backend("healthy", 50*time.Millisecond, nil)))
+		name, err := FastestProviderForTest(context.Background(), &registry, []string{"broken", "healthy"}, getenv)
+		require.NoError(t, err)
+		assert.Equal(t, "healthy", name)
+	})
+
+	t.Run("an empty stream counts as an answer", func(t *testing.T) {
+		t.Parallel()
+		var registry pipe.ProviderRegistry
+		require.NoError(t, registry.Register(backend("slow", time.Second, nil)))
+		require.NoError(t, registry.Register(backend("empty", 0, io.EOF)))
+		name, err := FastestProviderForTest(context.Background(), &registry, []string{"slow", "empty"}, getenv)
+		require.NoError(t, err)
+		assert.Equal(t, "empty", name)
+	})
+
+	t.Run("fails when no provider answers", func(t *testing.T) {
+		t.Parallel()
+		var registry pipe.ProviderRegistry
+		require.NoError(t, registry.Register(backend("a", 0, errors.New("overloaded"))))
+		require.NoError(t, registry.Register(backend("b", time.Minute, nil)))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := FastestProviderForTest(ctx, &registry, []string{"a", "b"}, getenv)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no provider answered")
+		assert.Contains(t, err.Error(), "a: overloaded")
+	})
+}
+
+func TestStartProvider(t *testing.T) {
+	t.Parallel()
+
+	t.Run("streams through the built client", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		client := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			return &mock.Stream{}, nil
+		}}
+		p := StartProviderForTest(func() (pipe.Provider, error) {
+			<-release
+			return client, nil
+		})
+		close(release)
+		stream, err := p.Stream(context.Background(), pipe.Request{})
+		require.NoError(t, err)
+		assert.NotNil(t, stream)
+	})
+
+	t.Run("returns the construction error", func(t *testing.T) {
+		t.Parallel()
+		p := StartProviderForTest(func() (pipe.Provider, error) {
+			return nil, errors.New("bad key")
+		})
+		_, err := p.Stream(context.Background(), pipe.Request{})
+		assert.EqualError(t, err, "bad key")
+	})
+
+	t.Run("gives up when the context is canceled", func(t *testing.T) {
+		t.Parallel()
+		block := make(chan struct{})
+		t.Cleanup(func() { close(block) })
+		p := StartProviderForTest(func() (pipe.Provider, error) {
+			<-block
+			return nil, nil
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := p.Stream(ctx, pipe.Request{})
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
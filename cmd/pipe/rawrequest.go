@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/fwojciec/pipe"
+	pipehttp "github.com/fwojciec/pipe/http"
+)
+
+// maxRecordedRequests bounds the provider requests kept for the raw
+// request view.
+const maxRecordedRequests = 50
+
+// requestLog files each provider request under the number of the turn it
+// produces, counting the turns completed in this process from 0 the way
+// the TUI does, so the TUI can show the raw request behind a turn.
+type requestLog struct {
+	rec *pipehttp.Recorder
+
+	mu    sync.Mutex
+	turns int // turns completed so far
+}
+
+// provider returns p with its requests tagged with the current turn.
+func (l *requestLog) provider(p pipe.Provider) pipe.Provider {
+	return &taggedProvider{Provider: p, log: l}
+}
+
+// onEvent counts the turns completed by the run whose events go to next.
+func (l *requestLog) onEvent(next func(pipe.Event)) func(pipe.Event) {
+	return func(e pipe.Event) {
+		if _, ok := e.(pipe.EventTurnComplete); ok {
+			l.mu.Lock()
+			l.turns++
+			l.mu.Unlock()
+		}
+		next(e)
+	}
+}
+
+// request returns the method, URL, and indented JSON body of the request
+// that produced turn.
+func (l *requestLog) request(turn int) (string, bool) {
+	rec, ok := l.rec.Request(strconv.Itoa(turn))
+	if !ok {
+		return "", false
+	}
+	var body bytes.Buffer
+	if err := json.Indent(&body, rec.Body, "", "  "); err != nil {
+		body.Reset()
+		body.Write(rec.Body)
+	}
+	return rec.Method + " " + rec.URL + "\n\n" + body.String(), true
+}
+
+var _ pipe.Provider = (*taggedProvider)(nil)
+
+// taggedProvider tags the context of each request with the turn it is for.
+type taggedProvider struct {
+	pipe.Provider
+	log *requestLog
+}
+
+func (p *taggedProvider) Stream(ctx context.Context, req pipe.Request) (pipe.Stream, error) {
+	p.log.mu.Lock()
+	turn := p.log.turns
+	p.log.mu.Unlock()
+	return p.Provider.Stream(pipehttp.WithRequestTag(ctx, strconv.Itoa(turn)), req)
+}
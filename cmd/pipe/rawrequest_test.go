@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipehttp "github.com/fwojciec/pipe/http"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLog(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	log := &requestLog{rec: pipehttp.NewRecorder(nil, maxRecordedRequests)}
+	client := &http.Client{Transport: log.rec}
+	// The mock provider posts the request's model the way a client would.
+	provider := log.provider(&mock.Provider{StreamFn: func(ctx context.Context, req pipe.Request) (pipe.Stream, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/v1/messages", strings.NewReader(`{"model":"`+req.Model+`"}`))
+		require.NoError(t, err)
+		resp, err := client.Do(httpReq)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return nil, nil
+	}})
+	onEvent := log.onEvent(func(pipe.Event) {})
+
+	_, _ = provider.Stream(context.Background(), pipe.Request{Model: "first"})
+	onEvent(pipe.EventTurnComplete{})
+	_, _ = provider.Stream(context.Background(), pipe.Request{Model: "second"})
+
+	text, ok := log.request(0)
+	require.True(t, ok)
+	assert.Equal(t, "POST "+srv.URL+"/v1/messages\n\n{\n  \"model\": \"first\"\n}", text)
+	text, ok = log.request(1)
+	require.True(t, ok)
+	assert.Contains(t, text, `"model": "second"`)
+	_, ok = log.request(2)
+	assert.False(t, ok)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/anthropic"
+	pipejson "github.com/fwojciec/pipe/json"
+)
+
+const reconcileUsage = "usage: pipe usage reconcile [-session file | -last 1d [-log file]] [-tolerance 0.05]"
+
+// defaultReconcileTolerance is the fraction by which local and reported
+// counts may differ before they are flagged.
+const defaultReconcileTolerance = 0.05
+
+// newUsageReporter returns the reporter of provider-recorded usage. Only
+// Anthropic reports usage, through its Admin API; Gemini has no usage API.
+func newUsageReporter(getenv func(string) string) (pipe.UsageReporter, error) {
+	key := getenv("ANTHROPIC_ADMIN_KEY")
+	if key == "" {
+		return nil, errors.New("usage reconcile: set ANTHROPIC_ADMIN_KEY to an Anthropic Admin API key (Gemini has no usage API)")
+	}
+	return anthropic.New(key), nil
+}
+
+// runReconcile implements "pipe usage reconcile": it compares the usage
+// pipe accumulated locally, for a saved session or from the usage log,
+// with the usage the provider reported for the same period, and flags
+// categories that differ by more than the tolerance.
+func runReconcile(ctx context.Context, args []string, stdout io.Writer, now time.Time, locale pipe.Locale, reporter pipe.UsageReporter) error {
+	flags := flag.NewFlagSet("usage reconcile", flag.ContinueOnError)
+	sessionPath := flags.String("session", "", "Session file to reconcile")
+	last := flags.String("last", "1d", "Period of the usage log to reconcile, e.g. 1d, 12h")
+	logPath := flags.String("log", defaultUsageLogPath(), "Usage log file")
+	tolerance := flags.Float64("tolerance", defaultReconcileTolerance, "Allowed difference as a fraction of the larger count")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || *tolerance < 0 {
+		return errors.New(reconcileUsage)
+	}
+
+	var local pipe.Usage
+	var start, end time.Time
+	if *sessionPath != "" {
+		session, err := pipejson.Load(*sessionPath, pipejson.WithPreserveUnknown())
+		if err != nil {
+			return fmt.Errorf("load session: %w", err)
+		}
+		local = session.TotalUsage()
+		start, end = session.CreatedAt, session.UpdatedAt
+	} else {
+		period, err := parsePeriod(*last)
+		if err != nil {
+			return err
+		}
+		records, err := pipejson.LoadRunRecords(*logPath)
+		if err != nil {
+			return err
+		}
+		start, end = now.Add(-period), now
+		for _, r := range records {
+			if !r.Time.Before(start) {
+				local = local.Add(r.Usage)
+			}
+		}
+	}
+
+	reported, err := reporter.ReportedUsage(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	flagged := make(map[string]bool)
+	for _, d := range pipe.ReconcileUsage(local, reported, *tolerance) {
+		flagged[d.Category] = true
+	}
+
+	fmt.Fprintf(stdout, "Usage from %s to %s:\n\n", locale.DateTime(start), locale.DateTime(end))
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CATEGORY\tLOCAL\tREPORTED\t")
+	for _, row := range []struct {
+		category        string
+		local, reported int
+	}{
+		{"input", local.InputTokens, reported.InputTokens},
+		{"cache_read", local.CacheReadTokens, reported.CacheReadTokens},
+		{"cache_write", local.CacheWriteTokens, reported.CacheWriteTokens},
+		{"output", local.OutputTokens, reported.OutputTokens},
+		{"total_input", local.InputTokens + local.CacheReadTokens + local.CacheWriteTokens,
+			reported.InputTokens + reported.CacheReadTokens + reported.CacheWriteTokens},
+	} {
+		mark := ""
+		if flagged[row.category] {
+			mark = "MISMATCH"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", row.category, locale.Int(row.local), locale.Int(row.reported), mark)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(flagged) == 0 {
+		_, err := fmt.Fprintf(stdout, "\nNo discrepancies beyond %g%%.\n", *tolerance*100)
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "\n%d categories differ by more than %g%%. Reported usage covers the whole organization in hourly buckets and can lag by several minutes, so other traffic or a recent run also explains a reported count above the local one.\n", len(flagged), *tolerance*100)
+	return err
+}
@@ -0,0 +1,83 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	. "github.com/fwojciec/pipe/cmd/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReconcile(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	iso := pipe.Locale{DateLayout: time.DateOnly, Location: time.UTC}
+
+	// reporter reports usage and records the period it was asked for.
+	reporter := func(usage pipe.Usage, start, end *time.Time) *mock.UsageReporter {
+		return &mock.UsageReporter{ReportedUsageFn: func(_ context.Context, s, e time.Time) (pipe.Usage, error) {
+			*start, *end = s, e
+			return usage, nil
+		}}
+	}
+
+	t.Run("usage log within tolerance", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "usage.jsonl")
+		require.NoError(t, pipejson.AppendRunRecord(path, pipe.RunRecord{Time: now.AddDate(0, 0, -3), Usage: pipe.Usage{InputTokens: 9999}}))
+		require.NoError(t, pipejson.AppendRunRecord(path, pipe.RunRecord{Time: now.Add(-time.Hour), Usage: pipe.Usage{InputTokens: 1000, OutputTokens: 50}}))
+
+		var start, end time.Time
+		var out bytes.Buffer
+		err := RunReconcileForTest(context.Background(), []string{"-log", path}, &out, now, iso,
+			reporter(pipe.Usage{InputTokens: 1010, OutputTokens: 50}, &start, &end))
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(-24*time.Hour), start)
+		assert.Equal(t, now, end)
+		assert.NotContains(t, out.String(), "MISMATCH")
+		assert.Contains(t, out.String(), "No discrepancies beyond 5%.")
+	})
+
+	t.Run("session with a discrepancy", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		session := pipe.Session{
+			ID:        "s1",
+			CreatedAt: now.Add(-2 * time.Hour),
+			UpdatedAt: now.Add(-time.Hour),
+			Messages: []pipe.Message{
+				pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}},
+				pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hello"}}, Usage: pipe.Usage{InputTokens: 5000, CacheReadTokens: 4000, OutputTokens: 20}},
+			},
+		}
+		require.NoError(t, pipejson.Save(path, session))
+
+		var start, end time.Time
+		var out bytes.Buffer
+		err := RunReconcileForTest(context.Background(), []string{"-session", path, "-tolerance", "0.01"}, &out, now, iso,
+			reporter(pipe.Usage{InputTokens: 1000, CacheReadTokens: 4000, OutputTokens: 20}, &start, &end))
+		require.NoError(t, err)
+		assert.Equal(t, session.CreatedAt, start.UTC())
+		assert.Equal(t, session.UpdatedAt, end.UTC())
+
+		report := out.String()
+		assert.Regexp(t, `input\s+5,000\s+1,000\s+MISMATCH`, report)
+		assert.Regexp(t, `total_input\s+9,000\s+5,000\s+MISMATCH`, report)
+		assert.Regexp(t, `cache_read\s+4,000\s+4,000\s*\n`, report)
+		assert.Contains(t, report, "2 categories differ by more than 1%.")
+	})
+
+	t.Run("rejects arguments", func(t *testing.T) {
+		t.Parallel()
+		var out bytes.Buffer
+		err := RunReconcileForTest(context.Background(), []string{"extra"}, &out, now, iso, &mock.UsageReporter{})
+		require.EqualError(t, err, "usage: pipe usage reconcile [-session file | -last 1d [-log file]] [-tolerance 0.05]")
+	})
+}
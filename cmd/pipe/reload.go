@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+// reloadInterval is how often the prompt and config files are checked for
+// changes.
+const reloadInterval = time.Second
+
+// reloader applies edits to the system prompt and config files to later
+// runs, so iterating on a prompt does not need a restart. watch announces
+// changes as they are saved; they are applied when the next run starts, or
+// at once by /reload. Settings used to set up the session, such as
+// tool_limits, webhook_url, memory, and locale, still need a restart.
+type reloader struct {
+	promptPath string
+	configPath string
+	profiles   *profileSwitcher
+	session    *pipe.Session
+	incognito  bool // reloaded configs keep the -incognito adjustments
+
+	mu      sync.Mutex
+	cfg     config
+	seen    map[string]time.Time // modification times as last announced
+	applied map[string]time.Time // modification times as last applied
+}
+
+// newReloader creates a reloader for settings loaded from cfg and the
+// files as they are now.
+func newReloader(promptPath, configPath string, cfg config, profiles *profileSwitcher, session *pipe.Session) *reloader {
+	r := &reloader{promptPath: promptPath, configPath: configPath, profiles: profiles, session: session, cfg: cfg}
+	r.applied = r.stamps()
+	r.seen = r.stamps()
+	return r
+}
+
+// reloadState is the validated content of the files.
+type reloadState struct {
+	cfg    config
+	prompt *string // nil when the prompt file does not exist
+}
+
+// config returns the config to use for the next run.
+func (r *reloader) config() config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cfg
+}
+
+// files returns the watched files: the prompt, the config, and the active
+// profile's prompt, if it has one.
+func (r *reloader) files() []string {
+	files := []string{r.promptPath, r.configPath}
+	if p, ok := r.cfg.Profiles[r.profiles.settings().profile]; ok && p.SystemPrompt != "" {
+		files = append(files, p.SystemPrompt)
+	}
+	return files
+}
+
+// stamps returns the modification times of the watched files; missing
+// files have the zero time.
+func (r *reloader) stamps() map[string]time.Time {
+	stamps := make(map[string]time.Time)
+	for _, f := range r.files() {
+		var mod time.Time
+		if info, err := os.Stat(f); err == nil {
+			mod = info.ModTime()
+		}
+		stamps[f] = mod
+	}
+	return stamps
+}
+
+// changed returns the watched files whose modification times differ from
+// those in since.
+func (r *reloader) changed(since map[string]time.Time) []string {
+	var files []string
+	for f, mod := range r.stamps() {
+		if old, ok := since[f]; !ok || !old.Equal(mod) {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// watch checks the files every reloadInterval until ctx is done and sends
+// a notice for each change.
+func (r *reloader) watch(ctx context.Context, notices chan<- string) {
+	t := time.NewTicker(reloadInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if notice := r.poll(); notice != "" {
+			select {
+			case notices <- notice:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// poll returns a notice describing files changed since the last poll, or
+// "" when none changed. Changes that do not load are reported so they can
+// be fixed before the next run.
+func (r *reloader) poll() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	files := r.changed(r.seen)
+	if len(files) == 0 {
+		return ""
+	}
+	r.seen = r.stamps()
+	if _, err := r.read(); err != nil {
+		return fmt.Sprintf("%s changed but cannot be applied: %v. The previous settings stay in effect.", strings.Join(files, " and "), err)
+	}
+	return fmt.Sprintf("%s changed; the change applies from the next prompt (/reload applies it now).", strings.Join(files, " and "))
+}
+
+// read loads and validates the files.
+func (r *reloader) read() (reloadState, error) {
+	cfg, err := loadConfig(r.configPath)
+	if err != nil {
+		return reloadState{}, err
+	}
+	if r.incognito {
+		cfg = cfg.incognito()
+	}
+	if _, err := cfg.resolve(r.profiles.settings().profile, r.profiles.model); err != nil {
+		return reloadState{}, fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.approver(nil); err != nil {
+		return reloadState{}, fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.stylePrompt(); err != nil {
+		return reloadState{}, fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.providerOptions(); err != nil {
+		return reloadState{}, fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.roots(""); err != nil {
+		return reloadState{}, fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.env(); err != nil {
+		return reloadState{}, fmt.Errorf("config: %w", err)
+	}
+	if _, err := cfg.subAgent(nil); err != nil {
+		return reloadState{}, fmt.Errorf("config: %w", err)
+	}
+	st := reloadState{cfg: cfg}
+	data, err := os.ReadFile(r.promptPath)
+	switch {
+	case err == nil:
+		prompt := string(data)
+		st.prompt = &prompt
+	case !errors.Is(err, os.ErrNotExist):
+		return reloadState{}, fmt.Errorf("read system prompt: %w", err)
+	}
+	return st, nil
+}
+
+// applyChanges applies the files if they changed since they were last
+// applied. Changes that do not load are skipped; poll has reported them.
+// It is called before each run.
+func (r *reloader) applyChanges() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.changed(r.applied)) == 0 {
+		return
+	}
+	st, err := r.read()
+	if err != nil {
+		return
+	}
+	_, _ = r.apply(st, false)
+}
+
+// apply switches to the loaded settings. The session takes the active
+// profile's prompt if it sets one, else the prompt file when it changed or
+// force is set, so an unchanged prompt file does not replace the prompt of
+// a resumed session.
+func (r *reloader) apply(st reloadState, force bool) (runSettings, error) {
+	promptChanged := force || !r.applied[r.promptPath].Equal(r.stamps()[r.promptPath])
+	settings, err := r.profiles.reconfigure(st.cfg)
+	if err != nil {
+		return runSettings{}, err
+	}
+	r.cfg = st.cfg
+	switch {
+	case settings.systemPrompt != "":
+		r.session.SystemPrompt = settings.systemPrompt
+	case promptChanged && st.prompt != nil:
+		r.session.SystemPrompt = *st.prompt
+	}
+	if promptChanged && st.prompt != nil {
+		r.profiles.setPrompt(*st.prompt)
+	}
+	r.applied = r.stamps()
+	r.seen = r.applied
+	return settings, nil
+}
+
+// command implements /reload: it applies the prompt and config files now.
+func (r *reloader) command(string) (bt.CommandResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st, err := r.read()
+	if err != nil {
+		return bt.CommandResult{}, fmt.Errorf("reload: %w", err)
+	}
+	settings, err := r.apply(st, true)
+	if err != nil {
+		return bt.CommandResult{}, fmt.Errorf("reload: %w", err)
+	}
+	return bt.CommandResult{
+		Notice:    fmt.Sprintf("Reloaded %s and %s.", r.promptPath, r.configPath),
+		ModelName: r.profiles.effectiveModel(settings.model),
+		Pricing:   r.profiles.pricing(settings.model),
+	}, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader(t *testing.T) {
+	t.Parallel()
+	start := time.Now().Add(-time.Hour)
+
+	// setup writes the prompt and config files and returns a reloader for
+	// a session using them.
+	setup := func(t *testing.T) (*reloader, *pipe.Session, string, string) {
+		t.Helper()
+		dir := t.TempDir()
+		promptPath := filepath.Join(dir, "prompt.md")
+		configPath := filepath.Join(dir, "config.json")
+		writeAt(t, promptPath, "be terse", start)
+		writeAt(t, configPath, `{"context_budget": 1000}`, start)
+		cfg, err := loadConfig(configPath)
+		require.NoError(t, err)
+		settings, err := cfg.resolve("", "")
+		require.NoError(t, err)
+		session := &pipe.Session{SystemPrompt: "be terse"}
+		profiles := &profileSwitcher{cfg: cfg, session: session, current: settings}
+		return newReloader(promptPath, configPath, cfg, profiles, session), session, promptPath, configPath
+	}
+
+	t.Run("announces and applies changes before the next run", func(t *testing.T) {
+		t.Parallel()
+		r, session, promptPath, configPath := setup(t)
+		assert.Empty(t, r.poll())
+
+		writeAt(t, promptPath, "be verbose", start.Add(time.Minute))
+		assert.Contains(t, r.poll(), "prompt.md changed; the change applies from the next prompt")
+		assert.Empty(t, r.poll(), "each change is announced once")
+		assert.Equal(t, "be terse", session.SystemPrompt, "nothing applies mid-run")
+
+		r.applyChanges()
+		assert.Equal(t, "be verbose", session.SystemPrompt)
+
+		writeAt(t, configPath, `{"context_budget": 2000}`, start.Add(time.Minute))
+		r.applyChanges()
+		assert.Equal(t, 2000, r.config().ContextBudget)
+	})
+
+	t.Run("keeps the previous settings when a change is invalid", func(t *testing.T) {
+		t.Parallel()
+		r, _, _, configPath := setup(t)
+		writeAt(t, configPath, `{"approve": ["teleport"]}`, start.Add(time.Minute))
+		assert.Contains(t, r.poll(), `cannot be applied: config: approve: unknown tool "teleport"`)
+
+		r.applyChanges()
+		assert.Equal(t, 1000, r.config().ContextBudget)
+
+		_, err := r.command("")
+		require.EqualError(t, err, `reload: config: approve: unknown tool "teleport"`)
+	})
+
+	t.Run("config changes keep the session prompt", func(t *testing.T) {
+		t.Parallel()
+		r, session, _, configPath := setup(t)
+		session.SystemPrompt = "resumed prompt"
+		writeAt(t, configPath, `{"context_budget": 3000}`, start.Add(time.Minute))
+		r.applyChanges()
+		assert.Equal(t, "resumed prompt", session.SystemPrompt)
+		assert.Equal(t, 3000, r.config().ContextBudget)
+	})
+
+	t.Run("/reload applies the files now", func(t *testing.T) {
+		t.Parallel()
+		r, session, _, _ := setup(t)
+		session.SystemPrompt = "resumed prompt"
+		res, err := r.command("")
+		require.NoError(t, err)
+		assert.Contains(t, res.Notice, "Reloaded ")
+		assert.Equal(t, "be terse", session.SystemPrompt)
+	})
+}
+
+// writeAt writes data to path and sets its modification time to mod, so
+// changes register regardless of the file system's timestamp resolution.
+func writeAt(t *testing.T, path, data string, mod time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	require.NoError(t, os.Chtimes(path, mod, mod))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+	pipejson "github.com/fwojciec/pipe/json"
+)
+
+// sessionEntries summarizes the saved sessions in dir for the resume
+// picker, most recently updated first. Files that do not load as sessions
+// are skipped.
+func sessionEntries(dir string) ([]bt.SessionEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var entries []bt.SessionEntry
+	for _, path := range paths {
+		s, err := pipejson.Load(path, pipejson.WithPreserveUnknown())
+		if err != nil {
+			continue
+		}
+		entries = append(entries, bt.SessionEntry{
+			Path:      path,
+			UpdatedAt: s.UpdatedAt,
+			Preview:   firstPrompt(s),
+			Tokens:    sessionTokens(s),
+		})
+	}
+	slices.SortStableFunc(entries, func(a, b bt.SessionEntry) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return entries, nil
+}
+
+// firstPrompt returns the text of the first user message in s.
+func firstPrompt(s pipe.Session) string {
+	for _, msg := range s.Messages {
+		if um, ok := msg.(pipe.UserMessage); ok {
+			for _, b := range um.Content {
+				if tb, ok := b.(pipe.TextBlock); ok {
+					return tb.Text
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// sessionTokens totals the input and output tokens of the requests made in
+// s.
+func sessionTokens(s pipe.Session) int {
+	u := s.TotalUsage()
+	return u.InputTokens + u.CacheReadTokens + u.CacheWriteTokens + u.OutputTokens
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionEntries(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	save := func(name string, s pipe.Session) {
+		require.NoError(t, pipejson.Save(filepath.Join(dir, name), s))
+	}
+	save("old.json", pipe.Session{ID: "old", UpdatedAt: at, Messages: []pipe.Message{
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "first"}}},
+		pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 100, CacheReadTokens: 20, OutputTokens: 5}},
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "second"}}},
+		pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 10, OutputTokens: 5}},
+	}})
+	save("new.json", pipe.Session{ID: "new", UpdatedAt: at.Add(time.Hour)})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o600))
+
+	entries, err := sessionEntries(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, filepath.Join(dir, "new.json"), entries[0].Path)
+	assert.Equal(t, filepath.Join(dir, "old.json"), entries[1].Path)
+	assert.Equal(t, "first", entries[1].Preview)
+	assert.Equal(t, 140, entries[1].Tokens)
+}
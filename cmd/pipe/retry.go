@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+// retrier implements /retry. It records the model and temperature overrides
+// given to the command; the run the command starts takes them.
+type retrier struct {
+	session *pipe.Session
+
+	mu          sync.Mutex
+	model       string
+	temperature *float64
+}
+
+// command re-requests the latest assistant turn. "--model NAME" and
+// "--temperature T" apply to the retried run only.
+func (r *retrier) command(args string) (bt.CommandResult, error) {
+	flags := flag.NewFlagSet("retry", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	model := flags.String("model", "", "")
+	temperature := flags.Float64("temperature", -1, "")
+	if err := flags.Parse(strings.Fields(args)); err != nil {
+		return bt.CommandResult{}, fmt.Errorf("usage: /retry [--model NAME] [--temperature T]: %w", err)
+	}
+	if flags.NArg() > 0 {
+		return bt.CommandResult{}, errors.New("usage: /retry [--model NAME] [--temperature T]")
+	}
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if set["temperature"] && (*temperature < 0 || *temperature > 2) {
+		return bt.CommandResult{}, fmt.Errorf("temperature must be in [0, 2], got %g", *temperature)
+	}
+	if r.session.LastPrompt() < 0 {
+		return bt.CommandResult{}, errors.New("nothing to retry: the session has no prompt")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.model = *model
+	r.temperature = nil
+	notice := "Retrying the last turn"
+	if *model != "" {
+		notice += " with " + *model
+	}
+	if set["temperature"] {
+		t := *temperature
+		r.temperature = &t
+		notice += fmt.Sprintf(" at temperature %g", t)
+	}
+	return bt.CommandResult{Notice: notice + ".", Retry: true}, nil
+}
+
+// take returns the pending overrides and clears them, so they apply to one
+// run. An empty model and nil temperature mean no override.
+func (r *retrier) take() (model string, temperature *float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	model, temperature = r.model, r.temperature
+	r.model, r.temperature = "", nil
+	return model, temperature
+}
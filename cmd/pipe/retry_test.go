@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrier(t *testing.T) {
+	t.Parallel()
+
+	prompted := func() *pipe.Session {
+		return &pipe.Session{Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}},
+			pipe.AssistantMessage{},
+		}}
+	}
+
+	t.Run("overrides apply to one run", func(t *testing.T) {
+		t.Parallel()
+		r := &retrier{session: prompted()}
+		res, err := r.command("--model opus --temperature 0.3")
+		require.NoError(t, err)
+		assert.True(t, res.Retry)
+		assert.Equal(t, "Retrying the last turn with opus at temperature 0.3.", res.Notice)
+
+		model, temp := r.take()
+		assert.Equal(t, "opus", model)
+		require.NotNil(t, temp)
+		assert.InDelta(t, 0.3, *temp, 1e-9)
+
+		model, temp = r.take()
+		assert.Empty(t, model)
+		assert.Nil(t, temp)
+	})
+
+	t.Run("plain retry has no overrides", func(t *testing.T) {
+		t.Parallel()
+		r := &retrier{session: prompted()}
+		res, err := r.command("")
+		require.NoError(t, err)
+		assert.Equal(t, "Retrying the last turn.", res.Notice)
+		model, temp := r.take()
+		assert.Empty(t, model)
+		assert.Nil(t, temp)
+	})
+
+	t.Run("rejects bad input without setting overrides", func(t *testing.T) {
+		t.Parallel()
+		r := &retrier{session: prompted()}
+		_, err := r.command("--temperature 3")
+		assert.ErrorContains(t, err, "temperature must be in [0, 2]")
+		_, err = r.command("--colour blue")
+		assert.ErrorContains(t, err, "usage: /retry")
+
+		r.session = &pipe.Session{}
+		_, err = r.command("--model opus")
+		assert.ErrorContains(t, err, "nothing to retry")
+
+		model, _ := r.take()
+		assert.Empty(t, model)
+	})
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fwojciec/pipe"
+)
+
+// rootPath returns the path argument of a tool that takes a target root,
+// and whether it takes one. Bash has no path argument; its command runs in
+// the root instead.
+func rootPath(tool string) (string, bool) {
+	switch tool {
+	case "read", "write", "edit":
+		return "file_path", true
+	case "grep", "glob":
+		return "path", true
+	case "run_tests":
+		return "dir", true
+	case "bash":
+		return "", true
+	}
+	return "", false
+}
+
+// workspaceRoot is a named directory of a multi-root workspace.
+type workspaceRoot struct {
+	dir  string // as configured, relative to the workspace
+	abs  string
+	real string // abs with symlinks resolved
+}
+
+// workspaceRoots are the named roots of a workspace split across
+// directories, e.g. "frontend" and "backend". A tool call naming a root
+// resolves its relative paths in that root and may not reach outside it,
+// even through symlinks; calls that name none work on the whole workspace as
+// before. A bash command naming a root only starts in it: the shell can still
+// reach anything outside, so roots are not a sandbox.
+type workspaceRoots struct {
+	roots  map[string]workspaceRoot
+	active *activeRoot // nil tracks nothing
+}
+
+// activeRoot is the root of the latest tool call that named one. It
+// outlives the roots of a single run, which a config reload may change.
+type activeRoot struct {
+	mu   sync.Mutex
+	name string
+}
+
+func (a *activeRoot) set(name string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.name = name
+}
+
+// label returns the active root for the status bar, or "" when no call has
+// named one.
+func (a *activeRoot) label() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.name == "" {
+		return ""
+	}
+	return "root: " + a.name
+}
+
+// newWorkspaceRoots returns the roots dirs names, resolving relative dirs
+// against base, or the working directory when base is empty. Every dir must
+// exist.
+func newWorkspaceRoots(base string, dirs map[string]string) (*workspaceRoots, error) {
+	r := &workspaceRoots{roots: make(map[string]workspaceRoot, len(dirs))}
+	for _, name := range slices.Sorted(maps.Keys(dirs)) {
+		dir := dirs[name]
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("roots: name is required")
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("roots: %s: directory is required", name)
+		}
+		abs := dir
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(base, abs)
+		}
+		abs, err := filepath.Abs(abs)
+		if err != nil {
+			return nil, fmt.Errorf("roots: %s: %w", name, err)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("roots: %s: %w", name, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("roots: %s: %s is not a directory", name, dir)
+		}
+		real, err := filepath.EvalSymlinks(abs)
+		if err != nil {
+			return nil, fmt.Errorf("roots: %s: %w", name, err)
+		}
+		r.roots[name] = workspaceRoot{dir: dir, abs: abs, real: real}
+	}
+	return r, nil
+}
+
+// resolve returns the args of a call to the named tool with its root
+// argument applied: the path made absolute within the root, or a bash
+// command run from it. It returns a message for the model when the root is
+// unknown or the path, once symlinks are resolved, is outside it. Calls that
+// name no root, and tools that take none, pass through unchanged.
+func (r *workspaceRoots) resolve(tool string, args json.RawMessage) (json.RawMessage, string) {
+	key, ok := rootPath(tool)
+	if !ok {
+		return args, ""
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(args, &fields); err != nil {
+		return args, "" // the tool reports malformed args itself
+	}
+	var name string
+	if raw, ok := fields["root"]; !ok {
+		return args, ""
+	} else if err := json.Unmarshal(raw, &name); err != nil || name == "" {
+		return args, "root must be the name of a workspace root"
+	}
+	root, ok := r.roots[name]
+	if !ok {
+		return args, fmt.Sprintf("unknown root %q; the roots are %s", name, strings.Join(slices.Sorted(maps.Keys(r.roots)), ", "))
+	}
+	delete(fields, "root")
+
+	if key == "" {
+		var command string
+		if err := json.Unmarshal(fields["command"], &command); err == nil && command != "" {
+			fields["command"], _ = json.Marshal("cd " + shellWord(root.dir) + " && " + command)
+		}
+	} else {
+		var path string
+		_ = json.Unmarshal(fields[key], &path)
+		switch {
+		case path == "" && key == "file_path":
+			// Left for the tool to report as missing.
+		case path == "":
+			fields[key], _ = json.Marshal(root.abs)
+		default:
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(root.abs, path)
+			}
+			path = filepath.Clean(path)
+			if !within(root.real, realPath(path)) {
+				return args, fmt.Sprintf("%s is outside root %s (%s)", path, name, root.dir)
+			}
+			fields[key], _ = json.Marshal(path)
+		}
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return args, fmt.Sprintf("invalid arguments: %s", err)
+	}
+	r.active.set(name)
+	return out, ""
+}
+
+// realPath returns path with symlinks resolved. Path elements that do not
+// exist yet, such as a file about to be written, are kept as they are.
+func realPath(path string) string {
+	var rest []string
+	for {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(append([]string{path}, rest...)...)
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
+}
+
+// within reports whether path is dir or inside it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// withRootParam returns tools with an optional root argument added to those
+// that take one, listing the root names the model may pass.
+func withRootParam(tools []pipe.Tool, dirs map[string]string) []pipe.Tool {
+	if len(dirs) == 0 {
+		return tools
+	}
+	var names, listed []string
+	for _, name := range slices.Sorted(maps.Keys(dirs)) {
+		names = append(names, name)
+		listed = append(listed, fmt.Sprintf("%s (%s)", name, dirs[name]))
+	}
+	param := map[string]any{
+		"type": "string",
+		"enum": names,
+		"description": "Workspace root to work in: " + strings.Join(listed, ", ") +
+			". Relative paths resolve in it and paths outside it are refused; bash commands start in it but are not confined to it. Omit to use the whole workspace.",
+	}
+	out := make([]pipe.Tool, len(tools))
+	for i, t := range tools {
+		out[i] = t
+		if _, ok := rootPath(t.Name); !ok {
+			continue
+		}
+		var schema map[string]any
+		if err := json.Unmarshal(t.Parameters, &schema); err != nil {
+			continue
+		}
+		props, _ := schema["properties"].(map[string]any)
+		if props == nil {
+			props = make(map[string]any)
+			schema["properties"] = props
+		}
+		props["root"] = param
+		if params, err := json.Marshal(schema); err == nil {
+			out[i].Parameters = params
+		}
+	}
+	return out
+}
+
+// shellWord quotes s as a single POSIX shell word.
+func shellWord(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceRoots_Resolve(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	front := filepath.Join(dir, "web")
+	require.NoError(t, os.Mkdir(front, 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "server"), 0o755))
+	roots, err := newWorkspaceRoots("", map[string]string{"frontend": front, "backend": filepath.Join(dir, "server")})
+	require.NoError(t, err)
+	roots.active = &activeRoot{}
+
+	resolve := func(tool, args string) (map[string]any, string) {
+		t.Helper()
+		out, msg := roots.resolve(tool, json.RawMessage(args))
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal(out, &fields))
+		return fields, msg
+	}
+
+	t.Run("relative path resolves in the root", func(t *testing.T) {
+		t.Parallel()
+		fields, msg := resolve("read", `{"file_path": "src/app.ts", "root": "frontend"}`)
+		assert.Empty(t, msg)
+		assert.Equal(t, map[string]any{"file_path": filepath.Join(front, "src", "app.ts")}, fields)
+	})
+	t.Run("path outside the root is refused", func(t *testing.T) {
+		t.Parallel()
+		_, msg := resolve("edit", `{"file_path": "../server/main.go", "root": "frontend"}`)
+		assert.Contains(t, msg, "is outside root frontend")
+		_, msg = resolve("write", `{"file_path": "/etc/passwd", "root": "frontend"}`)
+		assert.Contains(t, msg, "is outside root frontend")
+	})
+	t.Run("symlink out of the root is refused", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, os.Symlink(filepath.Join(dir, "server"), filepath.Join(front, "escape")))
+		_, msg := resolve("read", `{"file_path": "escape/main.go", "root": "frontend"}`)
+		assert.Contains(t, msg, "is outside root frontend")
+		_, msg = resolve("write", `{"file_path": "escape/new/file.go", "root": "frontend"}`)
+		assert.Contains(t, msg, "is outside root frontend")
+	})
+	t.Run("new file in the root is allowed", func(t *testing.T) {
+		t.Parallel()
+		fields, msg := resolve("write", `{"file_path": "new/dir/file.ts", "root": "frontend"}`)
+		assert.Empty(t, msg)
+		assert.Equal(t, filepath.Join(front, "new", "dir", "file.ts"), fields["file_path"])
+	})
+	t.Run("search defaults to the root", func(t *testing.T) {
+		t.Parallel()
+		fields, msg := resolve("grep", `{"pattern": "TODO", "root": "frontend"}`)
+		assert.Empty(t, msg)
+		assert.Equal(t, front, fields["path"])
+	})
+	t.Run("bash runs in the root", func(t *testing.T) {
+		t.Parallel()
+		fields, msg := resolve("bash", `{"command": "make", "root": "frontend"}`)
+		assert.Empty(t, msg)
+		assert.Equal(t, "cd "+shellWord(front)+" && make", fields["command"])
+	})
+	t.Run("unknown root", func(t *testing.T) {
+		t.Parallel()
+		_, msg := resolve("read", `{"file_path": "a", "root": "docs"}`)
+		assert.Equal(t, `unknown root "docs"; the roots are backend, frontend`, msg)
+	})
+	t.Run("no root passes through", func(t *testing.T) {
+		t.Parallel()
+		fields, msg := resolve("read", `{"file_path": "../anywhere"}`)
+		assert.Empty(t, msg)
+		assert.Equal(t, "../anywhere", fields["file_path"])
+	})
+}
+
+func TestNewWorkspaceRoots_RejectsMissingDir(t *testing.T) {
+	t.Parallel()
+	_, err := newWorkspaceRoots("", map[string]string{"frontend": filepath.Join(t.TempDir(), "missing")})
+	assert.ErrorContains(t, err, "roots: frontend")
+}
+
+func TestExecutor_RootScopesFileTools(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello\n"), 0o644))
+	roots, err := newWorkspaceRoots("", map[string]string{"docs": dir})
+	require.NoError(t, err)
+	active := &activeRoot{}
+	roots.active = active
+	e := &executor{bash: pipeexec.NewBashExecutor(), roots: roots}
+
+	result, err := e.Execute(context.Background(), "read", json.RawMessage(`{"file_path": "notes.txt", "root": "docs"}`))
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Contains(t, result.Content[0].(pipe.TextBlock).Text, "hello")
+	assert.Equal(t, "root: docs", active.label())
+
+	result, err = e.Execute(context.Background(), "read", json.RawMessage(`{"file_path": "../secret", "root": "docs"}`))
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
+func TestWithRootParam(t *testing.T) {
+	t.Parallel()
+	tools := withRootParam(tools(), map[string]string{"frontend": "web"})
+	for _, tool := range tools {
+		var schema struct {
+			Properties map[string]struct {
+				Enum []string `json:"enum"`
+			} `json:"properties"`
+		}
+		require.NoError(t, json.Unmarshal(tool.Parameters, &schema))
+		root, ok := schema.Properties["root"]
+		_, rooted := rootPath(tool.Name)
+		assert.Equal(t, rooted, ok, tool.Name)
+		if ok {
+			assert.Equal(t, []string{"frontend"}, root.Enum)
+		}
+	}
+}
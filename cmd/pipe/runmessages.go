@@ -0,0 +1,38 @@
+package main
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/fwojciec/pipe"
+)
+
+// runMessages collects the messages the latest run appended to the
+// session. The session's history cannot tell them apart afterwards:
+// compaction may rewrite it mid-run, summarizing the run's own messages
+// away.
+type runMessages struct {
+	mu   sync.Mutex
+	msgs []pipe.Message
+}
+
+// begin starts collecting the messages of a new run.
+func (r *runMessages) begin() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = nil
+}
+
+// add records a message the run appended.
+func (r *runMessages) add(msg pipe.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msg)
+}
+
+// messages returns the messages of the latest run, in order.
+func (r *runMessages) messages() []pipe.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.msgs)
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/fwojciec/pipe/fs"
+	pipejson "github.com/fwojciec/pipe/json"
+)
+
+const runsUsage = "usage: pipe runs list|exec [-dir sessions] [-config file] | pipe runs cancel [-dir sessions] ID"
+
+// scheduledRunner executes one scheduled run of a session, appending the
+// run's messages to it.
+type scheduledRunner func(ctx context.Context, s *pipe.Session, r pipe.ScheduledRun) error
+
+// runsLoader loads the config file at path for the runs subcommand,
+// returning the locale to report in and the runner for scheduled runs.
+type runsLoader func(path string) (pipe.Locale, scheduledRunner, error)
+
+// runRuns implements "pipe runs": list, cancel, or execute the follow-up
+// runs scheduled in the saved sessions under a directory, with the config
+// file load reads.
+func runRuns(ctx context.Context, args []string, stdout io.Writer, now time.Time, load runsLoader) error {
+	if len(args) == 0 {
+		return errors.New(runsUsage)
+	}
+	flags := flag.NewFlagSet("runs "+args[0], flag.ContinueOnError)
+	dir := flags.String("dir", defaultSessionDir(), "Directory of saved sessions")
+	configPath := flags.String("config", defaultConfigPath, "Path to config file")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	locale, execute, err := load(*configPath)
+	if err != nil {
+		return err
+	}
+	switch {
+	case args[0] == "list" && flags.NArg() == 0:
+		return listRuns(*dir, stdout, locale)
+	case args[0] == "cancel" && flags.NArg() == 1:
+		return cancelRun(*dir, flags.Arg(0), stdout)
+	case args[0] == "exec" && flags.NArg() == 0:
+		return execRuns(ctx, *dir, stdout, now, execute)
+	default:
+		return errors.New(runsUsage)
+	}
+}
+
+// loadRunsConfig is the runsLoader of the pipe command.
+func loadRunsConfig(path string) (pipe.Locale, scheduledRunner, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return pipe.Locale{}, nil, err
+	}
+	locale, err := cfg.locale(os.Getenv)
+	if err != nil {
+		return pipe.Locale{}, nil, fmt.Errorf("config: %w", err)
+	}
+	getenv := withStoredKeys(os.Getenv, systemKeyring(), newProviderRegistry(&cfg, nil).Backends())
+	return locale, headlessRunner(cfg, getenv), nil
+}
+
+// scheduledSessions returns the paths of the sessions in dir that have
+// scheduled runs, with the sessions themselves. Files that do not load are
+// skipped and reported to stdout, so one bad file does not hide the runs of
+// the others.
+func scheduledSessions(dir string, stdout io.Writer) ([]string, []pipe.Session, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		withRuns []string
+		sessions []pipe.Session
+	)
+	for _, path := range paths {
+		s, err := pipejson.Load(path, pipejson.WithPreserveUnknown())
+		if err != nil {
+			if _, err := fmt.Fprintf(stdout, "Skipping %s: %v\n", path, err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if len(s.Schedules) > 0 {
+			withRuns = append(withRuns, path)
+			sessions = append(sessions, s)
+		}
+	}
+	return withRuns, sessions, nil
+}
+
+func listRuns(dir string, stdout io.Writer, locale pipe.Locale) error {
+	_, sessions, err := scheduledSessions(dir, stdout)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		_, err := fmt.Fprintln(stdout, "No scheduled runs.")
+		return err
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSESSION\tNEXT\tEVERY\tDIR\tPROMPT")
+	for _, s := range sessions {
+		for _, r := range s.Schedules {
+			every := "once"
+			if r.Every > 0 {
+				every = r.Every.String()
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, s.ID, locale.DateTime(r.Next), every, r.Dir, truncatePrompt(r.Prompt, 40))
+		}
+	}
+	return tw.Flush()
+}
+
+func cancelRun(dir, id string, stdout io.Writer) error {
+	paths, sessions, err := scheduledSessions(dir, stdout)
+	if err != nil {
+		return err
+	}
+	for i, s := range sessions {
+		if !slices.ContainsFunc(s.Schedules, func(r pipe.ScheduledRun) bool { return r.ID == id }) {
+			continue
+		}
+		err := updateSession(paths[i], func(s *pipe.Session) {
+			s.Schedules = slices.DeleteFunc(s.Schedules, func(r pipe.ScheduledRun) bool { return r.ID == id })
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "Cancelled run %s.\n", id)
+		return err
+	}
+	return fmt.Errorf("no scheduled run %q", id)
+}
+
+// execRuns executes every run due at now. A run is rescheduled, or removed
+// when it runs once, before it executes, so a failing run is not retried
+// until its next time. Sessions open in another pipe process are skipped.
+func execRuns(ctx context.Context, dir string, stdout io.Writer, now time.Time, execute scheduledRunner) error {
+	paths, _, err := scheduledSessions(dir, stdout)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, path := range paths {
+		if err := execSessionRuns(ctx, path, stdout, now, execute); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func execSessionRuns(ctx context.Context, path string, stdout io.Writer, now time.Time, execute scheduledRunner) error {
+	lock, err := fs.Lock(path)
+	var locked *fs.LockedError
+	if errors.As(err, &locked) {
+		_, err := fmt.Fprintf(stdout, "Skipping %s: session in use.\n", path)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	s, err := pipejson.Load(path, pipejson.WithPreserveUnknown())
+	if err != nil {
+		return err
+	}
+	var due []pipe.ScheduledRun
+	kept := s.Schedules[:0]
+	for _, r := range s.Schedules {
+		if !r.Due(now) {
+			kept = append(kept, r)
+			continue
+		}
+		due = append(due, r)
+		if r.Advance(now) {
+			kept = append(kept, r)
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+	s.Schedules = kept
+	if err := pipejson.Save(path, s); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, r := range due {
+		err := execute(ctx, &s, r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("run %s: %w", r.ID, err))
+			fmt.Fprintf(stdout, "Run %s (session %s) failed: %v\n", r.ID, s.ID, err)
+		} else {
+			fmt.Fprintf(stdout, "Ran %s (session %s).\n", r.ID, s.ID)
+		}
+		// Save after each run so its messages, and any runs it scheduled,
+		// are kept even if a later run fails.
+		if err := pipejson.Save(path, s); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// updateSession applies fn to the session at path while holding its lock.
+func updateSession(path string, fn func(*pipe.Session)) error {
+	lock, err := lockSession(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	s, err := pipejson.Load(path, pipejson.WithPreserveUnknown())
+	if err != nil {
+		return err
+	}
+	fn(&s)
+	return pipejson.Save(path, s)
+}
+
+// scheduledPrompt returns the user prompt for a scheduled run, running its
+// after command first when it has one. The command is a bash call: approve,
+// when set, decides whether it runs, as it does for the run's tool calls.
+func scheduledPrompt(ctx context.Context, r pipe.ScheduledRun, approve func(context.Context, pipe.ToolCallBlock) (pipe.Decision, error)) string {
+	if r.AfterCommand == "" {
+		return r.Prompt
+	}
+	if approve != nil {
+		input, _ := json.Marshal(map[string]string{"command": r.AfterCommand})
+		decision, err := approve(ctx, pipe.ToolCallBlock{Name: "bash", Arguments: input})
+		if err != nil || (decision != pipe.DecisionAllow && decision != pipe.DecisionAlwaysAllow) {
+			return fmt.Sprintf("%s\n\n<after_command skipped=\"not approved\">\n$ %s\n</after_command>", r.Prompt, r.AfterCommand)
+		}
+	}
+	cmd := osexec.CommandContext(ctx, "bash", "-c", r.AfterCommand)
+	cmd.Dir = r.Dir
+	out, err := cmd.CombinedOutput()
+	exitCode := 0
+	var exitErr *osexec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case err != nil:
+		out = append(out, err.Error()...)
+		exitCode = -1
+	}
+	tr := pipeexec.TruncateTail(string(out), pipeexec.DefaultMaxLines, pipeexec.DefaultMaxBytes)
+	return fmt.Sprintf("%s\n\n<after_command exit_code=\"%d\">\n$ %s\n%s\n</after_command>",
+		r.Prompt, exitCode, r.AfterCommand, strings.TrimSuffix(tr.Content, "\n"))
+}
+
+// headlessRunner returns a scheduledRunner that runs the model with the
+// built-in tools and the default profile's settings. Tools that need a
+// user, such as ask_user, are left out.
+func headlessRunner(cfg config, getenv func(string) string) scheduledRunner {
+	var providerName, defaultModel string // set by provider
+	provider := sync.OnceValues(func() (pipe.Provider, error) {
+		providers := newProviderRegistry(&cfg, nil)
+		providerCfg, err := resolveConfig(providers, "", "", getenv)
+		if err != nil {
+			return nil, err
+		}
+		providerName = providerCfg.name
+		if b, ok := providers.Lookup(providerName); ok {
+			defaultModel = b.DefaultModel
+		}
+		return newProvider(providers, providerCfg)
+	})
+	bash := pipeexec.NewBashExecutor()
+	mem := newMemory(cfg, getenv)
+	return func(ctx context.Context, s *pipe.Session, r pipe.ScheduledRun) error {
+		p, err := provider()
+		if err != nil {
+			return err
+		}
+		st, err := cfg.resolve("", "")
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		st.tools = slices.DeleteFunc(st.tools, func(t pipe.Tool) bool { return t.Name == "ask_user" })
+		// Nobody is there to answer approval prompts or cost
+		// confirmations, so they are denied as in print mode.
+		approve, err := cfg.approver(denyApproval)
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		// The run's tools work in its directory; the process's working
+		// directory is shared by every run and left alone.
+		wd, err := filepath.Abs(r.Dir)
+		if err != nil {
+			return err
+		}
+		bash.SetDir(wd)
+		shell, paths, err := cfg.shell(wd, func(container string) ([]pipeexec.DockerMount, error) {
+			return pipeexec.InspectDockerMounts(ctx, container)
+		})
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		bash.SetShell(shell)
+		bash.SetPathMap(paths)
+		if note := cfg.Exec.note(); note != "" && !strings.Contains(s.SystemPrompt, note) {
+			s.SystemPrompt += "\n\n" + note
+		}
+		roots, err := cfg.roots(wd)
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		env, err := cfg.env()
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		bash.SetEnv(env)
+		var artifactDir string
+		if !cfg.TempArtifacts {
+			artifactDir = defaultArtifactDir(s.ID)
+			bash.SetArtifactDir(artifactDir)
+		}
+
+		s.Messages = append(s.Messages, pipe.UserMessage{
+			Content:   []pipe.ContentBlock{pipe.TextBlock{Text: scheduledPrompt(ctx, r, approve)}},
+			Timestamp: time.Now(),
+		})
+		exec := &executor{
+			bash:        bash,
+			sched:       &scheduler{session: s, dir: wd, now: time.Now},
+			mem:         mem.in(wd),
+			roots:       roots,
+			env:         env,
+			allowed:     st.allowedTools(),
+			artifactDir: artifactDir,
+			dir:         wd,
+		}
+		var opts []pipe.RunOption
+		if st.model != "" {
+			opts = append(opts, pipe.WithModel(st.model))
+		}
+		if len(st.serverTools) > 0 {
+			opts = append(opts, pipe.WithServerTools(st.serverTools...))
+		}
+		if st.maxTokens > 0 {
+			opts = append(opts, pipe.WithMaxTokens(st.maxTokens))
+		}
+		if cfg.ContextBudget > 0 {
+			opts = append(opts, pipe.WithContextBudget(cfg.ContextBudget))
+		}
+		if cfg.ToolResultTurns > 0 {
+			opts = append(opts, pipe.WithToolResultTurns(cfg.ToolResultTurns))
+		}
+		if cfg.RunStatus {
+			opts = append(opts, pipe.WithRunStatus())
+		}
+		if cfg.MaxTurns > 0 {
+			opts = append(opts, pipe.WithMaxTurns(cfg.MaxTurns))
+		}
+		if cfg.MaxCostTokens > 0 {
+			opts = append(opts, pipe.WithMaxCostTokens(cfg.MaxCostTokens))
+		}
+		if n := cfg.loopDetection(); n > 0 {
+			opts = append(opts, pipe.WithLoopDetection(n))
+		}
+		opts = append(opts, pipe.WithRetry(cfg.retryPolicy(providerName)))
+		if cfg.ToolConcurrency > 1 {
+			opts = append(opts, pipe.WithToolConcurrency(cfg.ToolConcurrency))
+		}
+		if d, _ := cfg.toolTimeout(); d > 0 {
+			opts = append(opts, pipe.WithToolTimeout(d))
+		}
+		providerOpts, err := cfg.providerOptions()
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		opts = append(opts, pipe.WithProviderOptions(providerOpts))
+		if approve != nil {
+			opts = append(opts, pipe.WithApprover(approve))
+		}
+		guard := cfg.costGuard(cmp.Or(st.model, defaultModel), denyCost)
+		if guard != nil {
+			opts = append(opts, pipe.WithRequestApprover(guard))
+		}
+		loopExec := pipeexec.BoundResults(exec, cfg.maxToolResultBytes(), artifactDir)
+		agent, err := cfg.subAgent(st.tools)
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		if agent != nil {
+			agent.Provider, agent.Executor = p, loopExec
+			agent.Options = []pipe.RunOption{pipe.WithRetry(cfg.retryPolicy(providerName)), pipe.WithProviderOptions(providerOpts)}
+			if st.model != "" {
+				agent.Options = append(agent.Options, pipe.WithModel(st.model))
+			}
+			if d, _ := cfg.toolTimeout(); d > 0 {
+				agent.Options = append(agent.Options, pipe.WithToolTimeout(d))
+			}
+			if n := cfg.loopDetection(); n > 0 {
+				agent.Options = append(agent.Options, pipe.WithLoopDetection(n))
+			}
+			if approve != nil {
+				agent.Options = append(agent.Options, pipe.WithApprover(approve))
+			}
+			if cfg.MaxCostTokens > 0 {
+				agent.Options = append(agent.Options, pipe.WithMaxCostTokens(cfg.MaxCostTokens))
+			}
+			if guard != nil {
+				agent.Options = append(agent.Options, pipe.WithRequestApprover(guard))
+			}
+			exec.task = &taskRunner{agent: *agent}
+		}
+		return pipe.NewLoop(p, loopExec).Run(ctx, s, st.tools, opts...)
+	}
+}
+
+// truncatePrompt shortens a prompt to its first line, at most n runes.
+func truncatePrompt(s string, n int) string {
+	line, _, cut := strings.Cut(s, "\n")
+	r := []rune(line)
+	if len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	if cut {
+		return line + "…"
+	}
+	return line
+}
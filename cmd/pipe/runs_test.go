@@ -0,0 +1,147 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	. "github.com/fwojciec/pipe/cmd/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunRuns(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// writeSessions saves a session with two runs: "hourly", due now and
+	// repeating, and "later", a one-off due tomorrow.
+	writeSessions := func(t *testing.T) (dir, path string) {
+		t.Helper()
+		dir = t.TempDir()
+		path = filepath.Join(dir, "s1.json")
+		require.NoError(t, pipejson.Save(path, pipe.Session{ID: "s1", Schedules: []pipe.ScheduledRun{
+			{ID: "hourly", Prompt: "check the flaky test", Dir: "/src/app", Every: time.Hour, Next: now},
+			{ID: "later", Prompt: "investigate\nin detail", Dir: "/src/app", Next: now.Add(24 * time.Hour)},
+		}}))
+		require.NoError(t, pipejson.Save(filepath.Join(dir, "s2.json"), pipe.Session{ID: "s2"}))
+		return dir, path
+	}
+	noRun := func(context.Context, *pipe.Session, pipe.ScheduledRun) error {
+		return errors.New("unexpected run")
+	}
+
+	t.Run("list shows scheduled runs", func(t *testing.T) {
+		t.Parallel()
+		dir, _ := writeSessions(t)
+		var out bytes.Buffer
+		require.NoError(t, RunRunsForTest(context.Background(), []string{"list", "-dir", dir}, &out, now, noRun))
+
+		list := out.String()
+		assert.Contains(t, list, "ID")
+		assert.Regexp(t, `hourly\s+s1\s+03/01/2026 12:00 PM\s+1h0m0s\s+/src/app\s+check the flaky test`, list)
+		assert.Regexp(t, `later\s+s1\s+03/02/2026 12:00 PM\s+once\s+/src/app\s+investigate…`, list)
+		assert.NotContains(t, list, "s2")
+	})
+
+	t.Run("list without runs", func(t *testing.T) {
+		t.Parallel()
+		var out bytes.Buffer
+		require.NoError(t, RunRunsForTest(context.Background(), []string{"list", "-dir", t.TempDir()}, &out, now, noRun))
+		assert.Equal(t, "No scheduled runs.\n", out.String())
+	})
+
+	t.Run("cancel removes a run", func(t *testing.T) {
+		t.Parallel()
+		dir, path := writeSessions(t)
+		var out bytes.Buffer
+		require.NoError(t, RunRunsForTest(context.Background(), []string{"cancel", "-dir", dir, "later"}, &out, now, noRun))
+		assert.Equal(t, "Cancelled run later.\n", out.String())
+
+		s, err := pipejson.Load(path)
+		require.NoError(t, err)
+		require.Len(t, s.Schedules, 1)
+		assert.Equal(t, "hourly", s.Schedules[0].ID)
+
+		err = RunRunsForTest(context.Background(), []string{"cancel", "-dir", dir, "later"}, &out, now, noRun)
+		require.EqualError(t, err, `no scheduled run "later"`)
+	})
+
+	t.Run("exec runs due runs and reschedules them", func(t *testing.T) {
+		t.Parallel()
+		dir, path := writeSessions(t)
+		var ran []string
+		execute := func(_ context.Context, s *pipe.Session, r pipe.ScheduledRun) error {
+			ran = append(ran, r.ID)
+			s.Messages = append(s.Messages, pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: r.Prompt}}})
+			return nil
+		}
+		var out bytes.Buffer
+		require.NoError(t, RunRunsForTest(context.Background(), []string{"exec", "-dir", dir}, &out, now, execute))
+		assert.Equal(t, []string{"hourly"}, ran)
+		assert.Equal(t, "Ran hourly (session s1).\n", out.String())
+
+		s, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Len(t, s.Messages, 1)
+		require.Len(t, s.Schedules, 2)
+		assert.Equal(t, now.Add(time.Hour), s.Schedules[0].Next)
+	})
+
+	t.Run("exec removes a one-off run even when it fails", func(t *testing.T) {
+		t.Parallel()
+		dir, path := writeSessions(t)
+		failing := func(context.Context, *pipe.Session, pipe.ScheduledRun) error {
+			return errors.New("provider down")
+		}
+		var out bytes.Buffer
+		err := RunRunsForTest(context.Background(), []string{"exec", "-dir", dir}, &out, now.Add(24*time.Hour), failing)
+		require.ErrorContains(t, err, "provider down")
+		assert.Contains(t, out.String(), "Run later (session s1) failed: provider down")
+
+		s, err := pipejson.Load(path)
+		require.NoError(t, err)
+		require.Len(t, s.Schedules, 1)
+		assert.Equal(t, "hourly", s.Schedules[0].ID)
+	})
+
+	t.Run("exec skips sessions that do not load", func(t *testing.T) {
+		t.Parallel()
+		dir, _ := writeSessions(t)
+		bad := filepath.Join(dir, "bad.json")
+		require.NoError(t, os.WriteFile(bad, []byte("{not json"), 0o644))
+		var ran []string
+		execute := func(_ context.Context, _ *pipe.Session, r pipe.ScheduledRun) error {
+			ran = append(ran, r.ID)
+			return nil
+		}
+		var out bytes.Buffer
+		require.NoError(t, RunRunsForTest(context.Background(), []string{"exec", "-dir", dir}, &out, now, execute))
+		assert.Equal(t, []string{"hourly"}, ran)
+		assert.Contains(t, out.String(), "Skipping "+bad+":")
+		assert.Contains(t, out.String(), "Ran hourly (session s1).")
+	})
+
+	t.Run("reads the config file given", func(t *testing.T) {
+		t.Parallel()
+		path, err := RunsConfigPathForTest([]string{"list", "-dir", t.TempDir(), "-config", "ci.json"})
+		require.NoError(t, err)
+		assert.Equal(t, "ci.json", path)
+
+		path, err = RunsConfigPathForTest([]string{"list", "-dir", t.TempDir()})
+		require.NoError(t, err)
+		assert.Equal(t, ".pipe/config.json", path)
+	})
+
+	t.Run("rejects unknown subcommands", func(t *testing.T) {
+		t.Parallel()
+		err := RunRunsForTest(context.Background(), []string{"start"}, &bytes.Buffer{}, now, noRun)
+		require.ErrorContains(t, err, "usage: pipe runs")
+	})
+}
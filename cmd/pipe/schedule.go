@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// minScheduleInterval keeps a repeating run from hammering the provider.
+const minScheduleInterval = time.Minute
+
+// scheduleTool returns the definition of the schedule tool, which lets the
+// model plan follow-up runs of the current session.
+func scheduleTool() pipe.Tool {
+	return pipe.Tool{
+		Name: "schedule",
+		Description: "Schedule a follow-up run of this session, e.g. to check on a flaky test every hour " +
+			"or to investigate once CI finishes. The run executes headlessly with the given prompt; " +
+			"the user can list and cancel scheduled runs with \"pipe runs\".",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"prompt": {
+					"type": "string",
+					"description": "Instructions for the follow-up run"
+				},
+				"in": {
+					"type": "string",
+					"description": "Delay before the first run, e.g. 30m or 2h (default: now, or one interval when every is set)"
+				},
+				"every": {
+					"type": "string",
+					"description": "Repeat interval, e.g. 1h; omit for a one-off run (minimum 1m)"
+				},
+				"after_command": {
+					"type": "string",
+					"description": "Shell command to run first, e.g. one that waits for CI to finish; its exit code and output are included with the prompt"
+				}
+			},
+			"required": ["prompt"]
+		}`),
+	}
+}
+
+type scheduleArgs struct {
+	Prompt       string `json:"prompt"`
+	In           string `json:"in"`
+	Every        string `json:"every"`
+	AfterCommand string `json:"after_command"`
+}
+
+// scheduler adds scheduled runs to a session. The runs are persisted when
+// the session is saved.
+type scheduler struct {
+	session *pipe.Session
+	dir     string // working directory of scheduled runs
+	now     func() time.Time
+
+	mu sync.Mutex
+}
+
+// Execute implements the schedule tool.
+func (s *scheduler) Execute(_ context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
+	var a scheduleArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return toolError(fmt.Sprintf("invalid arguments: %s", err)), nil
+	}
+	if a.Prompt == "" {
+		return toolError("prompt is required"), nil
+	}
+	var delay, every time.Duration
+	if a.Every != "" {
+		d, err := time.ParseDuration(a.Every)
+		if err != nil || d < minScheduleInterval {
+			return toolError(fmt.Sprintf("invalid every %q: use a duration of at least %s, e.g. 1h", a.Every, minScheduleInterval)), nil
+		}
+		every, delay = d, d
+	}
+	if a.In != "" {
+		d, err := time.ParseDuration(a.In)
+		if err != nil || d < 0 {
+			return toolError(fmt.Sprintf("invalid in %q: use a duration, e.g. 30m", a.In)), nil
+		}
+		delay = d
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	run := pipe.ScheduledRun{
+		ID:           strconv.FormatInt(now.UnixNano(), 36),
+		Prompt:       a.Prompt,
+		Dir:          s.dir,
+		AfterCommand: a.AfterCommand,
+		Every:        every,
+		Next:         now.Add(delay),
+		CreatedAt:    now,
+	}
+	s.session.Schedules = append(s.session.Schedules, run)
+
+	text := fmt.Sprintf("Scheduled run %s for %s", run.ID, run.Next.Format(time.RFC3339))
+	if every > 0 {
+		text += fmt.Sprintf(", repeating every %s", every)
+	}
+	text += `. Scheduled runs execute when "pipe runs exec" is run, e.g. from cron.`
+	return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: text}}}, nil
+}
+
+// toolError returns an IsError tool result with the given message.
+func toolError(msg string) *pipe.ToolResult {
+	return &pipe.ToolResult{
+		Content: []pipe.ContentBlock{pipe.TextBlock{Text: msg}},
+		IsError: true,
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	schedule := func(t *testing.T, s *scheduler, args string) *pipe.ToolResult {
+		t.Helper()
+		result, err := s.Execute(context.Background(), json.RawMessage(args))
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("adds a repeating run", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{}
+		s := &scheduler{session: session, dir: "/src/app", now: func() time.Time { return now }}
+
+		result := schedule(t, s, `{"prompt": "check the flaky test", "every": "1h"}`)
+		require.False(t, result.IsError)
+		require.Len(t, session.Schedules, 1)
+		r := session.Schedules[0]
+		assert.Equal(t, "check the flaky test", r.Prompt)
+		assert.Equal(t, "/src/app", r.Dir)
+		assert.Equal(t, time.Hour, r.Every)
+		assert.Equal(t, now.Add(time.Hour), r.Next)
+		text := result.Content[0].(pipe.TextBlock).Text
+		assert.Contains(t, text, "Scheduled run "+r.ID)
+		assert.Contains(t, text, "repeating every 1h0m0s")
+	})
+
+	t.Run("delays a one-off run", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{}
+		s := &scheduler{session: session, now: func() time.Time { return now }}
+
+		result := schedule(t, s, `{"prompt": "investigate", "in": "30m", "after_command": "gh run watch"}`)
+		require.False(t, result.IsError)
+		require.Len(t, session.Schedules, 1)
+		assert.Equal(t, now.Add(30*time.Minute), session.Schedules[0].Next)
+		assert.Zero(t, session.Schedules[0].Every)
+		assert.Equal(t, "gh run watch", session.Schedules[0].AfterCommand)
+	})
+
+	t.Run("rejects invalid arguments", func(t *testing.T) {
+		t.Parallel()
+		session := &pipe.Session{}
+		s := &scheduler{session: session, now: func() time.Time { return now }}
+
+		for _, args := range []string{
+			`{}`,
+			`{"prompt": "x", "every": "10s"}`,
+			`{"prompt": "x", "in": "soon"}`,
+		} {
+			assert.True(t, schedule(t, s, args).IsError, args)
+		}
+		assert.Empty(t, session.Schedules)
+	})
+}
+
+func TestScheduledPrompt(t *testing.T) {
+	t.Parallel()
+
+	t.Run("plain prompt", func(t *testing.T) {
+		t.Parallel()
+		r := pipe.ScheduledRun{Prompt: "check"}
+		assert.Equal(t, "check", scheduledPrompt(context.Background(), r, nil))
+	})
+
+	t.Run("includes the after command's result", func(t *testing.T) {
+		t.Parallel()
+		r := pipe.ScheduledRun{Prompt: "investigate", Dir: t.TempDir(), AfterCommand: "echo FAIL; exit 3"}
+		assert.Equal(t, "investigate\n\n<after_command exit_code=\"3\">\n$ echo FAIL; exit 3\nFAIL\n</after_command>",
+			scheduledPrompt(context.Background(), r, nil))
+	})
+
+	t.Run("skips the after command when bash is not approved", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		r := pipe.ScheduledRun{Prompt: "investigate", Dir: dir, AfterCommand: "touch ran"}
+		assert.Equal(t, "investigate\n\n<after_command skipped=\"not approved\">\n$ touch ran\n</after_command>",
+			scheduledPrompt(context.Background(), r, denyApproval))
+		assert.NoFileExists(t, filepath.Join(dir, "ran"))
+	})
+}
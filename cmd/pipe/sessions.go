@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+)
+
+const sessionsUsage = "usage: pipe sessions diff A B | pipe sessions migrate FILE... | pipe sessions dataset [FILE...]"
+
+// defaultTurnDumps is how many turn dumps a turnDumper keeps.
+const defaultTurnDumps = 50
+
+// turnDumper writes the marshaled session after every turn into dir, for
+// debugging persistence with "pipe sessions diff". Only the newest keep
+// dumps are kept.
+type turnDumper struct {
+	dir  string
+	keep int
+	now  func() time.Time
+	last int // message count of the last dump
+}
+
+// dump writes s unless its messages are unchanged since the last dump, as
+// when a request is retried, then removes the oldest dumps over the limit.
+func (d *turnDumper) dump(s pipe.Session) error {
+	if len(s.Messages) == d.last {
+		return nil
+	}
+	data, err := pipejson.MarshalSession(s)
+	if err != nil {
+		return fmt.Errorf("dump session: %w", err)
+	}
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return fmt.Errorf("dump session: %w", err)
+	}
+	name := fmt.Sprintf("turn-%d-%04d.json", d.now().UnixNano(), len(s.Messages))
+	if err := os.WriteFile(filepath.Join(d.dir, name), data, 0o600); err != nil {
+		return fmt.Errorf("dump session: %w", err)
+	}
+	d.last = len(s.Messages)
+
+	dumps, err := filepath.Glob(filepath.Join(d.dir, "turn-*.json"))
+	if err != nil {
+		return fmt.Errorf("dump session: %w", err)
+	}
+	slices.Sort(dumps)
+	for len(dumps) > d.keep {
+		if err := os.Remove(dumps[0]); err != nil {
+			return fmt.Errorf("dump session: %w", err)
+		}
+		dumps = dumps[1:]
+	}
+	return nil
+}
+
+var _ pipe.Provider = (*dumpingProvider)(nil)
+
+// dumpingProvider dumps the session before each request, which is right
+// after the previous turn was added to it.
+type dumpingProvider struct {
+	pipe.Provider
+	dumper  *turnDumper
+	session *pipe.Session
+}
+
+func (p *dumpingProvider) Stream(ctx context.Context, req pipe.Request) (pipe.Stream, error) {
+	if err := p.dumper.dump(*p.session); err != nil {
+		return nil, err
+	}
+	return p.Provider.Stream(ctx, req)
+}
+
+// runSessions implements "pipe sessions".
+func runSessions(args []string, stdout io.Writer) error {
+	if len(args) > 0 && args[0] == "migrate" {
+		return migrateSessions(args[1:], stdout)
+	}
+	if len(args) > 0 && args[0] == "dataset" {
+		return datasetSessions(args[1:], stdout)
+	}
+	if len(args) == 0 || args[0] != "diff" {
+		return errors.New(sessionsUsage)
+	}
+	flags := flag.NewFlagSet("sessions diff", flag.ContinueOnError)
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New(sessionsUsage)
+	}
+	var docs [2]any
+	for i, path := range flags.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if data, err = pipejson.Inflate(data); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := json.Unmarshal(data, &docs[i]); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, line := range diffJSON("", docs[0], docs[1]) {
+		if _, err := fmt.Fprintln(stdout, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateSessions implements "pipe sessions migrate": it rewrites each
+// session file in the current format.
+func migrateSessions(paths []string, stdout io.Writer) error {
+	if len(paths) == 0 {
+		return errors.New(sessionsUsage)
+	}
+	for _, path := range paths {
+		if err := pipejson.Migrate(path); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if _, err := fmt.Fprintf(stdout, "Migrated %s\n", path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffJSON compares two decoded JSON documents structurally and returns one
+// line per difference: "- path: value" for values only in a, "+ path:
+// value" for values only in b, and "~ path: a -> b" for changed values.
+// Objects are compared key by key and arrays index by index, so a dropped
+// field shows up under its own path.
+func diffJSON(path string, a, b any) []string {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := slices.Sorted(maps.Keys(a))
+		for k := range b {
+			if _, ok := a[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		var lines []string
+		for _, k := range keys {
+			lines = append(lines, diffMember(joinPath(path, k), a, b, k)...)
+		}
+		return lines
+	case []any:
+		b, ok := b.([]any)
+		if !ok {
+			break
+		}
+		var lines []string
+		for i := range max(len(a), len(b)) {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(b):
+				lines = append(lines, "- "+p+": "+compactJSON(a[i]))
+			case i >= len(a):
+				lines = append(lines, "+ "+p+": "+compactJSON(b[i]))
+			default:
+				lines = append(lines, diffJSON(p, a[i], b[i])...)
+			}
+		}
+		return lines
+	default:
+		if reflect.DeepEqual(a, b) {
+			return nil
+		}
+	}
+	return []string{"~ " + displayPath(path) + ": " + compactJSON(a) + " -> " + compactJSON(b)}
+}
+
+// diffMember compares the member k of two objects.
+func diffMember(path string, a, b map[string]any, k string) []string {
+	av, inA := a[k]
+	bv, inB := b[k]
+	switch {
+	case !inB:
+		return []string{"- " + path + ": " + compactJSON(av)}
+	case !inA:
+		return []string{"+ " + path + ": " + compactJSON(bv)}
+	default:
+		return diffJSON(path, av, bv)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
+
+// compactJSON renders v as one line of JSON, shortening long strings.
+func compactJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	s := string(data)
+	if len(s) > 120 {
+		s = strings.ToValidUTF8(s[:117], "") + "..."
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTurnDumper(t *testing.T) {
+	t.Parallel()
+
+	user := func(text string) pipe.Message {
+		return pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: text}}}
+	}
+
+	t.Run("dumps before each request and keeps the newest", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		clock := time.Unix(1700000000, 0)
+		d := &turnDumper{dir: dir, keep: 2, now: func() time.Time { clock = clock.Add(time.Second); return clock }}
+		session := &pipe.Session{ID: "s1"}
+		p := &dumpingProvider{
+			Provider: &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) { return &mock.Stream{}, nil }},
+			dumper:   d,
+			session:  session,
+		}
+		for _, text := range []string{"one", "two", "three"} {
+			session.Messages = append(session.Messages, user(text))
+			_, err := p.Stream(context.Background(), pipe.Request{})
+			require.NoError(t, err)
+			// A retry of the same request adds no dump.
+			_, err = p.Stream(context.Background(), pipe.Request{})
+			require.NoError(t, err)
+		}
+
+		dumps, err := filepath.Glob(filepath.Join(dir, "turn-*.json"))
+		require.NoError(t, err)
+		require.Len(t, dumps, 2)
+		assert.Contains(t, dumps[0], "-0002.json")
+		assert.Contains(t, dumps[1], "-0003.json")
+		data, err := os.ReadFile(dumps[1])
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "three")
+	})
+}
+
+func TestRunSessionsDiff(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	b := filepath.Join(dir, "b.json")
+	require.NoError(t, os.WriteFile(a, []byte(`{"id":"s1","messages":[
+		{"role":"assistant","content":[{"type":"thinking","thinking":"hm","signature":"sig"}]}
+	]}`), 0o600))
+	require.NoError(t, os.WriteFile(b, []byte(`{"id":"s1","title":"t","messages":[
+		{"role":"assistant","content":[{"type":"thinking","thinking":"hmm"}]},
+		{"role":"user"}
+	]}`), 0o600))
+
+	var out bytes.Buffer
+	require.NoError(t, runSessions([]string{"diff", a, b}, &out))
+	assert.Equal(t, `- messages[0].content[0].signature: "sig"
+~ messages[0].content[0].thinking: "hm" -> "hmm"
++ messages[1]: {"role":"user"}
++ title: "t"
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, runSessions([]string{"diff", a, a}, &out))
+	assert.Empty(t, out.String())
+
+	require.EqualError(t, runSessions([]string{"diff", a}, &out), sessionsUsage)
+	require.EqualError(t, runSessions(nil, &out), sessionsUsage)
+}
+
+func TestRunSessionsMigrate(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "s.json")
+	data, err := pipejson.MarshalSession(pipe.Session{ID: "s1"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	var out bytes.Buffer
+	require.NoError(t, runSessions([]string{"migrate", path}, &out))
+	assert.Equal(t, "Migrated "+path+"\n", out.String())
+	got, err := pipejson.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "s1", got.ID)
+
+	require.EqualError(t, runSessions([]string{"migrate"}, &out), sessionsUsage)
+}
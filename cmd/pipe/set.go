@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+// maxInputHeight is the most lines input_height may grow the input to. The
+// TUI also keeps it to what leaves room for the output.
+const maxInputHeight = 20
+
+// inputHeight returns the configured input height; zero means the TUI's
+// default.
+func (c config) inputHeight() (int, error) {
+	if c.InputHeight == 0 {
+		return 0, nil
+	}
+	return checkInputHeight(c.InputHeight)
+}
+
+func checkInputHeight(h int) (int, error) {
+	if h < 1 || h > maxInputHeight {
+		return 0, fmt.Errorf("input_height: must be between 1 and %d, got %d", maxInputHeight, h)
+	}
+	return h, nil
+}
+
+// setCommand implements /set NAME VALUE, which changes a TUI setting for
+// the rest of the session. input_height is the only one so far.
+func setCommand(args string) (bt.CommandResult, error) {
+	name, value, _ := strings.Cut(strings.TrimSpace(args), " ")
+	value = strings.TrimSpace(value)
+	switch name {
+	case "":
+		return bt.CommandResult{}, fmt.Errorf("set: usage: /set input_height LINES")
+	case "input_height":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return bt.CommandResult{}, fmt.Errorf("set: usage: /set input_height LINES")
+		}
+		h, err := checkInputHeight(n)
+		if err != nil {
+			return bt.CommandResult{}, fmt.Errorf("set: %w", err)
+		}
+		return bt.CommandResult{InputHeight: h, Notice: fmt.Sprintf("The input now grows to %d lines.", h)}, nil
+	}
+	return bt.CommandResult{}, fmt.Errorf("set: unknown setting %q; the settings are input_height", name)
+}
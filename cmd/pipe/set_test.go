@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCommand(t *testing.T) {
+	t.Parallel()
+
+	res, err := setCommand("input_height 8")
+	require.NoError(t, err)
+	assert.Equal(t, 8, res.InputHeight)
+	assert.Equal(t, "The input now grows to 8 lines.", res.Notice)
+
+	_, err = setCommand("input_height 0")
+	require.EqualError(t, err, "set: input_height: must be between 1 and 20, got 0")
+	_, err = setCommand("input_height tall")
+	require.EqualError(t, err, "set: usage: /set input_height LINES")
+	_, err = setCommand("")
+	require.EqualError(t, err, "set: usage: /set input_height LINES")
+	_, err = setCommand("theme dark")
+	require.EqualError(t, err, `set: unknown setting "theme"; the settings are input_height`)
+}
+
+func TestConfigInputHeight(t *testing.T) {
+	t.Parallel()
+
+	h, err := config{}.inputHeight()
+	require.NoError(t, err)
+	assert.Zero(t, h)
+
+	h, err = config{InputHeight: 8}.inputHeight()
+	require.NoError(t, err)
+	assert.Equal(t, 8, h)
+
+	_, err = config{InputHeight: 50}.inputHeight()
+	require.EqualError(t, err, "input_height: must be between 1 and 20, got 50")
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+// setupModelsTimeout bounds listing the chosen provider's models during
+// setup.
+const setupModelsTimeout = 15 * time.Second
+
+// promptTemplate seeds the system prompt file created during setup.
+const promptTemplate = `You are a coding assistant working in this repository.
+
+## Project
+
+Describe the project here: what it does, its layout, and how to build and
+test it.
+
+## Conventions
+
+- Follow the style of the surrounding code.
+- Run the tests after changing code.
+- Keep changes focused on the task.
+`
+
+// needsSetup reports whether this is a first run with nothing configured:
+// no config file and no API key in the environment or the keyring.
+func needsSetup(configPath string, registry *pipe.ProviderRegistry, getenv func(string) string) bool {
+	if _, err := os.Stat(configPath); !errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	return len(detectedProviders(registry, getenv)) == 0
+}
+
+// setup asks for a provider, its API key, a default model, and whether to
+// create the system prompt file, then stores the key in kr and writes the
+// config and prompt files. It reports false if the user quit first.
+func setup(ctx context.Context, registry *pipe.ProviderRegistry, kr keyring, configPath, promptPath string) (bool, error) {
+	steps := []bt.SetupStep{
+		{
+			Title:   "Provider",
+			Help:    "Choose the model provider to use.",
+			Choices: func([]string) ([]string, error) { return registry.Names(), nil },
+		},
+		{
+			Title:  "API key",
+			Help:   "Paste your API key. It is stored in the system keyring, not in the project.",
+			Secret: true,
+		},
+		{
+			Title: "Default model",
+			Help:  "Choose the model to use unless -model or a profile names another.",
+			Choices: func(answers []string) ([]string, error) {
+				return setupModels(ctx, registry, providerConfig{name: answers[0], key: answers[1]})
+			},
+		},
+	}
+	writePrompt := false
+	if _, err := os.Stat(promptPath); errors.Is(err, os.ErrNotExist) {
+		writePrompt = true
+		steps = append(steps, bt.SetupStep{
+			Title:   "System prompt",
+			Help:    fmt.Sprintf("Create %s from a template? Edit it to tell the model about this project.", promptPath),
+			Choices: func([]string) ([]string, error) { return []string{"Yes", "No"}, nil },
+		})
+	}
+
+	answers, err := bt.RunSetup(ctx, steps, pipe.DefaultTheme())
+	if err != nil || answers == nil {
+		return false, err
+	}
+	if err := kr.set(answers[0], answers[1]); err != nil {
+		return false, err
+	}
+	if err := writeSetupConfig(configPath, answers[2]); err != nil {
+		return false, err
+	}
+	if writePrompt && answers[3] == "Yes" {
+		if err := writeNewFile(promptPath, []byte(promptTemplate)); err != nil {
+			return false, fmt.Errorf("setup: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// setupModels lists the models of the provider described by cfg.
+func setupModels(ctx context.Context, registry *pipe.ProviderRegistry, cfg providerConfig) ([]string, error) {
+	provider, err := newProvider(registry, cfg)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := provider.(pipe.ModelLister)
+	if !ok {
+		return nil, errors.New("the provider cannot list its models")
+	}
+	ctx, cancel := context.WithTimeout(ctx, setupModelsTimeout)
+	defer cancel()
+	return lister.ListModels(ctx)
+}
+
+// writeSetupConfig writes a new config file at path that sets only the
+// default model.
+func writeSetupConfig(path, model string) error {
+	// Marshaling config itself would also write its empty nested objects.
+	data, err := json.MarshalIndent(map[string]string{"model": model}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("setup: %w", err)
+	}
+	if err := writeNewFile(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("setup: %w", err)
+	}
+	return nil
+}
+
+// writeNewFile creates the file at path, and its directory, failing if
+// the file exists.
+func writeNewFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
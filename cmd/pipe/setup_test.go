@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNeedsSetup(t *testing.T) {
+	t.Parallel()
+
+	registry := newProviderRegistry(&config{}, nil)
+	noKeys := func(string) string { return "" }
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "config.json")
+
+	assert.True(t, needsSetup(missing, registry, noKeys))
+	assert.False(t, needsSetup(missing, registry, func(k string) string {
+		if k == "ANTHROPIC_API_KEY" {
+			return "sk"
+		}
+		return ""
+	}))
+
+	existing := filepath.Join(dir, "existing.json")
+	require.NoError(t, os.WriteFile(existing, []byte("{}"), 0o644))
+	assert.False(t, needsSetup(existing, registry, noKeys))
+}
+
+func TestWriteSetupConfig(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".pipe", "config.json")
+	require.NoError(t, writeSetupConfig(path, "claude-sonnet-4-5"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"model\": \"claude-sonnet-4-5\"\n}\n", string(data))
+
+	cfg, err := loadConfig(path)
+	require.NoError(t, err)
+	settings, err := cfg.resolve("", "")
+	require.NoError(t, err)
+	assert.Equal(t, "claude-sonnet-4-5", settings.model)
+
+	require.Error(t, writeSetupConfig(path, "other"), "an existing config is never overwritten")
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	bt "github.com/fwojciec/pipe/bubbletea"
+	"github.com/fwojciec/pipe/fs"
+)
+
+const defaultSnapshotDir = ".pipe/snapshots"
+
+// snapshots keeps a workspace snapshot of files modified by the latest agent
+// run that modified any, so /rollback can restore them. The snapshot is
+// taken on a run's first mutating tool call, which discards the previous
+// one; runs that change nothing keep it.
+type snapshots struct {
+	root string
+
+	mu      sync.Mutex
+	run     string       // the run in progress or last run
+	current *fs.Snapshot // nil until a run changes a file
+	taken   string       // the run current belongs to
+}
+
+// begin starts the run identified by runID. Its snapshot is taken when it
+// first modifies a file.
+func (s *snapshots) begin(runID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.run = runID
+}
+
+// saveArgs records the file targeted by a mutating tool call's arguments.
+func (s *snapshots) saveArgs(args json.RawMessage) error {
+	var a struct {
+		FilePath string `json:"file_path"`
+	}
+	// Malformed arguments are reported by the tool itself.
+	if err := json.Unmarshal(args, &a); err != nil || a.FilePath == "" {
+		return nil
+	}
+	snap, err := s.take()
+	if err != nil || snap == nil {
+		return err
+	}
+	return snap.Save(a.FilePath)
+}
+
+// take returns the snapshot of the run in progress, replacing the previous
+// run's on its first call in a run. It returns nil outside a run.
+func (s *snapshots) take() (*fs.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.run == "" || s.taken == s.run {
+		return s.current, nil
+	}
+	if s.current != nil {
+		if err := os.RemoveAll(s.current.Dir()); err != nil {
+			return nil, fmt.Errorf("discard snapshot: %w", err)
+		}
+	}
+	s.current = fs.NewSnapshot(filepath.Join(s.root, s.run))
+	s.taken = s.run
+	return s.current, nil
+}
+
+// rollback restores the files changed by the latest run that changed any.
+// It implements the /rollback command.
+func (s *snapshots) rollback(string) (bt.CommandResult, error) {
+	s.mu.Lock()
+	snap := s.current
+	s.mu.Unlock()
+	if snap == nil || snap.Len() == 0 {
+		return bt.CommandResult{Notice: "Nothing to roll back."}, nil
+	}
+	paths, err := snap.Restore()
+	if err != nil {
+		return bt.CommandResult{}, fmt.Errorf("rollback: %w", err)
+	}
+	return bt.CommandResult{Notice: fmt.Sprintf("Rolled back %d file(s):\n%s", len(paths), strings.Join(paths, "\n"))}, nil
+}
+
+// diff shows the line changes made by the latest run that changed files.
+func (s *snapshots) diff(string) (bt.CommandResult, error) {
+	s.mu.Lock()
+	snap := s.current
+	s.mu.Unlock()
+	if snap == nil || snap.Len() == 0 {
+		return bt.CommandResult{Notice: "No file changes."}, nil
+	}
+	d, err := snap.Diff()
+	if err != nil {
+		return bt.CommandResult{}, fmt.Errorf("diff: %w", err)
+	}
+	if d == "" {
+		return bt.CommandResult{Notice: "No file changes."}, nil
+	}
+	return bt.CommandResult{Notice: strings.TrimSuffix(d, "\n")}, nil
+}
+
+// summary describes the files changed by the latest run, e.g.
+// "2 files changed: a.go +10/-2, b.go new". It returns "" when the run
+// changed nothing.
+func (s *snapshots) summary() string {
+	s.mu.Lock()
+	snap := s.current
+	latest := s.taken == s.run
+	s.mu.Unlock()
+	if snap == nil || !latest || snap.Len() == 0 {
+		return ""
+	}
+	changes, err := snap.Changes()
+	if err != nil {
+		return fmt.Sprintf("Could not summarize changes: %v", err)
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	wd, _ := os.Getwd()
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		path := c.Path
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		parts[i] = path + " " + describeChange(c)
+	}
+	noun := "files"
+	if len(changes) == 1 {
+		noun = "file"
+	}
+	return fmt.Sprintf("%d %s changed: %s", len(changes), noun, strings.Join(parts, ", "))
+}
+
+// describeChange renders a file change as "new", "deleted", or line counts
+// such as "+10/-2".
+func describeChange(c fs.FileChange) string {
+	switch {
+	case c.Created:
+		return "new"
+	case c.Deleted:
+		return "deleted"
+	case c.Removed == 0:
+		return fmt.Sprintf("+%d", c.Added)
+	case c.Added == 0:
+		return fmt.Sprintf("-%d", c.Removed)
+	default:
+		return fmt.Sprintf("+%d/-%d", c.Added, c.Removed)
+	}
+}
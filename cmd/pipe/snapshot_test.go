@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshots_Rollback(t *testing.T) {
+	t.Parallel()
+
+	t.Run("restores files changed by write and edit", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		edited := filepath.Join(dir, "edited.txt")
+		created := filepath.Join(dir, "created.txt")
+		require.NoError(t, os.WriteFile(edited, []byte("hello world"), 0o644))
+
+		snaps := &snapshots{root: filepath.Join(dir, ".pipe", "snapshots")}
+		exec := &executor{bash: pipeexec.NewBashExecutor(), snap: snaps}
+		snaps.begin("run1")
+
+		args, _ := json.Marshal(map[string]any{"file_path": edited, "old_string": "world", "new_string": "there"})
+		result, err := exec.Execute(context.Background(), "edit", args)
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		args, _ = json.Marshal(map[string]any{"file_path": created, "content": "new"})
+		result, err = exec.Execute(context.Background(), "write", args)
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		out, err := snaps.rollback("")
+		require.NoError(t, err)
+		assert.Contains(t, out.Notice, "Rolled back 2 file(s)")
+
+		data, err := os.ReadFile(edited)
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(data))
+		assert.NoFileExists(t, created)
+	})
+
+	t.Run("a run that changes nothing keeps the previous snapshot", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "f.txt")
+		snaps := &snapshots{root: filepath.Join(dir, "snapshots")}
+		exec := &executor{bash: pipeexec.NewBashExecutor(), snap: snaps}
+
+		snaps.begin("run1")
+		args, _ := json.Marshal(map[string]any{"file_path": path, "content": "v1"})
+		_, err := exec.Execute(context.Background(), "write", args)
+		require.NoError(t, err)
+
+		snaps.begin("run2")
+		assert.Empty(t, snaps.summary(), "the summary covers only the latest run")
+		out, err := snaps.rollback("")
+		require.NoError(t, err)
+		assert.Contains(t, out.Notice, "Rolled back 1 file(s)")
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("a run's first change replaces the previous snapshot", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		first := filepath.Join(dir, "first.txt")
+		second := filepath.Join(dir, "second.txt")
+		snaps := &snapshots{root: filepath.Join(dir, "snapshots")}
+		exec := &executor{bash: pipeexec.NewBashExecutor(), snap: snaps}
+
+		snaps.begin("run1")
+		args, _ := json.Marshal(map[string]any{"file_path": first, "content": "v1"})
+		_, err := exec.Execute(context.Background(), "write", args)
+		require.NoError(t, err)
+
+		snaps.begin("run2")
+		args, _ = json.Marshal(map[string]any{"file_path": second, "content": "v1"})
+		_, err = exec.Execute(context.Background(), "write", args)
+		require.NoError(t, err)
+		assert.NoDirExists(t, filepath.Join(dir, "snapshots", "run1"))
+
+		out, err := snaps.rollback("")
+		require.NoError(t, err)
+		assert.Contains(t, out.Notice, "Rolled back 1 file(s)")
+		assert.FileExists(t, first)
+		assert.NoFileExists(t, second)
+	})
+
+	t.Run("nothing to roll back before any run", func(t *testing.T) {
+		t.Parallel()
+		snaps := &snapshots{root: t.TempDir()}
+		out, err := snaps.rollback("")
+		require.NoError(t, err)
+		assert.Equal(t, "Nothing to roll back.", out.Notice)
+	})
+}
+
+func TestSnapshots_Summary(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lists files changed by the run", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		edited := filepath.Join(dir, "edited.txt")
+		created := filepath.Join(dir, "created.txt")
+		require.NoError(t, os.WriteFile(edited, []byte("hello\nworld\n"), 0o644))
+
+		snaps := &snapshots{root: filepath.Join(dir, "snapshots")}
+		exec := &executor{bash: pipeexec.NewBashExecutor(), snap: snaps}
+		snaps.begin("run1")
+
+		args, _ := json.Marshal(map[string]any{"file_path": edited, "old_string": "world", "new_string": "there"})
+		_, err := exec.Execute(context.Background(), "edit", args)
+		require.NoError(t, err)
+		args, _ = json.Marshal(map[string]any{"file_path": created, "content": "new\n"})
+		_, err = exec.Execute(context.Background(), "write", args)
+		require.NoError(t, err)
+
+		assert.Equal(t, "2 files changed: "+created+" new, "+edited+" +1/-1", snaps.summary())
+	})
+
+	t.Run("empty when nothing changed", func(t *testing.T) {
+		t.Parallel()
+		snaps := &snapshots{root: t.TempDir()}
+		assert.Empty(t, snaps.summary())
+		snaps.begin("run1")
+		assert.Empty(t, snaps.summary())
+	})
+}
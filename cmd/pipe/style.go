@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	bt "github.com/fwojciec/pipe/bubbletea"
+)
+
+// stylePreset is a response style: a fragment added to the end of the
+// system prompt.
+type stylePreset struct {
+	name        string
+	description string
+	prompt      string
+}
+
+// stylePresets returns the styles /style and the style config key choose
+// from.
+func stylePresets() []stylePreset {
+	return []stylePreset{
+		{
+			name:        "concise",
+			description: "short answers without preamble or recaps",
+			prompt:      "Respond concisely. Lead with the answer or the change, skip preamble and recaps, and explain only what the user needs to act on it.",
+		},
+		{
+			name:        "explanatory",
+			description: "explains choices and trade-offs while working",
+			prompt:      "Explain your work as you go. Say why you chose an approach, point out trade-offs and anything surprising you find in the code, so the user learns the codebase along the way.",
+		},
+		{
+			name:        "code-only",
+			description: "code and commands without commentary",
+			prompt:      "Respond with code only. Give the changed code or the commands to run without commentary, unless the user asks a question that needs a prose answer.",
+		},
+	}
+}
+
+// lookupStyle returns the preset named name.
+func lookupStyle(name string) (stylePreset, bool) {
+	for _, p := range stylePresets() {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return stylePreset{}, false
+}
+
+// styleNames lists the preset names for messages.
+func styleNames() string {
+	presets := stylePresets()
+	names := make([]string, len(presets))
+	for i, p := range presets {
+		names[i] = p.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// stylePrompt returns the system prompt fragment of the configured style,
+// or "" if none is set.
+func (c config) stylePrompt() (string, error) {
+	if c.Style == "" {
+		return "", nil
+	}
+	p, ok := lookupStyle(c.Style)
+	if !ok {
+		return "", fmt.Errorf("style: unknown style %q; choose one of %s", c.Style, styleNames())
+	}
+	return p.prompt, nil
+}
+
+// withStyle returns prompt ending with the style fragment instead of any
+// fragment added before, so switching styles does not pile them up.
+func withStyle(prompt, fragment string) string {
+	for _, p := range stylePresets() {
+		prompt = strings.TrimSuffix(strings.ReplaceAll(prompt, "\n\n"+p.prompt, ""), p.prompt)
+	}
+	switch {
+	case fragment == "":
+		return prompt
+	case prompt == "":
+		return fragment
+	default:
+		return prompt + "\n\n" + fragment
+	}
+}
+
+// styleSwitcher implements /style, which saves the chosen style in the
+// project's config file and applies it from the next prompt.
+type styleSwitcher struct {
+	reload *reloader
+}
+
+// command lists the styles, or with a name sets that style; "off" clears
+// it.
+func (s *styleSwitcher) command(args string) (bt.CommandResult, error) {
+	name := strings.TrimSpace(args)
+	current := s.reload.config().Style
+	if name == "" {
+		var b strings.Builder
+		b.WriteString("Styles (/style NAME to choose, /style off to clear):")
+		for _, p := range stylePresets() {
+			marker := "  "
+			if p.name == current {
+				marker = "* "
+			}
+			fmt.Fprintf(&b, "\n%s%s — %s", marker, p.name, p.description)
+		}
+		return bt.CommandResult{Notice: b.String()}, nil
+	}
+	if name == "off" {
+		name = ""
+	} else if _, ok := lookupStyle(name); !ok {
+		return bt.CommandResult{}, fmt.Errorf("style: unknown style %q; choose one of %s", name, styleNames())
+	}
+	if err := setConfigKey(s.reload.configPath, "style", name); err != nil {
+		return bt.CommandResult{}, fmt.Errorf("style: %w", err)
+	}
+	s.reload.applyChanges()
+	if s.reload.config().Style != name {
+		return bt.CommandResult{}, fmt.Errorf("style: saved to %s, but the config cannot be applied; fix it and /reload", s.reload.configPath)
+	}
+	if name == "" {
+		return bt.CommandResult{Notice: "Style cleared; it applies from the next prompt."}, nil
+	}
+	return bt.CommandResult{Notice: fmt.Sprintf("Style set to %s; it applies from the next prompt.", name)}, nil
+}
+
+// setConfigKey sets key in the config file at path to value, or removes
+// it when value is empty, keeping the other settings. The file and its
+// directory are created if missing.
+func setConfigKey(path, key, value string) error {
+	settings := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("parse config %s: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("read config: %w", err)
+	}
+	if value == "" {
+		delete(settings, key)
+	} else {
+		settings[key], _ = json.Marshal(value) // strings always marshal
+	}
+	data, err = json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
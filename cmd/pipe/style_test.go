@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStyle(t *testing.T) {
+	t.Parallel()
+	concise, _ := lookupStyle("concise")
+	explanatory, _ := lookupStyle("explanatory")
+
+	prompt := withStyle("be helpful", concise.prompt)
+	assert.Equal(t, "be helpful\n\n"+concise.prompt, prompt)
+	assert.Equal(t, prompt, withStyle(prompt, concise.prompt), "applying a style again changes nothing")
+	assert.Equal(t, "be helpful\n\n"+explanatory.prompt, withStyle(prompt, explanatory.prompt))
+	assert.Equal(t, "be helpful", withStyle(prompt, ""))
+	assert.Equal(t, concise.prompt, withStyle("", concise.prompt))
+}
+
+func TestStyleSwitcher(t *testing.T) {
+	t.Parallel()
+	start := time.Now().Add(-time.Hour)
+	dir := t.TempDir()
+	promptPath := filepath.Join(dir, "prompt.md")
+	configPath := filepath.Join(dir, "config.json")
+	writeAt(t, promptPath, "be terse", start)
+	writeAt(t, configPath, `{"context_budget": 1000}`, start)
+	cfg, err := loadConfig(configPath)
+	require.NoError(t, err)
+	settings, err := cfg.resolve("", "")
+	require.NoError(t, err)
+	session := &pipe.Session{SystemPrompt: "be terse"}
+	profiles := &profileSwitcher{cfg: cfg, session: session, current: settings}
+	reload := newReloader(promptPath, configPath, cfg, profiles, session)
+	styles := &styleSwitcher{reload: reload}
+
+	res, err := styles.command("")
+	require.NoError(t, err)
+	assert.Contains(t, res.Notice, "  concise — short answers")
+
+	res, err = styles.command(" code-only ")
+	require.NoError(t, err)
+	assert.Equal(t, "Style set to code-only; it applies from the next prompt.", res.Notice)
+	assert.Equal(t, "code-only", reload.config().Style)
+	assert.Equal(t, 1000, reload.config().ContextBudget)
+	assert.Empty(t, reload.poll(), "the saved change is not announced as an edit")
+
+	saved, err := loadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "code-only", saved.Style)
+	assert.Equal(t, 1000, saved.ContextBudget, "other settings are kept")
+
+	res, err = styles.command("")
+	require.NoError(t, err)
+	assert.Contains(t, res.Notice, "* code-only")
+
+	_, err = styles.command("poetic")
+	require.EqualError(t, err, `style: unknown style "poetic"; choose one of concise, explanatory, code-only`)
+
+	_, err = styles.command("off")
+	require.NoError(t, err)
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "style")
+}
+
+func TestSetConfigKey_CreatesFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".pipe", "config.json")
+	require.NoError(t, setConfigKey(path, "style", "concise"))
+	cfg, err := loadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "concise", cfg.Style)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fwojciec/pipe"
+)
+
+// maxTaskTurns bounds the task max_turns setting, so a misconfigured
+// sub-agent cannot run up an unbounded bill inside one tool call.
+const maxTaskTurns = 50
+
+// defaultTaskTools returns the tools a sub-agent may call when the task
+// config does not list them: read-only exploration.
+func defaultTaskTools() []string {
+	return []string{"read", "grep", "glob"}
+}
+
+// defaultTaskPrompt is the sub-agent's system prompt when the task config
+// does not set one.
+const defaultTaskPrompt = "You are a sub-agent working on a task delegated by another agent. " +
+	"Use your tools to do the task, then reply with a concise, self-contained report of what you found or did. " +
+	"Only your final message is returned to the delegating agent, which cannot see your tool calls."
+
+// taskConfig is the config file form of the task tool's sub-agent.
+type taskConfig struct {
+	// SystemPrompt replaces the built-in sub-agent system prompt.
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// Tools are the tools the sub-agent may call. Default read, grep, and
+	// glob.
+	Tools []string `json:"tools,omitempty"`
+	// MaxTurns caps the sub-agent's requests, 1 to 50. Default 10.
+	MaxTurns int `json:"max_turns,omitempty"`
+}
+
+// taskTool returns the definition of the task tool.
+func taskTool() pipe.Tool {
+	return pipe.Tool{
+		Name: "task",
+		Description: "Delegate a self-contained task, such as finding where something is implemented, to a sub-agent " +
+			"with its own context and a restricted set of tools. It returns only the sub-agent's final report, " +
+			"keeping the exploration out of this conversation. The sub-agent sees nothing of this conversation, " +
+			"so include everything it needs in the prompt.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"description": {
+					"type": "string",
+					"description": "A short description of the task, e.g. 'find the retry logic'"
+				},
+				"prompt": {
+					"type": "string",
+					"description": "The full instructions for the sub-agent"
+				}
+			},
+			"required": ["prompt"]
+		}`),
+	}
+}
+
+// subAgent returns the sub-agent the task tool runs, with its tools taken
+// from tools, or nil when the task tool is not enabled. Its turns are
+// capped by max_turns too. Provider, Executor, and Options are left for the
+// caller. It rejects tools the config does not enable and those a
+// sub-agent cannot use.
+func (c config) subAgent(tools []pipe.Tool) (*pipe.SubAgent, error) {
+	t := c.Task
+	if t == nil {
+		return nil, nil
+	}
+	if t.MaxTurns < 0 || t.MaxTurns > maxTaskTurns {
+		return nil, fmt.Errorf("task: max_turns must be 1 to %d, got %d", maxTaskTurns, t.MaxTurns)
+	}
+	names := t.Tools
+	if len(names) == 0 {
+		names = defaultTaskTools()
+	}
+	known := c.enabledTools()
+	agent := &pipe.SubAgent{SystemPrompt: defaultTaskPrompt, MaxTurns: t.MaxTurns}
+	if c.MaxTurns > 0 {
+		agent.MaxTurns = min(cmp.Or(t.MaxTurns, pipe.DefaultSubAgentTurns), c.MaxTurns)
+	}
+	if t.SystemPrompt != "" {
+		agent.SystemPrompt = t.SystemPrompt
+	}
+	for _, name := range names {
+		switch {
+		case name == "task" || name == "ask_user":
+			return nil, fmt.Errorf("task: tools: a sub-agent cannot use %s", name)
+		case !slices.ContainsFunc(known, func(t pipe.Tool) bool { return t.Name == name }):
+			return nil, fmt.Errorf("task: tools: unknown tool %q", name)
+		}
+		// The active profile may not allow every tool.
+		if i := slices.IndexFunc(tools, func(t pipe.Tool) bool { return t.Name == name }); i >= 0 {
+			agent.Tools = append(agent.Tools, tools[i])
+		}
+	}
+	return agent, nil
+}
+
+// taskRunner implements the task tool.
+type taskRunner struct {
+	agent pipe.SubAgent
+}
+
+// Execute runs the sub-agent on the prompt in args and returns its report.
+func (r *taskRunner) Execute(ctx context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
+	var params struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return toolError(fmt.Sprintf("invalid arguments: %s", err)), nil
+	}
+	if strings.TrimSpace(params.Prompt) == "" {
+		return toolError("prompt is required"), nil
+	}
+	result, err := r.agent.Run(ctx, params.Prompt)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		failed := toolError(fmt.Sprintf("sub-agent failed: %s", err))
+		failed.Usage = result.Usage
+		return failed, nil
+	}
+	text := result.Text
+	if text == "" {
+		text = "The sub-agent finished without a report."
+	}
+	if result.Truncated {
+		text += fmt.Sprintf("\n\n[The sub-agent was stopped after %d turns, its limit, so this report may be incomplete.]", result.Turns)
+	}
+	return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: text}}, Usage: result.Usage}, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSubAgent(t *testing.T) {
+	t.Parallel()
+
+	names := func(tools []pipe.Tool) []string {
+		var names []string
+		for _, t := range tools {
+			names = append(names, t.Name)
+		}
+		return names
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		agent, err := config{}.subAgent(tools())
+		require.NoError(t, err)
+		assert.Nil(t, agent)
+		assert.NotContains(t, names(config{}.enabledTools()), "task")
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Task: &taskConfig{}}
+		agent, err := cfg.subAgent(cfg.enabledTools())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"read", "grep", "glob"}, names(agent.Tools))
+		assert.Equal(t, defaultTaskPrompt, agent.SystemPrompt)
+		assert.Zero(t, agent.MaxTurns)
+		assert.Contains(t, names(cfg.enabledTools()), "task")
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		t.Parallel()
+		cfg := config{Task: &taskConfig{SystemPrompt: "Review code.", Tools: []string{"read", "bash"}, MaxTurns: 4}}
+		agent, err := cfg.subAgent(cfg.enabledTools())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"read", "bash"}, names(agent.Tools))
+		assert.Equal(t, "Review code.", agent.SystemPrompt)
+		assert.Equal(t, 4, agent.MaxTurns)
+	})
+
+	t.Run("the run's max_turns caps the sub-agent's", func(t *testing.T) {
+		t.Parallel()
+		agent, err := config{MaxTurns: 5, Task: &taskConfig{}}.subAgent(nil)
+		require.NoError(t, err)
+		assert.Equal(t, 5, agent.MaxTurns)
+		agent, err = config{MaxTurns: 5, Task: &taskConfig{MaxTurns: 3}}.subAgent(nil)
+		require.NoError(t, err)
+		assert.Equal(t, 3, agent.MaxTurns)
+	})
+
+	t.Run("tools the profile does not allow are left out", func(t *testing.T) {
+		t.Parallel()
+		agent, err := config{Task: &taskConfig{}}.subAgent([]pipe.Tool{{Name: "read"}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"read"}, names(agent.Tools))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		_, err := config{Task: &taskConfig{Tools: []string{"teleport"}}}.subAgent(nil)
+		require.EqualError(t, err, `task: tools: unknown tool "teleport"`)
+		_, err = config{Task: &taskConfig{Tools: []string{"task"}}}.subAgent(nil)
+		require.EqualError(t, err, "task: tools: a sub-agent cannot use task")
+		_, err = config{Task: &taskConfig{MaxTurns: 51}}.subAgent(nil)
+		require.EqualError(t, err, "task: max_turns must be 1 to 50, got 51")
+	})
+}
+
+func TestTaskTool(t *testing.T) {
+	t.Parallel()
+
+	reply := func(text string, stop pipe.StopReason, content ...pipe.ContentBlock) pipe.Stream {
+		if text != "" {
+			content = append(content, pipe.TextBlock{Text: text})
+		}
+		msg := pipe.AssistantMessage{Content: content, StopReason: stop}
+		return &mock.Stream{
+			NextFn:    func() (pipe.Event, error) { return nil, io.EOF },
+			MessageFn: func() (pipe.AssistantMessage, error) { return msg, nil },
+		}
+	}
+	readCall := pipe.ToolCallBlock{ID: "c1", Name: "read", Arguments: json.RawMessage(`{"file_path":"go.mod"}`)}
+	files := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+		return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "module example"}}}, nil
+	}}
+	run := func(t *testing.T, agent pipe.SubAgent, args string) *pipe.ToolResult {
+		t.Helper()
+		exec := &executor{task: &taskRunner{agent: agent}}
+		result, err := exec.Execute(context.Background(), "task", json.RawMessage(args))
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("returns the sub-agent's report", func(t *testing.T) {
+		t.Parallel()
+		var prompts []string
+		provider := &mock.Provider{StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+			if len(req.Messages) == 1 {
+				prompts = append(prompts, req.Messages[0].(pipe.UserMessage).Content[0].(pipe.TextBlock).Text)
+				return reply("", pipe.StopToolUse, readCall), nil
+			}
+			return reply("The module is example.", pipe.StopEndTurn), nil
+		}}
+		agent := pipe.SubAgent{Provider: provider, Executor: files, Tools: []pipe.Tool{{Name: "read"}}}
+
+		result := run(t, agent, `{"description": "module name", "prompt": "What is the module name?"}`)
+		assert.False(t, result.IsError)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "The module is example."}}, result.Content)
+		assert.Equal(t, []string{"What is the module name?"}, prompts)
+	})
+
+	t.Run("reports the sub-agent's usage", func(t *testing.T) {
+		t.Parallel()
+		usage := pipe.Usage{InputTokens: 900, OutputTokens: 40}
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			msg := pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Done."}}, StopReason: pipe.StopEndTurn, Usage: usage}
+			return &mock.Stream{
+				NextFn:    func() (pipe.Event, error) { return nil, io.EOF },
+				MessageFn: func() (pipe.AssistantMessage, error) { return msg, nil },
+			}, nil
+		}}
+		result := run(t, pipe.SubAgent{Provider: provider, Executor: files}, `{"prompt": "Do it."}`)
+		assert.Equal(t, usage, result.Usage)
+	})
+
+	t.Run("notes the turn limit", func(t *testing.T) {
+		t.Parallel()
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			return reply("Still looking.", pipe.StopToolUse, readCall), nil
+		}}
+		agent := pipe.SubAgent{Provider: provider, Executor: files, Tools: []pipe.Tool{{Name: "read"}}, MaxTurns: 2}
+
+		result := run(t, agent, `{"prompt": "Find it."}`)
+		assert.False(t, result.IsError)
+		text := result.Content[0].(pipe.TextBlock).Text
+		assert.Contains(t, text, "Still looking.")
+		assert.Contains(t, text, "stopped after 2 turns")
+	})
+
+	t.Run("requires a prompt", func(t *testing.T) {
+		t.Parallel()
+		result := run(t, pipe.SubAgent{}, `{"description": "nothing"}`)
+		assert.True(t, result.IsError)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "prompt is required"}}, result.Content)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		result, err := (&executor{}).Execute(context.Background(), "task", json.RawMessage(`{"prompt": "x"}`))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// openTee opens the target of the -tee flag: "unix:PATH" connects to a
+// listening unix socket, anything else is a file (or named pipe) appended
+// to.
+func openTee(target string) (io.WriteCloser, error) {
+	if path, ok := strings.CutPrefix(target, "unix:"); ok {
+		return net.Dial("unix", path)
+	}
+	return os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+}
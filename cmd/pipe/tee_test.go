@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenTee(t *testing.T) {
+	t.Parallel()
+
+	t.Run("appends to a file", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "events.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o600))
+		w, err := openTee(path)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, "new\n")
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "old\nnew\n", string(data))
+	})
+
+	t.Run("connects to a unix socket", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "tee.sock")
+		ln, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		defer ln.Close()
+		received := make(chan string, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				received <- err.Error()
+				return
+			}
+			defer conn.Close()
+			data, _ := io.ReadAll(conn)
+			received <- string(data)
+		}()
+
+		w, err := openTee("unix:" + path)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, "event\n")
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		assert.Equal(t, "event\n", <-received)
+	})
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 
 	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
 	pipeexec "github.com/fwojciec/pipe/exec"
 	"github.com/fwojciec/pipe/fs"
 )
@@ -16,40 +18,156 @@ var _ pipe.ToolExecutor = (*executor)(nil)
 // executor dispatches tool calls to the appropriate built-in tool implementation.
 type executor struct {
 	bash *pipeexec.BashExecutor
+	ask  *bt.Asker
+	// sched adds scheduled runs to the session; nil disables scheduling.
+	sched *scheduler
+	snap  *snapshots      // nil disables snapshots
+	mem   *memory         // nil disables remember and recall
+	task  *taskRunner     // nil disables the task tool
+	roots *workspaceRoots // nil when the workspace has a single root
+	// env is added to the environment of run_tests; bash has its own.
+	env []string
+	// allowed restricts which tools may run; nil allows all.
+	allowed map[string]bool
+	// artifactDir receives the full output of oversized grep results; the
+	// OS temp dir when empty.
+	artifactDir string
+	// dir is where the file tools resolve relative paths, and search in when
+	// given none; the working directory when empty. Bash has its own.
+	dir string
 }
 
 // Execute dispatches a tool call by name. Unknown tool names return an IsError
 // result so the model can self-correct.
 func (e *executor) Execute(ctx context.Context, name string, args json.RawMessage) (*pipe.ToolResult, error) {
-	switch name {
-	case "bash":
-		return e.bash.Execute(ctx, args)
-	case "read":
-		return fs.ExecuteRead(ctx, args)
-	case "write":
-		return fs.ExecuteWrite(ctx, args)
-	case "edit":
-		return fs.ExecuteEdit(ctx, args)
-	case "grep":
-		return fs.ExecuteGrep(ctx, args)
-	case "glob":
-		return fs.ExecuteGlob(ctx, args)
-	default:
+	if e.allowed != nil && !e.allowed[name] {
 		return &pipe.ToolResult{
-			Content: []pipe.ContentBlock{pipe.TextBlock{Text: fmt.Sprintf("unknown tool: %s", name)}},
+			Content: []pipe.ContentBlock{pipe.TextBlock{Text: fmt.Sprintf("tool %s is not allowed by the active profile", name)}},
 			IsError: true,
 		}, nil
 	}
+	if e.roots != nil {
+		resolved, msg := e.roots.resolve(name, args)
+		if msg != "" {
+			return toolError(msg), nil
+		}
+		args = resolved
+	}
+	if e.dir != "" {
+		args = inDir(name, args, e.dir)
+	}
+	if e.snap != nil && mutatesFiles(name) {
+		if err := e.snap.saveArgs(args); err != nil {
+			return &pipe.ToolResult{
+				Content: []pipe.ContentBlock{pipe.TextBlock{Text: fmt.Sprintf("refusing to modify file: %s", err)}},
+				IsError: true,
+			}, nil
+		}
+	}
+	return e.router().Execute(ctx, name, args)
 }
 
-// tools returns the tool definitions for all built-in tools.
-func tools() []pipe.Tool {
-	return []pipe.Tool{
-		pipeexec.BashExecutorTool(),
-		fs.ReadTool(),
-		fs.WriteTool(),
-		fs.EditTool(),
-		fs.GrepTool(),
-		fs.GlobTool(),
+// router routes calls to the built-in tools. Without memory, calls to the
+// memory tools say how to enable it rather than that the tool is unknown.
+func (e *executor) router() *pipeexec.Router {
+	var r pipeexec.Router
+	reg := e.registry()
+	for _, t := range reg.Tools() {
+		r.Route(reg, t.Name)
+	}
+	if e.mem == nil {
+		r.Route(memoryDisabled{}, "remember", "recall")
+	}
+	return &r
+}
+
+// registry assembles the built-in tools and the funcs that run them. The
+// memory tools are registered only when memory is set, and the task tool
+// only when task is.
+func (e *executor) registry() *pipeexec.Registry {
+	var r pipeexec.Registry
+	r.Register("bash", pipeexec.BashExecutorTool(), e.bash.Execute)
+	r.Register("read", fs.ReadTool(), fs.ExecuteRead)
+	r.Register("write", fs.WriteTool(), fs.ExecuteWrite)
+	r.Register("edit", fs.EditTool(), fs.ExecuteEdit)
+	r.Register("grep", fs.GrepTool(), func(ctx context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
+		result, err := fs.ExecuteGrep(ctx, args)
+		if err != nil || result.IsError {
+			return result, err
+		}
+		return boundResult("grep", result, e.artifactDir), nil
+	})
+	r.Register("glob", fs.GlobTool(), fs.ExecuteGlob)
+	r.Register("run_tests", pipeexec.RunTestsTool(), pipeexec.RunTestsWithEnv(e.env))
+	r.Register("ask_user", bt.AskUserTool(), e.ask.Execute)
+	r.Register("schedule", scheduleTool(), func(ctx context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
+		if e.sched == nil {
+			return toolError("scheduling is not available in this session"), nil
+		}
+		return e.sched.Execute(ctx, args)
+	})
+	if e.mem != nil {
+		r.Register("remember", rememberTool(), e.mem.remember)
+		r.Register("recall", recallTool(), e.mem.recall)
+	}
+	if e.task != nil {
+		r.Register("task", taskTool(), e.task.Execute)
+	}
+	return &r
+}
+
+// inDir returns the args of a call to the named tool with its relative path
+// argument made absolute in dir, or set to dir when a directory argument is
+// omitted. Malformed args are left for the tool to report.
+func inDir(tool string, args json.RawMessage, dir string) json.RawMessage {
+	key, ok := rootPath(tool)
+	if !ok || key == "" {
+		return args
 	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(args, &fields); err != nil {
+		return args
+	}
+	var path string
+	_ = json.Unmarshal(fields[key], &path)
+	switch {
+	case path == "" && key == "file_path", filepath.IsAbs(path):
+		return args
+	case path == "":
+		path = dir
+	default:
+		path = filepath.Join(dir, path)
+	}
+	fields[key], _ = json.Marshal(path)
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return args
+	}
+	return out
+}
+
+// boundResult bounds the text of a single-block result, offloading the full
+// text to dir when it is over the limit.
+func boundResult(name string, r *pipe.ToolResult, dir string) *pipe.ToolResult {
+	if len(r.Content) != 1 {
+		return r
+	}
+	text, ok := r.Content[0].(pipe.TextBlock)
+	if !ok {
+		return r
+	}
+	text.Text = pipeexec.BoundOutput(name, text.Text, dir)
+	return &pipe.ToolResult{Content: []pipe.ContentBlock{text}, IsError: r.IsError}
+}
+
+// mutatesFiles reports whether the named tool modifies the file given by its
+// file_path argument. Bash can modify anything and is not covered.
+func mutatesFiles(name string) bool {
+	return name == "write" || name == "edit"
+}
+
+// tools returns the tool definitions for all built-in tools that are always
+// enabled.
+func tools() []pipe.Tool {
+	return (&executor{}).registry().Tools()
 }
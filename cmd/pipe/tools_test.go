@@ -6,8 +6,10 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/fwojciec/pipe"
+	bt "github.com/fwojciec/pipe/bubbletea"
 	pipeexec "github.com/fwojciec/pipe/exec"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -46,6 +48,23 @@ func TestExecutor(t *testing.T) {
 		assert.Contains(t, text.Text, "read me")
 	})
 
+	t.Run("file tools resolve relative paths in dir", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("in the run's dir"), 0o644))
+
+		exec := &executor{bash: pipeexec.NewBashExecutor(), dir: dir}
+		result, err := exec.Execute(context.Background(), "read", json.RawMessage(`{"file_path": "notes.txt"}`))
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		assert.Contains(t, result.Content[0].(pipe.TextBlock).Text, "in the run's dir")
+
+		result, err = exec.Execute(context.Background(), "glob", json.RawMessage(`{"pattern": "*.txt"}`))
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		assert.Contains(t, result.Content[0].(pipe.TextBlock).Text, "notes.txt")
+	})
+
 	t.Run("dispatches write tool", func(t *testing.T) {
 		t.Parallel()
 		dir := t.TempDir()
@@ -128,9 +147,20 @@ func TestExecutor(t *testing.T) {
 		assert.Contains(t, text.Text, "nonexistent")
 	})
 
+	t.Run("dispatches ask_user tool", func(t *testing.T) {
+		t.Parallel()
+		exec := &executor{bash: pipeexec.NewBashExecutor(), ask: bt.NewAsker()}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		// With a cancelled context the question is never shown; reaching the
+		// Asker surfaces the cancellation instead of an unknown-tool result.
+		_, err := exec.Execute(ctx, "ask_user", json.RawMessage(`{"question":"ok?"}`))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
 	t.Run("every tool in tools() is dispatchable", func(t *testing.T) {
 		t.Parallel()
-		exec := &executor{bash: pipeexec.NewBashExecutor()}
+		exec := &executor{bash: pipeexec.NewBashExecutor(), ask: bt.NewAsker()}
 		for _, tool := range tools() {
 			t.Run(tool.Name, func(t *testing.T) {
 				t.Parallel()
@@ -148,3 +178,39 @@ func TestExecutor(t *testing.T) {
 		}
 	})
 }
+
+func TestConfigToolTimeout(t *testing.T) {
+	t.Parallel()
+
+	d, err := config{}.toolTimeout()
+	require.NoError(t, err)
+	assert.Zero(t, d)
+
+	d, err = config{ToolTimeout: "10m"}.toolTimeout()
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, d)
+
+	_, err = config{ToolTimeout: "-1s"}.toolTimeout()
+	require.EqualError(t, err, `tool_timeout: invalid duration "-1s"`)
+}
+
+func TestConfigToolResultFormat(t *testing.T) {
+	t.Parallel()
+
+	compact := pipe.ToolResultFormat{StripANSI: true}
+
+	f, err := config{}.toolResultFormat(compact)
+	require.NoError(t, err)
+	assert.Equal(t, pipe.ToolResultFormat{}, f)
+
+	f, err = config{ToolResultFormat: "raw"}.toolResultFormat(compact)
+	require.NoError(t, err)
+	assert.Equal(t, pipe.ToolResultFormat{}, f)
+
+	f, err = config{ToolResultFormat: "compact"}.toolResultFormat(compact)
+	require.NoError(t, err)
+	assert.Equal(t, pipe.ToolResultFormat{StripANSI: true, Verbatim: []string{"read"}}, f)
+
+	_, err = config{ToolResultFormat: "terse"}.toolResultFormat(compact)
+	require.EqualError(t, err, `tool_result_format: must be "compact" or "raw", got "terse"`)
+}
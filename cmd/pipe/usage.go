@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+)
+
+const usageUsage = "usage: pipe usage [-last 30d] [-log file]"
+
+// defaultUsageLogPath is where runs are recorded when usage_metrics is on.
+func defaultUsageLogPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".pipe", "usage.jsonl")
+}
+
+// runUsage implements "pipe usage": it reports per-day usage from the local
+// usage log for the period ending at now, with days, dates, and numbers in
+// locale. Costs are estimated from the models' list prices; runs of models
+// without one are left out of them.
+func runUsage(args []string, stdout io.Writer, now time.Time, locale pipe.Locale) error {
+	flags := flag.NewFlagSet("usage", flag.ContinueOnError)
+	last := flags.String("last", "30d", "Reporting period, e.g. 7d, 30d, 12h")
+	logPath := flags.String("log", defaultUsageLogPath(), "Usage log file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New(usageUsage)
+	}
+	period, err := parsePeriod(*last)
+	if err != nil {
+		return err
+	}
+
+	records, err := pipejson.LoadRunRecords(*logPath)
+	if err != nil {
+		return err
+	}
+	// Group by calendar day in the locale's time zone.
+	for i := range records {
+		records[i].Time = records[i].Time.In(locale.Zone())
+	}
+	since := now.Add(-period)
+	days := pipe.SummarizeUsage(records, since)
+	if len(days) == 0 {
+		_, err := fmt.Fprintf(stdout, "No usage recorded since %s. Set \"usage_metrics\": true in the config file to record runs.\n", locale.Date(since))
+		return err
+	}
+
+	var total pipe.Usage
+	sessions := make(map[string]bool)
+	tools := make(map[string]int)
+	models := make(map[string]int)
+	costs := make(map[time.Time]float64) // by day, as SummarizeUsage groups them
+	unpriced := 0
+	for _, r := range records {
+		if r.Time.Before(since) {
+			continue
+		}
+		sessions[r.SessionID] = true
+		price, ok := pipe.LookupPricing(r.Model)
+		if !ok {
+			unpriced++
+			continue
+		}
+		y, m, d := r.Time.Date()
+		costs[time.Date(y, m, d, 0, 0, 0, 0, r.Time.Location())] += price.Cost(r.Usage)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tSESSIONS\tRUNS\tINPUT\tCACHED\tOUTPUT\tTOOL CALLS\tCOST")
+	runs, calls := 0, 0
+	var cost float64
+	for _, d := range days {
+		dayCalls := 0
+		for name, n := range d.ToolCalls {
+			tools[name] += n
+			dayCalls += n
+		}
+		for name, n := range d.Models {
+			models[name] += n
+		}
+		total = total.Add(d.Usage)
+		runs += d.Runs
+		calls += dayCalls
+		cost += costs[d.Day]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t$%s\n", locale.Date(d.Day), locale.Int(d.Sessions), locale.Int(d.Runs),
+			locale.Int(d.Usage.InputTokens+d.Usage.CacheWriteTokens), locale.Int(d.Usage.CacheReadTokens), locale.Int(d.Usage.OutputTokens), locale.Int(dayCalls),
+			locale.Float(costs[d.Day], 2))
+	}
+	fmt.Fprintf(tw, "TOTAL\t%s\t%s\t%s\t%s\t%s\t%s\t$%s\n", locale.Int(len(sessions)), locale.Int(runs),
+		locale.Int(total.InputTokens+total.CacheWriteTokens), locale.Int(total.CacheReadTokens), locale.Int(total.OutputTokens), locale.Int(calls),
+		locale.Float(cost, 2))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if _, ok := models[""]; ok {
+		models["(default)"] += models[""]
+		delete(models, "")
+	}
+	fmt.Fprintf(stdout, "\nModels: %s\n", formatCounts(models, "run", locale))
+	if len(tools) > 0 {
+		fmt.Fprintf(stdout, "Tools: %s\n", formatCounts(tools, "call", locale))
+	}
+	switch {
+	case unpriced == 1:
+		fmt.Fprintln(stdout, "Cost leaves out 1 run of a model without a known price.")
+	case unpriced > 1:
+		fmt.Fprintf(stdout, "Cost leaves out %s runs of models without a known price.\n", locale.Int(unpriced))
+	}
+	return nil
+}
+
+// parsePeriod parses a reporting period: a number of days such as "30d", or
+// a Go duration such as "12h".
+func parsePeriod(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid period %q: use e.g. 30d or 12h", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid period %q: use e.g. 30d or 12h", s)
+	}
+	return d, nil
+}
+
+// formatCounts renders counts as "name (N units)", most frequent first.
+func formatCounts(counts map[string]int, unit string, locale pipe.Locale) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		n := counts[name]
+		if n == 1 {
+			parts[i] = fmt.Sprintf("%s (1 %s)", name, unit)
+		} else {
+			parts[i] = fmt.Sprintf("%s (%s %ss)", name, locale.Int(n), unit)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,96 @@
+package main_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	. "github.com/fwojciec/pipe/cmd/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunUsage(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	iso := pipe.Locale{DateLayout: time.DateOnly, Location: time.UTC}
+
+	writeLog := func(t *testing.T, records ...pipe.RunRecord) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "usage.jsonl")
+		for _, r := range records {
+			require.NoError(t, pipejson.AppendRunRecord(path, r))
+		}
+		return path
+	}
+
+	t.Run("reports days within the period", func(t *testing.T) {
+		t.Parallel()
+		path := writeLog(t,
+			pipe.RunRecord{Time: now.AddDate(0, 0, -40), SessionID: "old", Usage: pipe.Usage{InputTokens: 999}},
+			pipe.RunRecord{Time: now.AddDate(0, 0, -2), SessionID: "a", Model: "claude-sonnet", Usage: pipe.Usage{InputTokens: 100, OutputTokens: 7}, ToolCalls: map[string]int{"bash": 2}},
+			pipe.RunRecord{Time: now.AddDate(0, 0, -1), SessionID: "a", Model: "claude-sonnet", Usage: pipe.Usage{InputTokens: 50, CacheReadTokens: 20, OutputTokens: 3}, ToolCalls: map[string]int{"read": 1}},
+		)
+		var out bytes.Buffer
+		require.NoError(t, RunUsageForTest([]string{"-last", "30d", "-log", path}, &out, now, iso))
+
+		report := out.String()
+		assert.Contains(t, report, "2026-03-29")
+		assert.Contains(t, report, "2026-03-30")
+		assert.NotContains(t, report, "999")
+		assert.Regexp(t, `TOTAL\s+1\s+2\s+150\s+20\s+10\s+3`, report)
+		assert.Contains(t, report, "Models: claude-sonnet (2 runs)")
+		assert.Contains(t, report, "Tools: bash (2 calls), read (1 call)")
+	})
+
+	t.Run("estimates costs from list prices", func(t *testing.T) {
+		t.Parallel()
+		path := writeLog(t,
+			pipe.RunRecord{Time: now.AddDate(0, 0, -2), SessionID: "a", Model: "claude-sonnet-4-20250514", Usage: pipe.Usage{InputTokens: 1_000_000, OutputTokens: 100_000}},
+			pipe.RunRecord{Time: now.AddDate(0, 0, -1), SessionID: "a", Model: "claude-sonnet-4-20250514", Usage: pipe.Usage{InputTokens: 500_000}},
+			pipe.RunRecord{Time: now.AddDate(0, 0, -1), SessionID: "b", Model: "my-local-model", Usage: pipe.Usage{InputTokens: 500_000}},
+		)
+		var out bytes.Buffer
+		require.NoError(t, RunUsageForTest([]string{"-log", path}, &out, now, iso))
+
+		report := out.String()
+		assert.Regexp(t, `2026-03-29\s.*\s\$4\.50\n`, report)
+		assert.Regexp(t, `2026-03-30\s.*\s\$1\.50\n`, report)
+		assert.Regexp(t, `TOTAL\s.*\s\$6\.00\n`, report)
+		assert.Contains(t, report, "Cost leaves out 1 run of a model without a known price.")
+	})
+
+	t.Run("formats for the locale and its time zone", func(t *testing.T) {
+		t.Parallel()
+		path := writeLog(t, pipe.RunRecord{
+			Time:      time.Date(2026, 3, 29, 23, 30, 0, 0, time.UTC),
+			SessionID: "a",
+			Usage:     pipe.Usage{InputTokens: 12345},
+		})
+		de, ok := pipe.LookupLocale("de-DE")
+		require.True(t, ok)
+		warsaw, err := time.LoadLocation("Europe/Warsaw")
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		require.NoError(t, RunUsageForTest([]string{"-log", path}, &out, now, de.In(warsaw)))
+		assert.Contains(t, out.String(), "30.03.2026", "23:30 UTC is the next day in Warsaw")
+		assert.Contains(t, out.String(), "12.345")
+	})
+
+	t.Run("explains how to opt in when empty", func(t *testing.T) {
+		t.Parallel()
+		var out bytes.Buffer
+		require.NoError(t, RunUsageForTest([]string{"-log", filepath.Join(t.TempDir(), "none.jsonl")}, &out, now, iso))
+		assert.Contains(t, out.String(), `"usage_metrics": true`)
+	})
+
+	t.Run("rejects a malformed period", func(t *testing.T) {
+		t.Parallel()
+		err := RunUsageForTest([]string{"-last", "soon"}, &bytes.Buffer{}, now, iso)
+		assert.ErrorContains(t, err, `invalid period "soon"`)
+	})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// minKeepWarm keeps the pings from costing more than the cold starts they
+// save.
+const minKeepWarm = 30 * time.Second
+
+// keepWarmTimeout bounds a single ping; a ping that takes longer is
+// hitting the cold start it meant to avoid and can be abandoned.
+const keepWarmTimeout = time.Minute
+
+// keepWarmIdle is how long pings continue after the last run, so a TUI
+// left open overnight does not ping until it is closed.
+const keepWarmIdle = 30 * time.Minute
+
+// keepWarmer sends a tiny request whenever the provider has been idle for
+// an interval, so endpoints that scale down between requests answer the
+// next prompt without a cold start. It stops once no run has happened for
+// the idle period, and starts again with the next run.
+type keepWarmer struct {
+	provider pipe.Provider
+	model    func() string // model to ping; empty is the provider default
+	interval time.Duration
+	idle     time.Duration // pings stop this long after the last run
+	now      func() time.Time
+
+	mu      sync.Mutex
+	busy    int       // runs in progress
+	last    time.Time // end of the last run or ping
+	lastRun time.Time // end of the last run, or the start
+}
+
+func newKeepWarmer(provider pipe.Provider, model func() string, interval time.Duration) *keepWarmer {
+	now := time.Now()
+	return &keepWarmer{provider: provider, model: model, interval: interval, idle: keepWarmIdle, now: time.Now, last: now, lastRun: now}
+}
+
+// begin marks the start of a run; no pings are sent until the matching end.
+func (w *keepWarmer) begin() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.busy++
+}
+
+// end marks the end of a run, restarting the idle interval.
+func (w *keepWarmer) end() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.busy--
+	w.last = w.now()
+	w.lastRun = w.last
+}
+
+// run pings the provider until ctx is done.
+func (w *keepWarmer) run(ctx context.Context) {
+	// Checking at a fraction of the interval pings within a few seconds of
+	// the endpoint going idle for the full interval.
+	ticker := time.NewTicker(max(w.interval/4, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = w.tick(ctx) // best effort; the next prompt reports real errors
+		}
+	}
+}
+
+// tick pings the provider if it is idle, the interval has passed since it
+// was last used, and a run ended within the idle period. It reports whether
+// a ping was sent and the ping's error.
+func (w *keepWarmer) tick(ctx context.Context) (bool, error) {
+	w.mu.Lock()
+	now := w.now()
+	due := w.busy == 0 && now.Sub(w.last) >= w.interval && now.Sub(w.lastRun) < w.idle
+	if due {
+		w.last = now
+	}
+	w.mu.Unlock()
+	if !due {
+		return false, nil
+	}
+	return true, w.ping(ctx)
+}
+
+// ping streams a one-token response to a one-word prompt.
+func (w *keepWarmer) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, keepWarmTimeout)
+	defer cancel()
+	stream, err := w.provider.Stream(ctx, pipe.Request{
+		Model:     w.model(),
+		Messages:  []pipe.Message{pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ping"}}}},
+		MaxTokens: 1,
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	for {
+		if _, err := stream.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
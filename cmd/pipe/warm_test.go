@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeepWarmer(t *testing.T) {
+	t.Parallel()
+	var pings []pipe.Request
+	provider := &mock.Provider{StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+		pings = append(pings, req)
+		return &mock.Stream{NextFn: func() (pipe.Event, error) { return nil, io.EOF }}, nil
+	}}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	w := newKeepWarmer(provider, func() string { return "claude-test" }, 5*time.Minute)
+	w.now = func() time.Time { return now }
+	w.last, w.lastRun = now, now
+	ctx := context.Background()
+
+	now = now.Add(4 * time.Minute)
+	sent, err := w.tick(ctx)
+	require.NoError(t, err)
+	assert.False(t, sent, "the interval has not passed")
+
+	now = now.Add(time.Minute)
+	sent, err = w.tick(ctx)
+	require.NoError(t, err)
+	assert.True(t, sent)
+	require.Len(t, pings, 1)
+	assert.Equal(t, "claude-test", pings[0].Model)
+	assert.Equal(t, 1, pings[0].MaxTokens)
+
+	sent, _ = w.tick(ctx)
+	assert.False(t, sent, "a ping restarts the interval")
+
+	w.begin()
+	now = now.Add(time.Hour)
+	sent, _ = w.tick(ctx)
+	assert.False(t, sent, "no pings during a run")
+	w.end()
+	now = now.Add(time.Minute)
+	sent, _ = w.tick(ctx)
+	assert.False(t, sent, "a run restarts the interval")
+	assert.Len(t, pings, 1)
+
+	// Pings continue only for the idle period after the last run.
+	for range 5 {
+		now = now.Add(5 * time.Minute)
+		sent, _ = w.tick(ctx)
+		assert.True(t, sent)
+	}
+	now = now.Add(5 * time.Minute)
+	sent, _ = w.tick(ctx)
+	assert.False(t, sent, "pings stop 30 minutes after the last run")
+	now = now.Add(time.Hour)
+	sent, _ = w.tick(ctx)
+	assert.False(t, sent)
+
+	w.begin()
+	w.end()
+	now = now.Add(5 * time.Minute)
+	sent, _ = w.tick(ctx)
+	assert.True(t, sent, "the next run resumes them")
+}
+
+func TestConfig_KeepWarm(t *testing.T) {
+	t.Parallel()
+	d, err := config{}.keepWarm()
+	require.NoError(t, err)
+	assert.Zero(t, d)
+
+	d, err = config{KeepWarm: "5m"}.keepWarm()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, d)
+
+	_, err = config{KeepWarm: "soon"}.keepWarm()
+	require.ErrorContains(t, err, `keep_warm: invalid duration "soon"`)
+	_, err = config{KeepWarm: "10s"}.keepWarm()
+	require.ErrorContains(t, err, "below the 30s minimum")
+}
@@ -0,0 +1,204 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+const (
+	// compactionThreshold is the fraction of the context budget at which the
+	// loop compacts the session.
+	compactionThreshold = 0.8
+	// compactionKeep is the fraction of the context budget kept as recent
+	// messages when compacting.
+	compactionKeep = 0.25
+	// compactionBlockLen caps each block of the transcript sent for
+	// summarizing, in bytes, so long tool outputs don't dominate it.
+	compactionBlockLen = 2000
+)
+
+const compactionPrompt = "You are compacting a conversation between a user and a coding assistant so it can continue " +
+	"within a limited context window. Summarize the transcript you are given: the user's goals and " +
+	"instructions, decisions made and why, files and commands involved, results of tool calls that still " +
+	"matter, and any work in progress or open questions. Be concise but keep every detail needed to " +
+	"continue the work. Reply with the summary only."
+
+// summaryPrefix introduces the summary that replaces compacted messages.
+const summaryPrefix = "Summary of the earlier conversation, compacted to save context:\n\n"
+
+// contextTokens returns the context size of the session's latest request:
+// the input and output tokens of the last assistant message that reports
+// usage, or zero when none does.
+func contextTokens(msgs []Message) int {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		am, ok := msgs[i].(AssistantMessage)
+		if !ok || am.Usage == (Usage{}) {
+			continue
+		}
+		u := am.Usage
+		return u.InputTokens + u.CacheReadTokens + u.CacheWriteTokens + u.OutputTokens
+	}
+	return 0
+}
+
+// messageContent returns the content blocks of msg.
+func messageContent(msg Message) []ContentBlock {
+	switch m := msg.(type) {
+	case UserMessage:
+		return m.Content
+	case AssistantMessage:
+		return m.Content
+	case ToolResultMessage:
+		return m.Content
+	default:
+		return nil
+	}
+}
+
+// compactionSplit returns the index of the first message to keep when
+// compacting msgs: the earliest message that keeps the estimated tokens of
+// the kept messages within keep, or else the latest message that can start
+// the kept history. A tool result cannot start it, as its call would be
+// summarized away. Zero means there is nothing to compact.
+func compactionSplit(msgs []Message, keep int) int {
+	split, latest, kept := 0, 0, 0
+	for i := len(msgs) - 1; i > 0; i-- {
+		for _, b := range messageContent(msgs[i]) {
+			kept += attributeBlock(b).Tokens
+		}
+		if _, ok := msgs[i].(ToolResultMessage); ok {
+			continue
+		}
+		if latest == 0 {
+			latest = i
+		}
+		if kept > keep {
+			break
+		}
+		split = i
+	}
+	if split == 0 {
+		return latest
+	}
+	return split
+}
+
+// compact replaces older messages with a summary when the session's context
+// has reached the compaction threshold of the budget.
+func (l *Loop) compact(ctx context.Context, session *Session, cfg *runConfig) error {
+	before := contextTokens(session.Messages)
+	if float64(before) < float64(cfg.budget)*compactionThreshold {
+		return nil
+	}
+	split := compactionSplit(session.Messages, int(float64(cfg.budget)*compactionKeep))
+	if split == 0 {
+		return nil
+	}
+	summary, err := l.summarize(ctx, session.Messages[:split], cfg)
+	if err != nil {
+		return fmt.Errorf("compact: %w", err)
+	}
+
+	summaryBlock := TextBlock{Text: summaryPrefix + summary}
+	rest := session.Messages[split:]
+	if um, ok := rest[0].(UserMessage); ok {
+		// Merge into the user message so user turns still alternate.
+		um.Content = append([]ContentBlock{summaryBlock}, um.Content...)
+		session.Messages = append([]Message{um}, rest[1:]...)
+	} else {
+		summaryMsg := UserMessage{Content: []ContentBlock{summaryBlock}, Timestamp: cfg.now()}
+		session.Messages = append([]Message{summaryMsg}, slices.Clone(rest)...)
+	}
+	session.UpdatedAt = cfg.now()
+
+	if cfg.onEvent != nil {
+		cfg.onEvent(EventCompaction{Messages: split, TokensBefore: before, Summary: summary})
+	}
+	return nil
+}
+
+// summarize asks the provider for a summary of msgs, sent as a transcript.
+func (l *Loop) summarize(ctx context.Context, msgs []Message, cfg *runConfig) (string, error) {
+	req := Request{
+		Model:        cfg.model,
+		SystemPrompt: compactionPrompt,
+		Messages:     []Message{UserMessage{Content: []ContentBlock{TextBlock{Text: transcript(msgs)}}}},
+		MaxTokens:    cfg.maxTokens,
+	}
+	cfg.transcript.request(req)
+	stream, err := l.provider.Stream(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+	for {
+		if _, err := stream.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+	msg, err := stream.Message()
+	if err != nil {
+		return "", err
+	}
+	cfg.transcript.response(msg)
+	var sb strings.Builder
+	for _, b := range msg.Content {
+		if tb, ok := b.(TextBlock); ok {
+			sb.WriteString(tb.Text)
+		}
+	}
+	if strings.TrimSpace(sb.String()) == "" {
+		return "", errors.New("empty summary")
+	}
+	return sb.String(), nil
+}
+
+// transcript renders msgs as plain text for summarizing. Thinking is left
+// out and long blocks are shortened.
+func transcript(msgs []Message) string {
+	var sb strings.Builder
+	for _, msg := range msgs {
+		switch m := msg.(type) {
+		case UserMessage:
+			sb.WriteString("## User\n")
+		case AssistantMessage:
+			sb.WriteString("## Assistant\n")
+		case ToolResultMessage:
+			if m.IsError {
+				fmt.Fprintf(&sb, "## Tool result (%s, error)\n", m.ToolName)
+			} else {
+				fmt.Fprintf(&sb, "## Tool result (%s)\n", m.ToolName)
+			}
+		}
+		for _, b := range messageContent(msg) {
+			var text string
+			switch b := b.(type) {
+			case TextBlock:
+				text = b.Text
+			case ToolCallBlock:
+				text = fmt.Sprintf("[called %s %s]", b.Name, b.Arguments)
+			case ServerToolCallBlock:
+				text = fmt.Sprintf("[called %s %s]", b.Name, b.Arguments)
+			case ServerToolResultBlock:
+				text = fmt.Sprintf("[%s result %s]", b.Name, b.Content)
+			case ImageBlock:
+				text = "[image]"
+			default:
+				continue
+			}
+			if len(text) > compactionBlockLen {
+				text = strings.ToValidUTF8(text[:compactionBlockLen], "") + " […]"
+			}
+			sb.WriteString(text)
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,131 @@
+package pipe_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoop_ContextBudget(t *testing.T) {
+	t.Parallel()
+
+	userText := func(text string) pipe.UserMessage {
+		return pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: text}}}
+	}
+	reply := pipe.AssistantMessage{
+		Content:    []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}},
+		StopReason: pipe.StopEndTurn,
+		Usage:      pipe.Usage{InputTokens: 100},
+	}
+	// summarizingProvider answers summary requests with summary and records
+	// the other requests.
+	summarizingProvider := func(summary string, requests *[]pipe.Request, transcripts *[]string) *mock.Provider {
+		return &mock.Provider{
+			StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+				if req.SystemPrompt != "you are helpful" {
+					*transcripts = append(*transcripts, req.Messages[0].(pipe.UserMessage).Content[0].(pipe.TextBlock).Text)
+					return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: summary}}}), nil
+				}
+				*requests = append(*requests, req)
+				return completedStream(reply), nil
+			},
+		}
+	}
+
+	t.Run("summarizes older messages near the budget", func(t *testing.T) {
+		t.Parallel()
+		var requests []pipe.Request
+		var transcripts []string
+		var events []pipe.Event
+		session := &pipe.Session{SystemPrompt: "you are helpful", Messages: []pipe.Message{
+			userText("fix the login bug"),
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "fixed in auth.go"}}, Usage: pipe.Usage{InputTokens: 700, CacheReadTokens: 100, OutputTokens: 50}},
+			userText("now add a test"),
+		}}
+		loop := pipe.NewLoop(summarizingProvider("login bug fixed in auth.go", &requests, &transcripts), &mock.ToolExecutor{})
+
+		err := loop.Run(context.Background(), session, nil,
+			pipe.WithContextBudget(1000),
+			pipe.WithEventHandler(func(e pipe.Event) { events = append(events, e) }))
+		require.NoError(t, err)
+
+		require.Len(t, transcripts, 1)
+		assert.Contains(t, transcripts[0], "## User\nfix the login bug")
+		assert.NotContains(t, transcripts[0], "now add a test")
+
+		require.Len(t, requests, 1)
+		require.Len(t, requests[0].Messages, 3)
+		summary := requests[0].Messages[0].(pipe.UserMessage).Content[0].(pipe.TextBlock).Text
+		assert.True(t, strings.HasSuffix(summary, "login bug fixed in auth.go"))
+		assert.Equal(t, session.Messages[:3], requests[0].Messages)
+
+		require.Len(t, events, 2)
+		assert.Equal(t, pipe.EventCompaction{Messages: 1, TokensBefore: 850, Summary: "login bug fixed in auth.go"}, events[0])
+		assert.Equal(t, pipe.EventTurnComplete{StopReason: pipe.StopEndTurn, Usage: reply.Usage}, events[1])
+	})
+
+	t.Run("the message handler sees the run's messages", func(t *testing.T) {
+		t.Parallel()
+		var requests []pipe.Request
+		var transcripts []string
+		var appended []pipe.Message
+		session := &pipe.Session{SystemPrompt: "you are helpful", Messages: []pipe.Message{
+			userText("fix the login bug"),
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "fixed in auth.go"}}, Usage: pipe.Usage{InputTokens: 900}},
+			userText("now add a test"),
+		}}
+		loop := pipe.NewLoop(summarizingProvider("login bug fixed", &requests, &transcripts), &mock.ToolExecutor{})
+
+		err := loop.Run(context.Background(), session, nil,
+			pipe.WithContextBudget(1000),
+			pipe.WithMessageHandler(func(m pipe.Message) { appended = append(appended, m) }))
+		require.NoError(t, err)
+		require.Len(t, transcripts, 1)
+		assert.Equal(t, []pipe.Message{reply}, appended)
+	})
+
+	t.Run("keeps a tool call with its result", func(t *testing.T) {
+		t.Parallel()
+		var requests []pipe.Request
+		var transcripts []string
+		call := pipe.ToolCallBlock{ID: "c1", Name: "bash", Arguments: json.RawMessage(`{"command":"go test ./..."}`)}
+		session := &pipe.Session{SystemPrompt: "you are helpful", Messages: []pipe.Message{
+			userText("run the tests"),
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{call}, StopReason: pipe.StopToolUse, Usage: pipe.Usage{InputTokens: 950}},
+			pipe.ToolResultMessage{ToolCallID: "c1", ToolName: "bash", Content: []pipe.ContentBlock{pipe.TextBlock{Text: strings.Repeat("FAIL ", 1000)}}},
+		}}
+		loop := pipe.NewLoop(summarizingProvider("the user asked to run the tests", &requests, &transcripts), &mock.ToolExecutor{})
+
+		require.NoError(t, loop.Run(context.Background(), session, nil, pipe.WithContextBudget(1000)))
+
+		require.Len(t, requests, 1)
+		msgs := requests[0].Messages
+		require.Len(t, msgs, 3)
+		assert.IsType(t, pipe.UserMessage{}, msgs[0])
+		assert.Equal(t, []pipe.ContentBlock{call}, msgs[1].(pipe.AssistantMessage).Content)
+		assert.IsType(t, pipe.ToolResultMessage{}, msgs[2])
+	})
+
+	t.Run("below the threshold nothing is compacted", func(t *testing.T) {
+		t.Parallel()
+		var requests []pipe.Request
+		var transcripts []string
+		session := &pipe.Session{SystemPrompt: "you are helpful", Messages: []pipe.Message{
+			userText("hi"),
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hello"}}, Usage: pipe.Usage{InputTokens: 500}},
+			userText("bye"),
+		}}
+		loop := pipe.NewLoop(summarizingProvider("unused", &requests, &transcripts), &mock.ToolExecutor{})
+
+		require.NoError(t, loop.Run(context.Background(), session, nil, pipe.WithContextBudget(1000)))
+		assert.Empty(t, transcripts)
+		require.Len(t, requests, 1)
+		assert.Len(t, requests[0].Messages, 3)
+	})
+}
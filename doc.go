@@ -0,0 +1,30 @@
+// Package pipe is the domain of the pipe agent: sessions and their
+// messages, the [Provider] and [ToolExecutor] interfaces adapters
+// implement, and the [Loop] that runs a conversation between them.
+//
+// To embed the agent, create a loop with [NewLoop] and run it on a
+// [Session] with [Loop.Run], configuring the run with [RunOption] values
+// such as [WithEventHandler]:
+//
+//	loop := pipe.NewLoop(provider, executor)
+//	err := loop.Run(ctx, &session, tools, pipe.WithEventHandler(onEvent))
+//
+// # Compatibility
+//
+// The exported API of this package and of the adapter packages is
+// versioned with the module and follows the Go 1 compatibility rules
+// within a major version: code that compiles against it keeps compiling.
+// New behavior arrives as new identifiers, new [RunOption] and adapter
+// Option functions, new struct fields whose zero value keeps the old
+// behavior, and new variadic options on existing functions.
+//
+// When an identifier has to be renamed or replaced, the old one stays as a
+// shim, a type alias or a function calling its replacement, with a doc
+// comment paragraph starting "Deprecated:" that names the replacement.
+// Shims are removed only in the next major version. The interfaces
+// adapters implement, such as [Provider] and [ToolExecutor], gain methods
+// only in a major version too; optional capabilities are separate
+// interfaces an adapter may also implement, like [Embedder].
+//
+// The cmd directory is an application, not part of the API.
+package pipe
@@ -6,4 +6,28 @@ import "errors"
 var (
 	// ErrValidation indicates a request or message failed validation.
 	ErrValidation = errors.New("validation error")
+
+	// ErrRequestDenied ends a run whose next provider request was denied
+	// by the request approver.
+	ErrRequestDenied = errors.New("request denied")
+
+	// ErrMaxTurns ends a run that reached its WithMaxTurns limit.
+	ErrMaxTurns = errors.New("turn limit reached")
+
+	// ErrMaxCostTokens ends a run that reached its WithMaxCostTokens
+	// limit.
+	ErrMaxCostTokens = errors.New("token limit reached")
 )
+
+// ProviderError is a provider failure classified by cause. Providers return
+// it, wrapping the underlying error, so callers can tell why a request failed
+// without parsing error strings.
+type ProviderError struct {
+	Reason StopReason // StopRateLimited, StopContentFiltered, StopAuthFailed, or StopError
+	Raw    string     // provider-specific error type, e.g. "rate_limit_error"
+	Err    error
+}
+
+func (e *ProviderError) Error() string { return e.Err.Error() }
+
+func (e *ProviderError) Unwrap() error { return e.Err }
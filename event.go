@@ -1,5 +1,7 @@
 package pipe
 
+import "time"
+
 // Event is a sealed interface representing a streaming event.
 // Events are purely semantic. Transport/protocol errors come from
 // Next()'s error return, not from events.
@@ -17,6 +19,15 @@ type EventTextDelta struct {
 
 func (EventTextDelta) event() {}
 
+// EventCitation attaches a citation to a text block.
+// Index identifies which text block the citation belongs to within the message.
+type EventCitation struct {
+	Index    int
+	Citation Citation
+}
+
+func (EventCitation) event() {}
+
 // EventThinkingDelta represents a thinking content delta.
 // Index identifies which thinking block this delta belongs to within the message.
 type EventThinkingDelta struct {
@@ -49,23 +60,104 @@ type EventToolCallEnd struct {
 
 func (EventToolCallEnd) event() {}
 
+// EventServerToolCall signals a completed call to a provider-hosted tool.
+// It is informational only: the provider executes the tool itself.
+type EventServerToolCall struct {
+	Call ServerToolCallBlock
+}
+
+func (EventServerToolCall) event() {}
+
+// EventServerToolResult carries the provider's result for a server tool call.
+type EventServerToolResult struct {
+	Result ServerToolResultBlock
+}
+
+func (EventServerToolResult) event() {}
+
 // EventToolResult carries a tool result back to the TUI during the agent loop.
 // It is emitted by the loop after each tool execution, not by providers.
 type EventToolResult struct {
 	ID       string
 	ToolName string
 	Content  string
+	Images   []ImageBlock // images in the result, e.g. from a screenshot tool
 	IsError  bool
+	Usage    Usage // cost of provider requests the tool made
 }
 
 func (EventToolResult) event() {}
 
+// EventCompaction reports that the loop summarized older messages to keep
+// the session within its context budget. It is emitted by the loop, not by
+// providers.
+type EventCompaction struct {
+	Messages     int // number of messages replaced by the summary
+	TokensBefore int // context tokens of the request that crossed the threshold
+	Summary      string
+}
+
+func (EventCompaction) event() {}
+
+// EventRetry reports that a failed provider request will be retried after
+// Delay. Resumed is true when the retry continues the partial response
+// already streamed instead of starting over. It is emitted by the loop, not
+// by providers.
+type EventRetry struct {
+	Attempt    int // retry number, from 1
+	MaxRetries int
+	Delay      time.Duration
+	Err        error
+	Resumed    bool
+}
+
+func (EventRetry) event() {}
+
+// EventToolTimeout reports that a tool call ran longer than the run's tool
+// timeout and was cancelled; its error result follows as an
+// EventToolResult. It is emitted by the loop, not by providers.
+type EventToolTimeout struct {
+	ID       string
+	ToolName string
+	Timeout  time.Duration
+}
+
+func (EventToolTimeout) event() {}
+
+// EventTurnComplete reports that an assistant message was added to the
+// session, with its stop reason and the usage of the request behind it, so
+// consumers can track consumption turn by turn. It is emitted by the loop,
+// not by providers.
+type EventTurnComplete struct {
+	StopReason StopReason
+	Usage      Usage
+}
+
+func (EventTurnComplete) event() {}
+
+// EventRunStatus reports the status the model ended a run with, when the
+// run asked for one with [WithRunStatus] and the model gave it. It is the
+// last event of the run and is emitted by the loop, not by providers.
+type EventRunStatus struct {
+	Status RunStatus
+}
+
+func (EventRunStatus) event() {}
+
 // Interface compliance checks.
 var (
 	_ Event = EventTextDelta{}
+	_ Event = EventCitation{}
 	_ Event = EventThinkingDelta{}
 	_ Event = EventToolCallBegin{}
 	_ Event = EventToolCallDelta{}
 	_ Event = EventToolCallEnd{}
+	_ Event = EventServerToolCall{}
+	_ Event = EventServerToolResult{}
 	_ Event = EventToolResult{}
+	_ Event = EventCompaction{}
+	_ Event = EventRetry{}
+	_ Event = EventToolTimeout{}
+	_ Event = EventTurnComplete{}
+	_ Event = EventRunStatus{}
 )
@@ -59,20 +59,26 @@ func TestEventTypeSwitch_Exhaustive(t *testing.T) {
 	t.Parallel()
 	events := []pipe.Event{
 		pipe.EventTextDelta{Index: 0, Delta: "hello"},
+		pipe.EventCitation{Index: 0, Citation: pipe.Citation{URL: "https://go.dev"}},
 		pipe.EventThinkingDelta{Index: 0, Delta: "reasoning"},
 		pipe.EventToolCallBegin{ID: "tc_1", Name: "read"},
 		pipe.EventToolCallDelta{ID: "tc_1", Delta: `{"path":"`},
 		pipe.EventToolCallEnd{Call: pipe.ToolCallBlock{ID: "tc_1", Name: "read"}},
+		pipe.EventServerToolCall{Call: pipe.ServerToolCallBlock{ID: "srv_1", Name: "web_search"}},
+		pipe.EventServerToolResult{Result: pipe.ServerToolResultBlock{ToolCallID: "srv_1", Name: "web_search"}},
 		pipe.EventToolResult{ID: "tc_1", ToolName: "bash", Content: "output", IsError: false},
 	}
-	assert.Len(t, events, 6, "update slice and switch when adding new Event types")
+	assert.Len(t, events, 9, "update slice and switch when adding new Event types")
 	for _, e := range events {
 		switch e.(type) {
 		case pipe.EventTextDelta:
+		case pipe.EventCitation:
 		case pipe.EventThinkingDelta:
 		case pipe.EventToolCallBegin:
 		case pipe.EventToolCallDelta:
 		case pipe.EventToolCallEnd:
+		case pipe.EventServerToolCall:
+		case pipe.EventServerToolResult:
 		case pipe.EventToolResult:
 		default:
 			t.Fatalf("unexpected event type: %T", e)
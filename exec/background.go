@@ -82,8 +82,8 @@ func (r *BackgroundRegistry) Check(pid int) (*pipe.ToolResult, error) {
 	exitCode := bp.exitCode
 	bp.mu.Unlock()
 
-	stdoutStr, stdoutTR := processOutput(bp.stdout)
-	stderrStr, stderrTR := processOutput(bp.stderr)
+	stdoutStr, stdoutTR := processOutput(bp.stdout, StrategyTail, "")
+	stderrStr, stderrTR := processOutput(bp.stderr, StrategyTail, "")
 
 	var b strings.Builder
 	if done {
@@ -139,8 +139,8 @@ func (r *BackgroundRegistry) Kill(pid int) (*pipe.ToolResult, error) {
 	}
 
 	// Build result before removing from registry.
-	stdoutStr, stdoutTR := processOutput(bp.stdout)
-	stderrStr, stderrTR := processOutput(bp.stderr)
+	stdoutStr, stdoutTR := processOutput(bp.stdout, StrategyTail, "")
+	stderrStr, stderrTR := processOutput(bp.stderr, StrategyTail, "")
 
 	var b strings.Builder
 	if done {
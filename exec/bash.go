@@ -8,7 +8,9 @@ import (
 	"io"
 	"os"
 	osexec "os/exec"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -23,6 +25,7 @@ type bashExecutorArgs struct {
 	Timeout  int    `json:"timeout"`
 	CheckPID int    `json:"check_pid"`
 	KillPID  int    `json:"kill_pid"`
+	Truncate string `json:"truncate"`
 }
 
 // BashExecutorTool returns the tool definition with background parameters.
@@ -30,7 +33,8 @@ func BashExecutorTool() pipe.Tool {
 	return pipe.Tool{
 		Name: "bash",
 		Description: fmt.Sprintf(
-			"Execute a bash command. Output truncated to last %d lines or %dKB; "+
+			"Execute a bash command. Output truncated to %d lines or %dKB, keeping the part "+
+				"most likely to matter for the command (set truncate to override); "+
 				"if truncated, full output saved to temp file readable with the read tool. "+
 				"Commands exceeding timeout are auto-backgrounded.",
 			DefaultMaxLines, DefaultMaxBytes/1024,
@@ -53,6 +57,11 @@ func BashExecutorTool() pipe.Tool {
 				"kill_pid": {
 					"type": "integer",
 					"description": "Kill a backgrounded process and return final output"
+				},
+				"truncate": {
+					"type": "string",
+					"enum": ["auto", "head", "tail", "head_tail", "errors"],
+					"description": "Which part of long output to keep: head for compiler errors, tail for test summaries, head_tail for both ends, errors for lines around error messages (default: auto-detect)"
 				}
 			}
 		}`),
@@ -62,6 +71,13 @@ func BashExecutorTool() pipe.Tool {
 // BashExecutor executes bash commands with background process management.
 type BashExecutor struct {
 	bg *BackgroundRegistry
+
+	mu          sync.Mutex
+	artifactDir string
+	shell       Shell
+	paths       PathMap
+	env         []string
+	dir         string
 }
 
 // NewBashExecutor creates a BashExecutor with a fresh background registry.
@@ -69,6 +85,51 @@ func NewBashExecutor() *BashExecutor {
 	return &BashExecutor{bg: NewBackgroundRegistry()}
 }
 
+// SetArtifactDir sets the directory large outputs are offloaded to, e.g. a
+// session's artifacts folder so the files outlive the OS temp dir. Empty
+// means the OS temp dir.
+func (e *BashExecutor) SetArtifactDir(dir string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.artifactDir = dir
+}
+
+// SetShell sets where commands run, e.g. [SSHShell] for a remote host.
+// Nil means [LocalShell].
+func (e *BashExecutor) SetShell(shell Shell) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shell = shell
+}
+
+// SetPathMap sets how the workspace's paths translate to where commands
+// run, e.g. a container that mounts it elsewhere. Commands have local paths
+// rewritten before they run, and their output has them rewritten back, so
+// the paths agree with the file tools working on this machine.
+func (e *BashExecutor) SetPathMap(paths PathMap) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.paths = paths
+}
+
+// SetDir sets the local directory commands start in. Empty means the
+// process's working directory.
+func (e *BashExecutor) SetDir(dir string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dir = dir
+}
+
+// SetEnv sets variables, as "KEY=value" entries, exported to the commands
+// that run from now on, over ssh or in a container too. The shell passes
+// them through the environment or standard input, never the command line,
+// where any local user could read them.
+func (e *BashExecutor) SetEnv(env []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.env = slices.Clone(env)
+}
+
 // Execute runs a bash command or manages a background process.
 func (e *BashExecutor) Execute(ctx context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
 	var a bashExecutorArgs
@@ -76,27 +137,60 @@ func (e *BashExecutor) Execute(ctx context.Context, args json.RawMessage) (*pipe
 		return domainError(fmt.Sprintf("invalid arguments: %s", err)), nil
 	}
 
+	e.mu.Lock()
+	paths := e.paths
+	e.mu.Unlock()
+	var result *pipe.ToolResult
+	var err error
 	switch {
 	case a.CheckPID > 0:
-		return e.bg.Check(a.CheckPID)
+		result, err = e.bg.Check(a.CheckPID)
 	case a.KillPID > 0:
-		return e.bg.Kill(a.KillPID)
+		result, err = e.bg.Kill(a.KillPID)
 	case a.Command != "":
-		return e.runCommand(ctx, a)
+		a.Command = paths.ToRemote(a.Command)
+		result, err = e.runCommand(ctx, a)
 	default:
 		return domainError("one of command, check_pid, or kill_pid is required"), nil
 	}
+	if result != nil && paths != (PathMap{}) {
+		for i, c := range result.Content {
+			if text, ok := c.(pipe.TextBlock); ok {
+				result.Content[i] = pipe.TextBlock{Text: paths.ToLocal(text.Text)}
+			}
+		}
+	}
+	return result, err
 }
 
 func (e *BashExecutor) runCommand(ctx context.Context, a bashExecutorArgs) (*pipe.ToolResult, error) {
+	strategy, err := ParseTruncateStrategy(a.Truncate)
+	if err != nil {
+		return domainError(err.Error()), nil
+	}
 	timeout := 120 * time.Second
 	if a.Timeout > 0 {
 		timeout = time.Duration(a.Timeout) * time.Millisecond
 	}
 
+	e.mu.Lock()
+	shell, env, dir := e.shell, e.env, e.dir
+	e.mu.Unlock()
+	if shell == nil {
+		shell = LocalShell
+	}
+	sc := shell(a.Command, env)
+
 	// Use exec.Command (not CommandContext) so timeout doesn't auto-kill —
 	// we want to auto-background instead.
-	cmd := osexec.Command("bash", "-c", a.Command)
+	cmd := osexec.Command(sc.Argv[0], sc.Argv[1:]...)
+	cmd.Dir = dir
+	if len(sc.Env) > 0 {
+		cmd.Env = append(os.Environ(), sc.Env...)
+	}
+	if sc.Stdin != "" {
+		cmd.Stdin = strings.NewReader(sc.Stdin)
+	}
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	// Create pipes manually instead of using cmd.StdoutPipe/StderrPipe so
@@ -128,6 +222,10 @@ func (e *BashExecutor) runCommand(ctx context.Context, a bashExecutorArgs) (*pip
 
 	stdoutC := NewOutputCollector(int64(DefaultMaxBytes), rollingBufSize)
 	stderrC := NewOutputCollector(int64(DefaultMaxBytes), rollingBufSize)
+	e.mu.Lock()
+	stdoutC.SetDir(e.artifactDir)
+	stderrC.SetDir(e.artifactDir)
+	e.mu.Unlock()
 
 	stdoutDone := make(chan struct{})
 	stderrDone := make(chan struct{})
@@ -163,7 +261,7 @@ func (e *BashExecutor) runCommand(ctx context.Context, a bashExecutorArgs) (*pip
 		}
 		stdoutC.Close()
 		stderrC.Close()
-		return e.formatCompletedResult(waitErr, stdoutC, stderrC), nil
+		return e.formatCompletedResult(waitErr, a.Command, strategy, stdoutC, stderrC), nil
 
 	case <-timer.C:
 		// Timeout: auto-background.
@@ -180,8 +278,8 @@ func (e *BashExecutor) runCommand(ctx context.Context, a bashExecutorArgs) (*pip
 		go bg.watch()
 		e.bg.Register(pid, bg)
 
-		stdoutStr, _ := processOutput(stdoutC)
-		stderrStr, _ := processOutput(stderrC)
+		stdoutStr, _ := processOutput(stdoutC, StrategyTail, "")
+		stderrStr, _ := processOutput(stderrC, StrategyTail, "")
 
 		var b strings.Builder
 		fmt.Fprintf(&b, "[Command backgrounded after %s timeout (pid %d).\n", timeout, pid)
@@ -210,7 +308,7 @@ func (e *BashExecutor) runCommand(ctx context.Context, a bashExecutorArgs) (*pip
 	}
 }
 
-func (e *BashExecutor) formatCompletedResult(waitErr error, stdout, stderr *OutputCollector) *pipe.ToolResult {
+func (e *BashExecutor) formatCompletedResult(waitErr error, command string, strategy TruncateStrategy, stdout, stderr *OutputCollector) *pipe.ToolResult {
 	exitCode := 0
 	isError := false
 	if waitErr != nil {
@@ -222,17 +320,26 @@ func (e *BashExecutor) formatCompletedResult(waitErr error, stdout, stderr *Outp
 			exitCode = -1
 		}
 	}
-	return formatResult(exitCode, isError, stdout, stderr)
+	return formatResult(exitCode, isError, command, strategy, stdout, stderr)
 }
 
-// processOutput sanitizes and truncates collector output. Returns the processed
-// string and truncation metadata. For running processes, this returns a snapshot;
-// the collector's Bytes() and TotalNewlines() calls are independently locked, so
-// the line count may be slightly inconsistent with the content.
-func processOutput(c *OutputCollector) (string, TruncateResult) {
+// processOutput sanitizes and truncates collector output with strategy,
+// detecting it from command and the output for StrategyAuto. Returns the
+// processed string and truncation metadata. For running processes, this
+// returns a snapshot; the collector's Bytes() and TotalNewlines() calls are
+// independently locked, so the line count may be slightly inconsistent with
+// the content.
+func processOutput(c *OutputCollector, strategy TruncateStrategy, command string) (string, TruncateResult) {
+	if strategy == StrategyAuto {
+		strategy = DetectStrategy(command, Sanitize(string(c.Retained())))
+	}
+	// Strategies other than tail need the start of the output too.
 	raw := string(c.Bytes())
+	if strategy != StrategyTail {
+		raw = string(c.Retained())
+	}
 	clean := Sanitize(raw)
-	tr := TruncateTail(clean, DefaultMaxLines, DefaultMaxBytes)
+	tr := Truncate(clean, strategy, DefaultMaxLines, DefaultMaxBytes)
 	// Override total lines with the collector's accurate count (rolling buffer
 	// may have dropped early data). TotalNewlines() counts \n characters; add 1
 	// for an unterminated final line.
@@ -244,9 +351,9 @@ func processOutput(c *OutputCollector) (string, TruncateResult) {
 	return tr.Content, tr
 }
 
-func formatResult(exitCode int, isError bool, stdout, stderr *OutputCollector) *pipe.ToolResult {
-	stdoutStr, stdoutTR := processOutput(stdout)
-	stderrStr, stderrTR := processOutput(stderr)
+func formatResult(exitCode int, isError bool, command string, strategy TruncateStrategy, stdout, stderr *OutputCollector) *pipe.ToolResult {
+	stdoutStr, stdoutTR := processOutput(stdout, strategy, command)
+	stderrStr, stderrTR := processOutput(stderr, strategy, command)
 
 	var b strings.Builder
 	if stdoutStr != "" {
@@ -274,13 +381,27 @@ func appendOffloadNotice(b *strings.Builder, name string, tr TruncateResult, c *
 		return
 	}
 	if filePath != "" && offloadErr == nil {
-		fmt.Fprintf(b, "\n[%s: Showing last %d of %d lines. Full output: %s]",
-			name, tr.OutputLines, tr.TotalLines, filePath)
+		fmt.Fprintf(b, "\n[%s: Showing %s. Full output: %s]",
+			name, shownLines(tr), filePath)
 	} else if filePath != "" && offloadErr != nil {
-		fmt.Fprintf(b, "\n[%s: Showing last %d of %d lines. Full output file may be incomplete: %s (%s)]",
-			name, tr.OutputLines, tr.TotalLines, filePath, offloadErr)
+		fmt.Fprintf(b, "\n[%s: Showing %s. Full output file may be incomplete: %s (%s)]",
+			name, shownLines(tr), filePath, offloadErr)
 	} else if tr.Truncated {
-		fmt.Fprintf(b, "\n[%s: Showing last %d of %d lines]",
-			name, tr.OutputLines, tr.TotalLines)
+		fmt.Fprintf(b, "\n[%s: Showing %s]", name, shownLines(tr))
+	}
+}
+
+// shownLines describes which lines a truncated result kept, e.g.
+// "last 2000 of 5000 lines".
+func shownLines(tr TruncateResult) string {
+	switch tr.Strategy {
+	case StrategyHead:
+		return fmt.Sprintf("first %d of %d lines", tr.OutputLines, tr.TotalLines)
+	case StrategyHeadTail:
+		return fmt.Sprintf("first and last %d of %d lines", tr.OutputLines, tr.TotalLines)
+	case StrategyErrors:
+		return fmt.Sprintf("%d of %d lines around errors", tr.OutputLines, tr.TotalLines)
+	default:
+		return fmt.Sprintf("last %d of %d lines", tr.OutputLines, tr.TotalLines)
 	}
 }
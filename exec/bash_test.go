@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"syscall"
@@ -55,6 +56,65 @@ func TestBashExecutorTool(t *testing.T) {
 func TestBashExecutor(t *testing.T) {
 	t.Parallel()
 
+	t.Run("runs commands through the configured shell", func(t *testing.T) {
+		t.Parallel()
+		e := pipeexec.NewBashExecutor()
+		e.SetShell(func(command string, env []string) pipeexec.ShellCommand {
+			return pipeexec.ShellCommand{Argv: []string{"bash", "-c", "echo via shell: " + command}}
+		})
+		result, err := e.Execute(context.Background(), mustJSON(t, map[string]any{
+			"command": "ls",
+		}))
+		require.NoError(t, err)
+		assert.Contains(t, resultText(t, result), "stdout:\nvia shell: ls\n")
+	})
+
+	t.Run("translates paths between the workspace and the backend", func(t *testing.T) {
+		t.Parallel()
+		e := pipeexec.NewBashExecutor()
+		e.SetPathMap(pipeexec.PathMap{Local: "/home/dev/app", Remote: "/src"})
+		result, err := e.Execute(context.Background(), mustJSON(t, map[string]any{
+			"command": "echo /home/dev/app/main.go | tr / :; echo /src/go.mod",
+		}))
+		require.NoError(t, err)
+		assert.Contains(t, resultText(t, result), "stdout:\n:src:main.go\n/home/dev/app/go.mod\n")
+	})
+
+	t.Run("exports the environment set", func(t *testing.T) {
+		t.Parallel()
+		e := pipeexec.NewBashExecutor()
+		e.SetEnv([]string{"PIPE_TEST_DB=postgres://localhost/test db", "PIPE_TEST_QUOTE=it's"})
+		result, err := e.Execute(context.Background(), mustJSON(t, map[string]any{
+			"command": `echo "$PIPE_TEST_DB|$PIPE_TEST_QUOTE"`,
+		}))
+		require.NoError(t, err)
+		assert.Contains(t, resultText(t, result), "stdout:\npostgres://localhost/test db|it's\n")
+	})
+
+	t.Run("keeps environment values off the command line", func(t *testing.T) {
+		t.Parallel()
+		e := pipeexec.NewBashExecutor()
+		e.SetEnv([]string{"PIPE_TEST_SECRET=hidden"})
+		result, err := e.Execute(context.Background(), mustJSON(t, map[string]any{
+			"command": `tr '\0' ' ' < /proc/$$/cmdline`,
+		}))
+		require.NoError(t, err)
+		text := resultText(t, result)
+		assert.Contains(t, text, "cmdline")
+		assert.NotContains(t, text, "hidden")
+	})
+
+	t.Run("starts commands in the directory set", func(t *testing.T) {
+		t.Parallel()
+		dir, err := filepath.EvalSymlinks(t.TempDir())
+		require.NoError(t, err)
+		e := pipeexec.NewBashExecutor()
+		e.SetDir(dir)
+		result, err := e.Execute(context.Background(), mustJSON(t, map[string]any{"command": "pwd -P"}))
+		require.NoError(t, err)
+		assert.Contains(t, resultText(t, result), "stdout:\n"+dir+"\n")
+	})
+
 	t.Run("separates stdout and stderr", func(t *testing.T) {
 		t.Parallel()
 		e := pipeexec.NewBashExecutor()
@@ -93,6 +153,44 @@ func TestBashExecutor(t *testing.T) {
 		assert.Contains(t, text, "fail")
 	})
 
+	t.Run("truncate keeps the head when requested", func(t *testing.T) {
+		t.Parallel()
+		e := pipeexec.NewBashExecutor()
+		result, err := e.Execute(context.Background(), mustJSON(t, map[string]any{
+			"command":  fmt.Sprintf("seq 1 %d", pipeexec.DefaultMaxLines+1000),
+			"truncate": "head",
+		}))
+		require.NoError(t, err)
+		text := resultText(t, result)
+		assert.Contains(t, text, "stdout:\n1\n2\n")
+		assert.Contains(t, text, fmt.Sprintf("Showing first %d of %d lines", pipeexec.DefaultMaxLines, pipeexec.DefaultMaxLines+1000))
+		assert.NotContains(t, text, fmt.Sprintf("\n%d\n", pipeexec.DefaultMaxLines+1000))
+	})
+
+	t.Run("compiler output keeps the head automatically", func(t *testing.T) {
+		t.Parallel()
+		e := pipeexec.NewBashExecutor()
+		result, err := e.Execute(context.Background(), mustJSON(t, map[string]any{
+			"command": fmt.Sprintf(`echo 'main.go:1:1: first error'; seq 1 %d`, pipeexec.DefaultMaxLines+1000),
+		}))
+		require.NoError(t, err)
+		text := resultText(t, result)
+		assert.Contains(t, text, "main.go:1:1: first error")
+		assert.Contains(t, text, "Showing first")
+	})
+
+	t.Run("unknown truncate strategy is an error", func(t *testing.T) {
+		t.Parallel()
+		e := pipeexec.NewBashExecutor()
+		result, err := e.Execute(context.Background(), mustJSON(t, map[string]any{
+			"command":  "echo hi",
+			"truncate": "middle",
+		}))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "unknown truncate strategy")
+	})
+
 	t.Run("truncates large stdout by line count", func(t *testing.T) {
 		t.Parallel()
 		e := pipeexec.NewBashExecutor()
@@ -128,6 +226,25 @@ func TestBashExecutor(t *testing.T) {
 		assert.NoError(t, statErr, "temp file should exist")
 	})
 
+	t.Run("offloads to the artifact dir when set", func(t *testing.T) {
+		t.Parallel()
+		dir := filepath.Join(t.TempDir(), "artifacts")
+		e := pipeexec.NewBashExecutor()
+		e.SetArtifactDir(dir)
+		result, err := e.Execute(context.Background(), mustJSON(t, map[string]any{
+			"command": `for i in $(seq 1 1000); do printf '%099d\n' $i; done`,
+		}))
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		matches := fullOutputRe.FindStringSubmatch(resultText(t, result))
+		require.NotEmpty(t, matches, "should contain Full output path")
+		assert.Equal(t, dir, filepath.Dir(matches[1]))
+		data, err := os.ReadFile(matches[1])
+		require.NoError(t, err)
+		assert.Len(t, data, 100*1000)
+	})
+
 	t.Run("returns error for invalid JSON args", func(t *testing.T) {
 		t.Parallel()
 		e := pipeexec.NewBashExecutor()
@@ -0,0 +1,81 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/fwojciec/pipe"
+)
+
+// BoundOutput keeps the first DefaultMaxLines lines or DefaultMaxBytes bytes
+// of a tool's output. Output over the limit is offloaded in full through an
+// OutputCollector to a file in dir (the OS temp dir when empty) and a notice
+// naming the file is appended, the same way bash reports large output. Name
+// labels the notice.
+func BoundOutput(name, s, dir string) string {
+	return boundText(name, s, dir, DefaultMaxLines, DefaultMaxBytes)
+}
+
+// boundText keeps the head of s within maxLines and maxBytes, offloading
+// all of s to dir when it is over either.
+func boundText(name, s, dir string, maxLines, maxBytes int) string {
+	tr := Truncate(s, StrategyHead, maxLines, maxBytes)
+	if !tr.Truncated {
+		return s
+	}
+	// A zero threshold offloads from the first write; a zero buffer keeps
+	// nothing in memory since s is already held by the caller.
+	c := NewOutputCollector(0, 0)
+	c.SetDir(dir)
+	c.Write([]byte(s))
+	c.Close()
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(tr.Content, "\n"))
+	appendOffloadNotice(&b, name, tr, c)
+	return b.String()
+}
+
+// BoundResults returns a [pipe.ToolExecutor] that keeps the text of each
+// result from next within maxBytes, whatever the tool, so one huge result
+// cannot bloat the session and every later request. The text of a result
+// over the limit is offloaded in full to a file in dir, as BoundOutput
+// does, and replaced by its head and a notice naming the file. Images are
+// kept.
+func BoundResults(next pipe.ToolExecutor, maxBytes int, dir string) pipe.ToolExecutor {
+	return &boundExecutor{next: next, maxBytes: maxBytes, dir: dir}
+}
+
+type boundExecutor struct {
+	next     pipe.ToolExecutor
+	maxBytes int
+	dir      string
+}
+
+func (e *boundExecutor) Execute(ctx context.Context, name string, args json.RawMessage) (*pipe.ToolResult, error) {
+	result, err := e.next.Execute(ctx, name, args)
+	if err != nil || result == nil {
+		return result, err
+	}
+	var texts []string
+	size := 0
+	for _, b := range result.Content {
+		if tb, ok := b.(pipe.TextBlock); ok {
+			texts = append(texts, tb.Text)
+			size += len(tb.Text)
+		}
+	}
+	if size <= e.maxBytes {
+		return result, nil
+	}
+	bounded := pipe.TextBlock{Text: boundText(name, strings.Join(texts, "\n"), e.dir, math.MaxInt, e.maxBytes)}
+	content := []pipe.ContentBlock{bounded}
+	for _, b := range result.Content {
+		if _, ok := b.(pipe.TextBlock); !ok {
+			content = append(content, b)
+		}
+	}
+	return &pipe.ToolResult{Content: content, IsError: result.IsError}, nil
+}
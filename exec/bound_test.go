@@ -0,0 +1,89 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundOutput(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns small output unchanged", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, "a:1:x\nb:2:y\n", pipeexec.BoundOutput("grep", "a:1:x\nb:2:y\n", t.TempDir()))
+	})
+
+	t.Run("keeps the head and offloads the full output", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		var b strings.Builder
+		for i := range pipeexec.DefaultMaxLines + 500 {
+			fmt.Fprintf(&b, "f.go:%d:match\n", i+1)
+		}
+
+		got := pipeexec.BoundOutput("grep", b.String(), dir)
+		assert.True(t, strings.HasPrefix(got, "f.go:1:match\n"))
+		assert.NotContains(t, got, fmt.Sprintf("f.go:%d:", pipeexec.DefaultMaxLines+1))
+		assert.Contains(t, got, fmt.Sprintf("[grep: Showing first %d of %d lines. Full output: ", pipeexec.DefaultMaxLines, pipeexec.DefaultMaxLines+500))
+
+		m := regexp.MustCompile(`Full output: (\S+)\]`).FindStringSubmatch(got)
+		require.Len(t, m, 2)
+		assert.True(t, strings.HasPrefix(m[1], dir))
+		full, err := os.ReadFile(m[1])
+		require.NoError(t, err)
+		assert.Equal(t, b.String(), string(full))
+	})
+}
+
+func TestBoundResults(t *testing.T) {
+	t.Parallel()
+	image := pipe.ImageBlock{Data: []byte("png"), MimeType: "image/png"}
+	executor := func(content ...pipe.ContentBlock) *mock.ToolExecutor {
+		return &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+			return &pipe.ToolResult{Content: content, IsError: true}, nil
+		}}
+	}
+
+	t.Run("passes small results through", func(t *testing.T) {
+		t.Parallel()
+		small := []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}, image}
+		r, err := pipeexec.BoundResults(executor(small...), 10, t.TempDir()).Execute(context.Background(), "fetch", nil)
+		require.NoError(t, err)
+		assert.Equal(t, small, r.Content)
+	})
+
+	t.Run("offloads the text of large results", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		first := strings.Repeat("a\n", 40)
+		second := strings.Repeat("b\n", 40)
+		r, err := pipeexec.BoundResults(executor(pipe.TextBlock{Text: first}, image, pipe.TextBlock{Text: second}), 100, dir).
+			Execute(context.Background(), "fetch", nil)
+		require.NoError(t, err)
+		assert.True(t, r.IsError)
+		require.Len(t, r.Content, 2)
+		assert.Equal(t, image, r.Content[1], "images are kept")
+
+		text := r.Content[0].(pipe.TextBlock).Text
+		assert.True(t, strings.HasPrefix(text, "a\na\n"))
+		assert.NotContains(t, text, strings.Repeat("b\n", 10), "the text is cut to the limit")
+		assert.Contains(t, text, "[fetch: Showing first 50 of 81 lines. Full output: ")
+		m := regexp.MustCompile(`Full output: (\S+)\]`).FindStringSubmatch(text)
+		require.Len(t, m, 2)
+		assert.True(t, strings.HasPrefix(m[1], dir))
+		full, err := os.ReadFile(m[1])
+		require.NoError(t, err)
+		assert.Equal(t, first+"\n"+second, string(full))
+	})
+}
@@ -2,12 +2,14 @@ package exec
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"sync"
 )
 
 // OutputCollector is an io.Writer that captures command output with:
 //   - A rolling buffer (last maxBuf bytes) for in-memory access
+//   - A head buffer (first maxBuf bytes) so output start survives the roll
 //   - File offloading for full output when total exceeds threshold
 //   - Total byte and line counts (accurate even after rolling buffer trims)
 //
@@ -19,11 +21,13 @@ import (
 type OutputCollector struct {
 	mu            sync.Mutex
 	buf           []byte
+	head          []byte
 	total         int64
 	totalNewlines int
 	file          *os.File
 	filePath      string
-	err           error // first I/O error encountered during offloading
+	dir           string // offload directory; OS temp dir when empty
+	err           error  // first I/O error encountered during offloading
 	closed        bool
 	threshold     int64
 	maxBuf        int
@@ -43,6 +47,15 @@ func NewOutputCollector(threshold int64, maxBuf int) *OutputCollector {
 	}
 }
 
+// SetDir sets the directory the full output is offloaded to, created on
+// first use. The default is the OS temp dir. It must be called before the
+// first Write.
+func (c *OutputCollector) SetDir(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dir = dir
+}
+
 // Write implements io.Writer. Writes after Close are no-ops.
 func (c *OutputCollector) Write(p []byte) (int, error) {
 	c.mu.Lock()
@@ -59,10 +72,13 @@ func (c *OutputCollector) Write(p []byte) (int, error) {
 	c.totalNewlines += bytes.Count(p, []byte{'\n'})
 
 	c.buf = append(c.buf, p...)
+	if room := c.maxBuf - len(c.head); room > 0 {
+		c.head = append(c.head, p[:min(room, len(p))]...)
+	}
 
 	// File offloading: flush entire buffer to file when threshold first crossed.
 	if c.file == nil && c.err == nil && c.total > c.threshold {
-		f, err := os.CreateTemp("", "pipe-bash-*.log")
+		f, err := c.createFile()
 		if err != nil {
 			c.err = err
 		} else {
@@ -88,6 +104,15 @@ func (c *OutputCollector) Write(p []byte) (int, error) {
 	return n, nil
 }
 
+func (c *OutputCollector) createFile() (*os.File, error) {
+	if c.dir != "" {
+		if err := os.MkdirAll(c.dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return os.CreateTemp(c.dir, "pipe-bash-*.log")
+}
+
 // Bytes returns a copy of the current rolling buffer content.
 func (c *OutputCollector) Bytes() []byte {
 	c.mu.Lock()
@@ -95,6 +120,29 @@ func (c *OutputCollector) Bytes() []byte {
 	return append([]byte(nil), c.buf...)
 }
 
+// Head returns a copy of the first maxBuf bytes written.
+func (c *OutputCollector) Head() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.head...)
+}
+
+// Retained returns all output still held in memory: everything when nothing
+// was dropped, otherwise the head and the rolling tail joined by a marker
+// line for the dropped middle.
+func (c *OutputCollector) Retained() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dropped := c.total - int64(len(c.head)) - int64(len(c.buf))
+	if dropped <= 0 {
+		// Head and tail meet or overlap; skip the overlap in the tail.
+		return append(append([]byte(nil), c.head...), c.buf[-dropped:]...)
+	}
+	out := append([]byte(nil), c.head...)
+	out = fmt.Appendf(out, "\n[... %d bytes not retained ...]\n", dropped)
+	return append(out, c.buf...)
+}
+
 // TotalBytes returns the total number of bytes written (not just what's in buffer).
 func (c *OutputCollector) TotalBytes() int64 {
 	c.mu.Lock()
@@ -109,7 +157,7 @@ func (c *OutputCollector) TotalNewlines() int {
 	return c.totalNewlines
 }
 
-// FilePath returns the temp file path, or empty if output was not offloaded.
+// FilePath returns the offload file path, or empty if output was not offloaded.
 func (c *OutputCollector) FilePath() string {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -57,6 +57,25 @@ func TestOutputCollector(t *testing.T) {
 		assert.True(t, strings.HasSuffix(string(buf), strings.Repeat("b", 150)))
 	})
 
+	t.Run("retains head and tail of long output", func(t *testing.T) {
+		t.Parallel()
+		c := pipeexec.NewOutputCollector(10, 10)
+		c.Write([]byte(strings.Repeat("a", 10)))
+		c.Write([]byte(strings.Repeat("b", 5)))
+		c.Write([]byte(strings.Repeat("c", 10)))
+
+		assert.Equal(t, strings.Repeat("a", 10), string(c.Head()))
+		assert.Equal(t, strings.Repeat("a", 10)+"\n[... 5 bytes not retained ...]\n"+strings.Repeat("c", 10), string(c.Retained()))
+	})
+
+	t.Run("retained output is whole when head and tail overlap", func(t *testing.T) {
+		t.Parallel()
+		c := pipeexec.NewOutputCollector(10, 10)
+		c.Write([]byte("0123456789abcde"))
+
+		assert.Equal(t, "0123456789abcde", string(c.Retained()))
+	})
+
 	t.Run("offloads to file when threshold exceeded", func(t *testing.T) {
 		t.Parallel()
 		c := pipeexec.NewOutputCollector(100, 200)
@@ -0,0 +1,120 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	osexec "os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// probeTimeout bounds each command run while fingerprinting the environment.
+const probeTimeout = time.Second
+
+// DefaultBinaries are the tools whose presence an environment fingerprint
+// reports, chosen because agents otherwise probe for them every session.
+func DefaultBinaries() []string {
+	return []string{"git", "rg", "fd", "jq", "go", "node", "npm", "python3", "cargo", "make", "docker", "kubectl", "gh"}
+}
+
+// Environment is a compact fingerprint of the machine tools run on.
+type Environment struct {
+	OS        string
+	Arch      string
+	GoVersion string   // installed go toolchain; empty when go is absent
+	Binaries  []string // available binaries, in the order probed
+	Git       string   // working tree summary; empty outside a repository
+}
+
+// DetectEnvironment fingerprints the environment for commands run in dir,
+// checking which of binaries are on PATH. Probes run concurrently and each
+// is bounded by a short timeout, so a slow git or go never stalls the
+// caller for long.
+func DetectEnvironment(ctx context.Context, dir string, binaries []string) Environment {
+	env := Environment{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	for _, name := range binaries {
+		if _, err := osexec.LookPath(name); err == nil {
+			env.Binaries = append(env.Binaries, name)
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if out, err := probe(ctx, dir, "go", "env", "GOVERSION"); err == nil {
+			env.GoVersion = strings.TrimSpace(out)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if out, err := probe(ctx, dir, "git", "status", "--porcelain=v1", "--branch"); err == nil {
+			env.Git = summarizeGitStatus(out)
+		}
+	}()
+	wg.Wait()
+	return env
+}
+
+// String renders the fingerprint as a block for the system prompt.
+func (e Environment) String() string {
+	var b strings.Builder
+	b.WriteString("<environment>\n")
+	fmt.Fprintf(&b, "os: %s/%s\n", e.OS, e.Arch)
+	if e.GoVersion != "" {
+		fmt.Fprintf(&b, "go: %s\n", e.GoVersion)
+	}
+	if len(e.Binaries) > 0 {
+		fmt.Fprintf(&b, "binaries: %s\n", strings.Join(e.Binaries, ", "))
+	}
+	if e.Git != "" {
+		fmt.Fprintf(&b, "git: %s\n", e.Git)
+	}
+	b.WriteString("</environment>")
+	return b.String()
+}
+
+// probe runs a command in dir and returns its stdout.
+func probe(ctx context.Context, dir, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	cmd := osexec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// summarizeGitStatus condenses `git status --porcelain=v1 --branch` output
+// to e.g. "branch main, 2 modified, 1 untracked".
+func summarizeGitStatus(out string) string {
+	var branch string
+	changed, untracked := 0, 0
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			branch = strings.TrimPrefix(line, "## ")
+			if name, _, ok := strings.Cut(branch, "..."); ok {
+				// Keep the ahead/behind note but drop the upstream name.
+				_, rest, _ := strings.Cut(branch, " ")
+				branch = strings.TrimSpace(name + " " + rest)
+			}
+			branch = strings.TrimPrefix(branch, "No commits yet on ")
+		case strings.HasPrefix(line, "??"):
+			untracked++
+		case line != "":
+			changed++
+		}
+	}
+	parts := []string{"branch " + branch}
+	if changed == 0 && untracked == 0 {
+		parts = append(parts, "clean")
+	}
+	if changed > 0 {
+		parts = append(parts, fmt.Sprintf("%d modified", changed))
+	}
+	if untracked > 0 {
+		parts = append(parts, fmt.Sprintf("%d untracked", untracked))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,66 @@
+package exec_test
+
+import (
+	"context"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/fwojciec/pipe/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeGitStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		out  string
+		want string
+	}{
+		{"clean", "## main\n", "branch main, clean"},
+		{"upstream with changes", "## main...origin/main [ahead 2]\n M a.go\nA  b.go\n?? c.go\n", "branch main [ahead 2], 2 modified, 1 untracked"},
+		{"fresh repository", "## No commits yet on main\n?? README.md\n", "branch main, 1 untracked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, exec.SummarizeGitStatus(tt.out))
+		})
+	}
+}
+
+func TestDetectEnvironment(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reports platform and available binaries", func(t *testing.T) {
+		t.Parallel()
+		env := exec.DetectEnvironment(context.Background(), t.TempDir(), []string{"sh", "pipe-no-such-binary"})
+		assert.Equal(t, runtime.GOOS, env.OS)
+		assert.Equal(t, runtime.GOARCH, env.Arch)
+		assert.Equal(t, []string{"sh"}, env.Binaries)
+		assert.Empty(t, env.Git)
+	})
+
+	t.Run("summarizes the git working tree", func(t *testing.T) {
+		t.Parallel()
+		if _, err := osexec.LookPath("git"); err != nil {
+			t.Skip("git not installed")
+		}
+		dir := t.TempDir()
+		require.NoError(t, osexec.Command("git", "-C", dir, "init", "-q", "-b", "trunk").Run())
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new.txt"), []byte("x"), 0o644))
+
+		env := exec.DetectEnvironment(context.Background(), dir, nil)
+		assert.Equal(t, "branch trunk, 1 untracked", env.Git)
+	})
+}
+
+func TestEnvironment_String(t *testing.T) {
+	t.Parallel()
+	env := exec.Environment{OS: "linux", Arch: "amd64", GoVersion: "go1.24.1", Binaries: []string{"git", "rg"}, Git: "branch main, clean"}
+	assert.Equal(t, "<environment>\nos: linux/amd64\ngo: go1.24.1\nbinaries: git, rg\ngit: branch main, clean\n</environment>", env.String())
+}
@@ -1,4 +1,5 @@
-// Package exec provides the bash command execution tool.
+// Package exec provides the bash command execution and test runner tools,
+// and bounds the output of other tools the same way.
 package exec
 
 import "github.com/fwojciec/pipe"
@@ -0,0 +1,26 @@
+package exec
+
+// ParseGoTestOutput exposes the go test failure parser for testing.
+func ParseGoTestOutput(out string) []TestFailure {
+	return goTestRunner().parse(splitLines(out))
+}
+
+// ParseJestOutput exposes the npm/jest failure parser for testing.
+func ParseJestOutput(out string) []TestFailure {
+	return npmTestRunner().parse(splitLines(out))
+}
+
+// ParsePytestOutput exposes the pytest failure parser for testing.
+func ParsePytestOutput(out string) []TestFailure {
+	return pytestRunner().parse(splitLines(out))
+}
+
+// ExcerptGoTestOutput exposes failure excerpting with the go test marker.
+func ExcerptGoTestOutput(out string) string {
+	return excerptFailures(splitLines(out), goTestRunner().marker)
+}
+
+// SummarizeGitStatus exposes the git status summary for testing.
+func SummarizeGitStatus(out string) string {
+	return summarizeGitStatus(out)
+}
@@ -0,0 +1,136 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// ToolLimit caps how often one tool may run. Zero fields mean no limit.
+type ToolLimit struct {
+	MaxConcurrent int           // calls running at the same time
+	MaxCalls      int           // calls started per Window
+	Window        time.Duration // rate window; required when MaxCalls is set
+}
+
+// Limiter enforces per-tool concurrency and rate caps. Calls over a cap wait
+// until they are admitted or their context is done. A Limiter is safe for
+// concurrent use and keeps its state across the executors it wraps, so caps
+// hold across runs.
+type Limiter struct {
+	tools map[string]*toolLimiter
+}
+
+// NewLimiter creates a Limiter for the given per-tool limits, keyed by tool
+// name. Tools without an entry are not limited.
+func NewLimiter(limits map[string]ToolLimit) *Limiter {
+	l := &Limiter{tools: make(map[string]*toolLimiter, len(limits))}
+	for name, limit := range limits {
+		tl := &toolLimiter{limit: limit}
+		if limit.MaxConcurrent > 0 {
+			tl.slots = make(chan struct{}, limit.MaxConcurrent)
+		}
+		l.tools[name] = tl
+	}
+	return l
+}
+
+// Wrap returns a [pipe.ToolExecutor] that admits each call through l before
+// passing it to next.
+func (l *Limiter) Wrap(next pipe.ToolExecutor) pipe.ToolExecutor {
+	return &limitedExecutor{limiter: l, next: next}
+}
+
+// Acquire waits until a call to the named tool is admitted and returns a
+// function that releases its concurrency slot. It returns ctx.Err() if ctx
+// is done first.
+func (l *Limiter) Acquire(ctx context.Context, name string) (release func(), err error) {
+	tl, ok := l.tools[name]
+	if !ok {
+		return func() {}, nil
+	}
+	if tl.slots != nil {
+		select {
+		case tl.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		if tl.slots != nil {
+			<-tl.slots
+		}
+	}
+	if err := tl.waitForRate(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// toolLimiter holds the admission state for one tool.
+type toolLimiter struct {
+	limit ToolLimit
+	slots chan struct{} // buffered to MaxConcurrent; nil when unlimited
+
+	mu     sync.Mutex
+	starts []time.Time // call start times within the rate window, oldest first
+}
+
+// waitForRate blocks until starting another call stays within the rate cap,
+// then records the start.
+func (tl *toolLimiter) waitForRate(ctx context.Context) error {
+	if tl.limit.MaxCalls <= 0 || tl.limit.Window <= 0 {
+		return nil
+	}
+	for {
+		wait := tl.reserve(time.Now())
+		if wait <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve records a call start at t if the rate cap allows it and returns 0;
+// otherwise it returns how long until the oldest start leaves the window.
+func (tl *toolLimiter) reserve(t time.Time) time.Duration {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	cutoff := t.Add(-tl.limit.Window)
+	i := 0
+	for i < len(tl.starts) && !tl.starts[i].After(cutoff) {
+		i++
+	}
+	tl.starts = tl.starts[i:]
+	if len(tl.starts) < tl.limit.MaxCalls {
+		tl.starts = append(tl.starts, t)
+		return 0
+	}
+	return tl.starts[0].Sub(cutoff)
+}
+
+// limitedExecutor admits calls through a Limiter before executing them.
+type limitedExecutor struct {
+	limiter *Limiter
+	next    pipe.ToolExecutor
+}
+
+// Execute waits for admission, then runs the call with the wrapped executor.
+func (e *limitedExecutor) Execute(ctx context.Context, name string, args json.RawMessage) (*pipe.ToolResult, error) {
+	release, err := e.limiter.Acquire(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return e.next.Execute(ctx, name, args)
+}
@@ -0,0 +1,112 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("caps concurrent calls per tool", func(t *testing.T) {
+		t.Parallel()
+		var running, peak atomic.Int32
+		next := &mock.ToolExecutor{ExecuteFn: func(_ context.Context, _ string, _ json.RawMessage) (*pipe.ToolResult, error) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			running.Add(-1)
+			return &pipe.ToolResult{}, nil
+		}}
+		e := pipeexec.NewLimiter(map[string]pipeexec.ToolLimit{"bash": {MaxConcurrent: 1}}).Wrap(next)
+
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := e.Execute(context.Background(), "bash", nil)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), peak.Load())
+	})
+
+	t.Run("unlimited tools run concurrently", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		var started sync.WaitGroup
+		started.Add(2)
+		next := &mock.ToolExecutor{ExecuteFn: func(_ context.Context, _ string, _ json.RawMessage) (*pipe.ToolResult, error) {
+			started.Done()
+			<-release
+			return &pipe.ToolResult{}, nil
+		}}
+		e := pipeexec.NewLimiter(map[string]pipeexec.ToolLimit{"bash": {MaxConcurrent: 1}}).Wrap(next)
+
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = e.Execute(context.Background(), "read", nil)
+			}()
+		}
+		started.Wait() // both calls are inside next at once
+		close(release)
+		wg.Wait()
+	})
+
+	t.Run("rate cap delays calls beyond the window", func(t *testing.T) {
+		t.Parallel()
+		next := &mock.ToolExecutor{ExecuteFn: func(_ context.Context, _ string, _ json.RawMessage) (*pipe.ToolResult, error) {
+			return &pipe.ToolResult{}, nil
+		}}
+		window := 100 * time.Millisecond
+		e := pipeexec.NewLimiter(map[string]pipeexec.ToolLimit{"fetch": {MaxCalls: 2, Window: window}}).Wrap(next)
+
+		start := time.Now()
+		for range 3 {
+			_, err := e.Execute(context.Background(), "fetch", nil)
+			require.NoError(t, err)
+		}
+		assert.GreaterOrEqual(t, time.Since(start), window)
+	})
+
+	t.Run("waiting call honors context cancellation", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		entered := make(chan struct{})
+		next := &mock.ToolExecutor{ExecuteFn: func(_ context.Context, _ string, _ json.RawMessage) (*pipe.ToolResult, error) {
+			close(entered)
+			<-release
+			return &pipe.ToolResult{}, nil
+		}}
+		e := pipeexec.NewLimiter(map[string]pipeexec.ToolLimit{"bash": {MaxConcurrent: 1}}).Wrap(next)
+
+		go func() { _, _ = e.Execute(context.Background(), "bash", nil) }()
+		<-entered
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := e.Execute(ctx, "bash", nil)
+		require.ErrorIs(t, err, context.Canceled)
+		close(release)
+	})
+}
@@ -0,0 +1,63 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fwojciec/pipe"
+)
+
+// Compile-time interface check.
+var _ pipe.ToolExecutor = (*Registry)(nil)
+
+// ExecuteFunc runs one call of a registered tool.
+type ExecuteFunc func(ctx context.Context, args json.RawMessage) (*pipe.ToolResult, error)
+
+// Registry is a [pipe.ToolExecutor] that dispatches calls by tool name to
+// the funcs registered for them, so a tool's definition and implementation
+// are added in one place. Tools are kept in registration order. The zero
+// value is an empty registry.
+type Registry struct {
+	entries []registryEntry
+}
+
+type registryEntry struct {
+	name string
+	tool pipe.Tool
+	fn   ExecuteFunc
+}
+
+// Register adds a tool under name. Registering a name again replaces the
+// earlier tool in place.
+func (r *Registry) Register(name string, tool pipe.Tool, fn ExecuteFunc) {
+	e := registryEntry{name: name, tool: tool, fn: fn}
+	for i := range r.entries {
+		if r.entries[i].name == name {
+			r.entries[i] = e
+			return
+		}
+	}
+	r.entries = append(r.entries, e)
+}
+
+// Tools returns the definitions of the registered tools in registration
+// order.
+func (r *Registry) Tools() []pipe.Tool {
+	tools := make([]pipe.Tool, len(r.entries))
+	for i, e := range r.entries {
+		tools[i] = e.tool
+	}
+	return tools
+}
+
+// Execute runs the tool registered under name. Unknown names return an
+// IsError result so the model can self-correct.
+func (r *Registry) Execute(ctx context.Context, name string, args json.RawMessage) (*pipe.ToolResult, error) {
+	for _, e := range r.entries {
+		if e.name == name {
+			return e.fn(ctx, args)
+		}
+	}
+	return domainError(fmt.Sprintf("unknown tool: %s", name)), nil
+}
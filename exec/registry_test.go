@@ -0,0 +1,63 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	text := func(s string) pipeexec.ExecuteFunc {
+		return func(context.Context, json.RawMessage) (*pipe.ToolResult, error) {
+			return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: s}}}, nil
+		}
+	}
+
+	t.Run("dispatches by tool name", func(t *testing.T) {
+		t.Parallel()
+		var r pipeexec.Registry
+		r.Register("a", pipe.Tool{Name: "a"}, text("from a"))
+		r.Register("b", pipe.Tool{Name: "b"}, text("from b"))
+
+		result, err := r.Execute(context.Background(), "b", json.RawMessage(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "from b"}}, result.Content)
+	})
+
+	t.Run("returns a domain error for unknown tools", func(t *testing.T) {
+		t.Parallel()
+		var r pipeexec.Registry
+		result, err := r.Execute(context.Background(), "missing", json.RawMessage(`{}`))
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "unknown tool: missing"}}, result.Content)
+	})
+
+	t.Run("lists tools in registration order", func(t *testing.T) {
+		t.Parallel()
+		var r pipeexec.Registry
+		r.Register("b", pipe.Tool{Name: "b"}, text("b"))
+		r.Register("a", pipe.Tool{Name: "a"}, text("a"))
+		assert.Equal(t, []pipe.Tool{{Name: "b"}, {Name: "a"}}, r.Tools())
+	})
+
+	t.Run("registering a name again replaces the tool in place", func(t *testing.T) {
+		t.Parallel()
+		var r pipeexec.Registry
+		r.Register("a", pipe.Tool{Name: "a"}, text("old"))
+		r.Register("b", pipe.Tool{Name: "b"}, text("b"))
+		r.Register("a", pipe.Tool{Name: "a", Description: "new"}, text("new"))
+
+		assert.Equal(t, []pipe.Tool{{Name: "a", Description: "new"}, {Name: "b"}}, r.Tools())
+		result, err := r.Execute(context.Background(), "a", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "new"}}, result.Content)
+	})
+}
@@ -0,0 +1,63 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fwojciec/pipe"
+)
+
+// Compile-time interface check.
+var _ pipe.ToolExecutor = (*Router)(nil)
+
+// ErrUnknownTool is returned by a fallback executor of a [Router] that does
+// not run the named tool, passing the call on to the next fallback.
+var ErrUnknownTool = errors.New("unknown tool")
+
+// Router is a [pipe.ToolExecutor] that dispatches calls by tool name to
+// other executors. Calls to names without a route go to the fallbacks in
+// the order they were added; calls no executor runs return an IsError
+// result so the model can self-correct. The zero value routes nothing.
+//
+// A [Registry] answers every name, so route it under the names of its
+// tools rather than adding it as a fallback.
+type Router struct {
+	routes    map[string]pipe.ToolExecutor
+	fallbacks []pipe.ToolExecutor
+}
+
+// Route sends calls to the named tools to next. Routing a name again
+// replaces the earlier route.
+func (r *Router) Route(next pipe.ToolExecutor, names ...string) {
+	if r.routes == nil {
+		r.routes = make(map[string]pipe.ToolExecutor, len(names))
+	}
+	for _, name := range names {
+		r.routes[name] = next
+	}
+}
+
+// Fallback adds an executor for calls to names without a route. It is
+// tried after the fallbacks added before it, and passes a call on by
+// returning an error wrapping [ErrUnknownTool].
+func (r *Router) Fallback(next pipe.ToolExecutor) {
+	r.fallbacks = append(r.fallbacks, next)
+}
+
+// Execute runs the call on the executor routed for name, or on the first
+// fallback that runs it.
+func (r *Router) Execute(ctx context.Context, name string, args json.RawMessage) (*pipe.ToolResult, error) {
+	if next, ok := r.routes[name]; ok {
+		return next.Execute(ctx, name, args)
+	}
+	for _, next := range r.fallbacks {
+		result, err := next.Execute(ctx, name, args)
+		if errors.Is(err, ErrUnknownTool) {
+			continue
+		}
+		return result, err
+	}
+	return domainError(fmt.Sprintf("unknown tool: %s", name)), nil
+}
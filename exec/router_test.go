@@ -0,0 +1,90 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+
+	// answer returns an executor that reports its label and the tool name.
+	answer := func(label string) *mock.ToolExecutor {
+		return &mock.ToolExecutor{ExecuteFn: func(_ context.Context, name string, _ json.RawMessage) (*pipe.ToolResult, error) {
+			return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: label + ":" + name}}}, nil
+		}}
+	}
+	// only returns an executor that runs name and passes on everything else.
+	only := func(label, name string) *mock.ToolExecutor {
+		return &mock.ToolExecutor{ExecuteFn: func(ctx context.Context, n string, args json.RawMessage) (*pipe.ToolResult, error) {
+			if n != name {
+				return nil, fmt.Errorf("%s: %w", n, pipeexec.ErrUnknownTool)
+			}
+			return answer(label).Execute(ctx, n, args)
+		}}
+	}
+	text := func(t *testing.T, r *pipeexec.Router, name string) string {
+		t.Helper()
+		result, err := r.Execute(context.Background(), name, json.RawMessage(`{}`))
+		require.NoError(t, err)
+		require.Len(t, result.Content, 1)
+		return result.Content[0].(pipe.TextBlock).Text
+	}
+
+	t.Run("dispatches by tool name", func(t *testing.T) {
+		t.Parallel()
+		var r pipeexec.Router
+		r.Route(answer("files"), "read", "write")
+		r.Route(answer("shell"), "bash")
+		assert.Equal(t, "files:write", text(t, &r, "write"))
+		assert.Equal(t, "shell:bash", text(t, &r, "bash"))
+	})
+
+	t.Run("routing a name again replaces the route", func(t *testing.T) {
+		t.Parallel()
+		var r pipeexec.Router
+		r.Route(answer("old"), "bash")
+		r.Route(answer("new"), "bash")
+		assert.Equal(t, "new:bash", text(t, &r, "bash"))
+	})
+
+	t.Run("tries fallbacks in order", func(t *testing.T) {
+		t.Parallel()
+		var r pipeexec.Router
+		r.Route(answer("routed"), "bash")
+		r.Fallback(only("mcp", "search"))
+		r.Fallback(answer("catchall"))
+		assert.Equal(t, "routed:bash", text(t, &r, "bash"))
+		assert.Equal(t, "mcp:search", text(t, &r, "search"))
+		assert.Equal(t, "catchall:other", text(t, &r, "other"))
+	})
+
+	t.Run("returns a domain error for unknown tools", func(t *testing.T) {
+		t.Parallel()
+		var r pipeexec.Router
+		r.Fallback(only("mcp", "search"))
+		result, err := r.Execute(context.Background(), "missing", nil)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "unknown tool: missing"}}, result.Content)
+	})
+
+	t.Run("passes on other fallback errors", func(t *testing.T) {
+		t.Parallel()
+		var r pipeexec.Router
+		r.Fallback(&mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+			return nil, assert.AnError
+		}})
+		r.Fallback(answer("unreached"))
+		_, err := r.Execute(context.Background(), "x", nil)
+		require.ErrorIs(t, err, assert.AnError)
+	})
+}
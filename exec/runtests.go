@@ -0,0 +1,344 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+const (
+	defaultTestTimeout = 10 * time.Minute
+
+	// Lines kept around each failure when excerpting runner output.
+	failureContextBefore = 3
+	failureContextAfter  = 30
+	// Trailing lines always kept; runners print their summary last.
+	summaryLines = 15
+)
+
+// runTestsArgs holds the arguments for the run_tests tool.
+type runTestsArgs struct {
+	Dir     string `json:"dir"`
+	Filter  string `json:"filter"`
+	Timeout int    `json:"timeout"`
+}
+
+// TestFailure is a failing test parsed from test runner output.
+type TestFailure struct {
+	File    string // location such as "add_test.go:12"; may be empty
+	Test    string
+	Message string
+}
+
+// testRunner knows how to run and parse one kind of test suite.
+type testRunner struct {
+	command func(filter string) []string
+	// marker matches output lines that start a failure report.
+	marker *regexp.Regexp
+	parse  func(lines []string) []TestFailure
+}
+
+// RunTestsTool returns the tool definition for run_tests.
+func RunTestsTool() pipe.Tool {
+	return pipe.Tool{
+		Name: "run_tests",
+		Description: "Run the project's tests. Detects Go (go test), Node (npm test), and Python (pytest) " +
+			"projects, reports failing tests with file and message, and shows output around failures.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"dir": {
+					"type": "string",
+					"description": "Project directory (default: current directory)"
+				},
+				"filter": {
+					"type": "string",
+					"description": "Only run tests matching this pattern (go test -run, jest -t, pytest -k)"
+				},
+				"timeout": {
+					"type": "integer",
+					"description": "Timeout in milliseconds (default: 600000)"
+				}
+			}
+		}`),
+	}
+}
+
+// ExecuteRunTests detects the project type, runs its tests, and summarizes
+// failures.
+func ExecuteRunTests(ctx context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
+	return runTests(ctx, args, nil)
+}
+
+// RunTestsWithEnv returns an [ExecuteFunc] like [ExecuteRunTests] that
+// adds env, as "KEY=value" entries, to the test runner's environment.
+func RunTestsWithEnv(env []string) ExecuteFunc {
+	env = slices.Clone(env)
+	return func(ctx context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
+		return runTests(ctx, args, env)
+	}
+}
+
+func runTests(ctx context.Context, args json.RawMessage, env []string) (*pipe.ToolResult, error) {
+	var a runTestsArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return domainError(fmt.Sprintf("invalid arguments: %s", err)), nil
+	}
+	dir := a.Dir
+	if dir == "" {
+		dir = "."
+	}
+	runner, ok := detectTestRunner(dir)
+	if !ok {
+		return domainError(fmt.Sprintf("no supported test setup found in %s (looked for go.mod, package.json, pytest config)", dir)), nil
+	}
+
+	timeout := defaultTestTimeout
+	if a.Timeout > 0 {
+		timeout = time.Duration(a.Timeout) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	argv := runner.command(a.Filter)
+	cmd := osexec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	out := NewOutputCollector(int64(DefaultMaxBytes), rollingBufSize)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	runErr := cmd.Run()
+	out.Close()
+
+	exitCode := 0
+	if runErr != nil {
+		var exitErr *osexec.ExitError
+		switch {
+		case ctx.Err() != nil:
+			return domainError(fmt.Sprintf("%s: %s", strings.Join(argv, " "), ctx.Err())), nil
+		case errors.As(runErr, &exitErr):
+			exitCode = exitErr.ExitCode()
+		default:
+			return domainError(fmt.Sprintf("failed to run %s: %s", strings.Join(argv, " "), runErr)), nil
+		}
+	}
+
+	lines := splitLines(Sanitize(string(out.Bytes())))
+	failures := runner.parse(lines)
+	return formatTestResult(argv, exitCode, failures, excerptFailures(lines, runner.marker), out), nil
+}
+
+// detectTestRunner picks a runner from marker files in dir.
+func detectTestRunner(dir string) (testRunner, bool) {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	switch {
+	case exists("go.mod"):
+		return goTestRunner(), true
+	case exists("package.json"):
+		return npmTestRunner(), true
+	case exists("pytest.ini"), exists("conftest.py"), exists("pyproject.toml"), exists("setup.cfg"), exists("tox.ini"):
+		return pytestRunner(), true
+	default:
+		return testRunner{}, false
+	}
+}
+
+func goTestRunner() testRunner {
+	fail := regexp.MustCompile(`^\s*--- FAIL: (\S+)`)
+	loc := regexp.MustCompile(`^\s+(\S+\.go:\d+): (.*)$`)
+	buildErr := regexp.MustCompile(`^(\S+\.go:\d+(?::\d+)?): (.*)$`)
+	return testRunner{
+		command: func(filter string) []string {
+			argv := []string{"go", "test"}
+			if filter != "" {
+				argv = append(argv, "-run", filter)
+			}
+			return append(argv, "./...")
+		},
+		marker: regexp.MustCompile(`^\s*--- FAIL: |^FAIL\s|\[build failed\]|^\S+\.go:\d+:\d+: `),
+		parse: func(lines []string) []TestFailure {
+			var failures []TestFailure
+			var cur *TestFailure
+			for _, line := range lines {
+				if m := fail.FindStringSubmatch(line); m != nil {
+					failures = append(failures, TestFailure{Test: m[1]})
+					cur = &failures[len(failures)-1]
+					continue
+				}
+				if m := buildErr.FindStringSubmatch(line); m != nil {
+					failures = append(failures, TestFailure{File: m[1], Test: "(build)", Message: m[2]})
+					cur = nil
+					continue
+				}
+				if cur == nil {
+					continue
+				}
+				if m := loc.FindStringSubmatch(line); m != nil && cur.File == "" {
+					cur.File, cur.Message = m[1], m[2]
+					continue
+				}
+				if strings.HasPrefix(line, "        ") && cur.Message != "" {
+					// Continuation of a multi-line t.Error message.
+					cur.Message += "\n" + strings.TrimSpace(line)
+					continue
+				}
+				if !strings.HasPrefix(line, " ") {
+					cur = nil
+				}
+			}
+			return failures
+		},
+	}
+}
+
+func npmTestRunner() testRunner {
+	bullet := regexp.MustCompile(`^\s*● (.+)$`)
+	return testRunner{
+		command: func(filter string) []string {
+			argv := []string{"npm", "test", "--silent"}
+			if filter != "" {
+				argv = append(argv, "--", "-t", filter)
+			}
+			return argv
+		},
+		marker: bullet,
+		parse: func(lines []string) []TestFailure {
+			var failures []TestFailure
+			for i, line := range lines {
+				m := bullet.FindStringSubmatch(line)
+				if m == nil {
+					continue
+				}
+				f := TestFailure{Test: strings.TrimSpace(m[1])}
+				for _, next := range lines[i+1:] {
+					if s := strings.TrimSpace(next); s != "" {
+						f.Message = s
+						break
+					}
+				}
+				failures = append(failures, f)
+			}
+			return failures
+		},
+	}
+}
+
+func pytestRunner() testRunner {
+	summary := regexp.MustCompile(`^(FAILED|ERROR) (\S+?)(?:::(\S+))?(?: - (.*))?$`)
+	return testRunner{
+		command: func(filter string) []string {
+			argv := []string{"pytest", "-q", "-rfE"}
+			if filter != "" {
+				argv = append(argv, "-k", filter)
+			}
+			return argv
+		},
+		marker: regexp.MustCompile(`^_{3,} .* _{3,}$|^(FAILED|ERROR) `),
+		parse: func(lines []string) []TestFailure {
+			var failures []TestFailure
+			for _, line := range lines {
+				if m := summary.FindStringSubmatch(line); m != nil {
+					failures = append(failures, TestFailure{File: m[2], Test: m[3], Message: m[4]})
+				}
+			}
+			return failures
+		},
+	}
+}
+
+// excerptFailures keeps the output lines around failure markers plus the
+// trailing summary, so failures early in long output are not lost to tail
+// truncation. Omitted ranges are marked with "...". Output without failure
+// markers is returned whole.
+func excerptFailures(lines []string, marker *regexp.Regexp) string {
+	keep := make([]bool, len(lines))
+	found := false
+	for i, line := range lines {
+		if !marker.MatchString(line) {
+			continue
+		}
+		found = true
+		for j := max(0, i-failureContextBefore); j <= min(len(lines)-1, i+failureContextAfter); j++ {
+			keep[j] = true
+		}
+	}
+	if !found {
+		return strings.Join(lines, "\n")
+	}
+	for j := max(0, len(lines)-summaryLines); j < len(lines); j++ {
+		keep[j] = true
+	}
+
+	var b strings.Builder
+	skipped := false
+	for i, line := range lines {
+		if !keep[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			b.WriteString("...\n")
+			skipped = false
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func formatTestResult(argv []string, exitCode int, failures []TestFailure, output string, c *OutputCollector) *pipe.ToolResult {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ %s\n", strings.Join(argv, " "))
+	switch {
+	case exitCode == 0:
+		b.WriteString("PASS\n")
+	case len(failures) > 0:
+		fmt.Fprintf(&b, "FAIL (exit code %d): %d failure(s)\n", exitCode, len(failures))
+	default:
+		fmt.Fprintf(&b, "FAIL (exit code %d)\n", exitCode)
+	}
+
+	if len(failures) > 0 {
+		b.WriteString("\nFailures:\n")
+		for i, f := range failures {
+			fmt.Fprintf(&b, "%d. %s", i+1, f.Test)
+			if f.File != "" {
+				fmt.Fprintf(&b, " (%s)", f.File)
+			}
+			b.WriteByte('\n')
+			if f.Message != "" {
+				fmt.Fprintf(&b, "   %s\n", strings.ReplaceAll(f.Message, "\n", "\n   "))
+			}
+		}
+	}
+
+	tr := TruncateTail(output, DefaultMaxLines, DefaultMaxBytes)
+	if tr.Content != "" {
+		fmt.Fprintf(&b, "\nOutput:\n%s", tr.Content)
+	}
+	if path := c.FilePath(); path != "" {
+		fmt.Fprintf(&b, "\n[Full output: %s]", path)
+	} else if tr.Truncated {
+		fmt.Fprintf(&b, "\n[Showing last %d of %d lines]", tr.OutputLines, tr.TotalLines)
+	}
+
+	return &pipe.ToolResult{
+		Content: []pipe.ContentBlock{pipe.TextBlock{Text: strings.TrimRight(b.String(), "\n")}},
+		IsError: exitCode != 0,
+	}
+}
@@ -0,0 +1,142 @@
+package exec_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTestsTool(t *testing.T) {
+	t.Parallel()
+	tool := exec.RunTestsTool()
+	assert.Equal(t, "run_tests", tool.Name)
+	assert.True(t, json.Valid(tool.Parameters))
+}
+
+func TestParseGoTestOutput(t *testing.T) {
+	t.Parallel()
+	out := `=== RUN   TestAdd
+--- FAIL: TestAdd (0.00s)
+    add_test.go:10: expected 3, got 4
+        extra detail
+--- FAIL: TestSub (0.00s)
+    --- FAIL: TestSub/negative (0.00s)
+        sub_test.go:22: wrong sign
+FAIL
+FAIL	example.com/calc	0.003s
+`
+	got := exec.ParseGoTestOutput(out)
+	assert.Equal(t, []exec.TestFailure{
+		{Test: "TestAdd", File: "add_test.go:10", Message: "expected 3, got 4\nextra detail"},
+		{Test: "TestSub"},
+		{Test: "TestSub/negative", File: "sub_test.go:22", Message: "wrong sign"},
+	}, got)
+}
+
+func TestParseGoTestOutput_BuildFailure(t *testing.T) {
+	t.Parallel()
+	out := `# example.com/calc
+./add.go:5:2: undefined: foo
+FAIL	example.com/calc [build failed]
+`
+	got := exec.ParseGoTestOutput(out)
+	assert.Equal(t, []exec.TestFailure{{Test: "(build)", File: "./add.go:5:2", Message: "undefined: foo"}}, got)
+}
+
+func TestParsePytestOutput(t *testing.T) {
+	t.Parallel()
+	out := `F.                                                     [100%]
+=========================== short test summary info ===========================
+FAILED tests/test_calc.py::test_add - assert 4 == 3
+ERROR tests/test_db.py - ModuleNotFoundError: No module named 'db'
+1 failed, 1 passed, 1 error in 0.02s
+`
+	got := exec.ParsePytestOutput(out)
+	assert.Equal(t, []exec.TestFailure{
+		{File: "tests/test_calc.py", Test: "test_add", Message: "assert 4 == 3"},
+		{File: "tests/test_db.py", Message: "ModuleNotFoundError: No module named 'db'"},
+	}, got)
+}
+
+func TestParseJestOutput(t *testing.T) {
+	t.Parallel()
+	out := `FAIL src/calc.test.js
+  ● calc › adds numbers
+
+    expect(received).toBe(expected)
+`
+	got := exec.ParseJestOutput(out)
+	assert.Equal(t, []exec.TestFailure{{Test: "calc › adds numbers", Message: "expect(received).toBe(expected)"}}, got)
+}
+
+func TestExcerptFailures(t *testing.T) {
+	t.Parallel()
+	var b strings.Builder
+	b.WriteString("--- FAIL: TestEarly (0.00s)\n    a_test.go:1: early failure\n")
+	for i := range 200 {
+		fmt.Fprintf(&b, "noise line %d\n", i)
+	}
+	b.WriteString("FAIL\tpkg\t0.1s")
+
+	got := exec.ExcerptGoTestOutput(b.String())
+	assert.Contains(t, got, "early failure")
+	assert.Contains(t, got, "...")
+	assert.NotContains(t, got, "noise line 100\n")
+	assert.True(t, strings.HasSuffix(got, "FAIL\tpkg\t0.1s"))
+}
+
+func TestExecuteRunTests(t *testing.T) {
+	t.Parallel()
+
+	writeModule := func(t *testing.T, testBody string) string {
+		t.Helper()
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/calc\n\ngo 1.21\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "calc_test.go"), []byte("package calc\n\nimport \"testing\"\n\n"+testBody), 0o644))
+		return dir
+	}
+
+	t.Run("reports go test failures", func(t *testing.T) {
+		t.Parallel()
+		dir := writeModule(t, "func TestPass(t *testing.T) {}\n\nfunc TestBroken(t *testing.T) { t.Error(\"boom\") }\n")
+		args, _ := json.Marshal(map[string]any{"dir": dir})
+		result, err := exec.ExecuteRunTests(context.Background(), args)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		text := result.Content[0].(pipe.TextBlock).Text
+		assert.Contains(t, text, "$ go test ./...")
+		assert.Contains(t, text, "1 failure(s)")
+		assert.Contains(t, text, "TestBroken (calc_test.go:7)")
+		assert.Contains(t, text, "boom")
+	})
+
+	t.Run("filter selects passing tests", func(t *testing.T) {
+		t.Parallel()
+		dir := writeModule(t, "func TestPass(t *testing.T) {}\n\nfunc TestBroken(t *testing.T) { t.Error(\"boom\") }\n")
+		args, _ := json.Marshal(map[string]any{"dir": dir, "filter": "TestPass"})
+		result, err := exec.ExecuteRunTests(context.Background(), args)
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		text := result.Content[0].(pipe.TextBlock).Text
+		assert.Contains(t, text, "$ go test -run TestPass ./...")
+		assert.Contains(t, text, "PASS")
+	})
+
+	t.Run("unknown project type", func(t *testing.T) {
+		t.Parallel()
+		args, _ := json.Marshal(map[string]any{"dir": t.TempDir()})
+		result, err := exec.ExecuteRunTests(context.Background(), args)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(pipe.TextBlock).Text, "no supported test setup")
+	})
+}
@@ -0,0 +1,222 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Shell returns the command line that runs a bash command with env, given
+// as "KEY=value" entries, added to its environment. It decides where
+// commands run: on this machine or on another host.
+type Shell func(command string, env []string) ShellCommand
+
+// ShellCommand is a command line that runs a bash command, with how its
+// environment reaches it. Values of the environment are never part of
+// Argv, where any local user could read them.
+type ShellCommand struct {
+	Argv  []string
+	Env   []string // added to the environment of the process
+	Stdin string   // written to the standard input of the process
+}
+
+// LocalShell runs commands with the local bash.
+func LocalShell(command string, env []string) ShellCommand {
+	return ShellCommand{Argv: []string{"bash", "-c", command}, Env: env}
+}
+
+// SSHShell runs commands with bash on host over ssh, in dir when it is set.
+// Host is an ssh destination such as "user@build" or a Host alias from
+// ~/.ssh/config. Ssh runs in batch mode so a missing key fails the command
+// instead of waiting on a password prompt nobody can see. The environment
+// is sent on standard input, which the remote bash reads and evaluates
+// before the command; ssh would only pass variables the server accepts.
+func SSHShell(host, dir string) Shell {
+	return func(command string, env []string) ShellCommand {
+		var stdin string
+		if len(env) > 0 {
+			stdin = exportEnv(env)
+			command = `eval "$(cat)"` + "\n" + command
+		}
+		remote := "bash -c " + shellQuote(command)
+		if dir != "" {
+			remote = "cd " + shellQuote(dir) + " && " + remote
+		}
+		return ShellCommand{Argv: []string{"ssh", "-o", "BatchMode=yes", "--", host, remote}, Stdin: stdin}
+	}
+}
+
+// DockerExecShell runs commands with bash in a running container, in dir
+// when it is set.
+func DockerExecShell(container, dir string) Shell {
+	return func(command string, env []string) ShellCommand {
+		argv := append([]string{"docker", "exec"}, envNames(env)...)
+		if dir != "" {
+			argv = append(argv, "-w", dir)
+		}
+		return ShellCommand{Argv: append(argv, container, "bash", "-c", command), Env: env}
+	}
+}
+
+// DefaultContainerDir is where [DockerRunShell] mounts the workspace when no
+// directory is given.
+const DefaultContainerDir = "/workspace"
+
+// DockerRunShell runs each command with bash in a fresh container of image
+// that is removed when the command exits. The working directory at the time
+// of the command is bind-mounted at dir, /workspace when empty, and the
+// command runs there.
+func DockerRunShell(image, dir string) Shell {
+	if dir == "" {
+		dir = DefaultContainerDir
+	}
+	return func(command string, env []string) ShellCommand {
+		wd, err := os.Getwd()
+		if err != nil {
+			wd = "."
+		}
+		argv := append([]string{"docker", "run", "--rm"}, envNames(env)...)
+		argv = append(argv, "-v", wd+":"+dir, "-w", dir, "--", image, "bash", "-c", command)
+		return ShellCommand{Argv: argv, Env: env}
+	}
+}
+
+// PathMap translates paths between this machine and the backend commands
+// run on, where the workspace is mounted at another path. The zero value
+// translates nothing.
+type PathMap struct {
+	Local  string // the workspace on this machine
+	Remote string // where commands see it
+}
+
+// ToRemote returns s with paths in the local workspace rewritten to where
+// commands see them.
+func (m PathMap) ToRemote(s string) string {
+	return replaceDir(s, m.Local, m.Remote)
+}
+
+// ToLocal returns s with paths commands see rewritten to the local
+// workspace.
+func (m PathMap) ToLocal(s string) string {
+	return replaceDir(s, m.Remote, m.Local)
+}
+
+// replaceDir replaces the directory from with to where it is a whole path
+// or the start of one in s, leaving e.g. from+"2" and paths ending in from
+// alone.
+func replaceDir(s, from, to string) string {
+	if from == "" || from == to {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, from)
+		if i < 0 {
+			break
+		}
+		end := i + len(from)
+		whole := (i == 0 || !isPathByte(s[i-1])) && (end == len(s) || s[end] == '/' || !isPathByte(s[end]))
+		b.WriteString(s[:i])
+		if whole {
+			b.WriteString(to)
+		} else {
+			b.WriteString(from)
+		}
+		s = s[end:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// isPathByte reports whether c may be part of a file name or path.
+func isPathByte(c byte) bool {
+	return c == '/' || c == '.' || c == '_' || c == '-' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c >= 0x80
+}
+
+// DockerMount is a bind mount of a container: a directory of this machine
+// and where the container sees it.
+type DockerMount struct {
+	Source      string
+	Destination string
+}
+
+// InspectDockerMounts returns the bind mounts of container.
+func InspectDockerMounts(ctx context.Context, container string) ([]DockerMount, error) {
+	out, err := osexec.CommandContext(ctx, "docker", "inspect", "--type", "container", "--format", "{{json .Mounts}}", container).Output()
+	if err != nil {
+		var exitErr *osexec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("inspect container %s: %s", container, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("inspect container %s: %w", container, err)
+	}
+	var mounts []struct {
+		Type        string
+		Source      string
+		Destination string
+	}
+	if err := json.Unmarshal(out, &mounts); err != nil {
+		return nil, fmt.Errorf("inspect container %s: %w", container, err)
+	}
+	var binds []DockerMount
+	for _, m := range mounts {
+		if m.Type == "bind" {
+			binds = append(binds, DockerMount{Source: m.Source, Destination: m.Destination})
+		}
+	}
+	return binds, nil
+}
+
+// WorkspaceMount returns how paths in the workspace at dir, an absolute
+// path, translate to a container with mounts, and false if none of them
+// holds the workspace. The innermost mount holding it wins.
+func WorkspaceMount(mounts []DockerMount, dir string) (PathMap, bool) {
+	var best DockerMount
+	var remote string
+	for _, m := range mounts {
+		rel, err := filepath.Rel(m.Source, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		if remote == "" || len(m.Source) > len(best.Source) {
+			best, remote = m, filepath.Join(m.Destination, rel)
+		}
+	}
+	if remote == "" {
+		return PathMap{}, false
+	}
+	return PathMap{Local: dir, Remote: remote}, true
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// envNames returns the docker flags that pass the variables of env from
+// the docker client's environment, naming them without their values.
+func envNames(env []string) []string {
+	flags := make([]string, 0, 2*len(env))
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		flags = append(flags, "-e", key)
+	}
+	return flags
+}
+
+// exportEnv returns shell commands exporting env, given as "KEY=value"
+// entries, or "" when env is empty.
+func exportEnv(env []string) string {
+	var b strings.Builder
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		b.WriteString("export " + key + "=" + shellQuote(value) + "; ")
+	}
+	return b.String()
+}
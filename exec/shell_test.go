@@ -0,0 +1,104 @@
+package exec_test
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	pipeexec "github.com/fwojciec/pipe/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSHShell(t *testing.T) {
+	t.Parallel()
+
+	t.Run("runs the command in the remote directory", func(t *testing.T) {
+		t.Parallel()
+		argv := pipeexec.SSHShell("dev@build", "/srv/work")("go test ./...", nil).Argv
+		assert.Equal(t, []string{"ssh", "-o", "BatchMode=yes", "--", "dev@build", "cd '/srv/work' && bash -c 'go test ./...'"}, argv)
+	})
+
+	t.Run("quotes commands so the remote shell sees them unchanged", func(t *testing.T) {
+		t.Parallel()
+		argv := pipeexec.SSHShell("build", "")(`echo 'it''s' "$HOME"`, nil).Argv
+		remote := argv[len(argv)-1]
+		// The remote login shell parses the command line; sh does the same.
+		out, err := exec.Command("sh", "-c", "printf '%s' "+remote[len("bash -c "):]).Output()
+		require.NoError(t, err)
+		assert.Equal(t, `echo 'it''s' "$HOME"`, string(out))
+	})
+
+	t.Run("sends the environment on stdin, not the command line", func(t *testing.T) {
+		t.Parallel()
+		c := pipeexec.SSHShell("build", "")(`echo "$PIPE_SECRET"`, []string{"PIPE_SECRET=it's hidden"})
+		for _, arg := range c.Argv {
+			assert.NotContains(t, arg, "hidden")
+		}
+		assert.Empty(t, c.Env)
+		// Run the remote command line as the remote login shell would.
+		cmd := exec.Command("sh", "-c", c.Argv[len(c.Argv)-1])
+		cmd.Stdin = strings.NewReader(c.Stdin)
+		out, err := cmd.Output()
+		require.NoError(t, err)
+		assert.Equal(t, "it's hidden\n", string(out))
+	})
+}
+
+func TestDockerShell(t *testing.T) {
+	t.Parallel()
+
+	t.Run("exec runs in the container's default directory without a dir", func(t *testing.T) {
+		t.Parallel()
+		argv := pipeexec.DockerExecShell("dev", "")("make", nil).Argv
+		assert.Equal(t, []string{"docker", "exec", "dev", "bash", "-c", "make"}, argv)
+	})
+
+	t.Run("run mounts the working directory at dir", func(t *testing.T) {
+		t.Parallel()
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		argv := pipeexec.DockerRunShell("golang:1.24", "/src")("go test ./...", nil).Argv
+		assert.Equal(t, []string{"docker", "run", "--rm", "-v", wd + ":/src", "-w", "/src", "--", "golang:1.24", "bash", "-c", "go test ./..."}, argv)
+	})
+
+	t.Run("passes environment names on the command line and values in the environment", func(t *testing.T) {
+		t.Parallel()
+		env := []string{"PIPE_SECRET=hidden"}
+		c := pipeexec.DockerExecShell("dev", "/src")("make", env)
+		assert.Equal(t, []string{"docker", "exec", "-e", "PIPE_SECRET", "-w", "/src", "dev", "bash", "-c", "make"}, c.Argv)
+		assert.Equal(t, env, c.Env)
+	})
+}
+
+func TestPathMap(t *testing.T) {
+	t.Parallel()
+	m := pipeexec.PathMap{Local: "/home/dev/app", Remote: "/src"}
+
+	assert.Equal(t, "cd /src && cat /src/main.go", m.ToRemote("cd /home/dev/app && cat /home/dev/app/main.go"))
+	assert.Equal(t, "ls /home/dev/app2 /x/home/dev/app", m.ToRemote("ls /home/dev/app2 /x/home/dev/app"))
+	assert.Equal(t, "/home/dev/app/main.go:12: undefined: x\n(/home/dev/app)", m.ToLocal("/src/main.go:12: undefined: x\n(/src)"))
+	assert.Equal(t, "/srcs/a", m.ToLocal("/srcs/a"))
+	assert.Equal(t, "ls /a", pipeexec.PathMap{}.ToRemote("ls /a"))
+}
+
+func TestWorkspaceMount(t *testing.T) {
+	t.Parallel()
+	mounts := []pipeexec.DockerMount{
+		{Source: "/home/dev", Destination: "/home/dev"},
+		{Source: "/home/dev/app", Destination: "/src"},
+		{Source: "/var/cache", Destination: "/cache"},
+	}
+
+	m, ok := pipeexec.WorkspaceMount(mounts, "/home/dev/app/web")
+	require.True(t, ok)
+	assert.Equal(t, pipeexec.PathMap{Local: "/home/dev/app/web", Remote: "/src/web"}, m)
+
+	m, ok = pipeexec.WorkspaceMount(mounts, "/home/dev/other")
+	require.True(t, ok)
+	assert.Equal(t, pipeexec.PathMap{Local: "/home/dev/other", Remote: "/home/dev/other"}, m)
+
+	_, ok = pipeexec.WorkspaceMount(mounts, "/opt/app")
+	assert.False(t, ok)
+}
@@ -1,17 +1,22 @@
 package exec
 
-import "strings"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
 
 const (
 	DefaultMaxLines = 2000
 	DefaultMaxBytes = 50 * 1024 // 50KB
 )
 
-// TruncateResult describes the outcome of tail truncation.
+// TruncateResult describes the outcome of truncation.
 type TruncateResult struct {
 	Content         string
 	Truncated       bool
-	TruncatedBy     string // "lines" or "bytes"
+	TruncatedBy     string           // "lines" or "bytes"
+	Strategy        TruncateStrategy // set by Truncate and TruncateAroundErrors
 	TotalLines      int
 	TotalBytes      int
 	OutputLines     int
@@ -124,3 +129,256 @@ func splitLines(s string) []string {
 	s = strings.TrimSuffix(s, "\n")
 	return strings.Split(s, "\n")
 }
+
+// TruncateStrategy selects which part of long output is kept.
+type TruncateStrategy string
+
+const (
+	StrategyAuto     TruncateStrategy = "auto"      // detected from the command and output
+	StrategyHead     TruncateStrategy = "head"      // first lines; compiler errors come first
+	StrategyTail     TruncateStrategy = "tail"      // last lines; test summaries come last
+	StrategyHeadTail TruncateStrategy = "head_tail" // first and last lines
+	StrategyErrors   TruncateStrategy = "errors"    // lines around error messages
+)
+
+const (
+	// Lines kept around each error line by StrategyErrors.
+	errorContextBefore = 2
+	errorContextAfter  = 5
+)
+
+// ParseTruncateStrategy validates a strategy name. An empty name is
+// StrategyAuto.
+func ParseTruncateStrategy(name string) (TruncateStrategy, error) {
+	switch s := TruncateStrategy(name); s {
+	case "":
+		return StrategyAuto, nil
+	case StrategyAuto, StrategyHead, StrategyTail, StrategyHeadTail, StrategyErrors:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown truncate strategy %q (want auto, head, tail, head_tail, or errors)", name)
+	}
+}
+
+// DetectStrategy picks a truncation strategy for output of command. Test
+// runners report failures at the end, so they keep the tail; compilers and
+// linters report errors first, so they keep the head; other output with
+// error lines keeps the lines around them. Everything else keeps the tail.
+func DetectStrategy(command, output string) TruncateStrategy {
+	switch {
+	case testCommand.MatchString(command):
+		return StrategyTail
+	case buildCommand.MatchString(command), diagnosticLine.MatchString(output):
+		return StrategyHead
+	case errorLine.MatchString(output):
+		return StrategyErrors
+	default:
+		return StrategyTail
+	}
+}
+
+// testCommand matches commands that run a test runner.
+var testCommand = regexp.MustCompile(`\b(go test|pytest|jest|vitest|mocha|cargo test|npm (run )?test|yarn test|pnpm test|rspec|phpunit|ctest)\b`)
+
+// buildCommand matches commands that run a compiler or linter.
+var buildCommand = regexp.MustCompile(`\b(go (build|vet|install)|cargo (build|check|clippy)|tsc|gcc|g\+\+|clang|javac|rustc|make|mvn|gradle|golangci-lint|eslint|mypy)\b`)
+
+// diagnosticLine matches compiler diagnostics such as "main.go:12:3: ".
+var diagnosticLine = regexp.MustCompile(`(?m)^\S+\.\w+:\d+(:\d+)?: `)
+
+// errorLine matches lines that report an error.
+var errorLine = regexp.MustCompile(`(?im)\b(error|errors|failed|failure|panic|fatal|exception|traceback)\b`)
+
+// Truncate limits s with the given strategy, which must not be StrategyAuto.
+// The result records the strategy used; StrategyErrors falls back to
+// StrategyTail when s has no error lines.
+func Truncate(s string, strategy TruncateStrategy, maxLines, maxBytes int) TruncateResult {
+	var r TruncateResult
+	switch strategy {
+	case StrategyErrors:
+		return TruncateAroundErrors(s, maxLines, maxBytes)
+	case StrategyHead:
+		r = TruncateHead(s, maxLines, maxBytes)
+	case StrategyHeadTail:
+		r = TruncateHeadTail(s, maxLines, maxBytes)
+	default:
+		r = TruncateTail(s, maxLines, maxBytes)
+		strategy = StrategyTail
+	}
+	r.Strategy = strategy
+	return r
+}
+
+// TruncateHead keeps the first maxLines lines or maxBytes bytes of input,
+// whichever limit is hit first. If the first line alone exceeds maxBytes, it
+// takes the head of that line (setting LastLinePartial).
+func TruncateHead(s string, maxLines, maxBytes int) TruncateResult {
+	if s == "" {
+		return TruncateResult{}
+	}
+	lines := splitLines(s)
+	if len(lines) <= maxLines && len(s) <= maxBytes {
+		return untruncated(s, len(lines))
+	}
+
+	outputBytes := 0
+	n := 0
+	truncatedBy := "lines"
+	for n < len(lines) && n < maxLines {
+		lineBytes := len(lines[n]) + 1 // line plus its newline
+		if outputBytes+lineBytes > maxBytes {
+			truncatedBy = "bytes"
+			break
+		}
+		outputBytes += lineBytes
+		n++
+	}
+	if n == 0 {
+		head := lines[0][:min(len(lines[0]), maxBytes)]
+		return TruncateResult{
+			Content:         head,
+			Truncated:       true,
+			TruncatedBy:     "bytes",
+			TotalLines:      len(lines),
+			TotalBytes:      len(s),
+			OutputLines:     1,
+			OutputBytes:     len(head),
+			LastLinePartial: true,
+		}
+	}
+	content := strings.Join(lines[:n], "\n") + "\n"
+	return TruncateResult{
+		Content:     content,
+		Truncated:   true,
+		TruncatedBy: truncatedBy,
+		TotalLines:  len(lines),
+		TotalBytes:  len(s),
+		OutputLines: n,
+		OutputBytes: len(content),
+	}
+}
+
+// TruncateHeadTail keeps the first and last lines of input, splitting the
+// limits evenly between them, with a marker line for the omitted middle.
+func TruncateHeadTail(s string, maxLines, maxBytes int) TruncateResult {
+	if s == "" {
+		return TruncateResult{}
+	}
+	lines := splitLines(s)
+	if len(lines) <= maxLines && len(s) <= maxBytes {
+		return untruncated(s, len(lines))
+	}
+
+	head := TruncateHead(s, maxLines/2, maxBytes/2)
+	if head.LastLinePartial {
+		return head
+	}
+	rest := strings.Join(lines[head.OutputLines:], "\n")
+	if strings.HasSuffix(s, "\n") {
+		rest += "\n"
+	}
+	tail := TruncateTail(rest, maxLines-head.OutputLines, maxBytes-head.OutputBytes)
+	omitted := len(lines) - head.OutputLines - tail.OutputLines
+	content := head.Content + fmt.Sprintf("[... %d lines omitted ...]\n", omitted) + tail.Content
+	by := head.TruncatedBy
+	if tail.TruncatedBy == "bytes" {
+		by = "bytes"
+	}
+	return TruncateResult{
+		Content:     content,
+		Truncated:   true,
+		TruncatedBy: by,
+		TotalLines:  len(lines),
+		TotalBytes:  len(s),
+		OutputLines: head.OutputLines + tail.OutputLines,
+		OutputBytes: len(content),
+	}
+}
+
+// TruncateAroundErrors keeps the lines around error lines, earliest errors
+// first, until the limits are reached. Omitted ranges are marked with
+// "...". Input without error lines is truncated by TruncateTail.
+func TruncateAroundErrors(s string, maxLines, maxBytes int) TruncateResult {
+	if s == "" {
+		return TruncateResult{}
+	}
+	lines := splitLines(s)
+	if len(lines) <= maxLines && len(s) <= maxBytes {
+		r := untruncated(s, len(lines))
+		r.Strategy = StrategyErrors
+		return r
+	}
+
+	keep := make([]bool, len(lines))
+	kept, keptBytes := 0, 0
+	found, full := false, false
+	for i, line := range lines {
+		if !errorLine.MatchString(line) {
+			continue
+		}
+		found = true
+		for j := max(0, i-errorContextBefore); j <= min(len(lines)-1, i+errorContextAfter); j++ {
+			if keep[j] {
+				continue
+			}
+			if kept+1 > maxLines || keptBytes+len(lines[j])+1 > maxBytes {
+				full = true
+				break
+			}
+			keep[j] = true
+			kept++
+			keptBytes += len(lines[j]) + 1
+		}
+		if full {
+			break
+		}
+	}
+	if !found || kept == 0 {
+		r := TruncateTail(s, maxLines, maxBytes)
+		r.Strategy = StrategyTail
+		return r
+	}
+
+	var b strings.Builder
+	skipped := false
+	for i, line := range lines {
+		if !keep[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			b.WriteString("...\n")
+			skipped = false
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if skipped {
+		b.WriteString("...\n")
+	}
+	truncatedBy := "lines"
+	if full && kept < maxLines {
+		truncatedBy = "bytes"
+	}
+	return TruncateResult{
+		Content:     b.String(),
+		Truncated:   true,
+		TruncatedBy: truncatedBy,
+		Strategy:    StrategyErrors,
+		TotalLines:  len(lines),
+		TotalBytes:  len(s),
+		OutputLines: kept,
+		OutputBytes: b.Len(),
+	}
+}
+
+// untruncated describes input returned whole.
+func untruncated(s string, lines int) TruncateResult {
+	return TruncateResult{
+		Content:     s,
+		TotalLines:  lines,
+		TotalBytes:  len(s),
+		OutputLines: lines,
+		OutputBytes: len(s),
+	}
+}
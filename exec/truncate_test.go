@@ -131,3 +131,103 @@ func TestTruncateTail(t *testing.T) {
 		assert.NotContains(t, r.Content, "line 0000\n")
 	})
 }
+
+func numberedLines(n int) string {
+	lines := make([]string, n)
+	for i := range n {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestTruncateHead(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keeps the first lines", func(t *testing.T) {
+		t.Parallel()
+		r := pipeexec.TruncateHead(numberedLines(100), 10, 1024*1024)
+		assert.True(t, r.Truncated)
+		assert.Equal(t, "lines", r.TruncatedBy)
+		assert.Equal(t, 10, r.OutputLines)
+		assert.Equal(t, 100, r.TotalLines)
+		assert.True(t, strings.HasPrefix(r.Content, "line 0\n"))
+		assert.Contains(t, r.Content, "line 9\n")
+		assert.NotContains(t, r.Content, "line 10\n")
+	})
+
+	t.Run("truncates a single long line", func(t *testing.T) {
+		t.Parallel()
+		r := pipeexec.TruncateHead(strings.Repeat("x", 100)+"\n", 10, 8)
+		assert.Equal(t, "xxxxxxxx", r.Content)
+		assert.True(t, r.LastLinePartial)
+	})
+}
+
+func TestTruncateHeadTail(t *testing.T) {
+	t.Parallel()
+	r := pipeexec.TruncateHeadTail(numberedLines(100), 10, 1024*1024)
+	assert.True(t, r.Truncated)
+	assert.Equal(t, 10, r.OutputLines)
+	assert.True(t, strings.HasPrefix(r.Content, "line 0\n"))
+	assert.Contains(t, r.Content, "[... 90 lines omitted ...]\n")
+	assert.True(t, strings.HasSuffix(r.Content, "line 99\n"))
+}
+
+func TestTruncateAroundErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keeps context around error lines", func(t *testing.T) {
+		t.Parallel()
+		lines := strings.Split(strings.TrimSuffix(numberedLines(100), "\n"), "\n")
+		lines[20] = "ERROR: something broke"
+		r := pipeexec.TruncateAroundErrors(strings.Join(lines, "\n")+"\n", 50, 1024*1024)
+		assert.Equal(t, pipeexec.StrategyErrors, r.Strategy)
+		assert.Equal(t, "...\nline 18\nline 19\nERROR: something broke\nline 21\nline 22\nline 23\nline 24\nline 25\n...\n", r.Content)
+		assert.Equal(t, 8, r.OutputLines)
+	})
+
+	t.Run("falls back to tail without error lines", func(t *testing.T) {
+		t.Parallel()
+		r := pipeexec.TruncateAroundErrors(numberedLines(100), 10, 1024*1024)
+		assert.Equal(t, pipeexec.StrategyTail, r.Strategy)
+		assert.Contains(t, r.Content, "line 99")
+	})
+}
+
+func TestDetectStrategy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		command string
+		output  string
+		want    pipeexec.TruncateStrategy
+	}{
+		{"test runner keeps the tail", "go test ./...", "--- FAIL: TestX", pipeexec.StrategyTail},
+		{"compiler keeps the head", "cargo build", "warning: unused", pipeexec.StrategyHead},
+		{"diagnostics keep the head", "./build.sh", "main.go:3:1: expected declaration", pipeexec.StrategyHead},
+		{"error lines keep context", "./deploy.sh", "step 1\nError: connection refused\n", pipeexec.StrategyErrors},
+		{"plain output keeps the tail", "seq 1 10", "1\n2\n", pipeexec.StrategyTail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, pipeexec.DetectStrategy(tt.command, tt.output))
+		})
+	}
+}
+
+func TestParseTruncateStrategy(t *testing.T) {
+	t.Parallel()
+
+	s, err := pipeexec.ParseTruncateStrategy("")
+	assert.NoError(t, err)
+	assert.Equal(t, pipeexec.StrategyAuto, s)
+
+	s, err = pipeexec.ParseTruncateStrategy("head_tail")
+	assert.NoError(t, err)
+	assert.Equal(t, pipeexec.StrategyHeadTail, s)
+
+	_, err = pipeexec.ParseTruncateStrategy("middle")
+	assert.Error(t, err)
+}
@@ -7,9 +7,14 @@ import (
 	"os"
 	"strings"
 
+	"github.com/aymanbagabas/go-udiff"
 	"github.com/fwojciec/pipe"
 )
 
+// maxEditDiffLines caps the diff returned after an edit, so a replace_all
+// across a large file does not flood the context.
+const maxEditDiffLines = 60
+
 type editArgs struct {
 	FilePath   string `json:"file_path"`
 	OldString  string `json:"old_string"`
@@ -21,7 +26,7 @@ type editArgs struct {
 func EditTool() pipe.Tool {
 	return pipe.Tool{
 		Name:        "edit",
-		Description: "Replace a string in a file. Fails if old_string is not unique unless replace_all is true.",
+		Description: "Replace a string in a file and return a diff of the change. Fails if old_string is not unique unless replace_all is true.",
 		Parameters: json.RawMessage(`{
 			"type": "object",
 			"properties": {
@@ -54,8 +59,8 @@ func ExecuteEdit(_ context.Context, args json.RawMessage) (*pipe.ToolResult, err
 		return domainError(fmt.Sprintf("invalid arguments: %s", err)), nil
 	}
 
-	if a.FilePath == "" {
-		return domainError("file_path is required"), nil
+	if msg := checkPath(a.FilePath); msg != "" {
+		return domainError(msg), nil
 	}
 
 	if a.OldString == "" {
@@ -99,5 +104,17 @@ func ExecuteEdit(_ context.Context, args json.RawMessage) (*pipe.ToolResult, err
 		replacements = 1
 	}
 
-	return textResult(fmt.Sprintf("replaced %d occurrence(s) in %s", replacements, a.FilePath)), nil
+	summary := fmt.Sprintf("replaced %d occurrence(s) in %s", replacements, a.FilePath)
+	return textResult(summary + "\n\n" + editDiff(a.FilePath, content, newContent)), nil
+}
+
+// editDiff returns a unified diff of an edit to path, truncated to
+// maxEditDiffLines lines.
+func editDiff(path, before, after string) string {
+	diff := strings.TrimSuffix(udiff.Unified(path, path, before, after), "\n")
+	lines := strings.Split(diff, "\n")
+	if len(lines) <= maxEditDiffLines {
+		return diff
+	}
+	return fmt.Sprintf("%s\n... (%d more diff lines)", strings.Join(lines[:maxEditDiffLines], "\n"), len(lines)-maxEditDiffLines)
 }
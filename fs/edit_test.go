@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/fwojciec/pipe"
@@ -56,6 +57,46 @@ func TestEditTool(t *testing.T) {
 		assert.Equal(t, "func welcome() {\n\treturn \"hello\"\n}\n", string(data))
 	})
 
+	t.Run("returns a diff of the change", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "test.go")
+		require.NoError(t, os.WriteFile(path, []byte("a\nb\nc\n"), 0o644))
+
+		args, _ := json.Marshal(map[string]any{
+			"file_path":  path,
+			"old_string": "b",
+			"new_string": "B",
+		})
+		result, err := fs.ExecuteEdit(context.Background(), args)
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		text := result.Content[0].(pipe.TextBlock).Text
+		assert.Contains(t, text, "replaced 1 occurrence(s) in "+path+"\n\n--- "+path)
+		assert.Contains(t, text, "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c")
+	})
+
+	t.Run("truncates long diffs", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "test.txt")
+		require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x\n", 200)), 0o644))
+
+		args, _ := json.Marshal(map[string]any{
+			"file_path":   path,
+			"old_string":  "x",
+			"new_string":  "y",
+			"replace_all": true,
+		})
+		result, err := fs.ExecuteEdit(context.Background(), args)
+		require.NoError(t, err)
+		text := result.Content[0].(pipe.TextBlock).Text
+		assert.Contains(t, text, "replaced 200 occurrence(s)")
+		assert.Contains(t, text, "more diff lines)")
+		assert.Less(t, strings.Count(text, "\n"), 70)
+	})
+
 	t.Run("errors on non-unique match when replace_all is false", func(t *testing.T) {
 		t.Parallel()
 		dir := t.TempDir()
@@ -1,7 +1,24 @@
-// Package fs provides filesystem tools: read, write, edit, grep, and glob.
+// Package fs provides filesystem tools (read, write, edit, grep, and glob),
+// workspace snapshots for rolling back file changes, and advisory file locks.
 package fs
 
-import "github.com/fwojciec/pipe"
+import (
+	"strings"
+
+	"github.com/fwojciec/pipe"
+)
+
+// checkPath validates a file_path argument shared by the file tools. It
+// returns a message describing the problem, or "" when path is usable.
+func checkPath(path string) string {
+	switch {
+	case path == "":
+		return "file_path is required"
+	case strings.ContainsRune(path, 0):
+		return "file_path must not contain NUL bytes"
+	}
+	return ""
+}
 
 func domainError(msg string) *pipe.ToolResult {
 	return &pipe.ToolResult{
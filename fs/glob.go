@@ -7,7 +7,9 @@ import (
 	iofs "io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/fwojciec/pipe"
@@ -22,7 +24,7 @@ type globArgs struct {
 func GlobTool() pipe.Tool {
 	return pipe.Tool{
 		Name:        "glob",
-		Description: "Find files matching a glob pattern. Supports ** for recursive matching.",
+		Description: "Find files matching a glob pattern. Supports ** for recursive matching. Results are sorted by modification time, most recent first.",
 		Parameters: json.RawMessage(`{
 			"type": "object",
 			"properties": {
@@ -40,7 +42,8 @@ func GlobTool() pipe.Tool {
 	}
 }
 
-// ExecuteGlob finds files matching a glob pattern and returns their paths.
+// ExecuteGlob finds files matching a glob pattern and returns their paths,
+// most recently modified first so the files being worked on come up top.
 func ExecuteGlob(_ context.Context, args json.RawMessage) (*pipe.ToolResult, error) {
 	var a globArgs
 	if err := json.Unmarshal(args, &a); err != nil {
@@ -63,14 +66,22 @@ func ExecuteGlob(_ context.Context, args json.RawMessage) (*pipe.ToolResult, err
 		return domainError("path must be a directory"), nil
 	}
 
+	type match struct {
+		path    string
+		modTime time.Time
+	}
 	fsys := os.DirFS(a.Path)
-	var matches []string
+	var matches []match
 
 	err = doublestar.GlobWalk(fsys, a.Pattern, func(path string, d iofs.DirEntry) error {
 		if d.IsDir() {
 			return nil
 		}
-		matches = append(matches, filepath.FromSlash(path))
+		m := match{path: filepath.FromSlash(path)}
+		if info, err := d.Info(); err == nil {
+			m.modTime = info.ModTime()
+		}
+		matches = append(matches, m)
 		return nil
 	})
 	if err != nil {
@@ -81,5 +92,10 @@ func ExecuteGlob(_ context.Context, args json.RawMessage) (*pipe.ToolResult, err
 		return textResult("no matches found"), nil
 	}
 
-	return textResult(strings.Join(matches, "\n")), nil
+	slices.SortStableFunc(matches, func(a, b match) int { return b.modTime.Compare(a.modTime) })
+	paths := make([]string, len(matches))
+	for i, m := range matches {
+		paths[i] = m.path
+	}
+	return textResult(strings.Join(paths, "\n")), nil
 }
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/fwojciec/pipe"
 	"github.com/fwojciec/pipe/fs"
@@ -156,4 +157,25 @@ func TestGlobTool(t *testing.T) {
 		require.True(t, ok)
 		assert.Contains(t, text.Text, "deep.go")
 	})
+
+	t.Run("sorts matches by modification time, newest first", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		now := time.Now()
+		for i, name := range []string{"old.go", "new.go", "mid.go"} {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(path, []byte(""), 0o644))
+			mtime := now.Add(-time.Duration([]int{3, 1, 2}[i]) * time.Hour)
+			require.NoError(t, os.Chtimes(path, mtime, mtime))
+		}
+
+		args, _ := json.Marshal(map[string]any{"pattern": "*.go", "path": dir})
+		result, err := fs.ExecuteGlob(context.Background(), args)
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		text, ok := result.Content[0].(pipe.TextBlock)
+		require.True(t, ok)
+		assert.Equal(t, "new.go\nmid.go\nold.go", text.Text)
+	})
 }
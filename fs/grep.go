@@ -16,6 +16,10 @@ import (
 	"github.com/fwojciec/pipe"
 )
 
+// maxGrepLineLength caps how much of a matching line is shown, so a match
+// in minified or generated code doesn't flood the result.
+const maxGrepLineLength = 500
+
 type grepArgs struct {
 	Pattern string `json:"pattern"`
 	Path    string `json:"path"`
@@ -141,6 +145,9 @@ func grepFile(b *strings.Builder, path string, basePath string, re *regexp.Regex
 		lineNum++
 		line := scanner.Text()
 		if re.MatchString(line) {
+			if len(line) > maxGrepLineLength {
+				line = strings.ToValidUTF8(line[:maxGrepLineLength], "") + " [line truncated]"
+			}
 			fmt.Fprintf(b, "%s:%d:%s\n", relPath, lineNum, line)
 		}
 	}
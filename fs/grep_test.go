@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/fwojciec/pipe"
@@ -188,4 +189,22 @@ func TestGrepTool(t *testing.T) {
 		assert.Contains(t, text.Text, "text.txt")
 		assert.NotContains(t, text.Text, "binary.bin")
 	})
+
+	t.Run("truncates very long matching lines", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		line := "match" + strings.Repeat("x", 2000)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "min.js"), []byte(line+"\n"), 0o644))
+
+		args, _ := json.Marshal(map[string]any{"pattern": "match", "path": dir})
+		result, err := fs.ExecuteGrep(context.Background(), args)
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+
+		text, ok := result.Content[0].(pipe.TextBlock)
+		require.True(t, ok)
+		assert.Contains(t, text.Text, "min.js:1:match")
+		assert.Contains(t, text.Text, "[line truncated]")
+		assert.Less(t, len(text.Text), 600)
+	})
 }
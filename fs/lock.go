@@ -0,0 +1,79 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// LockedError reports that another process holds the lock on a file.
+type LockedError struct {
+	Path string
+	PID  int // holder's process ID; 0 when unknown
+}
+
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("%s is in use by another process", e.Path)
+	}
+	return fmt.Sprintf("%s is in use by pid %d", e.Path, e.PID)
+}
+
+// FileLock is an advisory lock on a file, held through a companion
+// "<path>.lock" file that records the holder's process ID. The lock is
+// released when the process exits, even if Unlock is never called.
+type FileLock struct {
+	f *os.File
+}
+
+// Lock acquires the lock for path without blocking. It returns a
+// *LockedError when another process holds it.
+//
+// The lock file is left in place on Unlock: removing it would let a process
+// that already opened it lock a file no one else can see.
+func Lock(path string) (*FileLock, error) {
+	lockPath := path + ".lock"
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, &LockedError{Path: path, PID: lockHolder(f)}
+		}
+		return nil, fmt.Errorf("lock %s: %w", lockPath, err)
+	}
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write lock file: %w", err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write lock file: %w", err)
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	if err := syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN); err != nil {
+		l.f.Close()
+		return fmt.Errorf("unlock: %w", err)
+	}
+	return l.f.Close()
+}
+
+// lockHolder reads the process ID recorded in a lock file, or 0.
+func lockHolder(f *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := f.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
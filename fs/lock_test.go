@@ -0,0 +1,55 @@
+package fs_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/fwojciec/pipe/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("records the holder pid", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		lock, err := fs.Lock(path)
+		require.NoError(t, err)
+		defer lock.Unlock()
+
+		data, err := os.ReadFile(path + ".lock")
+		require.NoError(t, err)
+		assert.Equal(t, strconv.Itoa(os.Getpid()), string(data))
+	})
+
+	t.Run("second lock reports the holder", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		lock, err := fs.Lock(path)
+		require.NoError(t, err)
+		defer lock.Unlock()
+
+		_, err = fs.Lock(path)
+		var locked *fs.LockedError
+		require.True(t, errors.As(err, &locked))
+		assert.Equal(t, os.Getpid(), locked.PID)
+		assert.Contains(t, err.Error(), "in use by pid "+strconv.Itoa(os.Getpid()))
+	})
+
+	t.Run("unlock lets the next process in", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		lock, err := fs.Lock(path)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
+
+		again, err := fs.Lock(path)
+		require.NoError(t, err)
+		require.NoError(t, again.Unlock())
+	})
+}
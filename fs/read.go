@@ -50,8 +50,8 @@ func ExecuteRead(_ context.Context, args json.RawMessage) (*pipe.ToolResult, err
 		return domainError(fmt.Sprintf("invalid arguments: %s", err)), nil
 	}
 
-	if a.FilePath == "" {
-		return domainError("file_path is required"), nil
+	if msg := checkPath(a.FilePath); msg != "" {
+		return domainError(msg), nil
 	}
 
 	f, err := os.Open(a.FilePath)
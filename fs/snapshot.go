@@ -0,0 +1,310 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Snapshot preserves the original state of files before they are modified so
+// the changes can be rolled back. Each file is copied into the snapshot
+// directory the first time it is saved; later saves of the same path are
+// no-ops, so the snapshot always holds the pre-modification contents. Files
+// that did not exist are recorded so rollback can remove them.
+//
+// Unlike git stash, snapshots cover untracked and ignored files too.
+type Snapshot struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]snapshotEntry // keyed by absolute path
+}
+
+type snapshotEntry struct {
+	existed bool
+	copy    string // path of the saved copy inside dir
+	mode    os.FileMode
+}
+
+// NewSnapshot creates a Snapshot that stores copies under dir. The directory
+// is created lazily on the first save.
+func NewSnapshot(dir string) *Snapshot {
+	return &Snapshot{dir: dir, files: make(map[string]snapshotEntry)}
+}
+
+// Dir returns the directory holding the saved copies.
+func (s *Snapshot) Dir() string { return s.dir }
+
+// Len returns the number of files recorded in the snapshot.
+func (s *Snapshot) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files)
+}
+
+// Save records the current state of path unless it is already recorded.
+func (s *Snapshot) Save(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("snapshot %s: %w", path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[abs]; ok {
+		return nil
+	}
+
+	info, err := os.Stat(abs)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		s.files[abs] = snapshotEntry{existed: false}
+		return nil
+	case err != nil:
+		return fmt.Errorf("snapshot %s: %w", path, err)
+	case info.IsDir():
+		return fmt.Errorf("snapshot %s: is a directory", path)
+	}
+
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	copyPath := filepath.Join(s.dir, strconv.Itoa(len(s.files)))
+	if err := os.WriteFile(copyPath, data, 0o600); err != nil {
+		return fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	s.files[abs] = snapshotEntry{existed: true, copy: copyPath, mode: info.Mode().Perm()}
+	return nil
+}
+
+// Restore puts every recorded file back in its original state: saved files
+// are rewritten and files that did not exist are removed. It returns the
+// restored paths in sorted order. On success the snapshot is emptied and its
+// directory removed.
+func (s *Snapshot) Restore() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths := make([]string, 0, len(s.files))
+	for path := range s.files {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	var errs []error
+	for _, path := range paths {
+		if err := restoreEntry(path, s.files[path]); err != nil {
+			errs = append(errs, fmt.Errorf("restore %s: %w", path, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	clear(s.files)
+	if err := os.RemoveAll(s.dir); err != nil {
+		return paths, fmt.Errorf("remove snapshot: %w", err)
+	}
+	return paths, nil
+}
+
+// FileChange summarizes how a recorded file differs from its snapshot.
+// Line counts compare the files as multisets of lines, so moved lines are
+// not counted.
+type FileChange struct {
+	Path    string // absolute path
+	Created bool   // did not exist when recorded
+	Deleted bool   // existed when recorded and is now gone
+	Added   int    // lines added
+	Removed int    // lines removed
+}
+
+// Changes compares every recorded file with its current state and returns
+// the files that differ, sorted by path.
+func (s *Snapshot) Changes() ([]FileChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changes []FileChange
+	for path, e := range s.files {
+		original, current, exists, err := readVersions(path, e)
+		if err != nil {
+			return nil, err
+		}
+		if !e.existed && !exists {
+			continue
+		}
+
+		c := FileChange{Path: path, Created: !e.existed, Deleted: !exists}
+		c.Added, c.Removed = lineChanges(string(original), string(current))
+		if !c.Created && !c.Deleted && c.Added == 0 && c.Removed == 0 {
+			continue
+		}
+		changes = append(changes, c)
+	}
+	slices.SortFunc(changes, func(a, b FileChange) int { return strings.Compare(a.Path, b.Path) })
+	return changes, nil
+}
+
+// Diff renders the changes to every recorded file as a line diff, sorted by
+// path. Each changed file gets "--- path" and "+++ path" headers followed by
+// its removed ("-") and added ("+") lines; unchanged lines are omitted and
+// separate runs of changes are divided by "@@". It returns "" when nothing
+// changed.
+func (s *Snapshot) Diff() (string, error) {
+	changes, err := s.Changes()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var b strings.Builder
+	for _, c := range changes {
+		e := s.files[c.Path]
+		original, current, _, err := readVersions(c.Path, e)
+		if err != nil {
+			return "", err
+		}
+		from, to := c.Path, c.Path
+		if c.Created {
+			from = "/dev/null"
+		}
+		if c.Deleted {
+			to = "/dev/null"
+		}
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+		writeLineDiff(&b, splitLines(string(original)), splitLines(string(current)))
+	}
+	return b.String(), nil
+}
+
+// readVersions returns the recorded and current contents of path. Missing
+// versions are empty; exists reports whether the file exists now.
+func readVersions(path string, e snapshotEntry) (original, current []byte, exists bool, err error) {
+	current, err = os.ReadFile(path)
+	exists = err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, false, fmt.Errorf("compare %s: %w", path, err)
+	}
+	if e.existed {
+		if original, err = os.ReadFile(e.copy); err != nil {
+			return nil, nil, false, fmt.Errorf("compare %s: %w", path, err)
+		}
+	}
+	return original, current, exists, nil
+}
+
+// maxDiffCells bounds the size of the table writeLineDiff builds; larger
+// files are summarized instead of diffed.
+const maxDiffCells = 4_000_000
+
+// writeLineDiff writes the changed lines between before and after, aligned
+// on their longest common subsequence.
+func writeLineDiff(b *strings.Builder, before, after []string) {
+	n, m := len(before), len(after)
+	if (n+1)*(m+1) > maxDiffCells {
+		added, removed := lineChanges(strings.Join(before, "\n"), strings.Join(after, "\n"))
+		fmt.Fprintf(b, "(too large to diff: +%d/-%d)\n", added, removed)
+		return
+	}
+	// lcs[i][j] is the common subsequence length of before[i:] and after[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	inRun, runs := false, 0
+	mark := func() {
+		if !inRun && runs > 0 {
+			b.WriteString("@@\n")
+		}
+		if !inRun {
+			runs++
+		}
+		inRun = true
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && before[i] == after[j]:
+			inRun = false
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			mark()
+			b.WriteString("-" + before[i] + "\n")
+			i++
+		default:
+			mark()
+			b.WriteString("+" + after[j] + "\n")
+			j++
+		}
+	}
+}
+
+// lineChanges counts lines present in after but not before (added) and the
+// reverse (removed), treating each text as a multiset of lines.
+func lineChanges(before, after string) (added, removed int) {
+	counts := make(map[string]int)
+	for _, line := range splitLines(before) {
+		counts[line]++
+	}
+	for _, line := range splitLines(after) {
+		if counts[line] > 0 {
+			counts[line]--
+			continue
+		}
+		added++
+	}
+	for _, n := range counts {
+		removed += n
+	}
+	return added, removed
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func restoreEntry(path string, e snapshotEntry) error {
+	if !e.existed {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := os.ReadFile(e.copy)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, e.mode); err != nil {
+		return err
+	}
+	// WriteFile only applies the mode when creating the file.
+	return os.Chmod(path, e.mode)
+}
@@ -0,0 +1,124 @@
+package fs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fwojciec/pipe/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	t.Run("restores modified files to their first saved state", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "main.go")
+		require.NoError(t, os.WriteFile(path, []byte("original"), 0o640))
+
+		snap := fs.NewSnapshot(filepath.Join(dir, ".pipe", "snapshots", "run1"))
+		require.NoError(t, snap.Save(path))
+		require.NoError(t, os.WriteFile(path, []byte("first edit"), 0o644))
+		// A second save must not overwrite the original copy.
+		require.NoError(t, snap.Save(path))
+		require.NoError(t, os.WriteFile(path, []byte("second edit"), 0o644))
+		assert.Equal(t, 1, snap.Len())
+
+		restored, err := snap.Restore()
+		require.NoError(t, err)
+		assert.Equal(t, []string{path}, restored)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "original", string(data))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+		assert.NoDirExists(t, snap.Dir())
+		assert.Equal(t, 0, snap.Len())
+	})
+
+	t.Run("removes files that did not exist", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "new", "file.txt")
+
+		snap := fs.NewSnapshot(filepath.Join(dir, "snap"))
+		require.NoError(t, snap.Save(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte("created"), 0o644))
+
+		_, err := snap.Restore()
+		require.NoError(t, err)
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("does not create directory until a file is copied", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		snap := fs.NewSnapshot(filepath.Join(dir, "snap"))
+		require.NoError(t, snap.Save(filepath.Join(dir, "missing.txt")))
+		assert.NoDirExists(t, snap.Dir())
+	})
+
+	t.Run("rejects directories", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		snap := fs.NewSnapshot(filepath.Join(dir, "snap"))
+		require.Error(t, snap.Save(dir))
+	})
+}
+
+func TestSnapshot_Changes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	modified := filepath.Join(dir, "a.go")
+	created := filepath.Join(dir, "b.go")
+	deleted := filepath.Join(dir, "c.go")
+	unchanged := filepath.Join(dir, "d.go")
+	never := filepath.Join(dir, "e.go")
+	require.NoError(t, os.WriteFile(modified, []byte("one\ntwo\nthree\n"), 0o644))
+	require.NoError(t, os.WriteFile(deleted, []byte("x\ny\n"), 0o644))
+	require.NoError(t, os.WriteFile(unchanged, []byte("same\n"), 0o644))
+
+	snap := fs.NewSnapshot(filepath.Join(dir, "snap"))
+	for _, p := range []string{modified, created, deleted, unchanged, never} {
+		require.NoError(t, snap.Save(p))
+	}
+	require.NoError(t, os.WriteFile(modified, []byte("one\n2\nthree\nfour\n"), 0o644))
+	require.NoError(t, os.WriteFile(created, []byte("package b\n"), 0o644))
+	require.NoError(t, os.Remove(deleted))
+	require.NoError(t, os.WriteFile(unchanged, []byte("same\n"), 0o644))
+
+	changes, err := snap.Changes()
+	require.NoError(t, err)
+	assert.Equal(t, []fs.FileChange{
+		{Path: modified, Added: 2, Removed: 1},
+		{Path: created, Created: true, Added: 1},
+		{Path: deleted, Deleted: true, Removed: 2},
+	}, changes)
+}
+
+func TestSnapshot_Diff(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	modified := filepath.Join(dir, "a.go")
+	created := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(modified, []byte("one\ntwo\nthree\nfour\nfive\n"), 0o644))
+
+	snap := fs.NewSnapshot(filepath.Join(dir, "snap"))
+	require.NoError(t, snap.Save(modified))
+	require.NoError(t, snap.Save(created))
+	require.NoError(t, os.WriteFile(modified, []byte("one\n2\nthree\nfour\nfive\nsix\n"), 0o644))
+	require.NoError(t, os.WriteFile(created, []byte("package b\n"), 0o644))
+
+	diff, err := snap.Diff()
+	require.NoError(t, err)
+	assert.Equal(t, "--- "+modified+"\n+++ "+modified+"\n-two\n+2\n@@\n+six\n"+
+		"--- /dev/null\n+++ "+created+"\n+package b\n", diff)
+}
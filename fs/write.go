@@ -11,15 +11,16 @@ import (
 )
 
 type writeArgs struct {
-	FilePath string `json:"file_path"`
-	Content  string `json:"content"`
+	FilePath  string `json:"file_path"`
+	Content   string `json:"content"`
+	Overwrite bool   `json:"overwrite"`
 }
 
 // WriteTool returns the tool definition for the write tool.
 func WriteTool() pipe.Tool {
 	return pipe.Tool{
 		Name:        "write",
-		Description: "Write content to a file, creating it if it doesn't exist or overwriting if it does.",
+		Description: "Write content to a file, creating it and any missing parent directories. Fails if the file exists unless overwrite is true; prefer edit for changes to existing files.",
 		Parameters: json.RawMessage(`{
 			"type": "object",
 			"properties": {
@@ -30,6 +31,10 @@ func WriteTool() pipe.Tool {
 				"content": {
 					"type": "string",
 					"description": "The content to write to the file"
+				},
+				"overwrite": {
+					"type": "boolean",
+					"description": "Replace the file if it already exists (default false)"
 				}
 			},
 			"required": ["file_path", "content"]
@@ -44,8 +49,8 @@ func ExecuteWrite(_ context.Context, args json.RawMessage) (*pipe.ToolResult, er
 		return domainError(fmt.Sprintf("invalid arguments: %s", err)), nil
 	}
 
-	if a.FilePath == "" {
-		return domainError("file_path is required"), nil
+	if msg := checkPath(a.FilePath); msg != "" {
+		return domainError(msg), nil
 	}
 
 	if err := os.MkdirAll(filepath.Dir(a.FilePath), 0o755); err != nil {
@@ -54,6 +59,12 @@ func ExecuteWrite(_ context.Context, args json.RawMessage) (*pipe.ToolResult, er
 
 	perm := os.FileMode(0o644)
 	if info, err := os.Stat(a.FilePath); err == nil {
+		if info.IsDir() {
+			return domainError(fmt.Sprintf("%s is a directory", a.FilePath)), nil
+		}
+		if !a.Overwrite {
+			return domainError(fmt.Sprintf("%s already exists; set overwrite to true to replace it, or use edit", a.FilePath)), nil
+		}
 		perm = info.Mode().Perm()
 	}
 
@@ -55,7 +55,7 @@ func TestWriteTool(t *testing.T) {
 		path := filepath.Join(dir, "existing.txt")
 		require.NoError(t, os.WriteFile(path, []byte("old content"), 0o644))
 
-		args, _ := json.Marshal(map[string]any{"file_path": path, "content": "new content"})
+		args, _ := json.Marshal(map[string]any{"file_path": path, "content": "new content", "overwrite": true})
 		result, err := fs.ExecuteWrite(context.Background(), args)
 		require.NoError(t, err)
 		require.False(t, result.IsError)
@@ -65,6 +65,41 @@ func TestWriteTool(t *testing.T) {
 		assert.Equal(t, "new content", string(data))
 	})
 
+	t.Run("refuses to overwrite without the flag", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "existing.txt")
+		require.NoError(t, os.WriteFile(path, []byte("old content"), 0o644))
+
+		args, _ := json.Marshal(map[string]any{"file_path": path, "content": "new content"})
+		result, err := fs.ExecuteWrite(context.Background(), args)
+		require.NoError(t, err)
+		require.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(pipe.TextBlock).Text, "already exists; set overwrite to true")
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "old content", string(data))
+	})
+
+	t.Run("refuses to write over a directory", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		args, _ := json.Marshal(map[string]any{"file_path": dir, "content": "x", "overwrite": true})
+		result, err := fs.ExecuteWrite(context.Background(), args)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+
+	t.Run("rejects paths with NUL bytes", func(t *testing.T) {
+		t.Parallel()
+		args, _ := json.Marshal(map[string]any{"file_path": "a\x00b", "content": "x"})
+		result, err := fs.ExecuteWrite(context.Background(), args)
+		require.NoError(t, err)
+		require.True(t, result.IsError)
+		assert.Equal(t, "file_path must not contain NUL bytes", result.Content[0].(pipe.TextBlock).Text)
+	})
+
 	t.Run("creates intermediate directories", func(t *testing.T) {
 		t.Parallel()
 		dir := t.TempDir()
@@ -86,7 +121,7 @@ func TestWriteTool(t *testing.T) {
 		path := filepath.Join(dir, "script.sh")
 		require.NoError(t, os.WriteFile(path, []byte("#!/bin/bash\necho old\n"), 0o755))
 
-		args, _ := json.Marshal(map[string]any{"file_path": path, "content": "#!/bin/bash\necho new\n"})
+		args, _ := json.Marshal(map[string]any{"file_path": path, "content": "#!/bin/bash\necho new\n", "overwrite": true})
 		result, err := fs.ExecuteWrite(context.Background(), args)
 		require.NoError(t, err)
 		require.False(t, result.IsError)
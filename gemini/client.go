@@ -5,19 +5,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/fwojciec/pipe"
 	"google.golang.org/genai"
 )
 
-// Interface compliance check.
-var _ pipe.Provider = (*Client)(nil)
+// Interface compliance checks.
+var (
+	_ pipe.Provider    = (*Client)(nil)
+	_ pipe.Embedder    = (*Client)(nil)
+	_ pipe.ModelLister = (*Client)(nil)
+)
 
 // Client implements [pipe.Provider] for the Google Gemini API.
 type Client struct {
-	client *genai.Client
-	model  string
+	client     *genai.Client
+	model      string
+	baseURL    string
+	httpClient *http.Client
+	results    pipe.ToolResultFormat
 }
 
 // Option configures a [Client].
@@ -28,22 +37,51 @@ func WithModel(model string) Option {
 	return func(c *Client) { c.model = model }
 }
 
+// WithBaseURL sets the API base URL, e.g. of a mock server. By default the
+// SDK's, which the GOOGLE_GEMINI_BASE_URL environment variable overrides.
+func WithBaseURL(url string) Option {
+	return func(c *Client) { c.baseURL = url }
+}
+
+// WithHTTPClient sets the HTTP client requests are sent with. By default
+// the SDK's.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithToolResultFormat sets how tool result text is rewritten before it is
+// sent. By default it is sent unchanged; [CompactToolResults] spends fewer
+// tokens on it.
+func WithToolResultFormat(f pipe.ToolResultFormat) Option {
+	return func(c *Client) { c.results = f }
+}
+
+// CompactToolResults returns the tool result format suited to Gemini. Tool
+// results reach the model as a string in a JSON function response, where
+// every newline and tab is escaped, so surplus whitespace is compacted
+// too, along with terminal escapes and a fence around the whole result.
+func CompactToolResults() pipe.ToolResultFormat {
+	return pipe.ToolResultFormat{StripANSI: true, CompactWhitespace: true, Unfence: true}
+}
+
 // New creates a new Gemini [Client] with the given API key and options.
 func New(ctx context.Context, apiKey string, opts ...Option) (*Client, error) {
-	gc, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("gemini: %w", err)
-	}
 	c := &Client{
-		client: gc,
-		model:  defaultModel,
+		model: DefaultModel,
 	}
 	for _, o := range opts {
 		o(c)
 	}
+	gc, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:      apiKey,
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: c.baseURL},
+		HTTPClient:  c.httpClient,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gemini: %w", err)
+	}
+	c.client = gc
 	return c, nil
 }
 
@@ -55,7 +93,7 @@ func (c *Client) Stream(ctx context.Context, req pipe.Request) (pipe.Stream, err
 		model = c.model
 	}
 
-	contents, err := ConvertMessages(req.Messages)
+	contents, err := ConvertMessages(pipe.FormatToolResults(req.Messages, c.results))
 	if err != nil {
 		return nil, fmt.Errorf("gemini: %w", err)
 	}
@@ -68,6 +106,41 @@ func (c *Client) Stream(ctx context.Context, req pipe.Request) (pipe.Stream, err
 	return newStream(ctx, iter), nil
 }
 
+// Embed returns an embedding for each text from [EmbeddingModel].
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+	resp, err := c.client.Models.EmbedContent(ctx, EmbeddingModel, contents, nil)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: %w", err)
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("gemini: got %d embeddings for %d texts", len(resp.Embeddings), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for i, e := range resp.Embeddings {
+		vectors[i] = e.Values
+	}
+	return vectors, nil
+}
+
+// ListModels returns the IDs of the models that can generate content, such
+// as "gemini-2.5-flash".
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
+	var ids []string
+	for m, err := range c.client.Models.All(ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("gemini: list models: %w", err)
+		}
+		if slices.Contains(m.SupportedActions, "generateContent") {
+			ids = append(ids, strings.TrimPrefix(m.Name, "models/"))
+		}
+	}
+	return ids, nil
+}
+
 func buildConfig(req pipe.Request) (*genai.GenerateContentConfig, error) {
 	maxTokens := req.MaxTokens
 	if maxTokens == 0 {
@@ -82,6 +155,7 @@ func buildConfig(req pipe.Request) (*genai.GenerateContentConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	tools = append(tools, ConvertServerTools(req.ServerTools)...)
 
 	config := &genai.GenerateContentConfig{
 		MaxOutputTokens: int32(maxTokens), //nolint:gosec // clamped above
@@ -104,10 +178,35 @@ func buildConfig(req pipe.Request) (*genai.GenerateContentConfig, error) {
 		temp := float32(*req.Temperature)
 		config.Temperature = &temp
 	}
+	applyOptions(config, req.ProviderOptions)
 
 	return config, nil
 }
 
+// applyOptions maps the provider options onto config.
+func applyOptions(config *genai.GenerateContentConfig, o pipe.ProviderOptions) {
+	if o.ThinkingBudget != nil {
+		budget := int32(min(*o.ThinkingBudget, math.MaxInt32)) //nolint:gosec // clamped
+		config.ThinkingConfig.ThinkingBudget = &budget
+		// A zero budget turns thinking off, leaving no thoughts to include.
+		config.ThinkingConfig.IncludeThoughts = budget > 0
+	}
+	if o.TopP != nil {
+		topP := float32(*o.TopP)
+		config.TopP = &topP
+	}
+	if o.TopK != nil {
+		topK := float32(*o.TopK)
+		config.TopK = &topK
+	}
+	for _, s := range o.SafetySettings {
+		config.SafetySettings = append(config.SafetySettings, &genai.SafetySetting{
+			Category:  genai.HarmCategory(s.Category),
+			Threshold: genai.HarmBlockThreshold(s.Threshold),
+		})
+	}
+}
+
 // ConvertMessages converts pipe Messages to genai Contents.
 // Exported for testing.
 func ConvertMessages(msgs []pipe.Message) ([]*genai.Content, error) {
@@ -124,6 +223,10 @@ func ConvertMessages(msgs []pipe.Message) ([]*genai.Content, error) {
 				Parts: parts,
 			})
 		case pipe.AssistantMessage:
+			if len(m.Content) == 0 {
+				// Failed requests are recorded as empty messages.
+				continue
+			}
 			parts, err := convertParts(m.Content)
 			if err != nil {
 				return nil, fmt.Errorf("assistant message: %w", err)
@@ -150,6 +253,9 @@ func ConvertMessages(msgs []pipe.Message) ([]*genai.Content, error) {
 					},
 				}},
 			})
+		case pipe.OpaqueMessage:
+			// Preserved from a newer version of pipe; nothing to send.
+			continue
 		default:
 			return nil, fmt.Errorf("unsupported message type: %T", msg)
 		}
@@ -185,6 +291,12 @@ func convertParts(blocks []pipe.ContentBlock) ([]*genai.Part, error) {
 				p.ThoughtSignature = bl.Signature
 			}
 			parts = append(parts, p)
+		case pipe.ServerToolCallBlock, pipe.ServerToolResultBlock:
+			// Provider-hosted tool traffic is not replayable as Gemini parts;
+			// the model's surrounding text carries the outcome.
+			continue
+		case pipe.OpaqueBlock:
+			continue
 		case pipe.ImageBlock:
 			parts = append(parts, &genai.Part{
 				InlineData: &genai.Blob{
@@ -231,3 +343,19 @@ func ConvertTools(tools []pipe.Tool) ([]*genai.Tool, error) {
 	}
 	return []*genai.Tool{{FunctionDeclarations: decls}}, nil
 }
+
+// ConvertServerTools maps provider-hosted tools onto Gemini's built-in tools.
+// Unsupported values are ignored.
+// Exported for testing.
+func ConvertServerTools(tools []pipe.ServerTool) []*genai.Tool {
+	var result []*genai.Tool
+	for _, t := range tools {
+		switch t {
+		case pipe.ServerToolWebSearch:
+			result = append(result, &genai.Tool{GoogleSearch: &genai.GoogleSearch{}})
+		case pipe.ServerToolCodeExecution:
+			result = append(result, &genai.Tool{CodeExecution: &genai.ToolCodeExecution{}})
+		}
+	}
+	return result
+}
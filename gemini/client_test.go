@@ -8,6 +8,7 @@ import (
 	"github.com/fwojciec/pipe/gemini"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
 )
 
 func TestConvertMessages_UserMessage(t *testing.T) {
@@ -38,6 +39,18 @@ func TestConvertMessages_AssistantMessage(t *testing.T) {
 	assert.Equal(t, "Let me help.", got[0].Parts[0].Text)
 }
 
+func TestConvertMessages_SkipsEmptyAssistantMessage(t *testing.T) {
+	t.Parallel()
+	msgs := []pipe.Message{
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Hello"}}},
+		pipe.AssistantMessage{StopReason: pipe.StopRateLimited},
+	}
+	got, err := gemini.ConvertMessages(msgs)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "user", got[0].Role)
+}
+
 func TestConvertMessages_ThinkingWithSignature(t *testing.T) {
 	t.Parallel()
 	sig := []byte("thought-sig-data")
@@ -300,3 +313,78 @@ func TestConvertMessages_ThinkingNoSignature(t *testing.T) {
 	assert.True(t, got[0].Parts[0].Thought)
 	assert.Nil(t, got[0].Parts[0].ThoughtSignature)
 }
+
+func TestConvertServerTools(t *testing.T) {
+	t.Parallel()
+	got := gemini.ConvertServerTools([]pipe.ServerTool{pipe.ServerToolWebSearch, pipe.ServerToolCodeExecution, "unsupported"})
+	require.Len(t, got, 2)
+	assert.NotNil(t, got[0].GoogleSearch)
+	assert.NotNil(t, got[1].CodeExecution)
+}
+
+func TestConvertMessages_ServerToolBlocksSkipped(t *testing.T) {
+	t.Parallel()
+	msgs := []pipe.Message{
+		pipe.AssistantMessage{Content: []pipe.ContentBlock{
+			pipe.ServerToolCallBlock{ID: "srv_1", Name: "web_search", Arguments: json.RawMessage(`{}`)},
+			pipe.ServerToolResultBlock{ToolCallID: "srv_1", Name: "web_search", Content: json.RawMessage(`[]`)},
+			pipe.TextBlock{Text: "Answer"},
+		}},
+	}
+	got, err := gemini.ConvertMessages(msgs)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Len(t, got[0].Parts, 1)
+	assert.Equal(t, "Answer", got[0].Parts[0].Text)
+}
+
+func TestBuildConfig_ProviderOptions(t *testing.T) {
+	t.Parallel()
+
+	config, err := gemini.BuildConfigForTest(pipe.Request{})
+	require.NoError(t, err)
+	assert.Nil(t, config.ThinkingConfig.ThinkingBudget)
+	assert.True(t, config.ThinkingConfig.IncludeThoughts)
+	assert.Nil(t, config.TopP)
+	assert.Nil(t, config.TopK)
+	assert.Empty(t, config.SafetySettings)
+
+	budget, topP, topK := 2048, 0.9, 40
+	config, err = gemini.BuildConfigForTest(pipe.Request{ProviderOptions: pipe.ProviderOptions{
+		ThinkingBudget: &budget,
+		TopP:           &topP,
+		TopK:           &topK,
+		SafetySettings: []pipe.SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH"}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2048), *config.ThinkingConfig.ThinkingBudget)
+	assert.True(t, config.ThinkingConfig.IncludeThoughts)
+	assert.InDelta(t, 0.9, *config.TopP, 1e-6)
+	assert.Equal(t, float32(40), *config.TopK)
+	assert.Equal(t, []*genai.SafetySetting{{Category: genai.HarmCategoryHarassment, Threshold: genai.HarmBlockThresholdBlockOnlyHigh}}, config.SafetySettings)
+
+	off := 0
+	config, err = gemini.BuildConfigForTest(pipe.Request{ProviderOptions: pipe.ProviderOptions{ThinkingBudget: &off}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), *config.ThinkingConfig.ThinkingBudget)
+	assert.False(t, config.ThinkingConfig.IncludeThoughts, "no thoughts to include when thinking is off")
+}
+
+func TestCompactToolResults(t *testing.T) {
+	t.Parallel()
+	output := "```\n\x1b[32m=== RUN   TestA\x1b[0m  \n\n\n--- PASS: TestA (0.00s)\t\n\n\nPASS\n```\n"
+	msgs := []pipe.Message{pipe.ToolResultMessage{ToolCallID: "c1", ToolName: "bash", Content: []pipe.ContentBlock{pipe.TextBlock{Text: output}}}}
+
+	// encoded returns the function response as it goes over the wire.
+	encoded := func(t *testing.T, f pipe.ToolResultFormat) []byte {
+		t.Helper()
+		got, err := gemini.ConvertMessages(pipe.FormatToolResults(msgs, f))
+		require.NoError(t, err)
+		data, err := json.Marshal(got[0].Parts[0].FunctionResponse.Response)
+		require.NoError(t, err)
+		return data
+	}
+	raw, compact := encoded(t, pipe.ToolResultFormat{}), encoded(t, gemini.CompactToolResults())
+	assert.JSONEq(t, `{"output": "=== RUN   TestA\n\n--- PASS: TestA (0.00s)\n\nPASS"}`, string(compact))
+	assert.Less(t, len(compact), len(raw)*2/3)
+}
@@ -2,3 +2,6 @@ package gemini
 
 // NewStreamFromIter exposes newStream for external tests.
 var NewStreamFromIter = newStream
+
+// BuildConfigForTest exposes buildConfig for external tests.
+var BuildConfigForTest = buildConfig
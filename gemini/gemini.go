@@ -6,6 +6,23 @@
 package gemini
 
 const (
-	defaultModel     = "gemini-3.1-pro-preview"
+	// DefaultModel is the model requests use when they name none.
+	DefaultModel     = "gemini-3.1-pro-preview"
 	defaultMaxTokens = 65536
+	// EmbeddingModel is the model Client.Embed uses.
+	EmbeddingModel = "gemini-embedding-001"
 )
+
+// codeExecutionArgs is the ServerToolCallBlock payload for an executable code
+// part.
+type codeExecutionArgs struct {
+	Language string `json:"language,omitempty"`
+	Code     string `json:"code"`
+}
+
+// codeExecutionResult is the ServerToolResultBlock payload for a code
+// execution result part.
+type codeExecutionResult struct {
+	Outcome string `json:"outcome"`
+	Output  string `json:"output,omitempty"`
+}
@@ -5,9 +5,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"iter"
+	"net/http"
 	"slices"
 	"strings"
 
@@ -40,13 +42,15 @@ type stream struct {
 
 	blocks      []*blockState
 	hasToolCall bool
+	codeCallID  string // ID of the last executable code part, for its result
 }
 
 // blockState tracks accumulation for a single content block.
 type blockState struct {
-	blockType string // "thinking", "text", "tool_call"
+	blockType string // "thinking", "text", "tool_call", "server_tool_call", "server_tool_result"
 	textBuf   strings.Builder
 	signature []byte
+	citations []pipe.Citation
 }
 
 // Interface compliance check.
@@ -136,17 +140,43 @@ func (s *stream) terminate(err error) {
 	s.state = pipe.StreamStateError
 	s.err = fmt.Errorf("gemini: %w", err)
 	s.stop() // Release iter.Pull2 goroutine.
-	if s.ctx.Err() != nil {
+	var apiErr genai.APIError
+	switch {
+	case s.ctx.Err() != nil:
 		s.msg.StopReason = pipe.StopAborted
 		s.msg.RawStopReason = "aborted"
-	} else if s.msg.StopReason != pipe.StopError {
-		// Preserve StopError if already set (e.g. blocked prompt), but
-		// overwrite non-error reasons like StopEndTurn.
+	case errors.As(err, &apiErr):
+		pe := classifyAPIError(apiErr, s.err)
+		s.err = pe
+		s.msg.StopReason = pe.Reason
+		s.msg.RawStopReason = pe.Raw
+	case s.msg.StopReason == pipe.StopContentFiltered:
+		// Keep the block reason of a blocked prompt.
+		s.err = &pipe.ProviderError{Reason: pipe.StopContentFiltered, Raw: s.msg.RawStopReason, Err: s.err}
+	case s.msg.StopReason != pipe.StopError:
+		// Preserve StopError if already set (e.g. malformed function call),
+		// but overwrite non-error reasons like StopEndTurn.
 		s.msg.StopReason = pipe.StopError
 		s.msg.RawStopReason = "error"
 	}
 }
 
+// classifyAPIError wraps err, which carries apiErr, with its stop reason.
+func classifyAPIError(apiErr genai.APIError, err error) *pipe.ProviderError {
+	reason := pipe.StopError
+	switch apiErr.Code {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		reason = pipe.StopRateLimited
+	case http.StatusUnauthorized, http.StatusForbidden:
+		reason = pipe.StopAuthFailed
+	}
+	raw := apiErr.Status
+	if raw == "" {
+		raw = fmt.Sprintf("http_%d", apiErr.Code)
+	}
+	return &pipe.ProviderError{Reason: reason, Raw: raw, Err: err}
+}
+
 func (s *stream) finalize() {
 	s.state = pipe.StreamStateComplete
 	s.stop() // Release iter.Pull2 goroutine (idempotent).
@@ -179,7 +209,7 @@ func (s *stream) processChunk(resp *genai.GenerateContentResponse) error {
 
 	// A blocked prompt arrives with PromptFeedback and zero candidates.
 	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" && len(resp.Candidates) == 0 {
-		s.msg.StopReason = pipe.StopError
+		s.msg.StopReason = pipe.StopContentFiltered
 		s.msg.RawStopReason = string(resp.PromptFeedback.BlockReason)
 		return fmt.Errorf("prompt blocked: %s", resp.PromptFeedback.BlockReason)
 	}
@@ -194,20 +224,85 @@ func (s *stream) processChunk(resp *genai.GenerateContentResponse) error {
 		s.msg.StopReason = mapFinishReason(candidate.FinishReason)
 	}
 
-	if candidate.Content == nil {
-		return nil
+	if candidate.Content != nil {
+		for _, part := range candidate.Content.Parts {
+			if err := s.processPart(part); err != nil {
+				return err
+			}
+		}
 	}
 
-	for _, part := range candidate.Content.Parts {
-		if err := s.processPart(part); err != nil {
-			return err
-		}
+	if candidate.GroundingMetadata != nil {
+		s.processGrounding(candidate.GroundingMetadata)
 	}
 	return nil
 }
 
+// processGrounding attaches web grounding sources to the most recent text
+// block. Each grounding support yields one citation per referenced chunk;
+// when no supports are present the chunks themselves are cited. Citations
+// repeated across chunks are only recorded once.
+func (s *stream) processGrounding(gm *genai.GroundingMetadata) {
+	idx := -1
+	for i := len(s.blocks) - 1; i >= 0; i-- {
+		if s.blocks[i].blockType == "text" {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return
+	}
+
+	webChunk := func(i int32) *genai.GroundingChunkWeb {
+		if i < 0 || int(i) >= len(gm.GroundingChunks) || gm.GroundingChunks[i] == nil {
+			return nil
+		}
+		return gm.GroundingChunks[i].Web
+	}
+
+	var citations []pipe.Citation
+	for _, support := range gm.GroundingSupports {
+		if support == nil {
+			continue
+		}
+		var citedText string
+		if support.Segment != nil {
+			citedText = support.Segment.Text
+		}
+		for _, ci := range support.GroundingChunkIndices {
+			if web := webChunk(ci); web != nil {
+				citations = append(citations, pipe.Citation{URL: web.URI, Title: web.Title, CitedText: citedText})
+			}
+		}
+	}
+	if len(gm.GroundingSupports) == 0 {
+		for i := range gm.GroundingChunks {
+			if web := webChunk(int32(i)); web != nil {
+				citations = append(citations, pipe.Citation{URL: web.URI, Title: web.Title})
+			}
+		}
+	}
+
+	bs := s.blocks[idx]
+	for _, c := range citations {
+		if slices.Contains(bs.citations, c) {
+			continue
+		}
+		bs.citations = append(bs.citations, c)
+		s.pending = append(s.pending, pipe.EventCitation{Index: idx, Citation: c})
+	}
+	s.msg.Content[idx] = pipe.TextBlock{Text: bs.textBuf.String(), Citations: bs.citations}
+}
+
 func (s *stream) processPart(part *genai.Part) error {
 	switch {
+	case part.ExecutableCode != nil:
+		return s.processExecutableCode(part.ExecutableCode)
+
+	case part.CodeExecutionResult != nil:
+		return s.processCodeExecutionResult(part.CodeExecutionResult)
+
 	case part.FunctionCall != nil:
 		s.hasToolCall = true
 
@@ -263,12 +358,55 @@ func (s *stream) processPart(part *genai.Part) error {
 		idx := s.currentBlockIndex("text")
 		bs := s.blocks[idx]
 		bs.textBuf.WriteString(part.Text)
-		s.msg.Content[idx] = pipe.TextBlock{Text: bs.textBuf.String()}
+		s.msg.Content[idx] = pipe.TextBlock{Text: bs.textBuf.String(), Citations: bs.citations}
 		s.pending = append(s.pending, pipe.EventTextDelta{Index: idx, Delta: part.Text})
 	}
 	return nil
 }
 
+// processExecutableCode records code the model runs with the code execution
+// tool as a server tool call. Gemini does not assign IDs to these parts, so
+// one is generated to pair the call with its result.
+func (s *stream) processExecutableCode(code *genai.ExecutableCode) error {
+	id, err := generateToolCallID()
+	if err != nil {
+		return fmt.Errorf("processing executable code: %w", err)
+	}
+	args, err := json.Marshal(codeExecutionArgs{Language: string(code.Language), Code: code.Code})
+	if err != nil {
+		return fmt.Errorf("processing executable code: %w", err)
+	}
+	call := pipe.ServerToolCallBlock{
+		ID:        id,
+		Name:      string(pipe.ServerToolCodeExecution),
+		Arguments: args,
+	}
+	s.codeCallID = id
+	s.msg.Content = append(s.msg.Content, call)
+	s.blocks = append(s.blocks, &blockState{blockType: "server_tool_call"})
+	s.pending = append(s.pending, pipe.EventServerToolCall{Call: call})
+	return nil
+}
+
+// processCodeExecutionResult records the outcome of the preceding executable
+// code part as a server tool result.
+func (s *stream) processCodeExecutionResult(res *genai.CodeExecutionResult) error {
+	content, err := json.Marshal(codeExecutionResult{Outcome: string(res.Outcome), Output: res.Output})
+	if err != nil {
+		return fmt.Errorf("processing code execution result: %w", err)
+	}
+	result := pipe.ServerToolResultBlock{
+		ToolCallID: s.codeCallID,
+		Name:       string(pipe.ServerToolCodeExecution),
+		Content:    content,
+		IsError:    res.Outcome != genai.OutcomeOK,
+	}
+	s.msg.Content = append(s.msg.Content, result)
+	s.blocks = append(s.blocks, &blockState{blockType: "server_tool_result"})
+	s.pending = append(s.pending, pipe.EventServerToolResult{Result: result})
+	return nil
+}
+
 // currentBlockIndex returns the index of the current block if it matches the
 // given type. If the last block is a different type (or no blocks exist), a new
 // block is appended.
@@ -315,7 +453,9 @@ func mapFinishReason(reason genai.FinishReason) pipe.StopReason {
 		return pipe.StopLength
 	case genai.FinishReasonSafety, genai.FinishReasonRecitation,
 		genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent,
-		genai.FinishReasonSPII, genai.FinishReasonMalformedFunctionCall:
+		genai.FinishReasonSPII:
+		return pipe.StopContentFiltered
+	case genai.FinishReasonMalformedFunctionCall:
 		return pipe.StopError
 	default:
 		return pipe.StopUnknown
@@ -580,6 +580,41 @@ func TestStream_IteratorError(t *testing.T) {
 	assert.Equal(t, pipe.StopError, msg.StopReason)
 }
 
+func TestStream_APIErrorClassification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		err    genai.APIError
+		reason pipe.StopReason
+		raw    string
+	}{
+		{"rate limited", genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED"}, pipe.StopRateLimited, "RESOURCE_EXHAUSTED"},
+		{"unauthorized", genai.APIError{Code: 401}, pipe.StopAuthFailed, "http_401"},
+		{"forbidden", genai.APIError{Code: 403, Status: "PERMISSION_DENIED"}, pipe.StopAuthFailed, "PERMISSION_DENIED"},
+		{"server error", genai.APIError{Code: 500, Status: "INTERNAL"}, pipe.StopError, "INTERNAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			errIter := func(yield func(*genai.GenerateContentResponse, error) bool) {
+				yield(nil, tt.err)
+			}
+
+			s := gemini.NewStreamFromIter(context.Background(), errIter)
+			_, err := s.Next()
+			var pe *pipe.ProviderError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tt.reason, pe.Reason)
+			assert.Contains(t, err.Error(), "gemini:")
+
+			msg, _ := s.Message()
+			assert.Equal(t, tt.reason, msg.StopReason)
+			assert.Equal(t, tt.raw, msg.RawStopReason)
+		})
+	}
+}
+
 func TestStream_State(t *testing.T) {
 	t.Parallel()
 
@@ -940,8 +975,9 @@ func TestStream_EmptyThoughtPart(t *testing.T) {
 
 func TestStream_FinalizePreservesNonDefaultStopReason(t *testing.T) {
 	t.Parallel()
-	// When a safety filter sets StopError and a tool call is also present,
-	// finalize should preserve StopError rather than overwriting to StopToolUse.
+	// When a safety filter sets StopContentFiltered and a tool call is also
+	// present, finalize should preserve it rather than overwriting to
+	// StopToolUse.
 	chunks := []*genai.GenerateContentResponse{
 		{
 			Candidates: []*genai.Candidate{{
@@ -962,7 +998,7 @@ func TestStream_FinalizePreservesNonDefaultStopReason(t *testing.T) {
 
 	msg, err := s.Message()
 	require.NoError(t, err)
-	assert.Equal(t, pipe.StopError, msg.StopReason)
+	assert.Equal(t, pipe.StopContentFiltered, msg.StopReason)
 	assert.Equal(t, string(genai.FinishReasonSafety), msg.RawStopReason)
 }
 
@@ -1081,8 +1117,11 @@ func TestStream_PromptBlocked(t *testing.T) {
 	assert.Contains(t, err.Error(), "prompt blocked")
 
 	assert.Equal(t, pipe.StreamStateError, s.State())
+	var pe *pipe.ProviderError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, pipe.StopContentFiltered, pe.Reason)
 	msg, _ := s.Message()
-	assert.Equal(t, pipe.StopError, msg.StopReason)
+	assert.Equal(t, pipe.StopContentFiltered, msg.StopReason)
 	assert.Equal(t, "SAFETY", msg.RawStopReason)
 }
 
@@ -1114,3 +1153,136 @@ func TestStream_ProcessPartMarshalError(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, pipe.StopError, msg.StopReason)
 }
+
+func TestStream_GroundingMetadata(t *testing.T) {
+	t.Parallel()
+
+	grounding := &genai.GroundingMetadata{
+		GroundingChunks: []*genai.GroundingChunk{
+			{Web: &genai.GroundingChunkWeb{URI: "https://go.dev", Title: "go.dev"}},
+			{Web: &genai.GroundingChunkWeb{URI: "https://pkg.go.dev", Title: "pkg.go.dev"}},
+		},
+		GroundingSupports: []*genai.GroundingSupport{
+			{Segment: &genai.Segment{Text: "Go is open source."}, GroundingChunkIndices: []int32{0, 1}},
+			{Segment: &genai.Segment{Text: "Out of range."}, GroundingChunkIndices: []int32{7}},
+		},
+	}
+	chunks := []*genai.GenerateContentResponse{
+		{Candidates: []*genai.Candidate{{
+			Content: &genai.Content{Parts: []*genai.Part{{Text: "Go is open source."}}},
+		}}},
+		{Candidates: []*genai.Candidate{{
+			FinishReason:      genai.FinishReasonStop,
+			GroundingMetadata: grounding,
+		}}},
+		// Repeated metadata does not duplicate citations.
+		{Candidates: []*genai.Candidate{{GroundingMetadata: grounding}}},
+	}
+
+	s := gemini.NewStreamFromIter(context.Background(), mockChunks(chunks))
+	events := collectStreamEvents(t, s)
+
+	want := []pipe.Citation{
+		{URL: "https://go.dev", Title: "go.dev", CitedText: "Go is open source."},
+		{URL: "https://pkg.go.dev", Title: "pkg.go.dev", CitedText: "Go is open source."},
+	}
+	require.Len(t, events, 3)
+	assert.Equal(t, pipe.EventCitation{Index: 0, Citation: want[0]}, events[1])
+	assert.Equal(t, pipe.EventCitation{Index: 0, Citation: want[1]}, events[2])
+
+	msg, err := s.Message()
+	require.NoError(t, err)
+	require.Len(t, msg.Content, 1)
+	assert.Equal(t, pipe.TextBlock{Text: "Go is open source.", Citations: want}, msg.Content[0])
+}
+
+func TestStream_GroundingMetadataWithoutSupports(t *testing.T) {
+	t.Parallel()
+
+	chunks := []*genai.GenerateContentResponse{
+		{Candidates: []*genai.Candidate{{
+			Content:      &genai.Content{Parts: []*genai.Part{{Text: "Answer"}}},
+			FinishReason: genai.FinishReasonStop,
+			GroundingMetadata: &genai.GroundingMetadata{
+				GroundingChunks: []*genai.GroundingChunk{
+					{Web: &genai.GroundingChunkWeb{URI: "https://go.dev", Title: "go.dev"}},
+				},
+			},
+		}}},
+	}
+
+	s := gemini.NewStreamFromIter(context.Background(), mockChunks(chunks))
+	collectStreamEvents(t, s)
+
+	msg, err := s.Message()
+	require.NoError(t, err)
+	require.Len(t, msg.Content, 1)
+	text, ok := msg.Content[0].(pipe.TextBlock)
+	require.True(t, ok)
+	assert.Equal(t, []pipe.Citation{{URL: "https://go.dev", Title: "go.dev"}}, text.Citations)
+}
+
+func TestStream_CodeExecution(t *testing.T) {
+	t.Parallel()
+
+	chunks := []*genai.GenerateContentResponse{
+		{Candidates: []*genai.Candidate{{
+			Content: &genai.Content{Parts: []*genai.Part{
+				{Text: "Let me compute that."},
+				{ExecutableCode: &genai.ExecutableCode{Code: "print(2 ** 10)", Language: genai.LanguagePython}},
+				{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK, Output: "1024\n"}},
+				{Text: "The answer is 1024."},
+			}},
+			FinishReason: genai.FinishReasonStop,
+		}}},
+	}
+
+	s := gemini.NewStreamFromIter(context.Background(), mockChunks(chunks))
+	events := collectStreamEvents(t, s)
+
+	require.Len(t, events, 4)
+	callEvt, ok := events[1].(pipe.EventServerToolCall)
+	require.True(t, ok)
+	assert.Equal(t, "code_execution", callEvt.Call.Name)
+	assert.NotEmpty(t, callEvt.Call.ID)
+	assert.JSONEq(t, `{"language":"PYTHON","code":"print(2 ** 10)"}`, string(callEvt.Call.Arguments))
+
+	resultEvt, ok := events[2].(pipe.EventServerToolResult)
+	require.True(t, ok)
+	assert.Equal(t, callEvt.Call.ID, resultEvt.Result.ToolCallID)
+	assert.Equal(t, "code_execution", resultEvt.Result.Name)
+	assert.False(t, resultEvt.Result.IsError)
+	assert.JSONEq(t, `{"outcome":"OUTCOME_OK","output":"1024\n"}`, string(resultEvt.Result.Content))
+
+	assert.Equal(t, pipe.EventTextDelta{Index: 3, Delta: "The answer is 1024."}, events[3])
+
+	msg, err := s.Message()
+	require.NoError(t, err)
+	assert.Equal(t, pipe.StopEndTurn, msg.StopReason, "server tools need no local execution")
+	require.Len(t, msg.Content, 4)
+	assert.Equal(t, callEvt.Call, msg.Content[1])
+	assert.Equal(t, resultEvt.Result, msg.Content[2])
+	assert.Equal(t, pipe.TextBlock{Text: "The answer is 1024."}, msg.Content[3])
+}
+
+func TestStream_CodeExecutionFailure(t *testing.T) {
+	t.Parallel()
+
+	chunks := []*genai.GenerateContentResponse{
+		{Candidates: []*genai.Candidate{{
+			Content: &genai.Content{Parts: []*genai.Part{
+				{ExecutableCode: &genai.ExecutableCode{Code: "1/0", Language: genai.LanguagePython}},
+				{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeFailed, Output: "ZeroDivisionError"}},
+			}},
+			FinishReason: genai.FinishReasonStop,
+		}}},
+	}
+
+	s := gemini.NewStreamFromIter(context.Background(), mockChunks(chunks))
+	events := collectStreamEvents(t, s)
+
+	require.Len(t, events, 2)
+	resultEvt, ok := events[1].(pipe.EventServerToolResult)
+	require.True(t, ok)
+	assert.True(t, resultEvt.Result.IsError)
+}
@@ -0,0 +1,221 @@
+package goldmark
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fwojciec/pipe"
+)
+
+// language describes enough of a programming language's lexical syntax to
+// color its keywords, strings, numbers, and comments.
+type language struct {
+	keywords     map[string]bool
+	lineComments []string
+	blockComment [2]string // opening and closing delimiters; empty if none
+	quotes       string    // string delimiters; a backtick string may span lines
+	// wordStartComments reports that a line comment only starts at the start
+	// of a word, as in shell, where ${#x} is not a comment.
+	wordStartComments bool
+}
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+// lookupLanguage returns the syntax for the fence info string name.
+func lookupLanguage(name string) (language, bool) {
+	switch strings.ToLower(name) {
+	case "go":
+		return language{
+			keywords: words(`break case chan const continue default defer else fallthrough for func go goto if
+				import interface map package range return select struct switch type var nil true false iota`),
+			lineComments: []string{"//"},
+			blockComment: [2]string{"/*", "*/"},
+			quotes:       "\"'`",
+		}, true
+	case "python", "py":
+		return language{
+			keywords: words(`and as assert async await break class continue def del elif else except finally for
+				from global if import in is lambda nonlocal not or pass raise return try while with yield None True False self`),
+			lineComments: []string{"#"},
+			quotes:       `"'`,
+		}, true
+	case "javascript", "js", "jsx", "typescript", "ts", "tsx":
+		return language{
+			keywords: words(`async await break case catch class const continue default delete do else export extends
+				finally for from function if import in instanceof interface let new of return switch this throw try type
+				typeof var void while yield null undefined true false`),
+			lineComments: []string{"//"},
+			blockComment: [2]string{"/*", "*/"},
+			quotes:       "\"'`",
+		}, true
+	case "rust", "rs":
+		return language{
+			keywords: words(`as async await break const continue crate dyn else enum extern false fn for if impl in let
+				loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while`),
+			lineComments: []string{"//"},
+			blockComment: [2]string{"/*", "*/"},
+			quotes:       `"`,
+		}, true
+	case "c", "cpp", "c++", "java":
+		return language{
+			keywords: words(`auto break case catch char class const continue default delete do double else enum extends
+				final float for if implements import int long namespace new null nullptr package private protected public
+				return short static struct switch template this throw try typedef union unsigned using void volatile while
+				true false`),
+			lineComments: []string{"//"},
+			blockComment: [2]string{"/*", "*/"},
+			quotes:       `"'`,
+		}, true
+	case "sh", "bash", "shell", "zsh":
+		return language{
+			keywords: words(`if then else elif fi for while until do done case esac in function return local export
+				set unset echo cd exit`),
+			lineComments:      []string{"#"},
+			quotes:            `"'`,
+			wordStartComments: true,
+		}, true
+	case "json":
+		return language{
+			keywords: words(`true false null`),
+			quotes:   `"`,
+		}, true
+	}
+	return language{}, false
+}
+
+// highlighter colors source code with the theme's colors: keywords in the
+// accent color, strings in the success color, numbers in the tool call
+// color, and comments muted.
+type highlighter struct {
+	keyword lipgloss.Style
+	str     lipgloss.Style
+	number  lipgloss.Style
+	comment lipgloss.Style
+}
+
+func newHighlighter(theme pipe.Theme) highlighter {
+	style := func(color int) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(ansiColor(color)).TabWidth(lipgloss.NoTabConversion)
+	}
+	return highlighter{
+		keyword: style(theme.Accent),
+		str:     style(theme.Success),
+		number:  style(theme.ToolCall),
+		comment: style(theme.Muted).Faint(true),
+	}
+}
+
+// highlight returns code with its tokens colored for the language named by
+// the fence info string lang, or code unchanged for unknown languages.
+// Unterminated strings and comments, as in a block still streaming, run to
+// the end of the code.
+func (h highlighter) highlight(code, lang string) string {
+	l, ok := lookupLanguage(lang)
+	if !ok {
+		return code
+	}
+	var out strings.Builder
+	emit := func(s lipgloss.Style, token string) {
+		// Style each line separately so every line of the block carries
+		// its own escape codes behind the gutter.
+		for i, line := range strings.Split(token, "\n") {
+			if i > 0 {
+				out.WriteByte('\n')
+			}
+			if line != "" {
+				out.WriteString(s.Render(line))
+			}
+		}
+	}
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		if open := l.blockComment[0]; open != "" && strings.HasPrefix(rest, open) {
+			n := len(rest)
+			if end := strings.Index(rest[len(open):], l.blockComment[1]); end >= 0 {
+				n = len(open) + end + len(l.blockComment[1])
+			}
+			emit(h.comment, rest[:n])
+			i += n
+			continue
+		}
+		if hasAnyPrefix(rest, l.lineComments) && (!l.wordStartComments || i == 0 || !isWord(code[i-1])) {
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			emit(h.comment, rest[:n])
+			i += n
+			continue
+		}
+		c := rest[0]
+		switch {
+		case strings.IndexByte(l.quotes, c) >= 0:
+			n := stringLen(rest)
+			emit(h.str, rest[:n])
+			i += n
+		case isDigit(c) && (i == 0 || !isWord(code[i-1])):
+			n := 1
+			for n < len(rest) && (isWord(rest[n]) || rest[n] == '.') {
+				n++
+			}
+			emit(h.number, rest[:n])
+			i += n
+		case isWord(c):
+			n := 1
+			for n < len(rest) && isWord(rest[n]) {
+				n++
+			}
+			if l.keywords[rest[:n]] {
+				emit(h.keyword, rest[:n])
+			} else {
+				out.WriteString(rest[:n])
+			}
+			i += n
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// stringLen returns the length of the string literal s starts with,
+// including its quotes. Backtick strings may span lines; others end at the
+// line's end if unterminated.
+func stringLen(s string) int {
+	quote := s[0]
+	for n := 1; n < len(s); n++ {
+		switch {
+		case s[n] == '\\' && quote != '`':
+			n++
+		case s[n] == quote:
+			return n + 1
+		case s[n] == '\n' && quote != '`':
+			return n
+		}
+	}
+	return len(s)
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isWord(c byte) bool {
+	return c == '_' || isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= 0x80
+}
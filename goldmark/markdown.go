@@ -6,7 +6,8 @@ import "github.com/fwojciec/pipe"
 
 // Render parses markdown source and returns ANSI-styled terminal output.
 // Paragraphs and list items are word-wrapped to width. Code blocks are
-// rendered at full width without reflow.
+// rendered at full width without reflow, with syntax highlighting for
+// common languages.
 func Render(source string, width int, theme pipe.Theme) string {
 	if source == "" {
 		return ""
@@ -231,4 +231,30 @@ func TestRender(t *testing.T) {
 		result := goldmark.Render("hello world", 0, theme)
 		assert.Contains(t, stripANSI(result), "hello world")
 	})
+
+	t.Run("fenced code block highlights known languages", func(t *testing.T) {
+		t.Parallel()
+		src := "```go\nfunc main() { // start\n\tx := \"hi\" + 42\n}\n```"
+		result := goldmark.Render(src, 80, theme)
+		assert.Contains(t, stripANSI(result), "│ func main() { // start\n│ \tx := \"hi\" + 42\n│ }")
+		assert.Contains(t, result, "\x1b[35mfunc\x1b[0m", "keywords use the accent color")
+		assert.Contains(t, result, "\x1b[32m\"hi\"\x1b[0m", "strings use the success color")
+		assert.Contains(t, result, "\x1b[33m42\x1b[0m", "numbers use the tool call color")
+		assert.NotContains(t, result, "\x1b[35mmain", "other identifiers are plain")
+	})
+
+	t.Run("fenced code block in unknown language is not highlighted", func(t *testing.T) {
+		t.Parallel()
+		result := goldmark.Render("```brainfuck\nfunc \"x\"\n```", 80, theme)
+		assert.Contains(t, result, "\x1b[0m func \"x\"")
+	})
+
+	t.Run("unterminated block comment colors each line", func(t *testing.T) {
+		t.Parallel()
+		result := goldmark.Render("```js\n/* one\ntwo\n```", 80, theme)
+		for _, line := range strings.Split(result, "\n")[1:] {
+			assert.Contains(t, line, "\x1b[", "line %q is styled", line)
+		}
+		assert.Contains(t, stripANSI(result), "│ /* one\n│ two")
+	})
 }
@@ -19,10 +19,12 @@ type ansiRenderer struct {
 	accent    lipgloss.Style
 	muted     lipgloss.Style
 	underline lipgloss.Style
+	code      highlighter
 }
 
 func newRenderer(theme pipe.Theme) *ansiRenderer {
 	return &ansiRenderer{
+		code:      newHighlighter(theme),
 		bold:      lipgloss.NewStyle().Bold(true),
 		italic:    lipgloss.NewStyle().Italic(true),
 		accent:    lipgloss.NewStyle().Foreground(ansiColor(theme.Accent)).Bold(true),
@@ -82,12 +84,18 @@ func (r *ansiRenderer) renderBlock(node ast.Node, source []byte, width int, buf
 			buf.WriteString("\n")
 		}
 		gutter := r.muted.Render("│") + " "
+		var code strings.Builder
 		lines := n.Lines()
 		for i := 0; i < lines.Len(); i++ {
 			line := lines.At(i)
-			content := strings.TrimRight(string(line.Value(source)), "\n")
-			buf.WriteString(gutter + content)
-			buf.WriteString("\n")
+			code.Write(line.Value(source))
+		}
+		if code.Len() > 0 {
+			highlighted := r.code.highlight(strings.TrimSuffix(code.String(), "\n"), lang)
+			for _, line := range strings.Split(highlighted, "\n") {
+				buf.WriteString(gutter + line)
+				buf.WriteString("\n")
+			}
 		}
 		if n.NextSibling() != nil {
 			buf.WriteString("\n")
@@ -0,0 +1,108 @@
+// Package html renders pipe reports as standalone HTML pages.
+package html
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/fwojciec/pipe"
+)
+
+const tokenReportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Token heatmap {{.SessionID}}</title>
+<style>
+body { font-family: ui-monospace, monospace; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.25em 0.5em; border-bottom: 1px solid #ddd; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Token heatmap</h1>
+<p>Session {{.SessionID}}: {{.Total}} estimated tokens</p>
+<h2>Top contributors</h2>
+<table>
+<tr><th>Message</th><th>Role</th><th>Kind</th><th>Label</th><th>Tokens</th><th>Share</th><th>Preview</th></tr>
+{{range .Top}}{{template "row" .}}{{end}}
+</table>
+<h2>All blocks</h2>
+<table>
+<tr><th>Message</th><th>Role</th><th>Kind</th><th>Label</th><th>Tokens</th><th>Share</th><th>Preview</th></tr>
+{{range .Blocks}}{{template "row" .}}{{end}}
+</table>
+</body>
+</html>
+{{define "row"}}<tr style="background: rgba(220, 50, 47, {{.Heat}})"><td>{{.Message}}</td><td>{{.Role}}</td><td>{{.Kind}}</td><td>{{.Label}}</td><td class="num">{{.Tokens}}</td><td class="num">{{.Share}}</td><td>{{.Preview}}</td></tr>
+{{end}}`
+
+type tokenReportData struct {
+	SessionID string
+	Total     string
+	Top       []tokenRow
+	Blocks    []tokenRow
+}
+
+type tokenRow struct {
+	Message string
+	Role    pipe.Role
+	Kind    string
+	Label   string
+	Preview string
+	Tokens  string
+	Share   string
+	Heat    template.CSS // row background opacity, relative to the largest block
+}
+
+// WriteTokenReport writes a session's token attribution as an HTML heatmap:
+// every block is shaded by its token count relative to the largest block.
+// Counts and shares are formatted for locale.
+func WriteTokenReport(w io.Writer, sessionID string, attrs []pipe.TokenAttribution, locale pipe.Locale) error {
+	tmpl, err := template.New("report").Parse(tokenReportTemplate)
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+
+	total, largest := 0, 0
+	for _, a := range attrs {
+		total += a.Tokens
+		largest = max(largest, a.Tokens)
+	}
+	rows := func(in []pipe.TokenAttribution) []tokenRow {
+		out := make([]tokenRow, len(in))
+		for i, a := range in {
+			msg := "system"
+			if a.MessageIndex >= 0 {
+				msg = fmt.Sprintf("%d.%d", a.MessageIndex, a.BlockIndex)
+			}
+			heat, share := 0.0, 0.0
+			if largest > 0 {
+				heat = float64(a.Tokens) / float64(largest)
+			}
+			if total > 0 {
+				share = 100 * float64(a.Tokens) / float64(total)
+			}
+			out[i] = tokenRow{
+				Message: msg,
+				Role:    a.Role,
+				Kind:    a.Kind,
+				Label:   a.Label,
+				Preview: a.Preview,
+				Tokens:  locale.Int(a.Tokens),
+				Share:   locale.Float(share, 1) + "%",
+				Heat:    template.CSS(fmt.Sprintf("%.2f", heat*0.6)),
+			}
+		}
+		return out
+	}
+
+	return tmpl.Execute(w, tokenReportData{
+		SessionID: sessionID,
+		Total:     locale.Int(total),
+		Top:       rows(pipe.TopTokens(attrs, pipe.TopTokenEntries)),
+		Blocks:    rows(attrs),
+	})
+}
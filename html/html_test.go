@@ -0,0 +1,48 @@
+package html_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/html"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTokenReport(t *testing.T) {
+	t.Parallel()
+	attrs := []pipe.TokenAttribution{
+		{MessageIndex: -1, Kind: "system", Preview: "You are helpful.", Tokens: 25},
+		{MessageIndex: 2, BlockIndex: 0, Role: pipe.RoleToolResult, Kind: "text", Label: "bash", Preview: "<script>alert(1)</script>", Tokens: 75},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, html.WriteTokenReport(&buf, "sess-1", attrs, pipe.Locale{}))
+	out := buf.String()
+
+	assert.Contains(t, out, "Session sess-1: 100 estimated tokens")
+	assert.Contains(t, out, "<td>2.0</td>")
+	assert.Contains(t, out, "75.0%")
+	assert.Contains(t, out, "rgba(220, 50, 47, 0.60)", "largest block gets full heat")
+	assert.NotContains(t, out, "<script>alert(1)</script>", "previews are escaped")
+}
+
+func TestWriteTokenReport_Locale(t *testing.T) {
+	t.Parallel()
+	attrs := []pipe.TokenAttribution{
+		{MessageIndex: 0, Kind: "text", Tokens: 1234},
+		{MessageIndex: 1, Kind: "text", Tokens: 1234},
+		{MessageIndex: 2, Kind: "text", Tokens: 1234},
+	}
+	de, ok := pipe.LookupLocale("de-DE")
+	require.True(t, ok)
+
+	var buf bytes.Buffer
+	require.NoError(t, html.WriteTokenReport(&buf, "sess-1", attrs, de))
+	out := buf.String()
+
+	assert.Contains(t, out, "3.702 estimated tokens")
+	assert.Contains(t, out, ">1.234<")
+	assert.Contains(t, out, "33,3%")
+}
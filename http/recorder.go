@@ -0,0 +1,94 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Compile-time interface check.
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// RecordedRequest is a request a [Recorder] sent.
+type RecordedRequest struct {
+	Method string
+	URL    string // without the query, which may carry an API key
+	Body   []byte
+}
+
+// Recorder is an [http.RoundTripper] that keeps the bodies of the requests
+// it sends, so the exact request a provider client made can be shown for
+// debugging. Requests are filed under the tag of their context, set with
+// [WithRequestTag]; untagged requests are not kept. A tag keeps only its
+// latest request, e.g. the last attempt of a retried one, and the oldest
+// tags are dropped past the limit.
+type Recorder struct {
+	next http.RoundTripper
+	keep int
+
+	mu       sync.Mutex
+	requests map[string]RecordedRequest
+	tags     []string // oldest first
+}
+
+// NewRecorder returns a Recorder sending requests with next and keeping
+// the requests of the last keep tags. A nil next uses
+// http.DefaultTransport.
+func NewRecorder(next http.RoundTripper, keep int) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next, keep: keep, requests: make(map[string]RecordedRequest)}
+}
+
+type tagKey struct{}
+
+// WithRequestTag returns a context whose HTTP requests a Recorder files
+// under tag.
+func WithRequestTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, tagKey{}, tag)
+}
+
+// RoundTrip records req if its context is tagged and sends it.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	tag, ok := req.Context().Value(tagKey{}).(string)
+	if !ok || req.Body == nil {
+		return r.next.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	u := *req.URL
+	u.RawQuery = ""
+	r.record(tag, RecordedRequest{Method: req.Method, URL: u.String(), Body: body})
+
+	// RoundTrippers must not modify the request they are given.
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	return r.next.RoundTrip(out)
+}
+
+func (r *Recorder) record(tag string, rec RecordedRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.requests[tag]; !ok {
+		r.tags = append(r.tags, tag)
+	}
+	r.requests[tag] = rec
+	for len(r.tags) > r.keep {
+		delete(r.requests, r.tags[0])
+		r.tags = r.tags[1:]
+	}
+}
+
+// Request returns the latest request filed under tag.
+func (r *Recorder) Request(tag string) (RecordedRequest, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.requests[tag]
+	return rec, ok
+}
@@ -0,0 +1,52 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pipehttp "github.com/fwojciec/pipe/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+	}))
+	defer srv.Close()
+
+	rec := pipehttp.NewRecorder(nil, 2)
+	client := &http.Client{Transport: rec}
+	post := func(ctx context.Context, body string) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/v1/messages?key=secret", strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	post(context.Background(), `{"untagged":true}`)
+	post(pipehttp.WithRequestTag(context.Background(), "0"), `{"attempt":1}`)
+	post(pipehttp.WithRequestTag(context.Background(), "0"), `{"attempt":2}`)
+	assert.Equal(t, []string{`{"untagged":true}`, `{"attempt":1}`, `{"attempt":2}`}, received, "bodies are sent intact")
+
+	got, ok := rec.Request("0")
+	require.True(t, ok)
+	assert.Equal(t, pipehttp.RecordedRequest{Method: http.MethodPost, URL: srv.URL + "/v1/messages", Body: []byte(`{"attempt":2}`)}, got,
+		"a tag keeps its latest request, without the query")
+
+	post(pipehttp.WithRequestTag(context.Background(), "1"), `{}`)
+	post(pipehttp.WithRequestTag(context.Background(), "2"), `{}`)
+	_, ok = rec.Request("0")
+	assert.False(t, ok, "the oldest tag is dropped past the limit")
+	_, ok = rec.Request("2")
+	assert.True(t, ok)
+}
@@ -0,0 +1,288 @@
+// Package http delivers agent run progress to webhooks over HTTP and records
+// the requests provider clients send.
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// Webhook event names.
+const (
+	EventRunStart  = "run_start"
+	EventTurnEnd   = "turn_end"
+	EventToolError = "tool_error"
+	EventRunEnd    = "run_end"
+)
+
+const (
+	// queueSize bounds payloads waiting for delivery; a slow endpoint
+	// drops payloads rather than stalling the run.
+	queueSize = 64
+	// sendTimeout bounds one delivery attempt.
+	sendTimeout = 10 * time.Second
+)
+
+// Payload is the JSON body posted to the webhook.
+type Payload struct {
+	Event      string          `json:"event"`
+	SessionID  string          `json:"session_id"`
+	Run        int             `json:"run"`
+	Time       time.Time       `json:"time"`
+	Turn       int             `json:"turn,omitempty"`
+	StopReason pipe.StopReason `json:"stop_reason,omitempty"`
+	Usage      *Usage          `json:"usage,omitempty"`
+	Tool       string          `json:"tool,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+var (
+	_ pipe.Provider = (*turnProvider)(nil)
+	_ pipe.Stream   = (*turnStream)(nil)
+)
+
+// Usage is the token usage reported with turn_end payloads.
+type Usage struct {
+	InputTokens      int `json:"input_tokens"`
+	OutputTokens     int `json:"output_tokens"`
+	CacheReadTokens  int `json:"cache_read_tokens"`
+	CacheWriteTokens int `json:"cache_write_tokens"`
+}
+
+// Notifier posts payloads to a webhook URL in the order they are queued,
+// from a background goroutine so delivery never blocks the agent. Delivery
+// errors are passed to OnError when set and otherwise dropped.
+type Notifier struct {
+	// OnError, when set, receives delivery failures. Set it before queuing
+	// payloads.
+	OnError func(error)
+
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	queue chan Payload
+	done  chan struct{}
+
+	mu     sync.Mutex
+	runs   int
+	closed bool // queue is closed
+}
+
+// NewNotifier creates a Notifier posting to url with client. A nil client
+// uses http.DefaultClient. Call Close to flush queued payloads.
+func NewNotifier(url string, client *http.Client) *Notifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	n := &Notifier{
+		url:    url,
+		client: client,
+		now:    time.Now,
+		queue:  make(chan Payload, queueSize),
+		done:   make(chan struct{}),
+	}
+	go n.deliver()
+	return n
+}
+
+// Notify queues p for delivery. It never blocks; when the queue is full or
+// the Notifier is closed the payload is dropped.
+func (n *Notifier) Notify(p Payload) {
+	n.mu.Lock()
+	var err error
+	if n.closed {
+		err = fmt.Errorf("webhook: notifier closed, dropped %s", p.Event)
+	} else {
+		select {
+		case n.queue <- p:
+		default:
+			err = fmt.Errorf("webhook: queue full, dropped %s", p.Event)
+		}
+	}
+	n.mu.Unlock()
+	if err != nil {
+		n.reportError(err)
+	}
+}
+
+// Close stops accepting payloads and waits until queued ones are delivered
+// or ctx is done. Payloads queued after Close are dropped.
+func (n *Notifier) Close(ctx context.Context) error {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+	select {
+	case <-n.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *Notifier) deliver() {
+	defer close(n.done)
+	for p := range n.queue {
+		if err := n.send(p); err != nil {
+			n.reportError(err)
+		}
+	}
+}
+
+func (n *Notifier) send(p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("webhook: encode %s: %w", p.Event, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: post %s: %w", p.Event, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: post %s: status %d", p.Event, resp.StatusCode)
+	}
+	return nil
+}
+
+func (n *Notifier) reportError(err error) {
+	if n.OnError != nil {
+		n.OnError(err)
+	}
+}
+
+// Run reports the progress of one agent run.
+type Run struct {
+	notifier  *Notifier
+	sessionID string
+	number    int
+
+	mu         sync.Mutex
+	turns      int
+	stopReason pipe.StopReason
+}
+
+// StartRun queues a run_start payload for a run on the given session and
+// returns the Run that reports the rest of its progress.
+func (n *Notifier) StartRun(sessionID string) *Run {
+	n.mu.Lock()
+	n.runs++
+	number := n.runs
+	n.mu.Unlock()
+
+	r := &Run{notifier: n, sessionID: sessionID, number: number}
+	r.notify(Payload{Event: EventRunStart})
+	return r
+}
+
+// Provider wraps p so every completed assistant turn queues a turn_end
+// payload with its stop reason and usage.
+func (r *Run) Provider(p pipe.Provider) pipe.Provider {
+	return &turnProvider{run: r, next: p}
+}
+
+// OnEvent wraps next so failed tool results queue a tool_error payload.
+// A nil next is allowed.
+func (r *Run) OnEvent(next func(pipe.Event)) func(pipe.Event) {
+	return func(e pipe.Event) {
+		if tr, ok := e.(pipe.EventToolResult); ok && tr.IsError {
+			r.notify(Payload{Event: EventToolError, Tool: tr.ToolName, Error: tr.Content})
+		}
+		if next != nil {
+			next(e)
+		}
+	}
+}
+
+// End queues the run_end payload. err is the run's error, if any.
+func (r *Run) End(err error) {
+	r.mu.Lock()
+	p := Payload{Event: EventRunEnd, Turn: r.turns, StopReason: r.stopReason}
+	r.mu.Unlock()
+	if err != nil {
+		p.Error = err.Error()
+		if errors.Is(err, context.Canceled) {
+			p.StopReason = pipe.StopAborted
+		}
+	}
+	r.notify(p)
+}
+
+func (r *Run) endTurn(msg pipe.AssistantMessage) {
+	r.mu.Lock()
+	r.turns++
+	r.stopReason = msg.StopReason
+	turn := r.turns
+	r.mu.Unlock()
+	r.notify(Payload{
+		Event:      EventTurnEnd,
+		Turn:       turn,
+		StopReason: msg.StopReason,
+		Usage: &Usage{
+			InputTokens:      msg.Usage.InputTokens,
+			OutputTokens:     msg.Usage.OutputTokens,
+			CacheReadTokens:  msg.Usage.CacheReadTokens,
+			CacheWriteTokens: msg.Usage.CacheWriteTokens,
+		},
+	})
+}
+
+func (r *Run) notify(p Payload) {
+	p.SessionID = r.sessionID
+	p.Run = r.number
+	p.Time = r.notifier.now()
+	r.notifier.Notify(p)
+}
+
+// turnProvider reports each stream that ends as a finished turn.
+type turnProvider struct {
+	run  *Run
+	next pipe.Provider
+}
+
+func (p *turnProvider) Stream(ctx context.Context, req pipe.Request) (pipe.Stream, error) {
+	s, err := p.next.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &turnStream{Stream: s, run: p.run}, nil
+}
+
+// turnStream reports the assembled message once the stream reaches a
+// terminal state.
+type turnStream struct {
+	pipe.Stream
+	run      *Run
+	reported bool
+}
+
+func (s *turnStream) Next() (pipe.Event, error) {
+	evt, err := s.Stream.Next()
+	if err != nil && !s.reported {
+		s.reported = true
+		if msg, msgErr := s.Stream.Message(); msgErr == nil {
+			s.run.endTurn(msg)
+		}
+	}
+	return evt, err
+}
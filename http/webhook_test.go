@@ -0,0 +1,149 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipehttp "github.com/fwojciec/pipe/http"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a webhook endpoint that records the payloads it receives.
+type recorder struct {
+	mu       sync.Mutex
+	payloads []pipehttp.Payload
+	status   int
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var p pipehttp.Payload
+	_ = json.NewDecoder(req.Body).Decode(&p)
+	r.mu.Lock()
+	r.payloads = append(r.payloads, p)
+	status := r.status
+	r.mu.Unlock()
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+}
+
+func (r *recorder) events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]string, len(r.payloads))
+	for i, p := range r.payloads {
+		events[i] = p.Event
+	}
+	return events
+}
+
+func TestNotifier(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reports a run's progress in order", func(t *testing.T) {
+		t.Parallel()
+		rec := &recorder{}
+		srv := httptest.NewServer(rec)
+		defer srv.Close()
+		n := pipehttp.NewNotifier(srv.URL, srv.Client())
+
+		turns := []pipe.AssistantMessage{
+			{
+				Content:    []pipe.ContentBlock{pipe.ToolCallBlock{ID: "tc_1", Name: "bash", Arguments: json.RawMessage(`{}`)}},
+				StopReason: pipe.StopToolUse,
+				Usage:      pipe.Usage{InputTokens: 10, OutputTokens: 5},
+			},
+			{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "done"}}, StopReason: pipe.StopEndTurn},
+		}
+		turn := 0
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			msg := turns[turn]
+			turn++
+			return &mock.Stream{
+				NextFn:    func() (pipe.Event, error) { return nil, io.EOF },
+				MessageFn: func() (pipe.AssistantMessage, error) { return msg, nil },
+			}, nil
+		}}
+		executor := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+			return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "boom"}}, IsError: true}, nil
+		}}
+
+		run := n.StartRun("s1")
+		loop := pipe.NewLoop(run.Provider(provider), executor)
+		err := loop.Run(context.Background(), &pipe.Session{ID: "s1"}, nil, pipe.WithEventHandler(run.OnEvent(nil)))
+		run.End(err)
+		require.NoError(t, err)
+		require.NoError(t, n.Close(context.Background()))
+
+		assert.Equal(t, []string{"run_start", "turn_end", "tool_error", "turn_end", "run_end"}, rec.events())
+		first := rec.payloads[1]
+		assert.Equal(t, "s1", first.SessionID)
+		assert.Equal(t, 1, first.Run)
+		assert.Equal(t, 1, first.Turn)
+		assert.Equal(t, pipe.StopToolUse, first.StopReason)
+		require.NotNil(t, first.Usage)
+		assert.Equal(t, 10, first.Usage.InputTokens)
+		assert.Equal(t, "bash", rec.payloads[2].Tool)
+		assert.Equal(t, "boom", rec.payloads[2].Error)
+		end := rec.payloads[4]
+		assert.Equal(t, 2, end.Turn)
+		assert.Equal(t, pipe.StopEndTurn, end.StopReason)
+	})
+
+	t.Run("run end carries the run error", func(t *testing.T) {
+		t.Parallel()
+		rec := &recorder{}
+		srv := httptest.NewServer(rec)
+		defer srv.Close()
+		n := pipehttp.NewNotifier(srv.URL, srv.Client())
+
+		n.StartRun("s1").End(context.Canceled)
+		require.NoError(t, n.Close(context.Background()))
+
+		require.Len(t, rec.payloads, 2)
+		assert.Equal(t, pipe.StopAborted, rec.payloads[1].StopReason)
+		assert.Equal(t, "context canceled", rec.payloads[1].Error)
+	})
+
+	t.Run("delivery failures are reported", func(t *testing.T) {
+		t.Parallel()
+		rec := &recorder{status: http.StatusInternalServerError}
+		srv := httptest.NewServer(rec)
+		defer srv.Close()
+		n := pipehttp.NewNotifier(srv.URL, srv.Client())
+		var errs []error
+		n.OnError = func(err error) { errs = append(errs, err) }
+
+		n.StartRun("s1")
+		require.NoError(t, n.Close(context.Background()))
+
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), "status 500")
+	})
+	t.Run("payloads after close are dropped", func(t *testing.T) {
+		t.Parallel()
+		rec := &recorder{}
+		srv := httptest.NewServer(rec)
+		defer srv.Close()
+		n := pipehttp.NewNotifier(srv.URL, srv.Client())
+		var errs []error
+		n.OnError = func(err error) { errs = append(errs, err) }
+
+		run := n.StartRun("s1")
+		require.NoError(t, n.Close(context.Background()))
+		run.End(nil)
+		require.NoError(t, n.Close(context.Background()))
+
+		assert.Equal(t, []string{pipehttp.EventRunStart}, rec.events())
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "webhook: notifier closed, dropped run_end")
+	})
+}
@@ -19,6 +19,61 @@ type contentBlock struct {
 	ID        *string          `json:"id,omitempty"`
 	Name      *string          `json:"name,omitempty"`
 	Arguments *json.RawMessage `json:"arguments,omitempty"`
+	// server tool result fields
+	ToolCallID *string          `json:"tool_call_id,omitempty"`
+	Content    *json.RawMessage `json:"content,omitempty"`
+	IsError    *bool            `json:"is_error,omitempty"`
+	// text block fields
+	Citations []citation `json:"citations,omitempty"`
+
+	// raw is an unknown-type block as read, written back unchanged by
+	// MarshalJSON.
+	raw json.RawMessage
+}
+
+// UnmarshalJSON records the raw encoding of unknown-type blocks, as
+// messageDTO does for messages.
+func (b *contentBlock) UnmarshalJSON(data []byte) error {
+	type plain contentBlock
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		var head struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(data, &head) != nil || knownBlockType(head.Type) {
+			return err
+		}
+		p = plain{Type: head.Type}
+	}
+	*b = contentBlock(p)
+	if !knownBlockType(b.Type) {
+		b.raw = append(json.RawMessage(nil), data...)
+	}
+	return nil
+}
+
+// MarshalJSON writes preserved blocks back verbatim.
+func (b contentBlock) MarshalJSON() ([]byte, error) {
+	if b.raw != nil {
+		return b.raw, nil
+	}
+	type plain contentBlock
+	return json.Marshal(plain(b))
+}
+
+func knownBlockType(t string) bool {
+	switch t {
+	case "text", "thinking", "image", "tool_call", "server_tool_call", "server_tool_result":
+		return true
+	}
+	return false
+}
+
+// citation is the JSON representation of a pipe.Citation.
+type citation struct {
+	URL       string `json:"url,omitempty"`
+	Title     string `json:"title,omitempty"`
+	CitedText string `json:"cited_text,omitempty"`
 }
 
 func marshalContentBlocks(blocks []pipe.ContentBlock) ([]contentBlock, error) {
@@ -36,7 +91,11 @@ func marshalContentBlocks(blocks []pipe.ContentBlock) ([]contentBlock, error) {
 func marshalContentBlock(b pipe.ContentBlock) (contentBlock, error) {
 	switch v := b.(type) {
 	case pipe.TextBlock:
-		return contentBlock{Type: "text", Text: &v.Text}, nil
+		cb := contentBlock{Type: "text", Text: &v.Text}
+		for _, c := range v.Citations {
+			cb.Citations = append(cb.Citations, citation(c))
+		}
+		return cb, nil
 	case pipe.ThinkingBlock:
 		cb := contentBlock{Type: "thinking", Thinking: &v.Thinking}
 		if len(v.Signature) > 0 {
@@ -55,14 +114,30 @@ func marshalContentBlock(b pipe.ContentBlock) (contentBlock, error) {
 			cb.Signature = &encoded
 		}
 		return cb, nil
+	case pipe.ServerToolCallBlock:
+		args := v.Arguments
+		return contentBlock{Type: "server_tool_call", ID: &v.ID, Name: &v.Name, Arguments: &args}, nil
+	case pipe.ServerToolResultBlock:
+		content := v.Content
+		cb := contentBlock{Type: "server_tool_result", ToolCallID: &v.ToolCallID, Name: &v.Name, Content: &content}
+		if v.IsError {
+			cb.IsError = &v.IsError
+		}
+		return cb, nil
+	case pipe.OpaqueBlock:
+		return contentBlock{Type: v.Type, raw: v.Raw}, nil
 	default:
 		return contentBlock{}, fmt.Errorf("unknown content block type: %T", b)
 	}
 }
 
-func unmarshalContentBlocks(dtos []contentBlock) ([]pipe.ContentBlock, error) {
+func unmarshalContentBlocks(dtos []contentBlock, preserveUnknown bool) ([]pipe.ContentBlock, error) {
 	result := make([]pipe.ContentBlock, len(dtos))
 	for i, dto := range dtos {
+		if preserveUnknown && !knownBlockType(dto.Type) {
+			result[i] = pipe.OpaqueBlock{Type: dto.Type, Raw: dto.raw}
+			continue
+		}
 		b, err := unmarshalContentBlock(dto)
 		if err != nil {
 			return nil, fmt.Errorf("content block %d: %w", i, err)
@@ -79,7 +154,11 @@ func unmarshalContentBlock(dto contentBlock) (pipe.ContentBlock, error) {
 		if dto.Text != nil {
 			text = *dto.Text
 		}
-		return pipe.TextBlock{Text: text}, nil
+		var citations []pipe.Citation
+		for _, c := range dto.Citations {
+			citations = append(citations, pipe.Citation(c))
+		}
+		return pipe.TextBlock{Text: text, Citations: citations}, nil
 	case "thinking":
 		var thinking string
 		if dto.Thinking != nil {
@@ -129,6 +208,36 @@ func unmarshalContentBlock(dto contentBlock) (pipe.ContentBlock, error) {
 			}
 		}
 		return pipe.ToolCallBlock{ID: id, Name: name, Arguments: args, Signature: sig}, nil
+	case "server_tool_call":
+		var id, name string
+		if dto.ID != nil {
+			id = *dto.ID
+		}
+		if dto.Name != nil {
+			name = *dto.Name
+		}
+		var args json.RawMessage
+		if dto.Arguments != nil {
+			args = *dto.Arguments
+		}
+		return pipe.ServerToolCallBlock{ID: id, Name: name, Arguments: args}, nil
+	case "server_tool_result":
+		var toolCallID, name string
+		if dto.ToolCallID != nil {
+			toolCallID = *dto.ToolCallID
+		}
+		if dto.Name != nil {
+			name = *dto.Name
+		}
+		var content json.RawMessage
+		if dto.Content != nil {
+			content = *dto.Content
+		}
+		var isError bool
+		if dto.IsError != nil {
+			isError = *dto.IsError
+		}
+		return pipe.ServerToolResultBlock{ToolCallID: toolCallID, Name: name, Content: content, IsError: isError}, nil
 	default:
 		return nil, fmt.Errorf("unknown content block type: %q", dto.Type)
 	}
@@ -0,0 +1,221 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fwojciec/pipe"
+)
+
+// DatasetFormat names a dataset record format written by
+// MarshalDatasetRecord.
+type DatasetFormat string
+
+const (
+	// DatasetOpenAI is the OpenAI chat fine-tuning format: a "messages"
+	// list of system, user, assistant, and tool messages with text content.
+	DatasetOpenAI DatasetFormat = "openai"
+	// DatasetAnthropic is the Anthropic Messages API format: a "system"
+	// prompt and a "messages" list of user and assistant turns with
+	// content blocks, as used for evals.
+	DatasetAnthropic DatasetFormat = "anthropic"
+)
+
+// DatasetOptions configures MarshalDatasetRecord.
+type DatasetOptions struct {
+	Format DatasetFormat
+	// StripTools leaves out tool calls and their results, keeping only
+	// the text of the conversation.
+	StripTools bool
+}
+
+// MarshalDatasetRecord converts s to one dataset record, a single line of
+// JSON without a trailing newline. Thinking, images, and server tool
+// blocks are left out, and consecutive messages of the same role are
+// merged.
+func MarshalDatasetRecord(s pipe.Session, opts DatasetOptions) ([]byte, error) {
+	switch opts.Format {
+	case DatasetOpenAI:
+		return json.Marshal(openAIRecord(s, opts.StripTools))
+	case DatasetAnthropic:
+		return json.Marshal(anthropicRecord(s, opts.StripTools))
+	default:
+		return nil, fmt.Errorf("unknown dataset format %q", opts.Format)
+	}
+}
+
+type openAIRecordDTO struct {
+	Messages []openAIMessageDTO `json:"messages"`
+}
+
+type openAIMessageDTO struct {
+	Role       string              `json:"role"`
+	Content    string              `json:"content,omitempty"`
+	ToolCalls  []openAIToolCallDTO `json:"tool_calls,omitempty"`
+	ToolCallID string              `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCallDTO struct {
+	ID       string            `json:"id"`
+	Type     string            `json:"type"`
+	Function openAIFunctionDTO `json:"function"`
+}
+
+type openAIFunctionDTO struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+func openAIRecord(s pipe.Session, stripTools bool) openAIRecordDTO {
+	var rec openAIRecordDTO
+	if s.SystemPrompt != "" {
+		rec.Messages = append(rec.Messages, openAIMessageDTO{Role: "system", Content: s.SystemPrompt})
+	}
+	add := func(m openAIMessageDTO) {
+		if m.Content == "" && len(m.ToolCalls) == 0 {
+			return
+		}
+		// Tool messages answer one call each and are never merged.
+		if n := len(rec.Messages); n > 0 && m.Role != "tool" && rec.Messages[n-1].Role == m.Role {
+			last := &rec.Messages[n-1]
+			last.Content = joinText(last.Content, m.Content)
+			last.ToolCalls = append(last.ToolCalls, m.ToolCalls...)
+			return
+		}
+		rec.Messages = append(rec.Messages, m)
+	}
+	for _, msg := range s.Messages {
+		switch msg := msg.(type) {
+		case pipe.UserMessage:
+			add(openAIMessageDTO{Role: "user", Content: datasetText(msg.Content)})
+		case pipe.AssistantMessage:
+			m := openAIMessageDTO{Role: "assistant", Content: datasetText(msg.Content)}
+			if !stripTools {
+				for _, b := range msg.Content {
+					if call, ok := b.(pipe.ToolCallBlock); ok {
+						m.ToolCalls = append(m.ToolCalls, openAIToolCallDTO{
+							ID:       call.ID,
+							Type:     "function",
+							Function: openAIFunctionDTO{Name: call.Name, Arguments: string(toolInput(call.Arguments))},
+						})
+					}
+				}
+			}
+			add(m)
+		case pipe.ToolResultMessage:
+			if !stripTools {
+				add(openAIMessageDTO{Role: "tool", ToolCallID: msg.ToolCallID, Content: toolResultText(msg)})
+			}
+		}
+	}
+	return rec
+}
+
+type anthropicRecordDTO struct {
+	System   string                `json:"system,omitempty"`
+	Messages []anthropicMessageDTO `json:"messages"`
+}
+
+type anthropicMessageDTO struct {
+	Role    string                `json:"role"`
+	Content []anthropicContentDTO `json:"content"`
+}
+
+type anthropicContentDTO struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+func anthropicRecord(s pipe.Session, stripTools bool) anthropicRecordDTO {
+	rec := anthropicRecordDTO{System: s.SystemPrompt}
+	add := func(role string, content []anthropicContentDTO) {
+		if len(content) == 0 {
+			return
+		}
+		// The API expects alternating roles, e.g. tool results and the
+		// next prompt in one user turn.
+		if n := len(rec.Messages); n > 0 && rec.Messages[n-1].Role == role {
+			rec.Messages[n-1].Content = append(rec.Messages[n-1].Content, content...)
+			return
+		}
+		rec.Messages = append(rec.Messages, anthropicMessageDTO{Role: role, Content: content})
+	}
+	for _, msg := range s.Messages {
+		switch msg := msg.(type) {
+		case pipe.UserMessage:
+			add("user", anthropicText(msg.Content))
+		case pipe.AssistantMessage:
+			content := anthropicText(msg.Content)
+			if !stripTools {
+				for _, b := range msg.Content {
+					if call, ok := b.(pipe.ToolCallBlock); ok {
+						content = append(content, anthropicContentDTO{Type: "tool_use", ID: call.ID, Name: call.Name, Input: toolInput(call.Arguments)})
+					}
+				}
+			}
+			add("assistant", content)
+		case pipe.ToolResultMessage:
+			if !stripTools {
+				add("user", []anthropicContentDTO{{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: toolResultText(msg), IsError: msg.IsError}})
+			}
+		}
+	}
+	return rec
+}
+
+// anthropicText returns the non-empty text blocks of blocks.
+func anthropicText(blocks []pipe.ContentBlock) []anthropicContentDTO {
+	var content []anthropicContentDTO
+	for _, b := range blocks {
+		if text, ok := b.(pipe.TextBlock); ok && strings.TrimSpace(text.Text) != "" {
+			content = append(content, anthropicContentDTO{Type: "text", Text: text.Text})
+		}
+	}
+	return content
+}
+
+// datasetText joins the text blocks of blocks.
+func datasetText(blocks []pipe.ContentBlock) string {
+	var text string
+	for _, b := range blocks {
+		if t, ok := b.(pipe.TextBlock); ok && strings.TrimSpace(t.Text) != "" {
+			text = joinText(text, t.Text)
+		}
+	}
+	return text
+}
+
+func joinText(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	default:
+		return a + "\n\n" + b
+	}
+}
+
+// toolResultText returns the text of a tool result; a result with no text,
+// such as an image, is described rather than left empty.
+func toolResultText(msg pipe.ToolResultMessage) string {
+	if text := datasetText(msg.Content); text != "" {
+		return text
+	}
+	return "(no text output)"
+}
+
+// toolInput returns tool call arguments as a JSON object, "{}" when empty.
+func toolInput(args json.RawMessage) json.RawMessage {
+	if len(strings.TrimSpace(string(args))) == 0 {
+		return json.RawMessage("{}")
+	}
+	return args
+}
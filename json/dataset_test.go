@@ -0,0 +1,88 @@
+package json_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalDatasetRecord(t *testing.T) {
+	t.Parallel()
+
+	text := func(s string) []pipe.ContentBlock { return []pipe.ContentBlock{pipe.TextBlock{Text: s}} }
+	session := pipe.Session{
+		SystemPrompt: "You are a coding assistant.",
+		Messages: []pipe.Message{
+			pipe.UserMessage{Content: text("List the files.")},
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{
+				pipe.ThinkingBlock{Thinking: "Use bash."},
+				pipe.TextBlock{Text: "Listing."},
+				pipe.ToolCallBlock{ID: "c1", Name: "bash", Arguments: json.RawMessage(`{"command":"ls"}`)},
+			}, StopReason: pipe.StopToolUse},
+			pipe.ToolResultMessage{ToolCallID: "c1", ToolName: "bash", Content: text("go.mod\nmain.go")},
+			pipe.AssistantMessage{Content: text("There are two files."), StopReason: pipe.StopEndTurn},
+			pipe.UserMessage{Content: text("Thanks.")},
+		},
+	}
+
+	t.Run("openai", func(t *testing.T) {
+		t.Parallel()
+		got, err := pipejson.MarshalDatasetRecord(session, pipejson.DatasetOptions{Format: pipejson.DatasetOpenAI})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"messages":[
+			{"role":"system","content":"You are a coding assistant."},
+			{"role":"user","content":"List the files."},
+			{"role":"assistant","content":"Listing.","tool_calls":[{"id":"c1","type":"function","function":{"name":"bash","arguments":"{\"command\":\"ls\"}"}}]},
+			{"role":"tool","tool_call_id":"c1","content":"go.mod\nmain.go"},
+			{"role":"assistant","content":"There are two files."},
+			{"role":"user","content":"Thanks."}
+		]}`, string(got))
+		assert.NotContains(t, string(got), "\n")
+	})
+
+	t.Run("openai without tools", func(t *testing.T) {
+		t.Parallel()
+		got, err := pipejson.MarshalDatasetRecord(session, pipejson.DatasetOptions{Format: pipejson.DatasetOpenAI, StripTools: true})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"messages":[
+			{"role":"system","content":"You are a coding assistant."},
+			{"role":"user","content":"List the files."},
+			{"role":"assistant","content":"Listing.\n\nThere are two files."},
+			{"role":"user","content":"Thanks."}
+		]}`, string(got))
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		t.Parallel()
+		got, err := pipejson.MarshalDatasetRecord(session, pipejson.DatasetOptions{Format: pipejson.DatasetAnthropic})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"system":"You are a coding assistant.","messages":[
+			{"role":"user","content":[{"type":"text","text":"List the files."}]},
+			{"role":"assistant","content":[{"type":"text","text":"Listing."},{"type":"tool_use","id":"c1","name":"bash","input":{"command":"ls"}}]},
+			{"role":"user","content":[{"type":"tool_result","tool_use_id":"c1","content":"go.mod\nmain.go"}]},
+			{"role":"assistant","content":[{"type":"text","text":"There are two files."}]},
+			{"role":"user","content":[{"type":"text","text":"Thanks."}]}
+		]}`, string(got))
+	})
+
+	t.Run("anthropic without tools", func(t *testing.T) {
+		t.Parallel()
+		got, err := pipejson.MarshalDatasetRecord(session, pipejson.DatasetOptions{Format: pipejson.DatasetAnthropic, StripTools: true})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"system":"You are a coding assistant.","messages":[
+			{"role":"user","content":[{"type":"text","text":"List the files."}]},
+			{"role":"assistant","content":[{"type":"text","text":"Listing."},{"type":"text","text":"There are two files."}]},
+			{"role":"user","content":[{"type":"text","text":"Thanks."}]}
+		]}`, string(got))
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		t.Parallel()
+		_, err := pipejson.MarshalDatasetRecord(session, pipejson.DatasetOptions{Format: "csv"})
+		require.EqualError(t, err, `unknown dataset format "csv"`)
+	})
+}
@@ -0,0 +1,214 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/fwojciec/pipe"
+)
+
+// EventOptions selects which events an EventWriter writes. Include and
+// Exclude hold event type names, e.g. "tool_call_end" or, in compact mode,
+// "text". An empty Include writes every type not excluded.
+type EventOptions struct {
+	Include []string
+	Exclude []string
+	// Compact merges consecutive text and thinking deltas into one "text"
+	// or "thinking" event per block and writes each tool call once, as a
+	// "tool_call" event, so readers don't have to reassemble streams.
+	Compact bool
+}
+
+// streamEventTypes are the event type names written in streaming mode.
+func streamEventTypes() []string {
+	return []string{
+		"text_delta", "citation", "thinking_delta",
+		"tool_call_begin", "tool_call_delta", "tool_call_end",
+		"server_tool_call", "server_tool_result", "tool_result", "tool_timeout", "turn_complete", "compaction", "retry", "run_status",
+	}
+}
+
+// compactEventTypes are the event type names written in compact mode.
+func compactEventTypes() []string {
+	return []string{"text", "thinking", "tool_call", "server_tool_call", "server_tool_result", "tool_result", "tool_timeout", "turn_complete", "compaction", "retry", "run_status"}
+}
+
+// eventDTO is the wire format of one event line.
+type eventDTO struct {
+	Type       string          `json:"type"`
+	Index      *int            `json:"index,omitempty"`
+	Delta      string          `json:"delta,omitempty"`
+	Text       string          `json:"text,omitempty"`
+	Thinking   string          `json:"thinking,omitempty"`
+	ID         string          `json:"id,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	Content    json.RawMessage `json:"content,omitempty"`
+	IsError    bool            `json:"is_error,omitempty"`
+	Citations  []citation      `json:"citations,omitempty"`
+	Messages   int             `json:"messages,omitempty"`
+	Tokens     int             `json:"tokens,omitempty"`
+	Attempt    int             `json:"attempt,omitempty"`
+	DelayMS    int64           `json:"delay_ms,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Resumed    bool            `json:"resumed,omitempty"`
+	TimeoutMS  int64           `json:"timeout_ms,omitempty"`
+	StopReason string          `json:"stop_reason,omitempty"`
+	Usage      *usageDTO       `json:"usage,omitempty"`
+	Status     string          `json:"status,omitempty"`
+}
+
+// EventWriter writes streaming events as JSON lines, one event per line.
+// Call Flush after the last event so compact mode writes any text still
+// being merged.
+type EventWriter struct {
+	w       io.Writer
+	include map[string]bool
+	exclude map[string]bool
+	compact bool
+
+	// pending is the text or thinking event being merged in compact mode.
+	pending *eventDTO
+}
+
+// NewEventWriter returns an EventWriter that writes the events selected by
+// opts to w. It returns an error for an unknown event type name.
+func NewEventWriter(w io.Writer, opts EventOptions) (*EventWriter, error) {
+	known := streamEventTypes()
+	if opts.Compact {
+		known = compactEventTypes()
+	}
+	include, err := eventTypeSet(opts.Include, known)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := eventTypeSet(opts.Exclude, known)
+	if err != nil {
+		return nil, err
+	}
+	return &EventWriter{w: w, include: include, exclude: exclude, compact: opts.Compact}, nil
+}
+
+func eventTypeSet(names, known []string) (map[string]bool, error) {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !slices.Contains(known, name) {
+			return nil, fmt.Errorf("unknown event type %q: must be one of %s", name, strings.Join(known, ", "))
+		}
+		set[name] = true
+	}
+	return set, nil
+}
+
+// Write writes e, or in compact mode merges it into the pending event.
+func (ew *EventWriter) Write(e pipe.Event) error {
+	if !ew.compact {
+		return ew.write(streamEvent(e))
+	}
+	switch e := e.(type) {
+	case pipe.EventTextDelta:
+		if p := ew.pending; p != nil && p.Type == "text" && *p.Index == e.Index {
+			p.Text += e.Delta
+			return nil
+		}
+		if err := ew.Flush(); err != nil {
+			return err
+		}
+		ew.pending = &eventDTO{Type: "text", Index: &e.Index, Text: e.Delta}
+		return nil
+	case pipe.EventThinkingDelta:
+		if p := ew.pending; p != nil && p.Type == "thinking" && *p.Index == e.Index {
+			p.Thinking += e.Delta
+			return nil
+		}
+		if err := ew.Flush(); err != nil {
+			return err
+		}
+		ew.pending = &eventDTO{Type: "thinking", Index: &e.Index, Thinking: e.Delta}
+		return nil
+	case pipe.EventCitation:
+		if p := ew.pending; p != nil && p.Type == "text" && *p.Index == e.Index {
+			p.Citations = append(p.Citations, citation(e.Citation))
+		}
+		return nil
+	case pipe.EventToolCallBegin, pipe.EventToolCallDelta:
+		// The assembled call follows in EventToolCallEnd.
+		return nil
+	}
+	if err := ew.Flush(); err != nil {
+		return err
+	}
+	dto := streamEvent(e)
+	if dto.Type == "tool_call_end" {
+		dto.Type = "tool_call"
+	}
+	return ew.write(dto)
+}
+
+// Flush writes the event being merged in compact mode, if any.
+func (ew *EventWriter) Flush() error {
+	if ew.pending == nil {
+		return nil
+	}
+	dto := *ew.pending
+	ew.pending = nil
+	return ew.write(dto)
+}
+
+func (ew *EventWriter) write(dto eventDTO) error {
+	if (len(ew.include) > 0 && !ew.include[dto.Type]) || ew.exclude[dto.Type] {
+		return nil
+	}
+	data, err := json.Marshal(dto)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", dto.Type, err)
+	}
+	_, err = ew.w.Write(append(data, '\n'))
+	return err
+}
+
+// streamEvent converts e to its streaming-mode wire format.
+func streamEvent(e pipe.Event) eventDTO {
+	switch e := e.(type) {
+	case pipe.EventTextDelta:
+		return eventDTO{Type: "text_delta", Index: &e.Index, Delta: e.Delta}
+	case pipe.EventCitation:
+		return eventDTO{Type: "citation", Index: &e.Index, Citations: []citation{citation(e.Citation)}}
+	case pipe.EventThinkingDelta:
+		return eventDTO{Type: "thinking_delta", Index: &e.Index, Delta: e.Delta}
+	case pipe.EventToolCallBegin:
+		return eventDTO{Type: "tool_call_begin", ID: e.ID, Name: e.Name}
+	case pipe.EventToolCallDelta:
+		return eventDTO{Type: "tool_call_delta", ID: e.ID, Delta: e.Delta}
+	case pipe.EventToolCallEnd:
+		return eventDTO{Type: "tool_call_end", ID: e.Call.ID, Name: e.Call.Name, Arguments: e.Call.Arguments}
+	case pipe.EventServerToolCall:
+		return eventDTO{Type: "server_tool_call", ID: e.Call.ID, Name: e.Call.Name, Arguments: e.Call.Arguments}
+	case pipe.EventServerToolResult:
+		return eventDTO{Type: "server_tool_result", ToolCallID: e.Result.ToolCallID, Name: e.Result.Name, Content: e.Result.Content, IsError: e.Result.IsError}
+	case pipe.EventToolResult:
+		content, _ := json.Marshal(e.Content)
+		return eventDTO{Type: "tool_result", ID: e.ID, Name: e.ToolName, Content: content, IsError: e.IsError}
+	case pipe.EventToolTimeout:
+		return eventDTO{Type: "tool_timeout", ID: e.ID, Name: e.ToolName, TimeoutMS: e.Timeout.Milliseconds()}
+	case pipe.EventTurnComplete:
+		u := e.Usage
+		return eventDTO{Type: "turn_complete", StopReason: string(e.StopReason), Usage: &usageDTO{InputTokens: u.InputTokens, OutputTokens: u.OutputTokens, CacheReadTokens: u.CacheReadTokens, CacheWriteTokens: u.CacheWriteTokens}}
+	case pipe.EventCompaction:
+		return eventDTO{Type: "compaction", Messages: e.Messages, Tokens: e.TokensBefore, Text: e.Summary}
+	case pipe.EventRetry:
+		dto := eventDTO{Type: "retry", Attempt: e.Attempt, DelayMS: e.Delay.Milliseconds(), Resumed: e.Resumed}
+		if e.Err != nil {
+			dto.Error = e.Err.Error()
+		}
+		return dto
+	case pipe.EventRunStatus:
+		return eventDTO{Type: "run_status", Status: string(e.Status.State), Text: e.Status.Detail}
+	default:
+		return eventDTO{Type: fmt.Sprintf("%T", e)}
+	}
+}
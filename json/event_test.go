@@ -0,0 +1,114 @@
+package json_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// turnEvents is a turn that thinks, writes text with a citation, calls a
+// tool, and receives its result.
+func turnEvents() []pipe.Event {
+	return []pipe.Event{
+		pipe.EventThinkingDelta{Index: 0, Delta: "Let me "},
+		pipe.EventThinkingDelta{Index: 0, Delta: "check."},
+		pipe.EventTextDelta{Index: 1, Delta: "Listing "},
+		pipe.EventCitation{Index: 1, Citation: pipe.Citation{URL: "https://example.com"}},
+		pipe.EventTextDelta{Index: 1, Delta: "files."},
+		pipe.EventToolCallBegin{ID: "c1", Name: "bash"},
+		pipe.EventToolCallDelta{ID: "c1", Delta: `{"command":`},
+		pipe.EventToolCallDelta{ID: "c1", Delta: `"ls"}`},
+		pipe.EventToolCallEnd{Call: pipe.ToolCallBlock{ID: "c1", Name: "bash", Arguments: json.RawMessage(`{"command":"ls"}`)}},
+		pipe.EventToolResult{ID: "c1", ToolName: "bash", Content: "a.go\n"},
+		pipe.EventTextDelta{Index: 0, Delta: "Done."},
+	}
+}
+
+func writeEvents(t *testing.T, opts pipejson.EventOptions, events []pipe.Event) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	ew, err := pipejson.NewEventWriter(&buf, opts)
+	require.NoError(t, err)
+	for _, e := range events {
+		require.NoError(t, ew.Write(e))
+	}
+	require.NoError(t, ew.Flush())
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func TestEventWriter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("streams every event", func(t *testing.T) {
+		t.Parallel()
+		lines := writeEvents(t, pipejson.EventOptions{}, turnEvents())
+		require.Len(t, lines, 11)
+		assert.JSONEq(t, `{"type":"thinking_delta","index":0,"delta":"Let me "}`, lines[0])
+		assert.JSONEq(t, `{"type":"citation","index":1,"citations":[{"url":"https://example.com"}]}`, lines[3])
+		assert.JSONEq(t, `{"type":"tool_call_begin","id":"c1","name":"bash"}`, lines[5])
+		assert.JSONEq(t, `{"type":"tool_call_end","id":"c1","name":"bash","arguments":{"command":"ls"}}`, lines[8])
+		assert.JSONEq(t, `{"type":"tool_result","id":"c1","name":"bash","content":"a.go\n"}`, lines[9])
+	})
+
+	t.Run("compact merges deltas", func(t *testing.T) {
+		t.Parallel()
+		lines := writeEvents(t, pipejson.EventOptions{Compact: true}, turnEvents())
+		require.Len(t, lines, 5)
+		assert.JSONEq(t, `{"type":"thinking","index":0,"thinking":"Let me check."}`, lines[0])
+		assert.JSONEq(t, `{"type":"text","index":1,"text":"Listing files.","citations":[{"url":"https://example.com"}]}`, lines[1])
+		assert.JSONEq(t, `{"type":"tool_call","id":"c1","name":"bash","arguments":{"command":"ls"}}`, lines[2])
+		assert.JSONEq(t, `{"type":"tool_result","id":"c1","name":"bash","content":"a.go\n"}`, lines[3])
+		assert.JSONEq(t, `{"type":"text","index":0,"text":"Done."}`, lines[4])
+	})
+
+	t.Run("writes tool timeouts", func(t *testing.T) {
+		t.Parallel()
+		lines := writeEvents(t, pipejson.EventOptions{Compact: true}, []pipe.Event{
+			pipe.EventToolTimeout{ID: "c1", ToolName: "bash", Timeout: 2 * time.Minute},
+		})
+		require.Len(t, lines, 1)
+		assert.JSONEq(t, `{"type":"tool_timeout","id":"c1","name":"bash","timeout_ms":120000}`, lines[0])
+	})
+
+	t.Run("writes turn usage", func(t *testing.T) {
+		t.Parallel()
+		lines := writeEvents(t, pipejson.EventOptions{Compact: true}, []pipe.Event{
+			pipe.EventTextDelta{Index: 0, Delta: "Done."},
+			pipe.EventTurnComplete{StopReason: pipe.StopEndTurn, Usage: pipe.Usage{InputTokens: 120, OutputTokens: 8, CacheReadTokens: 900}},
+		})
+		require.Len(t, lines, 2)
+		assert.JSONEq(t, `{"type":"turn_complete","stop_reason":"end_turn","usage":{"input_tokens":120,"output_tokens":8,"cache_read_tokens":900}}`, lines[1])
+	})
+
+	t.Run("include selects event types", func(t *testing.T) {
+		t.Parallel()
+		lines := writeEvents(t, pipejson.EventOptions{Compact: true, Include: []string{"tool_call", "text"}}, turnEvents())
+		require.Len(t, lines, 3)
+		assert.Contains(t, lines[0], `"type":"text"`)
+		assert.Contains(t, lines[1], `"type":"tool_call"`)
+		assert.Contains(t, lines[2], `"text":"Done."`)
+	})
+
+	t.Run("exclude drops event types", func(t *testing.T) {
+		t.Parallel()
+		lines := writeEvents(t, pipejson.EventOptions{Exclude: []string{"text_delta", "thinking_delta", "tool_call_delta"}}, turnEvents())
+		require.Len(t, lines, 4)
+		assert.Contains(t, lines[0], `"type":"citation"`)
+		assert.Contains(t, lines[1], `"type":"tool_call_begin"`)
+	})
+
+	t.Run("rejects unknown event types", func(t *testing.T) {
+		t.Parallel()
+		_, err := pipejson.NewEventWriter(&bytes.Buffer{}, pipejson.EventOptions{Include: []string{"text"}})
+		require.ErrorContains(t, err, `unknown event type "text"`)
+		_, err = pipejson.NewEventWriter(&bytes.Buffer{}, pipejson.EventOptions{Compact: true, Exclude: []string{"text_delta"}})
+		require.ErrorContains(t, err, `unknown event type "text_delta"`)
+	})
+}
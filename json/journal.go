@@ -0,0 +1,195 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// journalRecord is the wire format of one line of a session journal: the
+// messages from Offset on, which replace any the session already has
+// there, and the session fields a turn changes. A record at offset 0 is a
+// snapshot and also carries the fields set when the session was created.
+type journalRecord struct {
+	Offset       int           `json:"offset"`
+	ID           string        `json:"id"`
+	SystemPrompt string        `json:"system_prompt,omitempty"`
+	CreatedAt    *time.Time    `json:"created_at,omitempty"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	Status       *runStatusDTO `json:"status,omitempty"`
+	Messages     []messageDTO  `json:"messages"`
+}
+
+// JournalPath returns the path of the journal kept beside the session file
+// at path.
+func JournalPath(path string) string {
+	return path + ".journal"
+}
+
+// Journal is a write-ahead log of the turns of a session, kept beside its
+// session file so a process that dies before it saves the session, or while
+// saving it, loses nothing that was journaled. Each Append adds one compact
+// JSON line with the messages added since the last one. Load replays the
+// journal over the session file, and Save, having written the file,
+// removes it. Artifacts and schedules are not journaled.
+type Journal struct {
+	path string
+
+	mu     sync.Mutex
+	logged int    // messages journaled or already saved
+	last   []byte // the last of them, to notice a rewrite such as compaction
+}
+
+// NewJournal returns the journal of the session file at path. s is the
+// session as already saved or journaled; Append records what is added to
+// it.
+func NewJournal(path string, s pipe.Session) *Journal {
+	j := &Journal{path: JournalPath(path), logged: len(s.Messages)}
+	if n := len(s.Messages); n > 0 {
+		if dto, err := marshalMessage(s.Messages[n-1]); err == nil {
+			j.last, _ = json.Marshal(dto)
+		}
+	}
+	return j
+}
+
+// Append journals the messages of s added since the last call. When the
+// messages journaled before have since been rewritten, as compaction does,
+// it journals a snapshot of the whole session instead. The line is synced
+// to disk before Append returns.
+func (j *Journal) Append(s pipe.Session) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	offset := j.logged
+	if offset > len(s.Messages) || (offset > 0 && !j.unchanged(s.Messages[offset-1])) {
+		offset = 0
+	}
+	rec := journalRecord{Offset: offset, ID: s.ID, UpdatedAt: s.UpdatedAt, Messages: make([]messageDTO, 0, len(s.Messages)-offset)}
+	if offset == 0 {
+		rec.SystemPrompt = s.SystemPrompt
+		rec.CreatedAt = &s.CreatedAt
+	}
+	if s.Status != (pipe.RunStatus{}) {
+		rec.Status = &runStatusDTO{State: string(s.Status.State), Detail: s.Status.Detail}
+	}
+	var last []byte
+	for i, msg := range s.Messages[offset:] {
+		dto, err := marshalMessage(msg)
+		if err != nil {
+			return fmt.Errorf("message %d: %w", offset+i, err)
+		}
+		rec.Messages = append(rec.Messages, dto)
+		if last, err = json.Marshal(dto); err != nil {
+			return fmt.Errorf("message %d: %w", offset+i, err)
+		}
+	}
+	if last == nil {
+		last = j.last
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return fmt.Errorf("create directories: %w", err)
+	}
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("open journal: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync journal: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close journal: %w", err)
+	}
+	j.logged, j.last = len(s.Messages), last
+	return nil
+}
+
+// unchanged reports whether msg is the last message journaled.
+func (j *Journal) unchanged(msg pipe.Message) bool {
+	dto, err := marshalMessage(msg)
+	if err != nil {
+		return false
+	}
+	data, err := json.Marshal(dto)
+	return err == nil && bytes.Equal(data, j.last)
+}
+
+// replayJournal applies the journal data to s. A malformed final line,
+// left by an interrupted write, is skipped.
+func replayJournal(s *pipe.Session, data []byte, cfg loadConfig) error {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var rec journalRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			if truncated := !bytes.HasSuffix(data, []byte("\n")) && bytes.HasSuffix(data, sc.Bytes()); truncated {
+				break
+			}
+			return fmt.Errorf("journal line %d: %w", line, err)
+		}
+		if rec.Offset < 0 || rec.Offset > len(s.Messages) {
+			return fmt.Errorf("journal line %d: starts at message %d of %d", line, rec.Offset, len(s.Messages))
+		}
+		msgs := s.Messages[:rec.Offset:rec.Offset]
+		for i, dto := range rec.Messages {
+			msg, err := unmarshalMessage(dto, cfg.preserveUnknown)
+			if err != nil {
+				return fmt.Errorf("journal line %d: message %d: %w", line, rec.Offset+i, err)
+			}
+			msgs = append(msgs, msg)
+		}
+		s.Messages = msgs
+		if rec.Offset == 0 {
+			s.SystemPrompt = rec.SystemPrompt
+			if rec.CreatedAt != nil {
+				s.CreatedAt = *rec.CreatedAt
+			}
+		}
+		s.ID = rec.ID
+		s.UpdatedAt = rec.UpdatedAt
+		s.Status = pipe.RunStatus{}
+		if rec.Status != nil {
+			s.Status = pipe.RunStatus{State: pipe.RunState(rec.Status.State), Detail: rec.Status.Detail}
+		}
+	}
+	return nil
+}
+
+// Recover folds the journal of the session file at path into the file and
+// returns the session. A session that was only journaled, because the
+// process died before saving it, gets its file. Without a journal it
+// returns the session as Load does.
+func Recover(path string, opts ...LoadOption) (pipe.Session, error) {
+	s, err := Load(path, opts...)
+	if err != nil {
+		return pipe.Session{}, err
+	}
+	if _, err := os.Stat(JournalPath(path)); errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err := Save(path, s); err != nil {
+		return pipe.Session{}, err
+	}
+	return s, nil
+}
@@ -0,0 +1,175 @@
+package json_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func journalSession() pipe.Session {
+	return pipe.Session{
+		ID:           "journaled",
+		SystemPrompt: "You are helpful.",
+		CreatedAt:    time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+		UpdatedAt:    time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func text(role string, s string) pipe.Message {
+	if role == "user" {
+		return pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: s}}}
+	}
+	return pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: s}}, StopReason: pipe.StopEndTurn}
+}
+
+func TestJournal(t *testing.T) {
+	t.Parallel()
+
+	t.Run("load replays turns over the saved file", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		s := journalSession()
+		s.Messages = []pipe.Message{text("user", "hi"), text("assistant", "hello")}
+		require.NoError(t, pipejson.Save(path, s))
+
+		j := pipejson.NewJournal(path, s)
+		s.Messages = append(s.Messages, text("user", "again"), text("assistant", "sure"))
+		s.UpdatedAt = s.UpdatedAt.Add(time.Minute)
+		s.Status = pipe.RunStatus{State: pipe.RunDone, Detail: "said hi"}
+		require.NoError(t, j.Append(s))
+
+		got, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, s.Messages, got.Messages)
+		assert.Equal(t, s.UpdatedAt, got.UpdatedAt)
+		assert.Equal(t, s.Status, got.Status)
+	})
+
+	t.Run("session only journaled loads from the journal", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		s := journalSession()
+		j := pipejson.NewJournal(path, s)
+		s.Messages = []pipe.Message{text("user", "hi"), text("assistant", "hello")}
+		require.NoError(t, j.Append(s))
+		s.Messages = append(s.Messages, text("user", "more"), text("assistant", "ok"))
+		require.NoError(t, j.Append(s))
+
+		got, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, s, got)
+	})
+
+	t.Run("rewritten messages are journaled as a snapshot", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		s := journalSession()
+		s.Messages = []pipe.Message{text("user", "a"), text("assistant", "b"), text("user", "c"), text("assistant", "d")}
+		require.NoError(t, pipejson.Save(path, s))
+		j := pipejson.NewJournal(path, s)
+
+		// Compaction replaces the history with a summary of it.
+		s.Messages = []pipe.Message{text("user", "summary"), text("assistant", "b"), text("user", "e"), text("assistant", "f")}
+		require.NoError(t, j.Append(s))
+
+		got, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, s.Messages, got.Messages)
+	})
+
+	t.Run("interrupted final line is skipped", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		s := journalSession()
+		j := pipejson.NewJournal(path, s)
+		s.Messages = []pipe.Message{text("user", "hi")}
+		require.NoError(t, j.Append(s))
+		f, err := os.OpenFile(pipejson.JournalPath(path), os.O_WRONLY|os.O_APPEND, 0o600)
+		require.NoError(t, err)
+		_, err = f.WriteString(`{"offset":1,"id":"journ`)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		got, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, s.Messages, got.Messages)
+	})
+
+	t.Run("corrupt session file loads from a snapshot", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		s := journalSession()
+		j := pipejson.NewJournal(path, s)
+		s.Messages = []pipe.Message{text("user", "hi")}
+		require.NoError(t, j.Append(s))
+		require.NoError(t, os.WriteFile(path, []byte(`{"version": 1, "id": "jour`), 0o600))
+
+		got, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, s.Messages, got.Messages)
+	})
+
+	t.Run("save removes the journal", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		s := journalSession()
+		j := pipejson.NewJournal(path, s)
+		s.Messages = []pipe.Message{text("user", "hi")}
+		require.NoError(t, j.Append(s))
+		require.NoError(t, pipejson.Save(path, s))
+
+		_, err := os.Stat(pipejson.JournalPath(path))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		// Appends after the save continue from it.
+		s.Messages = append(s.Messages, text("assistant", "hello"))
+		require.NoError(t, j.Append(s))
+		got, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, s.Messages, got.Messages)
+	})
+}
+
+func TestRecover(t *testing.T) {
+	t.Parallel()
+
+	t.Run("folds the journal into the session file", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		s := journalSession()
+		j := pipejson.NewJournal(path, s)
+		s.Messages = []pipe.Message{text("user", "hi"), text("assistant", "hello")}
+		require.NoError(t, j.Append(s))
+
+		got, err := pipejson.Recover(path)
+		require.NoError(t, err)
+		assert.Equal(t, s, got)
+		_, err = os.Stat(pipejson.JournalPath(path))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		saved, err := pipejson.Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, s, saved)
+	})
+
+	t.Run("without a journal", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "session.json")
+		s := journalSession()
+		require.NoError(t, pipejson.Save(path, s))
+		got, err := pipejson.Recover(path)
+		require.NoError(t, err)
+		assert.Equal(t, s.ID, got.ID)
+	})
+
+	t.Run("nothing to recover", func(t *testing.T) {
+		t.Parallel()
+		_, err := pipejson.Recover(filepath.Join(t.TempDir(), "session.json"))
+		assert.Error(t, err)
+	})
+}
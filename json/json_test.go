@@ -1,9 +1,11 @@
 package json_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -47,6 +49,7 @@ func TestMarshalSession_RoundTrip(t *testing.T) {
 				Content:    []pipe.ContentBlock{pipe.TextBlock{Text: "package auth\n..."}},
 				IsError:    false,
 				Timestamp:  ts3,
+				Usage:      pipe.Usage{InputTokens: 900, OutputTokens: 40},
 			},
 		},
 	}
@@ -94,6 +97,7 @@ func TestMarshalSession_RoundTrip(t *testing.T) {
 	assert.Equal(t, "package auth\n...", trm.Content[0].(pipe.TextBlock).Text)
 	assert.False(t, trm.IsError)
 	assert.True(t, ts3.Equal(trm.Timestamp))
+	assert.Equal(t, pipe.Usage{InputTokens: 900, OutputTokens: 40}, trm.Usage)
 }
 
 func TestMarshalSession_V1Envelope(t *testing.T) {
@@ -126,6 +130,101 @@ func TestMarshalSession_V1Envelope(t *testing.T) {
 	assert.True(t, ok, "expected system_prompt key in JSON")
 }
 
+func TestMarshalSessionCompact(t *testing.T) {
+	t.Parallel()
+
+	session := func(output string) pipe.Session {
+		return pipe.Session{
+			ID:        "compact",
+			CreatedAt: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2026, 3, 1, 9, 5, 0, 0, time.UTC),
+			Messages: []pipe.Message{
+				pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "run it"}}},
+				pipe.ToolResultMessage{ToolCallID: "c1", ToolName: "bash", Content: []pipe.ContentBlock{pipe.TextBlock{Text: output}}},
+			},
+		}
+	}
+	decode := func(t *testing.T, data []byte) map[string]json.RawMessage {
+		t.Helper()
+		var env map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(data, &env))
+		assert.JSONEq(t, "2", string(env["version"]))
+		assert.NotContains(t, string(data), "\n  ", "not indented")
+		return env
+	}
+
+	t.Run("small history stays readable", func(t *testing.T) {
+		t.Parallel()
+		s := session("ok")
+		data, err := pipejson.MarshalSessionCompact(s)
+		require.NoError(t, err)
+		env := decode(t, data)
+		assert.Contains(t, env, "messages")
+		assert.NotContains(t, env, "messages_gzip")
+
+		got, err := pipejson.UnmarshalSession(data)
+		require.NoError(t, err)
+		assert.Equal(t, s, got)
+	})
+
+	t.Run("large history is compressed", func(t *testing.T) {
+		t.Parallel()
+		s := session(strings.Repeat("PASS ok github.com/fwojciec/pipe\n", 10000))
+		data, err := pipejson.MarshalSessionCompact(s)
+		require.NoError(t, err)
+		env := decode(t, data)
+		assert.NotContains(t, env, "messages")
+		assert.Contains(t, env, "messages_gzip")
+		assert.Contains(t, env, "id", "the header stays readable")
+		v1, err := pipejson.MarshalSession(s)
+		require.NoError(t, err)
+		assert.Less(t, len(data)*10, len(v1))
+
+		got, err := pipejson.UnmarshalSession(data)
+		require.NoError(t, err)
+		assert.Equal(t, s, got)
+
+		inflated, err := pipejson.Inflate(data)
+		require.NoError(t, err)
+		var doc struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		require.NoError(t, json.Unmarshal(inflated, &doc))
+		assert.Len(t, doc.Messages, 2)
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "session.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"version": 1,
+		"id": "old",
+		"system_prompt": "",
+		"created_at": "2026-02-18T12:00:00Z",
+		"updated_at": "2026-02-18T12:00:00Z",
+		"messages": [
+			{"role": "user", "content": [{"type": "text", "text": "hi"}], "timestamp": "2026-02-18T12:00:00Z"},
+			{"role": "hologram", "payload": 1}
+		]
+	}`), 0o600))
+
+	require.NoError(t, pipejson.Migrate(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var env struct {
+		Version int `json:"version"`
+	}
+	require.NoError(t, json.Unmarshal(data, &env))
+	assert.Equal(t, 2, env.Version)
+	got, err := pipejson.Load(path, pipejson.WithPreserveUnknown())
+	require.NoError(t, err)
+	assert.Equal(t, "old", got.ID)
+	require.Len(t, got.Messages, 2)
+	assert.IsType(t, pipe.OpaqueMessage{}, got.Messages[1], "unknown messages are kept")
+}
+
 func TestMarshalSession_AllContentBlockTypes(t *testing.T) {
 	t.Parallel()
 	session := pipe.Session{
@@ -365,6 +464,46 @@ func TestUnmarshalSession_UnknownContentBlockType(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestUnmarshalSession_PreserveUnknown(t *testing.T) {
+	t.Parallel()
+	data := []byte(`{
+		"version": 1,
+		"id": "test",
+		"created_at": "2026-02-18T12:00:00Z",
+		"updated_at": "2026-02-18T12:00:00Z",
+		"messages": [
+			{"type": "user", "content": [{"type": "text", "text": "hi"}, {"type": "audio", "data": "AAAA", "seconds": 3}], "timestamp": "2026-02-18T12:00:00Z"},
+			{"type": "system_note", "content": "a string, not blocks", "level": 2}
+		]
+	}`)
+
+	got, err := pipejson.UnmarshalSession(data, pipejson.WithPreserveUnknown())
+	require.NoError(t, err)
+	require.Len(t, got.Messages, 2)
+	user := got.Messages[0].(pipe.UserMessage)
+	assert.Equal(t, pipe.TextBlock{Text: "hi"}, user.Content[0])
+	block, ok := user.Content[1].(pipe.OpaqueBlock)
+	require.True(t, ok)
+	assert.Equal(t, "audio", block.Type)
+	msg, ok := got.Messages[1].(pipe.OpaqueMessage)
+	require.True(t, ok)
+	assert.Equal(t, "system_note", msg.Type)
+	assert.NoError(t, pipe.ValidateMessage(msg))
+
+	out, err := pipejson.MarshalSession(got)
+	require.NoError(t, err)
+	var env struct {
+		Messages []json.RawMessage `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(out, &env))
+	assert.JSONEq(t, `{"type": "system_note", "content": "a string, not blocks", "level": 2}`, string(env.Messages[1]))
+	assert.Contains(t, string(env.Messages[0]), `"seconds": 3`)
+
+	again, err := pipejson.UnmarshalSession(out, pipejson.WithPreserveUnknown())
+	require.NoError(t, err)
+	assert.Equal(t, "audio", again.Messages[0].(pipe.UserMessage).Content[1].(pipe.OpaqueBlock).Type)
+}
+
 func TestUnmarshalSession_UnsupportedVersion(t *testing.T) {
 	t.Parallel()
 	data := []byte(`{
@@ -511,6 +650,94 @@ func TestMarshalSession_CacheUsageOmitsZeroFields(t *testing.T) {
 	assert.NotContains(t, usage, "cache_write_tokens")
 }
 
+func TestMarshalSession_TotalUsage(t *testing.T) {
+	t.Parallel()
+	session := pipe.Session{
+		ID: "usage",
+		Messages: []pipe.Message{
+			pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 10, OutputTokens: 5, CacheReadTokens: 100}},
+			pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 20, OutputTokens: 7}},
+		},
+	}
+
+	data, err := pipejson.MarshalSession(session)
+	require.NoError(t, err)
+	var raw map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.JSONEq(t, `{"input_tokens": 30, "output_tokens": 12, "cache_read_tokens": 100}`, string(raw["usage"]))
+
+	data, err = pipejson.MarshalSession(pipe.Session{ID: "empty"})
+	require.NoError(t, err)
+	var empty map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &empty))
+	assert.NotContains(t, empty, "usage", "sessions without requests have no total")
+}
+
+func TestMarshalSession_ArtifactsRoundTrip(t *testing.T) {
+	t.Parallel()
+	session := pipe.Session{
+		ID:        "with-artifacts",
+		CreatedAt: time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC),
+		Messages:  []pipe.Message{},
+		Artifacts: []string{"/home/u/.pipe/sessions/with-artifacts/artifacts/pipe-bash-1.log"},
+	}
+
+	data, err := pipejson.MarshalSession(session)
+	require.NoError(t, err)
+	got, err := pipejson.UnmarshalSession(data)
+	require.NoError(t, err)
+	assert.Equal(t, session.Artifacts, got.Artifacts)
+
+	// Sessions without artifacts omit the field.
+	data, err = pipejson.MarshalSession(pipe.Session{ID: "none"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "artifacts")
+}
+
+func TestMarshalSession_SchedulesRoundTrip(t *testing.T) {
+	t.Parallel()
+	at := time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC)
+	session := pipe.Session{
+		ID:        "with-schedules",
+		CreatedAt: at,
+		UpdatedAt: at,
+		Messages:  []pipe.Message{},
+		Schedules: []pipe.ScheduledRun{
+			{ID: "r1", Prompt: "check the flaky test", Dir: "/src/app", Every: time.Hour, Next: at.Add(time.Hour), CreatedAt: at},
+			{ID: "r2", Prompt: "investigate", Dir: "/src/app", AfterCommand: "gh run watch", Next: at, CreatedAt: at},
+		},
+	}
+
+	data, err := pipejson.MarshalSession(session)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"every": "1h0m0s"`)
+	got, err := pipejson.UnmarshalSession(data)
+	require.NoError(t, err)
+	assert.Equal(t, session.Schedules, got.Schedules)
+}
+
+func TestMarshalSession_StatusRoundTrip(t *testing.T) {
+	t.Parallel()
+	session := pipe.Session{
+		ID:       "with-status",
+		Messages: []pipe.Message{},
+		Status:   pipe.RunStatus{State: pipe.RunBlocked, Detail: "needs the staging password"},
+	}
+
+	data, err := pipejson.MarshalSession(session)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"state": "blocked"`)
+	got, err := pipejson.UnmarshalSession(data)
+	require.NoError(t, err)
+	assert.Equal(t, session.Status, got.Status)
+
+	// Sessions without a status omit the field.
+	data, err = pipejson.MarshalSession(pipe.Session{ID: "none"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "status")
+}
+
 func TestMarshalSession_ThinkingBlockSignatureRoundTrip(t *testing.T) {
 	t.Parallel()
 	session := pipe.Session{
@@ -765,3 +992,110 @@ func TestUnmarshalSession_BackwardCompatNoToolCallSignature(t *testing.T) {
 	assert.Equal(t, "tc_1", tc.ID)
 	assert.Nil(t, tc.Signature)
 }
+
+func TestMarshalSession_ServerToolBlocksRoundTrip(t *testing.T) {
+	t.Parallel()
+	session := pipe.Session{
+		ID:        "server-tools",
+		CreatedAt: time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC),
+		Messages: []pipe.Message{
+			pipe.AssistantMessage{
+				Content: []pipe.ContentBlock{
+					pipe.ServerToolCallBlock{ID: "srv_1", Name: "web_search", Arguments: json.RawMessage(`{"query":"go"}`)},
+					pipe.ServerToolResultBlock{ToolCallID: "srv_1", Name: "web_search", Content: json.RawMessage(`[{"url":"https://go.dev"}]`)},
+					pipe.ServerToolResultBlock{ToolCallID: "srv_2", Name: "web_search", Content: json.RawMessage(`{"type":"web_search_tool_result_error"}`), IsError: true},
+				},
+				StopReason: pipe.StopEndTurn,
+				Timestamp:  time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	data, err := pipejson.MarshalSession(session)
+	require.NoError(t, err)
+
+	got, err := pipejson.UnmarshalSession(data)
+	require.NoError(t, err)
+
+	am, ok := got.Messages[0].(pipe.AssistantMessage)
+	require.True(t, ok)
+	require.Len(t, am.Content, 3)
+	call, ok := am.Content[0].(pipe.ServerToolCallBlock)
+	require.True(t, ok)
+	assert.Equal(t, "srv_1", call.ID)
+	assert.Equal(t, "web_search", call.Name)
+	assert.JSONEq(t, `{"query":"go"}`, string(call.Arguments))
+	result, ok := am.Content[1].(pipe.ServerToolResultBlock)
+	require.True(t, ok)
+	assert.Equal(t, "srv_1", result.ToolCallID)
+	assert.Equal(t, "web_search", result.Name)
+	assert.JSONEq(t, `[{"url":"https://go.dev"}]`, string(result.Content))
+	assert.False(t, result.IsError)
+	errResult, ok := am.Content[2].(pipe.ServerToolResultBlock)
+	require.True(t, ok)
+	assert.True(t, errResult.IsError)
+}
+
+func TestMarshalSession_TextCitationsRoundTrip(t *testing.T) {
+	t.Parallel()
+	session := pipe.Session{
+		ID:        "cited-text",
+		CreatedAt: time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC),
+		Messages: []pipe.Message{
+			pipe.AssistantMessage{
+				Content: []pipe.ContentBlock{
+					pipe.TextBlock{Text: "Go is open source.", Citations: []pipe.Citation{
+						{URL: "https://go.dev", Title: "go.dev", CitedText: "Go is an open source language."},
+					}},
+					pipe.TextBlock{Text: "No sources."},
+				},
+				StopReason: pipe.StopEndTurn,
+				Timestamp:  time.Date(2026, 2, 18, 12, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	data, err := pipejson.MarshalSession(session)
+	require.NoError(t, err)
+	assert.Equal(t, 1, bytes.Count(data, []byte(`"citations"`)))
+
+	got, err := pipejson.UnmarshalSession(data)
+	require.NoError(t, err)
+	assert.Equal(t, session.Messages, got.Messages)
+}
+
+func TestMarshalTokenReport(t *testing.T) {
+	t.Parallel()
+	attrs := []pipe.TokenAttribution{
+		{MessageIndex: -1, Kind: "system", Preview: "You are helpful.", Tokens: 25},
+		{MessageIndex: 0, Role: pipe.RoleToolResult, Kind: "text", Label: "bash", Tokens: 75},
+	}
+
+	data, err := pipejson.MarshalTokenReport("sess-1", attrs)
+	require.NoError(t, err)
+
+	var report struct {
+		SessionID   string `json:"session_id"`
+		TotalTokens int    `json:"total_tokens"`
+		Top         []struct {
+			Kind  string  `json:"kind"`
+			Label string  `json:"label"`
+			Share float64 `json:"share"`
+		} `json:"top"`
+		Blocks []struct {
+			MessageIndex int    `json:"message_index"`
+			Role         string `json:"role"`
+		} `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, "sess-1", report.SessionID)
+	assert.Equal(t, 100, report.TotalTokens)
+	require.Len(t, report.Top, 2)
+	assert.Equal(t, "bash", report.Top[0].Label)
+	assert.InDelta(t, 0.75, report.Top[0].Share, 1e-9)
+	require.Len(t, report.Blocks, 2)
+	assert.Equal(t, -1, report.Blocks[0].MessageIndex)
+	assert.Equal(t, "tool_result", report.Blocks[1].Role)
+}
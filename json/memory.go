@@ -0,0 +1,93 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+var _ pipe.NoteStore = (*NoteStore)(nil)
+
+// note is the wire format of one line of the memory file.
+type note struct {
+	ID        string    `json:"id"`
+	Text      string    `json:"text"`
+	Model     string    `json:"model"`
+	Embedding []float32 `json:"embedding"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NoteStore is a pipe.NoteStore kept in a JSON lines file, one note per
+// line. Notes are only ever appended.
+type NoteStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewNoteStore returns a NoteStore backed by the file at path. The file and
+// its parent directories are created on the first Add.
+func NewNoteStore(path string) *NoteStore {
+	return &NoteStore{path: path}
+}
+
+// Add appends n to the memory file.
+func (s *NoteStore) Add(_ context.Context, n pipe.Note) error {
+	data, err := json.Marshal(note(n))
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("create directories: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("open memory: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write memory: %w", err)
+	}
+	return f.Close()
+}
+
+// Notes reads every note in the memory file. A missing file holds no notes.
+// A malformed final line, left by an interrupted write, is skipped.
+func (s *NoteStore) Notes(context.Context) ([]pipe.Note, error) {
+	s.mu.Lock()
+	data, err := os.ReadFile(s.path)
+	s.mu.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read memory: %w", err)
+	}
+	var notes []pipe.Note
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var dto note
+		if err := json.Unmarshal(sc.Bytes(), &dto); err != nil {
+			if truncated := !bytes.HasSuffix(data, []byte("\n")) && bytes.HasSuffix(data, sc.Bytes()); truncated {
+				break
+			}
+			return nil, fmt.Errorf("memory line %d: %w", line, err)
+		}
+		notes = append(notes, pipe.Note(dto))
+	}
+	return notes, nil
+}
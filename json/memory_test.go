@@ -0,0 +1,49 @@
+package json_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+		store := pipejson.NewNoteStore(filepath.Join(t.TempDir(), ".pipe", "memory.jsonl"))
+		first := pipe.Note{ID: "a", Text: "use tabs", Model: "hash", Embedding: []float32{1, 0.5}, CreatedAt: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)}
+		second := pipe.Note{ID: "b", Text: "release via make", Model: "hash", Embedding: []float32{0, 1}, CreatedAt: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}
+		require.NoError(t, store.Add(context.Background(), first))
+		require.NoError(t, store.Add(context.Background(), second))
+
+		notes, err := store.Notes(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []pipe.Note{first, second}, notes)
+	})
+
+	t.Run("missing file has no notes", func(t *testing.T) {
+		t.Parallel()
+		notes, err := pipejson.NewNoteStore(filepath.Join(t.TempDir(), "memory.jsonl")).Notes(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, notes)
+	})
+
+	t.Run("skips a truncated final line", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "memory.jsonl")
+		data := `{"id":"a","text":"use tabs","model":"hash","embedding":[1],"created_at":"2026-03-01T09:00:00Z"}` + "\n" + `{"id":"b","te`
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		notes, err := pipejson.NewNoteStore(path).Notes(context.Background())
+		require.NoError(t, err)
+		require.Len(t, notes, 1)
+		assert.Equal(t, "a", notes[0].ID)
+	})
+}
@@ -1,6 +1,7 @@
 package json
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -18,6 +19,45 @@ type messageDTO struct {
 	ToolCallID    *string        `json:"tool_call_id,omitempty"`
 	ToolName      *string        `json:"tool_name,omitempty"`
 	IsError       *bool          `json:"is_error,omitempty"`
+
+	// raw is an unknown-type message as read, written back unchanged by
+	// MarshalJSON.
+	raw json.RawMessage
+}
+
+// UnmarshalJSON records the raw encoding of unknown-type messages. Those keep
+// only their type when their fields do not decode, so an unfamiliar shape can
+// still be preserved.
+func (m *messageDTO) UnmarshalJSON(data []byte) error {
+	type plain messageDTO
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		var head struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(data, &head) != nil || knownMessageType(head.Type) {
+			return err
+		}
+		p = plain{Type: head.Type}
+	}
+	*m = messageDTO(p)
+	if !knownMessageType(m.Type) {
+		m.raw = append(json.RawMessage(nil), data...)
+	}
+	return nil
+}
+
+// MarshalJSON writes preserved messages back verbatim.
+func (m messageDTO) MarshalJSON() ([]byte, error) {
+	if m.raw != nil {
+		return m.raw, nil
+	}
+	type plain messageDTO
+	return json.Marshal(plain(m))
+}
+
+func knownMessageType(t string) bool {
+	return t == "user" || t == "assistant" || t == "tool_result"
 }
 
 func marshalMessage(msg pipe.Message) (messageDTO, error) {
@@ -51,21 +91,30 @@ func marshalMessage(msg pipe.Message) (messageDTO, error) {
 		if err != nil {
 			return messageDTO{}, err
 		}
-		return messageDTO{
+		dto := messageDTO{
 			Type:       "tool_result",
 			Content:    blocks,
 			Timestamp:  m.Timestamp,
 			ToolCallID: &m.ToolCallID,
 			ToolName:   &m.ToolName,
 			IsError:    &m.IsError,
-		}, nil
+		}
+		if m.Usage != (pipe.Usage{}) {
+			dto.Usage = &usageDTO{InputTokens: m.Usage.InputTokens, OutputTokens: m.Usage.OutputTokens, CacheReadTokens: m.Usage.CacheReadTokens, CacheWriteTokens: m.Usage.CacheWriteTokens}
+		}
+		return dto, nil
+	case pipe.OpaqueMessage:
+		return messageDTO{Type: m.Type, raw: m.Raw}, nil
 	default:
 		return messageDTO{}, fmt.Errorf("unknown message type: %T", msg)
 	}
 }
 
-func unmarshalMessage(dto messageDTO) (pipe.Message, error) {
-	blocks, err := unmarshalContentBlocks(dto.Content)
+func unmarshalMessage(dto messageDTO, preserveUnknown bool) (pipe.Message, error) {
+	if preserveUnknown && !knownMessageType(dto.Type) {
+		return pipe.OpaqueMessage{Type: dto.Type, Raw: dto.raw}, nil
+	}
+	blocks, err := unmarshalContentBlocks(dto.Content, preserveUnknown)
 	if err != nil {
 		return nil, err
 	}
@@ -107,12 +156,17 @@ func unmarshalMessage(dto messageDTO) (pipe.Message, error) {
 		if dto.IsError != nil {
 			isError = *dto.IsError
 		}
+		var usage pipe.Usage
+		if dto.Usage != nil {
+			usage = pipe.Usage{InputTokens: dto.Usage.InputTokens, OutputTokens: dto.Usage.OutputTokens, CacheReadTokens: dto.Usage.CacheReadTokens, CacheWriteTokens: dto.Usage.CacheWriteTokens}
+		}
 		return pipe.ToolResultMessage{
 			ToolCallID: toolCallID,
 			ToolName:   toolName,
 			Content:    blocks,
 			IsError:    isError,
 			Timestamp:  dto.Timestamp,
+			Usage:      usage,
 		}, nil
 	default:
 		return nil, fmt.Errorf("unknown message type: %q", dto.Type)
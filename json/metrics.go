@@ -0,0 +1,85 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// runRecord is the wire format of one line of the usage log.
+type runRecord struct {
+	Time      time.Time      `json:"time"`
+	SessionID string         `json:"session_id"`
+	Model     string         `json:"model,omitempty"`
+	Usage     usageDTO       `json:"usage"`
+	ToolCalls map[string]int `json:"tool_calls,omitempty"`
+}
+
+// AppendRunRecord appends r as one JSON line to the usage log at path,
+// creating the file and its parent directories as needed.
+func AppendRunRecord(path string, r pipe.RunRecord) error {
+	data, err := json.Marshal(runRecord{
+		Time:      r.Time,
+		SessionID: r.SessionID,
+		Model:     r.Model,
+		Usage:     usageDTO(r.Usage),
+		ToolCalls: r.ToolCalls,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create directories: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("open usage log: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write usage log: %w", err)
+	}
+	return f.Close()
+}
+
+// LoadRunRecords reads the usage log at path. A missing log holds no
+// records. A malformed final line, left by an interrupted write, is skipped.
+func LoadRunRecords(path string) ([]pipe.RunRecord, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read usage log: %w", err)
+	}
+	var records []pipe.RunRecord
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var dto runRecord
+		if err := json.Unmarshal(sc.Bytes(), &dto); err != nil {
+			if truncated := !bytes.HasSuffix(data, []byte("\n")) && bytes.HasSuffix(data, sc.Bytes()); truncated {
+				break
+			}
+			return nil, fmt.Errorf("usage log line %d: %w", line, err)
+		}
+		records = append(records, pipe.RunRecord{
+			Time:      dto.Time,
+			SessionID: dto.SessionID,
+			Model:     dto.Model,
+			Usage:     pipe.Usage(dto.Usage),
+			ToolCalls: dto.ToolCalls,
+		})
+	}
+	return records, nil
+}
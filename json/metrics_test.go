@@ -0,0 +1,65 @@
+package json_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunRecords_RoundTrip(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "metrics", "usage.jsonl")
+	first := pipe.RunRecord{
+		Time:      time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+		SessionID: "s1",
+		Model:     "claude-sonnet",
+		Usage:     pipe.Usage{InputTokens: 100, OutputTokens: 10, CacheReadTokens: 5},
+		ToolCalls: map[string]int{"bash": 2},
+	}
+	second := pipe.RunRecord{Time: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC), SessionID: "s2"}
+
+	require.NoError(t, pipejson.AppendRunRecord(path, first))
+	require.NoError(t, pipejson.AppendRunRecord(path, second))
+
+	records, err := pipejson.LoadRunRecords(path)
+	require.NoError(t, err)
+	assert.Equal(t, []pipe.RunRecord{first, second}, records)
+}
+
+func TestLoadRunRecords(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing log has no records", func(t *testing.T) {
+		t.Parallel()
+		records, err := pipejson.LoadRunRecords(filepath.Join(t.TempDir(), "usage.jsonl"))
+		require.NoError(t, err)
+		assert.Empty(t, records)
+	})
+
+	t.Run("skips a truncated final line", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "usage.jsonl")
+		data := `{"time":"2026-03-01T09:00:00Z","session_id":"s1","usage":{"input_tokens":1,"output_tokens":2}}` + "\n" + `{"time":"2026-03-0`
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+		records, err := pipejson.LoadRunRecords(path)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "s1", records[0].SessionID)
+	})
+
+	t.Run("rejects a malformed line", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "usage.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte("garbage\n{}\n"), 0o600))
+
+		_, err := pipejson.LoadRunRecords(path)
+		assert.ErrorContains(t, err, "line 1")
+	})
+}
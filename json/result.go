@@ -0,0 +1,49 @@
+package json
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/fwojciec/pipe"
+)
+
+// runResult is the wire format of a headless run's outcome.
+type runResult struct {
+	SessionID  string        `json:"session_id"`
+	Text       string        `json:"text"`
+	StopReason string        `json:"stop_reason,omitempty"`
+	ToolCalls  int           `json:"tool_calls"`
+	Usage      usageDTO      `json:"usage"`
+	Status     *runStatusDTO `json:"status,omitempty"`
+}
+
+// MarshalRunResult serializes the outcome of a run from the messages it
+// added to a session: the text and stop reason of the last assistant
+// message, the number of tool calls, the usage summed over every request,
+// and the status the model reported, unless it is the zero value.
+func MarshalRunResult(sessionID string, msgs []pipe.Message, status pipe.RunStatus) ([]byte, error) {
+	r := runResult{SessionID: sessionID}
+	usage := pipe.SumUsage(msgs)
+	for _, msg := range msgs {
+		am, ok := msg.(pipe.AssistantMessage)
+		if !ok {
+			continue
+		}
+		var text []string
+		for _, b := range am.Content {
+			switch b := b.(type) {
+			case pipe.TextBlock:
+				text = append(text, b.Text)
+			case pipe.ToolCallBlock:
+				r.ToolCalls++
+			}
+		}
+		r.Text = strings.Join(text, "")
+		r.StopReason = string(am.StopReason)
+	}
+	if status != (pipe.RunStatus{}) {
+		r.Status = &runStatusDTO{State: string(status.State), Detail: status.Detail}
+	}
+	r.Usage = usageDTO{InputTokens: usage.InputTokens, OutputTokens: usage.OutputTokens, CacheReadTokens: usage.CacheReadTokens, CacheWriteTokens: usage.CacheWriteTokens}
+	return json.Marshal(r)
+}
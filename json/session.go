@@ -1,8 +1,12 @@
 package json
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -12,16 +16,105 @@ import (
 
 // envelope is the v1 wire format for a persisted session.
 type envelope struct {
-	Version      int          `json:"version"`
-	ID           string       `json:"id"`
-	SystemPrompt string       `json:"system_prompt"`
-	CreatedAt    time.Time    `json:"created_at"`
-	UpdatedAt    time.Time    `json:"updated_at"`
-	Messages     []messageDTO `json:"messages"`
+	Version      int           `json:"version"`
+	ID           string        `json:"id"`
+	SystemPrompt string        `json:"system_prompt"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	Messages     []messageDTO  `json:"messages"`
+	Artifacts    []string      `json:"artifacts,omitempty"`
+	Schedules    []scheduleDTO `json:"schedules,omitempty"`
+	Status       *runStatusDTO `json:"status,omitempty"`
+	// Usage totals the messages' usage for readers of the file; it is
+	// derived, so UnmarshalSession ignores it.
+	Usage *usageDTO `json:"usage,omitempty"`
+}
+
+// envelopeV2 is the v2 wire format for a persisted session: the v1
+// envelope written without indentation, with the messages gzip-compressed
+// into MessagesGzip instead when their JSON is large. Its fields shadow
+// the v1 Messages so that both versions decode into it.
+type envelopeV2 struct {
+	envelope
+	Messages     []messageDTO `json:"messages,omitempty"`
+	MessagesGzip []byte       `json:"messages_gzip,omitempty"`
+}
+
+// compressMessagesAt is the size of the messages' JSON from which v2
+// compresses them. Below it the gain is not worth the unreadable file.
+const compressMessagesAt = 64 << 10
+
+// scheduleDTO is the wire format for a pipe.ScheduledRun.
+type scheduleDTO struct {
+	ID           string    `json:"id"`
+	Prompt       string    `json:"prompt"`
+	Dir          string    `json:"dir"`
+	AfterCommand string    `json:"after_command,omitempty"`
+	Every        string    `json:"every,omitempty"` // Go duration, e.g. "1h0m0s"
+	Next         time.Time `json:"next"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// runStatusDTO is the wire format for a pipe.RunStatus.
+type runStatusDTO struct {
+	State  string `json:"state"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// LoadOption configures UnmarshalSession and Load.
+type LoadOption func(*loadConfig)
+
+type loadConfig struct {
+	preserveUnknown bool
+}
+
+// WithPreserveUnknown makes UnmarshalSession keep messages and content blocks
+// of unknown types as pipe.OpaqueMessage and pipe.OpaqueBlock instead of
+// failing, so a session written by a newer version can still be loaded.
+// MarshalSession writes them back verbatim.
+func WithPreserveUnknown() LoadOption {
+	return func(c *loadConfig) { c.preserveUnknown = true }
 }
 
 // MarshalSession serializes a Session to JSON in v1 envelope format.
 func MarshalSession(s pipe.Session) ([]byte, error) {
+	env, err := newEnvelope(s)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// MarshalSessionCompact serializes a Session to JSON in v2 envelope format:
+// without indentation, and with the messages gzip-compressed when they are
+// large. UnmarshalSession reads both formats.
+func MarshalSessionCompact(s pipe.Session) ([]byte, error) {
+	env, err := newEnvelope(s)
+	if err != nil {
+		return nil, err
+	}
+	env.Version = 2
+	v2 := envelopeV2{envelope: env, Messages: env.Messages}
+	msgs, err := json.Marshal(env.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("marshal messages: %w", err)
+	}
+	if len(msgs) >= compressMessagesAt {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(msgs); err != nil {
+			return nil, fmt.Errorf("compress messages: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("compress messages: %w", err)
+		}
+		v2.Messages, v2.MessagesGzip = nil, buf.Bytes()
+	}
+	return json.Marshal(v2)
+}
+
+// newEnvelope returns the v1 envelope of s.
+func newEnvelope(s pipe.Session) (envelope, error) {
 	env := envelope{
 		Version:      1,
 		ID:           s.ID,
@@ -29,46 +122,143 @@ func MarshalSession(s pipe.Session) ([]byte, error) {
 		CreatedAt:    s.CreatedAt,
 		UpdatedAt:    s.UpdatedAt,
 		Messages:     make([]messageDTO, len(s.Messages)),
+		Artifacts:    s.Artifacts,
+	}
+	if u := s.TotalUsage(); u != (pipe.Usage{}) {
+		env.Usage = &usageDTO{InputTokens: u.InputTokens, OutputTokens: u.OutputTokens, CacheReadTokens: u.CacheReadTokens, CacheWriteTokens: u.CacheWriteTokens}
+	}
+	if s.Status != (pipe.RunStatus{}) {
+		env.Status = &runStatusDTO{State: string(s.Status.State), Detail: s.Status.Detail}
+	}
+	for _, r := range s.Schedules {
+		dto := scheduleDTO{ID: r.ID, Prompt: r.Prompt, Dir: r.Dir, AfterCommand: r.AfterCommand, Next: r.Next, CreatedAt: r.CreatedAt}
+		if r.Every > 0 {
+			dto.Every = r.Every.String()
+		}
+		env.Schedules = append(env.Schedules, dto)
 	}
 	for i, msg := range s.Messages {
 		dto, err := marshalMessage(msg)
 		if err != nil {
-			return nil, fmt.Errorf("message %d: %w", i, err)
+			return envelope{}, fmt.Errorf("message %d: %w", i, err)
 		}
 		env.Messages[i] = dto
 	}
-	return json.MarshalIndent(env, "", "  ")
+	return env, nil
 }
 
-// UnmarshalSession deserializes a Session from JSON in v1 envelope format.
-func UnmarshalSession(data []byte) (pipe.Session, error) {
-	var env envelope
-	if err := json.Unmarshal(data, &env); err != nil {
+// UnmarshalSession deserializes a Session from JSON in v1 or v2 envelope
+// format. Unknown message and content block types are an error unless
+// WithPreserveUnknown is given.
+func UnmarshalSession(data []byte, opts ...LoadOption) (pipe.Session, error) {
+	var cfg loadConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	var v2 envelopeV2
+	if err := json.Unmarshal(data, &v2); err != nil {
 		return pipe.Session{}, fmt.Errorf("unmarshal envelope: %w", err)
 	}
-	if env.Version != 1 {
-		return pipe.Session{}, fmt.Errorf("unsupported envelope version: %d", env.Version)
+	if v2.Version != 1 && v2.Version != 2 {
+		return pipe.Session{}, fmt.Errorf("unsupported envelope version: %d", v2.Version)
+	}
+	env := v2.envelope
+	env.Messages = v2.Messages
+	if len(v2.MessagesGzip) > 0 {
+		msgs, err := inflateMessages(v2.MessagesGzip)
+		if err != nil {
+			return pipe.Session{}, err
+		}
+		env.Messages = msgs
 	}
 	msgs := make([]pipe.Message, len(env.Messages))
 	for i, dto := range env.Messages {
-		msg, err := unmarshalMessage(dto)
+		msg, err := unmarshalMessage(dto, cfg.preserveUnknown)
 		if err != nil {
 			return pipe.Session{}, fmt.Errorf("message %d: %w", i, err)
 		}
 		msgs[i] = msg
 	}
+	var schedules []pipe.ScheduledRun
+	for _, dto := range env.Schedules {
+		r := pipe.ScheduledRun{ID: dto.ID, Prompt: dto.Prompt, Dir: dto.Dir, AfterCommand: dto.AfterCommand, Next: dto.Next, CreatedAt: dto.CreatedAt}
+		if dto.Every != "" {
+			every, err := time.ParseDuration(dto.Every)
+			if err != nil {
+				return pipe.Session{}, fmt.Errorf("schedule %s: %w", dto.ID, err)
+			}
+			r.Every = every
+		}
+		schedules = append(schedules, r)
+	}
+	var status pipe.RunStatus
+	if env.Status != nil {
+		status = pipe.RunStatus{State: pipe.RunState(env.Status.State), Detail: env.Status.Detail}
+	}
 	return pipe.Session{
 		ID:           env.ID,
 		SystemPrompt: env.SystemPrompt,
 		CreatedAt:    env.CreatedAt,
 		UpdatedAt:    env.UpdatedAt,
 		Messages:     msgs,
+		Artifacts:    env.Artifacts,
+		Schedules:    schedules,
+		Status:       status,
 	}, nil
 }
 
-// Save writes a Session to a JSON file, creating parent directories as needed.
+// inflateMessages decodes the compressed messages of a v2 envelope.
+func inflateMessages(data []byte) ([]messageDTO, error) {
+	raw, err := gunzip(data)
+	if err != nil {
+		return nil, fmt.Errorf("decompress messages: %w", err)
+	}
+	var msgs []messageDTO
+	if err := json.Unmarshal(raw, &msgs); err != nil {
+		return nil, fmt.Errorf("decompress messages: %w", err)
+	}
+	return msgs, nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// Inflate returns the JSON of a v2 session file with its compressed
+// messages inlined, for tools that read the raw JSON. Other data is
+// returned unchanged.
+func Inflate(data []byte) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return data, nil
+	}
+	raw, ok := doc["messages_gzip"]
+	if !ok {
+		return data, nil
+	}
+	var compressed []byte
+	if err := json.Unmarshal(raw, &compressed); err != nil {
+		return nil, fmt.Errorf("decompress messages: %w", err)
+	}
+	msgs, err := gunzip(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("decompress messages: %w", err)
+	}
+	delete(doc, "messages_gzip")
+	doc["messages"] = msgs
+	return json.Marshal(doc)
+}
+
+// Save writes a Session to a JSON file in v2 envelope format, creating
+// parent directories as needed. The file is replaced atomically, and once
+// it is on disk the session's journal, which it supersedes, is removed.
 func Save(path string, s pipe.Session) error {
-	data, err := MarshalSession(s)
+	data, err := MarshalSessionCompact(s)
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
@@ -76,21 +266,84 @@ func Save(path string, s pipe.Session) error {
 		return fmt.Errorf("create directories: %w", err)
 	}
 	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+	if err := writeSynced(tmp, data); err != nil {
+		os.Remove(tmp) // best-effort cleanup
 		return fmt.Errorf("write temp file: %w", err)
 	}
 	if err := os.Rename(tmp, path); err != nil {
 		os.Remove(tmp) // best-effort cleanup
 		return fmt.Errorf("rename temp file: %w", err)
 	}
+	if err := os.Remove(JournalPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove journal: %w", err)
+	}
 	return nil
 }
 
-// Load reads a Session from a JSON file.
-func Load(path string) (pipe.Session, error) {
+// writeSynced writes data to the file at path and syncs it to disk.
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads a Session from a JSON file, replaying the session's journal
+// over it if there is one. A session that was only journaled loads from the
+// journal alone, as does one whose file is unreadable when the journal
+// holds a snapshot of it.
+func Load(path string, opts ...LoadOption) (pipe.Session, error) {
+	var cfg loadConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	s, err := loadFile(path, opts)
+	journal, jerr := os.ReadFile(JournalPath(path))
+	if errors.Is(jerr, os.ErrNotExist) {
+		return s, err
+	}
+	if jerr != nil {
+		return pipe.Session{}, fmt.Errorf("read journal: %w", jerr)
+	}
+	if err != nil {
+		// Replay from nothing: only a journal starting with a snapshot
+		// can stand in for the file.
+		var replayed pipe.Session
+		if rerr := replayJournal(&replayed, journal, cfg); rerr != nil || replayed.ID == "" {
+			return pipe.Session{}, err
+		}
+		return replayed, nil
+	}
+	if err := replayJournal(&s, journal, cfg); err != nil {
+		return pipe.Session{}, err
+	}
+	return s, nil
+}
+
+func loadFile(path string, opts []LoadOption) (pipe.Session, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return pipe.Session{}, fmt.Errorf("read file: %w", err)
 	}
-	return UnmarshalSession(data)
+	return UnmarshalSession(data, opts...)
+}
+
+// Migrate rewrites the session file at path in the current format, v2,
+// folding in its journal. Messages and content blocks of types this
+// version does not know are kept.
+func Migrate(path string) error {
+	s, err := Load(path, WithPreserveUnknown())
+	if err != nil {
+		return err
+	}
+	return Save(path, s)
 }
@@ -0,0 +1,59 @@
+package json
+
+import (
+	"encoding/json"
+
+	"github.com/fwojciec/pipe"
+)
+
+// tokenReport is the wire format of a token attribution report.
+type tokenReport struct {
+	SessionID   string             `json:"session_id"`
+	TotalTokens int                `json:"total_tokens"`
+	Top         []tokenAttribution `json:"top"`
+	Blocks      []tokenAttribution `json:"blocks"`
+}
+
+type tokenAttribution struct {
+	MessageIndex int     `json:"message_index"`
+	BlockIndex   int     `json:"block_index"`
+	Role         string  `json:"role,omitempty"`
+	Kind         string  `json:"kind"`
+	Label        string  `json:"label,omitempty"`
+	Preview      string  `json:"preview,omitempty"`
+	Tokens       int     `json:"tokens"`
+	Share        float64 `json:"share"` // fraction of total tokens
+}
+
+// MarshalTokenReport serializes a session's token attribution as a JSON
+// report listing every block in session order plus the largest contributors.
+func MarshalTokenReport(sessionID string, attrs []pipe.TokenAttribution) ([]byte, error) {
+	total := 0
+	for _, a := range attrs {
+		total += a.Tokens
+	}
+	toDTO := func(in []pipe.TokenAttribution) []tokenAttribution {
+		out := make([]tokenAttribution, len(in))
+		for i, a := range in {
+			out[i] = tokenAttribution{
+				MessageIndex: a.MessageIndex,
+				BlockIndex:   a.BlockIndex,
+				Role:         string(a.Role),
+				Kind:         a.Kind,
+				Label:        a.Label,
+				Preview:      a.Preview,
+				Tokens:       a.Tokens,
+			}
+			if total > 0 {
+				out[i].Share = float64(a.Tokens) / float64(total)
+			}
+		}
+		return out
+	}
+	return json.MarshalIndent(tokenReport{
+		SessionID:   sessionID,
+		TotalTokens: total,
+		Top:         toDTO(pipe.TopTokens(attrs, pipe.TopTokenEntries)),
+		Blocks:      toDTO(attrs),
+	}, "", "  ")
+}
@@ -0,0 +1,141 @@
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// Compile-time interface check.
+var _ pipe.TranscriptSink = (*TranscriptWriter)(nil)
+
+// transcriptDTO is the wire format of one transcript line. Type is
+// "event", "request", "response", or "tool", and names the field set.
+type transcriptDTO struct {
+	Time     time.Time    `json:"time"`
+	Type     string       `json:"type"`
+	Event    *eventDTO    `json:"event,omitempty"`
+	Request  *requestDTO  `json:"request,omitempty"`
+	Response *messageDTO  `json:"response,omitempty"`
+	Tool     *toolExecDTO `json:"tool,omitempty"`
+}
+
+type requestDTO struct {
+	Offset       int          `json:"offset,omitempty"`
+	Model        string       `json:"model,omitempty"`
+	SystemPrompt string       `json:"system_prompt,omitempty"`
+	Messages     []messageDTO `json:"messages"`
+	Tools        []string     `json:"tools,omitempty"`
+	ServerTools  []string     `json:"server_tools,omitempty"`
+	MaxTokens    int          `json:"max_tokens,omitempty"`
+	Temperature  *float64     `json:"temperature,omitempty"`
+}
+
+type toolExecDTO struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	Content    []contentBlock  `json:"content"`
+	IsError    bool            `json:"is_error,omitempty"`
+	DurationMS int64           `json:"duration_ms"`
+	TimedOut   bool            `json:"timed_out,omitempty"`
+}
+
+// TranscriptWriter writes a run's transcript as JSON lines, one record per
+// line, for debugging and audit. Events are written in their streaming
+// form, requests with the messages they add, after the offset of those
+// the previous request had, and the names of their tools.
+type TranscriptWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewTranscriptWriter returns a TranscriptWriter that appends to w.
+func NewTranscriptWriter(w io.Writer) *TranscriptWriter {
+	return &TranscriptWriter{w: w}
+}
+
+// WriteRecord writes r as one line.
+func (tw *TranscriptWriter) WriteRecord(r pipe.TranscriptRecord) error {
+	dto, err := transcriptRecord(r)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(dto)
+	if err != nil {
+		return fmt.Errorf("marshal %s record: %w", dto.Type, err)
+	}
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	_, err = tw.w.Write(append(data, '\n'))
+	return err
+}
+
+func transcriptRecord(r pipe.TranscriptRecord) (transcriptDTO, error) {
+	dto := transcriptDTO{Time: r.Time}
+	switch {
+	case r.Event != nil:
+		event := streamEvent(r.Event)
+		dto.Type, dto.Event = "event", &event
+	case r.Request != nil:
+		req, err := marshalRequest(*r.Request)
+		if err != nil {
+			return transcriptDTO{}, err
+		}
+		req.Offset = r.Offset
+		dto.Type, dto.Request = "request", &req
+	case r.Response != nil:
+		msg, err := marshalMessage(*r.Response)
+		if err != nil {
+			return transcriptDTO{}, err
+		}
+		dto.Type, dto.Response = "response", &msg
+	case r.Tool != nil:
+		content, err := marshalContentBlocks(r.Tool.Result.Content)
+		if err != nil {
+			return transcriptDTO{}, err
+		}
+		dto.Type = "tool"
+		dto.Tool = &toolExecDTO{
+			ID:         r.Tool.Call.ID,
+			Name:       r.Tool.Call.Name,
+			Arguments:  r.Tool.Call.Arguments,
+			Content:    content,
+			IsError:    r.Tool.Result.IsError,
+			DurationMS: r.Tool.Duration.Milliseconds(),
+			TimedOut:   r.Tool.TimedOut,
+		}
+	default:
+		return transcriptDTO{}, errors.New("empty transcript record")
+	}
+	return dto, nil
+}
+
+func marshalRequest(req pipe.Request) (requestDTO, error) {
+	dto := requestDTO{
+		Model:        req.Model,
+		SystemPrompt: req.SystemPrompt,
+		Messages:     make([]messageDTO, 0, len(req.Messages)),
+		MaxTokens:    req.MaxTokens,
+		Temperature:  req.Temperature,
+	}
+	for _, msg := range req.Messages {
+		m, err := marshalMessage(msg)
+		if err != nil {
+			return requestDTO{}, err
+		}
+		dto.Messages = append(dto.Messages, m)
+	}
+	for _, t := range req.Tools {
+		dto.Tools = append(dto.Tools, t.Name)
+	}
+	for _, t := range req.ServerTools {
+		dto.ServerTools = append(dto.ServerTools, string(t))
+	}
+	return dto, nil
+}
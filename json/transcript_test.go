@@ -0,0 +1,58 @@
+package json_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	pipejson "github.com/fwojciec/pipe/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptWriter(t *testing.T) {
+	t.Parallel()
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	call := pipe.ToolCallBlock{ID: "c1", Name: "bash", Arguments: json.RawMessage(`{"command":"ls"}`)}
+	records := []pipe.TranscriptRecord{
+		{Time: at, Request: &pipe.Request{
+			Model:    "claude-test",
+			Messages: []pipe.Message{pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "list files"}}, Timestamp: at}},
+			Tools:    []pipe.Tool{{Name: "bash"}},
+		}},
+		{Time: at.Add(time.Second), Event: pipe.EventTextDelta{Delta: "Listing"}},
+		{Time: at.Add(2 * time.Second), Response: &pipe.AssistantMessage{Content: []pipe.ContentBlock{call}, StopReason: pipe.StopToolUse, Timestamp: at}},
+		{Time: at.Add(3 * time.Second), Tool: &pipe.ToolExecution{
+			Call:     call,
+			Result:   pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "a.go\n"}}},
+			Duration: 1500 * time.Millisecond,
+		}},
+		{Time: at.Add(4 * time.Second), Offset: 2, Request: &pipe.Request{
+			Messages: []pipe.Message{pipe.ToolResultMessage{ToolCallID: "c1", ToolName: "bash", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "a.go\n"}}, Timestamp: at}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	w := pipejson.NewTranscriptWriter(&buf)
+	for _, r := range records {
+		require.NoError(t, w.WriteRecord(r))
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 5)
+	assert.JSONEq(t, `{"time":"2026-03-01T09:00:00Z","type":"request","request":{"model":"claude-test","messages":[
+		{"type":"user","content":[{"type":"text","text":"list files"}],"timestamp":"2026-03-01T09:00:00Z"}],"tools":["bash"]}}`, lines[0])
+	assert.JSONEq(t, `{"time":"2026-03-01T09:00:01Z","type":"event","event":{"type":"text_delta","index":0,"delta":"Listing"}}`, lines[1])
+	assert.JSONEq(t, `{"time":"2026-03-01T09:00:02Z","type":"response","response":{"type":"assistant","content":[
+		{"type":"tool_call","id":"c1","name":"bash","arguments":{"command":"ls"}}],"timestamp":"2026-03-01T09:00:00Z",
+		"stop_reason":"tool_use","raw_stop_reason":"","usage":{"input_tokens":0,"output_tokens":0}}}`, lines[2])
+	assert.JSONEq(t, `{"time":"2026-03-01T09:00:03Z","type":"tool","tool":{"id":"c1","name":"bash","arguments":{"command":"ls"},
+		"content":[{"type":"text","text":"a.go\n"}],"duration_ms":1500}}`, lines[3])
+	assert.JSONEq(t, `{"time":"2026-03-01T09:00:04Z","type":"request","request":{"offset":2,"messages":[
+		{"type":"tool_result","content":[{"type":"text","text":"a.go\n"}],"timestamp":"2026-03-01T09:00:00Z","tool_call_id":"c1","tool_name":"bash","is_error":false}]}}`, lines[4])
+
+	assert.ErrorContains(t, w.WriteRecord(pipe.TranscriptRecord{Time: at}), "empty transcript record")
+}
@@ -0,0 +1,66 @@
+package pipe
+
+import "fmt"
+
+// WithMaxTurns ends the run with ErrMaxTurns instead of making more than n
+// provider requests, so a model stuck calling tools cannot run up an
+// unbounded bill. Zero means no limit.
+func WithMaxTurns(n int) RunOption {
+	return func(c *runConfig) {
+		c.maxTurns = n
+	}
+}
+
+// WithMaxCostTokens ends the run with ErrMaxCostTokens instead of making
+// another provider request once its responses have reported n tokens or
+// more, counting input, output, and cache tokens alike. A request may
+// overshoot n; the limit applies before the next one. Zero means no limit.
+func WithMaxCostTokens(n int) RunOption {
+	return func(c *runConfig) {
+		c.maxCostTokens = n
+	}
+}
+
+// runLimit tracks the run against its WithMaxTurns and WithMaxCostTokens
+// limits.
+type runLimit struct {
+	turns  int
+	tokens int
+}
+
+// record counts a response that reported u.
+func (l *runLimit) record(u Usage) {
+	l.turns++
+	l.spend(u)
+}
+
+// spend counts tokens used outside the run's own requests, e.g. by a
+// sub-agent.
+func (l *runLimit) spend(u Usage) {
+	l.tokens += u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheWriteTokens
+}
+
+// checkLimits returns the error that ends the run when another request
+// would exceed a limit, having appended an assistant message saying so,
+// which the model sees on the next run. It returns nil otherwise.
+func checkLimits(session *Session, cfg *runConfig) error {
+	var err error
+	var note string
+	switch {
+	case cfg.maxTurns > 0 && cfg.limit.turns >= cfg.maxTurns:
+		err = ErrMaxTurns
+		note = fmt.Sprintf("[Run stopped: it reached its limit of %d turns before finishing.]", cfg.maxTurns)
+	case cfg.maxCostTokens > 0 && cfg.limit.tokens >= cfg.maxCostTokens:
+		err = ErrMaxCostTokens
+		note = fmt.Sprintf("[Run stopped: it reached its limit of %d tokens, having used %d, before finishing.]", cfg.maxCostTokens, cfg.limit.tokens)
+	default:
+		return nil
+	}
+	cfg.appendMessage(session, AssistantMessage{
+		Content:    []ContentBlock{TextBlock{Text: note}},
+		StopReason: StopLimit,
+		Timestamp:  cfg.now(),
+	})
+	session.UpdatedAt = cfg.now()
+	return err
+}
@@ -0,0 +1,101 @@
+package pipe_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoop_Limits(t *testing.T) {
+	t.Parallel()
+
+	// loopingProvider calls a tool on every turn, reporting usage each time.
+	loopingProvider := func(calls *int, usage pipe.Usage) *mock.Provider {
+		return &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			*calls++
+			return completedStream(pipe.AssistantMessage{
+				Content:    []pipe.ContentBlock{pipe.ToolCallBlock{ID: "c", Name: "read", Arguments: json.RawMessage(`{}`)}},
+				StopReason: pipe.StopToolUse,
+				Usage:      usage,
+			}), nil
+		}}
+	}
+	executor := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+		return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}}}, nil
+	}}
+	tools := []pipe.Tool{{Name: "read"}}
+	lastMessage := func(s *pipe.Session) pipe.AssistantMessage {
+		t.Helper()
+		am, ok := s.Messages[len(s.Messages)-1].(pipe.AssistantMessage)
+		require.True(t, ok)
+		return am
+	}
+
+	t.Run("max turns", func(t *testing.T) {
+		t.Parallel()
+		var calls int
+		session := &pipe.Session{}
+		err := pipe.NewLoop(loopingProvider(&calls, pipe.Usage{}), executor).Run(context.Background(), session, tools, pipe.WithMaxTurns(3))
+		require.ErrorIs(t, err, pipe.ErrMaxTurns)
+		assert.Equal(t, 3, calls)
+
+		note := lastMessage(session)
+		assert.Equal(t, pipe.StopLimit, note.StopReason)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "[Run stopped: it reached its limit of 3 turns before finishing.]"}}, note.Content)
+	})
+
+	t.Run("max cost tokens", func(t *testing.T) {
+		t.Parallel()
+		var calls int
+		session := &pipe.Session{}
+		usage := pipe.Usage{InputTokens: 300, OutputTokens: 50, CacheReadTokens: 100}
+		err := pipe.NewLoop(loopingProvider(&calls, usage), executor).Run(context.Background(), session, tools, pipe.WithMaxCostTokens(1000))
+		require.ErrorIs(t, err, pipe.ErrMaxCostTokens)
+		assert.Equal(t, 3, calls, "the third response takes the run to 1350 tokens")
+
+		note := lastMessage(session)
+		assert.Equal(t, pipe.StopLimit, note.StopReason)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "[Run stopped: it reached its limit of 1000 tokens, having used 1350, before finishing.]"}}, note.Content)
+	})
+
+	t.Run("usage reported by tools counts", func(t *testing.T) {
+		t.Parallel()
+		var calls int
+		session := &pipe.Session{}
+		spent := pipe.Usage{InputTokens: 600}
+		subAgent := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+			return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "report"}}, Usage: spent}, nil
+		}}
+		var events []pipe.Usage
+		onEvent := func(e pipe.Event) {
+			if tr, ok := e.(pipe.EventToolResult); ok {
+				events = append(events, tr.Usage)
+			}
+		}
+		err := pipe.NewLoop(loopingProvider(&calls, pipe.Usage{}), subAgent).Run(context.Background(), session, tools,
+			pipe.WithMaxCostTokens(1000), pipe.WithEventHandler(onEvent))
+		require.ErrorIs(t, err, pipe.ErrMaxCostTokens)
+		assert.Equal(t, 2, calls, "the second tool result takes the run to 1200 tokens")
+		assert.Equal(t, pipe.Usage{InputTokens: 1200}, session.TotalUsage())
+		assert.Equal(t, spent, session.Messages[1].(pipe.ToolResultMessage).Usage)
+		assert.Equal(t, []pipe.Usage{spent, spent}, events)
+	})
+
+	t.Run("limits count only this run", func(t *testing.T) {
+		t.Parallel()
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Done."}}, StopReason: pipe.StopEndTurn}), nil
+		}}
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Earlier."}}, Usage: pipe.Usage{InputTokens: 5000}},
+		}}
+		err := pipe.NewLoop(provider, nil).Run(context.Background(), session, nil, pipe.WithMaxTurns(1), pipe.WithMaxCostTokens(100))
+		require.NoError(t, err)
+		assert.Equal(t, pipe.StopEndTurn, lastMessage(session).StopReason)
+	})
+}
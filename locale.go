@@ -0,0 +1,142 @@
+package pipe
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale formats numbers, dates, and times for display in exports, stats,
+// and the TUI. The zero value formats like en-US in the local time zone.
+type Locale struct {
+	Tag        string // BCP 47 tag, e.g. "de-DE"
+	Decimal    string // decimal separator; "." when empty
+	Group      string // thousands separator; "," when empty
+	DateLayout string // Go time layout for dates; "01/02/2006" when empty
+	TimeLayout string // Go time layout for times of day; "3:04 PM" when empty
+	Location   *time.Location
+}
+
+// LookupLocale returns the locale for a tag such as "de-DE", "de_DE.UTF-8",
+// or "de". A region the table does not know falls back to its language.
+func LookupLocale(tag string) (Locale, bool) {
+	tag, _, _ = strings.Cut(tag, ".") // drop a POSIX encoding suffix
+	tag, _, _ = strings.Cut(tag, "@") // and modifier
+	tag = strings.ReplaceAll(tag, "_", "-")
+	lang, region, _ := strings.Cut(tag, "-")
+	lang = strings.ToLower(lang)
+	region = strings.ToUpper(region)
+
+	const nbsp = "\u00a0" // no-break space; French uses the narrow one
+	var l Locale
+	switch lang {
+	case "en":
+		switch region {
+		case "GB", "IE", "AU", "NZ", "IN":
+			l = Locale{Tag: "en-" + region, DateLayout: "02/01/2006", TimeLayout: "15:04"}
+		default:
+			l = Locale{Tag: "en-US"}
+		}
+	case "de":
+		l = Locale{Tag: "de-DE", Decimal: ",", Group: ".", DateLayout: "02.01.2006", TimeLayout: "15:04"}
+	case "fr":
+		l = Locale{Tag: "fr-FR", Decimal: ",", Group: "\u202f", DateLayout: "02/01/2006", TimeLayout: "15:04"}
+	case "es":
+		l = Locale{Tag: "es-ES", Decimal: ",", Group: ".", DateLayout: "02/01/2006", TimeLayout: "15:04"}
+	case "it":
+		l = Locale{Tag: "it-IT", Decimal: ",", Group: ".", DateLayout: "02/01/2006", TimeLayout: "15:04"}
+	case "nl":
+		l = Locale{Tag: "nl-NL", Decimal: ",", Group: ".", DateLayout: "02-01-2006", TimeLayout: "15:04"}
+	case "pl":
+		l = Locale{Tag: "pl-PL", Decimal: ",", Group: nbsp, DateLayout: "02.01.2006", TimeLayout: "15:04"}
+	case "pt":
+		l = Locale{Tag: "pt-BR", Decimal: ",", Group: ".", DateLayout: "02/01/2006", TimeLayout: "15:04"}
+	case "sv":
+		l = Locale{Tag: "sv-SE", Decimal: ",", Group: nbsp, DateLayout: "2006-01-02", TimeLayout: "15:04"}
+	case "ja":
+		l = Locale{Tag: "ja-JP", DateLayout: "2006/01/02", TimeLayout: "15:04"}
+	case "zh":
+		l = Locale{Tag: "zh-CN", DateLayout: "2006/01/02", TimeLayout: "15:04"}
+	default:
+		return Locale{}, false
+	}
+	if region != "" && !strings.HasSuffix(l.Tag, "-"+region) {
+		l.Tag = lang + "-" + region
+	}
+	return l, true
+}
+
+// In returns a copy of l that formats times in loc.
+func (l Locale) In(loc *time.Location) Locale {
+	l.Location = loc
+	return l
+}
+
+// Int formats n with thousands separators, e.g. "12,345".
+func (l Locale) Int(n int) string {
+	s := strconv.Itoa(n)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteString(orDefault(l.Group, ","))
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Float formats f with prec decimal places and thousands separators, e.g.
+// "1,234.5".
+func (l Locale) Float(f float64, prec int) string {
+	s := strconv.FormatFloat(f, 'f', prec, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	n, err := strconv.Atoi(whole)
+	if err != nil {
+		return s // out of int range; leave unformatted
+	}
+	out := l.Int(n)
+	if n == 0 && strings.HasPrefix(whole, "-") {
+		out = "-" + out // keep the sign of e.g. -0.5
+	}
+	if frac != "" {
+		out += orDefault(l.Decimal, ".") + frac
+	}
+	return out
+}
+
+// Date formats the calendar date of t.
+func (l Locale) Date(t time.Time) string {
+	return t.In(l.Zone()).Format(orDefault(l.DateLayout, "01/02/2006"))
+}
+
+// Time formats the time of day of t.
+func (l Locale) Time(t time.Time) string {
+	return t.In(l.Zone()).Format(orDefault(l.TimeLayout, "3:04 PM"))
+}
+
+// DateTime formats t as a date followed by a time of day.
+func (l Locale) DateTime(t time.Time) string {
+	return l.Date(t) + " " + l.Time(t)
+}
+
+// Zone returns the time zone times are formatted in: Location, or the local
+// zone when it is nil.
+func (l Locale) Zone() *time.Location {
+	if l.Location == nil {
+		return time.Local
+	}
+	return l.Location
+}
+
+// orDefault returns s, or def when s is empty.
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
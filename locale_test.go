@@ -0,0 +1,69 @@
+package pipe_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupLocale(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tag     string
+		wantTag string
+		wantOK  bool
+	}{
+		{"de-DE", "de-DE", true},
+		{"de_AT.UTF-8", "de-AT", true},
+		{"en", "en-US", true},
+		{"en_GB.UTF-8@euro", "en-GB", true},
+		{"C", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			t.Parallel()
+			l, ok := pipe.LookupLocale(tt.tag)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantTag, l.Tag)
+		})
+	}
+}
+
+func TestLocale_Format(t *testing.T) {
+	t.Parallel()
+	ts := time.Date(2026, 3, 9, 14, 5, 0, 0, time.UTC)
+
+	t.Run("zero value formats like en-US", func(t *testing.T) {
+		t.Parallel()
+		l := pipe.Locale{}.In(time.UTC)
+		assert.Equal(t, "1,234,567", l.Int(1234567))
+		assert.Equal(t, "-1,000", l.Int(-1000))
+		assert.Equal(t, "999", l.Int(999))
+		assert.Equal(t, "1,234.5", l.Float(1234.5, 1))
+		assert.Equal(t, "-0.5", l.Float(-0.5, 1))
+		assert.Equal(t, "03/09/2026 2:05 PM", l.DateTime(ts))
+	})
+
+	t.Run("german", func(t *testing.T) {
+		t.Parallel()
+		l, ok := pipe.LookupLocale("de-DE")
+		require.True(t, ok)
+		l = l.In(time.UTC)
+		assert.Equal(t, "1.234.567", l.Int(1234567))
+		assert.Equal(t, "1.234,5", l.Float(1234.5, 1))
+		assert.Equal(t, "09.03.2026 14:05", l.DateTime(ts))
+	})
+
+	t.Run("time zone", func(t *testing.T) {
+		t.Parallel()
+		tokyo, err := time.LoadLocation("Asia/Tokyo")
+		require.NoError(t, err)
+		l, _ := pipe.LookupLocale("ja-JP")
+		assert.Equal(t, "2026/03/09 23:05", l.In(tokyo).DateTime(ts))
+	})
+}
@@ -2,7 +2,10 @@ package pipe
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"time"
 )
@@ -22,8 +25,66 @@ func NewLoop(provider Provider, executor ToolExecutor) *Loop {
 type RunOption func(*runConfig)
 
 type runConfig struct {
-	onEvent func(Event)
-	model   string
+	onEvent     func(Event)
+	onMessage   func(Message)
+	model       string
+	serverTools []ServerTool
+	maxTokens   int
+	temperature *float64
+	options     ProviderOptions
+	speech      SpeechSink
+	budget      int
+	retry       RetryPolicy
+	approve     func(context.Context, ToolCallBlock) (Decision, error)
+	// approveRequest is asked before each provider request; requestsAllowed
+	// is set once it answers DecisionAlwaysAllow.
+	approveRequest  func(context.Context, Request) (Decision, error)
+	requestsAllowed bool
+	// toolConcurrency is how many tool calls of one turn may run at once.
+	toolConcurrency int
+	toolTimeout     time.Duration
+	tee             EventSink
+	clock           Clock // nil uses SystemClock
+	transcriptSink  TranscriptSink
+	transcript      *transcriptLog // nil without a sink
+	runStatus       bool
+	// keepResults is how many turns tool results are sent in full.
+	keepResults int
+	// maxTurns and maxCostTokens bound the run; limit is its progress
+	// toward them.
+	maxTurns      int
+	maxCostTokens int
+	limit         runLimit
+	// loopThreshold is how many identical tool calls in a row count as a
+	// loop; repeats tracks them.
+	loopThreshold int
+	repeats       callRepeats
+
+	alwaysAllowed map[string]bool // tools approved for the rest of the run
+}
+
+// now returns the time on the run's clock.
+func (c *runConfig) now() time.Time {
+	return c.clock.Now()
+}
+
+// appendMessage appends msg to the session and reports it to the message
+// handler.
+func (c *runConfig) appendMessage(session *Session, msg Message) {
+	session.Messages = append(session.Messages, msg)
+	if c.onMessage != nil {
+		c.onMessage(msg)
+	}
+}
+
+// WithMessageHandler sets a callback that receives each message the run
+// appends to the session, in order. Compaction may summarize a run's own
+// messages away, so the session's history after the run cannot tell which
+// messages it added; the handler sees them all.
+func WithMessageHandler(h func(Message)) RunOption {
+	return func(c *runConfig) {
+		c.onMessage = h
+	}
 }
 
 // WithEventHandler sets a callback that receives each streaming event during
@@ -42,6 +103,114 @@ func WithModel(model string) RunOption {
 	}
 }
 
+// WithServerTools enables provider-hosted tools for provider requests during
+// this run. Calls to these tools are executed by the provider, not the loop.
+func WithServerTools(tools ...ServerTool) RunOption {
+	return func(c *runConfig) {
+		c.serverTools = tools
+	}
+}
+
+// WithMaxTokens caps the output tokens of each provider request during this
+// run. Zero means the provider default.
+func WithMaxTokens(n int) RunOption {
+	return func(c *runConfig) {
+		c.maxTokens = n
+	}
+}
+
+// WithTemperature sets the sampling temperature of provider requests during
+// this run. If not set, the provider default is used.
+func WithTemperature(t float64) RunOption {
+	return func(c *runConfig) {
+		c.temperature = &t
+	}
+}
+
+// WithProviderOptions sets the provider-specific generation options of
+// provider requests during this run, e.g. Gemini's thinking budget.
+func WithProviderOptions(o ProviderOptions) RunOption {
+	return func(c *runConfig) {
+		c.options = o
+	}
+}
+
+// WithSpeechSink passes assistant text to sink one sentence at a time as it
+// streams. If nil or not set, no sentences are produced.
+func WithSpeechSink(sink SpeechSink) RunOption {
+	return func(c *runConfig) {
+		c.speech = sink
+	}
+}
+
+// WithContextBudget compacts the session when the context of a request
+// approaches tokens: older messages are replaced by a summary written by
+// the provider, and an EventCompaction is emitted. Zero disables compaction.
+func WithContextBudget(tokens int) RunOption {
+	return func(c *runConfig) {
+		c.budget = tokens
+	}
+}
+
+// WithToolResultTurns sends the tool results of only the last turns turns
+// in full; older ones are replaced in each request by a line saying what
+// was left out (see [ElideToolResults]). The session keeps every result.
+// Zero, the default, sends every result in full.
+func WithToolResultTurns(turns int) RunOption {
+	return func(c *runConfig) {
+		c.keepResults = turns
+	}
+}
+
+// WithToolConcurrency runs up to n of the tool calls in one assistant
+// message at the same time. Results are still appended to the session in
+// call order, and approvals are still asked one call at a time. The
+// executor must be safe for concurrent use. If not set, or if n is 1 or
+// less, calls run one after another.
+func WithToolConcurrency(n int) RunOption {
+	return func(c *runConfig) {
+		c.toolConcurrency = n
+	}
+}
+
+// WithToolTimeout cancels a tool call that runs longer than d. The call's
+// context is cancelled and the loop moves on without waiting for an
+// executor that ignores it; the model receives an error result, and an
+// EventToolTimeout is emitted before it. Zero means no timeout.
+func WithToolTimeout(d time.Duration) RunOption {
+	return func(c *runConfig) {
+		c.toolTimeout = d
+	}
+}
+
+// WithRequestApprover asks approve before each provider request of the
+// run, e.g. to confirm one whose estimated cost is high (see
+// [EstimateRequestUsage]). A denied request ends the run with
+// ErrRequestDenied; DecisionAlwaysAllow sends it and the rest of the run's
+// requests without asking. Retries of a request are not asked about again.
+func WithRequestApprover(approve func(ctx context.Context, req Request) (Decision, error)) RunOption {
+	return func(c *runConfig) {
+		c.approveRequest = approve
+	}
+}
+
+// WithClock sets the clock the run stamps messages and the session with
+// and waits out retry delays on. If not set, [SystemClock] is used.
+func WithClock(c Clock) RunOption {
+	return func(cfg *runConfig) {
+		cfg.clock = c
+	}
+}
+
+// WithRetry retries provider requests that fail transiently, such as on
+// rate limits, overload, or dropped connections, as policy describes. An
+// EventRetry is emitted before each retry. If not set, failures end the run.
+func WithRetry(policy RetryPolicy) RunOption {
+	return func(c *runConfig) {
+		c.retry = policy
+	}
+}
+
 // Run executes the agent loop. It sends the session's messages to the provider,
 // streams the response, executes any tool calls, and repeats until the assistant
 // stops requesting tools. It appends all messages to session.Messages.
@@ -50,12 +219,47 @@ func (l *Loop) Run(ctx context.Context, session *Session, tools []Tool, opts ...
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+	if cfg.clock == nil {
+		cfg.clock = SystemClock()
+	}
+	if cfg.transcriptSink != nil {
+		cfg.transcript = &transcriptLog{sink: cfg.transcriptSink, clock: cfg.clock}
+		onEvent := cfg.onEvent
+		cfg.onEvent = func(e Event) {
+			cfg.transcript.write(TranscriptRecord{Event: e})
+			if onEvent != nil {
+				onEvent(e)
+			}
+		}
+	}
+	if cfg.tee != nil {
+		tee := startEventTee(cfg.tee)
+		defer tee.close(ctx)
+		onEvent := cfg.onEvent
+		cfg.onEvent = func(e Event) {
+			if onEvent != nil {
+				onEvent(e)
+			}
+			tee.send(e)
+		}
+	}
 	for {
+		if err := checkLimits(session, &cfg); err != nil {
+			return err
+		}
+		if cfg.budget > 0 {
+			if err := l.compact(ctx, session, &cfg); err != nil {
+				return err
+			}
+		}
 		cont, err := l.turn(ctx, session, tools, &cfg)
 		if err != nil {
 			return err
 		}
 		if !cont {
+			if cfg.runStatus {
+				reportRunStatus(session, &cfg)
+			}
 			return nil
 		}
 	}
@@ -68,49 +272,102 @@ func (l *Loop) turn(ctx context.Context, session *Session, tools []Tool, cfg *ru
 		return false, err
 	}
 
+	// Tools the session called that are no longer registered are sent as
+	// placeholders so the history stays valid.
+	unavailable := UnavailableTools(session.Messages, tools)
+	if len(unavailable) > 0 {
+		tools = append(slices.Clip(tools), unavailable...)
+	}
+
+	messages := ElideToolResults(session.Messages, cfg.keepResults)
+	systemPrompt := session.SystemPrompt
+	if cfg.runStatus {
+		systemPrompt = strings.TrimLeft(systemPrompt+"\n\n"+RunStatusPrompt, "\n")
+	}
 	req := Request{
-		Model:        cfg.model,
-		SystemPrompt: session.SystemPrompt,
-		Messages:     session.Messages,
-		Tools:        tools,
+		Model:           cfg.model,
+		SystemPrompt:    systemPrompt,
+		Messages:        messages,
+		Tools:           tools,
+		ServerTools:     cfg.serverTools,
+		MaxTokens:       cfg.maxTokens,
+		Temperature:     cfg.temperature,
+		ProviderOptions: cfg.options,
 	}
 
-	stream, err := l.provider.Stream(ctx, req)
-	if err != nil {
+	if err := approveRequest(ctx, cfg, req); err != nil {
 		return false, err
 	}
-	defer stream.Close()
 
-	// Drain the stream, forwarding events to handler if set.
-	var streamErr error
-	for {
-		evt, err := stream.Next()
-		if err == io.EOF {
-			break
-		}
+	var prefix string // partial text a resumed request continues from
+	var msg AssistantMessage
+	for retry := 1; ; retry++ {
+		canRetry := retry <= cfg.retry.MaxRetries
+		cfg.transcript.request(req)
+		stream, err := l.provider.Stream(ctx, req)
 		if err != nil {
-			streamErr = err
-			break
+			if canRetry && Retryable(err) {
+				if err := l.waitRetry(ctx, cfg, retry, err, prefix != ""); err != nil {
+					return false, err
+				}
+				continue
+			}
+			// Record classified failures so the session shows why the run ended.
+			var pe *ProviderError
+			if errors.As(err, &pe) {
+				cfg.appendMessage(session, AssistantMessage{
+					StopReason:    pe.Reason,
+					RawStopReason: pe.Raw,
+					Timestamp:     cfg.now(),
+				})
+				session.UpdatedAt = cfg.now()
+			}
+			return false, err
 		}
+
+		var streamErr, msgErr error
+		msg, streamErr, msgErr = drain(stream, cfg)
+		if msgErr != nil {
+			if streamErr != nil {
+				return false, streamErr
+			}
+			return false, msgErr
+		}
+		cfg.transcript.response(msg)
+		if streamErr != nil && canRetry && Retryable(streamErr) {
+			if cfg.retry.Resume {
+				if text, ok := resumable(msg); ok {
+					prefix += text
+				}
+			}
+			if prefix != "" {
+				req.Messages = append(slices.Clip(messages), AssistantMessage{
+					Content: []ContentBlock{TextBlock{Text: prefix}},
+				})
+			}
+			if err := l.waitRetry(ctx, cfg, retry, streamErr, prefix != ""); err != nil {
+				return false, err
+			}
+			continue
+		}
+		if prefix != "" {
+			msg = prependText(prefix, msg)
+		}
+		if streamErr != nil && msg.StopReason == StopAborted {
+			msg = dropToolCalls(msg)
+		}
+
+		cfg.appendMessage(session, msg)
+		session.UpdatedAt = cfg.now()
+		cfg.limit.record(msg.Usage)
 		if cfg.onEvent != nil {
-			cfg.onEvent(evt)
+			cfg.onEvent(EventTurnComplete{StopReason: msg.StopReason, Usage: msg.Usage})
 		}
-	}
 
-	// Get the assembled message (partial or complete).
-	msg, msgErr := stream.Message()
-	if msgErr != nil {
 		if streamErr != nil {
 			return false, streamErr
 		}
-		return false, msgErr
-	}
-
-	session.Messages = append(session.Messages, msg)
-	session.UpdatedAt = time.Now()
-
-	if streamErr != nil {
-		return false, streamErr
+		break
 	}
 
 	// Extract tool calls from the response.
@@ -125,53 +382,277 @@ func (l *Loop) turn(ctx context.Context, session *Session, tools []Tool, cfg *ru
 		return false, nil
 	}
 
-	// Execute each tool call and append results to the session.
-	for _, tc := range toolCalls {
-		result, execErr := l.executor.Execute(ctx, tc.Name, tc.Arguments)
-		if execErr != nil || result == nil {
-			msg := "tool returned no result"
-			if execErr != nil {
-				msg = execErr.Error()
-			}
-			result = &ToolResult{
-				Content: []ContentBlock{TextBlock{Text: msg}},
-				IsError: true,
+	// Execute the tool calls and append their results to the session in
+	// call order.
+	if cfg.toolConcurrency > 1 && len(toolCalls) > 1 {
+		l.executeConcurrently(ctx, session, cfg, toolCalls, unavailable)
+	} else {
+		for _, tc := range toolCalls {
+			recordToolResult(session, cfg, tc, l.executeTool(ctx, cfg, tc, unavailable))
+		}
+	}
+	session.UpdatedAt = cfg.now()
+
+	return true, nil
+}
+
+// approveRequest asks the run's request approver, if any, whether req may
+// be sent.
+func approveRequest(ctx context.Context, cfg *runConfig, req Request) error {
+	if cfg.approveRequest == nil || cfg.requestsAllowed {
+		return nil
+	}
+	decision, err := cfg.approveRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	switch decision {
+	case DecisionAllow:
+		return nil
+	case DecisionAlwaysAllow:
+		cfg.requestsAllowed = true
+		return nil
+	default:
+		return ErrRequestDenied
+	}
+}
+
+// toolOutcome is what came of one tool call.
+type toolOutcome struct {
+	result   *ToolResult
+	timedOut bool
+}
+
+// executeTool runs tc unless it is unavailable or denied.
+func (l *Loop) executeTool(ctx context.Context, cfg *runConfig, tc ToolCallBlock, unavailable []Tool) toolOutcome {
+	if blocked := blockTool(ctx, cfg, tc, unavailable); blocked != nil {
+		return toolOutcome{result: blocked}
+	}
+	return l.recordTool(ctx, cfg, tc)
+}
+
+// recordTool runs tc and records the execution in the transcript.
+func (l *Loop) recordTool(ctx context.Context, cfg *runConfig, tc ToolCallBlock) toolOutcome {
+	if cfg.transcript == nil {
+		return l.runTool(ctx, cfg, tc)
+	}
+	start := cfg.now()
+	outcome := l.runTool(ctx, cfg, tc)
+	cfg.transcript.write(TranscriptRecord{Tool: &ToolExecution{
+		Call:     tc,
+		Result:   *outcome.result,
+		Duration: cfg.now().Sub(start),
+		TimedOut: outcome.timedOut,
+	}})
+	return outcome
+}
+
+// blockTool returns the result to record instead of running tc when its
+// tool is unavailable, the call is one of a loop (see WithLoopDetection),
+// or it is denied, or nil to run it.
+func blockTool(ctx context.Context, cfg *runConfig, tc ToolCallBlock, unavailable []Tool) *ToolResult {
+	if slices.ContainsFunc(unavailable, func(t Tool) bool { return t.Name == tc.Name }) {
+		return unavailableToolResult(tc.Name)
+	}
+	if looping := checkLoop(cfg, tc); looping != nil {
+		return looping
+	}
+	return checkApproval(ctx, cfg, tc)
+}
+
+// runTool executes tc, turning an executor error, a missing result, or a
+// timeout into an error result.
+func (l *Loop) runTool(ctx context.Context, cfg *runConfig, tc ToolCallBlock) toolOutcome {
+	var result *ToolResult
+	var err error
+	if cfg.toolTimeout > 0 {
+		callCtx, cancel := context.WithTimeout(ctx, cfg.toolTimeout)
+		defer cancel()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			result, err = l.executor.Execute(callCtx, tc.Name, tc.Arguments)
+		}()
+		select {
+		case <-done:
+		case <-callCtx.Done():
+			if ctx.Err() == nil {
+				msg := fmt.Sprintf("tool %s timed out after %s and was cancelled", tc.Name, cfg.toolTimeout)
+				return toolOutcome{result: &ToolResult{Content: []ContentBlock{TextBlock{Text: msg}}, IsError: true}, timedOut: true}
 			}
+			// The run itself was cancelled; let the executor wind down.
+			<-done
+		}
+	} else {
+		result, err = l.executor.Execute(ctx, tc.Name, tc.Arguments)
+	}
+	if err != nil || result == nil {
+		msg := "tool returned no result"
+		if err != nil {
+			msg = err.Error()
 		}
+		result = &ToolResult{
+			Content: []ContentBlock{TextBlock{Text: msg}},
+			IsError: true,
+		}
+	}
+	return toolOutcome{result: result}
+}
 
-		trm := ToolResultMessage{
-			ToolCallID: tc.ID,
-			ToolName:   tc.Name,
-			Content:    result.Content,
-			IsError:    result.IsError,
-			Timestamp:  time.Now(),
+// executeConcurrently runs up to cfg.toolConcurrency of toolCalls at once.
+// Calls start in order, and each is approved before it starts, one at a
+// time, so prompts never overlap. Results are recorded in call order as
+// they become available.
+func (l *Loop) executeConcurrently(ctx context.Context, session *Session, cfg *runConfig, toolCalls []ToolCallBlock, unavailable []Tool) {
+	outcomes := make([]chan toolOutcome, len(toolCalls))
+	for i := range outcomes {
+		outcomes[i] = make(chan toolOutcome, 1)
+	}
+	slots := make(chan struct{}, cfg.toolConcurrency)
+	go func() {
+		for i, tc := range toolCalls {
+			slots <- struct{}{}
+			if blocked := blockTool(ctx, cfg, tc, unavailable); blocked != nil {
+				outcomes[i] <- toolOutcome{result: blocked}
+				<-slots
+				continue
+			}
+			go func() {
+				defer func() { <-slots }()
+				outcomes[i] <- l.recordTool(ctx, cfg, tc)
+			}()
 		}
-		session.Messages = append(session.Messages, trm)
+	}()
+	for i, tc := range toolCalls {
+		recordToolResult(session, cfg, tc, <-outcomes[i])
+	}
+}
 
-		if cfg.onEvent != nil {
-			// Only text content is surfaced in the event; other block
-			// types (e.g. ImageBlock) are silently dropped by design.
-			// If no non-empty text blocks exist, the event is skipped.
-			var sb strings.Builder
-			for _, b := range result.Content {
-				if tb, ok := b.(TextBlock); ok && tb.Text != "" {
-					if sb.Len() > 0 {
-						sb.WriteByte('\n')
-					}
-					sb.WriteString(tb.Text)
-				}
+// recordToolResult appends the result of tc to the session and emits it as
+// an EventToolResult, preceded by an EventToolTimeout if the call timed out.
+func recordToolResult(session *Session, cfg *runConfig, tc ToolCallBlock, outcome toolOutcome) {
+	result := outcome.result
+	trm := ToolResultMessage{
+		ToolCallID: tc.ID,
+		ToolName:   tc.Name,
+		Content:    result.Content,
+		IsError:    result.IsError,
+		Timestamp:  cfg.now(),
+		Usage:      result.Usage,
+	}
+	cfg.appendMessage(session, trm)
+	cfg.limit.spend(result.Usage)
+
+	if cfg.onEvent == nil {
+		return
+	}
+	if outcome.timedOut {
+		cfg.onEvent(EventToolTimeout{ID: tc.ID, ToolName: tc.Name, Timeout: cfg.toolTimeout})
+	}
+	// Text content is joined and images are passed through; other block
+	// types are dropped. If the result has neither, nor usage to report,
+	// the event is skipped.
+	var sb strings.Builder
+	var images []ImageBlock
+	for _, b := range result.Content {
+		switch b := b.(type) {
+		case TextBlock:
+			if b.Text == "" {
+				continue
 			}
 			if sb.Len() > 0 {
-				cfg.onEvent(EventToolResult{
-					ID:       tc.ID,
-					ToolName: tc.Name,
-					Content:  sb.String(),
-					IsError:  result.IsError,
-				})
+				sb.WriteByte('\n')
 			}
+			sb.WriteString(b.Text)
+		case ImageBlock:
+			images = append(images, b)
 		}
 	}
-	session.UpdatedAt = time.Now()
+	if sb.Len() > 0 || len(images) > 0 || result.Usage != (Usage{}) {
+		cfg.onEvent(EventToolResult{
+			ID:       tc.ID,
+			ToolName: tc.Name,
+			Content:  sb.String(),
+			Images:   images,
+			IsError:  result.IsError,
+			Usage:    result.Usage,
+		})
+	}
+}
 
-	return true, nil
+// drain reads stream to the end, forwarding events to the handler and
+// speech sink, and closes it. It returns the assembled message, the error
+// that ended the stream early, if any, and the error from Message.
+func drain(stream Stream, cfg *runConfig) (AssistantMessage, error, error) {
+	defer stream.Close()
+	var speech *SentenceSegmenter
+	if cfg.speech != nil {
+		speech = NewSentenceSegmenter(cfg.speech)
+	}
+	var streamErr error
+	for {
+		evt, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			streamErr = err
+			break
+		}
+		if cfg.onEvent != nil {
+			cfg.onEvent(evt)
+		}
+		if d, ok := evt.(EventTextDelta); ok && speech != nil {
+			speech.Write(d.Delta)
+		}
+	}
+	if speech != nil {
+		speech.Flush()
+	}
+	msg, err := stream.Message()
+	return msg, streamErr, err
+}
+
+// waitRetry emits an EventRetry for retry number attempt and waits out its
+// delay.
+func (l *Loop) waitRetry(ctx context.Context, cfg *runConfig, attempt int, err error, resumed bool) error {
+	delay := cfg.retry.delay(attempt)
+	if cfg.onEvent != nil {
+		cfg.onEvent(EventRetry{
+			Attempt:    attempt,
+			MaxRetries: cfg.retry.MaxRetries,
+			Delay:      delay,
+			Err:        err,
+			Resumed:    resumed,
+		})
+	}
+	return sleepContext(ctx, cfg.clock, delay)
+}
+
+// dropToolCalls removes the tool calls from an interrupted response. They
+// never ran, and providers reject a conversation with a tool call but no
+// result.
+func dropToolCalls(msg AssistantMessage) AssistantMessage {
+	msg.Content = slices.DeleteFunc(slices.Clone(msg.Content), func(b ContentBlock) bool {
+		_, ok := b.(ToolCallBlock)
+		return ok
+	})
+	return msg
+}
+
+// prependText puts the text a resumed request continued from in front of
+// msg's content.
+func prependText(prefix string, msg AssistantMessage) AssistantMessage {
+	content := make([]ContentBlock, 0, len(msg.Content)+1)
+	if len(msg.Content) > 0 {
+		if tb, ok := msg.Content[0].(TextBlock); ok {
+			tb.Text = prefix + tb.Text
+			content = append(content, tb)
+			msg.Content = append(content, msg.Content[1:]...)
+			return msg
+		}
+	}
+	content = append(content, TextBlock{Text: prefix})
+	msg.Content = append(content, msg.Content...)
+	return msg
 }
@@ -6,8 +6,11 @@ import (
 	"errors"
 	"io"
 	"slices"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/fwojciec/pipe"
 	"github.com/fwojciec/pipe/mock"
@@ -378,6 +381,39 @@ func TestLoop_Run(t *testing.T) {
 		assert.Empty(t, session.Messages)
 	})
 
+	t.Run("classified provider error is recorded in the session", func(t *testing.T) {
+		t.Parallel()
+
+		providerErr := &pipe.ProviderError{
+			Reason: pipe.StopRateLimited,
+			Raw:    "rate_limit_error",
+			Err:    errors.New("anthropic: rate_limit_error: slow down"),
+		}
+		provider := &mock.Provider{
+			StreamFn: func(_ context.Context, _ pipe.Request) (pipe.Stream, error) {
+				return nil, providerErr
+			},
+		}
+		executor := &mock.ToolExecutor{
+			ExecuteFn: func(_ context.Context, _ string, _ json.RawMessage) (*pipe.ToolResult, error) {
+				return nil, nil
+			},
+		}
+
+		session := &pipe.Session{}
+		loop := pipe.NewLoop(provider, executor)
+
+		err := loop.Run(context.Background(), session, nil)
+		assert.ErrorIs(t, err, providerErr)
+
+		require.Len(t, session.Messages, 1)
+		am, ok := session.Messages[0].(pipe.AssistantMessage)
+		require.True(t, ok)
+		assert.Empty(t, am.Content)
+		assert.Equal(t, pipe.StopRateLimited, am.StopReason)
+		assert.Equal(t, "rate_limit_error", am.RawStopReason)
+	})
+
 	t.Run("context cancellation", func(t *testing.T) {
 		t.Parallel()
 
@@ -402,6 +438,45 @@ func TestLoop_Run(t *testing.T) {
 		assert.ErrorIs(t, err, context.Canceled)
 	})
 
+	t.Run("cancellation mid-stream keeps the partial response", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		partial := pipe.AssistantMessage{
+			Content: []pipe.ContentBlock{
+				pipe.TextBlock{Text: "Let me check"},
+				pipe.ToolCallBlock{ID: "t1", Name: "bash", Arguments: json.RawMessage(`{"comm`)},
+			},
+			StopReason: pipe.StopAborted,
+		}
+		provider := &mock.Provider{
+			StreamFn: func(ctx context.Context, _ pipe.Request) (pipe.Stream, error) {
+				return &mock.Stream{
+					NextFn: func() (pipe.Event, error) {
+						cancel()
+						return nil, ctx.Err()
+					},
+					MessageFn: func() (pipe.AssistantMessage, error) { return partial, nil },
+				}, nil
+			},
+		}
+		executor := &mock.ToolExecutor{
+			ExecuteFn: func(_ context.Context, _ string, _ json.RawMessage) (*pipe.ToolResult, error) {
+				t.Fatal("an interrupted tool call must not run")
+				return nil, nil
+			},
+		}
+
+		session := &pipe.Session{}
+		err := pipe.NewLoop(provider, executor).Run(ctx, session, nil)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		require.Len(t, session.Messages, 1)
+		am := session.Messages[0].(pipe.AssistantMessage)
+		assert.Equal(t, pipe.StopAborted, am.StopReason)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "Let me check"}}, am.Content, "unrun tool calls are dropped")
+	})
+
 	t.Run("request includes system prompt and tools", func(t *testing.T) {
 		t.Parallel()
 
@@ -442,6 +517,53 @@ func TestLoop_Run(t *testing.T) {
 		require.Len(t, capturedReq.Messages, 1)
 	})
 
+	t.Run("unregistered tools from the session are sent as placeholders", func(t *testing.T) {
+		t.Parallel()
+
+		var requests []pipe.Request
+		provider := &mock.Provider{
+			StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+				requests = append(requests, req)
+				if len(requests) == 1 {
+					return completedStream(pipe.AssistantMessage{
+						Content:    []pipe.ContentBlock{pipe.ToolCallBlock{ID: "c2", Name: "mcp_search", Arguments: json.RawMessage(`{}`)}},
+						StopReason: pipe.StopToolUse,
+					}), nil
+				}
+				return completedStream(pipe.AssistantMessage{StopReason: pipe.StopEndTurn}), nil
+			},
+		}
+		executor := &mock.ToolExecutor{
+			ExecuteFn: func(_ context.Context, name string, _ json.RawMessage) (*pipe.ToolResult, error) {
+				t.Fatalf("executor called for unavailable tool %s", name)
+				return nil, nil
+			},
+		}
+		session := &pipe.Session{Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "search"}}},
+			pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.ToolCallBlock{ID: "c1", Name: "mcp_search"}}},
+			pipe.ToolResultMessage{ToolCallID: "c1", ToolName: "mcp_search"},
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "again"}}},
+		}}
+		tools := []pipe.Tool{{Name: "bash"}}
+		loop := pipe.NewLoop(provider, executor)
+
+		err := loop.Run(context.Background(), session, tools)
+		require.NoError(t, err)
+
+		require.Len(t, requests, 2)
+		require.Len(t, requests[0].Tools, 2)
+		assert.Equal(t, "mcp_search", requests[0].Tools[1].Name)
+		assert.Len(t, tools, 1, "caller's tools are not modified")
+
+		result, ok := session.Messages[5].(pipe.ToolResultMessage)
+		require.True(t, ok)
+		assert.True(t, result.IsError)
+		text, ok := result.Content[0].(pipe.TextBlock)
+		require.True(t, ok)
+		assert.Contains(t, text.Text, "tool mcp_search is unavailable")
+	})
+
 	t.Run("WithModel sets model in request", func(t *testing.T) {
 		t.Parallel()
 
@@ -471,6 +593,116 @@ func TestLoop_Run(t *testing.T) {
 		assert.Equal(t, "claude-sonnet-4-20250514", capturedReq.Model)
 	})
 
+	t.Run("WithServerTools sets server tools in request", func(t *testing.T) {
+		t.Parallel()
+
+		var capturedReq pipe.Request
+		provider := &mock.Provider{
+			StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+				capturedReq = req
+				msg := pipe.AssistantMessage{
+					Content: []pipe.ContentBlock{
+						pipe.ServerToolCallBlock{ID: "srv_1", Name: "web_search", Arguments: json.RawMessage(`{"query":"go"}`)},
+						pipe.ServerToolResultBlock{ToolCallID: "srv_1", Name: "web_search", Content: json.RawMessage(`[]`)},
+						pipe.TextBlock{Text: "found it"},
+					},
+					StopReason: pipe.StopEndTurn,
+				}
+				return completedStream(msg), nil
+			},
+		}
+		executor := &mock.ToolExecutor{
+			ExecuteFn: func(_ context.Context, _ string, _ json.RawMessage) (*pipe.ToolResult, error) {
+				t.Fatal("server tool calls must not reach the executor")
+				return nil, nil
+			},
+		}
+
+		session := &pipe.Session{}
+		loop := pipe.NewLoop(provider, executor)
+
+		err := loop.Run(context.Background(), session, nil, pipe.WithServerTools(pipe.ServerToolWebSearch))
+		require.NoError(t, err)
+
+		assert.Equal(t, []pipe.ServerTool{pipe.ServerToolWebSearch}, capturedReq.ServerTools)
+		require.Len(t, session.Messages, 1)
+	})
+
+	t.Run("WithMaxTokens sets max tokens in request", func(t *testing.T) {
+		t.Parallel()
+
+		var capturedReq pipe.Request
+		provider := &mock.Provider{
+			StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+				capturedReq = req
+				return completedStream(pipe.AssistantMessage{
+					Content:    []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}},
+					StopReason: pipe.StopEndTurn,
+				}), nil
+			},
+		}
+
+		loop := pipe.NewLoop(provider, &mock.ToolExecutor{})
+		err := loop.Run(context.Background(), &pipe.Session{}, nil, pipe.WithMaxTokens(1024))
+		require.NoError(t, err)
+
+		assert.Equal(t, 1024, capturedReq.MaxTokens)
+	})
+
+	t.Run("WithTemperature sets temperature in request", func(t *testing.T) {
+		t.Parallel()
+
+		var capturedReq pipe.Request
+		provider := &mock.Provider{
+			StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+				capturedReq = req
+				return completedStream(pipe.AssistantMessage{StopReason: pipe.StopEndTurn}), nil
+			},
+		}
+
+		loop := pipe.NewLoop(provider, &mock.ToolExecutor{})
+		err := loop.Run(context.Background(), &pipe.Session{}, nil, pipe.WithTemperature(0.2))
+		require.NoError(t, err)
+
+		require.NotNil(t, capturedReq.Temperature)
+		assert.InDelta(t, 0.2, *capturedReq.Temperature, 1e-9)
+	})
+
+	t.Run("WithSpeechSink speaks streamed text by sentence", func(t *testing.T) {
+		t.Parallel()
+
+		events := []pipe.Event{
+			pipe.EventThinkingDelta{Index: 0, Delta: "Plan. Quietly."},
+			pipe.EventTextDelta{Index: 1, Delta: "Done. All tests"},
+			pipe.EventTextDelta{Index: 1, Delta: " pass"},
+		}
+		provider := &mock.Provider{
+			StreamFn: func(_ context.Context, _ pipe.Request) (pipe.Stream, error) {
+				idx := 0
+				return &mock.Stream{
+					NextFn: func() (pipe.Event, error) {
+						if idx >= len(events) {
+							return nil, io.EOF
+						}
+						e := events[idx]
+						idx++
+						return e, nil
+					},
+					MessageFn: func() (pipe.AssistantMessage, error) {
+						return pipe.AssistantMessage{StopReason: pipe.StopEndTurn}, nil
+					},
+				}, nil
+			},
+		}
+
+		var spoken sentences
+		loop := pipe.NewLoop(provider, &mock.ToolExecutor{})
+		err := loop.Run(context.Background(), &pipe.Session{}, nil, pipe.WithSpeechSink(&spoken))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Done.", "All tests pass"}, []string(spoken))
+	})
+
 	t.Run("event handler receives stream events", func(t *testing.T) {
 		t.Parallel()
 
@@ -519,7 +751,7 @@ func TestLoop_Run(t *testing.T) {
 		err := loop.Run(context.Background(), session, nil, pipe.WithEventHandler(handler))
 		require.NoError(t, err)
 
-		assert.Equal(t, events, received)
+		assert.Equal(t, append(events, pipe.EventTurnComplete{StopReason: pipe.StopEndTurn}), received)
 	})
 
 	t.Run("nil event handler is safe without option", func(t *testing.T) {
@@ -804,7 +1036,7 @@ func TestLoop_Run(t *testing.T) {
 		assert.Equal(t, "read output", toolResults[1].Content)
 	})
 
-	t.Run("event handler skips EventToolResult when content has no text blocks", func(t *testing.T) {
+	t.Run("event handler passes images through EventToolResult", func(t *testing.T) {
 		t.Parallel()
 
 		toolCallMsg := pipe.AssistantMessage{
@@ -850,9 +1082,57 @@ func TestLoop_Run(t *testing.T) {
 		err := loop.Run(context.Background(), session, nil, pipe.WithEventHandler(handler))
 		require.NoError(t, err)
 
+		var results []pipe.EventToolResult
+		for _, e := range received {
+			if tr, ok := e.(pipe.EventToolResult); ok {
+				results = append(results, tr)
+			}
+		}
+		require.Len(t, results, 1)
+		assert.Empty(t, results[0].Content)
+		assert.Equal(t, []pipe.ImageBlock{{Data: []byte{0x89}, MimeType: "image/png"}}, results[0].Images)
+	})
+
+	t.Run("event handler skips EventToolResult when content is empty", func(t *testing.T) {
+		t.Parallel()
+
+		toolCallMsg := pipe.AssistantMessage{
+			Content: []pipe.ContentBlock{
+				pipe.ToolCallBlock{ID: "tc_1", Name: "bash", Arguments: json.RawMessage(`{}`)},
+			},
+			StopReason: pipe.StopToolUse,
+		}
+		textMsg := pipe.AssistantMessage{
+			Content:    []pipe.ContentBlock{pipe.TextBlock{Text: "done"}},
+			StopReason: pipe.StopEndTurn,
+		}
+
+		turn := 0
+		provider := &mock.Provider{
+			StreamFn: func(_ context.Context, _ pipe.Request) (pipe.Stream, error) {
+				turn++
+				if turn == 1 {
+					return completedStream(toolCallMsg), nil
+				}
+				return completedStream(textMsg), nil
+			},
+		}
+		executor := &mock.ToolExecutor{
+			ExecuteFn: func(_ context.Context, _ string, _ json.RawMessage) (*pipe.ToolResult, error) {
+				return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{}}}, nil
+			},
+		}
+
+		var received []pipe.Event
+		loop := pipe.NewLoop(provider, executor)
+		err := loop.Run(context.Background(), &pipe.Session{}, nil, pipe.WithEventHandler(func(e pipe.Event) {
+			received = append(received, e)
+		}))
+		require.NoError(t, err)
+
 		for _, e := range received {
 			if _, ok := e.(pipe.EventToolResult); ok {
-				t.Fatal("expected no EventToolResult for non-text content")
+				t.Fatal("expected no EventToolResult for empty content")
 			}
 		}
 	})
@@ -912,6 +1192,31 @@ func TestLoop_Run(t *testing.T) {
 		assert.True(t, toolResults[0].IsError)
 	})
 
+	t.Run("turn complete carries the request's usage", func(t *testing.T) {
+		t.Parallel()
+
+		usage := pipe.Usage{InputTokens: 40, OutputTokens: 7, CacheReadTokens: 900}
+		provider := &mock.Provider{
+			StreamFn: func(_ context.Context, _ pipe.Request) (pipe.Stream, error) {
+				return completedStream(pipe.AssistantMessage{
+					Content:    []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}},
+					StopReason: pipe.StopEndTurn,
+					Usage:      usage,
+				}), nil
+			},
+		}
+		var turns []pipe.EventTurnComplete
+		handler := func(e pipe.Event) {
+			if tc, ok := e.(pipe.EventTurnComplete); ok {
+				turns = append(turns, tc)
+			}
+		}
+
+		err := pipe.NewLoop(provider, &mock.ToolExecutor{}).Run(context.Background(), &pipe.Session{}, nil, pipe.WithEventHandler(handler))
+		require.NoError(t, err)
+		assert.Equal(t, []pipe.EventTurnComplete{{StopReason: pipe.StopEndTurn, Usage: usage}}, turns)
+	})
+
 	t.Run("event handler receives events across multi-turn run", func(t *testing.T) {
 		t.Parallel()
 
@@ -991,8 +1296,12 @@ func TestLoop_Run(t *testing.T) {
 
 		allExpected := slices.Concat(
 			turn1Events,
-			[]pipe.Event{pipe.EventToolResult{ID: "tc_1", ToolName: "bash", Content: "output", IsError: false}},
+			[]pipe.Event{
+				pipe.EventTurnComplete{StopReason: pipe.StopToolUse},
+				pipe.EventToolResult{ID: "tc_1", ToolName: "bash", Content: "output", IsError: false},
+			},
 			turn2Events,
+			[]pipe.Event{pipe.EventTurnComplete{StopReason: pipe.StopEndTurn}},
 		)
 		assert.Equal(t, allExpected, received)
 	})
@@ -1049,3 +1358,200 @@ func TestLoop_Run(t *testing.T) {
 		assert.Len(t, requests[1].Messages, 3)
 	})
 }
+
+func TestLoop_ToolConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var content []pipe.ContentBlock
+	for _, id := range []string{"c1", "c2", "c3"} {
+		content = append(content, pipe.ToolCallBlock{ID: id, Name: "read", Arguments: json.RawMessage(`"` + id + `"`)})
+	}
+	turns := 0
+	provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+		turns++
+		if turns == 1 {
+			return completedStream(pipe.AssistantMessage{Content: content, StopReason: pipe.StopToolUse}), nil
+		}
+		return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "done"}}, StopReason: pipe.StopEndTurn}), nil
+	}}
+
+	// The first two calls wait for each other, so they only finish if they
+	// run at the same time; c1 then finishes last.
+	var mu sync.Mutex
+	running, peak := 0, 0
+	both := make(chan struct{})
+	var bothOnce sync.Once
+	executor := &mock.ToolExecutor{ExecuteFn: func(_ context.Context, _ string, args json.RawMessage) (*pipe.ToolResult, error) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		if running == 2 {
+			bothOnce.Do(func() { close(both) })
+		}
+		mu.Unlock()
+		select {
+		case <-both:
+		case <-time.After(5 * time.Second):
+		}
+		if string(args) == `"c1"` {
+			time.Sleep(20 * time.Millisecond)
+		}
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: string(args)}}}, nil
+	}}
+
+	var events []string
+	session := &pipe.Session{}
+	err := pipe.NewLoop(provider, executor).Run(context.Background(), session, nil,
+		pipe.WithToolConcurrency(2),
+		pipe.WithEventHandler(func(e pipe.Event) {
+			if r, ok := e.(pipe.EventToolResult); ok {
+				events = append(events, r.ID)
+			}
+		}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, peak)
+	var ids []string
+	for _, m := range session.Messages {
+		if r, ok := m.(pipe.ToolResultMessage); ok {
+			ids = append(ids, r.ToolCallID)
+			assert.Equal(t, `"`+r.ToolCallID+`"`, r.Content[0].(pipe.TextBlock).Text)
+		}
+	}
+	assert.Equal(t, []string{"c1", "c2", "c3"}, ids)
+	assert.Equal(t, []string{"c1", "c2", "c3"}, events)
+}
+
+func TestLoop_ToolTimeout(t *testing.T) {
+	t.Parallel()
+
+	content := []pipe.ContentBlock{
+		pipe.ToolCallBlock{ID: "c1", Name: "bash", Arguments: json.RawMessage(`{}`)},
+		pipe.ToolCallBlock{ID: "c2", Name: "read", Arguments: json.RawMessage(`{}`)},
+	}
+	turns := 0
+	provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+		turns++
+		if turns == 1 {
+			return completedStream(pipe.AssistantMessage{Content: content, StopReason: pipe.StopToolUse}), nil
+		}
+		return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "done"}}, StopReason: pipe.StopEndTurn}), nil
+	}}
+	// bash hangs without watching its context; read answers at once.
+	hang := make(chan struct{})
+	t.Cleanup(func() { close(hang) })
+	executor := &mock.ToolExecutor{ExecuteFn: func(_ context.Context, name string, _ json.RawMessage) (*pipe.ToolResult, error) {
+		if name == "bash" {
+			<-hang
+		}
+		return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}}}, nil
+	}}
+
+	var events []pipe.Event
+	session := &pipe.Session{}
+	err := pipe.NewLoop(provider, executor).Run(context.Background(), session, nil,
+		pipe.WithToolTimeout(20*time.Millisecond),
+		pipe.WithEventHandler(func(e pipe.Event) {
+			switch e.(type) {
+			case pipe.EventToolTimeout, pipe.EventToolResult:
+				events = append(events, e)
+			}
+		}))
+	require.NoError(t, err)
+
+	var results []pipe.ToolResultMessage
+	for _, m := range session.Messages {
+		if r, ok := m.(pipe.ToolResultMessage); ok {
+			results = append(results, r)
+		}
+	}
+	require.Len(t, results, 2)
+	assert.True(t, results[0].IsError)
+	assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "tool bash timed out after 20ms and was cancelled"}}, results[0].Content)
+	assert.False(t, results[1].IsError)
+
+	require.Len(t, events, 3)
+	assert.Equal(t, pipe.EventToolTimeout{ID: "c1", ToolName: "bash", Timeout: 20 * time.Millisecond}, events[0])
+	assert.Equal(t, "c1", events[1].(pipe.EventToolResult).ID)
+	assert.Equal(t, "c2", events[2].(pipe.EventToolResult).ID)
+}
+
+func TestLoop_ToolResultTurns(t *testing.T) {
+	t.Parallel()
+
+	var requests []pipe.Request
+	provider := &mock.Provider{StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+		requests = append(requests, req)
+		if len(requests) < 3 {
+			call := pipe.ToolCallBlock{ID: "c" + strconv.Itoa(len(requests)), Name: "read", Arguments: json.RawMessage(`{}`)}
+			return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{call}, StopReason: pipe.StopToolUse}), nil
+		}
+		return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "done"}}, StopReason: pipe.StopEndTurn}), nil
+	}}
+	executor := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+		return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "contents"}}}, nil
+	}}
+
+	session := &pipe.Session{}
+	tools := []pipe.Tool{{Name: "read"}}
+	err := pipe.NewLoop(provider, executor).Run(context.Background(), session, tools, pipe.WithToolResultTurns(1))
+	require.NoError(t, err)
+
+	require.Len(t, requests, 3)
+	last := requests[2].Messages
+	require.Len(t, last, 4)
+	assert.Contains(t, last[1].(pipe.ToolResultMessage).Content[0].(pipe.TextBlock).Text, "read result omitted")
+	assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "contents"}}, last[3].(pipe.ToolResultMessage).Content)
+	// The session keeps every result in full.
+	assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "contents"}}, session.Messages[1].(pipe.ToolResultMessage).Content)
+}
+
+func TestLoop_WithClock(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	var waited []time.Duration
+	clock := &mock.Clock{
+		NowFn: func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		},
+		AfterFn: func(d time.Duration) <-chan time.Time {
+			waited = append(waited, d)
+			ch := make(chan time.Time, 1)
+			ch <- now
+			return ch
+		},
+	}
+	calls := 0
+	provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+		calls++
+		switch calls {
+		case 1:
+			return nil, &pipe.ProviderError{Reason: pipe.StopRateLimited, Err: errors.New("overloaded")}
+		case 2:
+			call := pipe.ToolCallBlock{ID: "c1", Name: "read", Arguments: json.RawMessage(`{}`)}
+			return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{call}, StopReason: pipe.StopToolUse}), nil
+		}
+		return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "done"}}, StopReason: pipe.StopEndTurn}), nil
+	}}
+	executor := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+		return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}}}, nil
+	}}
+
+	session := &pipe.Session{}
+	err := pipe.NewLoop(provider, executor).Run(context.Background(), session, []pipe.Tool{{Name: "read"}},
+		pipe.WithClock(clock), pipe.WithRetry(pipe.RetryPolicy{MaxRetries: 1, BaseDelay: time.Hour, MaxDelay: time.Hour}))
+	require.NoError(t, err)
+
+	// The retry delay was waited out on the clock.
+	require.Len(t, waited, 1)
+	assert.Positive(t, waited[0])
+	require.Len(t, session.Messages, 3)
+	result := session.Messages[1].(pipe.ToolResultMessage)
+	assert.Equal(t, time.Date(2026, 3, 1, 9, 0, 2, 0, time.UTC), result.Timestamp)
+	assert.Equal(t, now, session.UpdatedAt)
+}
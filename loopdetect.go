@@ -0,0 +1,67 @@
+package pipe
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// WithLoopDetection stops the model repeating itself: the n-th call in a
+// row to the same tool with the same arguments, and any further ones, are
+// not run, and the model gets an error result telling it that it is
+// looping instead. Arguments are compared as JSON values, so whitespace
+// and key order do not matter. Zero or less disables detection.
+func WithLoopDetection(n int) RunOption {
+	return func(c *runConfig) {
+		c.loopThreshold = n
+	}
+}
+
+// callRepeats counts the run's identical tool calls in a row.
+type callRepeats struct {
+	last  string // name and canonical arguments of the last call
+	count int
+}
+
+// observe records tc and returns how many calls in a row, including tc,
+// have been identical to it.
+func (r *callRepeats) observe(tc ToolCallBlock) int {
+	key := tc.Name + "\x00" + canonicalJSON(tc.Arguments)
+	if key == r.last {
+		r.count++
+	} else {
+		r.last, r.count = key, 1
+	}
+	return r.count
+}
+
+// canonicalJSON returns data re-encoded with sorted keys and no
+// insignificant whitespace, or as is when it is not valid JSON.
+func canonicalJSON(data json.RawMessage) string {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return string(data)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+// checkLoop returns the result to record instead of running tc when it
+// repeats the previous calls often enough to count as a loop, or nil.
+func checkLoop(cfg *runConfig, tc ToolCallBlock) *ToolResult {
+	if cfg.loopThreshold <= 0 {
+		return nil
+	}
+	n := cfg.repeats.observe(tc)
+	if n < cfg.loopThreshold {
+		return nil
+	}
+	msg := fmt.Sprintf("tool %s was not run: this is call %d in a row with the same arguments, and repeating it will not change the result. "+
+		"You appear to be looping. Use what the earlier calls returned, try a different approach, or explain what is blocking you.", tc.Name, n)
+	return &ToolResult{Content: []ContentBlock{TextBlock{Text: msg}}, IsError: true}
+}
@@ -0,0 +1,88 @@
+package pipe_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoop_WithLoopDetection(t *testing.T) {
+	t.Parallel()
+
+	// run has the model make the given calls, one per turn, then finish,
+	// and returns the calls the executor ran and the tool results.
+	run := func(t *testing.T, calls []pipe.ToolCallBlock, opts ...pipe.RunOption) ([]string, []pipe.ToolResultMessage) {
+		t.Helper()
+		turn := 0
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			if turn == len(calls) {
+				return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Done."}}, StopReason: pipe.StopEndTurn}), nil
+			}
+			tc := calls[turn]
+			turn++
+			return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{tc}, StopReason: pipe.StopToolUse}), nil
+		}}
+		var executed []string
+		executor := &mock.ToolExecutor{ExecuteFn: func(_ context.Context, name string, args json.RawMessage) (*pipe.ToolResult, error) {
+			executed = append(executed, name+" "+string(args))
+			return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "ok"}}}, nil
+		}}
+		session := &pipe.Session{}
+		tools := []pipe.Tool{{Name: "read"}, {Name: "bash"}}
+		err := pipe.NewLoop(provider, executor).Run(context.Background(), session, tools, opts...)
+		require.NoError(t, err)
+		var results []pipe.ToolResultMessage
+		for _, msg := range session.Messages {
+			if r, ok := msg.(pipe.ToolResultMessage); ok {
+				results = append(results, r)
+			}
+		}
+		return executed, results
+	}
+	call := func(name, args string) pipe.ToolCallBlock {
+		return pipe.ToolCallBlock{ID: "c", Name: name, Arguments: json.RawMessage(args)}
+	}
+
+	t.Run("the threshold call is not run", func(t *testing.T) {
+		t.Parallel()
+		read := call("read", `{"file_path": "a.go"}`)
+		executed, results := run(t, []pipe.ToolCallBlock{read, read, read, read}, pipe.WithLoopDetection(3))
+
+		assert.Equal(t, []string{`read {"file_path": "a.go"}`, `read {"file_path": "a.go"}`}, executed)
+		require.Len(t, results, 4)
+		assert.False(t, results[1].IsError)
+		for _, r := range results[2:] {
+			assert.True(t, r.IsError)
+			assert.Contains(t, r.Content[0].(pipe.TextBlock).Text, "You appear to be looping.")
+		}
+		assert.Contains(t, results[2].Content[0].(pipe.TextBlock).Text, "call 3 in a row")
+	})
+
+	t.Run("arguments are compared as JSON", func(t *testing.T) {
+		t.Parallel()
+		executed, _ := run(t, []pipe.ToolCallBlock{
+			call("bash", `{"command": "ls", "timeout": 5}`),
+			call("bash", `{"timeout":5,"command":"ls"}`),
+		}, pipe.WithLoopDetection(2))
+		assert.Len(t, executed, 1)
+	})
+
+	t.Run("a different call resets the count", func(t *testing.T) {
+		t.Parallel()
+		a, b := call("read", `{"file_path": "a.go"}`), call("read", `{"file_path": "b.go"}`)
+		executed, _ := run(t, []pipe.ToolCallBlock{a, a, b, a, a}, pipe.WithLoopDetection(3))
+		assert.Len(t, executed, 5)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+		read := call("read", `{}`)
+		executed, _ := run(t, []pipe.ToolCallBlock{read, read, read, read, read})
+		assert.Len(t, executed, 5)
+	})
+}
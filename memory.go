@@ -0,0 +1,113 @@
+package pipe
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Embedder turns texts into embedding vectors, one per text, for semantic
+// search over remembered notes.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Note is a snippet the model chose to remember across sessions.
+type Note struct {
+	ID   string
+	Text string
+	// Model identifies the embedder that produced Embedding. Embeddings
+	// from different models are not comparable.
+	Model     string
+	Embedding []float32
+	CreatedAt time.Time
+}
+
+// NoteStore persists remembered notes.
+type NoteStore interface {
+	Add(ctx context.Context, n Note) error
+	Notes(ctx context.Context) ([]Note, error)
+}
+
+// ScoredNote is a note with its similarity to a query.
+type ScoredNote struct {
+	Note
+	Score float64
+}
+
+// RankNotes returns the k notes most similar to query, best first. Notes
+// whose embedding has a different length than query are skipped.
+func RankNotes(notes []Note, query []float32, k int) []ScoredNote {
+	var scored []ScoredNote
+	for _, n := range notes {
+		if len(n.Embedding) != len(query) {
+			continue
+		}
+		scored = append(scored, ScoredNote{Note: n, Score: cosineSimilarity(n.Embedding, query)})
+	}
+	slices.SortStableFunc(scored, func(a, b ScoredNote) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+	if len(scored) > k {
+		scored = scored[:k]
+	}
+	return scored
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+var _ Embedder = HashEmbedder{}
+
+// HashEmbedder is a local Embedder that needs no API: it hashes each word
+// of a text into one of Dims buckets. It matches shared words rather than
+// meaning, which is enough to recall notes by the terms they mention.
+type HashEmbedder struct {
+	Dims int
+}
+
+// HashEmbedderModel is the Note.Model of notes embedded by HashEmbedder.
+const HashEmbedderModel = "hash"
+
+// Embed implements Embedder.
+func (e HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	dims := e.Dims
+	if dims <= 0 {
+		dims = 256
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, dims)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, w := range words {
+			h := fnv.New32a()
+			h.Write([]byte(w))
+			v[h.Sum32()%uint32(dims)]++
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
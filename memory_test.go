@@ -0,0 +1,46 @@
+package pipe_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankNotes(t *testing.T) {
+	t.Parallel()
+	notes := []pipe.Note{
+		{ID: "far", Embedding: []float32{0, 1}},
+		{ID: "near", Embedding: []float32{1, 0.1}},
+		{ID: "other-model", Embedding: []float32{1, 0, 0}},
+		{ID: "middle", Embedding: []float32{1, 1}},
+	}
+	ranked := pipe.RankNotes(notes, []float32{1, 0}, 2)
+	require.Len(t, ranked, 2)
+	assert.Equal(t, "near", ranked[0].ID)
+	assert.Equal(t, "middle", ranked[1].ID)
+	assert.Greater(t, ranked[0].Score, ranked[1].Score)
+}
+
+func TestHashEmbedder(t *testing.T) {
+	t.Parallel()
+	vectors, err := pipe.HashEmbedder{Dims: 64}.Embed(context.Background(), []string{
+		"query",
+		"The release script lives in scripts/release.sh",
+		"How do we cut a release?",
+		"Tabs, not spaces.",
+	})
+	require.NoError(t, err)
+	require.Len(t, vectors, 4)
+	assert.Len(t, vectors[0], 64)
+
+	notes := []pipe.Note{
+		{ID: "script", Embedding: vectors[1]},
+		{ID: "style", Embedding: vectors[3]},
+	}
+	ranked := pipe.RankNotes(notes, vectors[2], 1)
+	require.Len(t, ranked, 1)
+	assert.Equal(t, "script", ranked[0].ID)
+}
@@ -46,6 +46,9 @@ type ToolResultMessage struct {
 	Content    []ContentBlock
 	IsError    bool
 	Timestamp  time.Time
+	// Usage is the cost of provider requests the tool made, from
+	// ToolResult.Usage.
+	Usage Usage
 }
 
 func (ToolResultMessage) isMessage() {}
@@ -53,19 +56,42 @@ func (ToolResultMessage) isMessage() {}
 // Role returns RoleToolResult.
 func (ToolResultMessage) Role() Role { return RoleToolResult }
 
+// OpaqueMessage holds a persisted message of a type this version does not
+// understand, such as one written by a newer release. Raw is the message's
+// original encoding, written back unchanged when the session is saved.
+// Providers leave it out of requests.
+type OpaqueMessage struct {
+	Type string
+	Raw  json.RawMessage
+}
+
+func (OpaqueMessage) isMessage() {}
+
+// Role returns the message's wire type, which matches no known role.
+func (m OpaqueMessage) Role() Role { return Role(m.Type) }
+
 // ContentBlock is a sealed interface representing a block of content.
 // The unexported marker method prevents external implementations.
 type ContentBlock interface {
 	contentBlock()
 }
 
-// TextBlock contains text content.
+// TextBlock contains text content. Citations attribute the text to sources
+// when the provider grounds its answer (e.g. web search results).
 type TextBlock struct {
-	Text string
+	Text      string
+	Citations []Citation
 }
 
 func (TextBlock) contentBlock() {}
 
+// Citation attributes part of a [TextBlock] to a source.
+type Citation struct {
+	URL       string
+	Title     string
+	CitedText string // source excerpt supporting the text; may be empty
+}
+
 // ThinkingBlock contains thinking/reasoning content.
 type ThinkingBlock struct {
 	Thinking  string
@@ -92,16 +118,53 @@ type ToolCallBlock struct {
 
 func (ToolCallBlock) contentBlock() {}
 
+// ServerToolCallBlock represents a call to a provider-hosted tool (see
+// [ServerTool]). The provider executes it; the loop never dispatches it to a
+// [ToolExecutor]. It is kept in the assistant message so it can be replayed.
+type ServerToolCallBlock struct {
+	ID        string
+	Name      string
+	Arguments json.RawMessage
+}
+
+func (ServerToolCallBlock) contentBlock() {}
+
+// ServerToolResultBlock carries the provider's result for a
+// [ServerToolCallBlock]. Content is the provider-specific payload, preserved
+// verbatim so the conversation can be sent back to the same provider.
+type ServerToolResultBlock struct {
+	ToolCallID string
+	Name       string
+	Content    json.RawMessage
+	IsError    bool
+}
+
+func (ServerToolResultBlock) contentBlock() {}
+
+// OpaqueBlock holds a persisted content block of a type this version does
+// not understand. Like [OpaqueMessage], Raw is written back unchanged on save
+// and providers leave the block out of requests.
+type OpaqueBlock struct {
+	Type string
+	Raw  json.RawMessage
+}
+
+func (OpaqueBlock) contentBlock() {}
+
 // Interface compliance checks.
 var (
 	_ Message = UserMessage{}
 	_ Message = AssistantMessage{}
 	_ Message = ToolResultMessage{}
+	_ Message = OpaqueMessage{}
 
 	_ ContentBlock = TextBlock{}
 	_ ContentBlock = ThinkingBlock{}
 	_ ContentBlock = ImageBlock{}
 	_ ContentBlock = ToolCallBlock{}
+	_ ContentBlock = ServerToolCallBlock{}
+	_ ContentBlock = ServerToolResultBlock{}
+	_ ContentBlock = OpaqueBlock{}
 )
 
 // ValidateMessage checks that a message's content blocks are valid for its role.
@@ -110,9 +173,11 @@ func ValidateMessage(msg Message) error {
 	case UserMessage:
 		return validateBlocks(m.Content, m.Role(), allowText|allowImage)
 	case AssistantMessage:
-		return validateBlocks(m.Content, m.Role(), allowText|allowThinking|allowToolCall)
+		return validateBlocks(m.Content, m.Role(), allowText|allowThinking|allowToolCall|allowServerTool)
 	case ToolResultMessage:
 		return validateBlocks(m.Content, m.Role(), allowText|allowImage)
+	case OpaqueMessage:
+		return nil
 	default:
 		return fmt.Errorf("unknown message type %T: %w", msg, ErrValidation)
 	}
@@ -125,6 +190,7 @@ const (
 	allowThinking
 	allowImage
 	allowToolCall
+	allowServerTool
 )
 
 func validateBlocks(blocks []ContentBlock, role Role, allowed blockAllow) error {
@@ -146,6 +212,16 @@ func validateBlocks(blocks []ContentBlock, role Role, allowed blockAllow) error
 			if allowed&allowToolCall == 0 {
 				return fmt.Errorf("ToolCallBlock not allowed in %s message: %w", role, ErrValidation)
 			}
+		case ServerToolCallBlock:
+			if allowed&allowServerTool == 0 {
+				return fmt.Errorf("ServerToolCallBlock not allowed in %s message: %w", role, ErrValidation)
+			}
+		case ServerToolResultBlock:
+			if allowed&allowServerTool == 0 {
+				return fmt.Errorf("ServerToolResultBlock not allowed in %s message: %w", role, ErrValidation)
+			}
+		case OpaqueBlock:
+			// Preserved from a newer version; any role may carry one.
 		default:
 			return fmt.Errorf("unknown content block type %T in %s message: %w", b, role, ErrValidation)
 		}
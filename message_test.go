@@ -120,14 +120,18 @@ func TestContentBlockTypeSwitch_Exhaustive(t *testing.T) {
 		pipe.ThinkingBlock{Thinking: "reasoning"},
 		pipe.ImageBlock{Data: []byte{0x89}, MimeType: "image/png"},
 		pipe.ToolCallBlock{ID: "tc_1", Name: "read", Arguments: json.RawMessage(`{}`)},
+		pipe.ServerToolCallBlock{ID: "srv_1", Name: "web_search", Arguments: json.RawMessage(`{}`)},
+		pipe.ServerToolResultBlock{ToolCallID: "srv_1", Name: "web_search", Content: json.RawMessage(`[]`)},
 	}
-	assert.Len(t, blocks, 4, "update slice and switch when adding new ContentBlock types")
+	assert.Len(t, blocks, 6, "update slice and switch when adding new ContentBlock types")
 	for _, block := range blocks {
 		switch block.(type) {
 		case pipe.TextBlock:
 		case pipe.ThinkingBlock:
 		case pipe.ImageBlock:
 		case pipe.ToolCallBlock:
+		case pipe.ServerToolCallBlock:
+		case pipe.ServerToolResultBlock:
 		default:
 			t.Fatalf("unexpected content block type: %T", block)
 		}
@@ -199,6 +203,15 @@ func TestValidateMessage_AssistantMessage(t *testing.T) {
 		assert.NoError(t, pipe.ValidateMessage(msg))
 	})
 
+	t.Run("server tool blocks are valid", func(t *testing.T) {
+		t.Parallel()
+		msg := pipe.AssistantMessage{Content: []pipe.ContentBlock{
+			pipe.ServerToolCallBlock{ID: "srv_1", Name: "web_search", Arguments: json.RawMessage(`{}`)},
+			pipe.ServerToolResultBlock{ToolCallID: "srv_1", Name: "web_search", Content: json.RawMessage(`[]`)},
+		}}
+		assert.NoError(t, pipe.ValidateMessage(msg))
+	})
+
 	t.Run("image block is invalid", func(t *testing.T) {
 		t.Parallel()
 		msg := pipe.AssistantMessage{Content: []pipe.ContentBlock{
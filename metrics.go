@@ -0,0 +1,78 @@
+package pipe
+
+import (
+	"slices"
+	"time"
+)
+
+// RunRecord summarizes one agent run for local usage analytics. Records hold
+// counts only, never message content.
+type RunRecord struct {
+	Time      time.Time
+	SessionID string
+	Model     string // empty when the provider default was used
+	Usage     Usage
+	ToolCalls map[string]int // calls per tool name
+}
+
+// NewRunRecord builds a record for a run of session that appended msgs.
+func NewRunRecord(at time.Time, sessionID, model string, msgs []Message) RunRecord {
+	r := RunRecord{Time: at, SessionID: sessionID, Model: model, Usage: SumUsage(msgs), ToolCalls: make(map[string]int)}
+	for _, msg := range msgs {
+		am, ok := msg.(AssistantMessage)
+		if !ok {
+			continue
+		}
+		for _, b := range am.Content {
+			if tc, ok := b.(ToolCallBlock); ok {
+				r.ToolCalls[tc.Name]++
+			}
+		}
+	}
+	return r
+}
+
+// DailyUsage aggregates the run records of one calendar day.
+type DailyUsage struct {
+	Day       time.Time // midnight in the records' location
+	Sessions  int       // distinct sessions with at least one run
+	Runs      int
+	Usage     Usage
+	ToolCalls map[string]int // calls per tool name
+	Models    map[string]int // runs per model
+}
+
+// SummarizeUsage groups records at or after since by calendar day, oldest
+// first. Days without runs are omitted.
+func SummarizeUsage(records []RunRecord, since time.Time) []DailyUsage {
+	var days []DailyUsage
+	index := make(map[time.Time]int)
+	sessions := make(map[time.Time]map[string]bool)
+	for _, r := range records {
+		if r.Time.Before(since) {
+			continue
+		}
+		y, m, d := r.Time.Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, r.Time.Location())
+		i, ok := index[day]
+		if !ok {
+			i = len(days)
+			index[day] = i
+			sessions[day] = make(map[string]bool)
+			days = append(days, DailyUsage{Day: day, ToolCalls: make(map[string]int), Models: make(map[string]int)})
+		}
+		u := &days[i]
+		u.Runs++
+		u.Usage = u.Usage.Add(r.Usage)
+		for name, n := range r.ToolCalls {
+			u.ToolCalls[name] += n
+		}
+		u.Models[r.Model]++
+		if !sessions[day][r.SessionID] {
+			sessions[day][r.SessionID] = true
+			u.Sessions++
+		}
+	}
+	slices.SortFunc(days, func(a, b DailyUsage) int { return a.Day.Compare(b.Day) })
+	return days
+}
@@ -0,0 +1,60 @@
+package pipe_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRunRecord(t *testing.T) {
+	t.Parallel()
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	msgs := []pipe.Message{
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "fix it"}}},
+		pipe.AssistantMessage{
+			Content: []pipe.ContentBlock{
+				pipe.ToolCallBlock{ID: "1", Name: "bash"},
+				pipe.ToolCallBlock{ID: "2", Name: "bash"},
+				pipe.ToolCallBlock{ID: "3", Name: "read"},
+			},
+			Usage: pipe.Usage{InputTokens: 100, OutputTokens: 10},
+		},
+		pipe.ToolResultMessage{ToolCallID: "1", ToolName: "bash"},
+		pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 50, OutputTokens: 5, CacheReadTokens: 90}},
+	}
+
+	r := pipe.NewRunRecord(at, "s1", "claude-sonnet", msgs)
+	assert.Equal(t, at, r.Time)
+	assert.Equal(t, "s1", r.SessionID)
+	assert.Equal(t, "claude-sonnet", r.Model)
+	assert.Equal(t, pipe.Usage{InputTokens: 150, OutputTokens: 15, CacheReadTokens: 90}, r.Usage)
+	assert.Equal(t, map[string]int{"bash": 2, "read": 1}, r.ToolCalls)
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	t.Parallel()
+	day := func(d, h int) time.Time { return time.Date(2026, 3, d, h, 0, 0, 0, time.UTC) }
+	records := []pipe.RunRecord{
+		{Time: day(2, 9), SessionID: "b", Model: "m2", Usage: pipe.Usage{InputTokens: 5}},
+		{Time: day(1, 9), SessionID: "a", Model: "m1", Usage: pipe.Usage{InputTokens: 10}, ToolCalls: map[string]int{"bash": 1}},
+		{Time: day(1, 18), SessionID: "a", Model: "m1", Usage: pipe.Usage{OutputTokens: 3}, ToolCalls: map[string]int{"bash": 2}},
+		{Time: day(1, 20), SessionID: "c", Model: "m2"},
+		{Time: time.Date(2026, 2, 27, 9, 0, 0, 0, time.UTC), SessionID: "old"},
+	}
+
+	days := pipe.SummarizeUsage(records, day(1, 0))
+	require.Len(t, days, 2)
+
+	assert.Equal(t, day(1, 0), days[0].Day)
+	assert.Equal(t, 2, days[0].Sessions)
+	assert.Equal(t, 3, days[0].Runs)
+	assert.Equal(t, pipe.Usage{InputTokens: 10, OutputTokens: 3}, days[0].Usage)
+	assert.Equal(t, map[string]int{"bash": 3}, days[0].ToolCalls)
+	assert.Equal(t, map[string]int{"m1": 2, "m2": 1}, days[0].Models)
+
+	assert.Equal(t, day(2, 0), days[1].Day)
+	assert.Equal(t, 1, days[1].Runs)
+}
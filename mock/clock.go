@@ -0,0 +1,34 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// Interface compliance check.
+var _ pipe.Clock = (*Clock)(nil)
+
+// Clock is a test double for pipe.Clock.
+// Set NowFn before calling Now; AfterFn is optional.
+type Clock struct {
+	NowFn func() time.Time
+	// AfterFn defaults to a channel that fires at once, so retry delays
+	// take no time.
+	AfterFn func(d time.Duration) <-chan time.Time
+}
+
+// Now delegates to NowFn.
+func (c *Clock) Now() time.Time {
+	return c.NowFn()
+}
+
+// After delegates to AfterFn, or fires at once when it is not set.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	if c.AfterFn != nil {
+		return c.AfterFn(d)
+	}
+	ch := make(chan time.Time, 1)
+	ch <- c.NowFn().Add(d)
+	return ch
+}
@@ -0,0 +1,31 @@
+package mock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("delegates to NowFn", func(t *testing.T) {
+		t.Parallel()
+		c := &mock.Clock{NowFn: func() time.Time { return now }}
+		assert.Equal(t, now, c.Now())
+	})
+	t.Run("After fires at once by default", func(t *testing.T) {
+		t.Parallel()
+		c := &mock.Clock{NowFn: func() time.Time { return now }}
+		assert.Equal(t, now.Add(time.Minute), <-c.After(time.Minute))
+	})
+	t.Run("delegates to AfterFn", func(t *testing.T) {
+		t.Parallel()
+		never := make(chan time.Time)
+		c := &mock.Clock{AfterFn: func(time.Duration) <-chan time.Time { return never }}
+		assert.Equal(t, (<-chan time.Time)(never), c.After(time.Minute))
+	})
+}
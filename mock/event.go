@@ -0,0 +1,17 @@
+package mock
+
+import "github.com/fwojciec/pipe"
+
+// Interface compliance check.
+var _ pipe.EventSink = (*EventSink)(nil)
+
+// EventSink is a test double for pipe.EventSink.
+// Set WriteFn before calling Write.
+type EventSink struct {
+	WriteFn func(e pipe.Event) error
+}
+
+// Write delegates to WriteFn.
+func (s *EventSink) Write(e pipe.Event) error {
+	return s.WriteFn(e)
+}
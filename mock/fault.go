@@ -0,0 +1,126 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/fwojciec/pipe"
+)
+
+// Errors injected by FaultProvider.
+var (
+	// ErrRateLimited is wrapped in the *pipe.ProviderError returned for an
+	// injected rate limit.
+	ErrRateLimited = errors.New("mock: injected rate limit (429)")
+	// ErrConnectionDropped ends a stream cut short by an injected drop. The
+	// returned error also matches io.ErrUnexpectedEOF, as a real truncated
+	// response body would.
+	ErrConnectionDropped = errors.New("mock: injected connection drop")
+	// ErrMalformedEvent ends a stream that received an injected malformed
+	// event, as a provider does when it cannot decode the wire data.
+	ErrMalformedEvent = errors.New("mock: injected malformed event")
+)
+
+// Interface compliance check.
+var _ pipe.Provider = (*FaultProvider)(nil)
+
+// FaultProvider wraps a provider and injects faults at deterministic points
+// so resilience features such as retries, resumption, and error
+// classification can be exercised without a flaky network. Zero-valued
+// fields inject nothing.
+type FaultProvider struct {
+	Provider pipe.Provider
+
+	// RateLimitEvery fails every Kth Stream call (K, 2K, ...) with a
+	// *pipe.ProviderError of reason pipe.StopRateLimited, without calling
+	// Provider.
+	RateLimitEvery int
+	// DropAfter ends each stream with ErrConnectionDropped after it has
+	// delivered this many events.
+	DropAfter int
+	// MalformedAfter ends each stream with ErrMalformedEvent after it has
+	// delivered this many events. DropAfter wins when both trigger at once.
+	MalformedAfter int
+
+	mu    sync.Mutex
+	calls int
+}
+
+// Calls returns how many times Stream has been called.
+func (p *FaultProvider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// Stream calls the wrapped provider unless a rate limit is due, and wraps
+// the stream it returns to inject mid-stream faults.
+func (p *FaultProvider) Stream(ctx context.Context, req pipe.Request) (pipe.Stream, error) {
+	p.mu.Lock()
+	p.calls++
+	call := p.calls
+	p.mu.Unlock()
+
+	if p.RateLimitEvery > 0 && call%p.RateLimitEvery == 0 {
+		return nil, &pipe.ProviderError{Reason: pipe.StopRateLimited, Raw: "rate_limit_error", Err: ErrRateLimited}
+	}
+	s, err := p.Provider.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if p.DropAfter <= 0 && p.MalformedAfter <= 0 {
+		return s, nil
+	}
+	return &faultStream{Stream: s, dropAfter: p.DropAfter, malformedAfter: p.MalformedAfter}, nil
+}
+
+// faultStream ends a stream with an injected error after a number of events.
+type faultStream struct {
+	pipe.Stream
+	dropAfter      int
+	malformedAfter int
+
+	events int
+	err    error
+}
+
+func (s *faultStream) Next() (pipe.Event, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	switch {
+	case s.dropAfter > 0 && s.events == s.dropAfter:
+		s.err = fmt.Errorf("%w: %w", ErrConnectionDropped, io.ErrUnexpectedEOF)
+	case s.malformedAfter > 0 && s.events == s.malformedAfter:
+		s.err = ErrMalformedEvent
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	evt, err := s.Stream.Next()
+	if err == nil {
+		s.events++
+	}
+	return evt, err
+}
+
+func (s *faultStream) State() pipe.StreamState {
+	if s.err != nil {
+		return pipe.StreamStateError
+	}
+	return s.Stream.State()
+}
+
+// Message returns the partial message assembled before the fault, marked as
+// failed.
+func (s *faultStream) Message() (pipe.AssistantMessage, error) {
+	msg, err := s.Stream.Message()
+	if err != nil || s.err == nil {
+		return msg, err
+	}
+	msg.StopReason = pipe.StopError
+	return msg, nil
+}
@@ -0,0 +1,102 @@
+package mock_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// textProvider streams three text deltas per request.
+func textProvider() *mock.Provider {
+	return &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+		deltas := []string{"a", "b", "c"}
+		return &mock.Stream{
+			NextFn: func() (pipe.Event, error) {
+				if len(deltas) == 0 {
+					return nil, io.EOF
+				}
+				d := deltas[0]
+				deltas = deltas[1:]
+				return pipe.EventTextDelta{Delta: d}, nil
+			},
+			MessageFn: func() (pipe.AssistantMessage, error) {
+				return pipe.AssistantMessage{StopReason: pipe.StopEndTurn}, nil
+			},
+		}, nil
+	}}
+}
+
+// drain reads a stream to its end, returning the events and final error.
+func drain(s pipe.Stream) ([]pipe.Event, error) {
+	var events []pipe.Event
+	for {
+		evt, err := s.Next()
+		if err != nil {
+			return events, err
+		}
+		events = append(events, evt)
+	}
+}
+
+func TestFaultProvider(t *testing.T) {
+	t.Parallel()
+
+	t.Run("passes through without faults", func(t *testing.T) {
+		t.Parallel()
+		p := &mock.FaultProvider{Provider: textProvider()}
+		s, err := p.Stream(context.Background(), pipe.Request{})
+		require.NoError(t, err)
+		events, err := drain(s)
+		assert.Len(t, events, 3)
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("rate limits every kth request", func(t *testing.T) {
+		t.Parallel()
+		p := &mock.FaultProvider{Provider: textProvider(), RateLimitEvery: 2}
+		var failed []int
+		for call := 1; call <= 4; call++ {
+			if _, err := p.Stream(context.Background(), pipe.Request{}); err != nil {
+				var pe *pipe.ProviderError
+				require.True(t, errors.As(err, &pe))
+				assert.Equal(t, pipe.StopRateLimited, pe.Reason)
+				assert.ErrorIs(t, err, mock.ErrRateLimited)
+				failed = append(failed, call)
+			}
+		}
+		assert.Equal(t, []int{2, 4}, failed)
+		assert.Equal(t, 4, p.Calls())
+	})
+
+	t.Run("drops the connection after n events", func(t *testing.T) {
+		t.Parallel()
+		p := &mock.FaultProvider{Provider: textProvider(), DropAfter: 2}
+		s, err := p.Stream(context.Background(), pipe.Request{})
+		require.NoError(t, err)
+		events, err := drain(s)
+		assert.Len(t, events, 2)
+		assert.ErrorIs(t, err, mock.ErrConnectionDropped)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, pipe.StreamStateError, s.State())
+
+		msg, err := s.Message()
+		require.NoError(t, err)
+		assert.Equal(t, pipe.StopError, msg.StopReason)
+	})
+
+	t.Run("emits a malformed event after n events", func(t *testing.T) {
+		t.Parallel()
+		p := &mock.FaultProvider{Provider: textProvider(), MalformedAfter: 1}
+		s, err := p.Stream(context.Background(), pipe.Request{})
+		require.NoError(t, err)
+		events, err := drain(s)
+		assert.Len(t, events, 1)
+		assert.ErrorIs(t, err, mock.ErrMalformedEvent)
+	})
+}
@@ -1,2 +1,3 @@
-// Package mock provides test doubles for pipe interfaces using function fields.
+// Package mock provides test doubles for pipe interfaces using function fields,
+// and a provider decorator that injects faults for resilience testing.
 package mock
@@ -0,0 +1,21 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/fwojciec/pipe"
+)
+
+// Interface compliance check.
+var _ pipe.ModelLister = (*ModelLister)(nil)
+
+// ModelLister is a test double for pipe.ModelLister.
+// Set ListModelsFn before calling ListModels.
+type ModelLister struct {
+	ListModelsFn func(ctx context.Context) ([]string, error)
+}
+
+// ListModels delegates to ListModelsFn.
+func (l *ModelLister) ListModels(ctx context.Context) ([]string, error) {
+	return l.ListModelsFn(ctx)
+}
@@ -0,0 +1,22 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/fwojciec/pipe"
+)
+
+// Interface compliance check.
+var _ pipe.UsageReporter = (*UsageReporter)(nil)
+
+// UsageReporter is a test double for pipe.UsageReporter.
+// Set ReportedUsageFn before calling ReportedUsage.
+type UsageReporter struct {
+	ReportedUsageFn func(ctx context.Context, start, end time.Time) (pipe.Usage, error)
+}
+
+// ReportedUsage delegates to ReportedUsageFn.
+func (r *UsageReporter) ReportedUsage(ctx context.Context, start, end time.Time) (pipe.Usage, error) {
+	return r.ReportedUsageFn(ctx, start, end)
+}
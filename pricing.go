@@ -0,0 +1,54 @@
+package pipe
+
+import "strings"
+
+// Pricing is what a model charges per million tokens of each Usage
+// category, in US dollars.
+type Pricing struct {
+	Input      float64
+	Output     float64
+	CacheRead  float64
+	CacheWrite float64
+}
+
+// Cost estimates the price of u in US dollars.
+func (p Pricing) Cost(u Usage) float64 {
+	return (float64(u.InputTokens)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheReadTokens)*p.CacheRead +
+		float64(u.CacheWriteTokens)*p.CacheWrite) / 1e6
+}
+
+// listPrices returns published list prices keyed by model ID prefix.
+// Cache writes are priced at the 5-minute TTL rate; Gemini's long-context
+// tiers are not modeled.
+func listPrices() map[string]Pricing {
+	return map[string]Pricing{
+		"claude-opus-4-5":       {Input: 5, Output: 25, CacheRead: 0.50, CacheWrite: 6.25},
+		"claude-opus-4":         {Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+		"claude-sonnet-4":       {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+		"claude-3-7-sonnet":     {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+		"claude-haiku-4-5":      {Input: 1, Output: 5, CacheRead: 0.10, CacheWrite: 1.25},
+		"claude-3-5-haiku":      {Input: 0.80, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+		"gemini-3.1-pro":        {Input: 2, Output: 12, CacheRead: 0.20},
+		"gemini-3-pro":          {Input: 2, Output: 12, CacheRead: 0.20},
+		"gemini-2.5-pro":        {Input: 1.25, Output: 10, CacheRead: 0.125},
+		"gemini-2.5-flash":      {Input: 0.30, Output: 2.50, CacheRead: 0.03},
+		"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40, CacheRead: 0.01},
+	}
+}
+
+// LookupPricing returns the list price of a model ID such as
+// "claude-sonnet-4-20250514", matched by its longest known prefix.
+func LookupPricing(model string) (Pricing, bool) {
+	var (
+		best  Pricing
+		found string
+	)
+	for prefix, p := range listPrices() {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(found) {
+			best, found = p, prefix
+		}
+	}
+	return best, found != ""
+}
@@ -0,0 +1,40 @@
+package pipe_test
+
+import (
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPricing_Cost(t *testing.T) {
+	t.Parallel()
+	p := pipe.Pricing{Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75}
+	u := pipe.Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheReadTokens: 2_000_000, CacheWriteTokens: 400_000}
+	assert.InDelta(t, 3+1.5+0.6+1.5, p.Cost(u), 1e-9)
+}
+
+func TestLookupPricing(t *testing.T) {
+	t.Parallel()
+
+	p, ok := pipe.LookupPricing("claude-sonnet-4-20250514")
+	assert.True(t, ok)
+	assert.Equal(t, 3.0, p.Input)
+
+	p, ok = pipe.LookupPricing("claude-opus-4-5-20251101")
+	assert.True(t, ok)
+	assert.Equal(t, 5.0, p.Input, "the longest matching prefix wins")
+
+	p, ok = pipe.LookupPricing("claude-opus-4-1-20250805")
+	assert.True(t, ok)
+	assert.Equal(t, 15.0, p.Input)
+
+	p, ok = pipe.LookupPricing("gemini-3.1-pro-preview")
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, p.Input)
+
+	_, ok = pipe.LookupPricing("my-local-model")
+	assert.False(t, ok)
+	_, ok = pipe.LookupPricing("")
+	assert.False(t, ok)
+}
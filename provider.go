@@ -11,3 +11,9 @@ import "context"
 type Provider interface {
 	Stream(ctx context.Context, req Request) (Stream, error)
 }
+
+// ModelLister lists the IDs of the models a provider can serve, e.g. for
+// a model picker. Providers may implement it besides Provider.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
@@ -208,3 +208,22 @@ func TestRequest_Validate_MaxTokens(t *testing.T) {
 		assert.Contains(t, err.Error(), "max_tokens")
 	})
 }
+
+func TestProviderOptions_Validate(t *testing.T) {
+	t.Parallel()
+	budget, negative, topP, topK := 1024, -1, 0.95, 40
+	assert.NoError(t, pipe.ProviderOptions{}.Validate())
+	assert.NoError(t, pipe.ProviderOptions{ThinkingBudget: &budget, TopP: &topP, TopK: &topK}.Validate())
+
+	bigP, zeroK := 1.5, 0
+	for name, o := range map[string]pipe.ProviderOptions{
+		"thinking_budget": {ThinkingBudget: &negative},
+		"top_p":           {TopP: &bigP},
+		"top_k":           {TopK: &zeroK},
+		"safety_settings": {SafetySettings: []pipe.SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT"}}},
+	} {
+		err := pipe.Request{ProviderOptions: o}.Validate()
+		require.ErrorIs(t, err, pipe.ErrValidation, name)
+		assert.ErrorContains(t, err, name)
+	}
+}
@@ -0,0 +1,99 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ProviderFactory constructs a provider client from an API key. The context
+// bounds construction only; providers must not keep it.
+type ProviderFactory func(ctx context.Context, apiKey string) (Provider, error)
+
+// ProviderBackend is a provider that can be selected by name.
+type ProviderBackend struct {
+	Name string
+	// EnvKey is the environment variable holding the backend's API key,
+	// e.g. "ANTHROPIC_API_KEY". A set variable makes the backend a
+	// candidate for auto-detection.
+	EnvKey string
+	New    ProviderFactory
+	// DefaultModel is the model the backend's provider uses for requests
+	// that name none, e.g. to price them. Empty when unknown.
+	DefaultModel string
+}
+
+// ProviderRegistry holds the provider backends available by name, so
+// third-party providers can be added alongside the built-in ones. Backends
+// are kept in registration order. The zero value is an empty registry.
+type ProviderRegistry struct {
+	backends []ProviderBackend
+}
+
+// Register adds a backend. Names must be unique and non-empty.
+func (r *ProviderRegistry) Register(b ProviderBackend) error {
+	if b.Name == "" || b.New == nil {
+		return errors.New("provider backend needs a name and a factory")
+	}
+	if _, ok := r.Lookup(b.Name); ok {
+		return fmt.Errorf("provider %q already registered", b.Name)
+	}
+	r.backends = append(r.backends, b)
+	return nil
+}
+
+// Lookup returns the backend registered under name.
+func (r *ProviderRegistry) Lookup(name string) (ProviderBackend, bool) {
+	for _, b := range r.backends {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return ProviderBackend{}, false
+}
+
+// Backends returns the registered backends in registration order.
+func (r *ProviderRegistry) Backends() []ProviderBackend {
+	return append([]ProviderBackend(nil), r.backends...)
+}
+
+// Names returns the registered backend names in registration order.
+func (r *ProviderRegistry) Names() []string {
+	names := make([]string, len(r.backends))
+	for i, b := range r.backends {
+		names[i] = b.Name
+	}
+	return names
+}
+
+// registered holds the backends added with RegisterProvider. Like
+// database/sql's drivers, it is global so that packages can register from
+// init functions.
+var registered struct { //nolint:gochecknoglobals // registration hook
+	mu sync.Mutex
+	r  ProviderRegistry
+}
+
+// RegisterProvider makes b available to the pipe command alongside its
+// built-in providers. It is meant to be called from the init function of a
+// provider package that a build of the command imports, e.g.
+//
+//	import _ "example.com/pipe-ollama"
+//
+// It panics if b has no name or factory, or its name is already registered.
+func RegisterProvider(b ProviderBackend) {
+	registered.mu.Lock()
+	defer registered.mu.Unlock()
+	if err := registered.r.Register(b); err != nil {
+		panic("pipe: RegisterProvider: " + err.Error())
+	}
+}
+
+// RegisteredProviders returns the backends added with RegisterProvider, in
+// registration order.
+func RegisteredProviders() []ProviderBackend {
+	registered.mu.Lock()
+	defer registered.mu.Unlock()
+	return registered.r.Backends()
+}
@@ -0,0 +1,56 @@
+package pipe_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderRegistry(t *testing.T) {
+	t.Parallel()
+	factory := func(context.Context, string) (pipe.Provider, error) { return &mock.Provider{}, nil }
+
+	t.Run("looks up registered backends", func(t *testing.T) {
+		t.Parallel()
+		var r pipe.ProviderRegistry
+		require.NoError(t, r.Register(pipe.ProviderBackend{Name: "b", EnvKey: "B_KEY", New: factory}))
+		require.NoError(t, r.Register(pipe.ProviderBackend{Name: "a", New: factory}))
+
+		assert.Equal(t, []string{"b", "a"}, r.Names())
+		b, ok := r.Lookup("b")
+		require.True(t, ok)
+		assert.Equal(t, "B_KEY", b.EnvKey)
+		_, ok = r.Lookup("c")
+		assert.False(t, ok)
+		assert.Len(t, r.Backends(), 2)
+	})
+
+	t.Run("rejects duplicate and incomplete backends", func(t *testing.T) {
+		t.Parallel()
+		var r pipe.ProviderRegistry
+		require.NoError(t, r.Register(pipe.ProviderBackend{Name: "a", New: factory}))
+		require.EqualError(t, r.Register(pipe.ProviderBackend{Name: "a", New: factory}), `provider "a" already registered`)
+		require.Error(t, r.Register(pipe.ProviderBackend{Name: "b"}))
+		require.Error(t, r.Register(pipe.ProviderBackend{New: factory}))
+	})
+}
+
+func TestRegisterProvider(t *testing.T) {
+	t.Parallel()
+	factory := func(context.Context, string) (pipe.Provider, error) { return &mock.Provider{}, nil }
+
+	pipe.RegisterProvider(pipe.ProviderBackend{Name: "registry-test", EnvKey: "REGISTRY_TEST_KEY", New: factory})
+	names := make([]string, 0, 1)
+	for _, b := range pipe.RegisteredProviders() {
+		names = append(names, b.Name)
+	}
+	assert.Contains(t, names, "registry-test")
+
+	assert.PanicsWithValue(t, `pipe: RegisterProvider: provider "registry-test" already registered`, func() {
+		pipe.RegisterProvider(pipe.ProviderBackend{Name: "registry-test", New: factory})
+	})
+}
@@ -9,8 +9,29 @@ type Request struct {
 	SystemPrompt string
 	Messages     []Message
 	Tools        []Tool
-	MaxTokens    int      // 0 = provider default
-	Temperature  *float64 // nil = provider default
+	ServerTools  []ServerTool // provider-hosted tools; unsupported values are ignored
+	MaxTokens    int          // 0 = provider default
+	Temperature  *float64     // nil = provider default
+	// ProviderOptions holds generation settings not every provider
+	// supports; providers ignore the ones they don't.
+	ProviderOptions ProviderOptions
+}
+
+// ProviderOptions are generation settings specific to some providers. Zero
+// values leave the provider default.
+type ProviderOptions struct {
+	ThinkingBudget *int            // thinking tokens per response; 0 turns thinking off where the model allows
+	TopP           *float64        // nucleus sampling probability in [0, 1]
+	TopK           *int            // sample from the K most likely tokens
+	SafetySettings []SafetySetting // content filter thresholds by harm category
+}
+
+// SafetySetting sets the threshold at which a provider blocks content of
+// one harm category. Both values are the provider's own names, e.g.
+// "HARM_CATEGORY_HARASSMENT" and "BLOCK_ONLY_HIGH" for Gemini.
+type SafetySetting struct {
+	Category  string
+	Threshold string
 }
 
 // Validate checks universal constraints on Request.
@@ -24,5 +45,24 @@ func (r Request) Validate() error {
 	if r.MaxTokens < 0 {
 		return fmt.Errorf("max_tokens must be non-negative, got %d: %w", r.MaxTokens, ErrValidation)
 	}
+	return r.ProviderOptions.Validate()
+}
+
+// Validate checks the universal constraints on provider options.
+func (o ProviderOptions) Validate() error {
+	if o.ThinkingBudget != nil && *o.ThinkingBudget < 0 {
+		return fmt.Errorf("thinking_budget must be non-negative, got %d: %w", *o.ThinkingBudget, ErrValidation)
+	}
+	if o.TopP != nil && (*o.TopP < 0 || *o.TopP > 1) {
+		return fmt.Errorf("top_p must be in [0, 1], got %g: %w", *o.TopP, ErrValidation)
+	}
+	if o.TopK != nil && *o.TopK < 1 {
+		return fmt.Errorf("top_k must be positive, got %d: %w", *o.TopK, ErrValidation)
+	}
+	for i, s := range o.SafetySettings {
+		if s.Category == "" || s.Threshold == "" {
+			return fmt.Errorf("safety_settings[%d]: category and threshold are required: %w", i, ErrValidation)
+		}
+	}
 	return nil
 }
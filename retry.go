@@ -0,0 +1,93 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// RetryPolicy configures how the loop retries provider requests that fail
+// transiently: rate limits, overload, and connections dropped mid-stream.
+// Delays grow exponentially from BaseDelay up to MaxDelay, with jitter.
+type RetryPolicy struct {
+	// MaxRetries is how many times a request is retried after its first
+	// attempt. Zero disables retries.
+	MaxRetries int
+	BaseDelay  time.Duration // default 1s
+	MaxDelay   time.Duration // default 30s
+	// Resume continues a response that failed mid-stream from its partial
+	// text, by sending the text as a trailing assistant message, instead of
+	// requesting the response again. Only providers that continue a
+	// trailing assistant message, such as Anthropic, support it. Partial
+	// responses with anything other than text are always requested again.
+	Resume bool
+}
+
+const (
+	defaultRetryBaseDelay = time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+)
+
+// delay returns the wait before retry number attempt, counting from 1: an
+// exponential backoff with equal jitter, so it lies between half and all of
+// the backoff.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	base, maxDelay := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = defaultRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+	backoff := base
+	for i := 1; i < attempt && backoff < maxDelay; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxDelay)
+	half := backoff / 2
+	return half + rand.N(backoff-half+1)
+}
+
+// Retryable reports whether err is a transient provider failure worth
+// retrying: a rate limit or overload, or a connection that dropped
+// mid-stream.
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return pe.Reason == StopRateLimited
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// resumable returns the partial text of msg that a resumed request can
+// continue from, and whether msg can be resumed: it must hold text only.
+func resumable(msg AssistantMessage) (string, bool) {
+	var sb strings.Builder
+	for _, b := range msg.Content {
+		tb, ok := b.(TextBlock)
+		if !ok || len(tb.Citations) > 0 {
+			return "", false
+		}
+		sb.WriteString(tb.Text)
+	}
+	// Providers reject a trailing assistant message ending in whitespace;
+	// the continuation regenerates it.
+	text := strings.TrimRight(sb.String(), " \t\r\n")
+	return text, text != ""
+}
+
+// sleepContext waits for d on clock or until ctx is done.
+func sleepContext(ctx context.Context, clock Clock, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clock.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,180 @@
+package pipe_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryable(t *testing.T) {
+	t.Parallel()
+	assert.True(t, pipe.Retryable(&pipe.ProviderError{Reason: pipe.StopRateLimited, Err: errors.New("overloaded")}))
+	assert.True(t, pipe.Retryable(fmt.Errorf("read body: %w", io.ErrUnexpectedEOF)))
+	assert.False(t, pipe.Retryable(&pipe.ProviderError{Reason: pipe.StopAuthFailed, Err: errors.New("bad key")}))
+	assert.False(t, pipe.Retryable(context.Canceled))
+	assert.False(t, pipe.Retryable(errors.New("invalid request")))
+	assert.False(t, pipe.Retryable(nil))
+}
+
+// partialStream streams one text delta of text and then fails with err,
+// leaving text as the partial message.
+func partialStream(text string, err error) *mock.Stream {
+	sent := false
+	return &mock.Stream{
+		NextFn: func() (pipe.Event, error) {
+			if !sent {
+				sent = true
+				return pipe.EventTextDelta{Delta: text}, nil
+			}
+			return nil, err
+		},
+		MessageFn: func() (pipe.AssistantMessage, error) {
+			return pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: text}}, StopReason: pipe.StopError}, nil
+		},
+	}
+}
+
+func TestLoop_Retry(t *testing.T) {
+	t.Parallel()
+	policy := pipe.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond}
+	rateLimited := &pipe.ProviderError{Reason: pipe.StopRateLimited, Raw: "overloaded_error", Err: errors.New("overloaded")}
+	// dropped is the error of a stream cut short, as mock.FaultProvider
+	// reports it.
+	dropped := fmt.Errorf("%w: %w", mock.ErrConnectionDropped, io.ErrUnexpectedEOF)
+	done := pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "done"}}, StopReason: pipe.StopEndTurn}
+
+	run := func(t *testing.T, provider pipe.Provider, policy pipe.RetryPolicy) (*pipe.Session, []pipe.EventRetry, error) {
+		t.Helper()
+		session := &pipe.Session{Messages: []pipe.Message{pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}}}}
+		var retries []pipe.EventRetry
+		err := pipe.NewLoop(provider, &mock.ToolExecutor{}).Run(context.Background(), session, nil,
+			pipe.WithRetry(policy),
+			pipe.WithEventHandler(func(e pipe.Event) {
+				if r, ok := e.(pipe.EventRetry); ok {
+					retries = append(retries, r)
+				}
+			}))
+		return session, retries, err
+	}
+
+	t.Run("retries rate limits with growing delays", func(t *testing.T) {
+		t.Parallel()
+		calls := 0
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			calls++
+			if calls <= 3 {
+				return nil, rateLimited
+			}
+			return completedStream(done), nil
+		}}
+		session, retries, err := run(t, provider, policy)
+		require.NoError(t, err)
+		require.Len(t, session.Messages, 2)
+		assert.Equal(t, done, session.Messages[1])
+
+		require.Len(t, retries, 3)
+		for i, r := range retries {
+			assert.Equal(t, i+1, r.Attempt)
+			assert.Equal(t, 3, r.MaxRetries)
+			assert.ErrorIs(t, r.Err, rateLimited)
+			assert.False(t, r.Resumed)
+		}
+		assert.InDelta(t, 750*time.Microsecond, retries[0].Delay, float64(250*time.Microsecond))
+		assert.InDelta(t, 3*time.Millisecond, retries[2].Delay, float64(time.Millisecond))
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		t.Parallel()
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			return nil, rateLimited
+		}}
+		session, retries, err := run(t, provider, policy)
+		require.ErrorIs(t, err, rateLimited)
+		assert.Len(t, retries, 3)
+		require.Len(t, session.Messages, 2)
+		assert.Equal(t, pipe.StopRateLimited, session.Messages[1].(pipe.AssistantMessage).StopReason)
+	})
+
+	t.Run("does not retry permanent failures", func(t *testing.T) {
+		t.Parallel()
+		authFailed := &pipe.ProviderError{Reason: pipe.StopAuthFailed, Err: errors.New("bad key")}
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			return nil, authFailed
+		}}
+		_, retries, err := run(t, provider, policy)
+		require.ErrorIs(t, err, authFailed)
+		assert.Empty(t, retries)
+	})
+
+	t.Run("restarts a dropped stream", func(t *testing.T) {
+		t.Parallel()
+		var requests []pipe.Request
+		provider := &mock.Provider{StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+			requests = append(requests, req)
+			if len(requests) == 1 {
+				return partialStream("Hello wor", dropped), nil
+			}
+			return completedStream(done), nil
+		}}
+		session, retries, err := run(t, provider, policy)
+		require.NoError(t, err)
+		require.Len(t, retries, 1)
+		assert.False(t, retries[0].Resumed)
+		assert.Len(t, requests[1].Messages, 1)
+		assert.Equal(t, done, session.Messages[1])
+	})
+
+	t.Run("resumes a dropped stream from its partial text", func(t *testing.T) {
+		t.Parallel()
+		var requests []pipe.Request
+		provider := &mock.Provider{StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+			requests = append(requests, req)
+			switch len(requests) {
+			case 1:
+				return partialStream("Hello wor", dropped), nil
+			case 2:
+				return partialStream("ld, and ", fmt.Errorf("read: %w", io.ErrUnexpectedEOF)), nil
+			default:
+				return completedStream(pipe.AssistantMessage{
+					Content:    []pipe.ContentBlock{pipe.TextBlock{Text: " goodbye!"}},
+					StopReason: pipe.StopEndTurn,
+				}), nil
+			}
+		}}
+		resume := policy
+		resume.Resume = true
+		session, retries, err := run(t, provider, resume)
+		require.NoError(t, err)
+		require.Len(t, retries, 2)
+		assert.True(t, retries[0].Resumed)
+
+		require.Len(t, requests, 3)
+		assert.Equal(t, pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Hello wor"}}}, requests[1].Messages[1])
+		assert.Equal(t, pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Hello world, and"}}}, requests[2].Messages[1])
+
+		require.Len(t, session.Messages, 2)
+		final := session.Messages[1].(pipe.AssistantMessage)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "Hello world, and goodbye!"}}, final.Content)
+		assert.Equal(t, pipe.StopEndTurn, final.StopReason)
+	})
+
+	t.Run("stops waiting when cancelled", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			cancel()
+			return nil, rateLimited
+		}}
+		slow := pipe.RetryPolicy{MaxRetries: 1, BaseDelay: time.Hour}
+		err := pipe.NewLoop(provider, &mock.ToolExecutor{}).Run(ctx, &pipe.Session{}, nil, pipe.WithRetry(slow))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,88 @@
+package pipe
+
+import "strings"
+
+// RunState is how the model says a run ended.
+type RunState string
+
+const (
+	RunDone    RunState = "done"    // the model believes the task is finished
+	RunBlocked RunState = "blocked" // the model cannot continue without help
+)
+
+// RunStatus is the model's own account of how a run ended, taken from the
+// status line [RunStatusPrompt] asks it to end with. The zero value means
+// the model gave none.
+type RunStatus struct {
+	State  RunState
+	Detail string // the summary when done, the reason when blocked
+}
+
+// RunStatusPrompt is added to the system prompt by [WithRunStatus] to ask
+// the model for a status line [ParseRunStatus] can read.
+const RunStatusPrompt = "When you have finished the task, or cannot continue without the user, end your final message " +
+	"with a line of its own: \"DONE: <one-line summary>\" or \"BLOCKED: <what you need>\"."
+
+// ParseRunStatus reads the status line from the end of text: its last
+// non-empty line, if it starts with "DONE:" or "BLOCKED:". Markdown
+// emphasis around the prefix, as in "**DONE:** ...", is allowed.
+func ParseRunStatus(text string) (RunStatus, bool) {
+	text = strings.TrimSpace(text)
+	line := text[strings.LastIndexByte(text, '\n')+1:]
+	line = strings.TrimLeft(line, "*_ ")
+	prefixes := []struct {
+		prefix string
+		state  RunState
+	}{
+		{"DONE:", RunDone},
+		{"BLOCKED:", RunBlocked},
+	}
+	for _, p := range prefixes {
+		rest, ok := strings.CutPrefix(line, p.prefix)
+		if !ok {
+			continue
+		}
+		rest = strings.TrimSpace(strings.TrimLeft(rest, "*_"))
+		return RunStatus{State: p.state, Detail: rest}, true
+	}
+	return RunStatus{}, false
+}
+
+// WithRunStatus asks the model to end the run with a status line, adding
+// [RunStatusPrompt] to the system prompt of each request. When the run
+// ends, the status is read from the final assistant message, stored in
+// the session's Status, and emitted as an EventRunStatus, so automation
+// can branch on whether the model believes it finished.
+func WithRunStatus() RunOption {
+	return func(c *runConfig) {
+		c.runStatus = true
+	}
+}
+
+// finalText returns the text of the last assistant message in msgs.
+func finalText(msgs []Message) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		am, ok := msgs[i].(AssistantMessage)
+		if !ok {
+			continue
+		}
+		var sb strings.Builder
+		for _, b := range am.Content {
+			if tb, ok := b.(TextBlock); ok {
+				sb.WriteString(tb.Text)
+			}
+		}
+		return sb.String()
+	}
+	return ""
+}
+
+// reportRunStatus stores the status the run ended with in the session and
+// emits it. A run that ends without a status line clears the previous one.
+func reportRunStatus(session *Session, cfg *runConfig) {
+	status, ok := ParseRunStatus(finalText(session.Messages))
+	session.Status = status
+	if ok && cfg.onEvent != nil {
+		cfg.onEvent(EventRunStatus{Status: status})
+	}
+}
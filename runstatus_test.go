@@ -0,0 +1,96 @@
+package pipe_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRunStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		text   string
+		want   pipe.RunStatus
+		wantOK bool
+	}{
+		{"done", "Fixed it.\n\nDONE: tests pass", pipe.RunStatus{State: pipe.RunDone, Detail: "tests pass"}, true},
+		{"blocked", "BLOCKED: need the staging credentials\n", pipe.RunStatus{State: pipe.RunBlocked, Detail: "need the staging credentials"}, true},
+		{"emphasis", "Summary.\n**DONE:** refactored the parser", pipe.RunStatus{State: pipe.RunDone, Detail: "refactored the parser"}, true},
+		{"no status", "All good.", pipe.RunStatus{}, false},
+		{"not on the last line", "DONE: tests pass\nAnything else?", pipe.RunStatus{}, false},
+		{"lowercase", "done: tests pass", pipe.RunStatus{}, false},
+		{"empty", "", pipe.RunStatus{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := pipe.ParseRunStatus(tt.text)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoop_WithRunStatus(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reports the status line", func(t *testing.T) {
+		t.Parallel()
+		var systemPrompt string
+		provider := &mock.Provider{StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+			systemPrompt = req.SystemPrompt
+			return completedStream(pipe.AssistantMessage{
+				Content:    []pipe.ContentBlock{pipe.TextBlock{Text: "I could not reach the database.\nBLOCKED: need DATABASE_URL"}},
+				StopReason: pipe.StopEndTurn,
+			}), nil
+		}}
+		var events []pipe.EventRunStatus
+		onEvent := func(e pipe.Event) {
+			if rs, ok := e.(pipe.EventRunStatus); ok {
+				events = append(events, rs)
+			}
+		}
+
+		session := &pipe.Session{SystemPrompt: "Be brief."}
+		err := pipe.NewLoop(provider, nil).Run(context.Background(), session, nil, pipe.WithRunStatus(), pipe.WithEventHandler(onEvent))
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(systemPrompt, "Be brief."))
+		assert.Contains(t, systemPrompt, pipe.RunStatusPrompt)
+		want := pipe.RunStatus{State: pipe.RunBlocked, Detail: "need DATABASE_URL"}
+		assert.Equal(t, want, session.Status)
+		assert.Equal(t, []pipe.EventRunStatus{{Status: want}}, events)
+	})
+
+	t.Run("no status line clears the previous status", func(t *testing.T) {
+		t.Parallel()
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "Hi."}}, StopReason: pipe.StopEndTurn}), nil
+		}}
+		session := &pipe.Session{Status: pipe.RunStatus{State: pipe.RunDone, Detail: "earlier"}}
+		err := pipe.NewLoop(provider, nil).Run(context.Background(), session, nil, pipe.WithRunStatus())
+		require.NoError(t, err)
+		assert.Equal(t, pipe.RunStatus{}, session.Status)
+	})
+
+	t.Run("not requested", func(t *testing.T) {
+		t.Parallel()
+		var systemPrompt string
+		provider := &mock.Provider{StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+			systemPrompt = req.SystemPrompt
+			return completedStream(pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "DONE: ok"}}, StopReason: pipe.StopEndTurn}), nil
+		}}
+		session := &pipe.Session{SystemPrompt: "Be brief."}
+		err := pipe.NewLoop(provider, nil).Run(context.Background(), session, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Be brief.", systemPrompt)
+		assert.Equal(t, pipe.RunStatus{}, session.Status)
+	})
+}
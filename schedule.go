@@ -0,0 +1,39 @@
+package pipe
+
+import "time"
+
+// ScheduledRun is a follow-up run a session has planned for itself, e.g.
+// "check this flaky test again every hour". Scheduled runs are persisted in
+// the session and executed headlessly by "pipe runs exec".
+type ScheduledRun struct {
+	ID     string
+	Prompt string
+	// Dir is the working directory the run executes in.
+	Dir string
+	// AfterCommand, when set, is a shell command run in Dir before the
+	// prompt, e.g. one that waits for CI to finish. Its exit code and output
+	// are passed to the model along with the prompt.
+	AfterCommand string
+	// Every repeats the run at this interval; zero runs it once.
+	Every     time.Duration
+	Next      time.Time
+	CreatedAt time.Time
+}
+
+// Due reports whether the run should execute at now.
+func (r ScheduledRun) Due(now time.Time) bool {
+	return !now.Before(r.Next)
+}
+
+// Advance moves a repeating run to its next time after now, skipping any
+// runs missed while nothing was executing them. It reports false for a
+// one-shot run, which should be removed instead.
+func (r *ScheduledRun) Advance(now time.Time) bool {
+	if r.Every <= 0 {
+		return false
+	}
+	for !r.Next.After(now) {
+		r.Next = r.Next.Add(r.Every)
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package pipe_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledRun(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("due at and after its next time", func(t *testing.T) {
+		t.Parallel()
+		r := pipe.ScheduledRun{Next: start}
+		assert.False(t, r.Due(start.Add(-time.Second)))
+		assert.True(t, r.Due(start))
+		assert.True(t, r.Due(start.Add(time.Hour)))
+	})
+
+	t.Run("one-shot run does not advance", func(t *testing.T) {
+		t.Parallel()
+		r := pipe.ScheduledRun{Next: start}
+		assert.False(t, r.Advance(start))
+	})
+
+	t.Run("repeating run skips missed runs", func(t *testing.T) {
+		t.Parallel()
+		r := pipe.ScheduledRun{Next: start, Every: time.Hour}
+		assert.True(t, r.Advance(start.Add(150*time.Minute)))
+		assert.Equal(t, start.Add(3*time.Hour), r.Next)
+	})
+}
@@ -9,4 +9,68 @@ type Session struct {
 	SystemPrompt string
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	// Artifacts are files saved alongside the session, such as full tool
+	// outputs referenced by truncation notices.
+	Artifacts []string
+	// Schedules are follow-up runs the session has planned for itself.
+	Schedules []ScheduledRun
+	// Status is how the model said its latest run ended, when the run
+	// asked it to (see [WithRunStatus]).
+	Status RunStatus
+}
+
+// LastPrompt returns the index of the last user message, or -1 if there is
+// none. Messages after it form the latest assistant turn.
+func (s Session) LastPrompt() int {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if _, ok := s.Messages[i].(UserMessage); ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// TotalUsage sums the usage of every request recorded in the session,
+// including cache reads and writes. Requests summarized away by compaction
+// are not counted.
+func (s Session) TotalUsage() Usage {
+	return SumUsage(s.Messages)
+}
+
+// SumUsage sums the usage of the requests behind msgs: those of assistant
+// messages and those tools made for their results, such as a sub-agent's.
+func SumUsage(msgs []Message) Usage {
+	var u Usage
+	for _, msg := range msgs {
+		switch m := msg.(type) {
+		case AssistantMessage:
+			u = u.Add(m.Usage)
+		case ToolResultMessage:
+			u = u.Add(m.Usage)
+		}
+	}
+	return u
+}
+
+// WithoutThinking returns a copy of s with the thinking blocks removed from
+// its assistant messages, e.g. to persist a transcript without the model's
+// reasoning. Providers accept the result: thinking is optional in earlier
+// turns.
+func (s Session) WithoutThinking() Session {
+	msgs := make([]Message, len(s.Messages))
+	for i, msg := range s.Messages {
+		if am, ok := msg.(AssistantMessage); ok {
+			content := make([]ContentBlock, 0, len(am.Content))
+			for _, b := range am.Content {
+				if _, thinking := b.(ThinkingBlock); !thinking {
+					content = append(content, b)
+				}
+			}
+			am.Content = content
+			msg = am
+		}
+		msgs[i] = msg
+	}
+	s.Messages = msgs
+	return s
 }
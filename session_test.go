@@ -24,3 +24,51 @@ func TestSession_Fields(t *testing.T) {
 	assert.Equal(t, now, s.CreatedAt)
 	assert.Equal(t, now, s.UpdatedAt)
 }
+
+func TestSession_LastPrompt(t *testing.T) {
+	t.Parallel()
+	prompt := pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}}
+	s := pipe.Session{Messages: []pipe.Message{
+		prompt,
+		pipe.AssistantMessage{},
+		prompt,
+		pipe.AssistantMessage{},
+		pipe.ToolResultMessage{},
+		pipe.AssistantMessage{},
+	}}
+	assert.Equal(t, 2, s.LastPrompt())
+	assert.Equal(t, -1, pipe.Session{}.LastPrompt())
+}
+
+func TestSession_TotalUsage(t *testing.T) {
+	t.Parallel()
+	s := pipe.Session{Messages: []pipe.Message{
+		pipe.UserMessage{},
+		pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 100, OutputTokens: 10, CacheWriteTokens: 50}},
+		pipe.ToolResultMessage{},
+		pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 20, OutputTokens: 5, CacheReadTokens: 150}},
+	}}
+	assert.Equal(t, pipe.Usage{InputTokens: 120, OutputTokens: 15, CacheReadTokens: 150, CacheWriteTokens: 50}, s.TotalUsage())
+	assert.Equal(t, pipe.Usage{}, pipe.Session{}.TotalUsage())
+}
+
+func TestSession_WithoutThinking(t *testing.T) {
+	t.Parallel()
+	prompt := pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}}
+	reply := pipe.AssistantMessage{
+		Content: []pipe.ContentBlock{
+			pipe.ThinkingBlock{Thinking: "the user greets me", Signature: []byte("sig")},
+			pipe.TextBlock{Text: "hello"},
+		},
+		StopReason: pipe.StopEndTurn,
+	}
+	s := pipe.Session{ID: "s1", Messages: []pipe.Message{prompt, reply}}
+
+	got := s.WithoutThinking()
+	assert.Equal(t, "s1", got.ID)
+	assert.Equal(t, []pipe.Message{prompt, pipe.AssistantMessage{
+		Content:    []pipe.ContentBlock{pipe.TextBlock{Text: "hello"}},
+		StopReason: pipe.StopEndTurn,
+	}}, got.Messages)
+	assert.Equal(t, reply, s.Messages[1], "the original session is unchanged")
+}
@@ -0,0 +1,87 @@
+package pipe
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SpeechSink receives assistant text one finalized sentence at a time while
+// a response streams, e.g. to drive text-to-speech for accessibility or
+// hands-free use. Speak is called on the loop's goroutine and should return
+// quickly, queueing slow work.
+type SpeechSink interface {
+	Speak(sentence string)
+}
+
+var _ SpeechSink = NopSpeechSink{}
+
+// NopSpeechSink discards all sentences. It is the default sink.
+type NopSpeechSink struct{}
+
+// Speak does nothing.
+func (NopSpeechSink) Speak(string) {}
+
+// SentenceSegmenter splits streamed text into sentences and passes each one
+// to a SpeechSink as soon as it is complete.
+type SentenceSegmenter struct {
+	sink SpeechSink
+	buf  []rune
+}
+
+// NewSentenceSegmenter creates a SentenceSegmenter that speaks to sink.
+func NewSentenceSegmenter(sink SpeechSink) *SentenceSegmenter {
+	return &SentenceSegmenter{sink: sink}
+}
+
+// Write adds a text delta, speaking every sentence it completes. A sentence
+// ends at a newline, or at '.', '!', or '?' followed by whitespace unless the
+// period ends a common abbreviation or an initial.
+func (s *SentenceSegmenter) Write(delta string) {
+	s.buf = append(s.buf, []rune(delta)...)
+	start := 0
+	// The last rune is held back: whether a terminator ends a sentence
+	// depends on the rune after it.
+	for i := 0; i < len(s.buf)-1; i++ {
+		r := s.buf[i]
+		end := r == '\n' ||
+			(r == '!' || r == '?' || (r == '.' && !isAbbreviation(s.buf[start:i]))) && unicode.IsSpace(s.buf[i+1])
+		if end {
+			s.speak(s.buf[start : i+1])
+			start = i + 1
+		}
+	}
+	if len(s.buf) > 0 && s.buf[len(s.buf)-1] == '\n' {
+		s.speak(s.buf[start:])
+		start = len(s.buf)
+	}
+	s.buf = append(s.buf[:0], s.buf[start:]...)
+}
+
+// Flush speaks any buffered text as a final sentence.
+func (s *SentenceSegmenter) Flush() {
+	s.speak(s.buf)
+	s.buf = s.buf[:0]
+}
+
+func (s *SentenceSegmenter) speak(text []rune) {
+	if sentence := strings.TrimSpace(string(text)); sentence != "" {
+		s.sink.Speak(sentence)
+	}
+}
+
+// isAbbreviation reports whether text, the sentence so far up to a period,
+// ends in a word that is usually followed by a period mid-sentence.
+func isAbbreviation(text []rune) bool {
+	i := len(text)
+	for i > 0 && !unicode.IsSpace(text[i-1]) {
+		i--
+	}
+	word := strings.ToLower(strings.TrimLeft(string(text[i:]), "(\"'"))
+	switch word {
+	case "e.g", "i.e", "etc", "vs", "mr", "mrs", "ms", "dr", "st", "no", "cf":
+		return true
+	}
+	// Single letters are initials, as in "J. R. R. Tolkien".
+	r := []rune(word)
+	return len(r) == 1 && unicode.IsLetter(r[0])
+}
@@ -0,0 +1,69 @@
+package pipe_test
+
+import (
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+)
+
+// sentences records what a SpeechSink is given.
+type sentences []string
+
+func (s *sentences) Speak(sentence string) { *s = append(*s, sentence) }
+
+func TestSentenceSegmenter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		deltas []string
+		want   []string
+	}{
+		{
+			name:   "splits on terminators across deltas",
+			deltas: []string{"Hel", "lo there. How", " are you? Fi", "ne!"},
+			want:   []string{"Hello there.", "How are you?", "Fine!"},
+		},
+		{
+			name:   "waits for the rune after a period",
+			deltas: []string{"Version 1.", "5 is out. Done"},
+			want:   []string{"Version 1.5 is out.", "Done"},
+		},
+		{
+			name:   "keeps abbreviations and initials",
+			deltas: []string{"Use a tool, e.g. grep. Ask J. R. R. Tolkien."},
+			want:   []string{"Use a tool, e.g. grep.", "Ask J. R. R. Tolkien."},
+		},
+		{
+			name:   "newlines end sentences",
+			deltas: []string{"- first item\n", "- second item\n\nNext"},
+			want:   []string{"- first item", "- second item", "Next"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got sentences
+			s := pipe.NewSentenceSegmenter(&got)
+			for _, d := range tt.deltas {
+				s.Write(d)
+			}
+			s.Flush()
+			assert.Equal(t, tt.want, []string(got))
+		})
+	}
+
+	t.Run("speaks complete sentences before the stream ends", func(t *testing.T) {
+		t.Parallel()
+		var got sentences
+		s := pipe.NewSentenceSegmenter(&got)
+		s.Write("One. Two")
+		assert.Equal(t, []string{"One."}, []string(got))
+	})
+}
+
+func TestNopSpeechSink(t *testing.T) {
+	t.Parallel()
+	assert.NotPanics(t, func() { pipe.NopSpeechSink{}.Speak("hello") })
+}
@@ -10,4 +10,13 @@ const (
 	StopError   StopReason = "error"
 	StopAborted StopReason = "aborted"
 	StopUnknown StopReason = "unknown"
+	// StopLimit marks the note the loop adds when it ends a run at a
+	// WithMaxTurns or WithMaxCostTokens limit.
+	StopLimit StopReason = "limit"
+
+	// Provider failures classified by cause. StopError covers failures
+	// that fit none of these.
+	StopRateLimited     StopReason = "rate_limited"     // request rejected by a rate limit or overload
+	StopContentFiltered StopReason = "content_filtered" // prompt or response blocked by a safety filter
+	StopAuthFailed      StopReason = "auth_failed"      // missing, invalid, or unauthorized credentials
 )
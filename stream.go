@@ -20,7 +20,9 @@ const (
 // Message() returns the assembled AssistantMessage. Behavior by stream state:
 //   - StreamStateComplete: complete message, nil error.
 //   - StreamStateError: partial message, nil error. StopReason is StopError
-//     for transport/protocol failures, StopAborted for context cancellation.
+//     for transport/protocol failures, a finer classification such as
+//     StopRateLimited when the provider reports one (see [ProviderError]),
+//     and StopAborted for context cancellation.
 //   - StreamStateStreaming: partial message, nil error. Content reflects
 //     deltas received so far.
 //   - StreamStateNew: zero-value message, non-nil error.
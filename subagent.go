@@ -0,0 +1,101 @@
+package pipe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DefaultSubAgentTurns is how many requests a [SubAgent] makes when its
+// MaxTurns is zero.
+const DefaultSubAgentTurns = 10
+
+// SubAgent runs a delegated task in a nested agent loop. It reuses the
+// provider and executor of the agent that spawned it, but works in a fresh
+// session with its own system prompt, so none of the parent's messages
+// reach it and none of its intermediate work reaches the parent.
+type SubAgent struct {
+	Provider     Provider
+	Executor     ToolExecutor
+	SystemPrompt string
+	// Tools are the only tools the sub-agent may call; calls to others
+	// fail without reaching Executor.
+	Tools []Tool
+	// MaxTurns caps the provider requests the sub-agent makes. Zero means
+	// DefaultSubAgentTurns.
+	MaxTurns int
+	// Clock stamps the task prompt. Nil means SystemClock.
+	Clock Clock
+	// Options configure the nested loop, e.g. WithModel.
+	Options []RunOption
+}
+
+// SubAgentResult is the outcome of a [SubAgent] run.
+type SubAgentResult struct {
+	// Text is the text of the sub-agent's last assistant message.
+	Text  string
+	Turns int
+	Usage Usage
+	// Truncated reports that the sub-agent used all its turns before it
+	// finished, so Text may be an intermediate message.
+	Truncated bool
+}
+
+// Run gives the sub-agent prompt and runs its loop until it answers or runs
+// out of turns.
+func (a SubAgent) Run(ctx context.Context, prompt string) (SubAgentResult, error) {
+	clock := a.Clock
+	if clock == nil {
+		clock = SystemClock()
+	}
+	maxTurns := a.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = DefaultSubAgentTurns
+	}
+	session := &Session{
+		SystemPrompt: a.SystemPrompt,
+		Messages:     []Message{UserMessage{Content: []ContentBlock{TextBlock{Text: prompt}}, Timestamp: clock.Now()}},
+	}
+	executor := &restrictedExecutor{ToolExecutor: a.Executor, tools: a.Tools}
+	opts := append([]RunOption{WithClock(clock)}, a.Options...)
+	opts = append(opts, WithMaxTurns(maxTurns))
+	err := NewLoop(a.Provider, executor).Run(ctx, session, a.Tools, opts...)
+
+	// The note the loop adds at the turn limit is not the sub-agent's.
+	msgs := session.Messages
+	if n := len(msgs); n > 0 {
+		if am, ok := msgs[n-1].(AssistantMessage); ok && am.StopReason == StopLimit {
+			msgs = msgs[:n-1]
+		}
+	}
+	result := SubAgentResult{Text: finalText(msgs), Usage: session.TotalUsage()}
+	for _, msg := range msgs {
+		if _, ok := msg.(AssistantMessage); ok {
+			result.Turns++
+		}
+	}
+	if errors.Is(err, ErrMaxTurns) {
+		result.Truncated = true
+		return result, nil
+	}
+	return result, err
+}
+
+// restrictedExecutor runs only the calls to tools.
+type restrictedExecutor struct {
+	ToolExecutor
+	tools []Tool
+}
+
+func (e *restrictedExecutor) Execute(ctx context.Context, name string, args json.RawMessage) (*ToolResult, error) {
+	for _, t := range e.tools {
+		if t.Name == name {
+			return e.ToolExecutor.Execute(ctx, name, args)
+		}
+	}
+	return &ToolResult{
+		Content: []ContentBlock{TextBlock{Text: fmt.Sprintf("tool %s is not available to this sub-agent", name)}},
+		IsError: true,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package pipe_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubAgent_Run(t *testing.T) {
+	t.Parallel()
+
+	readTool := pipe.Tool{Name: "read", Parameters: json.RawMessage(`{"type":"object"}`)}
+	toolCall := func(id, name string) pipe.AssistantMessage {
+		return pipe.AssistantMessage{
+			Content:    []pipe.ContentBlock{pipe.ToolCallBlock{ID: id, Name: name, Arguments: json.RawMessage(`{}`)}},
+			StopReason: pipe.StopToolUse,
+			Usage:      pipe.Usage{InputTokens: 10, OutputTokens: 5},
+		}
+	}
+	answer := pipe.AssistantMessage{
+		Content:    []pipe.ContentBlock{pipe.TextBlock{Text: "The config lives in .pipe/config.json."}},
+		StopReason: pipe.StopEndTurn,
+		Usage:      pipe.Usage{InputTokens: 20, OutputTokens: 8},
+	}
+
+	t.Run("returns the final text", func(t *testing.T) {
+		t.Parallel()
+		var requests []pipe.Request
+		provider := &mock.Provider{StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+			requests = append(requests, req)
+			if len(requests) == 1 {
+				return completedStream(toolCall("c1", "read")), nil
+			}
+			return completedStream(answer), nil
+		}}
+		var executed []string
+		executor := &mock.ToolExecutor{ExecuteFn: func(_ context.Context, name string, _ json.RawMessage) (*pipe.ToolResult, error) {
+			executed = append(executed, name)
+			return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "{}"}}}, nil
+		}}
+
+		agent := pipe.SubAgent{
+			Provider:     provider,
+			Executor:     executor,
+			SystemPrompt: "You explore code.",
+			Tools:        []pipe.Tool{readTool},
+			Options:      []pipe.RunOption{pipe.WithModel("small")},
+		}
+		got, err := agent.Run(context.Background(), "Where is the config?")
+		require.NoError(t, err)
+
+		assert.Equal(t, pipe.SubAgentResult{
+			Text:  "The config lives in .pipe/config.json.",
+			Turns: 2,
+			Usage: pipe.Usage{InputTokens: 30, OutputTokens: 13},
+		}, got)
+		assert.Equal(t, []string{"read"}, executed)
+		require.Len(t, requests, 2)
+		assert.Equal(t, "You explore code.", requests[0].SystemPrompt)
+		assert.Equal(t, "small", requests[0].Model)
+		assert.Equal(t, []pipe.Tool{readTool}, requests[0].Tools)
+		require.Len(t, requests[0].Messages, 1)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "Where is the config?"}}, requests[0].Messages[0].(pipe.UserMessage).Content)
+	})
+
+	t.Run("refuses tools outside its set", func(t *testing.T) {
+		t.Parallel()
+		var mu sync.Mutex
+		var results []pipe.ToolResultMessage
+		calls := 0
+		provider := &mock.Provider{StreamFn: func(_ context.Context, req pipe.Request) (pipe.Stream, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if calls == 1 {
+				return completedStream(toolCall("c1", "bash")), nil
+			}
+			for _, msg := range req.Messages {
+				if r, ok := msg.(pipe.ToolResultMessage); ok {
+					results = append(results, r)
+				}
+			}
+			return completedStream(answer), nil
+		}}
+		executor := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+			t.Error("executor called for a tool outside the sub-agent's set")
+			return nil, nil
+		}}
+
+		agent := pipe.SubAgent{Provider: provider, Executor: executor, Tools: []pipe.Tool{readTool}}
+		_, err := agent.Run(context.Background(), "Run the tests.")
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].IsError)
+	})
+
+	t.Run("stops at its turn limit", func(t *testing.T) {
+		t.Parallel()
+		calls := 0
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			calls++
+			return completedStream(toolCall("c", "read")), nil
+		}}
+		executor := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+			return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "{}"}}}, nil
+		}}
+
+		agent := pipe.SubAgent{Provider: provider, Executor: executor, Tools: []pipe.Tool{readTool}, MaxTurns: 3}
+		got, err := agent.Run(context.Background(), "Keep reading.")
+		require.NoError(t, err)
+		assert.True(t, got.Truncated)
+		assert.Equal(t, 3, got.Turns)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		t.Parallel()
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			return nil, assert.AnError
+		}}
+		agent := pipe.SubAgent{Provider: provider}
+		_, err := agent.Run(context.Background(), "Hi.")
+		require.ErrorIs(t, err, assert.AnError)
+	})
+}
@@ -0,0 +1,74 @@
+package pipe
+
+import (
+	"context"
+	"time"
+)
+
+// EventSink receives a copy of the events of a run, e.g. to mirror them to a
+// file or socket for an external monitor. json.EventWriter is one.
+type EventSink interface {
+	Write(Event) error
+}
+
+// teeBuffer is how many events WithEventTee queues for a slow sink before
+// it starts dropping them.
+const teeBuffer = 1024
+
+// teeDrainTimeout bounds how long Run waits for a sink to take the events
+// still queued when the run ends.
+const teeDrainTimeout = 5 * time.Second
+
+// WithEventTee mirrors every event of the run to sink, alongside the event
+// handler. The sink is written on its own goroutine so a slow reader does
+// not hold up the run: events it falls more than teeBuffer behind on are
+// dropped, and after the first write error it receives nothing more. Run
+// returns once the sink has been given every queued event, or, if the sink
+// stalls, after a few seconds or once ctx is done; the stalled sink gets
+// no more events.
+func WithEventTee(sink EventSink) RunOption {
+	return func(c *runConfig) {
+		c.tee = sink
+	}
+}
+
+// eventTee feeds events to a sink from a queue.
+type eventTee struct {
+	events chan Event
+	done   chan struct{}
+}
+
+func startEventTee(sink EventSink) *eventTee {
+	t := &eventTee{events: make(chan Event, teeBuffer), done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		failed := false
+		for e := range t.events {
+			if !failed {
+				failed = sink.Write(e) != nil
+			}
+		}
+	}()
+	return t
+}
+
+// send queues e, dropping it if the queue is full.
+func (t *eventTee) send(e Event) {
+	select {
+	case t.events <- e:
+	default:
+	}
+}
+
+// close waits for the queued events to be written, for at most
+// teeDrainTimeout or until ctx is done.
+func (t *eventTee) close(ctx context.Context) {
+	close(t.events)
+	timer := time.NewTimer(teeDrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-t.done:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
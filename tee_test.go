@@ -0,0 +1,86 @@
+package pipe_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoop_EventTee(t *testing.T) {
+	t.Parallel()
+
+	// provider streams two text deltas and ends the turn.
+	provider := func() *mock.Provider {
+		return &mock.Provider{
+			StreamFn: func(_ context.Context, _ pipe.Request) (pipe.Stream, error) {
+				events := []pipe.Event{pipe.EventTextDelta{Delta: "hel"}, pipe.EventTextDelta{Delta: "lo"}}
+				return &mock.Stream{
+					NextFn: func() (pipe.Event, error) {
+						if len(events) == 0 {
+							return nil, io.EOF
+						}
+						e := events[0]
+						events = events[1:]
+						return e, nil
+					},
+					MessageFn: func() (pipe.AssistantMessage, error) {
+						return pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hello"}}, StopReason: pipe.StopEndTurn}, nil
+					},
+				}, nil
+			},
+		}
+	}
+
+	t.Run("mirrors the handler's events", func(t *testing.T) {
+		t.Parallel()
+		var handled, teed []pipe.Event
+		sink := &mock.EventSink{WriteFn: func(e pipe.Event) error {
+			teed = append(teed, e)
+			return nil
+		}}
+		err := pipe.NewLoop(provider(), nil).Run(context.Background(), &pipe.Session{}, nil,
+			pipe.WithEventHandler(func(e pipe.Event) { handled = append(handled, e) }),
+			pipe.WithEventTee(sink),
+		)
+		require.NoError(t, err)
+		require.Len(t, handled, 3)
+		assert.Equal(t, handled, teed, "every event reaches the sink before Run returns")
+	})
+
+	t.Run("a failing sink does not fail the run", func(t *testing.T) {
+		t.Parallel()
+		writes := 0
+		sink := &mock.EventSink{WriteFn: func(pipe.Event) error {
+			writes++
+			return errors.New("broken pipe")
+		}}
+		session := &pipe.Session{}
+		err := pipe.NewLoop(provider(), nil).Run(context.Background(), session, nil, pipe.WithEventTee(sink))
+		require.NoError(t, err)
+		assert.Len(t, session.Messages, 1)
+		assert.Equal(t, 1, writes, "the sink is not written after an error")
+	})
+
+	t.Run("a stalled sink is abandoned when the context ends", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		defer close(release)
+		sink := &mock.EventSink{WriteFn: func(pipe.Event) error {
+			<-release
+			return nil
+		}}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := pipe.NewLoop(provider(), nil).Run(ctx, &pipe.Session{}, nil, pipe.WithEventTee(sink))
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
@@ -0,0 +1,169 @@
+package pipe
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// TokenAttribution estimates the tokens one content block contributes to a
+// session's context.
+type TokenAttribution struct {
+	MessageIndex int    // index into Session.Messages; -1 for the system prompt
+	BlockIndex   int    // index into the message's Content
+	Role         Role   // empty for the system prompt
+	Kind         string // "system", "text", "thinking", "tool_call", "image", "server_tool_call", "server_tool_result"
+	Label        string // tool name for tool blocks and tool results
+	Preview      string // first line of the block's text, shortened
+	Tokens       int
+}
+
+// TopTokenEntries is how many of the largest blocks a token report lists.
+const TopTokenEntries = 10
+
+// CharsPerToken approximates tokenizer density for English and code. It is
+// used wherever token counts are estimated from text length.
+const CharsPerToken = 4
+
+const (
+	// imageTokens is a flat estimate; image cost depends on dimensions,
+	// which are not recorded.
+	imageTokens = 1600
+	// previewLen is the maximum length of TokenAttribution.Preview in runes.
+	previewLen = 80
+)
+
+// AttributeTokens estimates per-block token counts for a session, in session
+// order. Counts are derived from content length; blocks of an assistant
+// message are scaled to sum to its reported output tokens when available.
+func AttributeTokens(s Session) []TokenAttribution {
+	var attrs []TokenAttribution
+	if s.SystemPrompt != "" {
+		attrs = append(attrs, TokenAttribution{
+			MessageIndex: -1,
+			Kind:         "system",
+			Preview:      preview(s.SystemPrompt),
+			Tokens:       estimateTokens(s.SystemPrompt),
+		})
+	}
+	for mi, msg := range s.Messages {
+		var content []ContentBlock
+		var label string
+		switch m := msg.(type) {
+		case UserMessage:
+			content = m.Content
+		case AssistantMessage:
+			content = m.Content
+		case ToolResultMessage:
+			content = m.Content
+			label = m.ToolName
+		}
+		start := len(attrs)
+		for bi, b := range content {
+			a := attributeBlock(b)
+			a.MessageIndex = mi
+			a.BlockIndex = bi
+			a.Role = msg.Role()
+			if a.Label == "" {
+				a.Label = label
+			}
+			attrs = append(attrs, a)
+		}
+		if am, ok := msg.(AssistantMessage); ok && am.Usage.OutputTokens > 0 {
+			scaleTokens(attrs[start:], am.Usage.OutputTokens)
+		}
+	}
+	return attrs
+}
+
+// TopTokens returns up to n attributions with the most tokens, largest first.
+func TopTokens(attrs []TokenAttribution, n int) []TokenAttribution {
+	sorted := slices.Clone(attrs)
+	slices.SortStableFunc(sorted, func(a, b TokenAttribution) int {
+		return cmp.Compare(b.Tokens, a.Tokens)
+	})
+	return sorted[:min(n, len(sorted))]
+}
+
+// EstimateRequestUsage estimates the input usage of sending req: the
+// context of the latest request that reported usage, counted as a cache
+// read if that request used the cache, plus an estimate of the messages
+// added since. Without reported usage, the whole request is estimated from
+// its text.
+func EstimateRequestUsage(req Request) Usage {
+	last := -1
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if am, ok := req.Messages[i].(AssistantMessage); ok && am.Usage != (Usage{}) {
+			last = i
+			break
+		}
+	}
+	added := 0
+	for _, msg := range req.Messages[last+1:] {
+		for _, b := range messageContent(msg) {
+			added += attributeBlock(b).Tokens
+		}
+	}
+	if last < 0 {
+		added += estimateTokens(req.SystemPrompt)
+		for _, t := range req.Tools {
+			added += estimateTokens(t.Name + t.Description + string(t.Parameters))
+		}
+		return Usage{InputTokens: added}
+	}
+	u := req.Messages[last].(AssistantMessage).Usage
+	base := u.InputTokens + u.CacheReadTokens + u.CacheWriteTokens + u.OutputTokens
+	if u.CacheReadTokens+u.CacheWriteTokens > 0 {
+		return Usage{InputTokens: added, CacheReadTokens: base}
+	}
+	return Usage{InputTokens: base + added}
+}
+
+func attributeBlock(b ContentBlock) TokenAttribution {
+	switch b := b.(type) {
+	case TextBlock:
+		return TokenAttribution{Kind: "text", Preview: preview(b.Text), Tokens: estimateTokens(b.Text)}
+	case ThinkingBlock:
+		return TokenAttribution{Kind: "thinking", Preview: preview(b.Thinking), Tokens: estimateTokens(b.Thinking)}
+	case ToolCallBlock:
+		args := string(b.Arguments)
+		return TokenAttribution{Kind: "tool_call", Label: b.Name, Preview: preview(args), Tokens: estimateTokens(b.Name + args)}
+	case ServerToolCallBlock:
+		args := string(b.Arguments)
+		return TokenAttribution{Kind: "server_tool_call", Label: b.Name, Preview: preview(args), Tokens: estimateTokens(b.Name + args)}
+	case ServerToolResultBlock:
+		content := string(b.Content)
+		return TokenAttribution{Kind: "server_tool_result", Label: b.Name, Preview: preview(content), Tokens: estimateTokens(content)}
+	case ImageBlock:
+		return TokenAttribution{Kind: "image", Preview: b.MimeType, Tokens: imageTokens}
+	default:
+		return TokenAttribution{Kind: "unknown"}
+	}
+}
+
+// scaleTokens distributes total across attrs in proportion to their
+// estimates.
+func scaleTokens(attrs []TokenAttribution, total int) {
+	estimated := 0
+	for _, a := range attrs {
+		estimated += a.Tokens
+	}
+	if estimated == 0 {
+		return
+	}
+	for i := range attrs {
+		attrs[i].Tokens = attrs[i].Tokens * total / estimated
+	}
+}
+
+func estimateTokens(s string) int {
+	return (len(s) + CharsPerToken - 1) / CharsPerToken
+}
+
+func preview(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	if r := []rune(line); len(r) > previewLen {
+		return string(r[:previewLen]) + "…"
+	}
+	return line
+}
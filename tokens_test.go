@@ -0,0 +1,100 @@
+package pipe_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fwojciec/pipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeTokens(t *testing.T) {
+	t.Parallel()
+	s := pipe.Session{
+		SystemPrompt: "You are helpful.", // 16 chars
+		Messages: []pipe.Message{
+			pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "list files\nplease"}}},
+			pipe.AssistantMessage{
+				Content: []pipe.ContentBlock{
+					pipe.TextBlock{Text: "12345678"},
+					pipe.ToolCallBlock{ID: "tc_1", Name: "bash", Arguments: json.RawMessage(`{"x":1}`)},
+				},
+				Usage: pipe.Usage{OutputTokens: 30},
+			},
+			pipe.ToolResultMessage{ToolName: "bash", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "a.go b.go"}}},
+		},
+	}
+
+	attrs := pipe.AttributeTokens(s)
+	require.Len(t, attrs, 5)
+
+	assert.Equal(t, pipe.TokenAttribution{MessageIndex: -1, Kind: "system", Preview: "You are helpful.", Tokens: 4}, attrs[0])
+	assert.Equal(t, pipe.TokenAttribution{MessageIndex: 0, Role: pipe.RoleUser, Kind: "text", Preview: "list files", Tokens: 5}, attrs[1])
+
+	// Assistant blocks estimate 2 and 3 tokens and are scaled to 30 total.
+	assert.Equal(t, 12, attrs[2].Tokens)
+	assert.Equal(t, "tool_call", attrs[3].Kind)
+	assert.Equal(t, "bash", attrs[3].Label)
+	assert.Equal(t, 18, attrs[3].Tokens)
+
+	assert.Equal(t, pipe.RoleToolResult, attrs[4].Role)
+	assert.Equal(t, "bash", attrs[4].Label)
+	assert.Equal(t, 3, attrs[4].Tokens)
+}
+
+func TestTopTokens(t *testing.T) {
+	t.Parallel()
+	attrs := []pipe.TokenAttribution{
+		{Kind: "a", Tokens: 1},
+		{Kind: "b", Tokens: 9},
+		{Kind: "c", Tokens: 5},
+	}
+	top := pipe.TopTokens(attrs, 2)
+	require.Len(t, top, 2)
+	assert.Equal(t, "b", top[0].Kind)
+	assert.Equal(t, "c", top[1].Kind)
+	assert.Equal(t, "a", attrs[0].Kind, "input is not reordered")
+	assert.Len(t, pipe.TopTokens(attrs, 10), 3)
+}
+
+func TestEstimateRequestUsage(t *testing.T) {
+	t.Parallel()
+	user := func(text string) pipe.Message {
+		return pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: text}}}
+	}
+
+	t.Run("first request estimates everything", func(t *testing.T) {
+		t.Parallel()
+		u := pipe.EstimateRequestUsage(pipe.Request{
+			SystemPrompt: "You are helpful.", // 4 tokens
+			Messages:     []pipe.Message{user("list files")},
+		})
+		assert.Equal(t, pipe.Usage{InputTokens: 4 + 3}, u)
+	})
+
+	t.Run("later requests build on the last reported usage", func(t *testing.T) {
+		t.Parallel()
+		u := pipe.EstimateRequestUsage(pipe.Request{
+			SystemPrompt: "You are helpful.",
+			Messages: []pipe.Message{
+				user("list files"),
+				pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 1000, OutputTokens: 50}},
+				user("12345678"),
+			},
+		})
+		assert.Equal(t, pipe.Usage{InputTokens: 1050 + 2}, u)
+	})
+
+	t.Run("cached prefix is read from the cache", func(t *testing.T) {
+		t.Parallel()
+		u := pipe.EstimateRequestUsage(pipe.Request{
+			Messages: []pipe.Message{
+				user("list files"),
+				pipe.AssistantMessage{Usage: pipe.Usage{InputTokens: 10, CacheReadTokens: 900, OutputTokens: 90}},
+				user("12345678"),
+			},
+		})
+		assert.Equal(t, pipe.Usage{InputTokens: 2, CacheReadTokens: 1000}, u)
+	})
+}
@@ -3,6 +3,10 @@ package pipe
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 )
 
 // Tool is the schema sent to the LLM describing a tool's capabilities.
@@ -12,6 +16,16 @@ type Tool struct {
 	Parameters  json.RawMessage
 }
 
+// ServerTool identifies a provider-hosted tool. Unlike [Tool], server tools are
+// executed by the provider and need no local [ToolExecutor]. Providers map each
+// value onto their own built-in tool and ignore values they do not support.
+type ServerTool string
+
+const (
+	ServerToolWebSearch     ServerTool = "web_search"
+	ServerToolCodeExecution ServerTool = "code_execution"
+)
+
 // ToolExecutor runs tools. Execute returns error for infrastructure failures.
 // ToolResult.IsError indicates tool-reported domain failures sent back to the LLM.
 type ToolExecutor interface {
@@ -22,4 +36,223 @@ type ToolExecutor interface {
 type ToolResult struct {
 	Content []ContentBlock
 	IsError bool
+	// Usage is what provider requests made to produce the result cost,
+	// such as a sub-agent's. It counts toward the run's usage and limits.
+	Usage Usage
+}
+
+// UnavailableTools returns placeholder definitions for the tools called in
+// msgs that are missing from tools, e.g. because a resumed session used a
+// tool that is no longer registered. Sending the placeholders keeps
+// providers that validate tool calls against the tool list from rejecting
+// the old session.
+func UnavailableTools(msgs []Message, tools []Tool) []Tool {
+	known := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		known[t.Name] = true
+	}
+	var placeholders []Tool
+	for _, msg := range msgs {
+		am, ok := msg.(AssistantMessage)
+		if !ok {
+			continue
+		}
+		for _, b := range am.Content {
+			tc, ok := b.(ToolCallBlock)
+			if !ok || known[tc.Name] {
+				continue
+			}
+			known[tc.Name] = true
+			placeholders = append(placeholders, Tool{
+				Name:        tc.Name,
+				Description: "Unavailable: this tool was used earlier in the conversation but is no longer registered. Do not call it.",
+				Parameters:  json.RawMessage(`{"type":"object","properties":{}}`),
+			})
+		}
+	}
+	return placeholders
+}
+
+// unavailableToolResult is the result of a call to an unavailable tool.
+func unavailableToolResult(name string) *ToolResult {
+	return &ToolResult{
+		Content: []ContentBlock{TextBlock{Text: "tool " + name + " is unavailable: it was used earlier in this conversation but is no longer registered. Use the available tools instead."}},
+		IsError: true,
+	}
+}
+
+// ElideToolResults returns msgs with the content of tool results older
+// than keepTurns turns replaced by a line saying what was left out, so a
+// long session's requests stay within the context window while the
+// session keeps every result. A result's age is the number of assistant
+// messages after it: with keepTurns 1 only the results of the latest
+// assistant message are kept in full. Zero or less keeps every result.
+// msgs is not modified.
+func ElideToolResults(msgs []Message, keepTurns int) []Message {
+	if keepTurns <= 0 {
+		return msgs
+	}
+	var out []Message
+	age := 0
+	for i := len(msgs) - 1; i >= 0; i-- {
+		switch m := msgs[i].(type) {
+		case AssistantMessage:
+			age++
+		case ToolResultMessage:
+			if age < keepTurns || len(m.Content) == 0 {
+				continue
+			}
+			if out == nil {
+				out = slices.Clone(msgs)
+			}
+			m.Content = []ContentBlock{TextBlock{Text: elidedResult(m, keepTurns)}}
+			out[i] = m
+		}
+	}
+	if out == nil {
+		return msgs
+	}
+	return out
+}
+
+// elidedResult is the line that stands in for an elided tool result.
+func elidedResult(m ToolResultMessage, keepTurns int) string {
+	var lines, size, other int
+	for _, b := range m.Content {
+		if t, ok := b.(TextBlock); ok {
+			size += len(t.Text)
+			lines += strings.Count(t.Text, "\n") + 1
+		} else {
+			other++
+		}
+	}
+	detail := fmt.Sprintf("%d lines, %d bytes", lines, size)
+	if other > 0 {
+		detail += fmt.Sprintf(", %d non-text blocks", other)
+	}
+	return fmt.Sprintf("[%s result omitted (%s): older than %d turns. Call the tool again if you need it.]", m.ToolName, detail, keepTurns)
+}
+
+// ToolResultFormat says how a provider rewrites the text of tool results
+// before sending them, to spend fewer tokens on what means nothing to the
+// model. The zero value sends the text as the tool produced it. Providers
+// choose the rewrites that pay off for how they encode tool results.
+type ToolResultFormat struct {
+	// StripANSI removes terminal escape sequences, such as colors.
+	StripANSI bool
+	// CompactWhitespace trims trailing whitespace from each line, collapses
+	// runs of blank lines into one, and drops blank lines at either end.
+	// Indentation is kept.
+	CompactWhitespace bool
+	// Unfence removes a markdown code fence wrapping the whole result. A
+	// tool result is already set apart from prose, so the fence is only
+	// needed around code within a longer text.
+	Unfence bool
+	// Verbatim names tools whose results are never rewritten, such as
+	// ones returning file contents the model later quotes in exact edits.
+	Verbatim []string
+}
+
+// rewrites reports whether f changes any text.
+func (f ToolResultFormat) rewrites() bool {
+	return f.StripANSI || f.CompactWhitespace || f.Unfence
+}
+
+// ansiEscape matches CSI and OSC terminal escape sequences.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// Format returns text rewritten as f says.
+func (f ToolResultFormat) Format(text string) string {
+	if f.StripANSI && strings.IndexByte(text, 0x1b) >= 0 {
+		text = ansiEscape.ReplaceAllString(text, "")
+	}
+	if f.Unfence {
+		text = unfence(text)
+	}
+	if f.CompactWhitespace {
+		text = compactWhitespace(text)
+	}
+	return text
+}
+
+// unfence returns the body of the code fence that makes up all of text,
+// or text when it is not wholly fenced.
+func unfence(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") {
+		return text
+	}
+	open, body, ok := strings.Cut(trimmed, "\n")
+	if !ok || strings.Contains(open[3:], "`") {
+		return text
+	}
+	body = strings.TrimSuffix(body, "```")
+	if strings.Contains(body, "```") {
+		return text // several fenced blocks
+	}
+	return strings.TrimSuffix(body, "\n")
+}
+
+// compactWhitespace trims trailing whitespace from the lines of text,
+// collapses runs of blank lines into one, and drops those at either end.
+func compactWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	out := lines[:0]
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.Trim(strings.Join(out, "\n"), "\n")
+}
+
+// FormatToolResults returns msgs with the text of their tool results
+// rewritten as f says. msgs is not modified.
+func FormatToolResults(msgs []Message, f ToolResultFormat) []Message {
+	if !f.rewrites() {
+		return msgs
+	}
+	var out []Message
+	for i, msg := range msgs {
+		m, ok := msg.(ToolResultMessage)
+		if !ok || slices.Contains(f.Verbatim, m.ToolName) {
+			continue
+		}
+		var content []ContentBlock
+		for j, b := range m.Content {
+			t, ok := b.(TextBlock)
+			if !ok {
+				continue
+			}
+			formatted := f.Format(t.Text)
+			if formatted == t.Text {
+				continue
+			}
+			if content == nil {
+				content = slices.Clone(m.Content)
+			}
+			t.Text = formatted
+			content[j] = t
+		}
+		if content == nil {
+			continue
+		}
+		if out == nil {
+			out = slices.Clone(msgs)
+		}
+		m.Content = content
+		out[i] = m
+	}
+	if out == nil {
+		return msgs
+	}
+	return out
 }
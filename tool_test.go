@@ -6,6 +6,7 @@ import (
 
 	"github.com/fwojciec/pipe"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTool_Fields(t *testing.T) {
@@ -39,3 +40,96 @@ func TestToolResult_Error(t *testing.T) {
 	}
 	assert.True(t, result.IsError)
 }
+
+func TestUnavailableTools(t *testing.T) {
+	t.Parallel()
+	msgs := []pipe.Message{
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}},
+		pipe.AssistantMessage{Content: []pipe.ContentBlock{
+			pipe.ToolCallBlock{ID: "1", Name: "bash"},
+			pipe.ToolCallBlock{ID: "2", Name: "mcp_search"},
+		}},
+		pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.ToolCallBlock{ID: "3", Name: "mcp_search"}}},
+	}
+
+	got := pipe.UnavailableTools(msgs, []pipe.Tool{{Name: "bash"}})
+	require.Len(t, got, 1)
+	assert.Equal(t, "mcp_search", got[0].Name)
+	assert.Contains(t, got[0].Description, "no longer registered")
+	assert.JSONEq(t, `{"type":"object","properties":{}}`, string(got[0].Parameters))
+
+	assert.Empty(t, pipe.UnavailableTools(msgs, []pipe.Tool{{Name: "bash"}, {Name: "mcp_search"}}))
+}
+
+func TestElideToolResults(t *testing.T) {
+	t.Parallel()
+	result := func(id, text string) pipe.ToolResultMessage {
+		return pipe.ToolResultMessage{ToolCallID: id, ToolName: "read", Content: []pipe.ContentBlock{pipe.TextBlock{Text: text}}}
+	}
+	call := func(id string) pipe.AssistantMessage {
+		return pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.ToolCallBlock{ID: id, Name: "read"}}}
+	}
+	msgs := []pipe.Message{
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "hi"}}},
+		call("1"), result("1", "one\ntwo"),
+		call("2"), result("2", "three"),
+		call("3"), result("3", "four"),
+	}
+
+	got := pipe.ElideToolResults(msgs, 2)
+	require.Len(t, got, len(msgs))
+	assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "[read result omitted (2 lines, 7 bytes): older than 2 turns. Call the tool again if you need it.]"}},
+		got[2].(pipe.ToolResultMessage).Content)
+	assert.Equal(t, "1", got[2].(pipe.ToolResultMessage).ToolCallID)
+	assert.Equal(t, msgs[4], got[4])
+	assert.Equal(t, msgs[6], got[6])
+	assert.Equal(t, result("1", "one\ntwo"), msgs[2], "the input is not modified")
+
+	assert.Equal(t, msgs, pipe.ElideToolResults(msgs, 0))
+	assert.Equal(t, msgs, pipe.ElideToolResults(msgs, 3))
+}
+
+func TestToolResultFormat_Format(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		format pipe.ToolResultFormat
+		text   string
+		want   string
+	}{
+		{"zero value keeps text", pipe.ToolResultFormat{}, "\x1b[1mbold\x1b[0m  \n\n\n", "\x1b[1mbold\x1b[0m  \n\n\n"},
+		{"strips colors", pipe.ToolResultFormat{StripANSI: true}, "\x1b[31;1mFAIL\x1b[0m x", "FAIL x"},
+		{"strips hyperlinks", pipe.ToolResultFormat{StripANSI: true}, "\x1b]8;;https://example.com\x07link\x1b]8;;\x07", "link"},
+		{"compacts whitespace", pipe.ToolResultFormat{CompactWhitespace: true}, "\n\na  \n\n\n\n  b\t\n\n", "a\n\n  b"},
+		{"unfences a whole result", pipe.ToolResultFormat{Unfence: true}, "```json\n{\"a\": 1}\n```\n", "{\"a\": 1}"},
+		{"keeps a fence within prose", pipe.ToolResultFormat{Unfence: true}, "Output:\n```\nx\n```", "Output:\n```\nx\n```"},
+		{"keeps several fences", pipe.ToolResultFormat{Unfence: true}, "```\nx\n```\n\n```\ny\n```", "```\nx\n```\n\n```\ny\n```"},
+		{"all rewrites", pipe.ToolResultFormat{StripANSI: true, CompactWhitespace: true, Unfence: true}, "```\n\x1b[32mok\x1b[0m   \n\n\n\ndone\n```", "ok\n\ndone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, tt.format.Format(tt.text))
+		})
+	}
+}
+
+func TestFormatToolResults(t *testing.T) {
+	t.Parallel()
+
+	image := pipe.ImageBlock{Data: []byte{1}, MimeType: "image/png"}
+	msgs := []pipe.Message{
+		pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "a  \n\n\n"}}},
+		pipe.ToolResultMessage{ToolCallID: "c1", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "out  \n\n\n"}, image}},
+		pipe.ToolResultMessage{ToolCallID: "c2", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "clean"}}},
+		pipe.ToolResultMessage{ToolCallID: "c3", ToolName: "read", Content: []pipe.ContentBlock{pipe.TextBlock{Text: "1\tx := 1  \n2\t\n3\t\n"}}},
+	}
+	got := pipe.FormatToolResults(msgs, pipe.ToolResultFormat{CompactWhitespace: true, Verbatim: []string{"read"}})
+
+	assert.Equal(t, msgs[0], got[0], "only tool results are formatted")
+	assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "out"}, image}, got[1].(pipe.ToolResultMessage).Content)
+	assert.Equal(t, msgs[2], got[2])
+	assert.Equal(t, msgs[3], got[3], "verbatim tools are not formatted")
+	assert.Equal(t, "out  \n\n\n", msgs[1].(pipe.ToolResultMessage).Content[0].(pipe.TextBlock).Text, "msgs is not modified")
+}
@@ -0,0 +1,100 @@
+package pipe
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// TranscriptSink receives the records of a run's transcript, e.g. to keep
+// an append-only log for debugging and audit. json.TranscriptWriter is one.
+type TranscriptSink interface {
+	WriteRecord(TranscriptRecord) error
+}
+
+// TranscriptRecord is one timestamped entry of a run's transcript. Exactly
+// one of Event, Request, Response, and Tool is set.
+type TranscriptRecord struct {
+	Time     time.Time
+	Event    Event
+	Request  *Request          // sent to the provider, once per attempt
+	Response *AssistantMessage // as the provider returned it
+	Tool     *ToolExecution
+	// Offset is, for a Request, how many of its leading messages are
+	// those of the previous request record. Request.Messages holds only
+	// the rest, so the transcript does not repeat the history on every
+	// request. It is zero after the history is rewritten, e.g. by
+	// compaction.
+	Offset int
+}
+
+// ToolExecution is a tool call the loop ran, with its result and how long
+// it took. Calls that were denied or never ran are not recorded.
+type ToolExecution struct {
+	Call     ToolCallBlock
+	Result   ToolResult
+	Duration time.Duration
+	TimedOut bool
+}
+
+// WithTranscript records the run to sink: every event, every provider
+// request and response, including those of retries and compaction, and
+// every tool execution with its duration, each stamped with the run's
+// clock. Unlike the session, which keeps only final messages, it captures
+// the deltas and timing of how they came about. Records are written in
+// order as they happen; after the first write error the sink receives
+// nothing more.
+func WithTranscript(sink TranscriptSink) RunOption {
+	return func(c *runConfig) {
+		c.transcriptSink = sink
+	}
+}
+
+// transcriptLog writes a run's records to its sink. A nil log writes
+// nothing.
+type transcriptLog struct {
+	sink  TranscriptSink
+	clock Clock
+
+	mu     sync.Mutex // tool calls may finish concurrently
+	failed bool
+
+	// Touched only by the loop's goroutine.
+	logged int     // messages of the last request recorded
+	last   Message // the last of them, to notice a rewrite
+}
+
+func (t *transcriptLog) write(r TranscriptRecord) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failed {
+		return
+	}
+	r.Time = t.clock.Now()
+	t.failed = t.sink.WriteRecord(r) != nil
+}
+
+// request records req with only the messages the previous request did not
+// have.
+func (t *transcriptLog) request(req Request) {
+	if t == nil {
+		return
+	}
+	offset := t.logged
+	if offset > len(req.Messages) || (offset > 0 && !reflect.DeepEqual(req.Messages[offset-1], t.last)) {
+		offset = 0
+	}
+	t.logged = len(req.Messages)
+	if t.logged > 0 {
+		t.last = req.Messages[t.logged-1]
+	}
+	req.Messages = req.Messages[offset:]
+	t.write(TranscriptRecord{Request: &req, Offset: offset})
+}
+
+func (t *transcriptLog) response(msg AssistantMessage) {
+	t.write(TranscriptRecord{Response: &msg})
+}
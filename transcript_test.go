@@ -0,0 +1,166 @@
+package pipe_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/fwojciec/pipe"
+	"github.com/fwojciec/pipe/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink collects transcript records, failing writes once err is
+// set.
+type recordingSink struct {
+	records []pipe.TranscriptRecord
+	err     error
+}
+
+func (s *recordingSink) WriteRecord(r pipe.TranscriptRecord) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, r)
+	return nil
+}
+
+func TestLoop_WithTranscript(t *testing.T) {
+	t.Parallel()
+
+	// provider calls read once, streaming a delta, then answers.
+	provider := func() *mock.Provider {
+		calls := 0
+		return &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			calls++
+			msg := pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "done"}}, StopReason: pipe.StopEndTurn}
+			if calls == 1 {
+				call := pipe.ToolCallBlock{ID: "c1", Name: "read", Arguments: json.RawMessage(`{}`)}
+				msg = pipe.AssistantMessage{Content: []pipe.ContentBlock{call}, StopReason: pipe.StopToolUse}
+			}
+			events := []pipe.Event{pipe.EventTextDelta{Delta: "x"}}
+			return &mock.Stream{
+				NextFn: func() (pipe.Event, error) {
+					if len(events) == 0 {
+						return nil, io.EOF
+					}
+					e := events[0]
+					events = events[1:]
+					return e, nil
+				},
+				MessageFn: func() (pipe.AssistantMessage, error) { return msg, nil },
+			}, nil
+		}}
+	}
+	executor := &mock.ToolExecutor{ExecuteFn: func(context.Context, string, json.RawMessage) (*pipe.ToolResult, error) {
+		return &pipe.ToolResult{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "contents"}}}, nil
+	}}
+	tools := []pipe.Tool{{Name: "read"}}
+
+	t.Run("records requests, responses, events, and tool executions in order", func(t *testing.T) {
+		t.Parallel()
+		now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+		clock := &mock.Clock{NowFn: func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}}
+		sink := &recordingSink{}
+		var handled int
+		session := &pipe.Session{Messages: []pipe.Message{pipe.UserMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "read it"}}}}}
+		err := pipe.NewLoop(provider(), executor).Run(context.Background(), session, tools,
+			pipe.WithClock(clock), pipe.WithTranscript(sink),
+			pipe.WithEventHandler(func(pipe.Event) { handled++ }))
+		require.NoError(t, err)
+
+		var kinds []string
+		for _, r := range sink.records {
+			switch {
+			case r.Request != nil:
+				kinds = append(kinds, "request")
+			case r.Response != nil:
+				kinds = append(kinds, "response")
+			case r.Tool != nil:
+				kinds = append(kinds, "tool")
+			default:
+				kinds = append(kinds, eventKind(r.Event))
+			}
+		}
+		assert.Equal(t, []string{
+			"request", "text_delta", "response", "turn_complete", "tool", "tool_result",
+			"request", "text_delta", "response", "turn_complete",
+		}, kinds)
+		assert.Equal(t, 5, handled, "the event handler still receives every event")
+
+		first, second := sink.records[0], sink.records[6]
+		assert.Zero(t, first.Offset)
+		assert.Len(t, first.Request.Messages, 1)
+		assert.Equal(t, 1, second.Offset, "a request records only the messages added since the previous one")
+		require.Len(t, second.Request.Messages, 2)
+		assert.IsType(t, pipe.ToolResultMessage{}, second.Request.Messages[1])
+
+		tool := sink.records[4].Tool
+		assert.Equal(t, "c1", tool.Call.ID)
+		assert.Equal(t, []pipe.ContentBlock{pipe.TextBlock{Text: "contents"}}, tool.Result.Content)
+		assert.Equal(t, time.Second, tool.Duration)
+		for i := 1; i < len(sink.records); i++ {
+			assert.True(t, sink.records[i].Time.After(sink.records[i-1].Time), "records are stamped in order")
+		}
+	})
+
+	t.Run("records tool executions that run concurrently", func(t *testing.T) {
+		t.Parallel()
+		calls := 0
+		provider := &mock.Provider{StreamFn: func(context.Context, pipe.Request) (pipe.Stream, error) {
+			calls++
+			msg := pipe.AssistantMessage{Content: []pipe.ContentBlock{pipe.TextBlock{Text: "done"}}, StopReason: pipe.StopEndTurn}
+			if calls == 1 {
+				msg = pipe.AssistantMessage{Content: []pipe.ContentBlock{
+					pipe.ToolCallBlock{ID: "c1", Name: "read", Arguments: json.RawMessage(`{}`)},
+					pipe.ToolCallBlock{ID: "c2", Name: "read", Arguments: json.RawMessage(`{}`)},
+				}, StopReason: pipe.StopToolUse}
+			}
+			return &mock.Stream{
+				NextFn:    func() (pipe.Event, error) { return nil, io.EOF },
+				MessageFn: func() (pipe.AssistantMessage, error) { return msg, nil },
+			}, nil
+		}}
+		sink := &recordingSink{}
+		err := pipe.NewLoop(provider, executor).Run(context.Background(), &pipe.Session{}, tools,
+			pipe.WithTranscript(sink), pipe.WithToolConcurrency(2))
+		require.NoError(t, err)
+
+		var ids []string
+		for _, r := range sink.records {
+			if r.Tool != nil {
+				ids = append(ids, r.Tool.Call.ID)
+			}
+		}
+		assert.ElementsMatch(t, []string{"c1", "c2"}, ids)
+	})
+
+	t.Run("a failing sink does not fail the run", func(t *testing.T) {
+		t.Parallel()
+		sink := &recordingSink{err: errors.New("disk full")}
+		err := pipe.NewLoop(provider(), executor).Run(context.Background(), &pipe.Session{}, tools, pipe.WithTranscript(sink))
+		require.NoError(t, err)
+		assert.Empty(t, sink.records)
+	})
+}
+
+// eventKind names the events the transcript test expects.
+func eventKind(e pipe.Event) string {
+	switch e.(type) {
+	case pipe.EventTextDelta:
+		return "text_delta"
+	case pipe.EventTurnComplete:
+		return "turn_complete"
+	case pipe.EventToolResult:
+		return "tool_result"
+	default:
+		return "other"
+	}
+}
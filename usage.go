@@ -1,5 +1,11 @@
 package pipe
 
+import (
+	"context"
+	"math"
+	"time"
+)
+
 // Usage tracks token consumption.
 //
 // Invariant across all providers:
@@ -20,3 +26,51 @@ type Usage struct {
 	CacheReadTokens  int
 	CacheWriteTokens int
 }
+
+// Add returns the sum of u and o.
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		InputTokens:      u.InputTokens + o.InputTokens,
+		OutputTokens:     u.OutputTokens + o.OutputTokens,
+		CacheReadTokens:  u.CacheReadTokens + o.CacheReadTokens,
+		CacheWriteTokens: u.CacheWriteTokens + o.CacheWriteTokens,
+	}
+}
+
+// UsageReporter reports the token usage a provider recorded for the
+// account over a period, e.g. from a usage or billing API.
+type UsageReporter interface {
+	ReportedUsage(ctx context.Context, start, end time.Time) (Usage, error)
+}
+
+// UsageDiscrepancy is a usage category whose locally accumulated count
+// differs from the provider's.
+type UsageDiscrepancy struct {
+	Category string // "input", "cache_read", "cache_write", "output", or "total_input"
+	Local    int
+	Reported int
+}
+
+// ReconcileUsage compares locally accumulated usage with the usage a
+// provider reported and returns the categories that differ by more than
+// tolerance, a fraction of the larger count. Total input is compared too,
+// so a count moved between categories is reported apart from a count
+// that was lost.
+func ReconcileUsage(local, reported Usage, tolerance float64) []UsageDiscrepancy {
+	totalInput := func(u Usage) int { return u.InputTokens + u.CacheReadTokens + u.CacheWriteTokens }
+	categories := []UsageDiscrepancy{
+		{Category: "input", Local: local.InputTokens, Reported: reported.InputTokens},
+		{Category: "cache_read", Local: local.CacheReadTokens, Reported: reported.CacheReadTokens},
+		{Category: "cache_write", Local: local.CacheWriteTokens, Reported: reported.CacheWriteTokens},
+		{Category: "output", Local: local.OutputTokens, Reported: reported.OutputTokens},
+		{Category: "total_input", Local: totalInput(local), Reported: totalInput(reported)},
+	}
+	var diffs []UsageDiscrepancy
+	for _, c := range categories {
+		delta := math.Abs(float64(c.Local - c.Reported))
+		if delta > tolerance*float64(max(c.Local, c.Reported)) {
+			diffs = append(diffs, c)
+		}
+	}
+	return diffs
+}
@@ -22,6 +22,9 @@ func TestStopReason_Values(t *testing.T) {
 	assert.Equal(t, pipe.StopReason("error"), pipe.StopError)
 	assert.Equal(t, pipe.StopReason("aborted"), pipe.StopAborted)
 	assert.Equal(t, pipe.StopReason("unknown"), pipe.StopUnknown)
+	assert.Equal(t, pipe.StopReason("rate_limited"), pipe.StopRateLimited)
+	assert.Equal(t, pipe.StopReason("content_filtered"), pipe.StopContentFiltered)
+	assert.Equal(t, pipe.StopReason("auth_failed"), pipe.StopAuthFailed)
 }
 
 func TestUsage_ZeroValue(t *testing.T) {
@@ -30,3 +33,40 @@ func TestUsage_ZeroValue(t *testing.T) {
 	assert.Equal(t, 0, u.InputTokens)
 	assert.Equal(t, 0, u.OutputTokens)
 }
+
+func TestUsage_Add(t *testing.T) {
+	t.Parallel()
+	a := pipe.Usage{InputTokens: 1, OutputTokens: 2, CacheReadTokens: 3, CacheWriteTokens: 4}
+	b := pipe.Usage{InputTokens: 10, OutputTokens: 20, CacheReadTokens: 30, CacheWriteTokens: 40}
+	assert.Equal(t, pipe.Usage{InputTokens: 11, OutputTokens: 22, CacheReadTokens: 33, CacheWriteTokens: 44}, a.Add(b))
+}
+
+func TestReconcileUsage(t *testing.T) {
+	t.Parallel()
+	reported := pipe.Usage{InputTokens: 1000, OutputTokens: 200, CacheReadTokens: 5000, CacheWriteTokens: 300}
+
+	t.Run("within tolerance", func(t *testing.T) {
+		t.Parallel()
+		local := pipe.Usage{InputTokens: 990, OutputTokens: 200, CacheReadTokens: 5000, CacheWriteTokens: 300}
+		assert.Empty(t, pipe.ReconcileUsage(local, reported, 0.02))
+	})
+
+	t.Run("cached tokens counted as input", func(t *testing.T) {
+		t.Parallel()
+		// Cache hits double-counted as uncached input inflate total input.
+		local := pipe.Usage{InputTokens: 6000, OutputTokens: 200, CacheReadTokens: 5000, CacheWriteTokens: 300}
+		assert.Equal(t, []pipe.UsageDiscrepancy{
+			{Category: "input", Local: 6000, Reported: 1000},
+			{Category: "total_input", Local: 11300, Reported: 6300},
+		}, pipe.ReconcileUsage(local, reported, 0.02))
+	})
+
+	t.Run("tokens moved between categories", func(t *testing.T) {
+		t.Parallel()
+		local := pipe.Usage{InputTokens: 1300, OutputTokens: 200, CacheReadTokens: 5000}
+		assert.Equal(t, []pipe.UsageDiscrepancy{
+			{Category: "input", Local: 1300, Reported: 1000},
+			{Category: "cache_write", Local: 0, Reported: 300},
+		}, pipe.ReconcileUsage(local, reported, 0.02))
+	})
+}